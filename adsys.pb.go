@@ -105,16 +105,17 @@ func (x *ListUsersRequest) GetActive() bool {
 	return false
 }
 
-type StopRequest struct {
+type ListUsersResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Force bool `protobuf:"varint,1,opt,name=force,proto3" json:"force,omitempty"`
+	Msg   string   `protobuf:"bytes,1,opt,name=msg,proto3" json:"msg,omitempty"` // Space separated list of users
+	Users []string `protobuf:"bytes,2,rep,name=users,proto3" json:"users,omitempty"`
 }
 
-func (x *StopRequest) Reset() {
-	*x = StopRequest{}
+func (x *ListUsersResponse) Reset() {
+	*x = ListUsersResponse{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_adsys_proto_msgTypes[2]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -122,13 +123,13 @@ func (x *StopRequest) Reset() {
 	}
 }
 
-func (x *StopRequest) String() string {
+func (x *ListUsersResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StopRequest) ProtoMessage() {}
+func (*ListUsersResponse) ProtoMessage() {}
 
-func (x *StopRequest) ProtoReflect() protoreflect.Message {
+func (x *ListUsersResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_adsys_proto_msgTypes[2]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -140,28 +141,42 @@ func (x *StopRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StopRequest.ProtoReflect.Descriptor instead.
-func (*StopRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use ListUsersResponse.ProtoReflect.Descriptor instead.
+func (*ListUsersResponse) Descriptor() ([]byte, []int) {
 	return file_adsys_proto_rawDescGZIP(), []int{2}
 }
 
-func (x *StopRequest) GetForce() bool {
+func (x *ListUsersResponse) GetMsg() string {
 	if x != nil {
-		return x.Force
+		return x.Msg
 	}
-	return false
+	return ""
 }
 
-type StringResponse struct {
+func (x *ListUsersResponse) GetUsers() []string {
+	if x != nil {
+		return x.Users
+	}
+	return nil
+}
+
+type StatusResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Msg string `protobuf:"bytes,1,opt,name=msg,proto3" json:"msg,omitempty"`
+	Msg                 string          `protobuf:"bytes,1,opt,name=msg,proto3" json:"msg,omitempty"` // Formatted status
+	Machine             *ObjectStatus   `protobuf:"bytes,2,opt,name=machine,proto3" json:"machine,omitempty"`
+	Users               []*ObjectStatus `protobuf:"bytes,3,rep,name=users,proto3" json:"users,omitempty"`             // Connected users
+	NextRefresh         string          `protobuf:"bytes,4,opt,name=nextRefresh,proto3" json:"nextRefresh,omitempty"` // RFC 3339 timestamp, empty if unknown
+	SubscriptionEnabled bool            `protobuf:"varint,5,opt,name=subscriptionEnabled,proto3" json:"subscriptionEnabled,omitempty"`
+	ProOnlyRules        []string        `protobuf:"bytes,6,rep,name=proOnlyRules,proto3" json:"proOnlyRules,omitempty"` // Policy types requiring an Ubuntu Pro subscription
+	AdInfo              string          `protobuf:"bytes,7,opt,name=adInfo,proto3" json:"adInfo,omitempty"`
+	Daemon              *DaemonStatus   `protobuf:"bytes,8,opt,name=daemon,proto3" json:"daemon,omitempty"`
 }
 
-func (x *StringResponse) Reset() {
-	*x = StringResponse{}
+func (x *StatusResponse) Reset() {
+	*x = StatusResponse{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_adsys_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -169,13 +184,13 @@ func (x *StringResponse) Reset() {
 	}
 }
 
-func (x *StringResponse) String() string {
+func (x *StatusResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StringResponse) ProtoMessage() {}
+func (*StatusResponse) ProtoMessage() {}
 
-func (x *StringResponse) ProtoReflect() protoreflect.Message {
+func (x *StatusResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_adsys_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -187,32 +202,79 @@ func (x *StringResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StringResponse.ProtoReflect.Descriptor instead.
-func (*StringResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use StatusResponse.ProtoReflect.Descriptor instead.
+func (*StatusResponse) Descriptor() ([]byte, []int) {
 	return file_adsys_proto_rawDescGZIP(), []int{3}
 }
 
-func (x *StringResponse) GetMsg() string {
+func (x *StatusResponse) GetMsg() string {
 	if x != nil {
 		return x.Msg
 	}
 	return ""
 }
 
-type UpdatePolicyRequest struct {
+func (x *StatusResponse) GetMachine() *ObjectStatus {
+	if x != nil {
+		return x.Machine
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetUsers() []*ObjectStatus {
+	if x != nil {
+		return x.Users
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetNextRefresh() string {
+	if x != nil {
+		return x.NextRefresh
+	}
+	return ""
+}
+
+func (x *StatusResponse) GetSubscriptionEnabled() bool {
+	if x != nil {
+		return x.SubscriptionEnabled
+	}
+	return false
+}
+
+func (x *StatusResponse) GetProOnlyRules() []string {
+	if x != nil {
+		return x.ProOnlyRules
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetAdInfo() string {
+	if x != nil {
+		return x.AdInfo
+	}
+	return ""
+}
+
+func (x *StatusResponse) GetDaemon() *DaemonStatus {
+	if x != nil {
+		return x.Daemon
+	}
+	return nil
+}
+
+type ObjectStatus struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	IsComputer bool   `protobuf:"varint,1,opt,name=isComputer,proto3" json:"isComputer,omitempty"`
-	All        bool   `protobuf:"varint,2,opt,name=all,proto3" json:"all,omitempty"` // Update policies of the machine and all the users
-	Target     string `protobuf:"bytes,3,opt,name=target,proto3" json:"target,omitempty"`
-	Krb5Cc     string `protobuf:"bytes,4,opt,name=krb5cc,proto3" json:"krb5cc,omitempty"`
-	Purge      bool   `protobuf:"varint,5,opt,name=purge,proto3" json:"purge,omitempty"`
+	Name       string            `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	LastUpdate string            `protobuf:"bytes,2,opt,name=lastUpdate,proto3" json:"lastUpdate,omitempty"` // RFC 3339 timestamp, empty if no policy was applied
+	Failures   []*ManagerFailure `protobuf:"bytes,3,rep,name=failures,proto3" json:"failures,omitempty"`     // Policy managers which failed on last refresh
 }
 
-func (x *UpdatePolicyRequest) Reset() {
-	*x = UpdatePolicyRequest{}
+func (x *ObjectStatus) Reset() {
+	*x = ObjectStatus{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_adsys_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -220,13 +282,13 @@ func (x *UpdatePolicyRequest) Reset() {
 	}
 }
 
-func (x *UpdatePolicyRequest) String() string {
+func (x *ObjectStatus) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UpdatePolicyRequest) ProtoMessage() {}
+func (*ObjectStatus) ProtoMessage() {}
 
-func (x *UpdatePolicyRequest) ProtoReflect() protoreflect.Message {
+func (x *ObjectStatus) ProtoReflect() protoreflect.Message {
 	mi := &file_adsys_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -238,74 +300,1207 @@ func (x *UpdatePolicyRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UpdatePolicyRequest.ProtoReflect.Descriptor instead.
-func (*UpdatePolicyRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use ObjectStatus.ProtoReflect.Descriptor instead.
+func (*ObjectStatus) Descriptor() ([]byte, []int) {
 	return file_adsys_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *UpdatePolicyRequest) GetIsComputer() bool {
+func (x *ObjectStatus) GetName() string {
 	if x != nil {
-		return x.IsComputer
+		return x.Name
 	}
-	return false
+	return ""
 }
 
-func (x *UpdatePolicyRequest) GetAll() bool {
+func (x *ObjectStatus) GetLastUpdate() string {
 	if x != nil {
-		return x.All
+		return x.LastUpdate
 	}
-	return false
+	return ""
 }
 
-func (x *UpdatePolicyRequest) GetTarget() string {
+func (x *ObjectStatus) GetFailures() []*ManagerFailure {
 	if x != nil {
-		return x.Target
+		return x.Failures
+	}
+	return nil
+}
+
+type ManagerFailure struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Manager string `protobuf:"bytes,1,opt,name=manager,proto3" json:"manager,omitempty"`
+	Error   string `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *ManagerFailure) Reset() {
+	*x = ManagerFailure{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_adsys_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ManagerFailure) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ManagerFailure) ProtoMessage() {}
+
+func (x *ManagerFailure) ProtoReflect() protoreflect.Message {
+	mi := &file_adsys_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ManagerFailure.ProtoReflect.Descriptor instead.
+func (*ManagerFailure) Descriptor() ([]byte, []int) {
+	return file_adsys_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ManagerFailure) GetManager() string {
+	if x != nil {
+		return x.Manager
+	}
+	return ""
+}
+
+func (x *ManagerFailure) GetError() string {
+	if x != nil {
+		return x.Error
 	}
 	return ""
 }
 
-func (x *UpdatePolicyRequest) GetKrb5Cc() string {
-	if x != nil {
-		return x.Krb5Cc
-	}
-	return ""
+type DaemonStatus struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Timeout      string `protobuf:"bytes,1,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	Socket       string `protobuf:"bytes,2,opt,name=socket,proto3" json:"socket,omitempty"`
+	CacheDir     string `protobuf:"bytes,3,opt,name=cacheDir,proto3" json:"cacheDir,omitempty"`
+	RunDir       string `protobuf:"bytes,4,opt,name=runDir,proto3" json:"runDir,omitempty"`
+	DconfDir     string `protobuf:"bytes,5,opt,name=dconfDir,proto3" json:"dconfDir,omitempty"`
+	SudoersDir   string `protobuf:"bytes,6,opt,name=sudoersDir,proto3" json:"sudoersDir,omitempty"`
+	PolicyKitDir string `protobuf:"bytes,7,opt,name=policyKitDir,proto3" json:"policyKitDir,omitempty"`
+	ApparmorDir  string `protobuf:"bytes,8,opt,name=apparmorDir,proto3" json:"apparmorDir,omitempty"`
+}
+
+func (x *DaemonStatus) Reset() {
+	*x = DaemonStatus{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_adsys_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DaemonStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DaemonStatus) ProtoMessage() {}
+
+func (x *DaemonStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_adsys_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DaemonStatus.ProtoReflect.Descriptor instead.
+func (*DaemonStatus) Descriptor() ([]byte, []int) {
+	return file_adsys_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DaemonStatus) GetTimeout() string {
+	if x != nil {
+		return x.Timeout
+	}
+	return ""
+}
+
+func (x *DaemonStatus) GetSocket() string {
+	if x != nil {
+		return x.Socket
+	}
+	return ""
+}
+
+func (x *DaemonStatus) GetCacheDir() string {
+	if x != nil {
+		return x.CacheDir
+	}
+	return ""
+}
+
+func (x *DaemonStatus) GetRunDir() string {
+	if x != nil {
+		return x.RunDir
+	}
+	return ""
+}
+
+func (x *DaemonStatus) GetDconfDir() string {
+	if x != nil {
+		return x.DconfDir
+	}
+	return ""
+}
+
+func (x *DaemonStatus) GetSudoersDir() string {
+	if x != nil {
+		return x.SudoersDir
+	}
+	return ""
+}
+
+func (x *DaemonStatus) GetPolicyKitDir() string {
+	if x != nil {
+		return x.PolicyKitDir
+	}
+	return ""
+}
+
+func (x *DaemonStatus) GetApparmorDir() string {
+	if x != nil {
+		return x.ApparmorDir
+	}
+	return ""
+}
+
+type StopRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Force bool `protobuf:"varint,1,opt,name=force,proto3" json:"force,omitempty"`
+}
+
+func (x *StopRequest) Reset() {
+	*x = StopRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_adsys_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StopRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StopRequest) ProtoMessage() {}
+
+func (x *StopRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_adsys_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StopRequest.ProtoReflect.Descriptor instead.
+func (*StopRequest) Descriptor() ([]byte, []int) {
+	return file_adsys_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *StopRequest) GetForce() bool {
+	if x != nil {
+		return x.Force
+	}
+	return false
+}
+
+type StringResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Msg string `protobuf:"bytes,1,opt,name=msg,proto3" json:"msg,omitempty"`
+}
+
+func (x *StringResponse) Reset() {
+	*x = StringResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_adsys_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StringResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StringResponse) ProtoMessage() {}
+
+func (x *StringResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_adsys_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StringResponse.ProtoReflect.Descriptor instead.
+func (*StringResponse) Descriptor() ([]byte, []int) {
+	return file_adsys_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *StringResponse) GetMsg() string {
+	if x != nil {
+		return x.Msg
+	}
+	return ""
+}
+
+type UpdatePolicyRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	IsComputer bool   `protobuf:"varint,1,opt,name=isComputer,proto3" json:"isComputer,omitempty"`
+	All        bool   `protobuf:"varint,2,opt,name=all,proto3" json:"all,omitempty"` // Update policies of the machine and all the users
+	Target     string `protobuf:"bytes,3,opt,name=target,proto3" json:"target,omitempty"`
+	Krb5Cc     string `protobuf:"bytes,4,opt,name=krb5cc,proto3" json:"krb5cc,omitempty"`
+	Purge      bool   `protobuf:"varint,5,opt,name=purge,proto3" json:"purge,omitempty"`
+	DryRun     bool   `protobuf:"varint,6,opt,name=dryRun,proto3" json:"dryRun,omitempty"` // Only print what would change, without applying anything
+}
+
+func (x *UpdatePolicyRequest) Reset() {
+	*x = UpdatePolicyRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_adsys_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UpdatePolicyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdatePolicyRequest) ProtoMessage() {}
+
+func (x *UpdatePolicyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_adsys_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdatePolicyRequest.ProtoReflect.Descriptor instead.
+func (*UpdatePolicyRequest) Descriptor() ([]byte, []int) {
+	return file_adsys_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *UpdatePolicyRequest) GetIsComputer() bool {
+	if x != nil {
+		return x.IsComputer
+	}
+	return false
+}
+
+func (x *UpdatePolicyRequest) GetAll() bool {
+	if x != nil {
+		return x.All
+	}
+	return false
+}
+
+func (x *UpdatePolicyRequest) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *UpdatePolicyRequest) GetKrb5Cc() string {
+	if x != nil {
+		return x.Krb5Cc
+	}
+	return ""
+}
+
+func (x *UpdatePolicyRequest) GetPurge() bool {
+	if x != nil {
+		return x.Purge
+	}
+	return false
+}
+
+func (x *UpdatePolicyRequest) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+type DumpPoliciesRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Target     string `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	IsComputer bool   `protobuf:"varint,2,opt,name=isComputer,proto3" json:"isComputer,omitempty"`
+	Details    bool   `protobuf:"varint,3,opt,name=details,proto3" json:"details,omitempty"` // Show rules in addition to GPO
+	All        bool   `protobuf:"varint,4,opt,name=all,proto3" json:"all,omitempty"`         // Show overridden rules
+}
+
+func (x *DumpPoliciesRequest) Reset() {
+	*x = DumpPoliciesRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_adsys_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DumpPoliciesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DumpPoliciesRequest) ProtoMessage() {}
+
+func (x *DumpPoliciesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_adsys_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DumpPoliciesRequest.ProtoReflect.Descriptor instead.
+func (*DumpPoliciesRequest) Descriptor() ([]byte, []int) {
+	return file_adsys_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *DumpPoliciesRequest) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *DumpPoliciesRequest) GetIsComputer() bool {
+	if x != nil {
+		return x.IsComputer
+	}
+	return false
+}
+
+func (x *DumpPoliciesRequest) GetDetails() bool {
+	if x != nil {
+		return x.Details
+	}
+	return false
+}
+
+func (x *DumpPoliciesRequest) GetAll() bool {
+	if x != nil {
+		return x.All
+	}
+	return false
+}
+
+type DumpPoliciesResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Msg  string `protobuf:"bytes,1,opt,name=msg,proto3" json:"msg,omitempty"`   // Formatted policies
+	Gpos []*GPO `protobuf:"bytes,2,rep,name=gpos,proto3" json:"gpos,omitempty"` // By order of priority
+}
+
+func (x *DumpPoliciesResponse) Reset() {
+	*x = DumpPoliciesResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_adsys_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DumpPoliciesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DumpPoliciesResponse) ProtoMessage() {}
+
+func (x *DumpPoliciesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_adsys_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DumpPoliciesResponse.ProtoReflect.Descriptor instead.
+func (*DumpPoliciesResponse) Descriptor() ([]byte, []int) {
+	return file_adsys_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *DumpPoliciesResponse) GetMsg() string {
+	if x != nil {
+		return x.Msg
+	}
+	return ""
+}
+
+func (x *DumpPoliciesResponse) GetGpos() []*GPO {
+	if x != nil {
+		return x.Gpos
+	}
+	return nil
+}
+
+type GPO struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id         string  `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name       string  `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	IsComputer bool    `protobuf:"varint,3,opt,name=isComputer,proto3" json:"isComputer,omitempty"` // From the machine configuration
+	LastUpdate string  `protobuf:"bytes,4,opt,name=lastUpdate,proto3" json:"lastUpdate,omitempty"`  // RFC 3339 timestamp
+	Rules      []*Rule `protobuf:"bytes,5,rep,name=rules,proto3" json:"rules,omitempty"`            // Only filled when details are requested
+}
+
+func (x *GPO) Reset() {
+	*x = GPO{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_adsys_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GPO) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GPO) ProtoMessage() {}
+
+func (x *GPO) ProtoReflect() protoreflect.Message {
+	mi := &file_adsys_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GPO.ProtoReflect.Descriptor instead.
+func (*GPO) Descriptor() ([]byte, []int) {
+	return file_adsys_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *GPO) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *GPO) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *GPO) GetIsComputer() bool {
+	if x != nil {
+		return x.IsComputer
+	}
+	return false
+}
+
+func (x *GPO) GetLastUpdate() string {
+	if x != nil {
+		return x.LastUpdate
+	}
+	return ""
+}
+
+func (x *GPO) GetRules() []*Rule {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+type Rule struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Type       string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Key        string `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Value      string `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	Disabled   bool   `protobuf:"varint,4,opt,name=disabled,proto3" json:"disabled,omitempty"`
+	Strategy   string `protobuf:"bytes,5,opt,name=strategy,proto3" json:"strategy,omitempty"`
+	Overridden bool   `protobuf:"varint,6,opt,name=overridden,proto3" json:"overridden,omitempty"` // Already set by a GPO with higher priority
+}
+
+func (x *Rule) Reset() {
+	*x = Rule{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_adsys_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Rule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Rule) ProtoMessage() {}
+
+func (x *Rule) ProtoReflect() protoreflect.Message {
+	mi := &file_adsys_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Rule.ProtoReflect.Descriptor instead.
+func (*Rule) Descriptor() ([]byte, []int) {
+	return file_adsys_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *Rule) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Rule) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *Rule) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *Rule) GetDisabled() bool {
+	if x != nil {
+		return x.Disabled
+	}
+	return false
+}
+
+func (x *Rule) GetStrategy() string {
+	if x != nil {
+		return x.Strategy
+	}
+	return ""
+}
+
+func (x *Rule) GetOverridden() bool {
+	if x != nil {
+		return x.Overridden
+	}
+	return false
+}
+
+type PolicyHistoryRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Target     string `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	IsComputer bool   `protobuf:"varint,2,opt,name=isComputer,proto3" json:"isComputer,omitempty"`
+}
+
+func (x *PolicyHistoryRequest) Reset() {
+	*x = PolicyHistoryRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_adsys_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PolicyHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PolicyHistoryRequest) ProtoMessage() {}
+
+func (x *PolicyHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_adsys_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PolicyHistoryRequest.ProtoReflect.Descriptor instead.
+func (*PolicyHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_adsys_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *PolicyHistoryRequest) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *PolicyHistoryRequest) GetIsComputer() bool {
+	if x != nil {
+		return x.IsComputer
+	}
+	return false
+}
+
+type PolicyRollbackRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Target     string `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	IsComputer bool   `protobuf:"varint,2,opt,name=isComputer,proto3" json:"isComputer,omitempty"`
+	Id         int32  `protobuf:"varint,3,opt,name=id,proto3" json:"id,omitempty"` // Snapshot to apply again
+}
+
+func (x *PolicyRollbackRequest) Reset() {
+	*x = PolicyRollbackRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_adsys_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PolicyRollbackRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PolicyRollbackRequest) ProtoMessage() {}
+
+func (x *PolicyRollbackRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_adsys_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PolicyRollbackRequest.ProtoReflect.Descriptor instead.
+func (*PolicyRollbackRequest) Descriptor() ([]byte, []int) {
+	return file_adsys_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *PolicyRollbackRequest) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *PolicyRollbackRequest) GetIsComputer() bool {
+	if x != nil {
+		return x.IsComputer
+	}
+	return false
+}
+
+func (x *PolicyRollbackRequest) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type VerifyPolicyRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	IsComputer bool   `protobuf:"varint,1,opt,name=isComputer,proto3" json:"isComputer,omitempty"`
+	All        bool   `protobuf:"varint,2,opt,name=all,proto3" json:"all,omitempty"` // Verify policies of the machine and all the users
+	Target     string `protobuf:"bytes,3,opt,name=target,proto3" json:"target,omitempty"`
+}
+
+func (x *VerifyPolicyRequest) Reset() {
+	*x = VerifyPolicyRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_adsys_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VerifyPolicyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyPolicyRequest) ProtoMessage() {}
+
+func (x *VerifyPolicyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_adsys_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyPolicyRequest.ProtoReflect.Descriptor instead.
+func (*VerifyPolicyRequest) Descriptor() ([]byte, []int) {
+	return file_adsys_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *VerifyPolicyRequest) GetIsComputer() bool {
+	if x != nil {
+		return x.IsComputer
+	}
+	return false
+}
+
+func (x *VerifyPolicyRequest) GetAll() bool {
+	if x != nil {
+		return x.All
+	}
+	return false
+}
+
+func (x *VerifyPolicyRequest) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+type VerifyPolicyResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Msg   string `protobuf:"bytes,1,opt,name=msg,proto3" json:"msg,omitempty"`
+	Drift bool   `protobuf:"varint,2,opt,name=drift,proto3" json:"drift,omitempty"` // System state differs from the applied policies
+}
+
+func (x *VerifyPolicyResponse) Reset() {
+	*x = VerifyPolicyResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_adsys_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VerifyPolicyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyPolicyResponse) ProtoMessage() {}
+
+func (x *VerifyPolicyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_adsys_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyPolicyResponse.ProtoReflect.Descriptor instead.
+func (*VerifyPolicyResponse) Descriptor() ([]byte, []int) {
+	return file_adsys_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *VerifyPolicyResponse) GetMsg() string {
+	if x != nil {
+		return x.Msg
+	}
+	return ""
+}
+
+func (x *VerifyPolicyResponse) GetDrift() bool {
+	if x != nil {
+		return x.Drift
+	}
+	return false
+}
+
+type PolicyReportRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Target     string `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	IsComputer bool   `protobuf:"varint,2,opt,name=isComputer,proto3" json:"isComputer,omitempty"`
+}
+
+func (x *PolicyReportRequest) Reset() {
+	*x = PolicyReportRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_adsys_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PolicyReportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PolicyReportRequest) ProtoMessage() {}
+
+func (x *PolicyReportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_adsys_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PolicyReportRequest.ProtoReflect.Descriptor instead.
+func (*PolicyReportRequest) Descriptor() ([]byte, []int) {
+	return file_adsys_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *PolicyReportRequest) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *PolicyReportRequest) GetIsComputer() bool {
+	if x != nil {
+		return x.IsComputer
+	}
+	return false
+}
+
+type PolicyDiffRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Target     string `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	IsComputer bool   `protobuf:"varint,2,opt,name=isComputer,proto3" json:"isComputer,omitempty"`
+	Snapshot   int32  `protobuf:"varint,3,opt,name=snapshot,proto3" json:"snapshot,omitempty"` // Snapshot to compare with the applied policies, 0 to compare with Active Directory
+	Krb5Cc     string `protobuf:"bytes,4,opt,name=krb5cc,proto3" json:"krb5cc,omitempty"`
+}
+
+func (x *PolicyDiffRequest) Reset() {
+	*x = PolicyDiffRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_adsys_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PolicyDiffRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PolicyDiffRequest) ProtoMessage() {}
+
+func (x *PolicyDiffRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_adsys_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PolicyDiffRequest.ProtoReflect.Descriptor instead.
+func (*PolicyDiffRequest) Descriptor() ([]byte, []int) {
+	return file_adsys_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *PolicyDiffRequest) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *PolicyDiffRequest) GetIsComputer() bool {
+	if x != nil {
+		return x.IsComputer
+	}
+	return false
+}
+
+func (x *PolicyDiffRequest) GetSnapshot() int32 {
+	if x != nil {
+		return x.Snapshot
+	}
+	return 0
+}
+
+func (x *PolicyDiffRequest) GetKrb5Cc() string {
+	if x != nil {
+		return x.Krb5Cc
+	}
+	return ""
+}
+
+type PolicyRSOPRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Target     string `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	IsComputer bool   `protobuf:"varint,2,opt,name=isComputer,proto3" json:"isComputer,omitempty"`
+}
+
+func (x *PolicyRSOPRequest) Reset() {
+	*x = PolicyRSOPRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_adsys_proto_msgTypes[20]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PolicyRSOPRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PolicyRSOPRequest) ProtoMessage() {}
+
+func (x *PolicyRSOPRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_adsys_proto_msgTypes[20]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PolicyRSOPRequest.ProtoReflect.Descriptor instead.
+func (*PolicyRSOPRequest) Descriptor() ([]byte, []int) {
+	return file_adsys_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *PolicyRSOPRequest) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *PolicyRSOPRequest) GetIsComputer() bool {
+	if x != nil {
+		return x.IsComputer
+	}
+	return false
+}
+
+type CapturePolicyRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Target     string `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	IsComputer bool   `protobuf:"varint,2,opt,name=isComputer,proto3" json:"isComputer,omitempty"`
+}
+
+func (x *CapturePolicyRequest) Reset() {
+	*x = CapturePolicyRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_adsys_proto_msgTypes[21]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CapturePolicyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CapturePolicyRequest) ProtoMessage() {}
+
+func (x *CapturePolicyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_adsys_proto_msgTypes[21]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CapturePolicyRequest.ProtoReflect.Descriptor instead.
+func (*CapturePolicyRequest) Descriptor() ([]byte, []int) {
+	return file_adsys_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *CapturePolicyRequest) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *CapturePolicyRequest) GetIsComputer() bool {
+	if x != nil {
+		return x.IsComputer
+	}
+	return false
+}
+
+type CapturePolicyResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Data []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"` // Chunk of the compressed snapshot archive
+}
+
+func (x *CapturePolicyResponse) Reset() {
+	*x = CapturePolicyResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_adsys_proto_msgTypes[22]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CapturePolicyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CapturePolicyResponse) ProtoMessage() {}
+
+func (x *CapturePolicyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_adsys_proto_msgTypes[22]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CapturePolicyResponse.ProtoReflect.Descriptor instead.
+func (*CapturePolicyResponse) Descriptor() ([]byte, []int) {
+	return file_adsys_proto_rawDescGZIP(), []int{22}
 }
 
-func (x *UpdatePolicyRequest) GetPurge() bool {
+func (x *CapturePolicyResponse) GetData() []byte {
 	if x != nil {
-		return x.Purge
+		return x.Data
 	}
-	return false
+	return nil
 }
 
-type DumpPoliciesRequest struct {
+type ApplySnapshotRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Target     string `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
-	IsComputer bool   `protobuf:"varint,2,opt,name=isComputer,proto3" json:"isComputer,omitempty"`
-	Details    bool   `protobuf:"varint,3,opt,name=details,proto3" json:"details,omitempty"` // Show rules in addition to GPO
-	All        bool   `protobuf:"varint,4,opt,name=all,proto3" json:"all,omitempty"`         // Show overridden rules
+	Archive string `protobuf:"bytes,1,opt,name=archive,proto3" json:"archive,omitempty"` // Path to the snapshot archive on the machine running the daemon
 }
 
-func (x *DumpPoliciesRequest) Reset() {
-	*x = DumpPoliciesRequest{}
+func (x *ApplySnapshotRequest) Reset() {
+	*x = ApplySnapshotRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_adsys_proto_msgTypes[5]
+		mi := &file_adsys_proto_msgTypes[23]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *DumpPoliciesRequest) String() string {
+func (x *ApplySnapshotRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DumpPoliciesRequest) ProtoMessage() {}
+func (*ApplySnapshotRequest) ProtoMessage() {}
 
-func (x *DumpPoliciesRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_adsys_proto_msgTypes[5]
+func (x *ApplySnapshotRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_adsys_proto_msgTypes[23]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -316,39 +1511,18 @@ func (x *DumpPoliciesRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DumpPoliciesRequest.ProtoReflect.Descriptor instead.
-func (*DumpPoliciesRequest) Descriptor() ([]byte, []int) {
-	return file_adsys_proto_rawDescGZIP(), []int{5}
+// Deprecated: Use ApplySnapshotRequest.ProtoReflect.Descriptor instead.
+func (*ApplySnapshotRequest) Descriptor() ([]byte, []int) {
+	return file_adsys_proto_rawDescGZIP(), []int{23}
 }
 
-func (x *DumpPoliciesRequest) GetTarget() string {
+func (x *ApplySnapshotRequest) GetArchive() string {
 	if x != nil {
-		return x.Target
+		return x.Archive
 	}
 	return ""
 }
 
-func (x *DumpPoliciesRequest) GetIsComputer() bool {
-	if x != nil {
-		return x.IsComputer
-	}
-	return false
-}
-
-func (x *DumpPoliciesRequest) GetDetails() bool {
-	if x != nil {
-		return x.Details
-	}
-	return false
-}
-
-func (x *DumpPoliciesRequest) GetAll() bool {
-	if x != nil {
-		return x.All
-	}
-	return false
-}
-
 type DumpPolicyDefinitionsRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -361,7 +1535,7 @@ type DumpPolicyDefinitionsRequest struct {
 func (x *DumpPolicyDefinitionsRequest) Reset() {
 	*x = DumpPolicyDefinitionsRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_adsys_proto_msgTypes[6]
+		mi := &file_adsys_proto_msgTypes[24]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -374,7 +1548,7 @@ func (x *DumpPolicyDefinitionsRequest) String() string {
 func (*DumpPolicyDefinitionsRequest) ProtoMessage() {}
 
 func (x *DumpPolicyDefinitionsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_adsys_proto_msgTypes[6]
+	mi := &file_adsys_proto_msgTypes[24]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -387,7 +1561,7 @@ func (x *DumpPolicyDefinitionsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DumpPolicyDefinitionsRequest.ProtoReflect.Descriptor instead.
 func (*DumpPolicyDefinitionsRequest) Descriptor() ([]byte, []int) {
-	return file_adsys_proto_rawDescGZIP(), []int{6}
+	return file_adsys_proto_rawDescGZIP(), []int{24}
 }
 
 func (x *DumpPolicyDefinitionsRequest) GetFormat() string {
@@ -416,7 +1590,7 @@ type DumpPolicyDefinitionsResponse struct {
 func (x *DumpPolicyDefinitionsResponse) Reset() {
 	*x = DumpPolicyDefinitionsResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_adsys_proto_msgTypes[7]
+		mi := &file_adsys_proto_msgTypes[25]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -429,7 +1603,7 @@ func (x *DumpPolicyDefinitionsResponse) String() string {
 func (*DumpPolicyDefinitionsResponse) ProtoMessage() {}
 
 func (x *DumpPolicyDefinitionsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_adsys_proto_msgTypes[7]
+	mi := &file_adsys_proto_msgTypes[25]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -442,7 +1616,7 @@ func (x *DumpPolicyDefinitionsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DumpPolicyDefinitionsResponse.ProtoReflect.Descriptor instead.
 func (*DumpPolicyDefinitionsResponse) Descriptor() ([]byte, []int) {
-	return file_adsys_proto_rawDescGZIP(), []int{7}
+	return file_adsys_proto_rawDescGZIP(), []int{25}
 }
 
 func (x *DumpPolicyDefinitionsResponse) GetAdmx() string {
@@ -470,7 +1644,7 @@ type GetDocRequest struct {
 func (x *GetDocRequest) Reset() {
 	*x = GetDocRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_adsys_proto_msgTypes[8]
+		mi := &file_adsys_proto_msgTypes[26]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -483,7 +1657,7 @@ func (x *GetDocRequest) String() string {
 func (*GetDocRequest) ProtoMessage() {}
 
 func (x *GetDocRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_adsys_proto_msgTypes[8]
+	mi := &file_adsys_proto_msgTypes[26]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -496,7 +1670,7 @@ func (x *GetDocRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetDocRequest.ProtoReflect.Descriptor instead.
 func (*GetDocRequest) Descriptor() ([]byte, []int) {
-	return file_adsys_proto_rawDescGZIP(), []int{8}
+	return file_adsys_proto_rawDescGZIP(), []int{26}
 }
 
 func (x *GetDocRequest) GetChapter() string {
@@ -517,7 +1691,7 @@ type ListDocReponse struct {
 func (x *ListDocReponse) Reset() {
 	*x = ListDocReponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_adsys_proto_msgTypes[9]
+		mi := &file_adsys_proto_msgTypes[27]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -530,7 +1704,7 @@ func (x *ListDocReponse) String() string {
 func (*ListDocReponse) ProtoMessage() {}
 
 func (x *ListDocReponse) ProtoReflect() protoreflect.Message {
-	mi := &file_adsys_proto_msgTypes[9]
+	mi := &file_adsys_proto_msgTypes[27]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -543,7 +1717,7 @@ func (x *ListDocReponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListDocReponse.ProtoReflect.Descriptor instead.
 func (*ListDocReponse) Descriptor() ([]byte, []int) {
-	return file_adsys_proto_rawDescGZIP(), []int{9}
+	return file_adsys_proto_rawDescGZIP(), []int{27}
 }
 
 func (x *ListDocReponse) GetChapters() []string {
@@ -560,82 +1734,234 @@ var file_adsys_proto_rawDesc = []byte{
 	0x05, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x22, 0x2a, 0x0a, 0x10, 0x4c, 0x69, 0x73, 0x74, 0x55, 0x73,
 	0x65, 0x72, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x63,
 	0x74, 0x69, 0x76, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x61, 0x63, 0x74, 0x69,
-	0x76, 0x65, 0x22, 0x23, 0x0a, 0x0b, 0x53, 0x74, 0x6f, 0x70, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
-	0x74, 0x12, 0x14, 0x0a, 0x05, 0x66, 0x6f, 0x72, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08,
-	0x52, 0x05, 0x66, 0x6f, 0x72, 0x63, 0x65, 0x22, 0x22, 0x0a, 0x0e, 0x53, 0x74, 0x72, 0x69, 0x6e,
-	0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x6d, 0x73, 0x67,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6d, 0x73, 0x67, 0x22, 0x8d, 0x01, 0x0a, 0x13,
-	0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52, 0x65, 0x71, 0x75,
+	0x76, 0x65, 0x22, 0x3b, 0x0a, 0x11, 0x4c, 0x69, 0x73, 0x74, 0x55, 0x73, 0x65, 0x72, 0x73, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x6d, 0x73, 0x67, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6d, 0x73, 0x67, 0x12, 0x14, 0x0a, 0x05, 0x75, 0x73, 0x65,
+	0x72, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x05, 0x75, 0x73, 0x65, 0x72, 0x73, 0x22,
+	0xa7, 0x02, 0x0a, 0x0e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x6d, 0x73, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x03, 0x6d, 0x73, 0x67, 0x12, 0x27, 0x0a, 0x07, 0x6d, 0x61, 0x63, 0x68, 0x69, 0x6e, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x4f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x53, 0x74,
+	0x61, 0x74, 0x75, 0x73, 0x52, 0x07, 0x6d, 0x61, 0x63, 0x68, 0x69, 0x6e, 0x65, 0x12, 0x23, 0x0a,
+	0x05, 0x75, 0x73, 0x65, 0x72, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x4f,
+	0x62, 0x6a, 0x65, 0x63, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x05, 0x75, 0x73, 0x65,
+	0x72, 0x73, 0x12, 0x20, 0x0a, 0x0b, 0x6e, 0x65, 0x78, 0x74, 0x52, 0x65, 0x66, 0x72, 0x65, 0x73,
+	0x68, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x6e, 0x65, 0x78, 0x74, 0x52, 0x65, 0x66,
+	0x72, 0x65, 0x73, 0x68, 0x12, 0x30, 0x0a, 0x13, 0x73, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x70,
+	0x74, 0x69, 0x6f, 0x6e, 0x45, 0x6e, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x13, 0x73, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x45,
+	0x6e, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x12, 0x22, 0x0a, 0x0c, 0x70, 0x72, 0x6f, 0x4f, 0x6e, 0x6c,
+	0x79, 0x52, 0x75, 0x6c, 0x65, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0c, 0x70, 0x72,
+	0x6f, 0x4f, 0x6e, 0x6c, 0x79, 0x52, 0x75, 0x6c, 0x65, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x64,
+	0x49, 0x6e, 0x66, 0x6f, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x61, 0x64, 0x49, 0x6e,
+	0x66, 0x6f, 0x12, 0x25, 0x0a, 0x06, 0x64, 0x61, 0x65, 0x6d, 0x6f, 0x6e, 0x18, 0x08, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x44, 0x61, 0x65, 0x6d, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x52, 0x06, 0x64, 0x61, 0x65, 0x6d, 0x6f, 0x6e, 0x22, 0x6f, 0x0a, 0x0c, 0x4f, 0x62, 0x6a,
+	0x65, 0x63, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d,
+	0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1e, 0x0a,
+	0x0a, 0x6c, 0x61, 0x73, 0x74, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0a, 0x6c, 0x61, 0x73, 0x74, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x12, 0x2b, 0x0a,
+	0x08, 0x66, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x0f, 0x2e, 0x4d, 0x61, 0x6e, 0x61, 0x67, 0x65, 0x72, 0x46, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65,
+	0x52, 0x08, 0x66, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x73, 0x22, 0x40, 0x0a, 0x0e, 0x4d, 0x61,
+	0x6e, 0x61, 0x67, 0x65, 0x72, 0x46, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x12, 0x18, 0x0a, 0x07,
+	0x6d, 0x61, 0x6e, 0x61, 0x67, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d,
+	0x61, 0x6e, 0x61, 0x67, 0x65, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0xf6, 0x01, 0x0a,
+	0x0c, 0x44, 0x61, 0x65, 0x6d, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x18, 0x0a,
+	0x07, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07,
+	0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x6f, 0x63, 0x6b, 0x65,
+	0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x6f, 0x63, 0x6b, 0x65, 0x74, 0x12,
+	0x1a, 0x0a, 0x08, 0x63, 0x61, 0x63, 0x68, 0x65, 0x44, 0x69, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x08, 0x63, 0x61, 0x63, 0x68, 0x65, 0x44, 0x69, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x72,
+	0x75, 0x6e, 0x44, 0x69, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x75, 0x6e,
+	0x44, 0x69, 0x72, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x63, 0x6f, 0x6e, 0x66, 0x44, 0x69, 0x72, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x64, 0x63, 0x6f, 0x6e, 0x66, 0x44, 0x69, 0x72, 0x12,
+	0x1e, 0x0a, 0x0a, 0x73, 0x75, 0x64, 0x6f, 0x65, 0x72, 0x73, 0x44, 0x69, 0x72, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0a, 0x73, 0x75, 0x64, 0x6f, 0x65, 0x72, 0x73, 0x44, 0x69, 0x72, 0x12,
+	0x22, 0x0a, 0x0c, 0x70, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x4b, 0x69, 0x74, 0x44, 0x69, 0x72, 0x18,
+	0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x70, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x4b, 0x69, 0x74,
+	0x44, 0x69, 0x72, 0x12, 0x20, 0x0a, 0x0b, 0x61, 0x70, 0x70, 0x61, 0x72, 0x6d, 0x6f, 0x72, 0x44,
+	0x69, 0x72, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x61, 0x70, 0x70, 0x61, 0x72, 0x6d,
+	0x6f, 0x72, 0x44, 0x69, 0x72, 0x22, 0x23, 0x0a, 0x0b, 0x53, 0x74, 0x6f, 0x70, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x66, 0x6f, 0x72, 0x63, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x05, 0x66, 0x6f, 0x72, 0x63, 0x65, 0x22, 0x22, 0x0a, 0x0e, 0x53, 0x74,
+	0x72, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x10, 0x0a, 0x03,
+	0x6d, 0x73, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6d, 0x73, 0x67, 0x22, 0xa5,
+	0x01, 0x0a, 0x13, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x69, 0x73, 0x43, 0x6f, 0x6d, 0x70,
+	0x75, 0x74, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a, 0x69, 0x73, 0x43, 0x6f,
+	0x6d, 0x70, 0x75, 0x74, 0x65, 0x72, 0x12, 0x10, 0x0a, 0x03, 0x61, 0x6c, 0x6c, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x03, 0x61, 0x6c, 0x6c, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x61, 0x72, 0x67,
+	0x65, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74,
+	0x12, 0x16, 0x0a, 0x06, 0x6b, 0x72, 0x62, 0x35, 0x63, 0x63, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x6b, 0x72, 0x62, 0x35, 0x63, 0x63, 0x12, 0x14, 0x0a, 0x05, 0x70, 0x75, 0x72, 0x67,
+	0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x70, 0x75, 0x72, 0x67, 0x65, 0x12, 0x16,
+	0x0a, 0x06, 0x64, 0x72, 0x79, 0x52, 0x75, 0x6e, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06,
+	0x64, 0x72, 0x79, 0x52, 0x75, 0x6e, 0x22, 0x79, 0x0a, 0x13, 0x44, 0x75, 0x6d, 0x70, 0x50, 0x6f,
+	0x6c, 0x69, 0x63, 0x69, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a,
+	0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x74,
+	0x61, 0x72, 0x67, 0x65, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x69, 0x73, 0x43, 0x6f, 0x6d, 0x70, 0x75,
+	0x74, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a, 0x69, 0x73, 0x43, 0x6f, 0x6d,
+	0x70, 0x75, 0x74, 0x65, 0x72, 0x12, 0x18, 0x0a, 0x07, 0x64, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x73,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x64, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x73, 0x12,
+	0x10, 0x0a, 0x03, 0x61, 0x6c, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x03, 0x61, 0x6c,
+	0x6c, 0x22, 0x42, 0x0a, 0x14, 0x44, 0x75, 0x6d, 0x70, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x69, 0x65,
+	0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x6d, 0x73, 0x67,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6d, 0x73, 0x67, 0x12, 0x18, 0x0a, 0x04, 0x67,
+	0x70, 0x6f, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x04, 0x2e, 0x47, 0x50, 0x4f, 0x52,
+	0x04, 0x67, 0x70, 0x6f, 0x73, 0x22, 0x86, 0x01, 0x0a, 0x03, 0x47, 0x50, 0x4f, 0x12, 0x0e, 0x0a,
+	0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a,
+	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d,
+	0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x69, 0x73, 0x43, 0x6f, 0x6d, 0x70, 0x75, 0x74, 0x65, 0x72, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a, 0x69, 0x73, 0x43, 0x6f, 0x6d, 0x70, 0x75, 0x74, 0x65,
+	0x72, 0x12, 0x1e, 0x0a, 0x0a, 0x6c, 0x61, 0x73, 0x74, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x6c, 0x61, 0x73, 0x74, 0x55, 0x70, 0x64, 0x61, 0x74,
+	0x65, 0x12, 0x1b, 0x0a, 0x05, 0x72, 0x75, 0x6c, 0x65, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x05, 0x2e, 0x52, 0x75, 0x6c, 0x65, 0x52, 0x05, 0x72, 0x75, 0x6c, 0x65, 0x73, 0x22, 0x9a,
+	0x01, 0x0a, 0x04, 0x52, 0x75, 0x6c, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x6b,
+	0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a,
+	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x64, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x12,
+	0x1a, 0x0a, 0x08, 0x73, 0x74, 0x72, 0x61, 0x74, 0x65, 0x67, 0x79, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x08, 0x73, 0x74, 0x72, 0x61, 0x74, 0x65, 0x67, 0x79, 0x12, 0x1e, 0x0a, 0x0a, 0x6f,
+	0x76, 0x65, 0x72, 0x72, 0x69, 0x64, 0x64, 0x65, 0x6e, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x0a, 0x6f, 0x76, 0x65, 0x72, 0x72, 0x69, 0x64, 0x64, 0x65, 0x6e, 0x22, 0x4e, 0x0a, 0x14, 0x50,
+	0x6f, 0x6c, 0x69, 0x63, 0x79, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x69,
+	0x73, 0x43, 0x6f, 0x6d, 0x70, 0x75, 0x74, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x0a, 0x69, 0x73, 0x43, 0x6f, 0x6d, 0x70, 0x75, 0x74, 0x65, 0x72, 0x22, 0x5f, 0x0a, 0x15, 0x50,
+	0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52, 0x6f, 0x6c, 0x6c, 0x62, 0x61, 0x63, 0x6b, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x12, 0x1e, 0x0a, 0x0a,
+	0x69, 0x73, 0x43, 0x6f, 0x6d, 0x70, 0x75, 0x74, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x0a, 0x69, 0x73, 0x43, 0x6f, 0x6d, 0x70, 0x75, 0x74, 0x65, 0x72, 0x12, 0x0e, 0x0a, 0x02,
+	0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x02, 0x69, 0x64, 0x22, 0x5f, 0x0a, 0x13,
+	0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52, 0x65, 0x71, 0x75,
 	0x65, 0x73, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x69, 0x73, 0x43, 0x6f, 0x6d, 0x70, 0x75, 0x74, 0x65,
 	0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a, 0x69, 0x73, 0x43, 0x6f, 0x6d, 0x70, 0x75,
 	0x74, 0x65, 0x72, 0x12, 0x10, 0x0a, 0x03, 0x61, 0x6c, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08,
 	0x52, 0x03, 0x61, 0x6c, 0x6c, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x18,
-	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x12, 0x16, 0x0a,
-	0x06, 0x6b, 0x72, 0x62, 0x35, 0x63, 0x63, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6b,
-	0x72, 0x62, 0x35, 0x63, 0x63, 0x12, 0x14, 0x0a, 0x05, 0x70, 0x75, 0x72, 0x67, 0x65, 0x18, 0x05,
-	0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x70, 0x75, 0x72, 0x67, 0x65, 0x22, 0x79, 0x0a, 0x13, 0x44,
-	0x75, 0x6d, 0x70, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x69, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x22, 0x3e, 0x0a,
+	0x14, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x6d, 0x73, 0x67, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x03, 0x6d, 0x73, 0x67, 0x12, 0x14, 0x0a, 0x05, 0x64, 0x72, 0x69, 0x66, 0x74,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x64, 0x72, 0x69, 0x66, 0x74, 0x22, 0x4d, 0x0a,
+	0x13, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x12, 0x1e, 0x0a, 0x0a,
+	0x69, 0x73, 0x43, 0x6f, 0x6d, 0x70, 0x75, 0x74, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x0a, 0x69, 0x73, 0x43, 0x6f, 0x6d, 0x70, 0x75, 0x74, 0x65, 0x72, 0x22, 0x7f, 0x0a, 0x11,
+	0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x44, 0x69, 0x66, 0x66, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x69, 0x73, 0x43,
+	0x6f, 0x6d, 0x70, 0x75, 0x74, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a, 0x69,
+	0x73, 0x43, 0x6f, 0x6d, 0x70, 0x75, 0x74, 0x65, 0x72, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x6e, 0x61,
+	0x70, 0x73, 0x68, 0x6f, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x73, 0x6e, 0x61,
+	0x70, 0x73, 0x68, 0x6f, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x6b, 0x72, 0x62, 0x35, 0x63, 0x63, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6b, 0x72, 0x62, 0x35, 0x63, 0x63, 0x22, 0x4b, 0x0a,
+	0x11, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52, 0x53, 0x4f, 0x50, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x69, 0x73,
+	0x43, 0x6f, 0x6d, 0x70, 0x75, 0x74, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a,
+	0x69, 0x73, 0x43, 0x6f, 0x6d, 0x70, 0x75, 0x74, 0x65, 0x72, 0x22, 0x4e, 0x0a, 0x14, 0x43, 0x61,
+	0x70, 0x74, 0x75, 0x72, 0x65, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65,
 	0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x18, 0x01, 0x20, 0x01,
 	0x28, 0x09, 0x52, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x69, 0x73,
 	0x43, 0x6f, 0x6d, 0x70, 0x75, 0x74, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a,
-	0x69, 0x73, 0x43, 0x6f, 0x6d, 0x70, 0x75, 0x74, 0x65, 0x72, 0x12, 0x18, 0x0a, 0x07, 0x64, 0x65,
-	0x74, 0x61, 0x69, 0x6c, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x64, 0x65, 0x74,
-	0x61, 0x69, 0x6c, 0x73, 0x12, 0x10, 0x0a, 0x03, 0x61, 0x6c, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28,
-	0x08, 0x52, 0x03, 0x61, 0x6c, 0x6c, 0x22, 0x52, 0x0a, 0x1c, 0x44, 0x75, 0x6d, 0x70, 0x50, 0x6f,
-	0x6c, 0x69, 0x63, 0x79, 0x44, 0x65, 0x66, 0x69, 0x6e, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52,
-	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x12, 0x1a,
-	0x0a, 0x08, 0x64, 0x69, 0x73, 0x74, 0x72, 0x6f, 0x49, 0x44, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x08, 0x64, 0x69, 0x73, 0x74, 0x72, 0x6f, 0x49, 0x44, 0x22, 0x47, 0x0a, 0x1d, 0x44, 0x75,
-	0x6d, 0x70, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x44, 0x65, 0x66, 0x69, 0x6e, 0x69, 0x74, 0x69,
-	0x6f, 0x6e, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x61,
-	0x64, 0x6d, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x61, 0x64, 0x6d, 0x78, 0x12,
-	0x12, 0x0a, 0x04, 0x61, 0x64, 0x6d, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x61,
-	0x64, 0x6d, 0x6c, 0x22, 0x29, 0x0a, 0x0d, 0x47, 0x65, 0x74, 0x44, 0x6f, 0x63, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x68, 0x61, 0x70, 0x74, 0x65, 0x72, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x68, 0x61, 0x70, 0x74, 0x65, 0x72, 0x22, 0x2c,
-	0x0a, 0x0e, 0x4c, 0x69, 0x73, 0x74, 0x44, 0x6f, 0x63, 0x52, 0x65, 0x70, 0x6f, 0x6e, 0x73, 0x65,
-	0x12, 0x1a, 0x0a, 0x08, 0x63, 0x68, 0x61, 0x70, 0x74, 0x65, 0x72, 0x73, 0x18, 0x01, 0x20, 0x03,
-	0x28, 0x09, 0x52, 0x08, 0x63, 0x68, 0x61, 0x70, 0x74, 0x65, 0x72, 0x73, 0x32, 0xc0, 0x04, 0x0a,
-	0x07, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x20, 0x0a, 0x03, 0x43, 0x61, 0x74, 0x12,
-	0x06, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x0f, 0x2e, 0x53, 0x74, 0x72, 0x69, 0x6e, 0x67,
-	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x12, 0x24, 0x0a, 0x07, 0x56, 0x65,
-	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x06, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x0f, 0x2e,
-	0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01,
-	0x12, 0x23, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x06, 0x2e, 0x45, 0x6d, 0x70,
-	0x74, 0x79, 0x1a, 0x0f, 0x2e, 0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x30, 0x01, 0x12, 0x1e, 0x0a, 0x04, 0x53, 0x74, 0x6f, 0x70, 0x12, 0x0c, 0x2e,
-	0x53, 0x74, 0x6f, 0x70, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x06, 0x2e, 0x45, 0x6d,
-	0x70, 0x74, 0x79, 0x30, 0x01, 0x12, 0x2e, 0x0a, 0x0c, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x50,
-	0x6f, 0x6c, 0x69, 0x63, 0x79, 0x12, 0x14, 0x2e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x50, 0x6f,
-	0x6c, 0x69, 0x63, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x06, 0x2e, 0x45, 0x6d,
-	0x70, 0x74, 0x79, 0x30, 0x01, 0x12, 0x37, 0x0a, 0x0c, 0x44, 0x75, 0x6d, 0x70, 0x50, 0x6f, 0x6c,
-	0x69, 0x63, 0x69, 0x65, 0x73, 0x12, 0x14, 0x2e, 0x44, 0x75, 0x6d, 0x70, 0x50, 0x6f, 0x6c, 0x69,
-	0x63, 0x69, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0f, 0x2e, 0x53, 0x74,
-	0x72, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x12, 0x5a,
-	0x0a, 0x17, 0x44, 0x75, 0x6d, 0x70, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x69, 0x65, 0x73, 0x44, 0x65,
-	0x66, 0x69, 0x6e, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x1d, 0x2e, 0x44, 0x75, 0x6d, 0x70,
-	0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x44, 0x65, 0x66, 0x69, 0x6e, 0x69, 0x74, 0x69, 0x6f, 0x6e,
-	0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1e, 0x2e, 0x44, 0x75, 0x6d, 0x70, 0x50,
-	0x6f, 0x6c, 0x69, 0x63, 0x79, 0x44, 0x65, 0x66, 0x69, 0x6e, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73,
-	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x12, 0x2b, 0x0a, 0x06, 0x47, 0x65,
-	0x74, 0x44, 0x6f, 0x63, 0x12, 0x0e, 0x2e, 0x47, 0x65, 0x74, 0x44, 0x6f, 0x63, 0x52, 0x65, 0x71,
+	0x69, 0x73, 0x43, 0x6f, 0x6d, 0x70, 0x75, 0x74, 0x65, 0x72, 0x22, 0x2b, 0x0a, 0x15, 0x43, 0x61,
+	0x70, 0x74, 0x75, 0x72, 0x65, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x30, 0x0a, 0x14, 0x41, 0x70, 0x70, 0x6c, 0x79,
+	0x53, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x18, 0x0a, 0x07, 0x61, 0x72, 0x63, 0x68, 0x69, 0x76, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x07, 0x61, 0x72, 0x63, 0x68, 0x69, 0x76, 0x65, 0x22, 0x52, 0x0a, 0x1c, 0x44, 0x75, 0x6d,
+	0x70, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x44, 0x65, 0x66, 0x69, 0x6e, 0x69, 0x74, 0x69, 0x6f,
+	0x6e, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x66, 0x6f, 0x72,
+	0x6d, 0x61, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x66, 0x6f, 0x72, 0x6d, 0x61,
+	0x74, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x69, 0x73, 0x74, 0x72, 0x6f, 0x49, 0x44, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x08, 0x64, 0x69, 0x73, 0x74, 0x72, 0x6f, 0x49, 0x44, 0x22, 0x47, 0x0a,
+	0x1d, 0x44, 0x75, 0x6d, 0x70, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x44, 0x65, 0x66, 0x69, 0x6e,
+	0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x12,
+	0x0a, 0x04, 0x61, 0x64, 0x6d, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x61, 0x64,
+	0x6d, 0x78, 0x12, 0x12, 0x0a, 0x04, 0x61, 0x64, 0x6d, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x61, 0x64, 0x6d, 0x6c, 0x22, 0x29, 0x0a, 0x0d, 0x47, 0x65, 0x74, 0x44, 0x6f, 0x63,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x68, 0x61, 0x70, 0x74,
+	0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x68, 0x61, 0x70, 0x74, 0x65,
+	0x72, 0x22, 0x2c, 0x0a, 0x0e, 0x4c, 0x69, 0x73, 0x74, 0x44, 0x6f, 0x63, 0x52, 0x65, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x68, 0x61, 0x70, 0x74, 0x65, 0x72, 0x73, 0x18,
+	0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08, 0x63, 0x68, 0x61, 0x70, 0x74, 0x65, 0x72, 0x73, 0x32,
+	0x97, 0x08, 0x0a, 0x07, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x20, 0x0a, 0x03, 0x43,
+	0x61, 0x74, 0x12, 0x06, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x0f, 0x2e, 0x53, 0x74, 0x72,
+	0x69, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x12, 0x24, 0x0a,
+	0x07, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x06, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79,
+	0x1a, 0x0f, 0x2e, 0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x30, 0x01, 0x12, 0x23, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x06, 0x2e,
+	0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x0f, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x12, 0x1e, 0x0a, 0x04, 0x53, 0x74, 0x6f, 0x70,
+	0x12, 0x0c, 0x2e, 0x53, 0x74, 0x6f, 0x70, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x06,
+	0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x30, 0x01, 0x12, 0x37, 0x0a, 0x0c, 0x55, 0x70, 0x64, 0x61,
+	0x74, 0x65, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x12, 0x14, 0x2e, 0x55, 0x70, 0x64, 0x61, 0x74,
+	0x65, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0f,
+	0x2e, 0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30,
+	0x01, 0x12, 0x3d, 0x0a, 0x0c, 0x44, 0x75, 0x6d, 0x70, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x69, 0x65,
+	0x73, 0x12, 0x14, 0x2e, 0x44, 0x75, 0x6d, 0x70, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x69, 0x65, 0x73,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e, 0x44, 0x75, 0x6d, 0x70, 0x50, 0x6f,
+	0x6c, 0x69, 0x63, 0x69, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01,
+	0x12, 0x39, 0x0a, 0x0d, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72,
+	0x79, 0x12, 0x15, 0x2e, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72,
+	0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0f, 0x2e, 0x53, 0x74, 0x72, 0x69, 0x6e,
+	0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x12, 0x32, 0x0a, 0x0e, 0x50,
+	0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52, 0x6f, 0x6c, 0x6c, 0x62, 0x61, 0x63, 0x6b, 0x12, 0x16, 0x2e,
+	0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52, 0x6f, 0x6c, 0x6c, 0x62, 0x61, 0x63, 0x6b, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x06, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x30, 0x01, 0x12,
+	0x3d, 0x0a, 0x0c, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x12,
+	0x14, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x50, 0x6f,
+	0x6c, 0x69, 0x63, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x12, 0x37,
+	0x0a, 0x0c, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x14,
+	0x2e, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x71,
 	0x75, 0x65, 0x73, 0x74, 0x1a, 0x0f, 0x2e, 0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73,
-	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x12, 0x24, 0x0a, 0x07, 0x4c, 0x69, 0x73, 0x74, 0x44,
-	0x6f, 0x63, 0x12, 0x06, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x0f, 0x2e, 0x4c, 0x69, 0x73,
-	0x74, 0x44, 0x6f, 0x63, 0x52, 0x65, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x12, 0x31, 0x0a,
-	0x09, 0x4c, 0x69, 0x73, 0x74, 0x55, 0x73, 0x65, 0x72, 0x73, 0x12, 0x11, 0x2e, 0x4c, 0x69, 0x73,
-	0x74, 0x55, 0x73, 0x65, 0x72, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0f, 0x2e,
-	0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01,
-	0x12, 0x2a, 0x0a, 0x0d, 0x47, 0x50, 0x4f, 0x4c, 0x69, 0x73, 0x74, 0x53, 0x63, 0x72, 0x69, 0x70,
-	0x74, 0x12, 0x06, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x0f, 0x2e, 0x53, 0x74, 0x72, 0x69,
-	0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x12, 0x31, 0x0a, 0x14,
-	0x43, 0x65, 0x72, 0x74, 0x41, 0x75, 0x74, 0x6f, 0x45, 0x6e, 0x72, 0x6f, 0x6c, 0x6c, 0x53, 0x63,
-	0x72, 0x69, 0x70, 0x74, 0x12, 0x06, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x0f, 0x2e, 0x53,
-	0x74, 0x72, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x42,
-	0x19, 0x5a, 0x17, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x75, 0x62,
-	0x75, 0x6e, 0x74, 0x75, 0x2f, 0x61, 0x64, 0x73, 0x79, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x33,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x12, 0x33, 0x0a, 0x0a, 0x50, 0x6f, 0x6c, 0x69, 0x63,
+	0x79, 0x44, 0x69, 0x66, 0x66, 0x12, 0x12, 0x2e, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x44, 0x69,
+	0x66, 0x66, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0f, 0x2e, 0x53, 0x74, 0x72, 0x69,
+	0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x12, 0x33, 0x0a, 0x0a,
+	0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52, 0x53, 0x4f, 0x50, 0x12, 0x12, 0x2e, 0x50, 0x6f, 0x6c,
+	0x69, 0x63, 0x79, 0x52, 0x53, 0x4f, 0x50, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0f,
+	0x2e, 0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30,
+	0x01, 0x12, 0x40, 0x0a, 0x0d, 0x43, 0x61, 0x70, 0x74, 0x75, 0x72, 0x65, 0x50, 0x6f, 0x6c, 0x69,
+	0x63, 0x79, 0x12, 0x15, 0x2e, 0x43, 0x61, 0x70, 0x74, 0x75, 0x72, 0x65, 0x50, 0x6f, 0x6c, 0x69,
+	0x63, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e, 0x43, 0x61, 0x70, 0x74,
+	0x75, 0x72, 0x65, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x30, 0x01, 0x12, 0x30, 0x0a, 0x0d, 0x41, 0x70, 0x70, 0x6c, 0x79, 0x53, 0x6e, 0x61, 0x70,
+	0x73, 0x68, 0x6f, 0x74, 0x12, 0x15, 0x2e, 0x41, 0x70, 0x70, 0x6c, 0x79, 0x53, 0x6e, 0x61, 0x70,
+	0x73, 0x68, 0x6f, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x06, 0x2e, 0x45, 0x6d,
+	0x70, 0x74, 0x79, 0x30, 0x01, 0x12, 0x5a, 0x0a, 0x17, 0x44, 0x75, 0x6d, 0x70, 0x50, 0x6f, 0x6c,
+	0x69, 0x63, 0x69, 0x65, 0x73, 0x44, 0x65, 0x66, 0x69, 0x6e, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73,
+	0x12, 0x1d, 0x2e, 0x44, 0x75, 0x6d, 0x70, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x44, 0x65, 0x66,
+	0x69, 0x6e, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x1e, 0x2e, 0x44, 0x75, 0x6d, 0x70, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x44, 0x65, 0x66, 0x69,
+	0x6e, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30,
+	0x01, 0x12, 0x2b, 0x0a, 0x06, 0x47, 0x65, 0x74, 0x44, 0x6f, 0x63, 0x12, 0x0e, 0x2e, 0x47, 0x65,
+	0x74, 0x44, 0x6f, 0x63, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0f, 0x2e, 0x53, 0x74,
+	0x72, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x12, 0x24,
+	0x0a, 0x07, 0x4c, 0x69, 0x73, 0x74, 0x44, 0x6f, 0x63, 0x12, 0x06, 0x2e, 0x45, 0x6d, 0x70, 0x74,
+	0x79, 0x1a, 0x0f, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x44, 0x6f, 0x63, 0x52, 0x65, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x30, 0x01, 0x12, 0x34, 0x0a, 0x09, 0x4c, 0x69, 0x73, 0x74, 0x55, 0x73, 0x65, 0x72,
+	0x73, 0x12, 0x11, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x55, 0x73, 0x65, 0x72, 0x73, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x12, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x55, 0x73, 0x65, 0x72, 0x73,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x12, 0x2a, 0x0a, 0x0d, 0x47, 0x50,
+	0x4f, 0x4c, 0x69, 0x73, 0x74, 0x53, 0x63, 0x72, 0x69, 0x70, 0x74, 0x12, 0x06, 0x2e, 0x45, 0x6d,
+	0x70, 0x74, 0x79, 0x1a, 0x0f, 0x2e, 0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x12, 0x31, 0x0a, 0x14, 0x43, 0x65, 0x72, 0x74, 0x41, 0x75,
+	0x74, 0x6f, 0x45, 0x6e, 0x72, 0x6f, 0x6c, 0x6c, 0x53, 0x63, 0x72, 0x69, 0x70, 0x74, 0x12, 0x06,
+	0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x0f, 0x2e, 0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x42, 0x19, 0x5a, 0x17, 0x67, 0x69, 0x74,
+	0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x75, 0x62, 0x75, 0x6e, 0x74, 0x75, 0x2f, 0x61,
+	0x64, 0x73, 0x79, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -650,49 +1976,89 @@ func file_adsys_proto_rawDescGZIP() []byte {
 	return file_adsys_proto_rawDescData
 }
 
-var file_adsys_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_adsys_proto_msgTypes = make([]protoimpl.MessageInfo, 28)
 var file_adsys_proto_goTypes = []any{
 	(*Empty)(nil),                         // 0: Empty
 	(*ListUsersRequest)(nil),              // 1: ListUsersRequest
-	(*StopRequest)(nil),                   // 2: StopRequest
-	(*StringResponse)(nil),                // 3: StringResponse
-	(*UpdatePolicyRequest)(nil),           // 4: UpdatePolicyRequest
-	(*DumpPoliciesRequest)(nil),           // 5: DumpPoliciesRequest
-	(*DumpPolicyDefinitionsRequest)(nil),  // 6: DumpPolicyDefinitionsRequest
-	(*DumpPolicyDefinitionsResponse)(nil), // 7: DumpPolicyDefinitionsResponse
-	(*GetDocRequest)(nil),                 // 8: GetDocRequest
-	(*ListDocReponse)(nil),                // 9: ListDocReponse
+	(*ListUsersResponse)(nil),             // 2: ListUsersResponse
+	(*StatusResponse)(nil),                // 3: StatusResponse
+	(*ObjectStatus)(nil),                  // 4: ObjectStatus
+	(*ManagerFailure)(nil),                // 5: ManagerFailure
+	(*DaemonStatus)(nil),                  // 6: DaemonStatus
+	(*StopRequest)(nil),                   // 7: StopRequest
+	(*StringResponse)(nil),                // 8: StringResponse
+	(*UpdatePolicyRequest)(nil),           // 9: UpdatePolicyRequest
+	(*DumpPoliciesRequest)(nil),           // 10: DumpPoliciesRequest
+	(*DumpPoliciesResponse)(nil),          // 11: DumpPoliciesResponse
+	(*GPO)(nil),                           // 12: GPO
+	(*Rule)(nil),                          // 13: Rule
+	(*PolicyHistoryRequest)(nil),          // 14: PolicyHistoryRequest
+	(*PolicyRollbackRequest)(nil),         // 15: PolicyRollbackRequest
+	(*VerifyPolicyRequest)(nil),           // 16: VerifyPolicyRequest
+	(*VerifyPolicyResponse)(nil),          // 17: VerifyPolicyResponse
+	(*PolicyReportRequest)(nil),           // 18: PolicyReportRequest
+	(*PolicyDiffRequest)(nil),             // 19: PolicyDiffRequest
+	(*PolicyRSOPRequest)(nil),             // 20: PolicyRSOPRequest
+	(*CapturePolicyRequest)(nil),          // 21: CapturePolicyRequest
+	(*CapturePolicyResponse)(nil),         // 22: CapturePolicyResponse
+	(*ApplySnapshotRequest)(nil),          // 23: ApplySnapshotRequest
+	(*DumpPolicyDefinitionsRequest)(nil),  // 24: DumpPolicyDefinitionsRequest
+	(*DumpPolicyDefinitionsResponse)(nil), // 25: DumpPolicyDefinitionsResponse
+	(*GetDocRequest)(nil),                 // 26: GetDocRequest
+	(*ListDocReponse)(nil),                // 27: ListDocReponse
 }
 var file_adsys_proto_depIdxs = []int32{
-	0,  // 0: service.Cat:input_type -> Empty
-	0,  // 1: service.Version:input_type -> Empty
-	0,  // 2: service.Status:input_type -> Empty
-	2,  // 3: service.Stop:input_type -> StopRequest
-	4,  // 4: service.UpdatePolicy:input_type -> UpdatePolicyRequest
-	5,  // 5: service.DumpPolicies:input_type -> DumpPoliciesRequest
-	6,  // 6: service.DumpPoliciesDefinitions:input_type -> DumpPolicyDefinitionsRequest
-	8,  // 7: service.GetDoc:input_type -> GetDocRequest
-	0,  // 8: service.ListDoc:input_type -> Empty
-	1,  // 9: service.ListUsers:input_type -> ListUsersRequest
-	0,  // 10: service.GPOListScript:input_type -> Empty
-	0,  // 11: service.CertAutoEnrollScript:input_type -> Empty
-	3,  // 12: service.Cat:output_type -> StringResponse
-	3,  // 13: service.Version:output_type -> StringResponse
-	3,  // 14: service.Status:output_type -> StringResponse
-	0,  // 15: service.Stop:output_type -> Empty
-	0,  // 16: service.UpdatePolicy:output_type -> Empty
-	3,  // 17: service.DumpPolicies:output_type -> StringResponse
-	7,  // 18: service.DumpPoliciesDefinitions:output_type -> DumpPolicyDefinitionsResponse
-	3,  // 19: service.GetDoc:output_type -> StringResponse
-	9,  // 20: service.ListDoc:output_type -> ListDocReponse
-	3,  // 21: service.ListUsers:output_type -> StringResponse
-	3,  // 22: service.GPOListScript:output_type -> StringResponse
-	3,  // 23: service.CertAutoEnrollScript:output_type -> StringResponse
-	12, // [12:24] is the sub-list for method output_type
-	0,  // [0:12] is the sub-list for method input_type
-	0,  // [0:0] is the sub-list for extension type_name
-	0,  // [0:0] is the sub-list for extension extendee
-	0,  // [0:0] is the sub-list for field type_name
+	4,  // 0: StatusResponse.machine:type_name -> ObjectStatus
+	4,  // 1: StatusResponse.users:type_name -> ObjectStatus
+	6,  // 2: StatusResponse.daemon:type_name -> DaemonStatus
+	5,  // 3: ObjectStatus.failures:type_name -> ManagerFailure
+	12, // 4: DumpPoliciesResponse.gpos:type_name -> GPO
+	13, // 5: GPO.rules:type_name -> Rule
+	0,  // 6: service.Cat:input_type -> Empty
+	0,  // 7: service.Version:input_type -> Empty
+	0,  // 8: service.Status:input_type -> Empty
+	7,  // 9: service.Stop:input_type -> StopRequest
+	9,  // 10: service.UpdatePolicy:input_type -> UpdatePolicyRequest
+	10, // 11: service.DumpPolicies:input_type -> DumpPoliciesRequest
+	14, // 12: service.PolicyHistory:input_type -> PolicyHistoryRequest
+	15, // 13: service.PolicyRollback:input_type -> PolicyRollbackRequest
+	16, // 14: service.VerifyPolicy:input_type -> VerifyPolicyRequest
+	18, // 15: service.PolicyReport:input_type -> PolicyReportRequest
+	19, // 16: service.PolicyDiff:input_type -> PolicyDiffRequest
+	20, // 17: service.PolicyRSOP:input_type -> PolicyRSOPRequest
+	21, // 18: service.CapturePolicy:input_type -> CapturePolicyRequest
+	23, // 19: service.ApplySnapshot:input_type -> ApplySnapshotRequest
+	24, // 20: service.DumpPoliciesDefinitions:input_type -> DumpPolicyDefinitionsRequest
+	26, // 21: service.GetDoc:input_type -> GetDocRequest
+	0,  // 22: service.ListDoc:input_type -> Empty
+	1,  // 23: service.ListUsers:input_type -> ListUsersRequest
+	0,  // 24: service.GPOListScript:input_type -> Empty
+	0,  // 25: service.CertAutoEnrollScript:input_type -> Empty
+	8,  // 26: service.Cat:output_type -> StringResponse
+	8,  // 27: service.Version:output_type -> StringResponse
+	3,  // 28: service.Status:output_type -> StatusResponse
+	0,  // 29: service.Stop:output_type -> Empty
+	8,  // 30: service.UpdatePolicy:output_type -> StringResponse
+	11, // 31: service.DumpPolicies:output_type -> DumpPoliciesResponse
+	8,  // 32: service.PolicyHistory:output_type -> StringResponse
+	0,  // 33: service.PolicyRollback:output_type -> Empty
+	17, // 34: service.VerifyPolicy:output_type -> VerifyPolicyResponse
+	8,  // 35: service.PolicyReport:output_type -> StringResponse
+	8,  // 36: service.PolicyDiff:output_type -> StringResponse
+	8,  // 37: service.PolicyRSOP:output_type -> StringResponse
+	22, // 38: service.CapturePolicy:output_type -> CapturePolicyResponse
+	0,  // 39: service.ApplySnapshot:output_type -> Empty
+	25, // 40: service.DumpPoliciesDefinitions:output_type -> DumpPolicyDefinitionsResponse
+	8,  // 41: service.GetDoc:output_type -> StringResponse
+	27, // 42: service.ListDoc:output_type -> ListDocReponse
+	2,  // 43: service.ListUsers:output_type -> ListUsersResponse
+	8,  // 44: service.GPOListScript:output_type -> StringResponse
+	8,  // 45: service.CertAutoEnrollScript:output_type -> StringResponse
+	26, // [26:46] is the sub-list for method output_type
+	6,  // [6:26] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
 }
 
 func init() { file_adsys_proto_init() }
@@ -726,7 +2092,7 @@ func file_adsys_proto_init() {
 			}
 		}
 		file_adsys_proto_msgTypes[2].Exporter = func(v any, i int) any {
-			switch v := v.(*StopRequest); i {
+			switch v := v.(*ListUsersResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -738,7 +2104,7 @@ func file_adsys_proto_init() {
 			}
 		}
 		file_adsys_proto_msgTypes[3].Exporter = func(v any, i int) any {
-			switch v := v.(*StringResponse); i {
+			switch v := v.(*StatusResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -750,7 +2116,7 @@ func file_adsys_proto_init() {
 			}
 		}
 		file_adsys_proto_msgTypes[4].Exporter = func(v any, i int) any {
-			switch v := v.(*UpdatePolicyRequest); i {
+			switch v := v.(*ObjectStatus); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -762,7 +2128,7 @@ func file_adsys_proto_init() {
 			}
 		}
 		file_adsys_proto_msgTypes[5].Exporter = func(v any, i int) any {
-			switch v := v.(*DumpPoliciesRequest); i {
+			switch v := v.(*ManagerFailure); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -774,7 +2140,7 @@ func file_adsys_proto_init() {
 			}
 		}
 		file_adsys_proto_msgTypes[6].Exporter = func(v any, i int) any {
-			switch v := v.(*DumpPolicyDefinitionsRequest); i {
+			switch v := v.(*DaemonStatus); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -786,7 +2152,7 @@ func file_adsys_proto_init() {
 			}
 		}
 		file_adsys_proto_msgTypes[7].Exporter = func(v any, i int) any {
-			switch v := v.(*DumpPolicyDefinitionsResponse); i {
+			switch v := v.(*StopRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -798,7 +2164,7 @@ func file_adsys_proto_init() {
 			}
 		}
 		file_adsys_proto_msgTypes[8].Exporter = func(v any, i int) any {
-			switch v := v.(*GetDocRequest); i {
+			switch v := v.(*StringResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -810,6 +2176,222 @@ func file_adsys_proto_init() {
 			}
 		}
 		file_adsys_proto_msgTypes[9].Exporter = func(v any, i int) any {
+			switch v := v.(*UpdatePolicyRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_adsys_proto_msgTypes[10].Exporter = func(v any, i int) any {
+			switch v := v.(*DumpPoliciesRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_adsys_proto_msgTypes[11].Exporter = func(v any, i int) any {
+			switch v := v.(*DumpPoliciesResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_adsys_proto_msgTypes[12].Exporter = func(v any, i int) any {
+			switch v := v.(*GPO); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_adsys_proto_msgTypes[13].Exporter = func(v any, i int) any {
+			switch v := v.(*Rule); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_adsys_proto_msgTypes[14].Exporter = func(v any, i int) any {
+			switch v := v.(*PolicyHistoryRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_adsys_proto_msgTypes[15].Exporter = func(v any, i int) any {
+			switch v := v.(*PolicyRollbackRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_adsys_proto_msgTypes[16].Exporter = func(v any, i int) any {
+			switch v := v.(*VerifyPolicyRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_adsys_proto_msgTypes[17].Exporter = func(v any, i int) any {
+			switch v := v.(*VerifyPolicyResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_adsys_proto_msgTypes[18].Exporter = func(v any, i int) any {
+			switch v := v.(*PolicyReportRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_adsys_proto_msgTypes[19].Exporter = func(v any, i int) any {
+			switch v := v.(*PolicyDiffRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_adsys_proto_msgTypes[20].Exporter = func(v any, i int) any {
+			switch v := v.(*PolicyRSOPRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_adsys_proto_msgTypes[21].Exporter = func(v any, i int) any {
+			switch v := v.(*CapturePolicyRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_adsys_proto_msgTypes[22].Exporter = func(v any, i int) any {
+			switch v := v.(*CapturePolicyResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_adsys_proto_msgTypes[23].Exporter = func(v any, i int) any {
+			switch v := v.(*ApplySnapshotRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_adsys_proto_msgTypes[24].Exporter = func(v any, i int) any {
+			switch v := v.(*DumpPolicyDefinitionsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_adsys_proto_msgTypes[25].Exporter = func(v any, i int) any {
+			switch v := v.(*DumpPolicyDefinitionsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_adsys_proto_msgTypes[26].Exporter = func(v any, i int) any {
+			switch v := v.(*GetDocRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_adsys_proto_msgTypes[27].Exporter = func(v any, i int) any {
 			switch v := v.(*ListDocReponse); i {
 			case 0:
 				return &v.state
@@ -828,7 +2410,7 @@ func file_adsys_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_adsys_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   10,
+			NumMessages:   28,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -25,6 +25,14 @@ const (
 	Service_Stop_FullMethodName                    = "/service/Stop"
 	Service_UpdatePolicy_FullMethodName            = "/service/UpdatePolicy"
 	Service_DumpPolicies_FullMethodName            = "/service/DumpPolicies"
+	Service_PolicyHistory_FullMethodName           = "/service/PolicyHistory"
+	Service_PolicyRollback_FullMethodName          = "/service/PolicyRollback"
+	Service_VerifyPolicy_FullMethodName            = "/service/VerifyPolicy"
+	Service_PolicyReport_FullMethodName            = "/service/PolicyReport"
+	Service_PolicyDiff_FullMethodName              = "/service/PolicyDiff"
+	Service_PolicyRSOP_FullMethodName              = "/service/PolicyRSOP"
+	Service_CapturePolicy_FullMethodName           = "/service/CapturePolicy"
+	Service_ApplySnapshot_FullMethodName           = "/service/ApplySnapshot"
 	Service_DumpPoliciesDefinitions_FullMethodName = "/service/DumpPoliciesDefinitions"
 	Service_GetDoc_FullMethodName                  = "/service/GetDoc"
 	Service_ListDoc_FullMethodName                 = "/service/ListDoc"
@@ -43,6 +51,14 @@ type ServiceClient interface {
 	Stop(ctx context.Context, in *StopRequest, opts ...grpc.CallOption) (Service_StopClient, error)
 	UpdatePolicy(ctx context.Context, in *UpdatePolicyRequest, opts ...grpc.CallOption) (Service_UpdatePolicyClient, error)
 	DumpPolicies(ctx context.Context, in *DumpPoliciesRequest, opts ...grpc.CallOption) (Service_DumpPoliciesClient, error)
+	PolicyHistory(ctx context.Context, in *PolicyHistoryRequest, opts ...grpc.CallOption) (Service_PolicyHistoryClient, error)
+	PolicyRollback(ctx context.Context, in *PolicyRollbackRequest, opts ...grpc.CallOption) (Service_PolicyRollbackClient, error)
+	VerifyPolicy(ctx context.Context, in *VerifyPolicyRequest, opts ...grpc.CallOption) (Service_VerifyPolicyClient, error)
+	PolicyReport(ctx context.Context, in *PolicyReportRequest, opts ...grpc.CallOption) (Service_PolicyReportClient, error)
+	PolicyDiff(ctx context.Context, in *PolicyDiffRequest, opts ...grpc.CallOption) (Service_PolicyDiffClient, error)
+	PolicyRSOP(ctx context.Context, in *PolicyRSOPRequest, opts ...grpc.CallOption) (Service_PolicyRSOPClient, error)
+	CapturePolicy(ctx context.Context, in *CapturePolicyRequest, opts ...grpc.CallOption) (Service_CapturePolicyClient, error)
+	ApplySnapshot(ctx context.Context, in *ApplySnapshotRequest, opts ...grpc.CallOption) (Service_ApplySnapshotClient, error)
 	DumpPoliciesDefinitions(ctx context.Context, in *DumpPolicyDefinitionsRequest, opts ...grpc.CallOption) (Service_DumpPoliciesDefinitionsClient, error)
 	GetDoc(ctx context.Context, in *GetDocRequest, opts ...grpc.CallOption) (Service_GetDocClient, error)
 	ListDoc(ctx context.Context, in *Empty, opts ...grpc.CallOption) (Service_ListDocClient, error)
@@ -142,7 +158,7 @@ func (c *serviceClient) Status(ctx context.Context, in *Empty, opts ...grpc.Call
 }
 
 type Service_StatusClient interface {
-	Recv() (*StringResponse, error)
+	Recv() (*StatusResponse, error)
 	grpc.ClientStream
 }
 
@@ -150,8 +166,8 @@ type serviceStatusClient struct {
 	grpc.ClientStream
 }
 
-func (x *serviceStatusClient) Recv() (*StringResponse, error) {
-	m := new(StringResponse)
+func (x *serviceStatusClient) Recv() (*StatusResponse, error) {
+	m := new(StatusResponse)
 	if err := x.ClientStream.RecvMsg(m); err != nil {
 		return nil, err
 	}
@@ -208,7 +224,7 @@ func (c *serviceClient) UpdatePolicy(ctx context.Context, in *UpdatePolicyReques
 }
 
 type Service_UpdatePolicyClient interface {
-	Recv() (*Empty, error)
+	Recv() (*StringResponse, error)
 	grpc.ClientStream
 }
 
@@ -216,8 +232,8 @@ type serviceUpdatePolicyClient struct {
 	grpc.ClientStream
 }
 
-func (x *serviceUpdatePolicyClient) Recv() (*Empty, error) {
-	m := new(Empty)
+func (x *serviceUpdatePolicyClient) Recv() (*StringResponse, error) {
+	m := new(StringResponse)
 	if err := x.ClientStream.RecvMsg(m); err != nil {
 		return nil, err
 	}
@@ -241,7 +257,7 @@ func (c *serviceClient) DumpPolicies(ctx context.Context, in *DumpPoliciesReques
 }
 
 type Service_DumpPoliciesClient interface {
-	Recv() (*StringResponse, error)
+	Recv() (*DumpPoliciesResponse, error)
 	grpc.ClientStream
 }
 
@@ -249,7 +265,205 @@ type serviceDumpPoliciesClient struct {
 	grpc.ClientStream
 }
 
-func (x *serviceDumpPoliciesClient) Recv() (*StringResponse, error) {
+func (x *serviceDumpPoliciesClient) Recv() (*DumpPoliciesResponse, error) {
+	m := new(DumpPoliciesResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *serviceClient) PolicyHistory(ctx context.Context, in *PolicyHistoryRequest, opts ...grpc.CallOption) (Service_PolicyHistoryClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Service_ServiceDesc.Streams[6], Service_PolicyHistory_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &servicePolicyHistoryClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Service_PolicyHistoryClient interface {
+	Recv() (*StringResponse, error)
+	grpc.ClientStream
+}
+
+type servicePolicyHistoryClient struct {
+	grpc.ClientStream
+}
+
+func (x *servicePolicyHistoryClient) Recv() (*StringResponse, error) {
+	m := new(StringResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *serviceClient) PolicyRollback(ctx context.Context, in *PolicyRollbackRequest, opts ...grpc.CallOption) (Service_PolicyRollbackClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Service_ServiceDesc.Streams[7], Service_PolicyRollback_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &servicePolicyRollbackClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Service_PolicyRollbackClient interface {
+	Recv() (*Empty, error)
+	grpc.ClientStream
+}
+
+type servicePolicyRollbackClient struct {
+	grpc.ClientStream
+}
+
+func (x *servicePolicyRollbackClient) Recv() (*Empty, error) {
+	m := new(Empty)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *serviceClient) VerifyPolicy(ctx context.Context, in *VerifyPolicyRequest, opts ...grpc.CallOption) (Service_VerifyPolicyClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Service_ServiceDesc.Streams[8], Service_VerifyPolicy_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &serviceVerifyPolicyClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Service_VerifyPolicyClient interface {
+	Recv() (*VerifyPolicyResponse, error)
+	grpc.ClientStream
+}
+
+type serviceVerifyPolicyClient struct {
+	grpc.ClientStream
+}
+
+func (x *serviceVerifyPolicyClient) Recv() (*VerifyPolicyResponse, error) {
+	m := new(VerifyPolicyResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *serviceClient) PolicyReport(ctx context.Context, in *PolicyReportRequest, opts ...grpc.CallOption) (Service_PolicyReportClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Service_ServiceDesc.Streams[9], Service_PolicyReport_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &servicePolicyReportClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Service_PolicyReportClient interface {
+	Recv() (*StringResponse, error)
+	grpc.ClientStream
+}
+
+type servicePolicyReportClient struct {
+	grpc.ClientStream
+}
+
+func (x *servicePolicyReportClient) Recv() (*StringResponse, error) {
+	m := new(StringResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *serviceClient) PolicyDiff(ctx context.Context, in *PolicyDiffRequest, opts ...grpc.CallOption) (Service_PolicyDiffClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Service_ServiceDesc.Streams[10], Service_PolicyDiff_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &servicePolicyDiffClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Service_PolicyDiffClient interface {
+	Recv() (*StringResponse, error)
+	grpc.ClientStream
+}
+
+type servicePolicyDiffClient struct {
+	grpc.ClientStream
+}
+
+func (x *servicePolicyDiffClient) Recv() (*StringResponse, error) {
+	m := new(StringResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *serviceClient) PolicyRSOP(ctx context.Context, in *PolicyRSOPRequest, opts ...grpc.CallOption) (Service_PolicyRSOPClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Service_ServiceDesc.Streams[11], Service_PolicyRSOP_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &servicePolicyRSOPClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Service_PolicyRSOPClient interface {
+	Recv() (*StringResponse, error)
+	grpc.ClientStream
+}
+
+type servicePolicyRSOPClient struct {
+	grpc.ClientStream
+}
+
+func (x *servicePolicyRSOPClient) Recv() (*StringResponse, error) {
 	m := new(StringResponse)
 	if err := x.ClientStream.RecvMsg(m); err != nil {
 		return nil, err
@@ -257,9 +471,75 @@ func (x *serviceDumpPoliciesClient) Recv() (*StringResponse, error) {
 	return m, nil
 }
 
+func (c *serviceClient) CapturePolicy(ctx context.Context, in *CapturePolicyRequest, opts ...grpc.CallOption) (Service_CapturePolicyClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Service_ServiceDesc.Streams[12], Service_CapturePolicy_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &serviceCapturePolicyClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Service_CapturePolicyClient interface {
+	Recv() (*CapturePolicyResponse, error)
+	grpc.ClientStream
+}
+
+type serviceCapturePolicyClient struct {
+	grpc.ClientStream
+}
+
+func (x *serviceCapturePolicyClient) Recv() (*CapturePolicyResponse, error) {
+	m := new(CapturePolicyResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *serviceClient) ApplySnapshot(ctx context.Context, in *ApplySnapshotRequest, opts ...grpc.CallOption) (Service_ApplySnapshotClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Service_ServiceDesc.Streams[13], Service_ApplySnapshot_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &serviceApplySnapshotClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Service_ApplySnapshotClient interface {
+	Recv() (*Empty, error)
+	grpc.ClientStream
+}
+
+type serviceApplySnapshotClient struct {
+	grpc.ClientStream
+}
+
+func (x *serviceApplySnapshotClient) Recv() (*Empty, error) {
+	m := new(Empty)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *serviceClient) DumpPoliciesDefinitions(ctx context.Context, in *DumpPolicyDefinitionsRequest, opts ...grpc.CallOption) (Service_DumpPoliciesDefinitionsClient, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	stream, err := c.cc.NewStream(ctx, &Service_ServiceDesc.Streams[6], Service_DumpPoliciesDefinitions_FullMethodName, cOpts...)
+	stream, err := c.cc.NewStream(ctx, &Service_ServiceDesc.Streams[14], Service_DumpPoliciesDefinitions_FullMethodName, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -292,7 +572,7 @@ func (x *serviceDumpPoliciesDefinitionsClient) Recv() (*DumpPolicyDefinitionsRes
 
 func (c *serviceClient) GetDoc(ctx context.Context, in *GetDocRequest, opts ...grpc.CallOption) (Service_GetDocClient, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	stream, err := c.cc.NewStream(ctx, &Service_ServiceDesc.Streams[7], Service_GetDoc_FullMethodName, cOpts...)
+	stream, err := c.cc.NewStream(ctx, &Service_ServiceDesc.Streams[15], Service_GetDoc_FullMethodName, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -325,7 +605,7 @@ func (x *serviceGetDocClient) Recv() (*StringResponse, error) {
 
 func (c *serviceClient) ListDoc(ctx context.Context, in *Empty, opts ...grpc.CallOption) (Service_ListDocClient, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	stream, err := c.cc.NewStream(ctx, &Service_ServiceDesc.Streams[8], Service_ListDoc_FullMethodName, cOpts...)
+	stream, err := c.cc.NewStream(ctx, &Service_ServiceDesc.Streams[16], Service_ListDoc_FullMethodName, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -358,7 +638,7 @@ func (x *serviceListDocClient) Recv() (*ListDocReponse, error) {
 
 func (c *serviceClient) ListUsers(ctx context.Context, in *ListUsersRequest, opts ...grpc.CallOption) (Service_ListUsersClient, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	stream, err := c.cc.NewStream(ctx, &Service_ServiceDesc.Streams[9], Service_ListUsers_FullMethodName, cOpts...)
+	stream, err := c.cc.NewStream(ctx, &Service_ServiceDesc.Streams[17], Service_ListUsers_FullMethodName, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -373,7 +653,7 @@ func (c *serviceClient) ListUsers(ctx context.Context, in *ListUsersRequest, opt
 }
 
 type Service_ListUsersClient interface {
-	Recv() (*StringResponse, error)
+	Recv() (*ListUsersResponse, error)
 	grpc.ClientStream
 }
 
@@ -381,8 +661,8 @@ type serviceListUsersClient struct {
 	grpc.ClientStream
 }
 
-func (x *serviceListUsersClient) Recv() (*StringResponse, error) {
-	m := new(StringResponse)
+func (x *serviceListUsersClient) Recv() (*ListUsersResponse, error) {
+	m := new(ListUsersResponse)
 	if err := x.ClientStream.RecvMsg(m); err != nil {
 		return nil, err
 	}
@@ -391,7 +671,7 @@ func (x *serviceListUsersClient) Recv() (*StringResponse, error) {
 
 func (c *serviceClient) GPOListScript(ctx context.Context, in *Empty, opts ...grpc.CallOption) (Service_GPOListScriptClient, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	stream, err := c.cc.NewStream(ctx, &Service_ServiceDesc.Streams[10], Service_GPOListScript_FullMethodName, cOpts...)
+	stream, err := c.cc.NewStream(ctx, &Service_ServiceDesc.Streams[18], Service_GPOListScript_FullMethodName, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -424,7 +704,7 @@ func (x *serviceGPOListScriptClient) Recv() (*StringResponse, error) {
 
 func (c *serviceClient) CertAutoEnrollScript(ctx context.Context, in *Empty, opts ...grpc.CallOption) (Service_CertAutoEnrollScriptClient, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	stream, err := c.cc.NewStream(ctx, &Service_ServiceDesc.Streams[11], Service_CertAutoEnrollScript_FullMethodName, cOpts...)
+	stream, err := c.cc.NewStream(ctx, &Service_ServiceDesc.Streams[19], Service_CertAutoEnrollScript_FullMethodName, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -465,6 +745,14 @@ type ServiceServer interface {
 	Stop(*StopRequest, Service_StopServer) error
 	UpdatePolicy(*UpdatePolicyRequest, Service_UpdatePolicyServer) error
 	DumpPolicies(*DumpPoliciesRequest, Service_DumpPoliciesServer) error
+	PolicyHistory(*PolicyHistoryRequest, Service_PolicyHistoryServer) error
+	PolicyRollback(*PolicyRollbackRequest, Service_PolicyRollbackServer) error
+	VerifyPolicy(*VerifyPolicyRequest, Service_VerifyPolicyServer) error
+	PolicyReport(*PolicyReportRequest, Service_PolicyReportServer) error
+	PolicyDiff(*PolicyDiffRequest, Service_PolicyDiffServer) error
+	PolicyRSOP(*PolicyRSOPRequest, Service_PolicyRSOPServer) error
+	CapturePolicy(*CapturePolicyRequest, Service_CapturePolicyServer) error
+	ApplySnapshot(*ApplySnapshotRequest, Service_ApplySnapshotServer) error
 	DumpPoliciesDefinitions(*DumpPolicyDefinitionsRequest, Service_DumpPoliciesDefinitionsServer) error
 	GetDoc(*GetDocRequest, Service_GetDocServer) error
 	ListDoc(*Empty, Service_ListDocServer) error
@@ -496,6 +784,30 @@ func (UnimplementedServiceServer) UpdatePolicy(*UpdatePolicyRequest, Service_Upd
 func (UnimplementedServiceServer) DumpPolicies(*DumpPoliciesRequest, Service_DumpPoliciesServer) error {
 	return status.Errorf(codes.Unimplemented, "method DumpPolicies not implemented")
 }
+func (UnimplementedServiceServer) PolicyHistory(*PolicyHistoryRequest, Service_PolicyHistoryServer) error {
+	return status.Errorf(codes.Unimplemented, "method PolicyHistory not implemented")
+}
+func (UnimplementedServiceServer) PolicyRollback(*PolicyRollbackRequest, Service_PolicyRollbackServer) error {
+	return status.Errorf(codes.Unimplemented, "method PolicyRollback not implemented")
+}
+func (UnimplementedServiceServer) VerifyPolicy(*VerifyPolicyRequest, Service_VerifyPolicyServer) error {
+	return status.Errorf(codes.Unimplemented, "method VerifyPolicy not implemented")
+}
+func (UnimplementedServiceServer) PolicyReport(*PolicyReportRequest, Service_PolicyReportServer) error {
+	return status.Errorf(codes.Unimplemented, "method PolicyReport not implemented")
+}
+func (UnimplementedServiceServer) PolicyDiff(*PolicyDiffRequest, Service_PolicyDiffServer) error {
+	return status.Errorf(codes.Unimplemented, "method PolicyDiff not implemented")
+}
+func (UnimplementedServiceServer) PolicyRSOP(*PolicyRSOPRequest, Service_PolicyRSOPServer) error {
+	return status.Errorf(codes.Unimplemented, "method PolicyRSOP not implemented")
+}
+func (UnimplementedServiceServer) CapturePolicy(*CapturePolicyRequest, Service_CapturePolicyServer) error {
+	return status.Errorf(codes.Unimplemented, "method CapturePolicy not implemented")
+}
+func (UnimplementedServiceServer) ApplySnapshot(*ApplySnapshotRequest, Service_ApplySnapshotServer) error {
+	return status.Errorf(codes.Unimplemented, "method ApplySnapshot not implemented")
+}
 func (UnimplementedServiceServer) DumpPoliciesDefinitions(*DumpPolicyDefinitionsRequest, Service_DumpPoliciesDefinitionsServer) error {
 	return status.Errorf(codes.Unimplemented, "method DumpPoliciesDefinitions not implemented")
 }
@@ -578,7 +890,7 @@ func _Service_Status_Handler(srv interface{}, stream grpc.ServerStream) error {
 }
 
 type Service_StatusServer interface {
-	Send(*StringResponse) error
+	Send(*StatusResponse) error
 	grpc.ServerStream
 }
 
@@ -586,7 +898,7 @@ type serviceStatusServer struct {
 	grpc.ServerStream
 }
 
-func (x *serviceStatusServer) Send(m *StringResponse) error {
+func (x *serviceStatusServer) Send(m *StatusResponse) error {
 	return x.ServerStream.SendMsg(m)
 }
 
@@ -620,7 +932,7 @@ func _Service_UpdatePolicy_Handler(srv interface{}, stream grpc.ServerStream) er
 }
 
 type Service_UpdatePolicyServer interface {
-	Send(*Empty) error
+	Send(*StringResponse) error
 	grpc.ServerStream
 }
 
@@ -628,7 +940,7 @@ type serviceUpdatePolicyServer struct {
 	grpc.ServerStream
 }
 
-func (x *serviceUpdatePolicyServer) Send(m *Empty) error {
+func (x *serviceUpdatePolicyServer) Send(m *StringResponse) error {
 	return x.ServerStream.SendMsg(m)
 }
 
@@ -641,7 +953,7 @@ func _Service_DumpPolicies_Handler(srv interface{}, stream grpc.ServerStream) er
 }
 
 type Service_DumpPoliciesServer interface {
-	Send(*StringResponse) error
+	Send(*DumpPoliciesResponse) error
 	grpc.ServerStream
 }
 
@@ -649,7 +961,175 @@ type serviceDumpPoliciesServer struct {
 	grpc.ServerStream
 }
 
-func (x *serviceDumpPoliciesServer) Send(m *StringResponse) error {
+func (x *serviceDumpPoliciesServer) Send(m *DumpPoliciesResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Service_PolicyHistory_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PolicyHistoryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ServiceServer).PolicyHistory(m, &servicePolicyHistoryServer{ServerStream: stream})
+}
+
+type Service_PolicyHistoryServer interface {
+	Send(*StringResponse) error
+	grpc.ServerStream
+}
+
+type servicePolicyHistoryServer struct {
+	grpc.ServerStream
+}
+
+func (x *servicePolicyHistoryServer) Send(m *StringResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Service_PolicyRollback_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PolicyRollbackRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ServiceServer).PolicyRollback(m, &servicePolicyRollbackServer{ServerStream: stream})
+}
+
+type Service_PolicyRollbackServer interface {
+	Send(*Empty) error
+	grpc.ServerStream
+}
+
+type servicePolicyRollbackServer struct {
+	grpc.ServerStream
+}
+
+func (x *servicePolicyRollbackServer) Send(m *Empty) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Service_VerifyPolicy_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(VerifyPolicyRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ServiceServer).VerifyPolicy(m, &serviceVerifyPolicyServer{ServerStream: stream})
+}
+
+type Service_VerifyPolicyServer interface {
+	Send(*VerifyPolicyResponse) error
+	grpc.ServerStream
+}
+
+type serviceVerifyPolicyServer struct {
+	grpc.ServerStream
+}
+
+func (x *serviceVerifyPolicyServer) Send(m *VerifyPolicyResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Service_PolicyReport_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PolicyReportRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ServiceServer).PolicyReport(m, &servicePolicyReportServer{ServerStream: stream})
+}
+
+type Service_PolicyReportServer interface {
+	Send(*StringResponse) error
+	grpc.ServerStream
+}
+
+type servicePolicyReportServer struct {
+	grpc.ServerStream
+}
+
+func (x *servicePolicyReportServer) Send(m *StringResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Service_PolicyDiff_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PolicyDiffRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ServiceServer).PolicyDiff(m, &servicePolicyDiffServer{ServerStream: stream})
+}
+
+type Service_PolicyDiffServer interface {
+	Send(*StringResponse) error
+	grpc.ServerStream
+}
+
+type servicePolicyDiffServer struct {
+	grpc.ServerStream
+}
+
+func (x *servicePolicyDiffServer) Send(m *StringResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Service_PolicyRSOP_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PolicyRSOPRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ServiceServer).PolicyRSOP(m, &servicePolicyRSOPServer{ServerStream: stream})
+}
+
+type Service_PolicyRSOPServer interface {
+	Send(*StringResponse) error
+	grpc.ServerStream
+}
+
+type servicePolicyRSOPServer struct {
+	grpc.ServerStream
+}
+
+func (x *servicePolicyRSOPServer) Send(m *StringResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Service_CapturePolicy_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(CapturePolicyRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ServiceServer).CapturePolicy(m, &serviceCapturePolicyServer{ServerStream: stream})
+}
+
+type Service_CapturePolicyServer interface {
+	Send(*CapturePolicyResponse) error
+	grpc.ServerStream
+}
+
+type serviceCapturePolicyServer struct {
+	grpc.ServerStream
+}
+
+func (x *serviceCapturePolicyServer) Send(m *CapturePolicyResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Service_ApplySnapshot_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ApplySnapshotRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ServiceServer).ApplySnapshot(m, &serviceApplySnapshotServer{ServerStream: stream})
+}
+
+type Service_ApplySnapshotServer interface {
+	Send(*Empty) error
+	grpc.ServerStream
+}
+
+type serviceApplySnapshotServer struct {
+	grpc.ServerStream
+}
+
+func (x *serviceApplySnapshotServer) Send(m *Empty) error {
 	return x.ServerStream.SendMsg(m)
 }
 
@@ -725,7 +1205,7 @@ func _Service_ListUsers_Handler(srv interface{}, stream grpc.ServerStream) error
 }
 
 type Service_ListUsersServer interface {
-	Send(*StringResponse) error
+	Send(*ListUsersResponse) error
 	grpc.ServerStream
 }
 
@@ -733,7 +1213,7 @@ type serviceListUsersServer struct {
 	grpc.ServerStream
 }
 
-func (x *serviceListUsersServer) Send(m *StringResponse) error {
+func (x *serviceListUsersServer) Send(m *ListUsersResponse) error {
 	return x.ServerStream.SendMsg(m)
 }
 
@@ -817,6 +1297,46 @@ var Service_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _Service_DumpPolicies_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "PolicyHistory",
+			Handler:       _Service_PolicyHistory_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "PolicyRollback",
+			Handler:       _Service_PolicyRollback_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "VerifyPolicy",
+			Handler:       _Service_VerifyPolicy_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "PolicyReport",
+			Handler:       _Service_PolicyReport_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "PolicyDiff",
+			Handler:       _Service_PolicyDiff_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "PolicyRSOP",
+			Handler:       _Service_PolicyRSOP_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "CapturePolicy",
+			Handler:       _Service_CapturePolicy_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ApplySnapshot",
+			Handler:       _Service_ApplySnapshot_Handler,
+			ServerStreams: true,
+		},
 		{
 			StreamName:    "DumpPoliciesDefinitions",
 			Handler:       _Service_DumpPoliciesDefinitions_Handler,
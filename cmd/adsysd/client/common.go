@@ -1,21 +1,34 @@
 package client
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"slices"
 
-	"github.com/ubuntu/adsys"
+	"github.com/leonelquinteros/gotext"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
 )
 
-type recver interface {
-	Recv() (*adsys.StringResponse, error)
+// outputFormats are the supported formats for commands with machine-readable output.
+// The first one is the default, human-readable, format.
+var outputFormats = []string{"text", "json", "yaml"}
+
+type msgResponse interface {
+	GetMsg() string
+}
+
+type recver[T msgResponse] interface {
+	Recv() (T, error)
 }
 
 // singleMsg returns a single string that is accepted from stream.
-// The stream should return StringReponse.
+// The stream should return a response with a Msg field.
 // In case there are multiple responses streamed, we return an error.
-func singleMsg(stream recver) (msg string, err error) {
+func singleMsg[T msgResponse](stream recver[T]) (msg string, err error) {
 	for {
 		r, err := stream.Recv()
 		if err != nil {
@@ -32,3 +45,64 @@ func singleMsg(stream recver) (msg string, err error) {
 
 	return msg, nil
 }
+
+// singleResponse returns the single response that is accepted from stream.
+// In case there are multiple responses streamed, we return an error.
+func singleResponse[T msgResponse](stream recver[T]) (r T, err error) {
+	var received bool
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return r, err
+		}
+		if received {
+			return r, fmt.Errorf("multiple answers from service streamed while we expected only one.\nWe already got:\n%s\n\nAnd now we are getting:\n%s", r.GetMsg(), resp.GetMsg())
+		}
+		r, received = resp, true
+	}
+
+	return r, nil
+}
+
+// checkOutputFormat returns an error if format is not one of the supported output formats.
+func checkOutputFormat(format string) error {
+	if !slices.Contains(outputFormats, format) {
+		return errors.New(gotext.Get("unsupported output format %q, expected one of %v", format, outputFormats))
+	}
+	return nil
+}
+
+// printStructured writes to w the response r in the machine-readable format requested, without its
+// preformatted Msg field.
+func printStructured(w io.Writer, r proto.Message, format string) error {
+	d, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(r)
+	if err != nil {
+		return err
+	}
+	// protojson output is purposely unstable: decode it to print it in a consistent way.
+	var v map[string]any
+	if err := json.Unmarshal(d, &v); err != nil {
+		return err
+	}
+	delete(v, "msg")
+
+	var out []byte
+	switch format {
+	case "json":
+		out, err = json.MarshalIndent(v, "", "  ")
+		out = append(out, '\n')
+	case "yaml":
+		out, err = yaml.Marshal(v)
+	default:
+		return errors.New(gotext.Get("unsupported machine-readable output format %q", format))
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(out)
+	return err
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/ubuntu/adsys"
+	"github.com/ubuntu/adsys/internal/testutils"
+)
+
+func TestPrintStructured(t *testing.T) {
+	t.Parallel()
+
+	r := &adsys.DumpPoliciesResponse{
+		Msg: "Formatted policies, not printed",
+		Gpos: []*adsys.GPO{
+			{
+				Id:         "{GPOId1}",
+				Name:       "GPOName1",
+				IsComputer: true,
+				LastUpdate: "2024-05-25T14:55:00Z",
+				Rules: []*adsys.Rule{
+					{Type: "dconf", Key: "path/to/key1", Value: "ValueOfKey1\nOn\nMultilines"},
+					{Type: "scripts", Key: "path/to/key2", Disabled: true},
+				},
+			},
+			{
+				Id:         "{GPOId2}",
+				Name:       "GPOName2",
+				LastUpdate: "2024-05-25T14:56:00Z",
+				Rules: []*adsys.Rule{
+					{Type: "dconf", Key: "path/to/key1", Value: "ValueOfKey1", Overridden: true},
+					{Type: "privilege", Key: "allow-local-admins", Value: "user1", Strategy: "append"},
+				},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		format string
+
+		wantErr bool
+	}{
+		"JSON output": {format: "json"},
+		"YAML output": {format: "yaml"},
+
+		// Error cases
+		"Error on text format":        {format: "text", wantErr: true},
+		"Error on unsupported format": {format: "xml", wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var out strings.Builder
+			err := printStructured(&out, r, tc.format)
+			if tc.wantErr {
+				require.Error(t, err, "printStructured should return an error but got none")
+				return
+			}
+			require.NoError(t, err, "printStructured should return no error but got one")
+
+			got := out.String()
+			want := testutils.LoadWithUpdateFromGolden(t, got)
+			require.Equal(t, want, got, "printStructured returned expected formatted output")
+		})
+	}
+}
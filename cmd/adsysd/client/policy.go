@@ -1,12 +1,16 @@
 package client
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/user"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -15,12 +19,15 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/ubuntu/adsys"
 	"github.com/ubuntu/adsys/internal/ad"
+	"github.com/ubuntu/adsys/internal/ad/registry"
 	"github.com/ubuntu/adsys/internal/adsysservice"
 	"github.com/ubuntu/adsys/internal/cmdhandler"
 	"github.com/ubuntu/adsys/internal/consts"
 	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
+	"github.com/ubuntu/adsys/internal/policies/entry"
 	"github.com/ubuntu/decorate"
 	"golang.org/x/sys/unix"
+	"gopkg.in/yaml.v3"
 )
 
 func (a *App) installPolicy() {
@@ -48,6 +55,7 @@ func (a *App) installPolicy() {
 	policyCmd.AddCommand(mainCmd)
 
 	var details, all, nocolor, isMachine *bool
+	var appliedFormat *string
 	appliedCmd := &cobra.Command{
 		Use:   "applied [USER_NAME]",
 		Short: gotext.Get("Print last applied GPOs for current or given user/machine"),
@@ -64,13 +72,14 @@ func (a *App) installPolicy() {
 			if len(args) > 0 {
 				target = args[0]
 			}
-			return a.dumpPolicies(target, *details, *all, *nocolor, *isMachine)
+			return a.dumpPolicies(target, *details, *all, *nocolor, *isMachine, *appliedFormat)
 		},
 	}
 	details = appliedCmd.Flags().BoolP("details", "", false, gotext.Get("show applied rules in addition to GPOs."))
 	all = appliedCmd.Flags().BoolP("all", "a", false, gotext.Get("show overridden rules in each GPOs."))
 	nocolor = appliedCmd.Flags().BoolP("no-color", "", false, gotext.Get("don't display colorized version."))
 	isMachine = appliedCmd.Flags().BoolP("machine", "m", false, gotext.Get("show applied rules to the machine."))
+	appliedFormat = appliedCmd.Flags().StringP("format", "", outputFormats[0], gotext.Get("output format, one of %v.", outputFormats))
 	policyCmd.AddCommand(appliedCmd)
 	cmdhandler.RegisterAlias(appliedCmd, &a.rootCmd)
 
@@ -115,7 +124,7 @@ The command is a no-op if the ticket is not present on disk or the detect_cached
 	}
 	debugCmd.AddCommand(ticketPathCmd)
 
-	var updateMachine, updateAll *bool
+	var updateMachine, updateAll, updateDryRun *bool
 	updateCmd := &cobra.Command{
 		Use:   "update [USER_NAME KERBEROS_TICKET_PATH]",
 		Short: gotext.Get("Updates/Create a policy for current user or given user with its kerberos ticket"),
@@ -142,11 +151,12 @@ The command is a no-op if the ticket is not present on disk or the detect_cached
 			if len(args) > 0 {
 				user, krb5cc = args[0], args[1]
 			}
-			return a.update(*updateMachine, *updateAll, user, krb5cc)
+			return a.update(*updateMachine, *updateAll, *updateDryRun, user, krb5cc)
 		},
 	}
 	updateMachine = updateCmd.Flags().BoolP("machine", "m", false, gotext.Get("machine updates the policy of the computer."))
 	updateAll = updateCmd.Flags().BoolP("all", "a", false, gotext.Get("all updates the policy of the computer and all the logged in users. -m or USER_NAME/TICKET cannot be used with this option."))
+	updateDryRun = updateCmd.Flags().BoolP("dry-run", "", false, gotext.Get("only print the changes the policy update would make, without applying them."))
 	policyCmd.AddCommand(updateCmd)
 	cmdhandler.RegisterAlias(updateCmd, &a.rootCmd)
 
@@ -177,6 +187,248 @@ The command is a no-op if the ticket is not present on disk or the detect_cached
 	purgeCmd.MarkFlagsMutuallyExclusive("machine", "all")
 	policyCmd.AddCommand(purgeCmd)
 
+	var historyMachine *bool
+	historyCmd := &cobra.Command{
+		Use:   "history [USER_NAME]",
+		Short: gotext.Get("List previously applied policies for current or given user/machine"),
+		Args:  cmdhandler.ZeroOrNArgs(1),
+		ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			if *historyMachine || len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+
+			// Get all users with cached policies
+			return a.users(false), cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			var target string
+			if len(args) > 0 {
+				target = args[0]
+			}
+			return a.policyHistory(*historyMachine, target)
+		},
+	}
+	historyMachine = historyCmd.Flags().BoolP("machine", "m", false, gotext.Get("machine lists the policies history of the computer."))
+	policyCmd.AddCommand(historyCmd)
+
+	var rollbackMachine *bool
+	var rollbackTo *int
+	rollbackCmd := &cobra.Command{
+		Use:   "rollback [USER_NAME]",
+		Short: gotext.Get("Apply again previously applied policies for current or given user/machine"),
+		Args:  cmdhandler.ZeroOrNArgs(1),
+		ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			if *rollbackMachine || len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+
+			// Get all users with cached policies
+			return a.users(false), cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			var target string
+			if len(args) > 0 {
+				target = args[0]
+			}
+			return a.policyRollback(*rollbackMachine, target, *rollbackTo)
+		},
+	}
+	rollbackMachine = rollbackCmd.Flags().BoolP("machine", "m", false, gotext.Get("machine rolls back the policy of the computer."))
+	rollbackTo = rollbackCmd.Flags().IntP("to", "", 0, gotext.Get("identifier of the snapshot to apply again, as listed by the history command."))
+	policyCmd.AddCommand(rollbackCmd)
+
+	var reportMachine *bool
+	reportCmd := &cobra.Command{
+		Use:   "report [USER_NAME]",
+		Short: gotext.Get("Show the outcome of each policy manager during the last policies application for current or given user/machine"),
+		Args:  cmdhandler.ZeroOrNArgs(1),
+		ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			if *reportMachine || len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+
+			// Get all users with cached policies
+			return a.users(false), cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			var target string
+			if len(args) > 0 {
+				target = args[0]
+			}
+			return a.policyReport(*reportMachine, target)
+		},
+	}
+	reportMachine = reportCmd.Flags().BoolP("machine", "m", false, gotext.Get("machine shows the policies report of the computer."))
+	policyCmd.AddCommand(reportCmd)
+
+	var verifyMachine, verifyAll *bool
+	verifyCmd := &cobra.Command{
+		Use:   "verify [USER_NAME]",
+		Short: gotext.Get("Verify that the system still matches the applied policies for current or given user/machine"),
+		Long: gotext.Get(`Verify that the system still matches the applied policies for current or given user/machine.
+
+Each policy manager compares the current state of the system with the one the last applied policies would produce.
+Nothing is modified. The command exits with an error if the system drifted from any of the applied policies.`),
+		Args: cmdhandler.ZeroOrNArgs(1),
+		ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			// All and machine options don’t take arguments
+			if *verifyAll || *verifyMachine || len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+
+			// Get all users with cached policies
+			return a.users(false), cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			var target string
+			if len(args) > 0 {
+				target = args[0]
+			}
+			return a.policyVerify(*verifyMachine, *verifyAll, target)
+		},
+	}
+	verifyMachine = verifyCmd.Flags().BoolP("machine", "m", false, gotext.Get("machine verifies the policy of the computer."))
+	verifyAll = verifyCmd.Flags().BoolP("all", "a", false, gotext.Get("all verifies the policy of the computer and all the users with applied policies. -m or USER_NAME cannot be used with this option."))
+	verifyCmd.MarkFlagsMutuallyExclusive("machine", "all")
+	policyCmd.AddCommand(verifyCmd)
+
+	var diffMachine *bool
+	var diffSnapshot *int
+	diffCmd := &cobra.Command{
+		Use:   "diff [USER_NAME]",
+		Short: gotext.Get("Show the rules which changed between applied policies and Active Directory or a previous snapshot"),
+		Long: gotext.Get(`Show the rules which changed for current or given user/machine, between the applied policies and the ones
+currently defined in Active Directory.
+
+With --snapshot, compare a snapshot listed by the history command with the applied policies instead.
+Each added, removed or changed key is listed per rule type, with the GPOs now setting it.`),
+		Args: cmdhandler.ZeroOrNArgs(1),
+		ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			if *diffMachine || len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+
+			// Get all users with cached policies
+			return a.users(false), cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			var target string
+			if len(args) > 0 {
+				target = args[0]
+			}
+			return a.policyDiff(*diffMachine, target, *diffSnapshot)
+		},
+	}
+	diffMachine = diffCmd.Flags().BoolP("machine", "m", false, gotext.Get("machine compares the policies of the computer."))
+	diffSnapshot = diffCmd.Flags().IntP("snapshot", "", 0, gotext.Get("identifier of the snapshot to compare with, as listed by the history command."))
+	policyCmd.AddCommand(diffCmd)
+
+	var rsopMachine *bool
+	rsopCmd := &cobra.Command{
+		Use:   "rsop [USER_NAME]",
+		Short: gotext.Get("Show the resultant set of policies for current or given user/machine"),
+		Long: gotext.Get(`Show the resultant set of policies for current or given user/machine.
+
+The applied GPOs are listed by order of precedence, with the container they are linked to and whether this link is
+enforced. Then, for each effective key, the GPO setting the resulting value is listed, along with every GPO whose value
+was overridden or appended. Values specific to the running release are flagged.`),
+		Args: cmdhandler.ZeroOrNArgs(1),
+		ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			if *rsopMachine || len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+
+			// Get all users with cached policies
+			return a.users(false), cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			var target string
+			if len(args) > 0 {
+				target = args[0]
+			}
+			return a.policyRSOP(*rsopMachine, target)
+		},
+	}
+	rsopMachine = rsopCmd.Flags().BoolP("machine", "m", false, gotext.Get("machine shows the resultant set of policies of the computer only."))
+	policyCmd.AddCommand(rsopCmd)
+
+	var captureMachine *bool
+	var captureOutput *string
+	captureCmd := &cobra.Command{
+		Use:   "capture [USER_NAME]",
+		Short: gotext.Get("Archive everything the last policies refresh used for current or given user/machine"),
+		Long: gotext.Get(`Archive everything the last policies refresh used for current or given user/machine: the ordered list of GPOs,
+their downloaded content and the assets.
+
+The archive can be applied on another machine, without Active Directory, with the apply-snapshot command.`),
+		Args: cmdhandler.ZeroOrNArgs(1),
+		ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			if *captureMachine || len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+
+			// Get all users with cached policies
+			return a.users(false), cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			var target string
+			if len(args) > 0 {
+				target = args[0]
+			}
+			return a.policyCapture(*captureMachine, target, *captureOutput)
+		},
+	}
+	captureMachine = captureCmd.Flags().BoolP("machine", "m", false, gotext.Get("machine captures the policies of the computer."))
+	captureOutput = captureCmd.Flags().StringP("output", "o", "", gotext.Get("path of the archive to create."))
+	decorate.LogOnError(captureCmd.MarkFlagRequired("output"))
+	policyCmd.AddCommand(captureCmd)
+
+	applySnapshotCmd := &cobra.Command{
+		Use:   "apply-snapshot ARCHIVE",
+		Short: gotext.Get("Apply the policies of an archive created with the capture command"),
+		Long: gotext.Get(`Apply the policies of an archive created with the capture command, without contacting Active Directory.
+
+Policies captured for a user are applied to the same user. Policies captured for a machine are applied to the current one.`),
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error { return a.policyApplySnapshot(args[0]) },
+	}
+	policyCmd.AddCommand(applySnapshotCmd)
+
+	polCmd := &cobra.Command{
+		Use:   "pol COMMAND",
+		Short: gotext.Get("Convert registry policy files"),
+		Long: gotext.Get(`Convert registry policy files, like the Registry.pol files of GPOs, to and from a human-readable format.
+
+Each rule is listed with its key, value, whether it is disabled, and the meta and strategy of the value if any.`),
+		Args: cmdhandler.SubcommandsRequiredWithSuggestions,
+		RunE: cmdhandler.NoCmd,
+	}
+	policyCmd.AddCommand(polCmd)
+	var polDecodeFormat *string
+	polDecodeCmd := &cobra.Command{
+		Use:   "decode POL_FILE",
+		Short: gotext.Get("Print the rules of a registry policy file"),
+		Args:  cobra.ExactArgs(1),
+		RunE:  func(_ *cobra.Command, args []string) error { return a.policyPolDecode(args[0], *polDecodeFormat) },
+	}
+	polDecodeFormat = polDecodeCmd.Flags().StringP("format", "", polFormats[0], gotext.Get("output format, one of %v.", polFormats))
+	polCmd.AddCommand(polDecodeCmd)
+	var polEncodeOutput *string
+	polEncodeCmd := &cobra.Command{
+		Use:   "encode RULES_FILE",
+		Short: gotext.Get("Create a registry policy file from rules in YAML or JSON format"),
+		Long: gotext.Get(`Create a registry policy file from rules in YAML or JSON format, as printed by the decode command.
+
+Rules with the same parent key should be listed together, in the order they are applied.
+The type of a rule is one of REG_SZ, REG_MULTI_SZ or REG_DWORD. Without any type, values are written as REG_SZ,
+or REG_MULTI_SZ for multi-line values.`),
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error { return a.policyPolEncode(args[0], *polEncodeOutput) },
+	}
+	polEncodeOutput = polEncodeCmd.Flags().StringP("output", "o", "", gotext.Get("path of the registry policy file to create."))
+	decorate.LogOnError(polEncodeCmd.MarkFlagRequired("output"))
+	polCmd.AddCommand(polEncodeCmd)
+
 	a.rootCmd.AddCommand(policyCmd)
 }
 
@@ -223,11 +475,14 @@ func (a App) getPolicyDefinitions(format, distroID string) (err error) {
 	return nil
 }
 
-func (a *App) dumpPolicies(target string, showDetails, showOverridden, nocolor, isMachine bool) error {
+func (a *App) dumpPolicies(target string, showDetails, showOverridden, nocolor, isMachine bool, format string) error {
 	// incompatible options
 	if showOverridden && !showDetails {
 		showDetails = true
 	}
+	if err := checkOutputFormat(format); err != nil {
+		return err
+	}
 
 	client, err := adsysservice.NewClient(a.config.Socket, a.getTimeout())
 	if err != nil {
@@ -262,15 +517,18 @@ func (a *App) dumpPolicies(target string, showDetails, showOverridden, nocolor,
 		return err
 	}
 
-	policies, err := singleMsg(stream)
+	r, err := singleResponse(stream)
 	if err != nil {
 		return err
 	}
+	if format != outputFormats[0] {
+		return printStructured(os.Stdout, r, format)
+	}
 
 	if nocolor {
 		color.NoColor = true
 	}
-	policies, err = colorizePolicies(policies)
+	policies, err := colorizePolicies(r.GetMsg())
 	if err != nil {
 		return err
 	}
@@ -459,7 +717,7 @@ func (s *stringsBuilderWithError) Println(l string) {
 	_, s.err = s.Builder.WriteString(l)
 }
 
-func (a *App) update(isComputer, updateAll bool, target, krb5cc string) error {
+func (a *App) update(isComputer, updateAll, dryRun bool, target, krb5cc string) error {
 	// incompatible options
 	if updateAll && (isComputer || target != "" || krb5cc != "") {
 		return errors.New(gotext.Get("machine or user arguments cannot be used with update all"))
@@ -491,33 +749,52 @@ func (a *App) update(isComputer, updateAll bool, target, krb5cc string) error {
 			return fmt.Errorf("failed to retrieve current user: %w", err)
 		}
 		target = u.Username
-		krb5cc = strings.TrimPrefix(os.Getenv("KRB5CCNAME"), "FILE:")
-		if krb5cc == "" && a.config.DetectCachedTicket {
-			krb5cc, err = ad.TicketPath()
-			// Don't return an error as we might still have a cached ticket
-			// under /run/adsys/krb5cc
-			if err != nil {
-				log.Warningf(a.ctx, "Failed to get ticket path: %v", err)
-			}
-		}
+		krb5cc = a.currentUserTicket()
 	}
 
 	stream, err := client.UpdatePolicy(a.ctx, &adsys.UpdatePolicyRequest{
 		IsComputer: isComputer,
 		All:        updateAll,
 		Target:     target,
-		Krb5Cc:     krb5cc})
+		Krb5Cc:     krb5cc,
+		DryRun:     dryRun,
+	})
 	if err != nil {
 		return err
 	}
 
-	if _, err := stream.Recv(); err != nil && !errors.Is(err, io.EOF) {
-		return err
+	// Print every planned change, one message per object.
+	for {
+		r, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			fmt.Print(r.GetMsg())
+		}
 	}
 
 	return nil
 }
 
+// currentUserTicket returns the path of the current user's Kerberos ticket, if any.
+func (a *App) currentUserTicket() string {
+	krb5cc := strings.TrimPrefix(os.Getenv("KRB5CCNAME"), "FILE:")
+	if krb5cc == "" && a.config.DetectCachedTicket {
+		var err error
+		krb5cc, err = ad.TicketPath()
+		// Don't return an error as we might still have a cached ticket
+		// under /run/adsys/krb5cc
+		if err != nil {
+			log.Warningf(a.ctx, "Failed to get ticket path: %v", err)
+		}
+	}
+	return krb5cc
+}
+
 func (a *App) purge(isComputer, purgeAll bool, target string) error {
 	// incompatible options
 	if purgeAll && target != "" {
@@ -569,6 +846,220 @@ func (a *App) purge(isComputer, purgeAll bool, target string) error {
 	return nil
 }
 
+// objectTarget returns the target for the computer or current user if none was provided.
+func objectTarget(isComputer bool, target string) (string, error) {
+	if target != "" {
+		return target, nil
+	}
+
+	if isComputer {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return "", err
+		}
+		// for malconfigured machines where /proc/sys/kernel/hostname returns the fqdn and not only the machine name, strip it
+		target, _, _ = strings.Cut(hostname, ".")
+		return target, nil
+	}
+
+	u, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve current user: %w", err)
+	}
+	return u.Username, nil
+}
+
+func (a *App) policyHistory(isComputer bool, target string) error {
+	if isComputer && target != "" {
+		return errors.New(gotext.Get("user arguments cannot be used with machine history"))
+	}
+
+	target, err := objectTarget(isComputer, target)
+	if err != nil {
+		return err
+	}
+
+	client, err := adsysservice.NewClient(a.config.Socket, a.getTimeout())
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	stream, err := client.PolicyHistory(a.ctx, &adsys.PolicyHistoryRequest{
+		Target:     target,
+		IsComputer: isComputer,
+	})
+	if err != nil {
+		return err
+	}
+
+	history, err := singleMsg(stream)
+	if err != nil {
+		return err
+	}
+	fmt.Print(history)
+
+	return nil
+}
+
+func (a *App) policyRollback(isComputer bool, target string, id int) error {
+	if isComputer && target != "" {
+		return errors.New(gotext.Get("user arguments cannot be used with machine rollback"))
+	}
+	if id < 1 {
+		return errors.New(gotext.Get("a snapshot identifier from the history command is required with --to"))
+	}
+
+	target, err := objectTarget(isComputer, target)
+	if err != nil {
+		return err
+	}
+
+	client, err := adsysservice.NewClient(a.config.Socket, a.getTimeout())
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	stream, err := client.PolicyRollback(a.ctx, &adsys.PolicyRollbackRequest{
+		Target:     target,
+		IsComputer: isComputer,
+		Id:         int32(id),
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := stream.Recv(); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	return nil
+}
+
+func (a *App) policyReport(isComputer bool, target string) error {
+	if isComputer && target != "" {
+		return errors.New(gotext.Get("user arguments cannot be used with machine report"))
+	}
+
+	target, err := objectTarget(isComputer, target)
+	if err != nil {
+		return err
+	}
+
+	client, err := adsysservice.NewClient(a.config.Socket, a.getTimeout())
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	stream, err := client.PolicyReport(a.ctx, &adsys.PolicyReportRequest{
+		Target:     target,
+		IsComputer: isComputer,
+	})
+	if err != nil {
+		return err
+	}
+
+	report, err := singleMsg(stream)
+	if err != nil {
+		return err
+	}
+	fmt.Print(report)
+
+	return nil
+}
+
+func (a *App) policyVerify(isComputer, verifyAll bool, target string) error {
+	// incompatible options
+	if (isComputer || verifyAll) && target != "" {
+		return errors.New(gotext.Get("user arguments cannot be used with machine or all verification"))
+	}
+
+	target, err := objectTarget(isComputer || verifyAll, target)
+	if err != nil {
+		return err
+	}
+
+	client, err := adsysservice.NewClient(a.config.Socket, a.getTimeout())
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	stream, err := client.VerifyPolicy(a.ctx, &adsys.VerifyPolicyRequest{
+		IsComputer: isComputer,
+		All:        verifyAll,
+		Target:     target,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Print the verification of every object, one message per object.
+	var drift bool
+	for {
+		r, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Print(r.GetMsg())
+		drift = drift || r.GetDrift()
+	}
+
+	if drift {
+		return errors.New(gotext.Get("system state drifted from the applied policies"))
+	}
+	return nil
+}
+
+func (a *App) policyDiff(isComputer bool, target string, snapshot int) error {
+	if isComputer && target != "" {
+		return errors.New(gotext.Get("user arguments cannot be used with machine diff"))
+	}
+	if snapshot < 0 {
+		return errors.New(gotext.Get("snapshot identifier must be positive"))
+	}
+
+	// Policies of the current user are fetched with its own ticket.
+	var krb5cc string
+	if !isComputer && target == "" && snapshot == 0 {
+		krb5cc = a.currentUserTicket()
+	}
+
+	target, err := objectTarget(isComputer, target)
+	if err != nil {
+		return err
+	}
+
+	client, err := adsysservice.NewClient(a.config.Socket, a.getTimeout())
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	stream, err := client.PolicyDiff(a.ctx, &adsys.PolicyDiffRequest{
+		Target:     target,
+		IsComputer: isComputer,
+		Snapshot:   int32(snapshot),
+		Krb5Cc:     krb5cc,
+	})
+	if err != nil {
+		return err
+	}
+
+	diff, err := singleMsg(stream)
+	if err != nil {
+		return err
+	}
+	fmt.Print(diff)
+
+	return nil
+}
+
 // users returns the list of connected users according to their cached policy information.
 // If active is true, the list of users is retrieved from the cached Kerberos ticket information.
 func (a App) users(active bool) []string {
@@ -581,10 +1072,209 @@ func (a App) users(active bool) []string {
 	if err != nil {
 		return nil
 	}
-	list, err := singleMsg(stream)
+	r, err := singleResponse(stream)
 	if err != nil {
 		return nil
 	}
 
-	return strings.Split(list, " ")
+	return r.GetUsers()
+}
+
+func (a *App) policyRSOP(isComputer bool, target string) error {
+	if isComputer && target != "" {
+		return errors.New(gotext.Get("user arguments cannot be used with machine resultant set of policies"))
+	}
+
+	target, err := objectTarget(isComputer, target)
+	if err != nil {
+		return err
+	}
+
+	client, err := adsysservice.NewClient(a.config.Socket, a.getTimeout())
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	stream, err := client.PolicyRSOP(a.ctx, &adsys.PolicyRSOPRequest{
+		Target:     target,
+		IsComputer: isComputer,
+	})
+	if err != nil {
+		return err
+	}
+
+	rsop, err := singleMsg(stream)
+	if err != nil {
+		return err
+	}
+	fmt.Print(rsop)
+
+	return nil
+}
+
+func (a *App) policyCapture(isComputer bool, target, output string) (err error) {
+	if isComputer && target != "" {
+		return errors.New(gotext.Get("user arguments cannot be used with machine capture"))
+	}
+
+	target, err = objectTarget(isComputer, target)
+	if err != nil {
+		return err
+	}
+
+	client, err := adsysservice.NewClient(a.config.Socket, a.getTimeout())
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	stream, err := client.CapturePolicy(a.ctx, &adsys.CapturePolicyRequest{
+		Target:     target,
+		IsComputer: isComputer,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Only create the archive once it has been fully received.
+	var archive []byte
+	for {
+		r, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		archive = append(archive, r.GetData()...)
+	}
+
+	return os.WriteFile(output, archive, 0600)
+}
+
+func (a *App) policyApplySnapshot(archive string) error {
+	// The archive is opened by the daemon, which doesn’t share our working directory.
+	archive, err := filepath.Abs(archive)
+	if err != nil {
+		return err
+	}
+
+	client, err := adsysservice.NewClient(a.config.Socket, a.getTimeout())
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	stream, err := client.ApplySnapshot(a.ctx, &adsys.ApplySnapshotRequest{
+		Archive: archive,
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := stream.Recv(); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	return nil
+}
+
+// polFormats are the supported formats of the rules of registry policy files.
+var polFormats = []string{"yaml", "json"}
+
+// polRule is a rule of a registry policy file, as printed or read by the pol commands.
+type polRule struct {
+	Key      string `yaml:"key" json:"key"`
+	Value    string `yaml:"value" json:"value"`
+	Type     string `yaml:"type,omitempty" json:"type,omitempty"`
+	Disabled bool   `yaml:"disabled,omitempty" json:"disabled,omitempty"`
+	Meta     string `yaml:"meta,omitempty" json:"meta,omitempty"`
+	Strategy string `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+}
+
+func (a *App) policyPolDecode(path, format string) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't decode %q", path))
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer decorate.LogFuncOnErrorContext(a.ctx, f.Close)
+
+	return decodePol(a.ctx, f, os.Stdout, format)
+}
+
+func (a *App) policyPolEncode(path, output string) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't encode %q", path))
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer decorate.LogFuncOnErrorContext(a.ctx, f.Close)
+
+	// Only create the policy file once all rules have been encoded.
+	var pol bytes.Buffer
+	if err := encodePol(f, &pol); err != nil {
+		return err
+	}
+
+	return os.WriteFile(output, pol.Bytes(), 0600)
+}
+
+// decodePol writes to w the rules of the registry policy file r in format.
+// Values of unsupported types are skipped with a warning.
+func decodePol(ctx context.Context, r io.Reader, w io.Writer, format string) error {
+	if !slices.Contains(polFormats, format) {
+		return errors.New(gotext.Get("unsupported output format %q, expected one of %v", format, polFormats))
+	}
+
+	values, err := registry.DecodePolicyValues(r)
+	if err != nil {
+		return err
+	}
+
+	rules := make([]polRule, 0, len(values))
+	for _, v := range values {
+		if v.Err != nil {
+			log.Warningf(ctx, "Skipping %s: %v", v.Key, v.Err)
+			continue
+		}
+		rules = append(rules, polRule{Key: v.Key, Value: v.Value, Type: v.Type, Disabled: v.Disabled, Meta: v.Meta, Strategy: v.Strategy})
+	}
+
+	var out []byte
+	switch format {
+	case "json":
+		out, err = json.MarshalIndent(rules, "", "  ")
+		out = append(out, '\n')
+	case "yaml":
+		out, err = yaml.Marshal(rules)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(out)
+	return err
+}
+
+// encodePol writes to w the registry policy file of the rules in YAML or JSON format read from r.
+func encodePol(r io.Reader, w io.Writer) error {
+	var rules []polRule
+	// JSON is a subset of YAML.
+	if err := yaml.NewDecoder(r).Decode(&rules); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	values := make([]registry.Value, 0, len(rules))
+	for _, rule := range rules {
+		values = append(values, registry.Value{
+			Entry: entry.Entry{Key: rule.Key, Value: rule.Value, Disabled: rule.Disabled, Meta: rule.Meta, Strategy: rule.Strategy},
+			Type:  rule.Type,
+		})
+	}
+
+	return registry.EncodePolicy(w, values)
 }
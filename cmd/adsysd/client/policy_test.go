@@ -1,11 +1,16 @@
 package client
 
 import (
+	"bytes"
+	"context"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/fatih/color"
 	"github.com/stretchr/testify/require"
+	"github.com/ubuntu/adsys/internal/ad/registry"
+	"github.com/ubuntu/adsys/internal/policies/entry"
 	"github.com/ubuntu/adsys/internal/testutils"
 )
 
@@ -51,3 +56,125 @@ Policies from user configuration:
 	want := testutils.LoadWithUpdateFromGolden(t, got)
 	require.Equal(t, want, got, "colorizePolicies returned expected formatted output")
 }
+
+func TestDecodePol(t *testing.T) {
+	t.Parallel()
+
+	var pol bytes.Buffer
+	err := registry.EncodePolicy(&pol, []registry.Value{
+		{Entry: entry.Entry{Key: "Software/Policies/Ubuntu/dconf/org/gnome/desktop/background/picture-uri/all", Value: "file:///usr/share/backgrounds/ubuntu.png", Meta: "s"}},
+		{Entry: entry.Entry{Key: "Software/Policies/Ubuntu/privilege/allow-local-admins/all", Disabled: true}},
+		{Entry: entry.Entry{Key: "Software/Policies/Ubuntu/scripts/user/logon/all", Value: "script1.sh\nscript2.sh", Strategy: entry.StrategyAppend}},
+		{Entry: entry.Entry{Key: "Software/Policies/Canonical/Ubuntu/Directory UI/QueryLimit", Value: "12345"}, Type: "REG_DWORD"},
+	})
+	require.NoError(t, err, "Setup: can't create registry policy file")
+
+	tests := map[string]struct {
+		pol    []byte
+		format string
+
+		wantErr bool
+	}{
+		"YAML output": {format: "yaml"},
+		"JSON output": {format: "json"},
+
+		// Error cases
+		"Error on invalid policy file": {pol: []byte("not a policy file"), format: "yaml", wantErr: true},
+		"Error on unsupported format":  {format: "text", wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if tc.pol == nil {
+				tc.pol = pol.Bytes()
+			}
+
+			var out strings.Builder
+			err := decodePol(context.Background(), bytes.NewReader(tc.pol), &out, tc.format)
+			if tc.wantErr {
+				require.Error(t, err, "decodePol should return an error but got none")
+				return
+			}
+			require.NoError(t, err, "decodePol should return no error but got one")
+
+			got := out.String()
+			want := testutils.LoadWithUpdateFromGolden(t, got)
+			require.Equal(t, want, got, "decodePol returned expected rules")
+		})
+	}
+}
+
+func TestEncodePol(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		rules string
+
+		want    []registry.Value
+		wantErr bool
+	}{
+		"From YAML": {
+			rules: `- key: Software/Policies/Ubuntu/dconf/org/gnome/desktop/background/picture-uri/all
+  value: file:///usr/share/backgrounds/ubuntu.png
+  meta: s
+- key: Software/Policies/Ubuntu/privilege/allow-local-admins/all
+  value: ""
+  disabled: true
+- key: Software/Policies/Ubuntu/scripts/user/logon/all
+  value: |-
+    script1.sh
+    script2.sh
+  strategy: append
+`,
+			want: []registry.Value{
+				{Entry: entry.Entry{Key: "Software/Policies/Ubuntu/dconf/org/gnome/desktop/background/picture-uri/all", Value: "file:///usr/share/backgrounds/ubuntu.png", Meta: "s"}, Type: "REG_SZ"},
+				{Entry: entry.Entry{Key: "Software/Policies/Ubuntu/privilege/allow-local-admins/all", Disabled: true}},
+				{Entry: entry.Entry{Key: "Software/Policies/Ubuntu/scripts/user/logon/all", Value: "script1.sh\nscript2.sh", Strategy: entry.StrategyAppend}, Type: "REG_MULTI_SZ"},
+			},
+		},
+		"From JSON": {
+			rules: `[{"key": "Software/Policies/Ubuntu/gdm/banner-message-text/all", "value": "Hello", "strategy": "override"}]`,
+			want: []registry.Value{
+				{Entry: entry.Entry{Key: "Software/Policies/Ubuntu/gdm/banner-message-text/all", Value: "Hello", Strategy: entry.StrategyOverride}, Type: "REG_SZ"},
+			},
+		},
+		"Type of values is kept": {
+			rules: `- key: Software/Policies/Canonical/Ubuntu/Directory UI/QueryLimit
+  value: "12345"
+  type: REG_DWORD
+- key: Software/Policies/Canonical/Ubuntu/Directory UI/Filter
+  value: "12345"
+  type: REG_SZ
+`,
+			want: []registry.Value{
+				{Entry: entry.Entry{Key: "Software/Policies/Canonical/Ubuntu/Directory UI/QueryLimit", Value: "12345"}, Type: "REG_DWORD"},
+				{Entry: entry.Entry{Key: "Software/Policies/Canonical/Ubuntu/Directory UI/Filter", Value: "12345"}, Type: "REG_SZ"},
+			},
+		},
+		"No rules": {rules: ""},
+
+		// Error cases
+		"Error on invalid rules":          {rules: "key: not a list", wantErr: true},
+		"Error on key without a path":     {rules: "- key: ValueName", wantErr: true},
+		"Error on unsupported value type": {rules: "- key: Software/ValueName\n  value: A\n  type: REG_BINARY", wantErr: true},
+		"Error on invalid REG_DWORD":      {rules: "- key: Software/ValueName\n  value: A\n  type: REG_DWORD", wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var pol bytes.Buffer
+			err := encodePol(strings.NewReader(tc.rules), &pol)
+			if tc.wantErr {
+				require.Error(t, err, "encodePol should return an error but got none")
+				return
+			}
+			require.NoError(t, err, "encodePol should return no error but got one")
+
+			got, err := registry.DecodePolicyValues(&pol)
+			require.NoError(t, err, "encodePol should write a valid registry policy file")
+			require.Equal(t, tc.want, got, "encodePol should write the rules as policy entries")
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
 
 	"github.com/leonelquinteros/gotext"
 	"github.com/spf13/cobra"
@@ -32,13 +33,15 @@ func (a *App) installService() {
 	}
 	mainCmd.AddCommand(cmd)
 
+	var statusFormat *string
 	cmd = &cobra.Command{
 		Use:               "status",
 		Short:             gotext.Get("Print service status"),
 		Args:              cobra.NoArgs,
 		ValidArgsFunction: cmdhandler.NoValidArgs,
-		RunE:              func(_ *cobra.Command, _ []string) error { return a.getStatus() },
+		RunE:              func(_ *cobra.Command, _ []string) error { return a.getStatus(*statusFormat) },
 	}
+	statusFormat = cmd.Flags().StringP("format", "", outputFormats[0], gotext.Get("output format, one of %v.", outputFormats))
 	mainCmd.AddCommand(cmd)
 
 	var stopForce *bool
@@ -82,7 +85,11 @@ func (a *App) serviceCat() error {
 }
 
 // getStatus returns the current server status.
-func (a App) getStatus() (err error) {
+func (a App) getStatus(format string) (err error) {
+	if err := checkOutputFormat(format); err != nil {
+		return err
+	}
+
 	client, err := adsysservice.NewClient(a.config.Socket, a.getTimeout())
 	if err != nil {
 		return err
@@ -94,11 +101,14 @@ func (a App) getStatus() (err error) {
 		return err
 	}
 
-	status, err := singleMsg(stream)
+	status, err := singleResponse(stream)
 	if err != nil {
 		return err
 	}
-	fmt.Println(status)
+	if format != outputFormats[0] {
+		return printStructured(os.Stdout, status, format)
+	}
+	fmt.Println(status.GetMsg())
 
 	return nil
 }
@@ -50,12 +50,21 @@ type daemonConfig struct {
 	ApparmorFsDir  string `mapstructure:"apparmorfs_dir"`
 	SystemUnitDir  string `mapstructure:"systemunit_dir"`
 	GlobalTrustDir string `mapstructure:"global_trust_dir"`
+	SecurityDir    string `mapstructure:"security_dir"`
+	NftablesDir    string `mapstructure:"nftables_dir"`
+	EnvironmentDir string `mapstructure:"environment_dir"`
+	ProfileDir     string `mapstructure:"profile_dir"`
+	PluginsDir     string `mapstructure:"plugins_dir"`
 
 	AdBackend     string         `mapstructure:"ad_backend"`
 	SSSdConfig    sss.Config     `mapstructure:"sssd"`
 	WinbindConfig winbind.Config `mapstructure:"winbind"`
+	// WMIFilterDefault is "apply" or "skip" for GPOs whose WMI filter can't be evaluated.
+	WMIFilterDefault string `mapstructure:"wmi_filter_default"`
 
 	ServiceTimeout int `mapstructure:"service_timeout"`
+	// DriftCheckInterval is the time in seconds between checks of the system state against the applied policies.
+	DriftCheckInterval int `mapstructure:"drift_check_interval"`
 }
 
 // New registers commands and return a new App.
@@ -122,9 +131,16 @@ func New() *App {
 				adsysservice.WithApparmorFsDir(a.config.ApparmorFsDir),
 				adsysservice.WithSystemUnitDir(a.config.SystemUnitDir),
 				adsysservice.WithGlobalTrustDir(a.config.GlobalTrustDir),
+				adsysservice.WithSecurityDir(a.config.SecurityDir),
+				adsysservice.WithNftablesDir(a.config.NftablesDir),
+				adsysservice.WithEnvironmentDir(a.config.EnvironmentDir),
+				adsysservice.WithProfileDir(a.config.ProfileDir),
+				adsysservice.WithPluginsDir(a.config.PluginsDir),
+				adsysservice.WithDriftCheckInterval(time.Duration(a.config.DriftCheckInterval)*time.Second),
 				adsysservice.WithADBackend(a.config.AdBackend),
 				adsysservice.WithSSSConfig(a.config.SSSdConfig),
 				adsysservice.WithWinbindConfig(a.config.WinbindConfig),
+				adsysservice.WithWMIFilterDefault(a.config.WMIFilterDefault),
 			)
 			if err != nil {
 				close(a.ready)
@@ -171,6 +187,7 @@ func New() *App {
 	a.installVersion()
 	a.installRunScripts()
 	a.installMount()
+	a.installApplySnapshot()
 	return &a
 }
 
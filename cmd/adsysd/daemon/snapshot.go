@@ -0,0 +1,42 @@
+package daemon
+
+import (
+	"context"
+
+	"github.com/leonelquinteros/gotext"
+	"github.com/spf13/cobra"
+	"github.com/ubuntu/adsys/internal/adsysservice"
+)
+
+func (a *App) installApplySnapshot() {
+	cmd := &cobra.Command{
+		Use:   "apply-snapshot ARCHIVE",
+		Short: gotext.Get("Apply the policies of an archive created with adsysctl policy capture, without Active Directory"),
+		Long: gotext.Get(`Apply the policies of an archive created with adsysctl policy capture, without Active Directory.
+
+No daemon nor domain configuration is needed: this can be used to provision a machine before joining it to a domain.`),
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error { return a.applySnapshot(args[0]) },
+	}
+	a.rootCmd.AddCommand(cmd)
+}
+
+func (a *App) applySnapshot(archive string) error {
+	return adsysservice.ApplySnapshot(context.Background(), archive,
+		adsysservice.WithCacheDir(a.config.CacheDir),
+		adsysservice.WithStateDir(a.config.StateDir),
+		adsysservice.WithRunDir(a.config.RunDir),
+		adsysservice.WithDconfDir(a.config.DconfDir),
+		adsysservice.WithSudoersDir(a.config.SudoersDir),
+		adsysservice.WithPolicyKitDir(a.config.PolicyKitDir),
+		adsysservice.WithApparmorDir(a.config.ApparmorDir),
+		adsysservice.WithApparmorFsDir(a.config.ApparmorFsDir),
+		adsysservice.WithSystemUnitDir(a.config.SystemUnitDir),
+		adsysservice.WithGlobalTrustDir(a.config.GlobalTrustDir),
+		adsysservice.WithSecurityDir(a.config.SecurityDir),
+		adsysservice.WithNftablesDir(a.config.NftablesDir),
+		adsysservice.WithEnvironmentDir(a.config.EnvironmentDir),
+		adsysservice.WithProfileDir(a.config.ProfileDir),
+		adsysservice.WithPluginsDir(a.config.PluginsDir),
+	)
+}
@@ -258,6 +258,10 @@ apparmor_dir: %[1]s/apparmor.d/adsys
 apparmorfs_dir: %[1]s/apparmorfs
 systemunit_dir: %[1]s/systemd/system
 global_trust_dir: %[1]s/share/ca-certificates
+security_dir: %[1]s/security
+nftables_dir: %[1]s/nftables.d
+environment_dir: %[1]s/environment.d
+profile_dir: %[1]s/profile.d
 
 detect_cached_ticket: %[3]t
 `, args.adsysDir, args.backend, args.detectCachedTicket))
@@ -118,6 +118,7 @@ func TestPolicyApplied(t *testing.T) {
 		"Error on unexisting user":                                  {args: []string{"doesnotexists@example.com"}, wantErr: true},
 		"Error on user name without domain and no default domain":   {args: []string{"doesnotexists"}, wantErr: true},
 		"Error on applied denied":                                   {systemAnswer: "polkit_no", wantErr: true},
+		"Error on unsupported output format":                        {args: []string{"--format", "xml"}, wantErr: true},
 		"Error on daemon not responding":                            {daemonNotStarted: true, wantErr: true},
 	}
 	for name, tc := range tests {
@@ -16,6 +16,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -23,7 +24,11 @@ import (
 	"github.com/leonelquinteros/gotext"
 	"github.com/ubuntu/adsys/internal/ad/backends"
 	adcommon "github.com/ubuntu/adsys/internal/ad/common"
+	"github.com/ubuntu/adsys/internal/ad/gpolist"
+	"github.com/ubuntu/adsys/internal/ad/gpp"
 	"github.com/ubuntu/adsys/internal/ad/registry"
+	"github.com/ubuntu/adsys/internal/ad/secedit"
+	"github.com/ubuntu/adsys/internal/ad/wmifilter"
 	"github.com/ubuntu/adsys/internal/consts"
 	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
 	"github.com/ubuntu/adsys/internal/policies"
@@ -48,8 +53,28 @@ const (
 	// policyServerPrefix is the GPO prefix containing keys that configure
 	// policy servers for certificate enrollment.
 	policyServersPrefix string = "Software/Policies/Microsoft/Cryptography/PolicyServers/"
+
+	// userPolicyModeKey is the computer GPO entry that configures the user policy loopback processing mode:
+	// 1 for merge and 2 for replace.
+	userPolicyModeKey string = "Software/Policies/Microsoft/Windows/System/UserPolicyMode"
+
+	// securityTemplatePath is the path of the security template in the machine directory of a GPO.
+	securityTemplatePath string = "Microsoft/Windows NT/SecEdit/GptTmpl.inf"
+	// securityTemplateSection is the security template section holding password and account lockout policies.
+	securityTemplateSection string = "System Access"
+	// securityTemplateRuleType is the rule type of the security template settings.
+	securityTemplateRuleType string = "systemaccess"
 )
 
+// securityTemplateSettings are the security template settings applied by the system access policy manager.
+var securityTemplateSettings = []string{
+	"MinimumPasswordLength",
+	"PasswordComplexity",
+	"LockoutBadCount",
+	"ResetLockoutCount",
+	"LockoutDuration",
+}
+
 type gpo downloadable
 
 type downloadable struct {
@@ -58,6 +83,14 @@ type downloadable struct {
 	mu       *sync.RWMutex
 	isAssets bool
 
+	// link is the container (domain or OU) a GPO is linked to, and enforced is set if this link is enforced.
+	link     string
+	enforced bool
+
+	// wmiFilterName and wmiFilter are the name and msWMI-Parm2 value of the WMI filter of the GPO, if any.
+	wmiFilterName string
+	wmiFilter     string
+
 	// This property is used to instrument the tests for concurrent download and parsing of GPOs
 	// Cf internal_test::TestFetchOneGPOWhileParsingItConcurrently()
 	testConcurrent bool
@@ -69,27 +102,45 @@ type AD struct {
 	configBackend backends.Backend
 
 	versionID        string
+	cacheDir         string
 	sysvolCacheDir   string
 	policiesCacheDir string
+	gpoListCacheDir  string
 	krb5CacheDir     string
 
 	downloadables map[string]*downloadable
 	sync.RWMutex
 	fetchMu sync.Mutex
 
+	// runOnce are the preferences items to apply once of the last policies returned per object, recorded as
+	// applied once these policies are.
+	runOnce   map[string]*runOnceTracker
+	runOnceMu sync.Mutex
+
 	withoutKerberos bool
+	gpoListDial     gpoListDialer
 	gpoListCmd      []string
 	gpoListTimeout  time.Duration
+
+	wmiFilterDefaultApply bool
+	wmiFactsRoot          string
 }
 
+// gpoListDialer connects to the LDAP server of the domain controller fqdn to list GPOs.
+type gpoListDialer func(ctx context.Context, fqdn, krb5CCPath string) (gpolist.Directory, error)
+
 type options struct {
 	versionID string
 	runDir    string
 	cacheDir  string
 
 	withoutKerberos bool
+	gpoListDial     gpoListDialer
 	gpoListCmd      []string
 	gpoListTimeout  time.Duration
+
+	wmiFilterDefaultApply bool
+	wmiFactsRoot          string
 }
 
 // Option reprents an optional function to change AD behavior.
@@ -111,7 +162,7 @@ func WithRunDir(runDir string) Option {
 	}
 }
 
-// WithGpoListTimeout specifies a custom timeout for the adsys-gpolist command.
+// WithGpoListTimeout specifies a custom timeout for listing GPOs, natively or with the adsys-gpolist command.
 func WithGpoListTimeout(timeout time.Duration) Option {
 	return func(o *options) error {
 		o.gpoListTimeout = timeout
@@ -119,8 +170,17 @@ func WithGpoListTimeout(timeout time.Duration) Option {
 	}
 }
 
+// WithWMIFilterDefault specifies if GPOs whose WMI filter can't be evaluated are applied or skipped.
+func WithWMIFilterDefault(apply bool) Option {
+	return func(o *options) error {
+		o.wmiFilterDefaultApply = apply
+		return nil
+	}
+}
+
 // AdsysGpoListCode is the embedded script which request
 // Samba to get our GPO list for the given object.
+// It is used as a fallback when the GPO list can't be fetched natively from LDAP.
 //
 //go:embed adsys-gpolist
 var AdsysGpoListCode string
@@ -136,11 +196,17 @@ func New(ctx context.Context, configBackend backends.Backend, hostname string, o
 
 	// defaults
 	args := options{
-		runDir:         consts.DefaultRunDir,
-		cacheDir:       consts.DefaultCacheDir,
+		runDir:   consts.DefaultRunDir,
+		cacheDir: consts.DefaultCacheDir,
+		gpoListDial: func(ctx context.Context, fqdn, krb5CCPath string) (gpolist.Directory, error) {
+			return gpolist.Dial(ctx, fqdn, krb5CCPath)
+		},
 		gpoListCmd:     []string{"python3", "-c", AdsysGpoListCode},
 		versionID:      versionID,
 		gpoListTimeout: 30 * time.Second, // this is used in tests and set to consts.DefaultGpoListTimeout in production
+
+		wmiFilterDefaultApply: true,
+		wmiFactsRoot:          "/",
 	}
 	// applied options
 	for _, o := range opts {
@@ -162,6 +228,10 @@ func New(ctx context.Context, configBackend backends.Backend, hostname string, o
 	if err := os.MkdirAll(policiesCacheDir, 0700); err != nil {
 		return nil, err
 	}
+	gpoListCacheDir := filepath.Join(args.cacheDir, GPOListCacheBaseName)
+	if err := os.MkdirAll(gpoListCacheDir, 0700); err != nil {
+		return nil, err
+	}
 
 	domain := configBackend.Domain()
 	serverFQDN, err := configBackend.ServerFQDN(ctx)
@@ -174,13 +244,20 @@ func New(ctx context.Context, configBackend backends.Backend, hostname string, o
 		hostname:         hostname,
 		configBackend:    configBackend,
 		versionID:        args.versionID,
+		cacheDir:         args.cacheDir,
 		sysvolCacheDir:   sysvolCacheDir,
 		policiesCacheDir: policiesCacheDir,
+		gpoListCacheDir:  gpoListCacheDir,
 		krb5CacheDir:     krb5CacheDir,
 
 		downloadables:  make(map[string]*downloadable),
+		runOnce:        make(map[string]*runOnceTracker),
+		gpoListDial:    args.gpoListDial,
 		gpoListCmd:     args.gpoListCmd,
 		gpoListTimeout: args.gpoListTimeout,
+
+		wmiFilterDefaultApply: args.wmiFilterDefaultApply,
+		wmiFactsRoot:          args.wmiFactsRoot,
 	}, nil
 }
 
@@ -190,7 +267,9 @@ func New(ctx context.Context, configBackend backends.Backend, hostname string, o
 // userKrb5CCName has no impact for computer object and is ignored. If empty, we will expect to find one cached
 // ticket <krb5CCDir>/<objectName>.
 // The GPOs are returned from the highest priority in the hierarchy, with enforcement in reverse order
-// to the lowest priority.
+// to the lowest priority. GPOs whose WMI filter doesn't match the machine are skipped and reported as such.
+// If the computer policies enable loopback processing, users get the user configuration of the GPOs linked to the
+// computer containers too, with a higher priority, or only them in replace mode.
 func (ad *AD) GetPolicies(ctx context.Context, objectName string, objectClass ObjectClass, userKrb5CCName string) (pols policies.Policies, err error) {
 	defer decorate.OnError(&err, gotext.Get("can't get policies for %q", objectName))
 
@@ -250,37 +329,59 @@ func (ad *AD) GetPolicies(ctx context.Context, objectName string, objectClass Ob
 		return policies.Policies{}, errors.New(gotext.Get("can't get current Server FQDN: %v", err))
 	}
 
-	// Otherwise, try fetching the GPO list from LDAP
-	args := append([]string{}, ad.gpoListCmd...) // Copy gpoListCmd to prevent data race
-	scriptArgs := []string{"--objectclass", string(objectClass), adServerFQDN, objectName}
-	cmdArgs := append(args, scriptArgs...)
-	cmdCtx, cancel := context.WithTimeout(ctx, time.Second*10)
-	defer cancel()
-	log.Debugf(ctx, "Getting gpo list with arguments: %q", strings.Join(scriptArgs, " "))
-	// #nosec G204 - cmdArgs is under our control (python embedded script or mock for tests)
-	cmd := exec.CommandContext(cmdCtx, cmdArgs[0], cmdArgs[1:]...)
-	cmd.Env = append(os.Environ(), fmt.Sprintf("KRB5CCNAME=%s", krb5CCPath))
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	var loopback policies.LoopbackMode
+	if objectClass == UserObject {
+		loopback = ad.userLoopbackMode(ctx)
+	}
 
-	smbsafe.WaitExec()
-	err = cmd.Run()
-	smbsafe.DoneExec()
-	if err != nil {
-		return pols, errors.New(gotext.Get("failed to retrieve the list of GPO (exited with %d): %v\n%s", cmd.ProcessState.ExitCode(), err, stderr.String()))
+	// Otherwise, try fetching the GPO list from LDAP
+	var gpoList []byte
+	if loopback != policies.LoopbackReplace {
+		if gpoList, err = ad.listGPOs(ctx, adServerFQDN, objectName, objectClass, krb5CCPath, ""); err != nil {
+			return pols, err
+		}
+	}
+	if loopback != "" {
+		log.Debugf(ctx, "Loopback processing of %q in %s mode", objectName, loopback)
+		loopbackList, err := ad.listGPOs(ctx, adServerFQDN, objectName, objectClass, krb5CCPath, ad.hostname)
+		if err != nil {
+			return pols, err
+		}
+		// Loopback GPOs are processed after the user ones on Windows: they take precedence.
+		gpoList = append(loopbackList, gpoList...)
 	}
 
 	downloadables := make(map[string]string)
 	var orderedGPOs []gpo
-	scanner := bufio.NewScanner(&stdout)
+	var skippedGPOs []policies.SkippedGPO
+	var appliedGPOList bytes.Buffer
+	var facts wmifilter.Facts
+	seen := make(map[string]struct{})
+	scanner := bufio.NewScanner(bytes.NewReader(gpoList))
 	for scanner.Scan() {
-		t := scanner.Text()
-		res := strings.SplitN(t, "\t", 2)
-		gpoName, gpoURL := res[0], res[1]
+		g, err := parseGPOListLine(scanner.Text())
+		if err != nil {
+			return pols, err
+		}
+		gpoName, gpoURL := g.name, g.url
+		// With loopback merge, GPOs linked to containers of both the user and the computer are only applied once.
+		if _, ok := seen[gpoURL]; ok {
+			continue
+		}
+		seen[gpoURL] = struct{}{}
+		reason, err := ad.wmiFilterSkipReason(ctx, g, &facts)
+		if err != nil {
+			return pols, err
+		}
+		if reason != "" {
+			log.Infof(ctx, "Skipping GPO %q for %q: %s", gpoName, objectName, reason)
+			skippedGPOs = append(skippedGPOs, policies.SkippedGPO{ID: filepath.Base(gpoURL), Name: gpoName, Reason: reason})
+			continue
+		}
+		appliedGPOList.WriteString(scanner.Text() + "\n")
 		log.Debugf(ctx, "GPO %q for %q available at %q", gpoName, objectName, gpoURL)
 		downloadables[gpoName] = gpoURL
-		orderedGPOs = append(orderedGPOs, gpo{name: gpoName, url: gpoURL})
+		orderedGPOs = append(orderedGPOs, g)
 
 		if _, ok := downloadables["assets"]; ok {
 			continue
@@ -304,11 +405,20 @@ func (ad *AD) GetPolicies(ctx context.Context, objectName string, objectClass Ob
 		return pols, err
 	}
 
+	runOnce, err := newRunOnceTracker(ad.cacheDir, objectName)
+	if err != nil {
+		return pols, err
+	}
+	target, err := ad.preferencesTarget(ctx, objectName, objectClass, adServerFQDN, krb5CCPath, facts)
+	if err != nil {
+		return pols, err
+	}
+
 	var errg errgroup.Group
 	// Parse policies
 	var gposRules []policies.GPO
 	errg.Go(func() (err error) {
-		gposRules, err = ad.parseGPOs(ctx, orderedGPOs, objectClass)
+		gposRules, err = ad.parseGPOs(ctx, orderedGPOs, objectClass, []gpp.Option{gpp.WithTarget(target), gpp.WithAppliedOnce(runOnce.appliedOnce)})
 		return err
 	})
 
@@ -353,7 +463,145 @@ func (ad *AD) GetPolicies(ctx context.Context, objectName string, objectClass Ob
 		return pols, fmt.Errorf("one or more error while parsing downloaded elements: %w", err)
 	}
 
-	return policies.New(ctx, gposRules, assetsDbPath)
+	// Preferences items to apply once are only recorded as applied once these policies are.
+	ad.runOnceMu.Lock()
+	ad.runOnce[objectName] = runOnce
+	ad.runOnceMu.Unlock()
+
+	// Keep the list of GPOs used by this refresh to be able to capture it later.
+	if err := ad.saveGPOList(objectName, appliedGPOList.Bytes()); err != nil {
+		return pols, err
+	}
+
+	if pols, err = policies.New(ctx, gposRules, assetsDbPath); err != nil {
+		return pols, err
+	}
+	pols.SkippedGPOs = skippedGPOs
+	return pols, nil
+}
+
+// PoliciesApplied records the preferences items to apply once of the last policies returned for objectName as
+// applied, so that they keep the entry they were applied with on the next refreshes.
+// It must only be called once these policies were successfully applied.
+func (ad *AD) PoliciesApplied(objectName string) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't record applied policies for %q", objectName))
+
+	ad.runOnceMu.Lock()
+	runOnce, ok := ad.runOnce[objectName]
+	delete(ad.runOnce, objectName)
+	ad.runOnceMu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	return runOnce.save()
+}
+
+// userLoopbackMode returns the user policy loopback processing mode enabled by the computer policies, read from the
+// cache of their last refresh.
+func (ad *AD) userLoopbackMode(ctx context.Context) policies.LoopbackMode {
+	pols, err := policies.NewFromCache(ctx, filepath.Join(ad.policiesCacheDir, ad.hostname))
+	if err != nil {
+		log.Debugf(ctx, "Can't read computer policies for loopback processing, considering it disabled: %v", err)
+		return ""
+	}
+	defer decorate.LogFuncOnErrorContext(ctx, pols.Close)
+
+	return pols.Loopback()
+}
+
+// wmiFilterSkipReason returns why g should be skipped because of its WMI filter, or an empty string if it applies.
+// The machine facts are loaded in facts on first use.
+// Filters which can't be evaluated follow the configured default.
+func (ad *AD) wmiFilterSkipReason(ctx context.Context, g gpo, facts *wmifilter.Facts) (reason string, err error) {
+	if g.wmiFilterName == "" {
+		return "", nil
+	}
+
+	f, err := wmifilter.Parse(g.wmiFilterName, g.wmiFilter)
+	if err != nil {
+		if ad.wmiFilterDefaultApply {
+			log.Warningf(ctx, "Applying GPO %q as its WMI filter can't be evaluated: %v", g.name, err)
+			return "", nil
+		}
+		return err.Error(), nil
+	}
+
+	if *facts == nil {
+		if *facts, err = wmifilter.LoadFacts(ad.wmiFactsRoot, ad.hostname); err != nil {
+			return "", err
+		}
+	}
+	if !f.Match(*facts) {
+		return gotext.Get("WMI filter %q doesn't match this machine", g.wmiFilterName), nil
+	}
+	return "", nil
+}
+
+// listGPOs returns the list of GPOs applying to objectName, one per line, as printed by the adsys-gpolist script.
+// The list is fetched natively from LDAP, with the adsys-gpolist script as a fallback.
+// If loopbackComputer is set, the GPOs are the ones linked to the containers of this computer, as with loopback
+// processing.
+func (ad *AD) listGPOs(ctx context.Context, fqdn, objectName string, objectClass ObjectClass, krb5CCPath, loopbackComputer string) ([]byte, error) {
+	if ad.gpoListDial != nil {
+		gpos, err := ad.listGPOsFromLDAP(ctx, fqdn, objectName, objectClass, krb5CCPath, loopbackComputer)
+		if err == nil {
+			return gpolist.Format(gpos), nil
+		}
+		if errors.Is(err, gpolist.ErrConnection) {
+			log.Debugf(ctx, "Can't connect to LDAP, falling back to adsys-gpolist: %v", err)
+		} else {
+			log.Warningf(ctx, "Can't list GPOs from LDAP, falling back to adsys-gpolist: %v", err)
+		}
+	}
+
+	args := append([]string{}, ad.gpoListCmd...) // Copy gpoListCmd to prevent data race
+	scriptArgs := []string{"--objectclass", string(objectClass)}
+	if loopbackComputer != "" {
+		scriptArgs = append(scriptArgs, "--loopback", loopbackComputer)
+	}
+	scriptArgs = append(scriptArgs, fqdn, objectName)
+	cmdArgs := append(args, scriptArgs...)
+	cmdCtx, cancel := context.WithTimeout(ctx, ad.gpoListTimeout)
+	defer cancel()
+	log.Debugf(ctx, "Getting gpo list with arguments: %q", strings.Join(scriptArgs, " "))
+	// #nosec G204 - cmdArgs is under our control (python embedded script or mock for tests)
+	cmd := exec.CommandContext(cmdCtx, cmdArgs[0], cmdArgs[1:]...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("KRB5CCNAME=%s", krb5CCPath))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	smbsafe.WaitExec()
+	err := cmd.Run()
+	smbsafe.DoneExec()
+	if err != nil {
+		return nil, errors.New(gotext.Get("failed to retrieve the list of GPO (exited with %d): %v\n%s", cmd.ProcessState.ExitCode(), err, stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// listGPOsFromLDAP returns the GPOs applying to objectName by walking the LDAP tree of the domain controller fqdn.
+func (ad *AD) listGPOsFromLDAP(ctx context.Context, fqdn, objectName string, objectClass ObjectClass, krb5CCPath, loopbackComputer string) ([]gpolist.GPO, error) {
+	ctx, cancel := context.WithTimeout(ctx, ad.gpoListTimeout)
+	defer cancel()
+
+	log.Debugf(ctx, "Getting gpo list of %q from LDAP server %q", objectName, fqdn)
+	dir, err := ad.gpoListDial(ctx, fqdn, krb5CCPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := dir.Close(); err != nil {
+			log.Warningf(ctx, "Can't close LDAP connection to %q: %v", fqdn, err)
+		}
+	}()
+
+	if loopbackComputer != "" {
+		return gpolist.ListLoopback(ctx, dir, fqdn, objectName, loopbackComputer)
+	}
+	return gpolist.List(ctx, dir, fqdn, objectName, objectClass == ComputerObject)
 }
 
 // ListUsers returns the list of users on the system based on their cached policy information.
@@ -480,21 +728,53 @@ func safeCopyFile(src, dst string, mode os.FileMode) error {
 	return os.Rename(dst+".new", dst)
 }
 
-func (ad *AD) parseGPOs(ctx context.Context, gpos []gpo, objectClass ObjectClass) (r []policies.GPO, err error) {
+// parseGPOListLine returns the GPO described by one line of the GPO list command output.
+// Each line is the GPO name and URL, optionally followed by the container it is linked to, "enforced" if
+// this link is enforced, and the name and msWMI-Parm2 value of its WMI filter, all separated by tabs.
+func parseGPOListLine(l string) (g gpo, err error) {
+	fields := strings.Split(l, "\t")
+	if len(fields) < 2 {
+		return g, errors.New(gotext.Get("invalid GPO list entry: %q", l))
+	}
+	g = gpo{name: fields[0], url: fields[1]}
+	if len(fields) > 2 {
+		g.link = fields[2]
+	}
+	if len(fields) > 3 {
+		g.enforced = fields[3] == "enforced"
+	}
+	if len(fields) > 5 {
+		g.wmiFilterName, g.wmiFilter = fields[4], fields[5]
+	}
+	return g, nil
+}
+
+func (ad *AD) parseGPOs(ctx context.Context, gpos []gpo, objectClass ObjectClass, gppOpts []gpp.Option) (r []policies.GPO, err error) {
+	return parseGPOs(ctx, ad.sysvolCacheDir, ad.versionID, gpos, objectClass, ad.downloadables, gppOpts)
+}
+
+// parseGPOs returns the rules of gpos, read from their Registry.pol files and Group Policy Preferences in sysvolDir.
+// Each GPO being downloaded in downloadables is locked while being parsed.
+// gppOpts are used to decode the Group Policy Preferences, to skip items which are not targeted or were already applied.
+func parseGPOs(ctx context.Context, sysvolDir, versionID string, gpos []gpo, objectClass ObjectClass, downloadables map[string]*downloadable, gppOpts []gpp.Option) (r []policies.GPO, err error) {
 	keyFilterPrefix := fmt.Sprintf("%s/%s/", adcommon.KeyPrefix, consts.DistroID)
 
 	for _, g := range gpos {
 		name, url := g.name, g.url
 		gpoWithRules := policies.GPO{
-			ID:    filepath.Base(url),
-			Name:  name,
-			Rules: make(map[string][]entry.Entry),
+			ID:       filepath.Base(url),
+			Name:     name,
+			Link:     g.link,
+			Enforced: g.enforced,
+			Rules:    make(map[string][]entry.Entry),
 		}
 		r = append(r, gpoWithRules)
 		if err := func() error {
-			ad.downloadables[name].mu.RLock()
-			defer ad.downloadables[name].mu.RUnlock()
-			_ = ad.downloadables[name].testConcurrent
+			if d, ok := downloadables[name]; ok {
+				d.mu.RLock()
+				defer d.mu.RUnlock()
+				_ = d.testConcurrent
+			}
 
 			log.Debugf(ctx, "Parsing GPO %q", name)
 
@@ -506,11 +786,28 @@ func (ad *AD) parseGPOs(ctx context.Context, gpos []gpo, objectClass ObjectClass
 				classes = []string{"Machine", "MACHINE"}
 			}
 
-			var err error
+			if objectClass == ComputerObject {
+				rules, err := parseSecurityTemplate(ctx, filepath.Join(sysvolDir, "Policies", filepath.Base(url)), classes)
+				if err != nil {
+					return err
+				}
+				if rules != nil {
+					gpoWithRules.Rules[securityTemplateRuleType] = rules
+				}
+			}
+
+			preferences, err := parsePreferences(ctx, filepath.Join(sysvolDir, "Policies", filepath.Base(url)), classes, objectClass, gppOpts)
+			if err != nil {
+				return err
+			}
+			for keyType, rules := range preferences {
+				gpoWithRules.Rules[keyType] = rules
+			}
+
 			var f *os.File
 			for _, class := range classes {
 				var e error
-				f, e = os.Open(filepath.Join(ad.sysvolCacheDir, "Policies", filepath.Base(url), class, "Registry.pol"))
+				f, e = os.Open(filepath.Join(sysvolDir, "Policies", filepath.Base(url), class, "Registry.pol"))
 
 				// We only care about the first error which is caused by opening
 				// the capitalized version of the class, instead of the
@@ -541,6 +838,11 @@ func (ad *AD) parseGPOs(ctx context.Context, gpos []gpo, objectClass ObjectClass
 			var currentKey string
 			var overrideEnabled bool
 			for _, pol := range pols {
+				if objectClass == ComputerObject && strings.EqualFold(pol.Key, userPolicyModeKey) {
+					gpoWithRules.Loopback = loopbackMode(pol)
+					continue
+				}
+
 				// Rewrite the certificate autoenrollment key so we can easily
 				// use it in the policy manager
 				if pol.Key == certAutoEnrollKey {
@@ -578,12 +880,12 @@ func (ad *AD) parseGPOs(ctx context.Context, gpos []gpo, objectClass ObjectClass
 					continue
 				}
 
-				if strings.HasPrefix(releaseID, "Override"+ad.versionID) && pol.Value == "true" {
+				if strings.HasPrefix(releaseID, "Override"+versionID) && pol.Value == "true" {
 					overrideEnabled = true
 					continue
 				}
 				// Check we have a matching override
-				if !overrideEnabled || releaseID != ad.versionID {
+				if !overrideEnabled || releaseID != versionID {
 					continue
 				}
 
@@ -593,16 +895,70 @@ func (ad *AD) parseGPOs(ctx context.Context, gpos []gpo, objectClass ObjectClass
 				p := gpoWithRules.Rules[keyType][iLast]
 				p.Value = pol.Value
 				gpoWithRules.Rules[keyType][iLast] = p
+				if gpoWithRules.ReleaseOverrides == nil {
+					gpoWithRules.ReleaseOverrides = make(map[string][]string)
+				}
+				gpoWithRules.ReleaseOverrides[keyType] = append(gpoWithRules.ReleaseOverrides[keyType], p.Key)
 			}
 			return nil
 		}(); err != nil {
 			return r, err
 		}
+		r[len(r)-1] = gpoWithRules
 	}
 
 	return r, nil
 }
 
+// parseSecurityTemplate returns the system access rules of the security template of the GPO in gpoDir, if any.
+// Only the settings supported by the system access policy manager are returned.
+func parseSecurityTemplate(ctx context.Context, gpoDir string, classes []string) (rules []entry.Entry, err error) {
+	var f *os.File
+	for _, class := range classes {
+		f, err = os.Open(filepath.Join(gpoDir, class, securityTemplatePath))
+		if err == nil {
+			break
+		}
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer decorate.LogFuncOnErrorContext(ctx, f.Close)
+
+	log.Debugf(ctx, "Parsing security template %q", f.Name())
+
+	settings, err := secedit.Decode(f)
+	if err != nil {
+		return nil, errors.New(gotext.Get("%s: %v", f.Name(), err))
+	}
+	for _, s := range settings {
+		name, found := strings.CutPrefix(s.Key, securityTemplateSection+"/")
+		if !found || !slices.Contains(securityTemplateSettings, name) {
+			continue
+		}
+		s.Key = name
+		rules = append(rules, s)
+	}
+
+	return rules, nil
+}
+
+// loopbackMode returns the loopback processing mode configured by the user policy mode entry e.
+func loopbackMode(e entry.Entry) policies.LoopbackMode {
+	if e.Disabled {
+		return policies.LoopbackDisabled
+	}
+	switch e.Value {
+	case "1":
+		return policies.LoopbackMerge
+	case "2":
+		return policies.LoopbackReplace
+	}
+	return policies.LoopbackDisabled
+}
+
 // GetInfo returns all information from the selected backend: static and dynamic part.
 func (ad *AD) GetInfo(ctx context.Context) (msg string) {
 	// static part
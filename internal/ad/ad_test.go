@@ -7,6 +7,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -118,6 +119,11 @@ func TestGetPolicies(t *testing.T) {
 		turnKrb5CCCacheRO bool
 		existing          map[string]string
 
+		wmiFilterDefaultSkip bool
+		wmiFactsRoot         string
+
+		computerLoopback policies.LoopbackMode
+
 		want             policies.Policies
 		wantAssetsEquals string
 		wantErr          bool
@@ -218,7 +224,8 @@ func TestGetPolicies(t *testing.T) {
 			want: policies.Policies{GPOs: []policies.GPO{{ID: "multiple-releases-one-enabled", Name: "multiple-releases-one-enabled-name", Rules: map[string][]entry.Entry{
 				"dconf": {
 					{Key: "A", Value: "21.04Value"},
-				}}}},
+				}},
+				ReleaseOverrides: map[string][]string{"dconf": {"A"}}}},
 			},
 		},
 		"Disabled override": {
@@ -236,7 +243,8 @@ func TestGetPolicies(t *testing.T) {
 			want: policies.Policies{GPOs: []policies.GPO{{ID: "multiple-releases", Name: "multiple-releases-name", Rules: map[string][]entry.Entry{
 				"dconf": {
 					{Key: "A", Value: "21.04Value"},
-				}}}},
+				}},
+				ReleaseOverrides: map[string][]string{"dconf": {"A"}}}},
 			},
 		},
 		"Disable override for matching release, other releases override ignored": {
@@ -451,6 +459,108 @@ func TestGetPolicies(t *testing.T) {
 			},
 		},
 
+		// WMI filters
+		"GPO with matching WMI filter is applied": {
+			gpoListArgs: []string{"gpoonly.com", "bob:standard@laptops"},
+			want:        policies.Policies{GPOs: []policies.GPO{standardUserGPO("standard")}},
+		},
+		"GPO with non matching WMI filter is skipped": {
+			gpoListArgs: []string{"gpoonly.com", "bob:one-value@vms::bob:standard"},
+			want: policies.Policies{
+				GPOs:        []policies.GPO{standardUserGPO("standard")},
+				SkippedGPOs: []policies.SkippedGPO{{ID: "one-value", Name: "one-value-name", Reason: `WMI filter "vms" doesn't match this machine`}},
+			},
+		},
+		"GPO with non matching WMI filter is skipped for computer object": {
+			objectName:  hostname,
+			objectClass: ad.ComputerObject,
+			gpoListArgs: []string{"gpoonly.com", hostname + ":standard@vms"},
+			want: policies.Policies{
+				SkippedGPOs: []policies.SkippedGPO{{ID: "standard", Name: "standard-name", Reason: `WMI filter "vms" doesn't match this machine`}},
+			},
+		},
+		"GPO with unsupported WMI filter is applied by default": {
+			gpoListArgs: []string{"gpoonly.com", "bob:standard@unsupported"},
+			want:        policies.Policies{GPOs: []policies.GPO{standardUserGPO("standard")}},
+		},
+		"GPO with unsupported WMI filter is skipped if configured": {
+			gpoListArgs:          []string{"gpoonly.com", "bob:standard@unsupported"},
+			wmiFilterDefaultSkip: true,
+			want: policies.Policies{
+				SkippedGPOs: []policies.SkippedGPO{{ID: "standard", Name: "standard-name",
+					Reason: `can't parse WMI filter "unsupported": unsupported WMI filter: class "Win32_Battery"`}},
+			},
+		},
+		"GPO with unreadable WMI filter follows the default": {
+			gpoListArgs:          []string{"gpoonly.com", "bob:standard@unreadable"},
+			wmiFilterDefaultSkip: true,
+			want: policies.Policies{
+				SkippedGPOs: []policies.SkippedGPO{{ID: "standard", Name: "standard-name",
+					Reason: `can't parse WMI filter "unreadable": missing field terminator in ""`}},
+			},
+		},
+
+		// Loopback processing
+		"Computer policy enables loopback in merge mode": {
+			objectName:  hostname,
+			objectClass: ad.ComputerObject,
+			gpoListArgs: []string{"gpoonly.com", hostname + ":loopback-merge::" + hostname + ":standard"},
+			want: policies.Policies{GPOs: []policies.GPO{
+				{ID: "loopback-merge", Name: "loopback-merge-name", Rules: map[string][]entry.Entry{}, Loopback: policies.LoopbackMerge},
+				standardComputerGPO("standard"),
+			}},
+		},
+		"Computer policy enables loopback in replace mode": {
+			objectName:  hostname,
+			objectClass: ad.ComputerObject,
+			gpoListArgs: []string{"gpoonly.com", hostname + ":loopback-replace"},
+			want: policies.Policies{GPOs: []policies.GPO{
+				{ID: "loopback-replace", Name: "loopback-replace-name", Rules: map[string][]entry.Entry{}, Loopback: policies.LoopbackReplace},
+			}},
+		},
+		"Computer policy disables loopback": {
+			objectName:  hostname,
+			objectClass: ad.ComputerObject,
+			gpoListArgs: []string{"gpoonly.com", hostname + ":loopback-disabled"},
+			want: policies.Policies{GPOs: []policies.GPO{
+				{ID: "loopback-disabled", Name: "loopback-disabled-name", Rules: map[string][]entry.Entry{}, Loopback: policies.LoopbackDisabled},
+			}},
+		},
+		"Loopback in merge mode appends computer linked GPOs with higher priority": {
+			gpoListArgs:      []string{"gpoonly.com", "bob:user-only::loopback:standard"},
+			computerLoopback: policies.LoopbackMerge,
+			want: policies.Policies{GPOs: []policies.GPO{
+				standardUserGPO("standard"),
+				{ID: "user-only", Name: "user-only-name", Rules: map[string][]entry.Entry{
+					"dconf": {
+						{Key: "A", Value: "userOnlyA"},
+						{Key: "B", Value: "userOnlyB"},
+					}}},
+			}},
+		},
+		"Loopback in merge mode only applies GPOs linked to both the user and the computer once": {
+			gpoListArgs:      []string{"gpoonly.com", "bob:standard::loopback:standard"},
+			computerLoopback: policies.LoopbackMerge,
+			want:             policies.Policies{GPOs: []policies.GPO{standardUserGPO("standard")}},
+		},
+		"Loopback in replace mode only applies computer linked GPOs": {
+			gpoListArgs:      []string{"gpoonly.com", "bob:user-only::loopback:standard"},
+			computerLoopback: policies.LoopbackReplace,
+			want:             policies.Policies{GPOs: []policies.GPO{standardUserGPO("standard")}},
+		},
+		"Disabled loopback only applies user linked GPOs": {
+			gpoListArgs:      []string{"gpoonly.com", "bob:standard::loopback:user-only"},
+			computerLoopback: policies.LoopbackDisabled,
+			want:             policies.Policies{GPOs: []policies.GPO{standardUserGPO("standard")}},
+		},
+		"Loopback does not apply to computer objects": {
+			objectName:       hostname,
+			objectClass:      ad.ComputerObject,
+			gpoListArgs:      []string{"gpoonly.com", hostname + ":standard::loopback:one-value"},
+			computerLoopback: policies.LoopbackReplace,
+			want:             policies.Policies{GPOs: []policies.GPO{standardComputerGPO("standard")}},
+		},
+
 		// Error cases
 		"Machine doesn’t match": {
 			objectName:  "NotHostname",
@@ -458,6 +568,11 @@ func TestGetPolicies(t *testing.T) {
 			gpoListArgs: []string{"gpoonly.com", "NotHostname:standard"},
 			wantErr:     true,
 		},
+		"Error on unreadable machine facts for WMI filters": {
+			gpoListArgs:  []string{"gpoonly.com", "bob:standard@laptops"},
+			wmiFactsRoot: "testdata/machine-with-unreadable-facts",
+			wantErr:      true,
+		},
 		"Without previous call, needs userKrb5CCBaseName": {
 			gpoListArgs:        []string{"gpoonly.com", "bob:standard"},
 			userKrb5CCBaseName: "-",
@@ -581,11 +696,16 @@ func TestGetPolicies(t *testing.T) {
 				}
 			}
 
+			if tc.wmiFactsRoot == "" {
+				tc.wmiFactsRoot = "testdata/machine"
+			}
+
 			cachedir, rundir := t.TempDir(), t.TempDir()
 			adc, err := ad.New(context.Background(), tc.backend, hostname,
 				ad.WithCacheDir(cachedir), ad.WithRunDir(rundir), ad.WithoutKerberos(),
 				ad.WithGPOListCmd(mockGPOListCmd(t, tc.gpoListArgs...)),
-				ad.WithVersionID(tc.versionID))
+				ad.WithVersionID(tc.versionID),
+				ad.WithWMIFilterDefault(!tc.wmiFilterDefaultSkip), ad.WithWMIFactsRoot(tc.wmiFactsRoot))
 			require.NoError(t, err, "Setup: cannot create ad object")
 
 			if tc.turnKrb5CCCacheRO {
@@ -597,6 +717,12 @@ func TestGetPolicies(t *testing.T) {
 				testutils.Copy(t, src, filepath.Join(adc.SysvolCacheDir(), n))
 			}
 
+			// the loopback mode is read from the computer policies of the last refresh
+			if tc.computerLoopback != "" {
+				machinePols := policies.Policies{GPOs: []policies.GPO{{ID: "loopback", Name: "loopback-name", Loopback: tc.computerLoopback}}}
+				require.NoError(t, machinePols.Save(filepath.Join(adc.PoliciesCacheDir(), hostname)), "Setup: can't save computer policies")
+			}
+
 			entries, err := adc.GetPolicies(context.Background(), tc.objectName, tc.objectClass, krb5CCName)
 			if tc.wantErr {
 				require.Error(t, err, "GetPolicies should have errored out")
@@ -606,6 +732,7 @@ func TestGetPolicies(t *testing.T) {
 
 			// Compare GPOs
 			require.Equal(t, tc.want.GPOs, entries.GPOs, "GetPolicies returns expected GPO entries in correct order")
+			require.Equal(t, tc.want.SkippedGPOs, entries.SkippedGPOs, "GetPolicies returns expected skipped GPOs")
 
 			// Compare assets
 			uncompressedAssets := t.TempDir()
@@ -1336,6 +1463,10 @@ func TestMockGPOList(_ *testing.T) {
 	// as in gpolist, we split on the @ if any
 	objectName := args[len(args)-1]
 	objectName = strings.Split(objectName, "@")[0]
+	// loopback GPOs are the ones listed for the "loopback" object
+	if slices.Contains(args, "--loopback") {
+		objectName = "loopback"
+	}
 
 	var gpos []string
 
@@ -1349,10 +1480,23 @@ func TestMockGPOList(_ *testing.T) {
 	}
 
 	for _, gpo := range gpos {
-		fmt.Fprintf(os.Stdout, "%s-name\tsmb://localhost:%d/SYSVOL/%s/Policies/%s\n", gpo, ad.SmbPort, domain, gpo)
+		// A GPO can be suffixed by @<filter> to attach one of the mock WMI filters to it.
+		gpo, filter, found := strings.Cut(gpo, "@")
+		fmt.Fprintf(os.Stdout, "%s-name\tsmb://localhost:%d/SYSVOL/%s/Policies/%s", gpo, ad.SmbPort, domain, gpo)
+		if found {
+			fmt.Fprintf(os.Stdout, "\t\t\t%s\t%s", filter, mockWMIFilters[filter])
+		}
+		fmt.Fprintln(os.Stdout)
 	}
 }
 
+// mockWMIFilters are the msWMI-Parm2 values of the WMI filters returned by the GPO list mock.
+var mockWMIFilters = map[string]string{
+	"laptops":     `1;3;10;64;WQL;root\CIMv2;SELECT * FROM Win32_ComputerSystem WHERE Model LIKE "%ThinkPad%";`,
+	"vms":         `1;3;10;62;WQL;root\CIMv2;SELECT * FROM Win32_ComputerSystem WHERE Manufacturer = "QEMU";`,
+	"unsupported": `1;3;10;27;WQL;root\CIMv2;SELECT * FROM Win32_Battery;`,
+}
+
 func mockGPOListCmd(t *testing.T, args ...string) []string {
 	t.Helper()
 
@@ -102,6 +102,18 @@ var (
 	defaultAppendNote = gotext.Get(`
  * Enabled: The value(s) referenced in the entry are applied on the client machine.
  * Disabled: The value(s) are removed from the target machine.
+ * Not configured: Value(s) declared higher in the GPO hierarchy will be used if available.`)
+
+	// defaultPrependNote is the default note for prepend-type policies. It will be used unless a specific note is provided.
+	defaultPrependNote = gotext.Get(`
+ * Enabled: The value(s) referenced in the entry are applied on the client machine, before the ones declared higher in the GPO hierarchy.
+ * Disabled: The value(s) are removed from the target machine.
+ * Not configured: Value(s) declared higher in the GPO hierarchy will be used if available.`)
+
+	// defaultMergeNote is the default note for merge-type policies. It will be used unless a specific note is provided.
+	defaultMergeNote = gotext.Get(`
+ * Enabled: The value(s) referenced in the entry are applied on the client machine, unless already declared higher in the GPO hierarchy.
+ * Disabled: The value(s) are removed from the target machine.
  * Not configured: Value(s) declared higher in the GPO hierarchy will be used if available.`)
 
 	// defaultOverrideNote is the default note for override-type policies. It will be used unless a specific note is provided.
@@ -265,6 +277,10 @@ func (g generator) generateExpandedCategories(categories []category, policies []
 			switch releasesElements["all"].Meta["strategy"] {
 			case entry.StrategyAppend:
 				note = defaultAppendNote
+			case entry.StrategyPrepend:
+				note = defaultPrependNote
+			case entry.StrategyMerge:
+				note = defaultMergeNote
 			default:
 				note = defaultOverrideNote
 			}
@@ -17,6 +17,7 @@ import (
 	"github.com/leonelquinteros/gotext"
 	log "github.com/sirupsen/logrus"
 	"github.com/ubuntu/adsys/internal/ad/admxgen/common"
+	"github.com/ubuntu/adsys/internal/policies/entry"
 	"github.com/ubuntu/decorate"
 	"gopkg.in/ini.v1"
 )
@@ -26,6 +27,9 @@ type Policy struct {
 	ObjectPath string
 	Schema     string
 	Class      string
+	// Strategy is how values of the same array key are combined between multiple GPOs.
+	// Default (empty) means "override".
+	Strategy string
 }
 
 // TODO:
@@ -154,6 +158,19 @@ func inflateToExpandedPolicies(policies []Policy, release, currentSessions strin
 		ep.MetaDisabled = map[string]string{
 			"meta": s.Type,
 		}
+		if policy.Strategy != "" && policy.Strategy != entry.StrategyOverride {
+			if !entry.IsListStrategy(policy.Strategy) {
+				return nil, errors.New(gotext.Get("unknown strategy %q for %s", policy.Strategy, policy.ObjectPath))
+			}
+			if s.Type != "as" && s.Type != "ai" {
+				return nil, errors.New(gotext.Get("strategy %q is only supported on array keys, %s is of type %q", policy.Strategy, policy.ObjectPath, s.Type))
+			}
+			ep.MetaEnabled["strategy"] = policy.Strategy
+			ep.MetaDisabled["strategy"] = policy.Strategy
+			// Used for the default note of the strategy.
+			ep.Meta = map[string]string{"strategy": policy.Strategy}
+			ep.Note = ""
+		}
 
 		if m.widgetType == common.WidgetTypeLongDecimal {
 			min := ep.RangeValues.Min
@@ -41,6 +41,11 @@ func TestGenerate(t *testing.T) {
 		"Double key":                           {root: "simple"},
 		"Double key with range":                {root: "simple"},
 
+		// Strategies
+		"Array of strings with merge strategy":    {root: "simple"},
+		"Array of integers with prepend strategy": {root: "simple"},
+		"Array with explicit override strategy":   {root: "simple"},
+
 		// Override cases
 		"Override without session":                                    {root: "simple", currentSessions: "-"},
 		"Override with no matching session defaults to root override": {root: "simple", currentSessions: "doesnotmatch"},
@@ -71,12 +76,14 @@ func TestGenerate(t *testing.T) {
 		"Missing XML declaration is successfully parsed":   {root: "missing_xml_declaration"},
 
 		// Error cases
-		"Unsupported key type": {root: "exotic_type", wantErr: true},
-		"Enum does not exist":  {root: "nonexistent_enum", wantErr: true},
-		"Invalid class":        {root: "simple", wantErr: true},
-		"Invalid min":          {root: "invalid_min", wantErr: true},
-		"NaN min":              {root: "nan_min", wantErr: true},
-		"Invalid schema files": {root: "broken_schema", wantErr: true},
+		"Unsupported key type":      {root: "exotic_type", wantErr: true},
+		"Enum does not exist":       {root: "nonexistent_enum", wantErr: true},
+		"Invalid class":             {root: "simple", wantErr: true},
+		"Invalid min":               {root: "invalid_min", wantErr: true},
+		"Unknown strategy":          {root: "simple", wantErr: true},
+		"Strategy on non array key": {root: "simple", wantErr: true},
+		"NaN min":                   {root: "nan_min", wantErr: true},
+		"Invalid schema files":      {root: "broken_schema", wantErr: true},
 	}
 	for name, tc := range tests {
 		def := strings.ToLower(strings.ReplaceAll(name, " ", "_"))
@@ -43,11 +43,13 @@ func TestGenerateExpandedCategories(t *testing.T) {
 		"with prefix": {},
 
 		// Optional content
-		"no defaults":             {},
-		"no note":                 {},
-		"no note strategy append": {},
-		"range":                   {},
-		"choices":                 {},
+		"no defaults":              {},
+		"no note":                  {},
+		"no note strategy append":  {},
+		"no note strategy prepend": {},
+		"no note strategy merge":   {},
+		"range":                    {},
+		"choices":                  {},
 
 		"default policy class is capitalized": {},
 		"requires ubuntu pro":                 {},
@@ -29,6 +29,8 @@ func TestAdsysGPOList(t *testing.T) {
 		url             string
 		accountName     string
 		objectClass     string
+		site            string
+		loopback        string
 		krb5ccNameState string
 
 		wantErr        bool
@@ -84,6 +86,51 @@ func TestAdsysGPOList(t *testing.T) {
 			accountName: "RnDUserWithBlockedInheritanceAndForcedPolicies@GPOONLY.COM",
 		},
 
+		// Loopback processing
+		"Loopback GPOs are the ones linked to the computer containers": {
+			accountName: "RnDUser@GPOONLY.COM",
+			loopback:    "hostname1",
+		},
+		"Loopback GPOs are filtered on the user configuration": {
+			accountName: "RnDUser@GPOONLY.COM",
+			loopback:    "hostname2",
+		},
+		"Loopback computer name is truncated to 15 characters": {
+			accountName: "RnDUser@GPOONLY.COM",
+			loopback:    "hostnameWithTruncatedLongName",
+		},
+
+		"WMI filters are returned with their GPOs": {
+			accountName: "RnDUserDep9@GPOONLY.COM",
+		},
+
+		// Sites
+		"Site GPOs are last": {
+			accountName: "RnDUser@GPOONLY.COM",
+			site:        "Branch",
+		},
+		"Site GPOs apply to computers": {
+			accountName: "hostname1",
+			objectClass: "computer",
+			site:        "Branch",
+		},
+		"Forced site GPOs are first": {
+			accountName: "RndUserSubDep2ForcedPolicy@GPOONLY.COM",
+			site:        "BranchWithForcedGPO",
+		},
+		"Block inheritance ignores site GPOs except forced ones": {
+			accountName: "RnDUserWithBlockedInheritance@GPOONLY.COM",
+			site:        "BranchWithForcedGPO",
+		},
+		"Unknown site is ignored": {
+			accountName: "RnDUser@GPOONLY.COM",
+			site:        "UnknownSite",
+		},
+		"Site resolution failure ignores site GPOs": {
+			accountName: "RnDUser@GPOONLY.COM",
+			site:        "FAILED",
+		},
+
 		// Access cases
 		"Security descriptor missing ignores GPO": { // AD is doing that for windows client
 			accountName: "RnDUserDep4@GPOONLY.COM",
@@ -159,6 +206,12 @@ func TestAdsysGPOList(t *testing.T) {
 			wantReturnCode: 1,
 			wantErr:        true,
 		},
+		"Error on loopback computer not found": {
+			accountName:    "RnDUser@GPOONLY.COM",
+			loopback:       "nonexistent",
+			wantReturnCode: 1,
+			wantErr:        true,
+		},
 		"Error on computer requested but found user": {
 			accountName:    "UserAtRoot@GPOONLY.COM",
 			objectClass:    "computer",
@@ -198,6 +251,7 @@ func TestAdsysGPOList(t *testing.T) {
 			if tc.url == "" {
 				tc.url = "adcontroller.example.com"
 			}
+			t.Setenv("ADSYS_TESTS_MOCK_SITE", tc.site)
 
 			// Ticket creation for mock
 			if tc.krb5ccNameState != "unset" {
@@ -224,8 +278,12 @@ func TestAdsysGPOList(t *testing.T) {
 				t.Setenv("KRB5CCNAME", krb5ccname)
 			}
 
+			args := []string{"--objectclass", tc.objectClass}
+			if tc.loopback != "" {
+				args = append(args, "--loopback", tc.loopback)
+			}
 			// #nosec G204: we control the command line name and only change it for tests
-			cmd := exec.Command(adsysGPOListcmd, "--objectclass", tc.objectClass, tc.url, tc.accountName)
+			cmd := exec.Command(adsysGPOListcmd, append(args, tc.url, tc.accountName)...)
 			got, err := cmd.CombinedOutput()
 			if tc.wantErr {
 				require.Error(t, err, "adsys-gpostlist should have failed but didn’t")
@@ -1,8 +1,9 @@
 package ad
 
 var (
-	WithoutKerberos = withoutKerberos
-	WithGPOListCmd  = withGPOListCmd
+	WithoutKerberos  = withoutKerberos
+	WithGPOListCmd   = withGPOListCmd
+	WithWMIFactsRoot = withWMIFactsRoot
 )
 
 func (ad *AD) SysvolCacheDir() string {
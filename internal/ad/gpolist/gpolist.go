@@ -0,0 +1,591 @@
+// Package gpolist lists the GPOs applying to a user or computer by walking the Active Directory LDAP tree.
+//
+// It follows the same rules than the domain controller: links are read from the object container up to the domain,
+// then from the site of the client, disabled links are skipped, enforced links win over blocked inheritance and each
+// GPO is filtered by its security descriptor and flags.
+package gpolist
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/go-ldap/ldap/v3/gssapi"
+	"github.com/leonelquinteros/gotext"
+	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
+	"github.com/ubuntu/decorate"
+)
+
+const (
+	// krb5ConfPath is the Kerberos configuration used to request the LDAP service ticket.
+	krb5ConfPath = "/etc/krb5.conf"
+
+	// gpoApplyGUID is the extended right to apply a GPO.
+	gpoApplyGUID = "edacfd8f-ffb3-11d1-b41d-00a0c968f939"
+
+	// sdFlagsControlOID is the LDAP control to select which parts of nTSecurityDescriptor are returned.
+	// Without it, reading the SACL requires privileges and the whole attribute is omitted.
+	sdFlagsControlOID = "1.2.840.113556.1.4.801"
+	// sdFlags requests the owner, group and DACL of the security descriptor.
+	sdFlags = 0x1 | 0x2 | 0x4
+
+	/* From dsdb */
+	gpLinkOptDisable      = 1
+	gpLinkOptEnforce      = 2
+	gpoBlockInheritance   = 1
+	gpoFlagUserDisable    = 1
+	gpoFlagMachineDisable = 2
+
+	// maxComputerNameLength is the length some AD truncates computer account names to.
+	maxComputerNameLength = 15
+)
+
+var (
+	// wellKnownSIDs are the SIDs which are part of any authenticated token: Everyone and Authenticated Users.
+	wellKnownSIDs = []string{"S-1-1-0", "S-1-5-11"}
+)
+
+var (
+	// ErrConnection is returned when the domain controller can't be reached or authenticated to.
+	ErrConnection = errors.New(gotext.Get("can't connect to the domain controller"))
+	// ErrAccountNotFound is returned when the requested user or computer doesn't exist in the directory.
+	ErrAccountNotFound = errors.New(gotext.Get("account not found"))
+	// ErrAccessDenied is returned when a GPO linked to the account can't be read.
+	ErrAccessDenied = errors.New(gotext.Get("access denied"))
+)
+
+// GPO is a GPO applying to an account.
+type GPO struct {
+	// Name is the GPO display name.
+	Name string
+	// URL is the smb URL of the GPO directory on the domain controller.
+	URL string
+	// Link is the container (site, domain or OU) the GPO is linked to.
+	Link string
+	// Enforced is set if the link to the GPO is enforced.
+	Enforced bool
+	// WMIFilterName is the name of the WMI filter of the GPO, if any.
+	WMIFilterName string
+	// WMIFilter is the msWMI-Parm2 value of the WMI filter, with its queries. It is empty if the filter can't be read.
+	WMIFilter string
+}
+
+// Account is a user or computer account with its security token.
+type Account struct {
+	// DN is the distinguished name of the account.
+	DN string
+	// SIDs are the SIDs of the account, of all the groups it is a member of and the well-known SIDs of authenticated
+	// accounts, sorted.
+	SIDs []string
+}
+
+// Directory is the part of an LDAP connection used to list GPOs.
+type Directory interface {
+	Search(*ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close() error
+}
+
+// Dial connects to the LDAP server of the domain controller fqdn, authenticating with the Kerberos ticket cache
+// krb5CCPath.
+// The connection is upgraded to TLS before binding: the GSSAPI bind doesn't negotiate signing nor sealing, so the GPO list
+// can only be trusted over TLS. ErrConnection is returned if the upgrade fails, so that callers can fall back to another
+// way of listing GPOs. The context deadline, if any, applies to every request.
+func Dial(ctx context.Context, fqdn, krb5CCPath string) (conn *ldap.Conn, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't connect to LDAP server %q", fqdn))
+
+	// Load the ticket first: there is no need to reach the network without any valid one.
+	client, err := gssapi.NewClientFromCCache(krb5CCPath, krb5ConfPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrConnection, err)
+	}
+	defer client.Close()
+
+	var timeout time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+
+	conn, err = ldap.DialURL("ldap://"+fqdn, ldap.DialWithDialer(&net.Dialer{Timeout: timeout}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrConnection, err)
+	}
+	if timeout > 0 {
+		conn.SetTimeout(timeout)
+	}
+
+	// Never continue in plain text: an attacker on the network could force it to tamper with the GPO list.
+	if err := conn.StartTLS(&tls.Config{ServerName: fqdn, MinVersion: tls.VersionTLS12}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%w: can't use TLS: %v", ErrConnection, err)
+	}
+
+	if err := conn.GSSAPIBind(client, "ldap/"+fqdn, ""); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%w: %v", ErrConnection, err)
+	}
+
+	return conn, nil
+}
+
+// List returns the GPOs applying to accountName, from the highest priority in the hierarchy to the lowest, with
+// enforced GPOs first in reverse order. As on Windows, GPOs linked to the site of the client, resolved by the domain
+// controller, have less weight than the domain ones.
+// fqdn is the domain controller the returned GPO URLs point to.
+func List(ctx context.Context, dir Directory, fqdn, accountName string, isComputer bool) (gpos []GPO, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't list GPOs for %q", accountName))
+
+	return list(ctx, dir, fqdn, accountName, isComputer, "")
+}
+
+// ListLoopback returns the GPOs applying to the user userName with loopback processing: the GPOs linked to the
+// containers of the computer computerName, filtered on the user groups and configuration. They are in the same order
+// than with List.
+func ListLoopback(ctx context.Context, dir Directory, fqdn, userName, computerName string) (gpos []GPO, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't list loopback GPOs of %q for %q", computerName, userName))
+
+	return list(ctx, dir, fqdn, userName, false, computerName)
+}
+
+// FindAccount returns the user or computer account accountName with its security token.
+func FindAccount(ctx context.Context, dir Directory, accountName string, isComputer bool) (a Account, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't find account %q", accountName))
+
+	// Users don’t need @, as we already have the specific-domain ticket.
+	if !isComputer {
+		accountName = strings.Split(accountName, "@")[0]
+	}
+
+	baseDN, _, err := namingContexts(dir)
+	if err != nil {
+		return a, err
+	}
+
+	account, err := findAccountWithCandidates(ctx, dir, baseDN, accountName, isComputer)
+	if err != nil {
+		return a, err
+	}
+
+	sids, err := tokenSIDs(dir, account)
+	if err != nil {
+		return a, err
+	}
+
+	a.DN = account.DN
+	for sid := range sids {
+		a.SIDs = append(a.SIDs, sid)
+	}
+	slices.Sort(a.SIDs)
+	return a, nil
+}
+
+// list returns the GPOs applying to accountName. They are the ones linked to the containers of the computer
+// loopbackComputer if set, or of the account otherwise.
+func list(ctx context.Context, dir Directory, fqdn, accountName string, isComputer bool, loopbackComputer string) (gpos []GPO, err error) {
+	// Users don’t need @, as we already have the specific-domain ticket.
+	if !isComputer {
+		accountName = strings.Split(accountName, "@")[0]
+	}
+
+	baseDN, configNC, err := namingContexts(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := findAccountWithCandidates(ctx, dir, baseDN, accountName, isComputer)
+	if err != nil {
+		return nil, err
+	}
+
+	sids, err := tokenSIDs(dir, account)
+	if err != nil {
+		return nil, err
+	}
+
+	containersOf := account.DN
+	if loopbackComputer != "" {
+		computer, err := findAccountWithCandidates(ctx, dir, baseDN, loopbackComputer, true)
+		if err != nil {
+			return nil, err
+		}
+		containersOf = computer.DN
+	}
+
+	// Sites are optional: a client outside of any known subnet only gets its domain and OU GPOs.
+	site, err := siteDN(dir, baseDN, configNC)
+	if err != nil {
+		log.Debugf(ctx, "Can't resolve client site, ignoring GPOs linked to it: %v", err)
+	}
+
+	l := lister{dir: dir, fqdn: fqdn, baseDN: baseDN, sids: sids, isComputer: isComputer}
+	return l.gposForDN(ctx, containersOf, site)
+}
+
+// Format returns the GPO list in the same format than the adsys-gpolist script: each line is the GPO name, its URL,
+// the container it is linked to and, if the link is enforced, "enforced". GPOs with a WMI filter have 2 more fields:
+// the filter name and its msWMI-Parm2 value. Fields are tab separated.
+func Format(gpos []GPO) []byte {
+	var b strings.Builder
+	for _, g := range gpos {
+		fmt.Fprintf(&b, "%s\t%s\t%s", g.Name, g.URL, g.Link)
+		if g.Enforced || g.WMIFilterName != "" {
+			b.WriteString("\t")
+		}
+		if g.Enforced {
+			b.WriteString("enforced")
+		}
+		if g.WMIFilterName != "" {
+			// WQL ignores whitespaces: replacing them keeps both lines and msWMI-Parm2 lengths valid.
+			fmt.Fprintf(&b, "\t%s\t%s", whitespaceReplacer.Replace(g.WMIFilterName), whitespaceReplacer.Replace(g.WMIFilter))
+		}
+		b.WriteString("\n")
+	}
+	return []byte(b.String())
+}
+
+// whitespaceReplacer replaces the field and line separators of the GPO list with spaces.
+var whitespaceReplacer = strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+
+// namingContexts returns the DN of the domain and of the configuration, read from the root DSE.
+func namingContexts(dir Directory) (defaultNC, configNC string, err error) {
+	res, err := dir.Search(ldap.NewSearchRequest("", ldap.ScopeBaseObject, ldap.NeverDerefAliases, 0, 0, false,
+		"(objectClass=*)", []string{"defaultNamingContext", "configurationNamingContext"}, nil))
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrConnection, err)
+	}
+	if len(res.Entries) == 0 || res.Entries[0].GetAttributeValue("defaultNamingContext") == "" {
+		return "", "", errors.New(gotext.Get("no default naming context on LDAP server"))
+	}
+	return res.Entries[0].GetAttributeValue("defaultNamingContext"), res.Entries[0].GetAttributeValue("configurationNamingContext"), nil
+}
+
+// findAccountWithCandidates returns the entry of the account name of the given class. Long computer names are
+// searched truncated too, as some AD truncate them.
+func findAccountWithCandidates(ctx context.Context, dir Directory, baseDN, name string, isComputer bool) (account *ldap.Entry, err error) {
+	candidates := []string{name}
+	if isComputer && len(name) > maxComputerNameLength {
+		candidates = append(candidates, name[:maxComputerNameLength])
+	}
+	for _, n := range candidates {
+		if account, err = findAccount(dir, baseDN, n, isComputer); err == nil {
+			return account, nil
+		}
+		log.Debugf(ctx, "Searching for account failed with: %v", err)
+	}
+	return nil, err
+}
+
+// findAccount returns the entry of the user or computer named name, with its objectClass and objectSid.
+func findAccount(dir Directory, baseDN, name string, isComputer bool) (*ldap.Entry, error) {
+	objectClass := "user"
+	if isComputer {
+		objectClass = "computer"
+	}
+
+	n := ldap.EscapeFilter(name)
+	filter := fmt.Sprintf("(&(|(samAccountName=%s)(samAccountName=%s$))(objectClass=%s))", n, n, objectClass)
+	res, err := dir.Search(ldap.NewSearchRequest(baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		filter, []string{"objectClass", "objectSid"}, nil))
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Entries) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, name)
+	}
+	account := res.Entries[0]
+
+	// Computers are users too: check that the object is really of the requested class.
+	var computer bool
+	for _, c := range account.GetAttributeValues("objectClass") {
+		if strings.EqualFold(c, "computer") {
+			computer = true
+		}
+	}
+	if computer != isComputer {
+		return nil, fmt.Errorf("%w: %s is not a %s", ErrAccountNotFound, name, objectClass)
+	}
+
+	return account, nil
+}
+
+// tokenSIDs returns the SIDs of the account, of all the groups it is a member of, including nested ones, and the
+// well-known SIDs of authenticated accounts.
+func tokenSIDs(dir Directory, account *ldap.Entry) (map[string]struct{}, error) {
+	objectSID, _, err := parseSID(account.GetRawAttributeValue("objectSid"), 0)
+	if err != nil {
+		return nil, errors.New(gotext.Get("invalid objectSid for %s: %v", account.DN, err))
+	}
+
+	// tokenGroups is computed by the server with the transitive group membership, including the primary group.
+	res, err := dir.Search(ldap.NewSearchRequest(account.DN, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 0, 0, false,
+		"(objectClass=*)", []string{"tokenGroups"}, nil))
+	if err != nil {
+		return nil, errors.New(gotext.Get("can't get groups of %s: %v", account.DN, err))
+	}
+
+	sids := map[string]struct{}{objectSID: {}}
+	for _, s := range wellKnownSIDs {
+		sids[s] = struct{}{}
+	}
+	for _, e := range res.Entries {
+		for _, raw := range e.GetRawAttributeValues("tokenGroups") {
+			sid, _, err := parseSID(raw, 0)
+			if err != nil {
+				return nil, errors.New(gotext.Get("invalid group SID for %s: %v", account.DN, err))
+			}
+			sids[sid] = struct{}{}
+		}
+	}
+
+	return sids, nil
+}
+
+// gpLink is a link to a GPO from a container.
+type gpLink struct {
+	dn      string
+	options int
+}
+
+// parseGPLink parses a gPLink attribute value: [LDAP://<GPO DN>;<options>]…
+func parseGPLink(v string) (links []gpLink, err error) {
+	for _, l := range strings.Split(v, "]") {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		d := strings.Split(l, ";")
+		if len(d) != 2 || !strings.HasPrefix(strings.ToUpper(d[0]), "[LDAP://") {
+			return nil, errors.New(gotext.Get("badly formed gPLink %q", l))
+		}
+		options, err := strconv.Atoi(d[1])
+		if err != nil {
+			return nil, errors.New(gotext.Get("badly formed gPLink options %q: %v", l, err))
+		}
+		links = append(links, gpLink{dn: d[0][len("[LDAP://"):], options: options})
+	}
+	return links, nil
+}
+
+// lister lists the GPOs of one account.
+type lister struct {
+	dir Directory
+	// fqdn is the domain controller the GPO URLs point to.
+	fqdn string
+	// baseDN is the DN of the domain.
+	baseDN string
+	// sids are the SIDs of the account and of its groups.
+	sids       map[string]struct{}
+	isComputer bool
+}
+
+// gposForDN walks the containers from the parent of dn up to the domain, then the site siteDN if any, and returns the
+// GPOs applying to the account, considering inheritance and enforced links.
+func (l lister) gposForDN(ctx context.Context, dn, siteDN string) ([]GPO, error) {
+	base, err := ldap.ParseDN(l.baseDN)
+	if err != nil {
+		return nil, err
+	}
+
+	var gpos []GPO
+	inherit := true
+	containerDN := dn
+	for {
+		containerDN = parentDN(containerDN)
+		if containerDN == "" {
+			return nil, errors.New(gotext.Get("%s is not in domain %s", dn, l.baseDN))
+		}
+
+		entry, err := readContainer(l.dir, containerDN)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			return nil, errors.New(gotext.Get("container %s not found", containerDN))
+		}
+
+		if gpos, err = l.appendLinkedGPOs(ctx, containerDN, entry, inherit, gpos); err != nil {
+			return nil, err
+		}
+
+		// Check if this blocks inheritance.
+		if gpOptions, _ := strconv.Atoi(entry.GetAttributeValue("gPOptions")); gpOptions&gpoBlockInheritance != 0 {
+			inherit = false
+		}
+
+		container, err := ldap.ParseDN(containerDN)
+		if err != nil {
+			return nil, err
+		}
+		if container.EqualFold(base) {
+			break
+		}
+	}
+
+	if siteDN == "" {
+		return gpos, nil
+	}
+
+	// The site is processed last, so that its GPOs have the least weight, and its enforced ones the most.
+	entry, err := readContainer(l.dir, siteDN)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		log.Warningf(ctx, "Site %s not found, ignoring GPOs linked to it", siteDN)
+		return gpos, nil
+	}
+	return l.appendLinkedGPOs(ctx, siteDN, entry, inherit, gpos)
+}
+
+// readContainer returns the gPLink and gPOptions of the container at dn, or nil if it doesn't exist.
+func readContainer(dir Directory, dn string) (*ldap.Entry, error) {
+	res, err := dir.Search(ldap.NewSearchRequest(dn, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 0, 0, false,
+		"(objectClass=*)", []string{"gPLink", "gPOptions"}, nil))
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New(gotext.Get("can't read container %s: %v", dn, err))
+	}
+	if len(res.Entries) == 0 {
+		return nil, nil
+	}
+	return res.Entries[0], nil
+}
+
+// appendLinkedGPOs appends to gpos the GPOs linked to the container entry at containerDN which apply to the account.
+// Only enforced links are followed if inherit is false.
+func (l lister) appendLinkedGPOs(ctx context.Context, containerDN string, entry *ldap.Entry, inherit bool, gpos []GPO) ([]GPO, error) {
+	links, err := parseGPLink(entry.GetAttributeValue("gPLink"))
+	if err != nil {
+		return nil, err
+	}
+	for _, link := range links {
+		enforced := link.options&gpLinkOptEnforce != 0
+		if !inherit && !enforced {
+			continue
+		}
+		if link.options&gpLinkOptDisable != 0 {
+			continue
+		}
+
+		g, ok, err := l.readGPO(ctx, link.dn)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		g.Link, g.Enforced = containerDN, enforced
+
+		// Enforced policy (higher wins), others (higher have less weight)
+		if enforced {
+			gpos = append([]GPO{g}, gpos...)
+		} else {
+			gpos = append(gpos, g)
+		}
+	}
+	return gpos, nil
+}
+
+// parentDN returns the DN of the parent of dn, as written by the server, or an empty string if dn has no parent.
+func parentDN(dn string) string {
+	for i := 0; i < len(dn); i++ {
+		switch dn[i] {
+		case '\\':
+			// Skip escaped character.
+			i++
+		case ',':
+			return strings.TrimSpace(dn[i+1:])
+		}
+	}
+	return ""
+}
+
+// readGPO returns the GPO at dn and if it applies to the account.
+func (l lister) readGPO(ctx context.Context, dn string) (g GPO, ok bool, err error) {
+	res, err := l.dir.Search(ldap.NewSearchRequest(dn, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 0, 0, false,
+		"(objectClass=*)", []string{"name", "displayName", "flags", "nTSecurityDescriptor", "gPCFileSysPath", "gPCWQLFilter"},
+		[]ldap.Control{sdFlagsControl()}))
+	if err != nil || len(res.Entries) == 0 || len(res.Entries[0].GetRawAttributeValue("nTSecurityDescriptor")) == 0 {
+		// GPOs that are unreadable are just skipped by AD.
+		log.Warningf(ctx, "Failed to fetch gpo object with nTSecurityDescriptor %s: %v", dn, err)
+		return g, false, nil
+	}
+	entry := res.Entries[0]
+
+	sd, err := parseSecurityDescriptor(entry.GetRawAttributeValue("nTSecurityDescriptor"))
+	if err != nil {
+		return g, false, errors.New(gotext.Get("invalid security descriptor on %s: %v", dn, err))
+	}
+	if !sd.canRead(l.sids) {
+		return g, false, fmt.Errorf("%w: %s", ErrAccessDenied, dn)
+	}
+	if !sd.canApply(l.sids) {
+		return g, false, nil
+	}
+
+	// Check the flags on the GPO.
+	flags, _ := strconv.Atoi(entry.GetAttributeValue("flags"))
+	if l.isComputer && flags&gpoFlagMachineDisable != 0 {
+		return g, false, nil
+	}
+	if !l.isComputer && flags&gpoFlagUserDisable != 0 {
+		return g, false, nil
+	}
+
+	g = GPO{
+		Name: entry.GetAttributeValue("displayName"),
+		URL:  gpoURL(entry.GetAttributeValue("gPCFileSysPath"), l.fqdn),
+	}
+	if wqlFilter := entry.GetAttributeValue("gPCWQLFilter"); wqlFilter != "" {
+		g.WMIFilterName, g.WMIFilter = l.readWMIFilter(ctx, wqlFilter)
+	}
+	return g, true, nil
+}
+
+// readWMIFilter returns the name and queries of the WMI filter referenced by the gPCWQLFilter value of a GPO:
+// [<domain>;<filter ID>;0].
+// Filters which can't be read are returned with their ID as name and no query, as they can't be evaluated.
+func (l lister) readWMIFilter(ctx context.Context, wqlFilter string) (name, parm2 string) {
+	fields := strings.Split(strings.Trim(wqlFilter, "[]"), ";")
+	if len(fields) < 2 || fields[1] == "" {
+		log.Warningf(ctx, "Invalid WMI filter reference %q", wqlFilter)
+		return wqlFilter, ""
+	}
+	id := fields[1]
+
+	dn := fmt.Sprintf("CN=%s,CN=SOM,CN=WMIPolicy,CN=System,%s", ldap.EscapeDN(id), l.baseDN)
+	res, err := l.dir.Search(ldap.NewSearchRequest(dn, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 0, 0, false,
+		"(objectClass=*)", []string{"msWMI-Name", "msWMI-Parm2"}, nil))
+	if err != nil || len(res.Entries) == 0 {
+		log.Warningf(ctx, "Failed to fetch WMI filter %s: %v", dn, err)
+		return id, ""
+	}
+
+	name = res.Entries[0].GetAttributeValue("msWMI-Name")
+	if name == "" {
+		name = id
+	}
+	return name, res.Entries[0].GetAttributeValue("msWMI-Parm2")
+}
+
+// gpoURL converts a GPO UNC path (\\domain\SysVol\…) to a smb URL on the domain controller fqdn.
+func gpoURL(path, fqdn string) string {
+	parts := strings.Split(strings.TrimPrefix(strings.ReplaceAll(path, `\`, "/"), "//"), "/")
+	parts[0] = fqdn
+	return "smb://" + strings.Join(parts, "/")
+}
+
+// sdFlagsControl returns the LDAP control requesting the owner, group and DACL of security descriptors.
+func sdFlagsControl() ldap.Control {
+	value := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "SDFlags")
+	value.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, sdFlags, "Flags"))
+	return ldap.NewControlString(sdFlagsControlOID, true, string(value.Bytes()))
+}
@@ -0,0 +1,356 @@
+package gpolist_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/ubuntu/adsys/internal/ad/gpolist"
+	"github.com/ubuntu/adsys/internal/ad/gpolist/mock"
+	"github.com/ubuntu/adsys/internal/testutils"
+)
+
+const (
+	domainDN  = "DC=example,DC=com"
+	itDN      = "OU=IT,DC=example,DC=com"
+	devDN     = "OU=Dev,OU=IT,DC=example,DC=com"
+	computeDN = "OU=Computers,DC=example,DC=com"
+	configDN  = "CN=Configuration,DC=example,DC=com"
+	siteDN    = "CN=Branch,CN=Sites,CN=Configuration,DC=example,DC=com"
+
+	domainSID   = "S-1-5-21-1-2-3"
+	bobSID      = domainSID + "-1105"
+	computerSID = domainSID + "-1106"
+	devsSID     = domainSID + "-1200"
+	// nestedSID is a group devs is member of.
+	nestedSID = domainSID + "-1201"
+
+	applyGUID = "edacfd8f-ffb3-11d1-b41d-00a0c968f939"
+)
+
+func TestList(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		account    string
+		isComputer bool
+		// loopbackComputer lists the GPOs of account with loopback processing on this computer.
+		loopbackComputer string
+		// site is the site of the client. An empty site makes the site resolution fail.
+		site string
+		// gpLinks are the GPOs linked to each container, as name;options.
+		gpLinks          map[string][]string
+		blockInheritance []string
+		rawGPLink        string
+		errSearch        []string
+
+		wantErr error
+	}{
+		"User GPOs from its OU up to the domain": {gpLinks: map[string][]string{
+			devDN: {"dev;0"}, itDN: {"it;0"}, domainDN: {"domain;0"}}},
+		"Multiple GPOs linked to the same container": {gpLinks: map[string][]string{
+			devDN: {"dev;0", "it;0"}, domainDN: {"domain;0"}}},
+		"Computer GPOs": {account: "mycomputer", isComputer: true, gpLinks: map[string][]string{
+			computeDN: {"it;0"}, domainDN: {"domain;0"}, devDN: {"dev;0"}}},
+		"Computer name is truncated to 15 characters": {account: "mycomputerwithalongname", isComputer: true, gpLinks: map[string][]string{
+			domainDN: {"domain;0"}}},
+		"User name without domain": {account: "bob", gpLinks: map[string][]string{
+			domainDN: {"domain;0"}}},
+		"No GPO linked": {},
+
+		// Links
+		"Enforced GPOs are first, in reverse order": {gpLinks: map[string][]string{
+			devDN: {"dev;2"}, itDN: {"it;0"}, domainDN: {"domain;2"}}},
+		"Disabled link is ignored": {gpLinks: map[string][]string{
+			devDN: {"dev;1"}, domainDN: {"domain;0"}}},
+		"Disabled enforced link is ignored": {gpLinks: map[string][]string{
+			devDN: {"dev;3"}, domainDN: {"domain;0"}}},
+		"Blocked inheritance ignores GPOs linked higher": {blockInheritance: []string{itDN}, gpLinks: map[string][]string{
+			devDN: {"dev;0"}, itDN: {"it;0"}, domainDN: {"domain;0"}}},
+		"Enforced GPOs linked higher than blocked inheritance apply": {blockInheritance: []string{devDN}, gpLinks: map[string][]string{
+			devDN: {"dev;0"}, itDN: {"it;0"}, domainDN: {"domain;2"}}},
+
+		// Sites
+		"Site GPOs have less weight than domain ones": {site: "Branch", gpLinks: map[string][]string{
+			devDN: {"dev;0"}, domainDN: {"domain;0"}, siteDN: {"site;0"}}},
+		"Enforced site GPOs win over enforced domain ones": {site: "Branch", gpLinks: map[string][]string{
+			devDN: {"dev;0"}, domainDN: {"domain;2"}, siteDN: {"site;2"}}},
+		"Site GPOs apply to computers": {account: "mycomputer", isComputer: true, site: "Branch", gpLinks: map[string][]string{
+			domainDN: {"domain;0"}, siteDN: {"site;0"}}},
+		"Blocked inheritance ignores site GPOs": {site: "Branch", blockInheritance: []string{domainDN}, gpLinks: map[string][]string{
+			domainDN: {"domain;0"}, siteDN: {"site;0"}}},
+		"Enforced site GPOs apply despite blocked inheritance": {site: "Branch", blockInheritance: []string{devDN}, gpLinks: map[string][]string{
+			devDN: {"dev;0"}, siteDN: {"site;2"}}},
+		"Disabled site link is ignored": {site: "Branch", gpLinks: map[string][]string{
+			domainDN: {"domain;0"}, siteDN: {"site;1"}}},
+		"Site GPOs are ignored if the client site can't be resolved": {gpLinks: map[string][]string{
+			domainDN: {"domain;0"}, siteDN: {"site;0"}}},
+		"Unknown site is ignored": {site: "Unknown", gpLinks: map[string][]string{
+			domainDN: {"domain;0"}, siteDN: {"site;0"}}},
+
+		// GPO filtering
+		"GPO disabled for users is filtered for users": {gpLinks: map[string][]string{
+			domainDN: {"domain;0", "user-disabled;0", "machine-disabled;0"}}},
+		"GPO disabled for machines is filtered for computers": {account: "mycomputer", isComputer: true, gpLinks: map[string][]string{
+			domainDN: {"domain;0", "user-disabled;0", "machine-disabled;0"}}},
+		"GPO denied to a group of the user is filtered": {gpLinks: map[string][]string{
+			domainDN: {"domain;0", "denied-to-devs;0"}}},
+		"GPO applying to a nested group of the user": {gpLinks: map[string][]string{
+			domainDN: {"domain;0", "nested-group-only;0"}}},
+		"GPO applying to a nested group of the user is filtered for others": {account: "mycomputer", isComputer: true, gpLinks: map[string][]string{
+			domainDN: {"domain;0", "nested-group-only;0"}}},
+		"GPO without apply right is filtered": {gpLinks: map[string][]string{
+			domainDN: {"domain;0", "no-apply-right;0"}}},
+		"Inherit only apply right is ignored": {gpLinks: map[string][]string{
+			domainDN: {"domain;0", "inherit-only-apply;0"}}},
+		"Unreadable GPO is skipped": {errSearch: []string{gpoDN("dev")}, gpLinks: map[string][]string{
+			devDN: {"dev;0"}, domainDN: {"domain;0"}}},
+
+		// WMI filters
+		"GPO with WMI filter": {gpLinks: map[string][]string{
+			domainDN: {"domain;0", "wmi-filtered;2"}}},
+		"GPO with unreadable WMI filter": {gpLinks: map[string][]string{
+			domainDN: {"domain;0", "wmi-filter-missing;0"}}},
+		"GPO with invalid WMI filter reference": {gpLinks: map[string][]string{
+			domainDN: {"domain;0", "wmi-filter-invalid-reference;0"}}},
+
+		// Loopback processing
+		"Loopback GPOs are the ones linked to the computer containers": {loopbackComputer: "mycomputer", gpLinks: map[string][]string{
+			computeDN: {"it;0"}, devDN: {"dev;0"}, domainDN: {"domain;0"}}},
+		"Loopback GPOs are filtered on the user configuration": {loopbackComputer: "mycomputer", gpLinks: map[string][]string{
+			computeDN: {"domain;0", "user-disabled;0", "machine-disabled;0"}}},
+		"Loopback GPOs are filtered on the user groups": {loopbackComputer: "mycomputer", gpLinks: map[string][]string{
+			computeDN: {"domain;0", "denied-to-devs;0", "nested-group-only;0"}}},
+		"Loopback computer name is truncated to 15 characters": {loopbackComputer: "mycomputerwithalongname", gpLinks: map[string][]string{
+			computeDN: {"it;0"}, domainDN: {"domain;0"}}},
+
+		// Error cases
+		"Error on account not found":       {account: "doesnotexist@example.com", wantErr: gpolist.ErrAccountNotFound},
+		"Error on user being a computer":   {account: "mycomputer@example.com", wantErr: gpolist.ErrAccountNotFound},
+		"Error on computer being a user":   {account: "bob", isComputer: true, wantErr: gpolist.ErrAccountNotFound},
+		"Error on unreachable root DSE":    {errSearch: []string{""}, wantErr: gpolist.ErrConnection},
+		"Error on GPO not readable":        {gpLinks: map[string][]string{domainDN: {"domain;0", "read-denied;0"}}, wantErr: gpolist.ErrAccessDenied},
+		"Error on badly formed gPLink":     {rawGPLink: "[LDAP://cn=something]"},
+		"Error on invalid gPLink options":  {rawGPLink: "[LDAP://cn=something;notanumber]"},
+		"Error on unreadable container":    {errSearch: []string{itDN}},
+		"Error on failing to fetch groups": {errSearch: []string{"CN=bob,OU=Dev,OU=IT,DC=example,DC=com"}},
+		"Error on invalid security descriptor": {gpLinks: map[string][]string{
+			domainDN: {"invalid-security-descriptor;0"}}},
+		"Error on unreadable site":                {site: "Branch", errSearch: []string{siteDN}},
+		"Error on loopback computer not found":    {loopbackComputer: "doesnotexist", wantErr: gpolist.ErrAccountNotFound},
+		"Error on loopback computer being a user": {loopbackComputer: "bob", wantErr: gpolist.ErrAccountNotFound},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if tc.account == "" {
+				tc.account = "bob@example.com"
+			}
+
+			dir := newDirectory(t, tc.gpLinks, tc.blockInheritance)
+			dir.ClientSite = tc.site
+			if tc.rawGPLink != "" {
+				dir.Entries[itDN]["gPLink"] = []string{tc.rawGPLink}
+			}
+			dir.ErrSearch = make(map[string]bool)
+			for _, dn := range tc.errSearch {
+				dir.ErrSearch[strings.ToLower(dn)] = true
+			}
+
+			var gpos []gpolist.GPO
+			var err error
+			if tc.loopbackComputer != "" {
+				gpos, err = gpolist.ListLoopback(context.Background(), dir, "dc.example.com", tc.account, tc.loopbackComputer)
+			} else {
+				gpos, err = gpolist.List(context.Background(), dir, "dc.example.com", tc.account, tc.isComputer)
+			}
+			if tc.wantErr != nil || strings.HasPrefix(name, "Error") {
+				require.Error(t, err, "List should have failed but didn't")
+				if tc.wantErr != nil {
+					require.ErrorIs(t, err, tc.wantErr, "List should have returned the expected error")
+				}
+				return
+			}
+			require.NoError(t, err, "List should not have failed")
+
+			got := string(gpolist.Format(gpos))
+			want := testutils.LoadWithUpdateFromGolden(t, got)
+			require.Equal(t, want, got, "List should return the expected GPOs")
+		})
+	}
+}
+
+func TestFindAccount(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		account    string
+		isComputer bool
+		errSearch  []string
+
+		want    gpolist.Account
+		wantErr error
+	}{
+		"User with its groups": {account: "bob@example.com", want: gpolist.Account{
+			DN:   "CN=bob," + devDN,
+			SIDs: []string{"S-1-1-0", "S-1-5-11", bobSID, devsSID, nestedSID, domainSID + "-513"},
+		}},
+		"User name without domain": {account: "bob", want: gpolist.Account{
+			DN:   "CN=bob," + devDN,
+			SIDs: []string{"S-1-1-0", "S-1-5-11", bobSID, devsSID, nestedSID, domainSID + "-513"},
+		}},
+		"Computer with its groups": {account: "mycomputer", isComputer: true, want: gpolist.Account{
+			DN:   "CN=mycomputer," + computeDN,
+			SIDs: []string{"S-1-1-0", "S-1-5-11", computerSID, domainSID + "-515"},
+		}},
+
+		// Error cases
+		"Error on account not found":       {account: "doesnotexist@example.com", wantErr: gpolist.ErrAccountNotFound},
+		"Error on computer being a user":   {account: "bob", isComputer: true, wantErr: gpolist.ErrAccountNotFound},
+		"Error on unreachable root DSE":    {account: "bob", errSearch: []string{""}, wantErr: gpolist.ErrConnection},
+		"Error on failing to fetch groups": {account: "bob", errSearch: []string{"CN=bob,OU=Dev,OU=IT,DC=example,DC=com"}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := newDirectory(t, nil, nil)
+			dir.ErrSearch = make(map[string]bool)
+			for _, dn := range tc.errSearch {
+				dir.ErrSearch[strings.ToLower(dn)] = true
+			}
+
+			got, err := gpolist.FindAccount(context.Background(), dir, tc.account, tc.isComputer)
+			if tc.wantErr != nil || strings.HasPrefix(name, "Error") {
+				require.Error(t, err, "FindAccount should have failed but didn't")
+				if tc.wantErr != nil {
+					require.ErrorIs(t, err, tc.wantErr, "FindAccount should have returned the expected error")
+				}
+				return
+			}
+			require.NoError(t, err, "FindAccount should not have failed")
+
+			require.Equal(t, tc.want, got, "FindAccount should return the expected account")
+		})
+	}
+}
+
+// gpoDN returns the DN of the GPO named name.
+func gpoDN(name string) string {
+	return fmt.Sprintf("CN={%s},CN=Policies,CN=System,%s", strings.ToUpper(name), domainDN)
+}
+
+// newDirectory returns a directory with a user, bob, in the Dev OU, a computer, mycomputer, in the Computers OU and a
+// Branch site.
+// gpLinks lists the GPOs linked to each container and blockInheritance the containers blocking inheritance.
+func newDirectory(t *testing.T, gpLinks map[string][]string, blockInheritance []string) mock.Directory {
+	t.Helper()
+
+	const admins = domainSID + "-512"
+	readAndApply := func(sid string) []mock.ACE {
+		return []mock.ACE{
+			{Type: mock.AccessAllowed, Mask: 0x20094, SID: sid},
+			{Type: mock.AccessAllowedObject, Mask: 0x100, ObjectType: applyGUID, SID: sid},
+		}
+	}
+	adminsFullControl := mock.ACE{Type: mock.AccessAllowed, Mask: 0xF01FF, SID: admins}
+
+	securityDescriptors := map[string]string{
+		"domain":           mock.SecurityDescriptor(admins, append(readAndApply("S-1-5-11"), adminsFullControl)...),
+		"it":               mock.SecurityDescriptor(admins, readAndApply("S-1-5-11")...),
+		"dev":              mock.SecurityDescriptor(admins, readAndApply("S-1-5-11")...),
+		"site":             mock.SecurityDescriptor(admins, readAndApply("S-1-5-11")...),
+		"user-disabled":    mock.SecurityDescriptor(admins, readAndApply("S-1-5-11")...),
+		"machine-disabled": mock.SecurityDescriptor(admins, readAndApply("S-1-5-11")...),
+		"denied-to-devs": mock.SecurityDescriptor(admins, append(readAndApply("S-1-5-11"),
+			mock.ACE{Type: mock.AccessDeniedObject, Mask: 0x100, ObjectType: applyGUID, SID: devsSID})...),
+		"nested-group-only": mock.SecurityDescriptor(admins, append(readAndApply(nestedSID),
+			mock.ACE{Type: mock.AccessAllowed, Mask: 0x20094, SID: "S-1-5-11"})...),
+		"no-apply-right": mock.SecurityDescriptor(admins, mock.ACE{Type: mock.AccessAllowed, Mask: 0x20094, SID: "S-1-5-11"}),
+		"inherit-only-apply": mock.SecurityDescriptor(admins,
+			mock.ACE{Type: mock.AccessAllowed, Mask: 0x20094, SID: "S-1-5-11"},
+			mock.ACE{Type: mock.AccessAllowedObject, Flags: 0x08, Mask: 0x100, ObjectType: applyGUID, SID: "S-1-5-11"}),
+		"read-denied": mock.SecurityDescriptor(admins,
+			mock.ACE{Type: mock.AccessDenied, Mask: 0x10, SID: "S-1-1-0"},
+			mock.ACE{Type: mock.AccessAllowed, Mask: 0x20094, SID: "S-1-5-11"},
+			mock.ACE{Type: mock.AccessAllowedObject, Mask: 0x100, ObjectType: applyGUID, SID: "S-1-5-11"}),
+		"invalid-security-descriptor":  "too short",
+		"wmi-filtered":                 mock.SecurityDescriptor(admins, readAndApply("S-1-5-11")...),
+		"wmi-filter-missing":           mock.SecurityDescriptor(admins, readAndApply("S-1-5-11")...),
+		"wmi-filter-invalid-reference": mock.SecurityDescriptor(admins, readAndApply("S-1-5-11")...),
+	}
+	flags := map[string]string{
+		"user-disabled":    "1",
+		"machine-disabled": "2",
+	}
+	wqlFilters := map[string]string{
+		"wmi-filtered":                 "[example.com;{LAPTOPS};0]",
+		"wmi-filter-missing":           "[example.com;{DOESNOTEXIST};0]",
+		"wmi-filter-invalid-reference": "[example.com]",
+	}
+
+	dir := mock.Directory{Entries: map[string]map[string][]string{
+		"":        {"defaultNamingContext": {domainDN}, "configurationNamingContext": {configDN}},
+		domainDN:  {"objectClass": {"top", "domain", "domainDNS"}},
+		itDN:      {"objectClass": {"top", "organizationalUnit"}},
+		devDN:     {"objectClass": {"top", "organizationalUnit"}},
+		computeDN: {"objectClass": {"top", "organizationalUnit"}},
+		siteDN:    {"objectClass": {"top", "site"}},
+		"CN={LAPTOPS},CN=SOM,CN=WMIPolicy,CN=System," + domainDN: {
+			"msWMI-Name": {"Laptops"},
+			// Separators of the GPO list are replaced in queries.
+			"msWMI-Parm2": {"1;3;10;65;WQL;root\\CIMv2;SELECT * FROM Win32_ComputerSystem\n\tWHERE Model LIKE \"%ThinkPad%\";"},
+		},
+		"CN=bob," + devDN: {
+			"objectClass":    {"top", "person", "organizationalPerson", "user"},
+			"samAccountName": {"bob"},
+			"objectSid":      {mock.SID(bobSID)},
+			"tokenGroups":    {mock.SID(domainSID + "-513"), mock.SID(devsSID), mock.SID(nestedSID)},
+		},
+		"CN=mycomputer," + computeDN: {
+			"objectClass":    {"top", "person", "organizationalPerson", "user", "computer"},
+			"samAccountName": {"mycomputer$"},
+			"objectSid":      {mock.SID(computerSID)},
+			"tokenGroups":    {mock.SID(domainSID + "-515")},
+		},
+		"CN=mycomputerwithal," + computeDN: {
+			"objectClass":    {"top", "person", "organizationalPerson", "user", "computer"},
+			"samAccountName": {"mycomputerwitha$"},
+			"objectSid":      {mock.SID(computerSID)},
+			"tokenGroups":    {mock.SID(domainSID + "-515")},
+		},
+	}}
+
+	for name, sd := range securityDescriptors {
+		attrs := map[string][]string{
+			"name":                 {"{" + strings.ToUpper(name) + "}"},
+			"displayName":          {name + "-name"},
+			"gPCFileSysPath":       {fmt.Sprintf(`\\example.com\SysVol\example.com\Policies\{%s}`, strings.ToUpper(name))},
+			"nTSecurityDescriptor": {sd},
+		}
+		if f, ok := flags[name]; ok {
+			attrs["flags"] = []string{f}
+		}
+		if f, ok := wqlFilters[name]; ok {
+			attrs["gPCWQLFilter"] = []string{f}
+		}
+		dir.Entries[gpoDN(name)] = attrs
+	}
+
+	for container, links := range gpLinks {
+		var gpLink string
+		for _, l := range links {
+			name, options, _ := strings.Cut(l, ";")
+			gpLink += fmt.Sprintf("[LDAP://%s;%s]", gpoDN(name), options)
+		}
+		dir.Entries[container]["gPLink"] = []string{gpLink}
+	}
+	for _, container := range blockInheritance {
+		dir.Entries[container]["gPOptions"] = []string{"1"}
+	}
+
+	return dir
+}
@@ -0,0 +1,58 @@
+package gpolist
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseClientSite(t *testing.T) {
+	t.Parallel()
+
+	header := append([]byte{logonSAMLogonResponseEx, 0}, make([]byte, netlogonHeaderSize-2)...)
+	// Forest, domain, host, netbios domain, netbios computer, user and DC site names.
+	names := []byte("\x07example\x03com\x00\xc0\x18\x02dc\xc0\x18\x07EXAMPLE\x00\x02DC\x00\x00\x04Main\x00")
+
+	tests := map[string]struct {
+		data []byte
+
+		want    string
+		wantErr bool
+	}{
+		"Client site":                  {data: concat(header, names, []byte("\x06Branch\x00")), want: "Branch"},
+		"Client site for unknown user": {data: concat([]byte{logonSAMUserUnknownEx, 0}, header[2:], names, []byte("\x06Branch\x00")), want: "Branch"},
+		"Compressed client site":       {data: concat(header, names, []byte("\xc0\x3a")), want: "Main"},
+		"No client site":               {data: concat(header, names, []byte("\x00"))},
+
+		"Error on too short response":    {data: header[:10], wantErr: true},
+		"Error on unexpected opcode":     {data: concat([]byte{19, 0}, header[2:], names, []byte("\x00")), wantErr: true},
+		"Error on missing names":         {data: concat(header, names), wantErr: true},
+		"Error on label out of bounds":   {data: concat(header, names, []byte("\x06Bra")), wantErr: true},
+		"Error on pointer out of bounds": {data: concat(header, names, []byte("\xc0")), wantErr: true},
+		"Error on pointer outside data":  {data: concat(header, names, []byte("\xc0\xff")), wantErr: true},
+		"Error on looping name pointers": {data: concat(header, names[:len(names)-6], []byte("\xc0\x3a")), wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseClientSite(tc.data)
+			if tc.wantErr {
+				require.Error(t, err, "parseClientSite should have failed but didn't")
+				return
+			}
+			require.NoError(t, err, "parseClientSite should not have failed")
+
+			require.Equal(t, tc.want, got, "parseClientSite should return the expected site")
+		})
+	}
+}
+
+// concat returns the concatenation of parts.
+func concat(parts ...[]byte) []byte {
+	var r []byte
+	for _, p := range parts {
+		r = append(r, p...)
+	}
+	return r
+}
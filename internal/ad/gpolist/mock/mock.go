@@ -0,0 +1,269 @@
+// Package mock gives an in-memory LDAP directory to list GPOs from, without any domain controller.
+package mock
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Directory is an in-memory LDAP directory. Each entry is indexed by its DN and attributes values are raw strings.
+// Only base searches and subtree searches with and, or, equality and presence filters are supported.
+type Directory struct {
+	Entries map[string]map[string][]string
+
+	// ErrSearch lists the DNs for which any search fails.
+	ErrSearch map[string]bool
+
+	// ClientSite is the site returned to LDAP pings. LDAP pings fail if it is empty.
+	ClientSite string
+}
+
+// Search returns the entries matching the request.
+func (d Directory) Search(r *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	if d.ErrSearch[strings.ToLower(r.BaseDN)] {
+		return nil, fmt.Errorf("search on %q failed as requested", r.BaseDN)
+	}
+
+	if r.BaseDN == "" && len(r.Attributes) == 1 && r.Attributes[0] == "Netlogon" {
+		if d.ClientSite == "" {
+			return nil, errors.New("LDAP ping failed as requested")
+		}
+		return &ldap.SearchResult{Entries: []*ldap.Entry{
+			ldap.NewEntry("", map[string][]string{"Netlogon": {netlogonResponse(d.ClientSite)}}),
+		}}, nil
+	}
+
+	filter, err := ldap.CompileFilter(r.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ldap.SearchResult{}
+	for dn, attrs := range d.Entries {
+		switch r.Scope {
+		case ldap.ScopeBaseObject:
+			if !strings.EqualFold(dn, r.BaseDN) {
+				continue
+			}
+		case ldap.ScopeWholeSubtree:
+			if !strings.EqualFold(dn, r.BaseDN) && !strings.HasSuffix(strings.ToLower(dn), ","+strings.ToLower(r.BaseDN)) {
+				continue
+			}
+		default:
+			return nil, fmt.Errorf("unsupported scope %d", r.Scope)
+		}
+
+		ok, err := matches(filter, attrs)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		selected := make(map[string][]string)
+		for _, a := range r.Attributes {
+			if v, ok := attrs[a]; ok {
+				selected[a] = v
+			}
+		}
+		res.Entries = append(res.Entries, ldap.NewEntry(dn, selected))
+	}
+
+	return res, nil
+}
+
+// Close does nothing.
+func (d Directory) Close() error {
+	return nil
+}
+
+// matches evaluates the compiled filter against attrs.
+func matches(filter *ber.Packet, attrs map[string][]string) (bool, error) {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, c := range filter.Children {
+			if ok, err := matches(c, attrs); err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	case ldap.FilterOr:
+		for _, c := range filter.Children {
+			if ok, err := matches(c, attrs); err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	case ldap.FilterEqualityMatch:
+		name, value := filter.Children[0].Value.(string), filter.Children[1].Value.(string)
+		for _, v := range attribute(attrs, name) {
+			if strings.EqualFold(v, value) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case ldap.FilterPresent:
+		// Every entry has an objectClass.
+		name := filter.Data.String()
+		return strings.EqualFold(name, "objectClass") || len(attribute(attrs, name)) > 0, nil
+	}
+	return false, fmt.Errorf("unsupported filter %s", ldap.FilterMap[uint64(filter.Tag)])
+}
+
+// attribute returns the values of attribute name, which is case insensitive.
+func attribute(attrs map[string][]string, name string) []string {
+	for n, v := range attrs {
+		if strings.EqualFold(n, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+// netlogonResponse returns a NETLOGON_SAM_LOGON_RESPONSE_EX message for a client in site.
+// The domain name is compressed as a pointer to the forest name, like domain controllers do.
+func netlogonResponse(site string) string {
+	// Opcode, sbz, flags and an empty domain GUID.
+	b := binary.LittleEndian.AppendUint16(nil, 23)
+	b = append(b, make([]byte, 22)...)
+
+	name := func(labels ...string) {
+		for _, l := range labels {
+			b = append(b, byte(len(l)))
+			b = append(b, l...)
+		}
+		b = append(b, 0)
+	}
+	// DnsForestName, DnsDomainName, DnsHostName, NetbiosDomainName, NetbiosComputerName, UserName, DcSiteName
+	// and ClientSiteName.
+	name("example", "com")
+	b = append(b, 0xC0, 24)
+	name("dc", "example", "com")
+	name("EXAMPLE")
+	name("DC")
+	name()
+	name("Default-First-Site-Name")
+	name(site)
+
+	return string(b)
+}
+
+// SID returns the binary form of the string SID s (S-1-5-…), as stored in objectSid.
+func SID(s string) string {
+	parts := strings.Split(s, "-")
+	if len(parts) < 3 || parts[0] != "S" {
+		panic(fmt.Sprintf("invalid SID %q", s))
+	}
+	revision, err := strconv.ParseUint(parts[1], 10, 8)
+	if err != nil {
+		panic(err)
+	}
+	authority, err := strconv.ParseUint(parts[2], 10, 48)
+	if err != nil {
+		panic(err)
+	}
+
+	b := []byte{byte(revision), byte(len(parts) - 3)}
+	for i := 5; i >= 0; i-- {
+		b = append(b, byte(authority>>(8*i)))
+	}
+	for _, p := range parts[3:] {
+		sub, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			panic(err)
+		}
+		b = binary.LittleEndian.AppendUint32(b, uint32(sub))
+	}
+	return string(b)
+}
+
+// ACE types which can be used in security descriptors.
+const (
+	AccessAllowed       = 0x00
+	AccessDenied        = 0x01
+	AccessAllowedObject = 0x05
+	AccessDeniedObject  = 0x06
+)
+
+// ACE is an access control entry of a security descriptor DACL.
+type ACE struct {
+	Type  uint8
+	Flags uint8
+	Mask  uint32
+	// ObjectType is the GUID of the right the entry applies to, for object entries.
+	ObjectType string
+	SID        string
+}
+
+// SecurityDescriptor returns the binary form of a self-relative security descriptor, as stored in
+// nTSecurityDescriptor, with owner and a DACL made of aces.
+func SecurityDescriptor(owner string, aces ...ACE) string {
+	ownerSID := SID(owner)
+
+	var acl []byte
+	for _, a := range aces {
+		body := binary.LittleEndian.AppendUint32(nil, a.Mask)
+		if a.Type == AccessAllowedObject || a.Type == AccessDeniedObject {
+			var flags uint32
+			var guid []byte
+			if a.ObjectType != "" {
+				flags = 1
+				var err error
+				if guid, err = guidBytes(a.ObjectType); err != nil {
+					panic(err)
+				}
+			}
+			body = binary.LittleEndian.AppendUint32(body, flags)
+			body = append(body, guid...)
+		}
+		body = append(body, SID(a.SID)...)
+
+		acl = append(acl, a.Type, a.Flags)
+		acl = binary.LittleEndian.AppendUint16(acl, uint16(4+len(body)))
+		acl = append(acl, body...)
+	}
+	dacl := []byte{2, 0}
+	dacl = binary.LittleEndian.AppendUint16(dacl, uint16(8+len(acl)))
+	dacl = binary.LittleEndian.AppendUint16(dacl, uint16(len(aces)))
+	dacl = append(dacl, 0, 0)
+	dacl = append(dacl, acl...)
+
+	// Header, then owner and DACL. Group and SACL are not set.
+	const headerSize = 20
+	sd := []byte{1, 0}
+	// Self relative and DACL present.
+	sd = binary.LittleEndian.AppendUint16(sd, 0x8000|0x0004)
+	sd = binary.LittleEndian.AppendUint32(sd, headerSize)
+	sd = binary.LittleEndian.AppendUint32(sd, 0)
+	sd = binary.LittleEndian.AppendUint32(sd, 0)
+	sd = binary.LittleEndian.AppendUint32(sd, uint32(headerSize+len(ownerSID)))
+	sd = append(sd, ownerSID...)
+	sd = append(sd, dacl...)
+
+	return string(sd)
+}
+
+// guidBytes returns the binary form of a GUID string, whose first 3 fields are little endian.
+func guidBytes(s string) ([]byte, error) {
+	var a uint32
+	var b, c uint16
+	var d, e uint64
+	if _, err := fmt.Sscanf(s, "%08x-%04x-%04x-%04x-%012x", &a, &b, &c, &d, &e); err != nil {
+		return nil, errors.New("invalid GUID " + s)
+	}
+	r := binary.LittleEndian.AppendUint32(nil, a)
+	r = binary.LittleEndian.AppendUint16(r, b)
+	r = binary.LittleEndian.AppendUint16(r, c)
+	r = binary.BigEndian.AppendUint16(r, uint16(d))
+	for i := 5; i >= 0; i-- {
+		r = append(r, byte(e>>(8*i)))
+	}
+	return r, nil
+}
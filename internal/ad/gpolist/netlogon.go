@@ -0,0 +1,129 @@
+package gpolist
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/leonelquinteros/gotext"
+)
+
+/* From MS-ADTS */
+const (
+	// ntVersionFilter requests a NETLOGON_SAM_LOGON_RESPONSE_EX: NETLOGON_NT_VERSION_5 | NETLOGON_NT_VERSION_5EX, as
+	// an escaped little endian 32 bits value.
+	ntVersionFilter = `\06\00\00\00`
+
+	logonSAMLogonResponseEx = 23
+	logonSAMUserUnknownEx   = 25
+
+	// netlogonHeaderSize is the size of the opcode, sbz, flags and domain GUID fields preceding the names.
+	netlogonHeaderSize = 24
+	// clientSiteNameIndex is the position of ClientSiteName in the response names, after DnsForestName,
+	// DnsDomainName, DnsHostName, NetbiosDomainName, NetbiosComputerName, UserName and DcSiteName.
+	clientSiteNameIndex = 7
+)
+
+// siteDN returns the DN of the site of the client in the configuration naming context configNC, or an empty string if
+// the client is not in any site.
+func siteDN(dir Directory, baseDN, configNC string) (string, error) {
+	if configNC == "" {
+		return "", errors.New(gotext.Get("no configuration naming context on LDAP server"))
+	}
+
+	site, err := clientSite(dir, dnsDomain(baseDN))
+	if err != nil || site == "" {
+		return "", err
+	}
+
+	return fmt.Sprintf("CN=%s,CN=Sites,%s", ldap.EscapeDN(site), configNC), nil
+}
+
+// clientSite returns the site name the domain controller computed from the client address, in response to an LDAP ping.
+func clientSite(dir Directory, domain string) (string, error) {
+	filter := fmt.Sprintf("(&(DnsDomain=%s)(NtVer=%s))", ldap.EscapeFilter(domain), ntVersionFilter)
+	res, err := dir.Search(ldap.NewSearchRequest("", ldap.ScopeBaseObject, ldap.NeverDerefAliases, 0, 0, false,
+		filter, []string{"Netlogon"}, nil))
+	if err != nil {
+		return "", errors.New(gotext.Get("LDAP ping failed: %v", err))
+	}
+	if len(res.Entries) == 0 || len(res.Entries[0].GetRawAttributeValue("Netlogon")) == 0 {
+		return "", errors.New(gotext.Get("no netlogon response to LDAP ping"))
+	}
+
+	return parseClientSite(res.Entries[0].GetRawAttributeValue("Netlogon"))
+}
+
+// parseClientSite returns the client site name of a NETLOGON_SAM_LOGON_RESPONSE_EX message.
+func parseClientSite(data []byte) (site string, err error) {
+	if len(data) < netlogonHeaderSize {
+		return "", errors.New(gotext.Get("netlogon response is too short: %d bytes", len(data)))
+	}
+	if opcode := binary.LittleEndian.Uint16(data[0:2]); opcode != logonSAMLogonResponseEx && opcode != logonSAMUserUnknownEx {
+		return "", errors.New(gotext.Get("unexpected netlogon response opcode %d", opcode))
+	}
+
+	off := netlogonHeaderSize
+	for i := 0; i <= clientSiteNameIndex; i++ {
+		if site, off, err = parseCompressedName(data, off); err != nil {
+			return "", errors.New(gotext.Get("invalid name %d in netlogon response: %v", i, err))
+		}
+	}
+	return site, nil
+}
+
+// parseCompressedName decodes the RFC 1035 compressed name starting at off in data and returns it with the offset
+// following it.
+func parseCompressedName(data []byte, off int) (name string, next int, err error) {
+	var labels []string
+	next = -1
+	// Each pointer jumps backward: more jumps than bytes means we are looping.
+	for jumps := 0; jumps <= len(data); {
+		if off >= len(data) {
+			return "", 0, errors.New(gotext.Get("name is out of bounds"))
+		}
+		l := int(data[off])
+		switch {
+		case l == 0:
+			if next < 0 {
+				next = off + 1
+			}
+			return strings.Join(labels, "."), next, nil
+		case l&0xC0 == 0xC0:
+			if off+1 >= len(data) {
+				return "", 0, errors.New(gotext.Get("name pointer is out of bounds"))
+			}
+			if next < 0 {
+				next = off + 2
+			}
+			off = (l&0x3F)<<8 | int(data[off+1])
+			jumps++
+		default:
+			if off+1+l > len(data) {
+				return "", 0, errors.New(gotext.Get("name label is out of bounds"))
+			}
+			labels = append(labels, string(data[off+1:off+1+l]))
+			off += 1 + l
+		}
+	}
+	return "", 0, errors.New(gotext.Get("name pointers are looping"))
+}
+
+// dnsDomain returns the DNS name of the domain baseDN: DC=example,DC=com is example.com.
+func dnsDomain(baseDN string) string {
+	dn, err := ldap.ParseDN(baseDN)
+	if err != nil {
+		return ""
+	}
+	var parts []string
+	for _, rdn := range dn.RDNs {
+		for _, a := range rdn.Attributes {
+			if strings.EqualFold(a.Type, "DC") {
+				parts = append(parts, a.Value)
+			}
+		}
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,230 @@
+package gpolist
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/leonelquinteros/gotext"
+)
+
+/* From winnt.h */
+const (
+	aceTypeAccessAllowed       = 0x00
+	aceTypeAccessDenied        = 0x01
+	aceTypeAccessAllowedObject = 0x05
+	aceTypeAccessDeniedObject  = 0x06
+
+	aceFlagInheritOnly = 0x08
+
+	aceObjectTypePresent          = 0x1
+	aceInheritedObjectTypePresent = 0x2
+
+	rightReadControl = 0x00020000
+	rightDsList      = 0x00000004
+	rightDsReadProp  = 0x00000010
+	// rightDsListObject is part of the generic read mapping of directory objects.
+	rightDsListObject = 0x00000080
+	rightGenericAll   = 0x10000000
+	rightGenericRead  = 0x80000000
+)
+
+// ace is an access control entry of a DACL.
+type ace struct {
+	aceType uint8
+	flags   uint8
+	mask    uint32
+	// objectType is the GUID of the right or property the entry applies to, if any.
+	objectType string
+	sid        string
+}
+
+// securityDescriptor is the part of a self-relative security descriptor needed to check GPOs rights.
+type securityDescriptor struct {
+	owner string
+	// dacl is nil if the object has no DACL, which grants full access to everyone.
+	dacl []ace
+}
+
+// parseSecurityDescriptor decodes a self-relative security descriptor, as stored in nTSecurityDescriptor.
+func parseSecurityDescriptor(data []byte) (sd securityDescriptor, err error) {
+	if len(data) < 20 {
+		return sd, errors.New(gotext.Get("security descriptor is too short: %d bytes", len(data)))
+	}
+	ownerOffset := binary.LittleEndian.Uint32(data[4:8])
+	daclOffset := binary.LittleEndian.Uint32(data[16:20])
+
+	if ownerOffset != 0 {
+		if sd.owner, _, err = parseSID(data, int(ownerOffset)); err != nil {
+			return sd, err
+		}
+	}
+	if daclOffset == 0 {
+		return sd, nil
+	}
+
+	off := int(daclOffset)
+	if off+8 > len(data) {
+		return sd, errors.New(gotext.Get("DACL is out of security descriptor bounds"))
+	}
+	aceCount := int(binary.LittleEndian.Uint16(data[off+4 : off+6]))
+	off += 8
+
+	sd.dacl = make([]ace, 0, aceCount)
+	for i := 0; i < aceCount; i++ {
+		if off+4 > len(data) {
+			return sd, errors.New(gotext.Get("ACE %d is out of security descriptor bounds", i))
+		}
+		aceSize := int(binary.LittleEndian.Uint16(data[off+2 : off+4]))
+		if aceSize < 8 || off+aceSize > len(data) {
+			return sd, errors.New(gotext.Get("ACE %d has an invalid size: %d", i, aceSize))
+		}
+		a, err := parseACE(data[off : off+aceSize])
+		if err != nil {
+			return sd, errors.New(gotext.Get("invalid ACE %d: %v", i, err))
+		}
+		sd.dacl = append(sd.dacl, a)
+		off += aceSize
+	}
+
+	return sd, nil
+}
+
+// parseACE decodes an access control entry. Entries which are not access allowed or denied ones are returned with
+// only their type and flags.
+func parseACE(data []byte) (a ace, err error) {
+	a.aceType = data[0]
+	a.flags = data[1]
+
+	switch a.aceType {
+	case aceTypeAccessAllowed, aceTypeAccessDenied:
+		a.mask = binary.LittleEndian.Uint32(data[4:8])
+		a.sid, _, err = parseSID(data, 8)
+		return a, err
+	case aceTypeAccessAllowedObject, aceTypeAccessDeniedObject:
+		if len(data) < 12 {
+			return a, errors.New(gotext.Get("object ACE is too short"))
+		}
+		a.mask = binary.LittleEndian.Uint32(data[4:8])
+		objectFlags := binary.LittleEndian.Uint32(data[8:12])
+		off := 12
+		if objectFlags&aceObjectTypePresent != 0 {
+			if off+16 > len(data) {
+				return a, errors.New(gotext.Get("object type is out of ACE bounds"))
+			}
+			a.objectType = formatGUID(data[off : off+16])
+			off += 16
+		}
+		if objectFlags&aceInheritedObjectTypePresent != 0 {
+			off += 16
+		}
+		a.sid, _, err = parseSID(data, off)
+		return a, err
+	}
+
+	// Audit, alarm and callback entries are not needed to check GPO rights.
+	return a, nil
+}
+
+// parseSID decodes the binary SID starting at off in data and returns its string form (S-1-5-…) with its length.
+func parseSID(data []byte, off int) (sid string, n int, err error) {
+	if off+8 > len(data) {
+		return "", 0, errors.New(gotext.Get("SID is out of bounds"))
+	}
+	revision := data[off]
+	subAuthorityCount := int(data[off+1])
+	n = 8 + 4*subAuthorityCount
+	if off+n > len(data) {
+		return "", 0, errors.New(gotext.Get("SID is out of bounds"))
+	}
+
+	// Identifier authority is a 48 bits big endian value.
+	var authority uint64
+	for _, b := range data[off+2 : off+8] {
+		authority = authority<<8 | uint64(b)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "S-%d-%d", revision, authority)
+	for i := 0; i < subAuthorityCount; i++ {
+		start := off + 8 + 4*i
+		fmt.Fprintf(&b, "-%d", binary.LittleEndian.Uint32(data[start:start+4]))
+	}
+	return b.String(), n, nil
+}
+
+// formatGUID returns the string form of a binary GUID, whose first 3 fields are little endian.
+func formatGUID(b []byte) string {
+	return fmt.Sprintf("%08x-%04x-%04x-%x-%x",
+		binary.LittleEndian.Uint32(b[0:4]),
+		binary.LittleEndian.Uint16(b[4:6]),
+		binary.LittleEndian.Uint16(b[6:8]),
+		b[8:10], b[10:16])
+}
+
+// canRead returns true if the token, a list of SIDs, grants read access to the object protected by sd.
+// Rights are evaluated in DACL order: the first entry allowing or denying a right wins.
+func (sd securityDescriptor) canRead(token map[string]struct{}) bool {
+	const wanted = rightReadControl | rightDsList | rightDsReadProp
+
+	if sd.dacl == nil {
+		return true
+	}
+
+	var granted, denied uint32
+	// The owner can always read the security descriptor.
+	if _, ok := token[sd.owner]; ok {
+		granted |= rightReadControl
+	}
+
+	for _, a := range sd.dacl {
+		// Inherit only entries don't apply to the object itself, and rights on specific properties don't grant
+		// reading the whole object.
+		if a.flags&aceFlagInheritOnly != 0 || a.objectType != "" {
+			continue
+		}
+		if _, ok := token[a.sid]; !ok {
+			continue
+		}
+		mask := mapGenericRights(a.mask)
+		switch a.aceType {
+		case aceTypeAccessAllowed, aceTypeAccessAllowedObject:
+			granted |= mask &^ denied
+		case aceTypeAccessDenied, aceTypeAccessDeniedObject:
+			denied |= mask &^ granted
+		}
+	}
+
+	return granted&wanted == wanted
+}
+
+// mapGenericRights converts the generic rights of mask to the ones specific to directory objects.
+func mapGenericRights(mask uint32) uint32 {
+	if mask&(rightGenericAll|rightGenericRead) != 0 {
+		mask |= rightReadControl | rightDsList | rightDsReadProp | rightDsListObject
+	}
+	return mask
+}
+
+// canApply returns true if one of the sids is allowed to apply the GPO protected by sd, and none is denied.
+func (sd securityDescriptor) canApply(sids map[string]struct{}) bool {
+	var applied bool
+	for _, a := range sd.dacl {
+		if a.flags&aceFlagInheritOnly != 0 || a.objectType != gpoApplyGUID {
+			continue
+		}
+		if _, ok := sids[a.sid]; !ok {
+			continue
+		}
+
+		switch a.aceType {
+		case aceTypeAccessAllowedObject:
+			applied = true
+		case aceTypeAccessDeniedObject:
+			// One denial is enough for denying the whole policy.
+			return false
+		}
+	}
+	return applied
+}
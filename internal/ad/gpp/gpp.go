@@ -0,0 +1,176 @@
+// Package gpp handles parsing Group Policy Preferences files (Drives.xml, Groups.xml…)
+// to convert them to comprehensible entries datastructure for adsys to consume.
+//
+// Each preference item is converted to an entry:
+//   - its key is in the form <Element>/<name>, like "Drive/H:";
+//   - its value lists the attributes of the item properties, one per line in the form <attribute>=<value>,
+//     in the file order. The action attribute is not listed, and each member of a group is listed as
+//     member=<action>:<name>;
+//   - its metadata is the action letter of the item: C (Create), R (Replace), U (Update) or D (Delete);
+//   - it is disabled when the item deletes its target.
+//
+// Disabled items and items whose item-level targeting doesn't match are skipped. Items to apply once which were
+// already applied keep the entry they were first applied with.
+package gpp
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/leonelquinteros/gotext"
+	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/decorate"
+)
+
+// Action is the action a preference item does on its target.
+type Action string
+
+const (
+	// Create creates the target if it doesn't exist.
+	Create Action = "C"
+	// Replace deletes and recreates the target.
+	Replace Action = "R"
+	// Update modifies the target, creating it if it doesn't exist.
+	Update Action = "U"
+	// Delete deletes the target.
+	Delete Action = "D"
+)
+
+type collection struct {
+	Items []item `xml:",any"`
+}
+
+type item struct {
+	XMLName    xml.Name
+	Name       string     `xml:"name,attr"`
+	Disabled   string     `xml:"disabled,attr"`
+	Properties properties `xml:"Properties"`
+	Filters    struct {
+		Filters []filter `xml:",any"`
+	} `xml:"Filters"`
+}
+
+type properties struct {
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Members []struct {
+		Name   string `xml:"name,attr"`
+		Action string `xml:"action,attr"`
+	} `xml:"Members>Member"`
+}
+
+type options struct {
+	appliedOnce func(id string, e entry.Entry) entry.Entry
+	target      *Target
+}
+
+// Option reprents an optional function to change Decode behavior.
+type Option func(*options)
+
+// WithAppliedOnce specifies the function called with the identifier and the entry of each item to apply once.
+// It returns the entry to use, which is the one the item was first applied with if it was already applied.
+func WithAppliedOnce(appliedOnce func(id string, e entry.Entry) entry.Entry) Option {
+	return func(o *options) {
+		o.appliedOnce = appliedOnce
+	}
+}
+
+// WithTarget specifies the machine and account items are applied to. Items whose item-level targeting doesn't
+// match the target are skipped. Without any target, item-level targeting is ignored.
+func WithTarget(t Target) Option {
+	return func(o *options) {
+		o.target = &t
+	}
+}
+
+// Decode parses a Group Policy Preferences file stream and returns a slice of entries.
+// An entry with an unknown action has its Err field set.
+func Decode(r io.Reader, opts ...Option) (entries []entry.Entry, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't parse preferences"))
+
+	var args options
+	for _, o := range opts {
+		o(&args)
+	}
+
+	var c collection
+	if err := xml.NewDecoder(r).Decode(&c); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	for _, i := range c.Items {
+		if i.Disabled == "1" {
+			continue
+		}
+		if args.target != nil {
+			match, err := args.target.match(i.Filters.Filters)
+			if err != nil {
+				return nil, errors.New(gotext.Get("%s/%s: %v", i.XMLName.Local, i.Name, err))
+			}
+			if !match {
+				continue
+			}
+		}
+		// Update is the default action when none is set.
+		action := Update
+		var values []string
+		for _, a := range i.Properties.Attrs {
+			if a.Name.Local == "action" {
+				if a.Value != "" {
+					action = Action(a.Value)
+				}
+				continue
+			}
+			values = append(values, fmt.Sprintf("%s=%s", a.Name.Local, a.Value))
+		}
+		for _, m := range i.Properties.Members {
+			values = append(values, fmt.Sprintf("member=%s:%s", m.Action, m.Name))
+		}
+
+		e := entry.Entry{
+			Key:      i.XMLName.Local + "/" + i.Name,
+			Value:    strings.Join(values, "\n"),
+			Meta:     string(action),
+			Disabled: action == Delete,
+		}
+		switch action {
+		case Create, Replace, Update, Delete:
+		default:
+			e.Err = errors.New(gotext.Get("%s: unknown action %q", e.Key, action))
+		}
+		// Items are only marked as applied once if they are targeted.
+		if runOnce := runOnceFilter(i.Filters.Filters); runOnce != nil && args.appliedOnce != nil {
+			e = args.appliedOnce(runOnce.attr("id"), e)
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// Property returns the value of the attribute name of the properties of the item decoded as e.
+// It returns an empty string if the item has no such attribute.
+func Property(e entry.Entry, name string) string {
+	if values := Properties(e, name); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Properties returns all the values of the attribute name of the properties of the item decoded as e,
+// like the members of a group.
+func Properties(e entry.Entry, name string) (values []string) {
+	for _, l := range strings.Split(e.Value, "\n") {
+		n, v, found := strings.Cut(l, "=")
+		if !found || n != name {
+			continue
+		}
+		values = append(values, v)
+	}
+	return values
+}
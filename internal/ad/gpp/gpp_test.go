@@ -0,0 +1,312 @@
+package gpp_test
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/ubuntu/adsys/internal/ad/gpp"
+	"github.com/ubuntu/adsys/internal/policies/entry"
+)
+
+func TestDecode(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		appliedOnce map[string]entry.Entry
+
+		want    []entry.Entry
+		wantErr bool
+	}{
+		"drives": {want: []entry.Entry{
+			{Key: "Drive/H:", Value: `thisDrive=NOCHANGE
+allDrives=NOCHANGE
+userName=
+path=\\fs.example.com\home
+label=Home
+persistent=1
+useLetter=1
+letter=H`, Meta: "U"},
+			{Key: "Drive/P:", Value: `thisDrive=NOCHANGE
+allDrives=NOCHANGE
+userName=
+path=\\fs.example.com\projects
+label=Projects
+persistent=0
+useLetter=1
+letter=P`, Meta: "C"},
+		}},
+		"groups": {want: []entry.Entry{
+			{Key: "Group/sudo", Value: `newName=
+description=
+deleteAllUsers=0
+deleteAllGroups=0
+removeAccounts=0
+groupName=sudo
+member=ADD:EXAMPLE\alice
+member=REMOVE:EXAMPLE\bob`, Meta: "U"},
+		}},
+		"environment variables": {want: []entry.Entry{
+			{Key: "EnvironmentVariable/EDITOR", Value: "name=EDITOR\nvalue=vim\nuser=0\npartial=0", Meta: "U"},
+			{Key: "EnvironmentVariable/OLD_PROXY", Value: "name=OLD_PROXY\nvalue=\nuser=0\npartial=0", Meta: "D", Disabled: true},
+		}},
+		"files": {want: []entry.Entry{
+			{Key: "File/motd", Value: `fromPath=\\fs.example.com\config\motd
+targetPath=/etc/motd
+readOnly=0
+archive=1
+hidden=0
+suppress=0`, Meta: "R"},
+		}},
+		"shortcuts": {want: []entry.Entry{
+			{Key: "Shortcut/Intranet", Value: `pidl=
+targetType=URL
+comment=
+shortcutKey=0
+startIn=
+arguments=
+iconIndex=0
+targetPath=https://intranet.example.com
+iconPath=
+window=
+shortcutPath=%DesktopDir%\Intranet`, Meta: "U"},
+		}},
+		"no action defaults to update": {want: []entry.Entry{{Key: "Drive/H:", Value: `path=\\fs.example.com\home`, Meta: "U"}}},
+		"disabled items are skipped":   {want: []entry.Entry{{Key: "Drive/P:", Value: `path=\\fs.example.com\projects`, Meta: "U"}}},
+
+		// Apply once
+		"apply once items are returned when not applied yet": {want: []entry.Entry{
+			{Key: "Drive/H:", Value: `path=\\fs.example.com\home`, Meta: "U"},
+			{Key: "Drive/P:", Value: `path=\\fs.example.com\projects`, Meta: "U"},
+			{Key: "Drive/S:", Value: `path=\\fs.example.com\shared`, Meta: "U"},
+		}},
+		"apply once items already applied keep their first applied entry": {
+			appliedOnce: map[string]entry.Entry{"{AAAAAAAA-1111-4111-8111-AAAAAAAAAAAA}": {Key: "Drive/H:", Value: `path=\\fs.example.com\users`, Meta: "U"}},
+			want: []entry.Entry{
+				{Key: "Drive/H:", Value: `path=\\fs.example.com\users`, Meta: "U"},
+				{Key: "Drive/P:", Value: `path=\\fs.example.com\projects`, Meta: "U"},
+				{Key: "Drive/S:", Value: `path=\\fs.example.com\shared`, Meta: "U"},
+			}},
+
+		// Empty files
+		"no items":   {},
+		"empty file": {},
+
+		// Error cases
+		"unknown action":       {want: []entry.Entry{{Key: "Drive/H:", Value: `path=\\fs.example.com\home`, Meta: "X", Err: errors.New(`Drive/H:: unknown action "X"`)}}},
+		"error on invalid xml": {wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			fileName := name
+			if strings.HasPrefix(name, "apply once") {
+				fileName = "apply once"
+			}
+			f, err := os.Open(preferencesFilePath(fileName))
+			require.NoError(t, err, "Setup: can't open preferences file")
+			defer f.Close()
+
+			var runOnceIDs []string
+			got, err := gpp.Decode(f, gpp.WithAppliedOnce(func(id string, e entry.Entry) entry.Entry {
+				runOnceIDs = append(runOnceIDs, id)
+				if applied, ok := tc.appliedOnce[id]; ok {
+					return applied
+				}
+				return e
+			}))
+			if tc.wantErr {
+				require.Error(t, err, "Decode should have errored out")
+				return
+			}
+			require.NoError(t, err, "Decode should not have errored out")
+
+			require.Equal(t, tc.want, got, "Decode returns the expected entries")
+			if strings.HasPrefix(name, "apply once") {
+				require.Equal(t, []string{"{AAAAAAAA-1111-4111-8111-AAAAAAAAAAAA}", "{BBBBBBBB-2222-4222-8222-BBBBBBBBBBBB}"}, runOnceIDs,
+					"Decode checks all items to apply once")
+			}
+		})
+	}
+}
+
+func TestProperties(t *testing.T) {
+	t.Parallel()
+
+	e := entry.Entry{Key: "Group/sudo", Value: "groupName=sudo\ndescription=\nmember=ADD:EXAMPLE\\alice\nmember=REMOVE:EXAMPLE\\bob"}
+
+	tests := map[string]struct {
+		name string
+
+		want       string
+		wantValues []string
+	}{
+		"single property":       {name: "groupName", want: "sudo", wantValues: []string{"sudo"}},
+		"empty property":        {name: "description", want: "", wantValues: []string{""}},
+		"multi-valued property": {name: "member", want: `ADD:EXAMPLE\alice`, wantValues: []string{`ADD:EXAMPLE\alice`, `REMOVE:EXAMPLE\bob`}},
+		"missing property":      {name: "newName", want: "", wantValues: nil},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.want, gpp.Property(e, tc.name), "Property returns the first value of the property")
+			require.Equal(t, tc.wantValues, gpp.Properties(e, tc.name), "Properties returns all values of the property")
+		})
+	}
+}
+
+func preferencesFilePath(name string) string {
+	return filepath.Join("testdata", strings.ReplaceAll(name, " ", "_")+".xml")
+}
+
+func TestTargeting(t *testing.T) {
+	t.Parallel()
+
+	const (
+		bobDN  = "CN=bob,OU=Dev,OU=IT,DC=example,DC=com"
+		devsID = "S-1-5-21-1-2-3-1200"
+	)
+
+	tests := map[string]struct {
+		filters    string
+		noTarget   bool
+		accountErr bool
+
+		want    bool
+		wantErr bool
+	}{
+		"No targeting": {want: true},
+
+		// Security groups
+		"User in security group":                {filters: `<FilterGroup bool="AND" not="0" name="EXAMPLE\devs" sid="S-1-5-21-1-2-3-1200" userContext="1"/>`, want: true},
+		"User not in security group":            {filters: `<FilterGroup bool="AND" not="0" name="EXAMPLE\sales" sid="S-1-5-21-1-2-3-1300" userContext="1"/>`},
+		"Computer in security group":            {filters: `<FilterGroup bool="AND" not="0" name="EXAMPLE\Domain Computers" sid="S-1-5-21-1-2-3-515" userContext="0"/>`, want: true},
+		"Security group without SID":            {filters: `<FilterGroup bool="AND" not="0" name="EXAMPLE\devs" sid="" userContext="1"/>`},
+		"Local security group is not supported": {filters: `<FilterGroup bool="AND" not="0" name="BUILTIN\Administrators" sid="S-1-5-32-544" userContext="1" localGroup="1"/>`},
+
+		// Organizational units
+		"User in OU":                        {filters: `<FilterOrgUnit bool="AND" not="0" name="OU=IT,DC=example,DC=com" userContext="1" directMember="0"/>`, want: true},
+		"User directly in OU":               {filters: `<FilterOrgUnit bool="AND" not="0" name="ou=dev,ou=it,dc=example,dc=com" userContext="1" directMember="1"/>`, want: true},
+		"User not directly in OU":           {filters: `<FilterOrgUnit bool="AND" not="0" name="OU=IT,DC=example,DC=com" userContext="1" directMember="1"/>`},
+		"User not in OU":                    {filters: `<FilterOrgUnit bool="AND" not="0" name="OU=Sales,DC=example,DC=com" userContext="1" directMember="0"/>`},
+		"OU which is only a suffix of name": {filters: `<FilterOrgUnit bool="AND" not="0" name="OU=T,DC=example,DC=com" userContext="1" directMember="0"/>`},
+		"Computer in OU":                    {filters: `<FilterOrgUnit bool="AND" not="0" name="OU=Computers,DC=example,DC=com" userContext="0" directMember="0"/>`, want: true},
+
+		// Computer name
+		"Computer NetBIOS name":    {filters: `<FilterComputer bool="AND" not="0" type="NETBIOS" name="MYCOMPUTER"/>`, want: true},
+		"Computer DNS name":        {filters: `<FilterComputer bool="AND" not="0" type="DNS" name="mycomputer.example.com"/>`, want: true},
+		"Other computer name":      {filters: `<FilterComputer bool="AND" not="0" type="NETBIOS" name="OTHER"/>`},
+		"Computer name is not set": {filters: `<FilterComputer bool="AND" not="0" type="NETBIOS" name=""/>`},
+
+		// IP range
+		"IPv4 in range":     {filters: `<FilterIpRange bool="AND" not="0" min="10.0.0.1" max="10.0.0.254" useIPv6="0"/>`, want: true},
+		"IPv4 out of range": {filters: `<FilterIpRange bool="AND" not="0" min="10.1.0.1" max="10.1.0.254" useIPv6="0"/>`},
+		"IPv6 in range":     {filters: `<FilterIpRange bool="AND" not="0" min="fd00::1" max="fd00::ffff" useIPv6="1"/>`, want: true},
+		"Full IPv4 range":   {filters: `<FilterIpRange bool="AND" not="0" min="0.0.0.0" max="255.255.255.255" useIPv6="0"/>`, want: true},
+		"Invalid IP range":  {filters: `<FilterIpRange bool="AND" not="0" min="notanip" max="10.0.0.254" useIPv6="0"/>`},
+
+		// Operating system
+		"OS version":                    {filters: `<FilterOs bool="AND" not="0" class="NT" version="24.04" type="NE" edition="NE" sp="NE"/>`, want: true},
+		"OS name":                       {filters: `<FilterOs bool="AND" not="0" class="NT" version="Ubuntu 24.04 LTS" type="NE" edition="NE" sp="NE"/>`, want: true},
+		"Windows version never matches": {filters: `<FilterOs bool="AND" not="0" class="NT" version="WIN10" type="NE" edition="NE" sp="NE"/>`},
+
+		// Environment variable
+		"Environment variable":             {filters: `<FilterVariable bool="AND" not="0" variableName="SITE" value="paris"/>`, want: true},
+		"Environment variable other value": {filters: `<FilterVariable bool="AND" not="0" variableName="SITE" value="london"/>`},
+		"Environment variable is not set":  {filters: `<FilterVariable bool="AND" not="0" variableName="DOESNOTEXIST" value=""/>`},
+
+		// File exists
+		"File exists":                     {filters: `<FilterFile bool="AND" not="0" path="/etc/adsys-marker" type="EXISTS" folder="0"/>`, want: true},
+		"Folder exists":                   {filters: `<FilterFile bool="AND" not="0" path="/etc" type="EXISTS" folder="1"/>`, want: true},
+		"File does not exist":             {filters: `<FilterFile bool="AND" not="0" path="/etc/doesnotexist" type="EXISTS" folder="0"/>`},
+		"File is a folder":                {filters: `<FilterFile bool="AND" not="0" path="/etc" type="EXISTS" folder="0"/>`},
+		"Windows file path never matches": {filters: `<FilterFile bool="AND" not="0" path="C:\Windows" type="EXISTS" folder="1"/>`},
+		"File version is not supported":   {filters: `<FilterFile bool="AND" not="0" path="/etc/adsys-marker" type="VERSION" folder="0"/>`},
+
+		// Logical operators
+		"Negated targeting item": {filters: `<FilterComputer bool="AND" not="1" type="NETBIOS" name="OTHER"/>`, want: true},
+		"All AND items match": {filters: `<FilterComputer bool="AND" not="0" type="NETBIOS" name="MYCOMPUTER"/>
+			<FilterVariable bool="AND" not="0" variableName="SITE" value="paris"/>`, want: true},
+		"One AND item does not match": {filters: `<FilterComputer bool="AND" not="0" type="NETBIOS" name="MYCOMPUTER"/>
+			<FilterVariable bool="AND" not="0" variableName="SITE" value="london"/>`},
+		"One OR item matches": {filters: `<FilterComputer bool="AND" not="0" type="NETBIOS" name="OTHER"/>
+			<FilterVariable bool="OR" not="0" variableName="SITE" value="paris"/>`, want: true},
+		"Items are evaluated in order": {filters: `<FilterComputer bool="AND" not="0" type="NETBIOS" name="MYCOMPUTER"/>
+			<FilterVariable bool="OR" not="0" variableName="SITE" value="london"/>
+			<FilterComputer bool="AND" not="0" type="NETBIOS" name="OTHER"/>`},
+		"Collection": {filters: `<FilterComputer bool="AND" not="0" type="NETBIOS" name="MYCOMPUTER"/>
+			<FilterCollection bool="AND" not="0">
+				<FilterVariable bool="AND" not="0" variableName="SITE" value="london"/>
+				<FilterVariable bool="OR" not="0" variableName="SITE" value="paris"/>
+			</FilterCollection>`, want: true},
+		"Negated collection": {filters: `<FilterCollection bool="AND" not="1">
+				<FilterComputer bool="AND" not="0" type="NETBIOS" name="MYCOMPUTER"/>
+			</FilterCollection>`},
+		"Empty collection matches":            {filters: `<FilterCollection bool="AND" not="0"/>`, want: true},
+		"Apply once is not a targeting item":  {filters: `<FilterRunOnce hidden="1" not="0" bool="AND" id="{AAAAAAAA-1111-4111-8111-AAAAAAAAAAAA}"/>`, want: true},
+		"Unsupported targeting item":          {filters: `<FilterBattery bool="AND" not="0"/>`},
+		"Targeting is ignored without target": {filters: `<FilterComputer bool="AND" not="0" type="NETBIOS" name="OTHER"/>`, noTarget: true, want: true},
+
+		// Error cases
+		"Error on account lookup failure": {filters: `<FilterGroup bool="AND" not="0" name="EXAMPLE\devs" sid="S-1-5-21-1-2-3-1200" userContext="1"/>`, accountErr: true, wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			root := t.TempDir()
+			require.NoError(t, os.MkdirAll(filepath.Join(root, "etc"), 0750), "Setup: can't create etc directory")
+			require.NoError(t, os.WriteFile(filepath.Join(root, "etc", "adsys-marker"), nil, 0600), "Setup: can't create marker file")
+
+			target := gpp.Target{
+				Hostname:    "mycomputer.example.com",
+				IPs:         []net.IP{net.ParseIP("10.0.0.42"), net.ParseIP("fd00::42")},
+				OSName:      "Ubuntu 24.04 LTS",
+				OSVersion:   "24.04",
+				Environment: map[string]string{"SITE": "Paris"},
+				Root:        root,
+				Account: func(user bool) (gpp.Account, error) {
+					if tc.accountErr {
+						return gpp.Account{}, errors.New("account lookup error")
+					}
+					if user {
+						return gpp.Account{DN: bobDN, SIDs: []string{"S-1-5-21-1-2-3-1105", devsID}}, nil
+					}
+					return gpp.Account{DN: "CN=mycomputer,OU=Computers,DC=example,DC=com", SIDs: []string{"S-1-5-21-1-2-3-515"}}, nil
+				},
+			}
+			var opts []gpp.Option
+			if !tc.noTarget {
+				opts = append(opts, gpp.WithTarget(target))
+			}
+
+			content := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<Drives clsid="{8FDDCC1A-0C3C-43cd-A6B4-71A6DF20DA8C}">
+	<Drive clsid="{935D1B74-9CB8-4e3c-9914-7DD559B7A417}" name="H:" uid="{B1B7E0D5-3D0B-4F43-9DF1-7C0A6C2A5A11}">
+		<Properties action="U" path="\\fs.example.com\home"/>
+		<Filters>%s</Filters>
+	</Drive>
+</Drives>`, tc.filters)
+
+			got, err := gpp.Decode(strings.NewReader(content), opts...)
+			if tc.wantErr {
+				require.Error(t, err, "Decode should have errored out")
+				return
+			}
+			require.NoError(t, err, "Decode should not have errored out")
+
+			if !tc.want {
+				require.Empty(t, got, "Decode should skip items whose targeting doesn't match")
+				return
+			}
+			require.Len(t, got, 1, "Decode should keep items whose targeting matches")
+		})
+	}
+}
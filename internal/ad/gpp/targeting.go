@@ -0,0 +1,180 @@
+package gpp
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Target is the machine and account preference items are applied to, to evaluate their item-level targeting.
+//
+// Supported targeting items are security groups, organizational units, computer names, IP address ranges,
+// operating systems, environment variables, file exists and collections of them. They are evaluated in order, each
+// one being combined with the result of the previous ones with its AND or OR operator. Any other targeting item
+// doesn't match.
+type Target struct {
+	// Hostname is the name of the machine, matched by computer name targeting.
+	Hostname string
+	// IPs are the addresses of the network interfaces of the machine, matched by IP address range targeting.
+	IPs []net.IP
+	// OSName and OSVersion are the PRETTY_NAME and VERSION_ID of os-release, matched by operating system targeting.
+	OSName, OSVersion string
+	// Environment are the environment variables of the machine, matched by environment variable targeting.
+	Environment map[string]string
+	// Root is the root directory of file exists targeting.
+	Root string
+	// Account returns the user account, if user is true, or the computer account otherwise, matched by security
+	// group and organizational unit targeting.
+	Account func(user bool) (Account, error)
+}
+
+// Account is a user or computer account.
+type Account struct {
+	// DN is the distinguished name of the account.
+	DN string
+	// SIDs are the SIDs of the account and of all the groups it is a member of.
+	SIDs []string
+}
+
+// filter is a targeting item of a preference item.
+type filter struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Filters []filter   `xml:",any"`
+}
+
+// attr returns the value of the attribute name of f, or an empty string if f has no such attribute.
+func (f filter) attr(name string) string {
+	for _, a := range f.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// runOnceFilter returns the filter marking the item to apply once, if any.
+func runOnceFilter(filters []filter) *filter {
+	for _, f := range filters {
+		if f.XMLName.Local == "FilterRunOnce" {
+			return &f
+		}
+	}
+	return nil
+}
+
+// match returns true if the targeting items filters match t. An empty list of targeting items matches.
+func (t Target) match(filters []filter) (bool, error) {
+	result := true
+	first := true
+	for _, f := range filters {
+		// Apply once is not a targeting item.
+		if f.XMLName.Local == "FilterRunOnce" {
+			continue
+		}
+
+		m, err := t.matchOne(f)
+		if err != nil {
+			return false, err
+		}
+		if f.attr("not") == "1" {
+			m = !m
+		}
+
+		switch {
+		case first:
+			result = m
+			first = false
+		case strings.EqualFold(f.attr("bool"), "OR"):
+			result = result || m
+		default:
+			result = result && m
+		}
+	}
+	return result, nil
+}
+
+// matchOne returns true if the targeting item f, without its negation, matches t.
+func (t Target) matchOne(f filter) (bool, error) {
+	switch f.XMLName.Local {
+	case "FilterCollection":
+		return t.match(f.Filters)
+
+	case "FilterGroup":
+		// Local groups of the machine are not supported.
+		if f.attr("localGroup") == "1" || f.attr("sid") == "" || t.Account == nil {
+			return false, nil
+		}
+		a, err := t.Account(f.attr("userContext") == "1")
+		if err != nil {
+			return false, err
+		}
+		return slices.ContainsFunc(a.SIDs, func(sid string) bool { return strings.EqualFold(sid, f.attr("sid")) }), nil
+
+	case "FilterOrgUnit":
+		if t.Account == nil {
+			return false, nil
+		}
+		a, err := t.Account(f.attr("userContext") == "1")
+		if err != nil {
+			return false, err
+		}
+		ou := strings.ToLower(f.attr("name"))
+		_, parent, _ := strings.Cut(strings.ToLower(a.DN), ",")
+		if f.attr("directMember") == "1" {
+			return parent == ou, nil
+		}
+		return parent == ou || strings.HasSuffix(parent, ","+ou), nil
+
+	case "FilterComputer":
+		name := f.attr("name")
+		shortName, _, _ := strings.Cut(t.Hostname, ".")
+		return name != "" && (strings.EqualFold(name, t.Hostname) || strings.EqualFold(name, shortName)), nil
+
+	case "FilterIpRange":
+		low, high := net.ParseIP(f.attr("min")), net.ParseIP(f.attr("max"))
+		if low == nil || high == nil {
+			return false, nil
+		}
+		for _, ip := range t.IPs {
+			if (ip.To4() == nil) != (low.To4() == nil) {
+				continue
+			}
+			if bytes.Compare(ip.To16(), low.To16()) >= 0 && bytes.Compare(ip.To16(), high.To16()) <= 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case "FilterOs":
+		// Windows versions, like WIN10, never match.
+		v := f.attr("version")
+		return v != "" && (strings.EqualFold(v, t.OSVersion) || strings.EqualFold(v, t.OSName)), nil
+
+	case "FilterVariable":
+		v, ok := t.Environment[f.attr("variableName")]
+		return ok && strings.EqualFold(v, f.attr("value")), nil
+
+	case "FilterFile":
+		p := f.attr("path")
+		// Only existence is supported, on absolute Linux paths.
+		if typ := f.attr("type"); (typ != "" && typ != "EXISTS") || !strings.HasPrefix(p, "/") {
+			return false, nil
+		}
+		fi, err := os.Stat(filepath.Join(t.Root, p))
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		return fi.IsDir() == (f.attr("folder") == "1"), nil
+	}
+
+	return false, nil
+}
@@ -2,8 +2,10 @@ package ad
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
@@ -16,7 +18,13 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/termie/go-shutil"
 	"github.com/ubuntu/adsys/internal/ad/backends/mock"
+	"github.com/ubuntu/adsys/internal/ad/gpolist"
+	gpolistmock "github.com/ubuntu/adsys/internal/ad/gpolist/mock"
+	"github.com/ubuntu/adsys/internal/ad/gpp"
+	"github.com/ubuntu/adsys/internal/policies"
+	"github.com/ubuntu/adsys/internal/policies/entry"
 	"github.com/ubuntu/adsys/internal/testutils"
+	"gopkg.in/yaml.v3"
 )
 
 func TestFetch(t *testing.T) {
@@ -535,7 +543,7 @@ func TestFetchOneGPOWhileParsingItConcurrently(t *testing.T) {
 	go func() {
 		defer wg.Done()
 		// we can’t test returned values as it’s either the old of new version of the gpo
-		_, err := adc.parseGPOs(context.Background(), orderedGPOs, UserObject)
+		_, err := adc.parseGPOs(context.Background(), orderedGPOs, UserObject, nil)
 		require.NoError(t, err, "parseGPOs returned an error but shouldn't")
 	}()
 	wg.Wait()
@@ -569,13 +577,430 @@ func TestParseGPOConcurrent(t *testing.T) {
 		go func() {
 			defer wg.Done()
 			// we can’t test returned values as it’s either the old of new version of the gpo
-			_, err := adc.parseGPOs(context.Background(), orderedGPOs, UserObject)
+			_, err := adc.parseGPOs(context.Background(), orderedGPOs, UserObject, nil)
 			require.NoError(t, err, "parseGPOs returned an error but shouldn't")
 		}()
 	}
 	wg.Wait()
 }
 
+func TestParseGPOsLoopbackMode(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		gpos        []string
+		objectClass ObjectClass
+
+		want policies.LoopbackMode
+	}{
+		"Merge mode":                         {gpos: []string{"loopback-merge"}, want: policies.LoopbackMerge},
+		"Replace mode":                       {gpos: []string{"loopback-replace"}, want: policies.LoopbackReplace},
+		"Not configured":                     {gpos: []string{"standard"}},
+		"Disabled":                           {gpos: []string{"loopback-disabled"}},
+		"Highest priority GPO wins":          {gpos: []string{"loopback-replace", "loopback-merge"}, want: policies.LoopbackReplace},
+		"GPO not configuring it are ignored": {gpos: []string{"standard", "loopback-merge"}, want: policies.LoopbackMerge},
+		"Disabled by a higher priority GPO":  {gpos: []string{"loopback-disabled", "loopback-merge"}},
+		"Only for computers":                 {gpos: []string{"loopback-merge"}, objectClass: UserObject},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if tc.objectClass == "" {
+				tc.objectClass = ComputerObject
+			}
+
+			var gpos []gpo
+			for _, n := range tc.gpos {
+				gpos = append(gpos, gpo{name: n + "-name", url: "smb://localhost/SYSVOL/gpoonly.com/Policies/" + n})
+			}
+
+			r, err := parseGPOs(context.Background(), filepath.Join("testdata", "AD", "SYSVOL", "gpoonly.com"), "", gpos, tc.objectClass, nil, nil)
+			require.NoError(t, err, "parseGPOs should not have failed")
+
+			pols := policies.Policies{GPOs: r}
+			require.Equal(t, tc.want, pols.Loopback(), "parseGPOs should return GPOs with the expected loopback mode")
+			for _, g := range r {
+				if strings.HasPrefix(g.ID, "loopback-") {
+					require.Empty(t, g.Rules, "the loopback processing mode should not be a rule")
+				}
+			}
+		})
+	}
+}
+
+func TestParseGPOsSecurityTemplate(t *testing.T) {
+	t.Parallel()
+
+	securityTemplateRules := func(minLength string) []entry.Entry {
+		return []entry.Entry{
+			{Key: "MinimumPasswordLength", Value: minLength},
+			{Key: "PasswordComplexity", Value: "1"},
+			{Key: "LockoutBadCount", Value: "5"},
+			{Key: "ResetLockoutCount", Value: "15"},
+			{Key: "LockoutDuration", Value: "30"},
+		}
+	}
+
+	tests := map[string]struct {
+		gpo         string
+		objectClass ObjectClass
+
+		want    map[string][]entry.Entry
+		wantErr bool
+	}{
+		"Security template system access settings":     {gpo: "security-template", want: map[string][]entry.Entry{"systemaccess": securityTemplateRules("12")}},
+		"Security template in uppercase class":         {gpo: "security-template-uppercase-class", want: map[string][]entry.Entry{"systemaccess": securityTemplateRules("8")}},
+		"Security template is only read for computers": {gpo: "security-template", objectClass: UserObject, want: map[string][]entry.Entry{}},
+		"No security template": {gpo: "machine-only", want: map[string][]entry.Entry{
+			"dconf": {
+				{Key: "A", Value: "machOnlyA"},
+				{Key: "D", Value: "machOnlyD"},
+			}}},
+
+		// Error cases
+		"Error on corrupted security template": {gpo: "security-template-corrupted", wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if tc.objectClass == "" {
+				tc.objectClass = ComputerObject
+			}
+
+			gpos := []gpo{{name: tc.gpo + "-name", url: "smb://localhost/SYSVOL/gpoonly.com/Policies/" + tc.gpo}}
+			r, err := parseGPOs(context.Background(), filepath.Join("testdata", "AD", "SYSVOL", "gpoonly.com"), "", gpos, tc.objectClass, nil, nil)
+			if tc.wantErr {
+				require.Error(t, err, "parseGPOs should have failed")
+				return
+			}
+			require.NoError(t, err, "parseGPOs should not have failed")
+
+			require.Len(t, r, 1, "parseGPOs should return one GPO")
+			require.Equal(t, tc.want, r[0].Rules, "parseGPOs should return the expected rules")
+		})
+	}
+}
+
+func TestParseGPOsPreferences(t *testing.T) {
+	t.Parallel()
+
+	userMounts := func(mounts ...string) map[string][]entry.Entry {
+		return map[string][]entry.Entry{"mount": {{Key: "user-mounts", Value: strings.Join(mounts, "\n"), Strategy: entry.StrategyAppend}}}
+	}
+
+	tests := map[string]struct {
+		gpo         string
+		objectClass ObjectClass
+		appliedOnce map[string]entry.Entry
+		target      *gpp.Target
+
+		want    map[string][]entry.Entry
+		wantErr bool
+	}{
+		"Computer preferences": {gpo: "preferences", want: map[string][]entry.Entry{
+			"localgroups": {{Key: "sudo", Value: "EXAMPLE\\alice\n-EXAMPLE\\bob", Strategy: entry.StrategyMerge}},
+			"environment": {
+				{Key: "PATH", Value: "/opt/tools/bin", Strategy: entry.StrategyAppend},
+				{Key: "OLD_PROXY", Disabled: true},
+			},
+		}},
+		"User preferences": {gpo: "preferences", objectClass: UserObject, want: map[string][]entry.Entry{
+			"mount":       userMounts("[krb5]smb://fs.example.com/home", "[krb5]smb://fs.example.com/projects/ubuntu")["mount"],
+			"environment": {{Key: "EDITOR", Value: "vim"}},
+		}},
+		"Items already applied once keep their first applied entry": {gpo: "preferences", objectClass: UserObject,
+			appliedOnce: map[string]entry.Entry{"{AAAAAAAA-1111-4111-8111-AAAAAAAAAAAA}": {Key: "Drive/P:", Value: `path=\\fs.example.com\projects`, Meta: "U"}},
+			want: map[string][]entry.Entry{
+				"mount":       userMounts("[krb5]smb://fs.example.com/home", "[krb5]smb://fs.example.com/projects")["mount"],
+				"environment": {{Key: "EDITOR", Value: "vim"}},
+			}},
+		"Items are filtered by their targeting": {gpo: "preferences-targeted", objectClass: UserObject, target: &gpp.Target{
+			Hostname: "hostname",
+			Account: func(bool) (gpp.Account, error) {
+				return gpp.Account{DN: "CN=bob,DC=example,DC=com", SIDs: []string{"S-1-5-21-1-2-3-1105", "S-1-5-21-1-2-3-1200"}}, nil
+			},
+		}, want: userMounts("[krb5]smb://fs.example.com/home", "[krb5]smb://fs.example.com/projects")},
+		"Preferences in uppercase class":         {gpo: "preferences-uppercase-class", objectClass: UserObject, want: userMounts("[krb5]smb://fs.example.com/shared")},
+		"Drive mappings are only read for users": {gpo: "preferences-uppercase-class", want: map[string][]entry.Entry{}},
+		"No preferences":                         {gpo: "security-template", objectClass: UserObject, want: map[string][]entry.Entry{}},
+
+		// Error cases
+		"Error on corrupted preferences":           {gpo: "preferences-corrupted", objectClass: UserObject, wantErr: true},
+		"Error on preferences item unknown action": {gpo: "preferences-unknown-action", objectClass: UserObject, wantErr: true},
+		"Error on preferences targeting failure": {gpo: "preferences-targeted", objectClass: UserObject, target: &gpp.Target{
+			Account: func(bool) (gpp.Account, error) { return gpp.Account{}, errors.New("account lookup error") },
+		}, wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if tc.objectClass == "" {
+				tc.objectClass = ComputerObject
+			}
+
+			opts := []gpp.Option{gpp.WithAppliedOnce(func(id string, e entry.Entry) entry.Entry {
+				if applied, ok := tc.appliedOnce[id]; ok {
+					return applied
+				}
+				return e
+			})}
+			if tc.target != nil {
+				opts = append(opts, gpp.WithTarget(*tc.target))
+			}
+
+			gpos := []gpo{{name: tc.gpo + "-name", url: "smb://localhost/SYSVOL/gpoonly.com/Policies/" + tc.gpo}}
+			r, err := parseGPOs(context.Background(), filepath.Join("testdata", "AD", "SYSVOL", "gpoonly.com"), "", gpos, tc.objectClass, nil, opts)
+			if tc.wantErr {
+				require.Error(t, err, "parseGPOs should have failed")
+				return
+			}
+			require.NoError(t, err, "parseGPOs should not have failed")
+
+			require.Len(t, r, 1, "parseGPOs should return one GPO")
+			require.Equal(t, tc.want, r[0].Rules, "parseGPOs should return the expected rules")
+		})
+	}
+}
+
+func TestPreferencesTarget(t *testing.T) {
+	t.Parallel()
+
+	dir := gpolistmock.Directory{Entries: map[string]map[string][]string{
+		"": {"defaultNamingContext": {"DC=example,DC=com"}},
+		"CN=bob,OU=Dev,DC=example,DC=com": {
+			"objectClass":    {"user"},
+			"samAccountName": {"bob"},
+			"objectSid":      {gpolistmock.SID("S-1-5-21-1-2-3-1105")},
+			"tokenGroups":    {gpolistmock.SID("S-1-5-21-1-2-3-1200")},
+		},
+		"CN=hostname,OU=Computers,DC=example,DC=com": {
+			"objectClass":    {"user", "computer"},
+			"samAccountName": {"hostname$"},
+			"objectSid":      {gpolistmock.SID("S-1-5-21-1-2-3-1106")},
+		},
+	}}
+	bob := gpp.Account{DN: "CN=bob,OU=Dev,DC=example,DC=com", SIDs: []string{"S-1-1-0", "S-1-5-11", "S-1-5-21-1-2-3-1105", "S-1-5-21-1-2-3-1200"}}
+	computer := gpp.Account{DN: "CN=hostname,OU=Computers,DC=example,DC=com", SIDs: []string{"S-1-1-0", "S-1-5-11", "S-1-5-21-1-2-3-1106"}}
+
+	tests := map[string]struct {
+		objectName  string
+		objectClass ObjectClass
+		noLDAP      bool
+		userContext bool
+
+		want       gpp.Account
+		wantErr    bool
+		wantAccErr bool
+	}{
+		"User account for user preferences":         {userContext: true, want: bob},
+		"Computer account for user preferences":     {want: computer},
+		"Computer account for computer preferences": {objectName: "hostname", objectClass: ComputerObject, userContext: true, want: computer},
+
+		// Error cases
+		"Error on unreadable machine facts":    {objectName: "hostname", objectClass: ComputerObject, wantErr: true},
+		"Error on account lookup without LDAP": {noLDAP: true, userContext: true, wantAccErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if tc.objectName == "" {
+				tc.objectName = "bob@example.com"
+			}
+			if tc.objectClass == "" {
+				tc.objectClass = UserObject
+			}
+
+			opt := withGPOListDirectory(dir, nil)
+			if tc.noLDAP {
+				opt = withGPOListCmd(nil)
+			}
+			factsRoot := filepath.Join("testdata", "machine")
+			if tc.wantErr {
+				factsRoot = filepath.Join("testdata", "machine-with-unreadable-facts")
+			}
+
+			adc, err := New(context.Background(), mock.Backend{Dom: "example.com"}, "hostname",
+				WithCacheDir(t.TempDir()), WithRunDir(t.TempDir()), withoutKerberos(), opt, withWMIFactsRoot(factsRoot))
+			require.NoError(t, err, "Setup: cannot create ad object")
+
+			target, err := adc.preferencesTarget(context.Background(), tc.objectName, tc.objectClass, "dc.example.com", "", nil)
+			if tc.wantErr {
+				require.Error(t, err, "preferencesTarget should have failed but didn't")
+				return
+			}
+			require.NoError(t, err, "preferencesTarget should not have failed")
+
+			require.Equal(t, "hostname", target.Hostname, "preferencesTarget should target the machine")
+			require.Equal(t, "Ubuntu 24.04 LTS", target.OSName, "preferencesTarget should read the OS name from os-release")
+			require.Equal(t, "24.04", target.OSVersion, "preferencesTarget should read the OS version from os-release")
+
+			got, err := target.Account(tc.userContext)
+			if tc.wantAccErr {
+				require.Error(t, err, "Account should have failed but didn't")
+				return
+			}
+			require.NoError(t, err, "Account should not have failed")
+			require.Equal(t, tc.want, got, "Account should return the expected account")
+		})
+	}
+}
+
+func TestRunOnceTracker(t *testing.T) {
+	t.Parallel()
+
+	a := entry.Entry{Key: "Drive/H:", Value: `path=\\fs.example.com\home`, Meta: "U"}
+	b := entry.Entry{Key: "Drive/P:", Value: `path=\\fs.example.com\projects`, Meta: "C"}
+
+	tests := map[string]struct {
+		alreadyApplied map[string]entry.Entry
+		seen           map[string]entry.Entry
+		notSaved       bool
+
+		want      map[string]entry.Entry
+		wantSaved map[string]entry.Entry
+	}{
+		"Nothing applied yet": {seen: map[string]entry.Entry{"{A}": a, "{B}": b},
+			want: map[string]entry.Entry{"{A}": a, "{B}": b}, wantSaved: map[string]entry.Entry{"{A}": a, "{B}": b}},
+		"Items already applied keep their first applied entry": {alreadyApplied: map[string]entry.Entry{"{A}": b}, seen: map[string]entry.Entry{"{A}": a},
+			want: map[string]entry.Entry{"{A}": b}, wantSaved: map[string]entry.Entry{"{A}": b}},
+		"Items not in any GPO anymore are forgotten": {alreadyApplied: map[string]entry.Entry{"{A}": a, "{C}": b}, seen: map[string]entry.Entry{"{A}": a},
+			want: map[string]entry.Entry{"{A}": a}, wantSaved: map[string]entry.Entry{"{A}": a}},
+		"Items with an error are not recorded": {seen: map[string]entry.Entry{"{A}": a, "{B}": {Key: "Drive/P:", Err: errors.New("unknown action")}},
+			want: map[string]entry.Entry{"{A}": a, "{B}": {Key: "Drive/P:", Err: errors.New("unknown action")}}, wantSaved: map[string]entry.Entry{"{A}": a}},
+		"Items are not recorded until saved": {seen: map[string]entry.Entry{"{A}": a}, notSaved: true,
+			want: map[string]entry.Entry{"{A}": a}},
+		"No items to apply once remove the file": {alreadyApplied: map[string]entry.Entry{"{A}": a}},
+		"No items to apply once and no file":     {},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			cacheDir := t.TempDir()
+			p := filepath.Join(cacheDir, RunOnceCacheBaseName, "bob@example.com")
+			if tc.alreadyApplied != nil {
+				d, err := yaml.Marshal(tc.alreadyApplied)
+				require.NoError(t, err, "Setup: can't marshal applied items")
+				require.NoError(t, os.MkdirAll(filepath.Dir(p), 0700), "Setup: can't create run once cache directory")
+				require.NoError(t, os.WriteFile(p, d, 0600), "Setup: can't write applied items")
+			}
+
+			tracker, err := newRunOnceTracker(cacheDir, "bob@example.com")
+			require.NoError(t, err, "newRunOnceTracker should not have failed")
+
+			var got map[string]entry.Entry
+			for id, e := range tc.seen {
+				if got == nil {
+					got = make(map[string]entry.Entry)
+				}
+				got[id] = tracker.appliedOnce(id, e)
+			}
+			require.Equal(t, tc.want, got, "appliedOnce should return the entries to apply")
+
+			if !tc.notSaved {
+				require.NoError(t, tracker.save(), "save should not have failed")
+			}
+			d, err := os.ReadFile(p)
+			if tc.wantSaved == nil {
+				require.ErrorIs(t, err, fs.ErrNotExist, "save should not leave any file without items to apply once")
+				return
+			}
+			require.NoError(t, err, "Teardown: can't read applied items")
+			var saved map[string]entry.Entry
+			require.NoError(t, yaml.Unmarshal(d, &saved), "Teardown: can't unmarshal applied items")
+			require.Equal(t, tc.wantSaved, saved, "save should record all items seen with the entry they were applied with")
+		})
+	}
+}
+
+func TestListGPOs(t *testing.T) {
+	t.Parallel()
+
+	const applyGUID = "edacfd8f-ffb3-11d1-b41d-00a0c968f939"
+	dir := gpolistmock.Directory{Entries: map[string]map[string][]string{
+		"": {"defaultNamingContext": {"DC=example,DC=com"}},
+		"DC=example,DC=com": {
+			"gPLink": {"[LDAP://CN={GPO1},CN=Policies,CN=System,DC=example,DC=com;2]"},
+		},
+		"CN=bob,DC=example,DC=com": {
+			"objectClass":    {"user"},
+			"samAccountName": {"bob"},
+			"objectSid":      {gpolistmock.SID("S-1-5-21-1-2-3-1105")},
+		},
+		"CN=hostname,DC=example,DC=com": {
+			"objectClass":    {"user", "computer"},
+			"samAccountName": {"hostname$"},
+			"objectSid":      {gpolistmock.SID("S-1-5-21-1-2-3-1106")},
+		},
+		"CN={GPO1},CN=Policies,CN=System,DC=example,DC=com": {
+			"displayName":    {"ldap-gpo"},
+			"gPCFileSysPath": {`\\example.com\SysVol\example.com\Policies\{GPO1}`},
+			"nTSecurityDescriptor": {gpolistmock.SecurityDescriptor("S-1-5-21-1-2-3-512",
+				gpolistmock.ACE{Type: gpolistmock.AccessAllowed, Mask: 0x20094, SID: "S-1-5-11"},
+				gpolistmock.ACE{Type: gpolistmock.AccessAllowedObject, Mask: 0x100, ObjectType: applyGUID, SID: "S-1-5-11"})},
+		},
+	}}
+	scriptCmd := []string{"sh", "-c", `printf 'script-gpo\tsmb://dc.example.com/SysVol/example.com/Policies/{GPO2}\n'`}
+
+	tests := map[string]struct {
+		dir              gpolist.Directory
+		noLDAP           bool
+		failedCmd        bool
+		cmd              []string
+		loopbackComputer string
+
+		want    string
+		wantErr bool
+	}{
+		"List from LDAP": {dir: dir, failedCmd: true, want: "ldap-gpo\tsmb://dc.example.com/SysVol/example.com/Policies/{GPO1}\tDC=example,DC=com\tenforced\n"},
+		"Fallback to script when LDAP is unreachable":     {want: "script-gpo\tsmb://dc.example.com/SysVol/example.com/Policies/{GPO2}\n"},
+		"Fallback to script when listing from LDAP fails": {dir: gpolistmock.Directory{}, want: "script-gpo\tsmb://dc.example.com/SysVol/example.com/Policies/{GPO2}\n"},
+		"Only script is used when LDAP is disabled":       {dir: dir, noLDAP: true, want: "script-gpo\tsmb://dc.example.com/SysVol/example.com/Policies/{GPO2}\n"},
+
+		// Loopback processing
+		"List loopback GPOs from LDAP": {dir: dir, failedCmd: true, loopbackComputer: "hostname",
+			want: "ldap-gpo\tsmb://dc.example.com/SysVol/example.com/Policies/{GPO1}\tDC=example,DC=com\tenforced\n"},
+		"Loopback computer is passed to the script": {noLDAP: true, loopbackComputer: "hostname",
+			cmd:  []string{"sh", "-c", `echo "$@"`, "--"},
+			want: "--objectclass user --loopback hostname dc.example.com bob@example.com\n"},
+
+		"Error when both LDAP and script fail": {failedCmd: true, wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			cmd := scriptCmd
+			if tc.cmd != nil {
+				cmd = tc.cmd
+			}
+			if tc.failedCmd {
+				cmd = []string{"false"}
+			}
+			opt := withGPOListDirectory(tc.dir, cmd)
+			if tc.noLDAP {
+				opt = withGPOListCmd(cmd)
+			}
+
+			adc, err := New(context.Background(), mock.Backend{Dom: "example.com"}, "hostname",
+				WithCacheDir(t.TempDir()), WithRunDir(t.TempDir()), withoutKerberos(), opt)
+			require.NoError(t, err, "Setup: cannot create ad object")
+
+			got, err := adc.listGPOs(context.Background(), "dc.example.com", "bob@example.com", UserObject, "", tc.loopbackComputer)
+			if tc.wantErr {
+				require.Error(t, err, "listGPOs should have failed but didn't")
+				return
+			}
+			require.NoError(t, err, "listGPOs should not have failed")
+			require.Equal(t, tc.want, string(got), "listGPOs should return the expected GPO list")
+		})
+	}
+}
+
 const SmbPort = 1445
 
 func TestMain(m *testing.M) {
@@ -1,5 +1,11 @@
 package ad
 
+import (
+	"context"
+
+	"github.com/ubuntu/adsys/internal/ad/gpolist"
+)
+
 func withoutKerberos() Option {
 	return func(o *options) error {
 		o.withoutKerberos = true
@@ -7,8 +13,25 @@ func withoutKerberos() Option {
 	}
 }
 
+// withGPOListCmd forces listing GPOs with cmd instead of LDAP.
 func withGPOListCmd(cmd []string) Option {
 	return func(o *options) error {
+		o.gpoListDial = nil
+		o.gpoListCmd = cmd
+		return nil
+	}
+}
+
+// withGPOListDirectory lists GPOs from dir before falling back to cmd. If dir is nil, connecting to the directory
+// fails.
+func withGPOListDirectory(dir gpolist.Directory, cmd []string) Option {
+	return func(o *options) error {
+		o.gpoListDial = func(context.Context, string, string) (gpolist.Directory, error) {
+			if dir == nil {
+				return nil, gpolist.ErrConnection
+			}
+			return dir, nil
+		}
 		o.gpoListCmd = cmd
 		return nil
 	}
@@ -21,3 +44,11 @@ func WithVersionID(versionID string) Option {
 		return nil
 	}
 }
+
+// withWMIFactsRoot reads the machine facts for WMI filters from root instead of /.
+func withWMIFactsRoot(root string) Option {
+	return func(o *options) error {
+		o.wmiFactsRoot = root
+		return nil
+	}
+}
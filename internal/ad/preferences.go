@@ -0,0 +1,335 @@
+package ad
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/leonelquinteros/gotext"
+	"github.com/ubuntu/adsys/internal/ad/gpolist"
+	"github.com/ubuntu/adsys/internal/ad/gpp"
+	"github.com/ubuntu/adsys/internal/ad/wmifilter"
+	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
+	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/decorate"
+	"gopkg.in/yaml.v3"
+)
+
+// RunOnceCacheBaseName is the base directory where we keep, per object, the Group Policy Preferences items to
+// apply once which were already applied, with the entry they were applied with.
+const RunOnceCacheBaseName = "gpp-runonce"
+
+// preferencesParser converts the entries decoded from a Group Policy Preferences file to rules of a given type.
+type preferencesParser struct {
+	// file is the path of the preferences file, relative to the class directory of a GPO.
+	file string
+	// ruleType is the rule type the entries are converted to.
+	ruleType string
+	// objectClass, if set, is the only object class the preferences are applied to.
+	objectClass ObjectClass
+	// convert converts the decoded entries to rules.
+	convert func(ctx context.Context, entries []entry.Entry) []entry.Entry
+}
+
+// preferencesParsers are the Group Policy Preferences files applied by adsys.
+// Files.xml and Shortcuts.xml are not supported yet.
+var preferencesParsers = []preferencesParser{
+	{file: "Preferences/Drives/Drives.xml", ruleType: "mount", objectClass: UserObject, convert: drivesToMounts},
+	{file: "Preferences/Groups/Groups.xml", ruleType: "localgroups", objectClass: ComputerObject, convert: groupsToLocalGroups},
+	{file: "Preferences/EnvironmentVariables/EnvironmentVariables.xml", ruleType: "environment", convert: environmentVariables},
+}
+
+// parsePreferences returns the rules, per rule type, of the Group Policy Preferences of the GPO in gpoDir.
+// opts are used to decode the preferences files, to skip items which are not targeted or were already applied.
+func parsePreferences(ctx context.Context, gpoDir string, classes []string, objectClass ObjectClass, opts []gpp.Option) (rules map[string][]entry.Entry, err error) {
+	for _, p := range preferencesParsers {
+		if p.objectClass != "" && p.objectClass != objectClass {
+			continue
+		}
+
+		var f *os.File
+		for _, class := range classes {
+			f, err = os.Open(filepath.Join(gpoDir, class, p.file))
+			if err == nil {
+				break
+			}
+		}
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		log.Debugf(ctx, "Parsing preferences %q", f.Name())
+
+		entries, err := gpp.Decode(f, opts...)
+		decorate.LogFuncOnErrorContext(ctx, f.Close)
+		if err != nil {
+			return nil, errors.New(gotext.Get("%s: %v", f.Name(), err))
+		}
+		for _, e := range entries {
+			if e.Err != nil {
+				return nil, errors.New(gotext.Get("%s: %v", f.Name(), e.Err))
+			}
+		}
+
+		r := p.convert(ctx, entries)
+		if len(r) == 0 {
+			continue
+		}
+		if rules == nil {
+			rules = make(map[string][]entry.Entry)
+		}
+		rules[p.ruleType] = append(rules[p.ruleType], r...)
+	}
+
+	return rules, nil
+}
+
+// drivesToMounts converts drive mappings to a user mounts rule.
+// Drives are mapped with the credentials of the user, so they are mounted with the user Kerberos ticket.
+func drivesToMounts(ctx context.Context, entries []entry.Entry) []entry.Entry {
+	var mounts []string
+	for _, e := range entries {
+		// The drive mapping is removed, which is the case of any mount not listed.
+		if e.Disabled {
+			continue
+		}
+
+		path := gpp.Property(e, "path")
+		if !strings.HasPrefix(path, `\\`) {
+			log.Warningf(ctx, "Ignoring drive mapping %s: %q is not a network share", e.Key, path)
+			continue
+		}
+		if gpp.Property(e, "userName") != "" {
+			log.Warningf(ctx, "Drive mapping %s is set to connect as a given user, which is not supported: using the user Kerberos ticket", e.Key)
+		}
+		mounts = append(mounts, "[krb5]smb://"+strings.ReplaceAll(strings.TrimPrefix(path, `\\`), `\`, "/"))
+	}
+
+	if mounts == nil {
+		return nil
+	}
+	return []entry.Entry{{
+		Key:      "user-mounts",
+		Value:    strings.Join(mounts, "\n"),
+		Strategy: entry.StrategyAppend,
+	}}
+}
+
+// groupsToLocalGroups converts local group items to local groups rules, keyed by group name.
+// Each line of a rule value is a member to add, or a member to remove prefixed with "-".
+// Members of the same group are merged between GPOs.
+func groupsToLocalGroups(ctx context.Context, entries []entry.Entry) (rules []entry.Entry) {
+	for _, e := range entries {
+		group := gpp.Property(e, "groupName")
+		if !strings.HasPrefix(e.Key, "Group/") || group == "" {
+			log.Debugf(ctx, "Ignoring unsupported local users and groups item %s", e.Key)
+			continue
+		}
+
+		var members []string
+		for _, m := range gpp.Properties(e, "member") {
+			action, name, _ := strings.Cut(m, ":")
+			if strings.EqualFold(action, "REMOVE") {
+				name = "-" + name
+			}
+			members = append(members, name)
+		}
+
+		rules = append(rules, entry.Entry{
+			Key:      group,
+			Value:    strings.Join(members, "\n"),
+			Disabled: e.Disabled,
+			Strategy: entry.StrategyMerge,
+		})
+	}
+	return rules
+}
+
+// environmentVariables converts environment variable items to environment rules, keyed by variable name.
+// Partial variables, like PATH, are appended to the values of other GPOs.
+func environmentVariables(_ context.Context, entries []entry.Entry) (rules []entry.Entry) {
+	for _, e := range entries {
+		name := gpp.Property(e, "name")
+		if name == "" {
+			name = strings.TrimPrefix(e.Key, "EnvironmentVariable/")
+		}
+
+		r := entry.Entry{
+			Key:      name,
+			Value:    gpp.Property(e, "value"),
+			Disabled: e.Disabled,
+		}
+		if gpp.Property(e, "partial") == "1" {
+			r.Strategy = entry.StrategyAppend
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// preferencesTarget returns the target of the preference items applied to objectName, to evaluate their item-level
+// targeting. facts are the machine facts, loaded if nil.
+// The user and computer accounts are only looked up, with the krb5CCPath ticket, from the directory server fqdn when
+// an item targets them.
+func (ad *AD) preferencesTarget(ctx context.Context, objectName string, objectClass ObjectClass, fqdn, krb5CCPath string, facts wmifilter.Facts) (t gpp.Target, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't get preferences target for %q", objectName))
+
+	t, err = machineTarget(ctx, ad.wmiFactsRoot, ad.hostname, facts)
+	if err != nil {
+		return t, err
+	}
+
+	accounts := make(map[bool]gpp.Account)
+	t.Account = func(user bool) (a gpp.Account, err error) {
+		// Computer preferences only target the computer account.
+		user = user && objectClass == UserObject
+		if a, ok := accounts[user]; ok {
+			return a, nil
+		}
+
+		name, isComputer := objectName, false
+		if !user {
+			name, isComputer = ad.hostname, true
+		}
+		defer decorate.OnError(&err, gotext.Get("can't look up account %q for item-level targeting", name))
+
+		if ad.gpoListDial == nil {
+			return a, errors.New(gotext.Get("no connection to the directory"))
+		}
+		dir, err := ad.gpoListDial(ctx, fqdn, krb5CCPath)
+		if err != nil {
+			return a, err
+		}
+		defer func() {
+			if err := dir.Close(); err != nil {
+				log.Warningf(ctx, "Can't close LDAP connection to %q: %v", fqdn, err)
+			}
+		}()
+
+		account, err := gpolist.FindAccount(ctx, dir, name, isComputer)
+		if err != nil {
+			return a, err
+		}
+		a = gpp.Account{DN: account.DN, SIDs: account.SIDs}
+		accounts[user] = a
+		return a, nil
+	}
+
+	return t, nil
+}
+
+// machineTarget returns the target of the preference items applied to the machine named hostname, whose root
+// filesystem is root, without any account. Items targeting security groups or organizational units don't match it.
+// facts are the machine facts, loaded if nil.
+func machineTarget(ctx context.Context, root, hostname string, facts wmifilter.Facts) (t gpp.Target, err error) {
+	if facts == nil {
+		if facts, err = wmifilter.LoadFacts(root, hostname); err != nil {
+			return t, err
+		}
+	}
+
+	t = gpp.Target{
+		Hostname:    hostname,
+		Environment: make(map[string]string),
+		Root:        root,
+	}
+	for _, operatingSystem := range facts["win32_operatingsystem"] {
+		t.OSName, t.OSVersion = operatingSystem["caption"], operatingSystem["version"]
+	}
+	for _, env := range facts["win32_environment"] {
+		t.Environment[env["name"]] = env["variablevalue"]
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		log.Warningf(ctx, "Can't list network interfaces addresses for IP range targeting: %v", err)
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			t.IPs = append(t.IPs, ipNet.IP)
+		}
+	}
+
+	return t, nil
+}
+
+// runOnceTracker keeps track of the preferences items to apply once for an object.
+type runOnceTracker struct {
+	path    string
+	applied map[string]entry.Entry
+	seen    map[string]entry.Entry
+}
+
+// newRunOnceTracker returns a tracker of the preferences items to apply once for objectName,
+// loading the items already applied from cacheDir.
+func newRunOnceTracker(cacheDir, objectName string) (t *runOnceTracker, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't load preferences items applied once for %q", objectName))
+
+	t = &runOnceTracker{
+		path:    filepath.Join(cacheDir, RunOnceCacheBaseName, objectName),
+		applied: make(map[string]entry.Entry),
+		seen:    make(map[string]entry.Entry),
+	}
+	d, err := os.ReadFile(t.path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(d, &t.applied); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// appliedOnce records the item id as seen and returns the entry it was first applied with, or e if it
+// was not applied yet.
+// Items with a parsing error are not recorded, to be applied again once fixed.
+func (t *runOnceTracker) appliedOnce(id string, e entry.Entry) entry.Entry {
+	if applied, ok := t.applied[id]; ok {
+		e = applied
+	}
+	if e.Err == nil {
+		t.seen[id] = e
+	}
+	return e
+}
+
+// save marks all the items seen while parsing as applied, with the entry they were applied with.
+// Items which are not part of any GPO anymore are forgotten.
+func (t *runOnceTracker) save() (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't save preferences items applied once"))
+
+	if maps.EqualFunc(t.seen, t.applied, func(a, b entry.Entry) bool { return a == b }) {
+		return nil
+	}
+
+	if len(t.seen) == 0 {
+		if err := os.Remove(t.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		t.applied = t.seen
+		return nil
+	}
+
+	d, err := yaml.Marshal(t.seen)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(t.path+".new", d, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(t.path+".new", t.path); err != nil {
+		return err
+	}
+	t.applied = t.seen
+	return nil
+}
@@ -50,8 +50,37 @@ type meta struct {
 	Strategy string
 }
 
+// valueTypes are the registry types of the values which can be decoded and encoded, indexed by their name.
+var valueTypes = map[string]dataType{
+	"REG_SZ":       regSz,
+	"REG_MULTI_SZ": regMultiSz,
+	"REG_DWORD":    regDword,
+}
+
+// Value is a policy entry with the registry type of its value.
+type Value struct {
+	entry.Entry
+	// Type is the registry type of the value: REG_SZ, REG_MULTI_SZ or REG_DWORD. It is empty for disabled entries
+	// and values of unsupported types.
+	Type string
+}
+
 // DecodePolicy parses a policy stream in registry file format and returns a slice of entries.
 func DecodePolicy(r io.Reader) (entries []entry.Entry, err error) {
+	values, err := DecodePolicyValues(r)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range values {
+		entries = append(entries, v.Entry)
+	}
+	return entries, nil
+}
+
+// DecodePolicyValues parses a policy stream in registry file format and returns a slice of entries, with the
+// registry type of their value.
+func DecodePolicyValues(r io.Reader) (values []Value, err error) {
 	defer decorate.OnError(&err, gotext.Get("can't parse policy"))
 
 	ent, err := readPolicy(r)
@@ -127,6 +156,7 @@ func DecodePolicy(r io.Reader) (entries []entry.Entry, err error) {
 		e.path = strings.ReplaceAll(e.path, `\`, `/`)
 
 		// if the key is enabled, load value (or replace with default values for empty results)
+		var valueType string
 		if !disabled {
 			switch t := e.dType; t {
 			case regSz, regMultiSz:
@@ -134,12 +164,18 @@ func DecodePolicy(r io.Reader) (entries []entry.Entry, err error) {
 				if err != nil {
 					return nil, err
 				}
+				// multiple strings are terminated by an additional \x00
+				if t == regMultiSz {
+					res = strings.TrimSuffix(res, "\x00")
+				}
 				if res == "" {
 					res = metaValues[e.key].Empty
 				}
+				valueType = "REG_SZ"
 				// lines separators for multi lines textbox are \x00
 				if t == regMultiSz {
 					res = strings.ReplaceAll(res, "\x00", "\n")
+					valueType = "REG_MULTI_SZ"
 				}
 			case regDword:
 				var resInt uint32
@@ -148,22 +184,151 @@ func DecodePolicy(r io.Reader) (entries []entry.Entry, err error) {
 					return nil, err
 				}
 				res = strconv.FormatUint(uint64(resInt), 10)
+				valueType = "REG_DWORD"
 			default:
 				e.err = fmt.Errorf("%d type is not supported for key %s", t, e.key)
 			}
 		}
 
-		entries = append(entries, entry.Entry{
-			Key:      filepath.Join(e.path, e.key),
-			Value:    res,
-			Disabled: disabled,
-			Meta:     metaValues[e.key].Meta,
-			Strategy: metaValues[e.key].Strategy,
-			Err:      e.err,
+		values = append(values, Value{
+			Entry: entry.Entry{
+				Key:      filepath.Join(e.path, e.key),
+				Value:    res,
+				Disabled: disabled,
+				Meta:     metaValues[e.key].Meta,
+				Strategy: metaValues[e.key].Strategy,
+				Err:      e.err,
+			},
+			Type: valueType,
 		})
 	}
 
-	return entries, nil
+	return values, nil
+}
+
+// EncodePolicy writes values to w in registry file format, so that DecodePolicyValues returns the same values.
+// Each entry key is split in a registry key, its parent path, and a value name, its base name.
+// Values are written with their registry type. Without any type, they are written as strings, or as multiple
+// strings for multi-lines values. Disabled entries are written as deletion markers.
+// The meta and strategy of entries are written in a metaValues companion value of their registry key,
+// before the first entry of this key.
+func EncodePolicy(w io.Writer, values []Value) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't encode policy"))
+
+	var raw []policyRawEntry
+	// metaValues are the meta values of each registry key, indexed by their companion value position in raw.
+	metaValues := make(map[int]map[string]meta)
+	var hasMeta bool
+	var currentPath string
+	var iMetaValues int
+	for _, v := range values {
+		e := v.Entry
+		path, key := filepath.Dir(e.Key), filepath.Base(e.Key)
+		if !strings.Contains(e.Key, "/") || strings.HasSuffix(e.Key, "/") || path == "/" {
+			return fmt.Errorf("key %q should be in the form <path>/<value name>", e.Key)
+		}
+		path = strings.ReplaceAll(path, "/", `\`)
+
+		// New registry key: reserve the place of its meta values.
+		if path != currentPath || len(raw) == 0 {
+			currentPath = path
+			iMetaValues = len(raw)
+			metaValues[iMetaValues] = make(map[string]meta)
+			raw = append(raw, policyRawEntry{path: path, key: policyContainerName, dType: regSz})
+		}
+		if e.Meta != "" || e.Strategy != "" {
+			metaValues[iMetaValues][key] = meta{Meta: e.Meta, Strategy: e.Strategy}
+			hasMeta = true
+		}
+
+		if e.Disabled {
+			// Windows writes a space as data for deletion markers.
+			raw = append(raw, policyRawEntry{path: path, key: "**del." + key, dType: regSz, data: encodeUtf16(" ")})
+			continue
+		}
+		dType := regSz
+		if strings.Contains(e.Value, "\n") {
+			dType = regMultiSz
+		}
+		if v.Type != "" {
+			t, ok := valueTypes[v.Type]
+			if !ok {
+				return fmt.Errorf("unsupported type %q for key %q", v.Type, e.Key)
+			}
+			dType = t
+		}
+		data, err := encodeValue(e.Value, dType)
+		if err != nil {
+			return fmt.Errorf("invalid value for key %q: %w", e.Key, err)
+		}
+		raw = append(raw, policyRawEntry{path: path, key: key, dType: dType, data: data})
+	}
+
+	// The meta values of a registry key apply until the next one: they are only skipped if no entry has any.
+	for i, m := range metaValues {
+		if !hasMeta {
+			raw[i].dType = regNone
+			continue
+		}
+		d, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		raw[i].data = encodeUtf16(string(d))
+	}
+
+	return writePolicy(w, raw)
+}
+
+// encodeValue returns value encoded as registry data of type dType.
+func encodeValue(value string, dType dataType) ([]byte, error) {
+	switch dType {
+	case regMultiSz:
+		// lines separators for multi lines textbox are \x00, and multiple strings are terminated by an additional \x00
+		return append(encodeUtf16(strings.ReplaceAll(value, "\n", "\x00")), 0, 0), nil
+	case regDword:
+		i, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		return binary.LittleEndian.AppendUint32(nil, uint32(i)), nil
+	}
+	return encodeUtf16(value), nil
+}
+
+// writePolicy writes the header and entries in registry file format to w.
+// Entries of type regNone are skipped.
+func writePolicy(w io.Writer, entries []policyRawEntry) error {
+	var b bytes.Buffer
+	if err := binary.Write(&b, binary.LittleEndian, policyFileHeader{Signature: 0x67655250, Version: 1}); err != nil {
+		return err
+	}
+
+	sep := encodeUtf16Raw(";")
+	for _, e := range entries {
+		if e.dType == regNone {
+			continue
+		}
+		// [key;value;type;size;data]
+		b.Write(encodeUtf16Raw("["))
+		b.Write(encodeUtf16(e.path))
+		b.Write(sep)
+		b.Write(encodeUtf16(e.key))
+		b.Write(sep)
+		if err := binary.Write(&b, binary.LittleEndian, uint32(e.dType)); err != nil {
+			return err
+		}
+		b.Write(sep)
+		if err := binary.Write(&b, binary.LittleEndian, uint32(len(e.data))); err != nil {
+			return err
+		}
+		b.Write(sep)
+		b.Write(e.data)
+		b.Write(encodeUtf16Raw("]"))
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
 }
 
 type policyRawEntry struct {
@@ -301,6 +466,21 @@ func decodeUtf16(b []byte) (string, error) {
 	return string(utf16.Decode(ints)), nil
 }
 
+// encodeUtf16 returns s as a null terminated little endian UTF-16 string.
+func encodeUtf16(s string) []byte {
+	return append(encodeUtf16Raw(s), 0, 0)
+}
+
+// encodeUtf16Raw returns s as a little endian UTF-16 string, without any terminating null character.
+func encodeUtf16Raw(s string) []byte {
+	ints := utf16.Encode([]rune(s))
+	b := make([]byte, 0, len(ints)*2)
+	for _, i := range ints {
+		b = binary.LittleEndian.AppendUint16(b, i)
+	}
+	return b
+}
+
 // getMetaValues returns meta values (including empty value) for options.
 func getMetaValues(data []byte, keypath string) (metaValues map[string]meta, err error) {
 	defer decorate.OnError(&err, gotext.Get("can't decode meta value for %s: %v", keypath, err))
@@ -43,6 +43,13 @@ func TestDecodePolicy(t *testing.T) {
 					Value: "B\nA",
 				},
 			}},
+		"one element, well-formed multitext value": {
+			want: []entry.Entry{
+				{
+					Key:   defaultKey,
+					Value: "B\nA",
+				},
+			}},
 		"two elements": {
 			want: []entry.Entry{
 				{
@@ -116,6 +123,24 @@ func TestDecodePolicy(t *testing.T) {
 					Strategy: "override",
 				},
 			}},
+		"basic type with prepend strategy": {
+			want: []entry.Entry{
+				{
+					Key:      `Software/Policies/Ubuntu/privilege/allow-local-admins/all`,
+					Value:    "",
+					Meta:     "foo",
+					Strategy: entry.StrategyPrepend,
+				},
+			}},
+		"basic type with merge strategy": {
+			want: []entry.Entry{
+				{
+					Key:      `Software/Policies/Ubuntu/privilege/allow-local-admins/all`,
+					Value:    "",
+					Meta:     "foo",
+					Strategy: entry.StrategyMerge,
+				},
+			}},
 		"basic type is ignored for meta of wrong type": {
 			want: nil},
 
@@ -410,6 +435,113 @@ func TestDecodePolicy(t *testing.T) {
 	}
 }
 
+func TestEncodePolicy(t *testing.T) {
+	t.Parallel()
+
+	defaultKey := `Software/Canonical/Ubuntu/ValueName`
+	tests := map[string]struct {
+		values []registry.Value
+
+		want    []registry.Value
+		wantErr bool
+	}{
+		"one element, string value":     {values: []registry.Value{{Entry: entry.Entry{Key: defaultKey, Value: "BA"}, Type: "REG_SZ"}}},
+		"one element, empty value":      {values: []registry.Value{{Entry: entry.Entry{Key: defaultKey, Value: ""}, Type: "REG_SZ"}}},
+		"one element, multitext value":  {values: []registry.Value{{Entry: entry.Entry{Key: defaultKey, Value: "B\nA"}, Type: "REG_MULTI_SZ"}}},
+		"one element, decimal value":    {values: []registry.Value{{Entry: entry.Entry{Key: defaultKey, Value: "1234"}, Type: "REG_DWORD"}}},
+		"one element, disabled":         {values: []registry.Value{{Entry: entry.Entry{Key: defaultKey, Disabled: true}}}},
+		"one element, non ascii value":  {values: []registry.Value{{Entry: entry.Entry{Key: defaultKey, Value: "Ünicode 🐧"}, Type: "REG_SZ"}}},
+		"one element, multiline string": {values: []registry.Value{{Entry: entry.Entry{Key: defaultKey, Value: "B\nA"}, Type: "REG_SZ"}}},
+		"one element, decimal string":   {values: []registry.Value{{Entry: entry.Entry{Key: defaultKey, Value: "1234"}, Type: "REG_SZ"}}},
+		"two elements in different keys": {values: []registry.Value{
+			{Entry: entry.Entry{Key: defaultKey, Value: "1"}, Type: "REG_DWORD"},
+			{Entry: entry.Entry{Key: `Software/Policies/Canonical/Ubuntu/Directory UI/QueryLimit`, Value: "12345"}, Type: "REG_DWORD"},
+		}},
+		"elements with meta and strategy": {values: []registry.Value{
+			{Entry: entry.Entry{Key: `Software/Policies/Ubuntu/dconf/org/gnome/desktop/background/picture-uri/all`, Value: "file:///usr/share/backgrounds/ubuntu.png", Meta: "s"}, Type: "REG_SZ"},
+			{Entry: entry.Entry{Key: `Software/Policies/Ubuntu/dconf/org/gnome/desktop/background/picture-options/all`, Value: "zoom", Meta: "s"}, Type: "REG_SZ"},
+			{Entry: entry.Entry{Key: `Software/Policies/Ubuntu/privilege/allow-local-admins/all`, Disabled: true, Meta: "foo"}},
+			{Entry: entry.Entry{Key: `Software/Policies/Ubuntu/scripts/user/logon/all`, Value: "script1.sh\nscript2.sh", Strategy: entry.StrategyAppend}, Type: "REG_MULTI_SZ"},
+		}},
+		"meta values don't leak to next key": {values: []registry.Value{
+			{Entry: entry.Entry{Key: `Software/Policies/Ubuntu/gdm/key1/all`, Value: "a", Meta: "s"}, Type: "REG_SZ"},
+			{Entry: entry.Entry{Key: `Software/Policies/Ubuntu/gdm/key2/all`, Value: "b"}, Type: "REG_SZ"},
+		}},
+		"same key split in multiple blocks": {values: []registry.Value{
+			{Entry: entry.Entry{Key: `Software/Policies/Ubuntu/gdm/key1/all`, Value: "a", Meta: "s"}, Type: "REG_SZ"},
+			{Entry: entry.Entry{Key: `Software/Policies/Ubuntu/gdm/key2/all`, Value: "b", Meta: "i"}, Type: "REG_SZ"},
+			{Entry: entry.Entry{Key: `Software/Policies/Ubuntu/gdm/key1/20.04`, Value: "c", Strategy: entry.StrategyPrepend}, Type: "REG_SZ"},
+		}},
+		"type is inferred from value when not set": {
+			values: []registry.Value{
+				{Entry: entry.Entry{Key: defaultKey, Value: "1234"}},
+				{Entry: entry.Entry{Key: `Software/Canonical/Ubuntu/Other`, Value: "B\nA"}},
+			},
+			want: []registry.Value{
+				{Entry: entry.Entry{Key: defaultKey, Value: "1234"}, Type: "REG_SZ"},
+				{Entry: entry.Entry{Key: `Software/Canonical/Ubuntu/Other`, Value: "B\nA"}, Type: "REG_MULTI_SZ"},
+			}},
+		"no element": {},
+
+		// Error cases
+		"Error on key without path":        {values: []registry.Value{{Entry: entry.Entry{Key: "ValueName", Value: "BA"}}}, wantErr: true},
+		"Error on key without value name":  {values: []registry.Value{{Entry: entry.Entry{Key: "Software/Canonical/", Value: "BA"}}}, wantErr: true},
+		"Error on key at root of registry": {values: []registry.Value{{Entry: entry.Entry{Key: "/ValueName", Value: "BA"}}}, wantErr: true},
+		"Error on unsupported type":        {values: []registry.Value{{Entry: entry.Entry{Key: defaultKey, Value: "BA"}, Type: "REG_BINARY"}}, wantErr: true},
+		"Error on invalid decimal value":   {values: []registry.Value{{Entry: entry.Entry{Key: defaultKey, Value: "BA"}, Type: "REG_DWORD"}}, wantErr: true},
+		"Error on decimal value overflow":  {values: []registry.Value{{Entry: entry.Entry{Key: defaultKey, Value: "4294967296"}, Type: "REG_DWORD"}}, wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var b bytes.Buffer
+			err := registry.EncodePolicy(&b, tc.values)
+			if tc.wantErr {
+				require.Error(t, err, "EncodePolicy should have errored out")
+				return
+			}
+			require.NoError(t, err, "EncodePolicy should not have errored out")
+
+			if tc.want == nil {
+				tc.want = tc.values
+			}
+			got, err := registry.DecodePolicyValues(&b)
+			require.NoError(t, err, "DecodePolicyValues should decode what EncodePolicy wrote")
+			require.Equal(t, tc.want, got, "DecodePolicyValues should return the encoded values")
+		})
+	}
+}
+
+func TestEncodeDecodedPolicy(t *testing.T) {
+	t.Parallel()
+
+	// Only well-formed policy files are listed: the others are crafted to exercise decoding errors and edge cases.
+	tests := []string{
+		"Registry",
+		"one element, string value",
+		"one element, decimal value",
+		"one element, well-formed multitext value",
+		"two elements",
+		"section separators in data",
+	}
+	for _, name := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			want, err := os.ReadFile(policyFilePath(name))
+			require.NoError(t, err, "Setup: can't read registry file")
+
+			values, err := registry.DecodePolicyValues(bytes.NewReader(want))
+			require.NoError(t, err, "Setup: can't decode registry file")
+
+			var got bytes.Buffer
+			require.NoError(t, registry.EncodePolicy(&got, values), "EncodePolicy should not have errored out")
+			require.Equal(t, want, got.Bytes(), "EncodePolicy should write the decoded policy file as is")
+		})
+	}
+}
+
 func FuzzDecodePolicy(f *testing.F) {
 	// To seed the corpus, we need to read the example files.
 	policyfiles, err := os.ReadDir("testdata")
@@ -0,0 +1,97 @@
+// Package secedit handles parsing Windows security template files (GptTmpl.inf)
+// to convert them to comprehensible entries datastructure for adsys to consume.
+package secedit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/leonelquinteros/gotext"
+	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/decorate"
+)
+
+var (
+	utf16LEBOM = []byte{0xff, 0xfe}
+	utf8BOM    = []byte{0xef, 0xbb, 0xbf}
+)
+
+// Decode parses a security template stream and returns a slice of entries.
+// Each entry key is in the form <Section>/<Name>, like "System Access/MinimumPasswordLength".
+// The Unicode and Version sections, which only describe the file itself, are skipped, as well
+// as lines which are not settings, like comments or file and registry ACLs.
+func Decode(r io.Reader) (entries []entry.Entry, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't parse security template"))
+
+	d, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Security templates are saved as UTF-16 by Windows tools, but can be edited manually.
+	switch {
+	case bytes.HasPrefix(d, utf16LEBOM):
+		if d, err = decodeUTF16(d[len(utf16LEBOM):]); err != nil {
+			return nil, err
+		}
+	case bytes.HasPrefix(d, utf8BOM):
+		d = d[len(utf8BOM):]
+	}
+
+	var section string
+	scanner := bufio.NewScanner(bytes.NewReader(d))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, errors.New(gotext.Get("line %d: section %q does not end with ']'", n, line))
+			}
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		name, value, found := strings.Cut(line, "=")
+		if !found || section == "" {
+			continue
+		}
+		if strings.EqualFold(section, "Unicode") || strings.EqualFold(section, "Version") {
+			continue
+		}
+
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if len(value) > 1 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+			value = value[1 : len(value)-1]
+		}
+		entries = append(entries, entry.Entry{
+			Key:   section + "/" + name,
+			Value: value,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// decodeUTF16 converts little endian UTF-16 content to UTF-8.
+func decodeUTF16(d []byte) ([]byte, error) {
+	if len(d)%2 != 0 {
+		return nil, errors.New(gotext.Get("invalid UTF-16 content: odd number of bytes"))
+	}
+
+	u := make([]uint16, len(d)/2)
+	if err := binary.Read(bytes.NewReader(d), binary.LittleEndian, &u); err != nil {
+		return nil, err
+	}
+	return []byte(string(utf16.Decode(u))), nil
+}
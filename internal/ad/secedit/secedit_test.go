@@ -0,0 +1,85 @@
+package secedit_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/ubuntu/adsys/internal/ad/secedit"
+	"github.com/ubuntu/adsys/internal/policies/entry"
+)
+
+func TestDecode(t *testing.T) {
+	t.Parallel()
+
+	defaultDomainPolicy := []entry.Entry{
+		{Key: "System Access/MinimumPasswordAge", Value: "1"},
+		{Key: "System Access/MaximumPasswordAge", Value: "42"},
+		{Key: "System Access/MinimumPasswordLength", Value: "7"},
+		{Key: "System Access/PasswordComplexity", Value: "1"},
+		{Key: "System Access/PasswordHistorySize", Value: "24"},
+		{Key: "System Access/LockoutBadCount", Value: "0"},
+		{Key: "System Access/RequireLogonToChangePassword", Value: "0"},
+		{Key: "System Access/ForceLogoffWhenHourExpire", Value: "0"},
+		{Key: "System Access/ClearTextPassword", Value: "0"},
+		{Key: "System Access/LSAAnonymousNameLookup", Value: "0"},
+		{Key: "Kerberos Policy/MaxTicketAge", Value: "10"},
+		{Key: "Kerberos Policy/MaxRenewAge", Value: "7"},
+		{Key: "Kerberos Policy/MaxServiceAge", Value: "600"},
+		{Key: "Kerberos Policy/MaxClockSkew", Value: "5"},
+		{Key: "Kerberos Policy/TicketValidateClient", Value: "1"},
+		{Key: `Registry Values/MACHINE\System\CurrentControlSet\Control\Lsa\NoLMHash`, Value: "4,1"},
+	}
+
+	tests := map[string]struct {
+		want    []entry.Entry
+		wantErr bool
+	}{
+		"utf-16 template":         {want: defaultDomainPolicy},
+		"utf-8 template with BOM": {want: defaultDomainPolicy},
+		"utf-8 template":          {want: []entry.Entry{{Key: "System Access/MinimumPasswordLength", Value: "12"}, {Key: "System Access/LockoutBadCount", Value: "5"}}},
+		"settings are kept in the file order": {want: []entry.Entry{
+			{Key: "Privilege Rights/SeInteractiveLogonRight", Value: "*S-1-5-32-544,*S-1-5-32-545"},
+			{Key: "System Access/LockoutDuration", Value: "30"},
+			{Key: "System Access/LockoutBadCount", Value: "5"},
+		}},
+		"quoted values are unquoted": {want: []entry.Entry{
+			{Key: "System Access/NewAdministratorName", Value: "admin"},
+			{Key: "System Access/NewGuestName", Value: `"`},
+		}},
+
+		// Ignored content
+		"comments and empty lines are ignored":     {want: []entry.Entry{{Key: "System Access/LockoutBadCount", Value: "5"}}},
+		"lines which are not settings are ignored": {want: []entry.Entry{{Key: "System Access/LockoutDuration", Value: "30"}}},
+		"only unicode and version sections":        {},
+		"empty template":                           {},
+
+		// Error cases
+		"error on section not ending with a bracket":        {wantErr: true},
+		"error on utf-16 template with odd number of bytes": {wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := os.Open(templateFilePath(name))
+			require.NoError(t, err, "Setup: can't open security template file")
+			defer f.Close()
+
+			got, err := secedit.Decode(f)
+			if tc.wantErr {
+				require.Error(t, err, "Decode should have errored out")
+				return
+			}
+			require.NoError(t, err, "Decode should not have errored out")
+
+			require.Equal(t, tc.want, got, "Decode returns the expected entries")
+		})
+	}
+}
+
+func templateFilePath(name string) string {
+	return filepath.Join("testdata", strings.ReplaceAll(name, " ", "_")+".inf")
+}
@@ -0,0 +1,352 @@
+package ad
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/leonelquinteros/gotext"
+	"github.com/ubuntu/adsys/internal/ad/backends"
+	adcommon "github.com/ubuntu/adsys/internal/ad/common"
+	"github.com/ubuntu/adsys/internal/ad/gpp"
+	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
+	"github.com/ubuntu/adsys/internal/policies"
+	"github.com/ubuntu/decorate"
+	"gopkg.in/yaml.v3"
+)
+
+// GPOListCacheBaseName is the base directory where we keep the ordered list of GPOs used by the last refresh of
+// each object.
+const GPOListCacheBaseName = "gpolist"
+
+const (
+	snapshotMetadataName = "snapshot.yaml"
+	snapshotGPOListName  = "gpolist"
+	snapshotAssetsName   = "assets.db"
+)
+
+// snapshotMetadata describes the object a snapshot was captured for.
+type snapshotMetadata struct {
+	ObjectName  string      `yaml:"object_name"`
+	ObjectClass ObjectClass `yaml:"object_class"`
+	Domain      string      `yaml:"domain"`
+}
+
+// Snapshot is the content of a captured archive, parsed as if its policies were fetched from Active Directory.
+type Snapshot struct {
+	ObjectName  string
+	ObjectClass ObjectClass
+	Domain      string
+	Policies    policies.Policies
+}
+
+// saveGPOList stores the ordered list of GPOs, as returned by the GPO list command, used by the last refresh of
+// objectName.
+func (ad *AD) saveGPOList(objectName string, gpoList []byte) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't save GPO list for %q", objectName))
+
+	p := filepath.Join(ad.gpoListCacheDir, objectName)
+	if err := os.WriteFile(p+".new", gpoList, 0600); err != nil {
+		return err
+	}
+	return os.Rename(p+".new", p)
+}
+
+// CaptureSnapshot writes to w a compressed archive of everything the last refresh of objectName used: the ordered
+// list of GPOs, their downloaded content and the assets.
+func (ad *AD) CaptureSnapshot(ctx context.Context, objectName string, objectClass ObjectClass, w io.Writer) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't capture policies snapshot for %q", objectName))
+
+	log.Infof(ctx, "Capturing policies snapshot for %s", objectName)
+
+	// Prevent any refresh to modify the sysvol cache while we archive it.
+	ad.RLock()
+	defer ad.RUnlock()
+
+	gpoList, err := os.ReadFile(filepath.Join(ad.gpoListCacheDir, objectName))
+	if err != nil {
+		return errors.New(gotext.Get("no policies were fetched for %q: %v", objectName, err))
+	}
+	gpos, err := gposFromList(gpoList)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := yaml.Marshal(snapshotMetadata{
+		ObjectName:  objectName,
+		ObjectClass: objectClass,
+		Domain:      ad.configBackend.Domain(),
+	})
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	if err := addToArchive(tw, snapshotMetadataName, metadata); err != nil {
+		return err
+	}
+	if err := addToArchive(tw, snapshotGPOListName, gpoList); err != nil {
+		return err
+	}
+	for _, g := range gpos {
+		if err := addTreeToArchive(tw, ad.sysvolCacheDir, filepath.Join("Policies", filepath.Base(g.url))); err != nil {
+			return err
+		}
+	}
+	assets, err := os.ReadFile(filepath.Join(ad.sysvolCacheDir, snapshotAssetsName))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	} else if err == nil {
+		if err := addToArchive(tw, snapshotAssetsName, assets); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// LoadSnapshot extracts in dir the archive read from r, captured with CaptureSnapshot, and returns the policies
+// it contains. No connection to Active Directory is needed.
+// Item-level targeting of Group Policy Preferences is evaluated against the local machine. Without any directory to
+// look accounts up, items targeting security groups or organizational units are skipped.
+// dir should be kept until the returned policies are closed.
+func LoadSnapshot(ctx context.Context, r io.Reader, dir string) (s Snapshot, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't load policies snapshot"))
+
+	if err := extractArchive(r, dir); err != nil {
+		return s, err
+	}
+
+	d, err := os.ReadFile(filepath.Join(dir, snapshotMetadataName))
+	if err != nil {
+		return s, errors.New(gotext.Get("invalid snapshot: %v", err))
+	}
+	var metadata snapshotMetadata
+	if err := yaml.Unmarshal(d, &metadata); err != nil {
+		return s, errors.New(gotext.Get("invalid snapshot metadata: %v", err))
+	}
+	if metadata.ObjectClass != UserObject && metadata.ObjectClass != ComputerObject {
+		return s, errors.New(gotext.Get("invalid object class %q in snapshot", metadata.ObjectClass))
+	}
+	objectName, err := snapshotObjectName(metadata)
+	if err != nil {
+		return s, err
+	}
+
+	log.Infof(ctx, "Loading policies snapshot for %s", objectName)
+
+	gpoList, err := os.ReadFile(filepath.Join(dir, snapshotGPOListName))
+	if err != nil {
+		return s, errors.New(gotext.Get("invalid snapshot: %v", err))
+	}
+	gpos, err := gposFromList(gpoList)
+	if err != nil {
+		return s, err
+	}
+
+	versionID, err := adcommon.GetVersionID("/")
+	if err != nil {
+		return s, err
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return s, err
+	}
+	target, err := machineTarget(ctx, "/", hostname, nil)
+	if err != nil {
+		return s, err
+	}
+	rules, err := parseGPOs(ctx, dir, versionID, gpos, metadata.ObjectClass, nil, []gpp.Option{gpp.WithTarget(target)})
+	if err != nil {
+		return s, err
+	}
+
+	var assetsDbPath string
+	if _, err := os.Stat(filepath.Join(dir, snapshotAssetsName)); err == nil {
+		assetsDbPath = filepath.Join(dir, snapshotAssetsName)
+	}
+	pols, err := policies.New(ctx, rules, assetsDbPath)
+	if err != nil {
+		return s, err
+	}
+
+	return Snapshot{
+		ObjectName:  objectName,
+		ObjectClass: metadata.ObjectClass,
+		Domain:      metadata.Domain,
+		Policies:    pols,
+	}, nil
+}
+
+// snapshotObjectName returns the object name of the snapshot, normalized as the live Active Directory targets.
+// As it is then used in file names, names which are empty, contain path separators or refer to a directory are
+// rejected.
+func snapshotObjectName(metadata snapshotMetadata) (string, error) {
+	name := strings.ToLower(metadata.ObjectName)
+	if metadata.ObjectClass == ComputerObject {
+		name, _, _ = strings.Cut(name, ".")
+	} else if name != "" && !strings.Contains(name, "@") {
+		if metadata.Domain == "" {
+			return "", errors.New(gotext.Get("no domain in snapshot for user %q", metadata.ObjectName))
+		}
+		name = fmt.Sprintf("%s@%s", name, strings.ToLower(metadata.Domain))
+	}
+
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\\x00") {
+		return "", errors.New(gotext.Get("invalid object name %q in snapshot", metadata.ObjectName))
+	}
+	return name, nil
+}
+
+// Backend returns an offline backend for the domain the snapshot was captured on.
+func (s Snapshot) Backend() backends.Backend {
+	return snapshotBackend{domain: s.Domain}
+}
+
+// gposFromList returns the ordered GPOs listed in gpoList, as returned by the GPO list command.
+func gposFromList(gpoList []byte) (gpos []gpo, err error) {
+	for _, l := range strings.Split(strings.TrimSpace(string(gpoList)), "\n") {
+		if l == "" {
+			continue
+		}
+		g, err := parseGPOListLine(l)
+		if err != nil {
+			return nil, err
+		}
+		gpos = append(gpos, g)
+	}
+	return gpos, nil
+}
+
+// addToArchive adds a file named name with content to tw.
+func addToArchive(tw *tar.Writer, name string, content []byte) error {
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0600, Size: int64(len(content))}); err != nil {
+		return err
+	}
+	_, err := tw.Write(content)
+	return err
+}
+
+// addTreeToArchive adds rel directory from root, with all its content, to tw.
+func addTreeToArchive(tw *tar.Writer, root, rel string) error {
+	return filepath.WalkDir(filepath.Join(root, rel), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		name = filepath.ToSlash(name)
+
+		if d.IsDir() {
+			return tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: name + "/", Mode: 0700})
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		return addToArchive(tw, name, content)
+	})
+}
+
+// extractArchive extracts the compressed archive read from r in dir.
+// Only directories and regular files are supported.
+func extractArchive(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return errors.New(gotext.Get("invalid snapshot archive: %v", err))
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return errors.New(gotext.Get("invalid snapshot archive: %v", err))
+		}
+
+		name := filepath.Clean(filepath.FromSlash(hdr.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return errors.New(gotext.Get("invalid path %q in snapshot archive", hdr.Name))
+		}
+		p := filepath.Join(dir, name)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(p, 0700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+				return err
+			}
+			var content bytes.Buffer
+			// #nosec G110: the archive is provided by the administrator
+			if _, err := io.Copy(&content, tr); err != nil {
+				return err
+			}
+			if err := os.WriteFile(p, content.Bytes(), 0600); err != nil {
+				return err
+			}
+		default:
+			return errors.New(gotext.Get("unsupported file type for %q in snapshot archive", hdr.Name))
+		}
+	}
+
+	return nil
+}
+
+// snapshotBackend is an offline backend used to apply policies from a snapshot, without Active Directory.
+type snapshotBackend struct {
+	domain string
+}
+
+// Domain returns the domain the snapshot was captured on.
+func (b snapshotBackend) Domain() string {
+	return b.domain
+}
+
+// ServerFQDN returns that there is no active server.
+func (b snapshotBackend) ServerFQDN(context.Context) (string, error) {
+	return "", backends.ErrNoActiveServer
+}
+
+// HostKrb5CCName returns an error as no machine ticket is used with snapshots.
+func (b snapshotBackend) HostKrb5CCName() (string, error) {
+	return "", errors.New(gotext.Get("no Kerberos ticket when applying a snapshot"))
+}
+
+// DefaultDomainSuffix returns the domain the snapshot was captured on.
+func (b snapshotBackend) DefaultDomainSuffix() string {
+	return b.domain
+}
+
+// IsOnline returns false as snapshots are applied without Active Directory.
+func (b snapshotBackend) IsOnline() (bool, error) {
+	return false, nil
+}
+
+// Config returns a stringified configuration of the backend.
+func (b snapshotBackend) Config() string {
+	return gotext.Get("Snapshot of domain %s", b.domain)
+}
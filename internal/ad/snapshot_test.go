@@ -0,0 +1,230 @@
+package ad
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/termie/go-shutil"
+	"github.com/ubuntu/adsys/internal/ad/backends"
+	"github.com/ubuntu/adsys/internal/ad/backends/mock"
+	"github.com/ubuntu/adsys/internal/testutils"
+)
+
+func TestCaptureAndLoadSnapshot(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		gpos        []string
+		objectClass ObjectClass
+		withAssets  bool
+		noGPOList   bool
+
+		wantCaptureErr bool
+	}{
+		"User snapshot":                 {gpos: []string{"standard", "one-value"}},
+		"Computer snapshot":             {gpos: []string{"standard", "one-value"}, objectClass: ComputerObject},
+		"Snapshot with assets":          {gpos: []string{"standard"}, withAssets: true},
+		"Snapshot without any GPO":      {},
+		"Order of GPOs is kept":         {gpos: []string{"one-value", "standard"}},
+		"Error on no policies fetched":  {noGPOList: true, wantCaptureErr: true},
+		"Error on GPO missing in cache": {gpos: []string{"standard", "doesnotexist"}, wantCaptureErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if tc.objectClass == "" {
+				tc.objectClass = UserObject
+			}
+			objectName := "bob@assetsandgpo.com"
+			if tc.objectClass == ComputerObject {
+				objectName = "hostname"
+			}
+
+			adc, err := New(context.Background(), mock.Backend{Dom: "assetsandgpo.com"}, "hostname",
+				WithCacheDir(t.TempDir()), WithRunDir(t.TempDir()), withoutKerberos())
+			require.NoError(t, err, "Setup: cannot create ad object")
+
+			// Set up the sysvol cache as a refresh would have done.
+			var gpos []gpo
+			var gpoList string
+			for _, g := range tc.gpos {
+				url := fmt.Sprintf("smb://localhost:%d/SYSVOL/assetsandgpo.com/Policies/%s", SmbPort, g)
+				gpos = append(gpos, gpo{name: g + "-name", url: url, link: "DC=assetsandgpo,DC=com", enforced: g == "standard"})
+				enforced := ""
+				if g == "standard" {
+					enforced = "\tenforced"
+				}
+				gpoList += fmt.Sprintf("%s-name\t%s\tDC=assetsandgpo,DC=com%s\n", g, url, enforced)
+				if _, err := os.Stat(filepath.Join("testdata", "AD", "SYSVOL", "assetsandgpo.com", "Policies", g)); err != nil {
+					continue
+				}
+				err := shutil.CopyTree(filepath.Join("testdata", "AD", "SYSVOL", "assetsandgpo.com", "Policies", g),
+					filepath.Join(adc.sysvolCacheDir, "Policies", g), nil)
+				require.NoError(t, err, "Setup: cannot copy GPO to sysvol cache")
+			}
+			if tc.withAssets {
+				testutils.Copy(t, filepath.Join("testdata", "sysvolcache", "assets.db"), filepath.Join(adc.sysvolCacheDir, "assets.db"))
+			}
+			if !tc.noGPOList {
+				require.NoError(t, adc.saveGPOList(objectName, []byte(gpoList)), "Setup: cannot save GPO list")
+			}
+
+			var archive bytes.Buffer
+			err = adc.CaptureSnapshot(context.Background(), objectName, tc.objectClass, &archive)
+			if tc.wantCaptureErr {
+				require.Error(t, err, "CaptureSnapshot should have failed but didn't")
+				return
+			}
+			require.NoError(t, err, "CaptureSnapshot should not have failed")
+
+			s, err := LoadSnapshot(context.Background(), &archive, t.TempDir())
+			require.NoError(t, err, "LoadSnapshot should not have failed")
+			defer s.Policies.Close()
+
+			require.Equal(t, objectName, s.ObjectName, "LoadSnapshot should return the captured object name")
+			require.Equal(t, tc.objectClass, s.ObjectClass, "LoadSnapshot should return the captured object class")
+			require.Equal(t, "assetsandgpo.com", s.Domain, "LoadSnapshot should return the captured domain")
+
+			want, err := adc.parseGPOs(context.Background(), gpos, tc.objectClass, nil)
+			require.NoError(t, err, "Setup: cannot parse GPOs from sysvol cache")
+			require.Equal(t, want, s.Policies.GPOs, "LoadSnapshot should return the same rules than parsing the sysvol cache")
+			for i, g := range s.Policies.GPOs {
+				require.Equal(t, "DC=assetsandgpo,DC=com", g.Link, "LoadSnapshot should return the container each GPO is linked to")
+				require.Equal(t, gpos[i].enforced, g.Enforced, "LoadSnapshot should return if each GPO link is enforced")
+			}
+			err = s.Policies.SaveAssetsTo(context.Background(), ".", filepath.Join(t.TempDir(), "assets"), -1, -1)
+			if tc.withAssets {
+				require.NoError(t, err, "LoadSnapshot should return captured assets")
+			} else {
+				require.Error(t, err, "LoadSnapshot should not return any assets when none were captured")
+			}
+
+			online, err := s.Backend().IsOnline()
+			require.NoError(t, err, "IsOnline should not fail on snapshot backend")
+			require.False(t, online, "Snapshot backend should be offline")
+			_, err = s.Backend().ServerFQDN(context.Background())
+			require.ErrorIs(t, err, backends.ErrNoActiveServer, "Snapshot backend should have no active server")
+		})
+	}
+}
+
+func TestLoadSnapshotErrors(t *testing.T) {
+	t.Parallel()
+
+	metadata := "object_name: bob\nobject_class: user\ndomain: example.com\n"
+
+	tests := map[string]struct {
+		files  map[string]string
+		notGz  bool
+		symlnk bool
+	}{
+		"Error on not a compressed archive":     {notGz: true},
+		"Error on missing metadata":             {files: map[string]string{"gpolist": ""}},
+		"Error on invalid metadata":             {files: map[string]string{"snapshot.yaml": "-invalid-", "gpolist": ""}},
+		"Error on invalid object class":         {files: map[string]string{"snapshot.yaml": "object_class: group\n", "gpolist": ""}},
+		"Error on empty object name":            {files: map[string]string{"snapshot.yaml": "object_class: user\ndomain: example.com\n", "gpolist": ""}},
+		"Error on object name outside of cache": {files: map[string]string{"snapshot.yaml": "object_name: ../../x\nobject_class: user\ndomain: example.com\n", "gpolist": ""}},
+		"Error on object name with separators":  {files: map[string]string{"snapshot.yaml": "object_name: bob/x@example.com\nobject_class: user\n", "gpolist": ""}},
+		"Error on computer name as directory":   {files: map[string]string{"snapshot.yaml": "object_name: ..\nobject_class: computer\n", "gpolist": ""}},
+		"Error on user without domain":          {files: map[string]string{"snapshot.yaml": "object_name: bob\nobject_class: user\n", "gpolist": ""}},
+		"Error on missing GPO list":             {files: map[string]string{"snapshot.yaml": metadata}},
+		"Error on invalid GPO list":             {files: map[string]string{"snapshot.yaml": metadata, "gpolist": "no tab here\n"}},
+		"Error on path outside of destination":  {files: map[string]string{"snapshot.yaml": metadata, "../escaped": ""}},
+		"Error on absolute path":                {files: map[string]string{"snapshot.yaml": metadata, "/etc/escaped": ""}},
+		"Error on unsupported file type":        {files: map[string]string{"snapshot.yaml": metadata}, symlnk: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var archive bytes.Buffer
+			if tc.notGz {
+				archive.WriteString("not an archive")
+			} else {
+				gz := gzip.NewWriter(&archive)
+				tw := tar.NewWriter(gz)
+				for n, content := range tc.files {
+					require.NoError(t, addToArchive(tw, n, []byte(content)), "Setup: cannot add file to archive")
+				}
+				if tc.symlnk {
+					require.NoError(t, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeSymlink, Name: "gpolist", Linkname: "/etc/passwd"}),
+						"Setup: cannot add symlink to archive")
+				}
+				require.NoError(t, tw.Close(), "Setup: cannot close archive")
+				require.NoError(t, gz.Close(), "Setup: cannot close compressed archive")
+			}
+
+			dir := t.TempDir()
+			_, err := LoadSnapshot(context.Background(), &archive, dir)
+			require.Error(t, err, "LoadSnapshot should have failed but didn't")
+			require.NoFileExists(t, filepath.Join(filepath.Dir(dir), "escaped"), "No file should be extracted outside of the destination")
+		})
+	}
+}
+
+func TestLoadSnapshotNormalizesObjectName(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		metadata string
+
+		want string
+	}{
+		"User name is lowercased":           {metadata: "object_name: Bob@Example.COM\nobject_class: user\n", want: "bob@example.com"},
+		"User name without domain gets one": {metadata: "object_name: bob\nobject_class: user\ndomain: Example.com\n", want: "bob@example.com"},
+		"Computer name is not qualified":    {metadata: "object_name: Host.example.com\nobject_class: computer\n", want: "host"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var archive bytes.Buffer
+			gz := gzip.NewWriter(&archive)
+			tw := tar.NewWriter(gz)
+			require.NoError(t, addToArchive(tw, "snapshot.yaml", []byte(tc.metadata)), "Setup: cannot add metadata to archive")
+			require.NoError(t, addToArchive(tw, "gpolist", nil), "Setup: cannot add GPO list to archive")
+			require.NoError(t, tw.Close(), "Setup: cannot close archive")
+			require.NoError(t, gz.Close(), "Setup: cannot close compressed archive")
+
+			s, err := LoadSnapshot(context.Background(), &archive, t.TempDir())
+			require.NoError(t, err, "LoadSnapshot should not have failed")
+			defer s.Policies.Close()
+
+			require.Equal(t, tc.want, s.ObjectName, "LoadSnapshot should return the normalized object name")
+		})
+	}
+}
+
+func TestLoadSnapshotTargetsPreferencesToLocalMachine(t *testing.T) {
+	t.Parallel()
+
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, addToArchive(tw, "snapshot.yaml", []byte("object_name: bob@example.com\nobject_class: user\ndomain: example.com\n")),
+		"Setup: cannot add metadata to archive")
+	require.NoError(t, addToArchive(tw, "gpolist", []byte("preferences-targeted-name\tsmb://localhost/SYSVOL/gpoonly.com/Policies/preferences-targeted\n")),
+		"Setup: cannot add GPO list to archive")
+	require.NoError(t, addTreeToArchive(tw, filepath.Join("testdata", "AD", "SYSVOL", "gpoonly.com"), filepath.Join("Policies", "preferences-targeted")),
+		"Setup: cannot add GPO to archive")
+	require.NoError(t, tw.Close(), "Setup: cannot close archive")
+	require.NoError(t, gz.Close(), "Setup: cannot close compressed archive")
+
+	s, err := LoadSnapshot(context.Background(), &archive, t.TempDir())
+	require.NoError(t, err, "LoadSnapshot should not have failed")
+	defer s.Policies.Close()
+
+	require.Len(t, s.Policies.GPOs, 1, "LoadSnapshot should return the captured GPO")
+	for _, e := range s.Policies.GPOs[0].Rules["mount"] {
+		require.NotContains(t, e.Value, "projects", "Items targeting security groups should be skipped")
+		require.NotContains(t, e.Value, "sales", "Items targeting security groups should be skipped")
+	}
+}
@@ -0,0 +1,136 @@
+package wmifilter
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/leonelquinteros/gotext"
+	"github.com/ubuntu/decorate"
+)
+
+// classes are the supported WMI classes with their properties, in lower case.
+var classes = map[string][]string{
+	"win32_operatingsystem": {"caption", "version"},
+	"win32_computersystem":  {"manufacturer", "model", "name"},
+	"win32_environment":     {"name", "variablevalue"},
+}
+
+// Facts are the instances of each supported WMI class on the machine. Classes and properties are in lower case.
+type Facts map[string][]map[string]string
+
+// LoadFacts returns the facts of the machine whose root filesystem is root and named hostname.
+// Facts which can't be read, like DMI in some containers, are left unset.
+func LoadFacts(root, hostname string) (facts Facts, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't load machine facts for WMI filters"))
+
+	operatingSystem, err := osRelease(root)
+	if err != nil {
+		return nil, err
+	}
+	computer := map[string]string{"name": hostname}
+	for prop, file := range map[string]string{"manufacturer": "sys_vendor", "model": "product_name"} {
+		v, err := readFileIfExists(filepath.Join(root, "sys/class/dmi/id", file))
+		if err != nil {
+			return nil, err
+		}
+		if v = strings.TrimSpace(v); v != "" {
+			computer[prop] = v
+		}
+	}
+	env, err := environment(root)
+	if err != nil {
+		return nil, err
+	}
+
+	return Facts{
+		"win32_operatingsystem": {operatingSystem},
+		"win32_computersystem":  {computer},
+		"win32_environment":     env,
+	}, nil
+}
+
+// osRelease returns the Win32_OperatingSystem instance from os-release: PRETTY_NAME is the caption and VERSION_ID
+// the version.
+func osRelease(root string) (map[string]string, error) {
+	var content string
+	for _, p := range []string{"etc/os-release", "usr/lib/os-release"} {
+		var err error
+		if content, err = readFileIfExists(filepath.Join(root, p)); err != nil {
+			return nil, err
+		}
+		if content != "" {
+			break
+		}
+	}
+
+	instance := make(map[string]string)
+	for k, v := range parseShellVars(content) {
+		switch k {
+		case "PRETTY_NAME":
+			instance["caption"] = v
+		case "VERSION_ID":
+			instance["version"] = v
+		}
+	}
+	return instance, nil
+}
+
+// environment returns the Win32_Environment instances, one per variable of /etc/environment.
+func environment(root string) (instances []map[string]string, err error) {
+	content, err := readFileIfExists(filepath.Join(root, "etc/environment"))
+	if err != nil {
+		return nil, err
+	}
+	vars := parseShellVars(content)
+	names := make([]string, 0, len(vars))
+	for k := range vars {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	instances = make([]map[string]string, 0, len(names))
+	for _, k := range names {
+		instances = append(instances, map[string]string{"name": k, "variablevalue": vars[k]})
+	}
+	return instances, nil
+}
+
+// parseShellVars returns the KEY=value assignments of content, skipping comments and unquoting values.
+func parseShellVars(content string) map[string]string {
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewBufferString(content))
+	for scanner.Scan() {
+		l := strings.TrimSpace(scanner.Text())
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		k, v, found := strings.Cut(strings.TrimPrefix(l, "export "), "=")
+		if !found {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+			v = v[1 : len(v)-1]
+		}
+		vars[strings.TrimSpace(k)] = v
+	}
+	return vars
+}
+
+// readFileIfExists returns the content of p, or an empty string if it doesn't exist or can't be read.
+// Other errors are returned.
+func readFileIfExists(p string) (string, error) {
+	d, err := os.ReadFile(p)
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(d), nil
+}
@@ -0,0 +1,123 @@
+// Package wmifilter evaluates the WMI filters attached to GPOs against the local machine.
+//
+// Windows clients run the WQL queries of a filter against their WMI repository. We support a subset of WQL on a few
+// classes, whose properties are mapped to Linux facts:
+//   - Win32_OperatingSystem: Caption and Version, from os-release.
+//   - Win32_ComputerSystem: Manufacturer and Model, from DMI, and Name, the hostname.
+//   - Win32_Environment: Name and VariableValue, one instance per variable of /etc/environment.
+package wmifilter
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/leonelquinteros/gotext"
+	"github.com/ubuntu/decorate"
+)
+
+const (
+	// supportedLanguage is the only query language of WMI filters.
+	supportedLanguage = "WQL"
+	// supportedNamespace is the only WMI namespace we have facts for.
+	supportedNamespace = `root\CIMv2`
+)
+
+// ErrUnsupported is returned when a filter uses a WQL construct, a namespace, a class or a property we can't evaluate.
+var ErrUnsupported = errors.New(gotext.Get("unsupported WMI filter"))
+
+// Filter is a WMI filter. It matches if each of its queries returns at least one instance.
+type Filter struct {
+	Name    string
+	queries []query
+}
+
+// Parse returns the filter named name from its msWMI-Parm2 attribute value.
+// This value is the number of queries followed, for each query, by the length of its language, namespace and
+// statement, then by these 3 fields. All the elements are semicolon terminated:
+// 1;3;10;56;WQL;root\CIMv2;SELECT * FROM Win32_ComputerSystem WHERE Model = "Virtual";
+// The returned error wraps ErrUnsupported if the filter is valid but can't be evaluated on this machine.
+func Parse(name, parm2 string) (f Filter, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't parse WMI filter %q", name))
+
+	f.Name = name
+
+	r := parm2
+	field := func() (string, error) {
+		v, rest, found := strings.Cut(r, ";")
+		if !found {
+			return "", errors.New(gotext.Get("missing field terminator in %q", parm2))
+		}
+		r = rest
+		return v, nil
+	}
+	// fixedField reads a field of length n, which can contain semicolons.
+	fixedField := func(n int) (string, error) {
+		if n < 0 || n >= len(r) || r[n] != ';' {
+			return "", errors.New(gotext.Get("invalid field length %d in %q", n, parm2))
+		}
+		v := r[:n]
+		r = r[n+1:]
+		return v, nil
+	}
+	number := func() (int, error) {
+		v, err := field()
+		if err != nil {
+			return 0, err
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, errors.New(gotext.Get("invalid number %q in %q", v, parm2))
+		}
+		return n, nil
+	}
+
+	count, err := number()
+	if err != nil {
+		return f, err
+	}
+	for i := 0; i < count; i++ {
+		var lengths [3]int
+		for j := range lengths {
+			if lengths[j], err = number(); err != nil {
+				return f, err
+			}
+		}
+		var values [3]string
+		for j := range values {
+			if values[j], err = fixedField(lengths[j]); err != nil {
+				return f, err
+			}
+		}
+		language, namespace, statement := values[0], values[1], values[2]
+
+		if !strings.EqualFold(language, supportedLanguage) {
+			return f, fmt.Errorf("%w: %s", ErrUnsupported, gotext.Get("query language %q", language))
+		}
+		if !strings.EqualFold(strings.TrimPrefix(namespace, `\\.\`), supportedNamespace) {
+			return f, fmt.Errorf("%w: %s", ErrUnsupported, gotext.Get("namespace %q", namespace))
+		}
+
+		q, err := parseQuery(statement)
+		if err != nil {
+			return f, err
+		}
+		f.queries = append(f.queries, q)
+	}
+	if len(f.queries) == 0 {
+		return f, errors.New(gotext.Get("no query in %q", parm2))
+	}
+
+	return f, nil
+}
+
+// Match returns true if each query of the filter returns at least one instance from facts.
+func (f Filter) Match(facts Facts) bool {
+	for _, q := range f.queries {
+		if !q.match(facts) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,197 @@
+package wmifilter_test
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/ubuntu/adsys/internal/ad/wmifilter"
+	"github.com/ubuntu/adsys/internal/testutils"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		parm2 string
+
+		wantErr         bool
+		wantUnsupported bool
+	}{
+		"One query":                            {parm2: parm2(`SELECT * FROM Win32_ComputerSystem`)},
+		"Multiple queries":                     {parm2: parm2(`SELECT * FROM Win32_ComputerSystem`, `SELECT * FROM Win32_OperatingSystem`)},
+		"Semicolon in query":                   {parm2: parm2(`SELECT * FROM Win32_ComputerSystem WHERE Model = "a;b"`)},
+		"Namespace with local computer prefix": {parm2: `1;3;14;34;WQL;\\.\root\CIMv2;SELECT * FROM Win32_ComputerSystem;`},
+		"Keywords and names are case insensitive": {parm2: parm2(
+			`select name from win32_computersystem where NAME = 'host'`)},
+		"Selected properties": {parm2: parm2(`SELECT Model, Manufacturer FROM Win32_ComputerSystem`)},
+
+		"Error on unsupported language":          {parm2: `1;3;10;34;SQL;root\CIMv2;SELECT * FROM Win32_ComputerSystem;`, wantUnsupported: true},
+		"Error on unsupported namespace":         {parm2: `1;3;11;34;WQL;root\Custom;SELECT * FROM Win32_ComputerSystem;`, wantUnsupported: true},
+		"Error on unsupported class":             {parm2: parm2(`SELECT * FROM Win32_Battery`), wantUnsupported: true},
+		"Error on unsupported property":          {parm2: parm2(`SELECT * FROM Win32_ComputerSystem WHERE TotalPhysicalMemory > 1000`), wantUnsupported: true},
+		"Error on unsupported selected property": {parm2: parm2(`SELECT Domain FROM Win32_ComputerSystem`), wantUnsupported: true},
+		"Error on unsupported operator":          {parm2: parm2(`SELECT * FROM Win32_ComputerSystem WHERE Name ISA "a"`), wantUnsupported: true},
+
+		"Error on empty value":                   {parm2: "", wantErr: true},
+		"Error on no query":                      {parm2: "0;", wantErr: true},
+		"Error on invalid query count":           {parm2: "a;", wantErr: true},
+		"Error on missing lengths":               {parm2: "1;3;10;", wantErr: true},
+		"Error on invalid length":                {parm2: `1;3;10;500;WQL;root\CIMv2;SELECT * FROM Win32_ComputerSystem;`, wantErr: true},
+		"Error on negative length":               {parm2: `1;3;10;-1;WQL;root\CIMv2;SELECT * FROM Win32_ComputerSystem;`, wantErr: true},
+		"Error on missing query":                 {parm2: "2;" + parm2(`SELECT * FROM Win32_ComputerSystem`)[2:], wantErr: true},
+		"Error on missing SELECT":                {parm2: parm2(`DELETE FROM Win32_ComputerSystem`), wantErr: true},
+		"Error on missing FROM":                  {parm2: parm2(`SELECT * Win32_ComputerSystem`), wantErr: true},
+		"Error on missing class":                 {parm2: parm2(`SELECT * FROM`), wantErr: true},
+		"Error on missing selected property":     {parm2: parm2(`SELECT Name, FROM Win32_ComputerSystem`), wantErr: true},
+		"Error on unterminated string":           {parm2: parm2(`SELECT * FROM Win32_ComputerSystem WHERE Name = "a`), wantErr: true},
+		"Error on invalid operator":              {parm2: parm2(`SELECT * FROM Win32_ComputerSystem WHERE Name => "a"`), wantErr: true},
+		"Error on unexpected character":          {parm2: parm2(`SELECT * FROM Win32_ComputerSystem WHERE Name = @`), wantErr: true},
+		"Error on missing value":                 {parm2: parm2(`SELECT * FROM Win32_ComputerSystem WHERE Name =`), wantErr: true},
+		"Error on missing operator":              {parm2: parm2(`SELECT * FROM Win32_ComputerSystem WHERE Name`), wantErr: true},
+		"Error on missing property in condition": {parm2: parm2(`SELECT * FROM Win32_ComputerSystem WHERE = "a"`), wantErr: true},
+		"Error on unclosed parenthesis":          {parm2: parm2(`SELECT * FROM Win32_ComputerSystem WHERE (Name = "a"`), wantErr: true},
+		"Error on invalid right operand":         {parm2: parm2(`SELECT * FROM Win32_ComputerSystem WHERE Name = (Model)`), wantErr: true},
+		"Error on IS without NULL":               {parm2: parm2(`SELECT * FROM Win32_ComputerSystem WHERE Name IS "a"`), wantErr: true},
+		"Error on NOT without LIKE":              {parm2: parm2(`SELECT * FROM Win32_ComputerSystem WHERE Name NOT = "a"`), wantErr: true},
+		"Error on LIKE without pattern":          {parm2: parm2(`SELECT * FROM Win32_ComputerSystem WHERE Name LIKE 3`), wantErr: true},
+		"Error on unterminated LIKE set":         {parm2: parm2(`SELECT * FROM Win32_ComputerSystem WHERE Name LIKE "[a"`), wantErr: true},
+		"Error on trailing tokens":               {parm2: parm2(`SELECT * FROM Win32_ComputerSystem WHERE Name = "a" "b"`), wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := wmifilter.Parse("myfilter", tc.parm2)
+			if tc.wantErr || tc.wantUnsupported {
+				require.Error(t, err, "Parse should have failed but didn't")
+				require.Equal(t, tc.wantUnsupported, errors.Is(err, wmifilter.ErrUnsupported), "Parse should return ErrUnsupported only for unsupported filters")
+				return
+			}
+			require.NoError(t, err, "Parse should not have failed")
+			require.Equal(t, "myfilter", f.Name, "Parse should set the filter name")
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		queries []string
+		machine string
+
+		want bool
+	}{
+		// Classes
+		"Operating system version":      {queries: []string{`SELECT * FROM Win32_OperatingSystem WHERE Version = "22.04"`}, want: true},
+		"Operating system caption":      {queries: []string{`SELECT * FROM Win32_OperatingSystem WHERE Caption LIKE "Ubuntu%LTS"`}, want: true},
+		"Operating system from usr lib": {machine: "vm", queries: []string{`SELECT * FROM Win32_OperatingSystem WHERE Version = "24.04"`}, want: true},
+		"Computer manufacturer":         {queries: []string{`SELECT * FROM Win32_ComputerSystem WHERE Manufacturer = "Lenovo"`}, want: true},
+		"Computer model":                {queries: []string{`SELECT * FROM Win32_ComputerSystem WHERE Model LIKE "%ThinkPad%"`}, want: true},
+		"Computer model of a VM":        {machine: "vm", queries: []string{`SELECT * FROM Win32_ComputerSystem WHERE Manufacturer = "QEMU" OR Model LIKE "%Virtual%"`}, want: true},
+		"Computer name":                 {queries: []string{`SELECT * FROM Win32_ComputerSystem WHERE Name = "MYHOST"`}, want: true},
+		"Environment variable":          {queries: []string{`SELECT * FROM Win32_Environment WHERE Name = "OFFICE" AND VariableValue = "Paris"`}, want: true},
+		"Environment variable not set":  {queries: []string{`SELECT * FROM Win32_Environment WHERE Name = "FLOOR"`}},
+		"Environment variables are separate instances": {queries: []string{
+			`SELECT * FROM Win32_Environment WHERE Name = "OFFICE" AND VariableValue = "Sales"`}},
+		"Any instance of a class":                      {queries: []string{`SELECT * FROM Win32_ComputerSystem`}, want: true},
+		"No instance of a class":                       {machine: "empty", queries: []string{`SELECT * FROM Win32_Environment`}},
+		"Missing facts are NULL":                       {machine: "empty", queries: []string{`SELECT * FROM Win32_ComputerSystem WHERE Model IS NULL`}, want: true},
+		"Missing facts don't match comparisons":        {machine: "empty", queries: []string{`SELECT * FROM Win32_ComputerSystem WHERE Model <> "a"`}},
+		"Present facts are not NULL":                   {queries: []string{`SELECT * FROM Win32_ComputerSystem WHERE Model IS NOT NULL`}, want: true},
+		"Comparison to NULL never matches":             {queries: []string{`SELECT * FROM Win32_ComputerSystem WHERE Model = NULL`}},
+		"All queries must match":                       {queries: []string{`SELECT * FROM Win32_ComputerSystem`, `SELECT * FROM Win32_OperatingSystem WHERE Version = "24.04"`}},
+		"Multiple queries matching":                    {queries: []string{`SELECT * FROM Win32_ComputerSystem`, `SELECT * FROM Win32_OperatingSystem WHERE Version = "22.04"`}, want: true},
+		"Selected properties don't change the results": {queries: []string{`SELECT Model FROM Win32_ComputerSystem WHERE Name = "myhost"`}, want: true},
+
+		// Operators
+		"Numeric comparison":                {queries: []string{`SELECT * FROM Win32_OperatingSystem WHERE Version >= 20.10`}, want: true},
+		"Numeric comparison is not lexical": {queries: []string{`SELECT * FROM Win32_OperatingSystem WHERE Version < "3"`}},
+		"String comparison":                 {queries: []string{`SELECT * FROM Win32_ComputerSystem WHERE Manufacturer > "Dell" AND Manufacturer <= 'lenovo'`}, want: true},
+		"Different values":                  {queries: []string{`SELECT * FROM Win32_ComputerSystem WHERE Manufacturer <> "Dell" AND Manufacturer != "HP"`}, want: true},
+		"Lesser value":                      {queries: []string{`SELECT * FROM Win32_OperatingSystem WHERE Version < 22.04 OR Version > 22.04`}},
+		"NOT":                               {queries: []string{`SELECT * FROM Win32_ComputerSystem WHERE NOT Manufacturer = "QEMU"`}, want: true},
+		"NOT LIKE":                          {queries: []string{`SELECT * FROM Win32_ComputerSystem WHERE Model NOT LIKE "%Virtual%"`}, want: true},
+		"Parenthesis":                       {queries: []string{`SELECT * FROM Win32_ComputerSystem WHERE (Manufacturer = "QEMU" OR Manufacturer = "Lenovo") AND Name = "myhost"`}, want: true},
+		"AND has precedence over OR":        {queries: []string{`SELECT * FROM Win32_ComputerSystem WHERE Manufacturer = "Lenovo" OR Manufacturer = "QEMU" AND Name = "other"`}, want: true},
+		"Boolean literal":                   {queries: []string{`SELECT * FROM Win32_Environment WHERE VariableValue = TRUE`}},
+		"Escaped quote in string":           {queries: []string{`SELECT * FROM Win32_ComputerSystem WHERE Model = "Thin\"kPad"`}},
+
+		// LIKE patterns
+		"LIKE any character":                {queries: []string{`SELECT * FROM Win32_OperatingSystem WHERE Version LIKE "22_04"`}, want: true},
+		"LIKE character set":                {queries: []string{`SELECT * FROM Win32_OperatingSystem WHERE Version LIKE "2[0-2].04"`}, want: true},
+		"LIKE negated character set":        {queries: []string{`SELECT * FROM Win32_OperatingSystem WHERE Version LIKE "2[^0-2].04"`}},
+		"LIKE matches the whole value":      {queries: []string{`SELECT * FROM Win32_ComputerSystem WHERE Model LIKE "ThinkPad"`}},
+		"LIKE is case insensitive":          {queries: []string{`SELECT * FROM Win32_ComputerSystem WHERE Model LIKE "thinkpad%"`}, want: true},
+		"LIKE special characters are plain": {queries: []string{`SELECT * FROM Win32_OperatingSystem WHERE Version LIKE "22.0."`}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if tc.machine == "" {
+				tc.machine = "laptop"
+			}
+			root := filepath.Join("testdata", "machines", tc.machine)
+			if tc.machine == "empty" {
+				root = t.TempDir()
+			}
+			facts, err := wmifilter.LoadFacts(root, "myhost")
+			require.NoError(t, err, "Setup: LoadFacts should not have failed")
+
+			f, err := wmifilter.Parse("myfilter", parm2(tc.queries...))
+			require.NoError(t, err, "Setup: Parse should not have failed")
+
+			require.Equal(t, tc.want, f.Match(facts), "Match should return the expected result")
+		})
+	}
+}
+
+func TestLoadFacts(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		machine string
+
+		wantErr bool
+	}{
+		"Laptop":                                {machine: "laptop"},
+		"VM with os-release only under usr lib": {machine: "vm"},
+		"No facts available":                    {},
+		"Error on unreadable os-release":        {machine: "os-release-is-a-directory", wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			root := t.TempDir()
+			if tc.machine != "" {
+				root = filepath.Join("testdata", "machines", tc.machine)
+			}
+
+			got, err := wmifilter.LoadFacts(root, "myhost")
+			if tc.wantErr {
+				require.Error(t, err, "LoadFacts should have failed but didn't")
+				return
+			}
+			require.NoError(t, err, "LoadFacts should not have failed")
+
+			want := testutils.LoadWithUpdateFromGoldenYAML(t, got)
+			require.Equal(t, want, got, "LoadFacts should return the expected facts")
+		})
+	}
+}
+
+// parm2 returns the msWMI-Parm2 value of a filter made of queries.
+func parm2(queries ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d;", len(queries))
+	for _, q := range queries {
+		fmt.Fprintf(&b, "3;10;%d;WQL;root\\CIMv2;%s;", len(q), q)
+	}
+	return b.String()
+}
@@ -0,0 +1,461 @@
+package wmifilter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/leonelquinteros/gotext"
+)
+
+// query is a WQL SELECT statement.
+type query struct {
+	class string
+	// where is nil when the query has no WHERE clause.
+	where expr
+}
+
+// match returns true if at least one instance of the query class in facts matches its WHERE clause.
+func (q query) match(facts Facts) bool {
+	for _, instance := range facts[q.class] {
+		if q.where == nil || q.where.eval(instance) {
+			return true
+		}
+	}
+	return false
+}
+
+// expr is a condition of a WHERE clause, evaluated on one instance.
+type expr interface {
+	eval(instance map[string]string) bool
+}
+
+type andExpr struct{ left, right expr }
+
+func (e andExpr) eval(instance map[string]string) bool {
+	return e.left.eval(instance) && e.right.eval(instance)
+}
+
+type orExpr struct{ left, right expr }
+
+func (e orExpr) eval(instance map[string]string) bool {
+	return e.left.eval(instance) || e.right.eval(instance)
+}
+
+type notExpr struct{ e expr }
+
+func (e notExpr) eval(instance map[string]string) bool {
+	return !e.e.eval(instance)
+}
+
+// comparison compares a property to a literal value. A property without any value is NULL, which only matches
+// IS NULL.
+type comparison struct {
+	property string
+	op       string
+	value    string
+	// null is set when value is the NULL literal.
+	null bool
+	// like is the compiled pattern of LIKE comparisons.
+	like *regexp.Regexp
+}
+
+func (c comparison) eval(instance map[string]string) bool {
+	v, ok := instance[c.property]
+	switch c.op {
+	case "IS":
+		return !ok
+	case "IS NOT":
+		return ok
+	}
+	if !ok || c.null {
+		return false
+	}
+
+	switch c.op {
+	case "LIKE":
+		return c.like.MatchString(v)
+	case "=":
+		return compare(v, c.value) == 0
+	case "<>", "!=":
+		return compare(v, c.value) != 0
+	case "<":
+		return compare(v, c.value) < 0
+	case "<=":
+		return compare(v, c.value) <= 0
+	case ">":
+		return compare(v, c.value) > 0
+	case ">=":
+		return compare(v, c.value) >= 0
+	}
+	return false
+}
+
+// compare compares a and b numerically if both are numbers, and as case insensitive strings otherwise.
+func compare(a, b string) int {
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// likeToRegexp converts a WQL LIKE pattern to a case insensitive regular expression.
+// % matches any string, _ any character and [] a character range or set, negated with ^.
+func likeToRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("(?is)^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return nil, errors.New(gotext.Get("unterminated [ in LIKE pattern %q", pattern))
+			}
+			set := pattern[i+1 : i+1+end]
+			b.WriteString("[")
+			if strings.HasPrefix(set, "^") {
+				b.WriteString("^")
+				set = set[1:]
+			}
+			// Only ranges are special in sets.
+			b.WriteString(strings.ReplaceAll(regexp.QuoteMeta(set), `\-`, "-"))
+			b.WriteString("]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+/*
+ * Lexer
+ */
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenPunct
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+// tokenize splits a WQL statement in tokens.
+func tokenize(s string) (tokens []token, err error) {
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '\'' || c == '"':
+			var v strings.Builder
+			j := i + 1
+			for ; j < len(s) && rune(s[j]) != c; j++ {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+				}
+				v.WriteByte(s[j])
+			}
+			if j >= len(s) {
+				return nil, errors.New(gotext.Get("unterminated string in %q", s))
+			}
+			tokens = append(tokens, token{kind: tokenString, value: v.String()})
+			i = j + 1
+		case c == '-' || c == '.' || unicode.IsDigit(c):
+			j := i + 1
+			for j < len(s) && (s[j] == '.' || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, value: s[i:j]})
+			i = j
+		case c == '_' || unicode.IsLetter(c):
+			j := i + 1
+			for j < len(s) && (s[j] == '_' || unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, value: s[i:j]})
+			i = j
+		case strings.ContainsRune("<>!=", c):
+			j := i + 1
+			if j < len(s) && strings.ContainsRune("<>=", rune(s[j])) {
+				j++
+			}
+			op := s[i:j]
+			if !strings.Contains(" = <> != < <= > >= ", " "+op+" ") {
+				return nil, errors.New(gotext.Get("invalid operator %q in %q", op, s))
+			}
+			tokens = append(tokens, token{kind: tokenOperator, value: op})
+			i = j
+		case strings.ContainsRune("(),*", c):
+			tokens = append(tokens, token{kind: tokenPunct, value: string(c)})
+			i++
+		default:
+			return nil, errors.New(gotext.Get("unexpected character %q in %q", c, s))
+		}
+	}
+	return tokens, nil
+}
+
+/*
+ * Parser
+ */
+
+type parser struct {
+	tokens []token
+	pos    int
+	class  string
+}
+
+// parseQuery parses a WQL SELECT statement on one of the supported classes:
+// SELECT * | property [, property…] FROM class [WHERE condition]
+// Conditions are comparisons of a property to a literal, combined with AND, OR, NOT and parenthesis.
+func parseQuery(statement string) (q query, err error) {
+	tokens, err := tokenize(statement)
+	if err != nil {
+		return q, err
+	}
+	p := parser{tokens: tokens}
+
+	if !p.keyword("SELECT") {
+		return q, p.errorf(gotext.Get("expected SELECT"))
+	}
+	var selected []string
+	if !p.punct("*") {
+		for {
+			t := p.next()
+			if t.kind != tokenIdent {
+				return q, p.errorf(gotext.Get("expected property name"))
+			}
+			selected = append(selected, t.value)
+			if !p.punct(",") {
+				break
+			}
+		}
+	}
+
+	if !p.keyword("FROM") {
+		return q, p.errorf(gotext.Get("expected FROM"))
+	}
+	t := p.next()
+	if t.kind != tokenIdent {
+		return q, p.errorf(gotext.Get("expected class name"))
+	}
+	p.class = strings.ToLower(t.value)
+	if _, ok := classes[p.class]; !ok {
+		return q, fmt.Errorf("%w: %s", ErrUnsupported, gotext.Get("class %q", t.value))
+	}
+	q.class = p.class
+	for _, prop := range selected {
+		if _, err := p.property(prop); err != nil {
+			return q, err
+		}
+	}
+
+	if p.keyword("WHERE") {
+		if q.where, err = p.or(); err != nil {
+			return q, err
+		}
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return q, p.errorf(gotext.Get("unexpected %q", t.value))
+	}
+
+	return q, nil
+}
+
+func (p *parser) peek() token {
+	if p.pos >= len(p.tokens) {
+		return token{kind: tokenEOF}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// keyword consumes the next token if it is the keyword k.
+func (p *parser) keyword(k string) bool {
+	if t := p.peek(); t.kind == tokenIdent && strings.EqualFold(t.value, k) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// punct consumes the next token if it is the punctuation c.
+func (p *parser) punct(c string) bool {
+	if t := p.peek(); t.kind == tokenPunct && t.value == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) errorf(msg string) error {
+	return errors.New(gotext.Get("invalid WQL statement at token %d: %s", p.pos+1, msg))
+}
+
+// property returns the normalized name of property, which must be one of the query class.
+func (p *parser) property(name string) (string, error) {
+	n := strings.ToLower(name)
+	for _, prop := range classes[p.class] {
+		if n == prop {
+			return n, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnsupported, gotext.Get("property %q", name))
+}
+
+// or parses: and [OR and…].
+func (p *parser) or() (expr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left, right}
+	}
+	return left, nil
+}
+
+// and parses: not [AND not…].
+func (p *parser) and() (expr, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left, right}
+	}
+	return left, nil
+}
+
+// not parses: [NOT] primary.
+func (p *parser) not() (expr, error) {
+	if p.keyword("NOT") {
+		e, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{e}, nil
+	}
+	return p.primary()
+}
+
+// primary parses: ( or ) | property operator literal | property [NOT] LIKE string | property IS [NOT] NULL.
+func (p *parser) primary() (expr, error) {
+	if p.punct("(") {
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.punct(")") {
+			return nil, p.errorf(gotext.Get("expected )"))
+		}
+		return e, nil
+	}
+
+	t := p.next()
+	if t.kind != tokenIdent {
+		return nil, p.errorf(gotext.Get("expected property name"))
+	}
+	prop, err := p.property(t.value)
+	if err != nil {
+		return nil, err
+	}
+	c := comparison{property: prop}
+
+	switch {
+	case p.keyword("IS"):
+		c.op = "IS"
+		if p.keyword("NOT") {
+			c.op = "IS NOT"
+		}
+		if !p.keyword("NULL") {
+			return nil, p.errorf(gotext.Get("expected NULL"))
+		}
+		return c, nil
+
+	case p.keyword("NOT"):
+		if !p.keyword("LIKE") {
+			return nil, p.errorf(gotext.Get("expected LIKE"))
+		}
+		e, err := p.like(c)
+		return notExpr{e}, err
+
+	case p.keyword("LIKE"):
+		return p.like(c)
+
+	case p.peek().kind == tokenOperator:
+		c.op = p.next().value
+	default:
+		if p.peek().kind == tokenIdent {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupported, gotext.Get("operator %q", p.peek().value))
+		}
+		return nil, p.errorf(gotext.Get("expected operator"))
+	}
+
+	v := p.next()
+	switch {
+	case v.kind == tokenString, v.kind == tokenNumber:
+		c.value = v.value
+	case v.kind == tokenIdent && strings.EqualFold(v.value, "NULL"):
+		c.null = true
+	case v.kind == tokenIdent && (strings.EqualFold(v.value, "TRUE") || strings.EqualFold(v.value, "FALSE")):
+		c.value = strings.ToLower(v.value)
+	default:
+		return nil, p.errorf(gotext.Get("expected literal value"))
+	}
+	return c, nil
+}
+
+// like completes c with the LIKE pattern which follows.
+func (p *parser) like(c comparison) (expr, error) {
+	v := p.next()
+	if v.kind != tokenString {
+		return nil, p.errorf(gotext.Get("expected LIKE pattern"))
+	}
+	re, err := likeToRegexp(v.value)
+	if err != nil {
+		return nil, err
+	}
+	c.op, c.value, c.like = "LIKE", v.value, re
+	return c, nil
+}
@@ -44,6 +44,9 @@ type Service struct {
 
 	bus    *dbus.Conn
 	daemon *daemon.Daemon
+
+	// stopDriftWatch stops checking periodically for policies drift, if enabled.
+	stopDriftWatch func()
 }
 
 type state struct {
@@ -59,20 +62,27 @@ type state struct {
 }
 
 type options struct {
-	cacheDir       string
-	stateDir       string
-	runDir         string
-	dconfDir       string
-	sudoersDir     string
-	policyKitDir   string
-	apparmorDir    string
-	apparmorFsDir  string
-	systemUnitDir  string
-	globalTrustDir string
-	adBackend      string
-	sssConfig      sss.Config
-	winbindConfig  winbind.Config
-	authorizer     authorizerer
+	cacheDir         string
+	stateDir         string
+	runDir           string
+	dconfDir         string
+	sudoersDir       string
+	policyKitDir     string
+	apparmorDir      string
+	apparmorFsDir    string
+	systemUnitDir    string
+	globalTrustDir   string
+	securityDir      string
+	nftablesDir      string
+	environmentDir   string
+	profileDir       string
+	pluginsDir       string
+	driftInterval    time.Duration
+	adBackend        string
+	sssConfig        sss.Config
+	winbindConfig    winbind.Config
+	wmiFilterDefault string
+	authorizer       authorizerer
 }
 type option func(*options) error
 
@@ -162,6 +172,55 @@ func WithGlobalTrustDir(p string) func(o *options) error {
 	}
 }
 
+// WithSecurityDir specifies a personalized directory for PAM modules configuration.
+func WithSecurityDir(p string) func(o *options) error {
+	return func(o *options) error {
+		o.securityDir = p
+		return nil
+	}
+}
+
+// WithNftablesDir specifies a personalized directory for nftables rulesets.
+func WithNftablesDir(p string) func(o *options) error {
+	return func(o *options) error {
+		o.nftablesDir = p
+		return nil
+	}
+}
+
+// WithEnvironmentDir specifies a personalized environment.d directory for machine environment variables.
+func WithEnvironmentDir(p string) func(o *options) error {
+	return func(o *options) error {
+		o.environmentDir = p
+		return nil
+	}
+}
+
+// WithProfileDir specifies a personalized profile.d directory for login shells environment variables.
+func WithProfileDir(p string) func(o *options) error {
+	return func(o *options) error {
+		o.profileDir = p
+		return nil
+	}
+}
+
+// WithPluginsDir specifies a personalized directory for policy plugins.
+func WithPluginsDir(p string) func(o *options) error {
+	return func(o *options) error {
+		o.pluginsDir = p
+		return nil
+	}
+}
+
+// WithDriftCheckInterval enables checking periodically that the system state still matches the applied policies,
+// applying them again if it drifted. 0 disables the check.
+func WithDriftCheckInterval(d time.Duration) func(o *options) error {
+	return func(o *options) error {
+		o.driftInterval = d
+		return nil
+	}
+}
+
 // WithADBackend specifies our specific backend to select.
 func WithADBackend(backend string) func(o *options) error {
 	return func(o *options) error {
@@ -186,6 +245,15 @@ func WithWinbindConfig(c winbind.Config) func(o *options) error {
 	}
 }
 
+// WithWMIFilterDefault specifies if GPOs whose WMI filter can't be evaluated are applied ("apply") or
+// skipped ("skip").
+func WithWMIFilterDefault(d string) func(o *options) error {
+	return func(o *options) error {
+		o.wmiFilterDefault = d
+		return nil
+	}
+}
+
 // New returns a new instance of an AD service.
 // If url or domain is empty, we load the missing parameters from sssd.conf, taking first
 // domain in the list if not provided.
@@ -219,19 +287,10 @@ func New(ctx context.Context, opts ...option) (s *Service, err error) {
 		return nil, err
 	}
 
-	// Don’t call dbus.SystemBus which caches globally system dbus (issues in tests)
-	bus, err := dbus.SystemBusPrivate()
+	bus, err := newSystemBus()
 	if err != nil {
 		return nil, err
 	}
-	if err = bus.Auth(nil); err != nil {
-		_ = bus.Close()
-		return nil, err
-	}
-	if err = bus.Hello(); err != nil {
-		_ = bus.Close()
-		return nil, err
-	}
 
 	var adOptions []ad.Option
 	if args.cacheDir != "" {
@@ -241,13 +300,18 @@ func New(ctx context.Context, opts ...option) (s *Service, err error) {
 		adOptions = append(adOptions, ad.WithRunDir(args.runDir))
 	}
 	adOptions = append(adOptions, ad.WithGpoListTimeout(consts.DefaultGpoListTimeout))
+	switch args.wmiFilterDefault {
+	default:
+		log.Warningf(ctx, "Unknown configured WMI filter default %q. Defaulting to apply.", args.wmiFilterDefault)
+	case "", "apply":
+	case "skip":
+		adOptions = append(adOptions, ad.WithWMIFilterDefault(false))
+	}
 
-	hostname, err := os.Hostname()
+	hostname, err := localHostname()
 	if err != nil {
 		return nil, err
 	}
-	// For machines where /proc/sys/kernel/hostname returns FQDN, cut it.
-	hostname, _, _ = strings.Cut(hostname, ".")
 
 	// AD Backend selection
 	var adBackend backends.Backend
@@ -279,38 +343,7 @@ func New(ctx context.Context, opts ...option) (s *Service, err error) {
 		}
 	}
 
-	var policyOptions []policies.Option
-	if args.cacheDir != "" {
-		policyOptions = append(policyOptions, policies.WithCacheDir(args.cacheDir))
-	}
-	if args.stateDir != "" {
-		policyOptions = append(policyOptions, policies.WithStateDir(args.stateDir))
-	}
-	if args.dconfDir != "" {
-		policyOptions = append(policyOptions, policies.WithDconfDir(args.dconfDir))
-	}
-	if args.sudoersDir != "" {
-		policyOptions = append(policyOptions, policies.WithSudoersDir(args.sudoersDir))
-	}
-	if args.policyKitDir != "" {
-		policyOptions = append(policyOptions, policies.WithPolicyKitDir(args.policyKitDir))
-	}
-	if args.runDir != "" {
-		policyOptions = append(policyOptions, policies.WithRunDir(args.runDir))
-	}
-	if args.apparmorDir != "" {
-		policyOptions = append(policyOptions, policies.WithApparmorDir(args.apparmorDir))
-	}
-	if args.apparmorFsDir != "" {
-		policyOptions = append(policyOptions, policies.WithApparmorFsDir(args.apparmorFsDir))
-	}
-	if args.systemUnitDir != "" {
-		policyOptions = append(policyOptions, policies.WithSystemUnitDir(args.systemUnitDir))
-	}
-	if args.globalTrustDir != "" {
-		policyOptions = append(policyOptions, policies.WithGlobalTrustDir(args.globalTrustDir))
-	}
-	m, err := policies.NewManager(bus, hostname, adBackend, policyOptions...)
+	m, err := policies.NewManager(bus, hostname, adBackend, args.policyOptions()...)
 	if err != nil {
 		return nil, err
 	}
@@ -318,7 +351,7 @@ func New(ctx context.Context, opts ...option) (s *Service, err error) {
 	// Init system reference time
 	initSysTime := initSystemTime(bus)
 
-	return &Service{
+	s = &Service{
 		adc:           adc,
 		policyManager: m,
 		authorizer:    args.authorizer,
@@ -335,7 +368,14 @@ func New(ctx context.Context, opts ...option) (s *Service, err error) {
 		},
 		initSystemTime: initSysTime,
 		bus:            bus,
-	}, nil
+	}
+
+	if args.driftInterval > 0 {
+		log.Infof(ctx, "Checking for policies drift every %s", args.driftInterval)
+		s.stopDriftWatch = s.watchDrift(context.Background(), args.driftInterval)
+	}
+
+	return s, nil
 }
 
 // RegisterGRPCServer registers our service with the new interceptor chains.
@@ -355,11 +395,93 @@ func (s *Service) RegisterGRPCServer(d *daemon.Daemon) *grpc.Server {
 
 // Quit cleans every ressources than the service was using.
 func (s *Service) Quit(ctx context.Context) {
+	if s.stopDriftWatch != nil {
+		s.stopDriftWatch()
+	}
 	if err := s.bus.Close(); err != nil {
 		log.Warning(ctx, gotext.Get("Can't disconnect system dbus: %v", err))
 	}
 }
 
+// policyOptions returns the policies manager options matching args.
+func (args options) policyOptions() (policyOptions []policies.Option) {
+	if args.cacheDir != "" {
+		policyOptions = append(policyOptions, policies.WithCacheDir(args.cacheDir))
+	}
+	if args.stateDir != "" {
+		policyOptions = append(policyOptions, policies.WithStateDir(args.stateDir))
+	}
+	if args.dconfDir != "" {
+		policyOptions = append(policyOptions, policies.WithDconfDir(args.dconfDir))
+	}
+	if args.sudoersDir != "" {
+		policyOptions = append(policyOptions, policies.WithSudoersDir(args.sudoersDir))
+	}
+	if args.policyKitDir != "" {
+		policyOptions = append(policyOptions, policies.WithPolicyKitDir(args.policyKitDir))
+	}
+	if args.runDir != "" {
+		policyOptions = append(policyOptions, policies.WithRunDir(args.runDir))
+	}
+	if args.apparmorDir != "" {
+		policyOptions = append(policyOptions, policies.WithApparmorDir(args.apparmorDir))
+	}
+	if args.apparmorFsDir != "" {
+		policyOptions = append(policyOptions, policies.WithApparmorFsDir(args.apparmorFsDir))
+	}
+	if args.systemUnitDir != "" {
+		policyOptions = append(policyOptions, policies.WithSystemUnitDir(args.systemUnitDir))
+	}
+	if args.globalTrustDir != "" {
+		policyOptions = append(policyOptions, policies.WithGlobalTrustDir(args.globalTrustDir))
+	}
+	if args.securityDir != "" {
+		policyOptions = append(policyOptions, policies.WithSecurityDir(args.securityDir))
+	}
+	if args.nftablesDir != "" {
+		policyOptions = append(policyOptions, policies.WithNftablesDir(args.nftablesDir))
+	}
+	if args.environmentDir != "" {
+		policyOptions = append(policyOptions, policies.WithEnvironmentDir(args.environmentDir))
+	}
+	if args.profileDir != "" {
+		policyOptions = append(policyOptions, policies.WithProfileDir(args.profileDir))
+	}
+	if args.pluginsDir != "" {
+		policyOptions = append(policyOptions, policies.WithPluginsDir(args.pluginsDir))
+	}
+	return policyOptions
+}
+
+// newSystemBus returns a new private connection to the system bus.
+func newSystemBus() (*dbus.Conn, error) {
+	// Don’t call dbus.SystemBus which caches globally system dbus (issues in tests)
+	bus, err := dbus.SystemBusPrivate()
+	if err != nil {
+		return nil, err
+	}
+	if err = bus.Auth(nil); err != nil {
+		_ = bus.Close()
+		return nil, err
+	}
+	if err = bus.Hello(); err != nil {
+		_ = bus.Close()
+		return nil, err
+	}
+	return bus, nil
+}
+
+// localHostname returns the short hostname of the machine.
+func localHostname() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+	// For machines where /proc/sys/kernel/hostname returns FQDN, cut it.
+	hostname, _, _ = strings.Cut(hostname, ".")
+	return hostname, nil
+}
+
 // initSystemTime returns systemd generator init system time.
 func initSystemTime(bus *dbus.Conn) *time.Time {
 	systemd := bus.Object(consts.SystemdDbusRegisteredName, consts.SystemdDbusObjectPath)
@@ -64,6 +64,10 @@ func TestNew(t *testing.T) {
 			apparmorDir := filepath.Join(temp, "apparmor.d", "adsys")
 			apparmorFsDir := filepath.Join(temp, "apparmorfs")
 			globalTrustDir := filepath.Join(temp, "ca-certificates")
+			securityDir := filepath.Join(temp, "security")
+			nftablesDir := filepath.Join(temp, "nftables.d")
+			environmentDir := filepath.Join(temp, "environment.d")
+			profileDir := filepath.Join(temp, "profile.d")
 			if tc.existingAdsysDirs {
 				require.NoError(t, os.MkdirAll(adsysCacheDir, 0700), "Setup: could not create adsys cache directory")
 				require.NoError(t, os.MkdirAll(adsysRunDir, 0700), "Setup: could not create adsys run directory")
@@ -95,6 +99,10 @@ func TestNew(t *testing.T) {
 				adsysservice.WithApparmorDir(apparmorDir),
 				adsysservice.WithApparmorFsDir(apparmorFsDir),
 				adsysservice.WithGlobalTrustDir(globalTrustDir),
+				adsysservice.WithSecurityDir(securityDir),
+				adsysservice.WithNftablesDir(nftablesDir),
+				adsysservice.WithEnvironmentDir(environmentDir),
+				adsysservice.WithProfileDir(profileDir),
 				adsysservice.WithSSSConfig(sssdConfig),
 				adsysservice.WithWinbindConfig(winbindConfig),
 			}
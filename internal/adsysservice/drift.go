@@ -0,0 +1,73 @@
+package adsysservice
+
+import (
+	"context"
+	"time"
+
+	"github.com/leonelquinteros/gotext"
+	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
+)
+
+// watchDrift verifies the applied policies every interval, until the returned function is called.
+// Policies of any object whose state drifted are applied again.
+func (s *Service) watchDrift(ctx context.Context, interval time.Duration) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			s.repairDrift(ctx)
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// repairDrift applies again the policies of the machine and of all users with applied policies
+// if the system state drifted from them.
+func (s *Service) repairDrift(ctx context.Context) {
+	log.Debug(ctx, "Checking for policies drift")
+
+	type object struct {
+		name       string
+		isComputer bool
+	}
+	objects := []object{{name: s.adc.Hostname(), isComputer: true}}
+	users, err := s.adc.ListUsers(ctx, false)
+	if err != nil {
+		log.Warning(ctx, gotext.Get("Can't list users to check for policies drift: %v", err))
+	}
+	for _, u := range users {
+		objects = append(objects, object{name: u})
+	}
+
+	for _, o := range objects {
+		if ctx.Err() != nil {
+			return
+		}
+		_, drift, err := s.policyManager.VerifyPolicies(ctx, o.name, o.isComputer)
+		if err != nil {
+			log.Warning(ctx, gotext.Get("Can't check policies drift for %s: %v", o.name, err))
+			continue
+		}
+		if !drift {
+			continue
+		}
+		log.Warning(ctx, gotext.Get("Policies drift detected for %s, applying them again", o.name))
+		if err := s.policyManager.Repair(ctx, o.name, o.isComputer); err != nil {
+			log.Warning(ctx, gotext.Get("Can't repair policies drift for %s: %v", o.name, err))
+		}
+	}
+}
@@ -3,6 +3,9 @@ package adsysservice
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/leonelquinteros/gotext"
 	"github.com/ubuntu/adsys"
@@ -41,44 +44,83 @@ func (s *Service) UpdatePolicy(r *adsys.UpdatePolicyRequest, stream adsys.Servic
 		return err
 	}
 
+	// With dry run, each object sends the list of changes which would be applied.
+	var plansMu sync.Mutex
+	var plans []string
+	defer func() {
+		if !r.GetDryRun() {
+			return
+		}
+		for _, msg := range plans {
+			if err := stream.Send(&adsys.StringResponse{Msg: msg}); err != nil {
+				log.Warningf(stream.Context(), "couldn't send policy update plan to client: %v", err)
+			}
+		}
+	}()
+	updatePolicyFor := func(isComputer bool, target string, objectClass ad.ObjectClass, krb5cc string) error {
+		msg, err := s.updatePolicyFor(stream.Context(), isComputer, target, objectClass, krb5cc, r.GetPurge(), r.GetDryRun())
+		if err != nil {
+			return err
+		}
+		if msg != "" {
+			plansMu.Lock()
+			plans = append(plans, msg)
+			plansMu.Unlock()
+		}
+		return nil
+	}
+
 	if r.GetIsComputer() || r.GetAll() {
 		hostname := s.adc.Hostname()
 
-		err = s.updatePolicyFor(stream.Context(), true, hostname, ad.ComputerObject, "", r.GetPurge())
+		err = updatePolicyFor(true, hostname, ad.ComputerObject, "")
 
 		if r.GetAll() {
 			users, err := s.adc.ListUsers(stream.Context(), !r.GetPurge())
 			if err != nil {
 				return err
 			}
+			nMachinePlans := len(plans)
 			errg := new(errgroup.Group)
 			for _, user := range users {
 				errg.Go(func() (err error) {
-					return s.updatePolicyFor(stream.Context(), false, user, ad.UserObject, "", r.GetPurge())
+					return updatePolicyFor(false, user, ad.UserObject, "")
 				})
 			}
 			if err := errg.Wait(); err != nil {
 				return fmt.Errorf("one or more error for updating all users: %w", err)
 			}
+			// Users plans are computed concurrently: list them in a stable order after the machine one.
+			slices.Sort(plans[nMachinePlans:])
 		}
 
 		return err
 	}
 	// Update a single user
-	return s.updatePolicyFor(stream.Context(), r.GetIsComputer(), target, objectClass, r.Krb5Cc, r.GetPurge())
+	return updatePolicyFor(r.GetIsComputer(), target, objectClass, r.Krb5Cc)
 }
 
 // updatePolicyFor updates the policy for a given object.
-func (s *Service) updatePolicyFor(ctx context.Context, isComputer bool, target string, objectClass ad.ObjectClass, krb5cc string, purge bool) (err error) {
+// In dry run mode, nothing is applied and the returned message lists the changes which would be made.
+func (s *Service) updatePolicyFor(ctx context.Context, isComputer bool, target string, objectClass ad.ObjectClass, krb5cc string, purge, dryRun bool) (msg string, err error) {
 	var pols policies.Policies
 	if !purge {
 		pols, err = s.adc.GetPolicies(ctx, target, objectClass, krb5cc)
 		if err != nil {
-			return err
+			return "", err
 		}
 	}
 
-	return s.policyManager.ApplyPolicies(ctx, target, isComputer, &pols)
+	if dryRun {
+		return s.policyManager.PlanPolicies(ctx, target, isComputer, &pols)
+	}
+	if err := s.policyManager.ApplyPolicies(ctx, target, isComputer, &pols); err != nil {
+		return "", err
+	}
+	if purge {
+		return "", nil
+	}
+	return "", s.adc.PoliciesApplied(target)
 }
 
 // DumpPolicies displays all applied policies for a given user.
@@ -107,10 +149,296 @@ func (s *Service) DumpPolicies(r *adsys.DumpPoliciesRequest, stream adsys.Servic
 	if err != nil {
 		return err
 	}
+	applied, err := s.policyManager.AppliedPolicies(stream.Context(), target, r.GetIsComputer())
+	if err != nil {
+		return err
+	}
+	if err := stream.Send(&adsys.DumpPoliciesResponse{
+		Msg:  msg,
+		Gpos: gposToProto(applied, r.GetDetails() || r.GetAll(), r.GetAll()),
+	}); err != nil {
+		log.Warningf(stream.Context(), "couldn't send currently applied policies to client: %v", err)
+	}
+
+	return nil
+}
+
+// gposToProto converts applied GPOs to their protobuf representation.
+// Rules are only included withRules, and overridden ones only withOverridden.
+func gposToProto(applied []policies.AppliedGPO, withRules, withOverridden bool) (gpos []*adsys.GPO) {
+	for _, g := range applied {
+		gpo := &adsys.GPO{
+			Id:         g.ID,
+			Name:       g.Name,
+			IsComputer: g.IsComputer,
+			LastUpdate: g.LastUpdate.Format(time.RFC3339),
+		}
+		for _, r := range g.Rules {
+			if !withRules || (!withOverridden && r.Overridden) {
+				continue
+			}
+			gpo.Rules = append(gpo.Rules, &adsys.Rule{
+				Type:       r.Type,
+				Key:        r.Key,
+				Value:      r.Value,
+				Disabled:   r.Disabled,
+				Strategy:   r.Strategy,
+				Overridden: r.Overridden,
+			})
+		}
+		gpos = append(gpos, gpo)
+	}
+	return gpos
+}
+
+// PolicyHistory displays the snapshots of previously applied policies for a given user or the machine.
+func (s *Service) PolicyHistory(r *adsys.PolicyHistoryRequest, stream adsys.Service_PolicyHistoryServer) (err error) {
+	defer decorate.OnError(&err, gotext.Get("error while displaying policies history"))
+
+	objectClass := ad.UserObject
+	if r.GetIsComputer() {
+		objectClass = ad.ComputerObject
+	}
+
+	target, err := s.adc.NormalizeTargetName(stream.Context(), r.GetTarget(), objectClass)
+	if err != nil {
+		return err
+	}
+
+	// hostname policy display is allowed to all users
+	if target != s.adc.Hostname() {
+		if err := s.authorizer.IsAllowedFromContext(context.WithValue(stream.Context(), authorizer.OnUserKey, target),
+			actions.ActionPolicyDump); err != nil {
+			return err
+		}
+	}
+
+	msg, err := s.policyManager.History(stream.Context(), target)
+	if err != nil {
+		return err
+	}
 	if err := stream.Send(&adsys.StringResponse{
 		Msg: msg,
 	}); err != nil {
-		log.Warningf(stream.Context(), "couldn't send currently applied policies to client: %v", err)
+		log.Warningf(stream.Context(), "couldn't send policies history to client: %v", err)
+	}
+
+	return nil
+}
+
+// PolicyRollback applies again a previous snapshot of policies for a given user or the machine.
+func (s *Service) PolicyRollback(r *adsys.PolicyRollbackRequest, stream adsys.Service_PolicyRollbackServer) (err error) {
+	defer decorate.OnError(&err, gotext.Get("error while rolling back policies"))
+
+	objectClass := ad.UserObject
+	if r.GetIsComputer() {
+		objectClass = ad.ComputerObject
+	}
+	target, err := s.adc.NormalizeTargetName(stream.Context(), r.GetTarget(), objectClass)
+	if err != nil {
+		return err
+	}
+
+	targetForAuthorizer := target
+	// prevent case of username == machine name to allow rolling back machine policies.
+	if r.GetIsComputer() {
+		targetForAuthorizer = "root"
+	}
+
+	if err := s.authorizer.IsAllowedFromContext(context.WithValue(stream.Context(), authorizer.OnUserKey, targetForAuthorizer),
+		actions.ActionPolicyUpdate); err != nil {
+		return err
+	}
+
+	return s.policyManager.Rollback(stream.Context(), target, r.GetIsComputer(), int(r.GetId()))
+}
+
+// PolicyReport displays the outcome of each policy manager during the last policies application for a given user
+// or the machine.
+func (s *Service) PolicyReport(r *adsys.PolicyReportRequest, stream adsys.Service_PolicyReportServer) (err error) {
+	defer decorate.OnError(&err, gotext.Get("error while displaying policies report"))
+
+	objectClass := ad.UserObject
+	if r.GetIsComputer() {
+		objectClass = ad.ComputerObject
+	}
+
+	target, err := s.adc.NormalizeTargetName(stream.Context(), r.GetTarget(), objectClass)
+	if err != nil {
+		return err
+	}
+
+	// hostname policy display is allowed to all users
+	if target != s.adc.Hostname() {
+		if err := s.authorizer.IsAllowedFromContext(context.WithValue(stream.Context(), authorizer.OnUserKey, target),
+			actions.ActionPolicyDump); err != nil {
+			return err
+		}
+	}
+
+	msg, err := s.policyManager.Report(stream.Context(), target)
+	if err != nil {
+		return err
+	}
+	if err := stream.Send(&adsys.StringResponse{
+		Msg: msg,
+	}); err != nil {
+		log.Warningf(stream.Context(), "couldn't send policies report to client: %v", err)
+	}
+
+	return nil
+}
+
+// PolicyDiff displays the rules which changed for a given user or the machine, between its applied policies and
+// either the ones currently defined in Active Directory or a previous snapshot.
+func (s *Service) PolicyDiff(r *adsys.PolicyDiffRequest, stream adsys.Service_PolicyDiffServer) (err error) {
+	defer decorate.OnError(&err, gotext.Get("error while comparing policies"))
+
+	objectClass := ad.UserObject
+	if r.GetIsComputer() {
+		objectClass = ad.ComputerObject
+	}
+
+	target, err := s.adc.NormalizeTargetName(stream.Context(), r.GetTarget(), objectClass)
+	if err != nil {
+		return err
+	}
+
+	var msg string
+	if r.GetSnapshot() > 0 {
+		// hostname policy display is allowed to all users
+		if target != s.adc.Hostname() {
+			if err := s.authorizer.IsAllowedFromContext(context.WithValue(stream.Context(), authorizer.OnUserKey, target),
+				actions.ActionPolicyDump); err != nil {
+				return err
+			}
+		}
+
+		msg, err = s.policyManager.DiffSnapshot(stream.Context(), target, int(r.GetSnapshot()))
+		if err != nil {
+			return err
+		}
+	} else {
+		// Fetching policies from Active Directory requires the same permissions than updating them.
+		targetForAuthorizer := target
+		if r.GetIsComputer() {
+			targetForAuthorizer = "root"
+		}
+		if err := s.authorizer.IsAllowedFromContext(context.WithValue(stream.Context(), authorizer.OnUserKey, targetForAuthorizer),
+			actions.ActionPolicyUpdate); err != nil {
+			return err
+		}
+
+		pols, err := s.adc.GetPolicies(stream.Context(), target, objectClass, r.GetKrb5Cc())
+		if err != nil {
+			return err
+		}
+		defer pols.Close()
+
+		msg, err = s.policyManager.DiffPolicies(stream.Context(), target, &pols)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := stream.Send(&adsys.StringResponse{
+		Msg: msg,
+	}); err != nil {
+		log.Warningf(stream.Context(), "couldn't send policies changes to client: %v", err)
+	}
+
+	return nil
+}
+
+// PolicyRSOP displays the resultant set of policies of a given user or the machine, with the GPOs setting each key.
+func (s *Service) PolicyRSOP(r *adsys.PolicyRSOPRequest, stream adsys.Service_PolicyRSOPServer) (err error) {
+	defer decorate.OnError(&err, gotext.Get("error while displaying resultant set of policies"))
+
+	objectClass := ad.UserObject
+	if r.GetIsComputer() {
+		objectClass = ad.ComputerObject
+	}
+
+	target, err := s.adc.NormalizeTargetName(stream.Context(), r.GetTarget(), objectClass)
+	if err != nil {
+		return err
+	}
+
+	// hostname policy display is allowed to all users
+	if target != s.adc.Hostname() {
+		if err := s.authorizer.IsAllowedFromContext(context.WithValue(stream.Context(), authorizer.OnUserKey, target),
+			actions.ActionPolicyDump); err != nil {
+			return err
+		}
+	}
+
+	msg, err := s.policyManager.RSOP(stream.Context(), target, r.GetIsComputer())
+	if err != nil {
+		return err
+	}
+	if err := stream.Send(&adsys.StringResponse{
+		Msg: msg,
+	}); err != nil {
+		log.Warningf(stream.Context(), "couldn't send resultant set of policies to client: %v", err)
+	}
+
+	return nil
+}
+
+// VerifyPolicy compares the system state with the policies applied for current user or user given as argument.
+// It can verify the machine and all users with applied policies instead.
+func (s *Service) VerifyPolicy(r *adsys.VerifyPolicyRequest, stream adsys.Service_VerifyPolicyServer) (err error) {
+	defer decorate.OnError(&err, gotext.Get("error while verifying policies"))
+
+	objectClass := ad.UserObject
+	if r.GetIsComputer() || r.GetAll() {
+		objectClass = ad.ComputerObject
+	}
+	target, err := s.adc.NormalizeTargetName(stream.Context(), r.GetTarget(), objectClass)
+	if err != nil {
+		return err
+	}
+
+	targetForAuthorizer := target
+	// prevent case of username == machine name to allow verifying all users policies.
+	if r.GetAll() {
+		targetForAuthorizer = "root"
+	}
+	// hostname policy verification is allowed to all users
+	if r.GetAll() || target != s.adc.Hostname() {
+		if err := s.authorizer.IsAllowedFromContext(context.WithValue(stream.Context(), authorizer.OnUserKey, targetForAuthorizer),
+			actions.ActionPolicyDump); err != nil {
+			return err
+		}
+	}
+
+	type object struct {
+		name       string
+		isComputer bool
+	}
+	objects := []object{{name: target, isComputer: r.GetIsComputer() || r.GetAll()}}
+	if r.GetAll() {
+		users, err := s.adc.ListUsers(stream.Context(), false)
+		if err != nil {
+			return err
+		}
+		slices.Sort(users)
+		for _, u := range users {
+			objects = append(objects, object{name: u})
+		}
+	}
+
+	for _, o := range objects {
+		msg, drift, err := s.policyManager.VerifyPolicies(stream.Context(), o.name, o.isComputer)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(&adsys.VerifyPolicyResponse{
+			Msg:   msg,
+			Drift: drift,
+		}); err != nil {
+			log.Warningf(stream.Context(), "couldn't send policies verification to client: %v", err)
+		}
 	}
 
 	return nil
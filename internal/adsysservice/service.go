@@ -1,6 +1,7 @@
 package adsysservice
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"slices"
@@ -14,7 +15,6 @@ import (
 	"github.com/ubuntu/adsys/internal/authorizer"
 	"github.com/ubuntu/adsys/internal/consts"
 	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
-	"github.com/ubuntu/adsys/internal/policies"
 	"github.com/ubuntu/adsys/internal/stdforward"
 	"github.com/ubuntu/decorate"
 	"google.golang.org/grpc"
@@ -97,8 +97,10 @@ func (s *Service) Status(_ *adsys.Empty, stream adsys.Service_StatusServer) (err
 	timeLayout := "Mon Jan 2 15:04"
 
 	nextRefresh := gotext.Get("unknown")
+	var nextRefreshRFC3339 string
 	if next, err := s.nextRefreshTime(); err == nil {
 		nextRefresh = next.Format(timeLayout)
+		nextRefreshRFC3339 = next.Format(time.RFC3339)
 	} else {
 		log.Warning(stream.Context(), err)
 	}
@@ -106,18 +108,23 @@ func (s *Service) Status(_ *adsys.Empty, stream adsys.Service_StatusServer) (err
 	// FIXME: gotext.Get needs to have the arguments parsed.
 	updateFmt := "%s" + gotext.Get(", updated on ") + "%s"
 	updateMachine := gotext.Get("Machine, no gpo applied found")
-	t, err := s.policyManager.LastUpdateFor(stream.Context(), "", true)
-	if err == nil {
+	machineStatus, t := s.objectStatus(stream.Context(), "", true)
+	if !t.IsZero() {
 		updateMachine = fmt.Sprintf(updateFmt, gotext.Get("Machine"), t.Format(timeLayout))
+		updateMachine += formatFailures(machineStatus.GetFailures(), "\n  ")
 	}
 
+	var usersStatus []*adsys.ObjectStatus
 	updateUsers := fmt.Sprint(gotext.Get("Can't get connected users"))
 	users, err := s.adc.ListUsers(stream.Context(), true)
 	if err == nil {
 		updateUsers = fmt.Sprint(gotext.Get("Connected users:"))
 		for _, u := range users {
-			if t, err := s.policyManager.LastUpdateFor(stream.Context(), u, false); err == nil {
+			userStatus, t := s.objectStatus(stream.Context(), u, false)
+			usersStatus = append(usersStatus, userStatus)
+			if !t.IsZero() {
 				updateUsers = updateUsers + "\n  " + fmt.Sprintf(updateFmt, u, t.Format(timeLayout))
+				updateUsers += formatFailures(userStatus.GetFailures(), "\n    ")
 			} else {
 				updateUsers = updateUsers + "\n  " + gotext.Get("%s, no gpo applied found", u)
 			}
@@ -128,7 +135,7 @@ func (s *Service) Status(_ *adsys.Empty, stream adsys.Service_StatusServer) (err
 	}
 
 	ubuntuProStatus := gotext.Get("Ubuntu Pro subscription is not active on this machine. Rules belonging to the following policy types will not be applied:\n")
-	proOnlyRules := slices.Clone(policies.ProOnlyRules)
+	proOnlyRules := s.policyManager.ProOnlyRules()
 	slices.Sort(proOnlyRules)
 	ubuntuProStatus = ubuntuProStatus + "  - " + strings.Join(proOnlyRules, "\n  - ")
 
@@ -160,8 +167,24 @@ Daemon:
 		timeout, socket, state.cacheDir, state.runDir, state.dconfDir,
 		state.sudoersDir, state.policyKitDir, state.apparmorDir)
 
-	if err := stream.Send(&adsys.StringResponse{
-		Msg: status,
+	if err := stream.Send(&adsys.StatusResponse{
+		Msg:                 status,
+		Machine:             machineStatus,
+		Users:               usersStatus,
+		NextRefresh:         nextRefreshRFC3339,
+		SubscriptionEnabled: subscriptionEnabled,
+		ProOnlyRules:        proOnlyRules,
+		AdInfo:              adInfo,
+		Daemon: &adsys.DaemonStatus{
+			Timeout:      timeout,
+			Socket:       socket,
+			CacheDir:     state.cacheDir,
+			RunDir:       state.runDir,
+			DconfDir:     state.dconfDir,
+			SudoersDir:   state.sudoersDir,
+			PolicyKitDir: state.policyKitDir,
+			ApparmorDir:  state.apparmorDir,
+		},
 	}); err != nil {
 		log.Warningf(stream.Context(), "couldn't send status to client: %v", err)
 	}
@@ -195,8 +218,9 @@ func (s *Service) ListUsers(r *adsys.ListUsersRequest, stream adsys.Service_List
 		return err
 	}
 
-	if err := stream.Send(&adsys.StringResponse{
-		Msg: strings.Join(users, " "),
+	if err := stream.Send(&adsys.ListUsersResponse{
+		Msg:   strings.Join(users, " "),
+		Users: users,
 	}); err != nil {
 		log.Warningf(stream.Context(), "couldn't send service version to client: %v", err)
 	}
@@ -229,3 +253,40 @@ func (s Service) nextRefreshTime() (next *time.Time, err error) {
 	nextRefresh := s.initSystemTime.Add(time.Duration(nextRaw) * time.Microsecond / time.Nanosecond)
 	return &nextRefresh, nil
 }
+
+// objectStatus returns the status of the last policies application of an object, with the policy managers
+// which failed during it. lastUpdate is zero if no policy was applied.
+func (s Service) objectStatus(ctx context.Context, objectName string, isMachine bool) (status *adsys.ObjectStatus, lastUpdate time.Time) {
+	status = &adsys.ObjectStatus{Name: objectName}
+	if isMachine {
+		status.Name = s.adc.Hostname()
+	}
+
+	t, err := s.policyManager.LastUpdateFor(ctx, objectName, isMachine)
+	if err != nil {
+		return status, time.Time{}
+	}
+	status.LastUpdate = t.Format(time.RFC3339)
+
+	failures, err := s.policyManager.LastFailures(ctx, objectName, isMachine)
+	if err != nil {
+		log.Debug(ctx, err)
+	}
+	for _, f := range failures {
+		status.Failures = append(status.Failures, &adsys.ManagerFailure{Manager: f.Name, Error: f.Error})
+	}
+
+	return status, t
+}
+
+// formatFailures returns the policy managers which failed during the last policies application of an object,
+// each on its own line starting with indent. It is empty if there was no failure.
+func formatFailures(failures []*adsys.ManagerFailure, indent string) string {
+	var out string
+	for _, f := range failures {
+		// Only keep the first line of the error to keep the status short.
+		msg, _, _ := strings.Cut(f.GetError(), "\n")
+		out += indent + gotext.Get("%s failed on last refresh: %s", f.GetManager(), msg)
+	}
+	return out
+}
@@ -0,0 +1,171 @@
+package adsysservice
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+
+	"github.com/leonelquinteros/gotext"
+	"github.com/ubuntu/adsys"
+	"github.com/ubuntu/adsys/internal/ad"
+	"github.com/ubuntu/adsys/internal/adsysservice/actions"
+	"github.com/ubuntu/adsys/internal/authorizer"
+	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
+	"github.com/ubuntu/adsys/internal/policies"
+	"github.com/ubuntu/decorate"
+)
+
+// snapshotChunkSize is the maximum size of each chunk of a snapshot archive sent to the client.
+const snapshotChunkSize = 1024 * 1024
+
+// CapturePolicy streams an archive of everything the last policies refresh of a given user or the machine used.
+func (s *Service) CapturePolicy(r *adsys.CapturePolicyRequest, stream adsys.Service_CapturePolicyServer) (err error) {
+	defer decorate.OnError(&err, gotext.Get("error while capturing policies"))
+
+	objectClass := ad.UserObject
+	if r.GetIsComputer() {
+		objectClass = ad.ComputerObject
+	}
+
+	target, err := s.adc.NormalizeTargetName(stream.Context(), r.GetTarget(), objectClass)
+	if err != nil {
+		return err
+	}
+
+	// hostname policy display is allowed to all users
+	if target != s.adc.Hostname() {
+		if err := s.authorizer.IsAllowedFromContext(context.WithValue(stream.Context(), authorizer.OnUserKey, target),
+			actions.ActionPolicyDump); err != nil {
+			return err
+		}
+	}
+
+	w := bufio.NewWriterSize(chunkWriter{stream: stream}, snapshotChunkSize)
+	if err := s.adc.CaptureSnapshot(stream.Context(), target, objectClass, w); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// chunkWriter sends each write as a chunk of the snapshot archive to the client.
+type chunkWriter struct {
+	stream adsys.Service_CapturePolicyServer
+}
+
+func (w chunkWriter) Write(p []byte) (n int, err error) {
+	// The buffer is reused by the caller once we return: send a copy.
+	if err := w.stream.Send(&adsys.CapturePolicyResponse{Data: bytes.Clone(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// ApplySnapshot applies the policies of a snapshot archive, captured with CapturePolicy, without contacting
+// Active Directory.
+func (s *Service) ApplySnapshot(r *adsys.ApplySnapshotRequest, stream adsys.Service_ApplySnapshotServer) (err error) {
+	defer decorate.OnError(&err, gotext.Get("error while applying policies snapshot"))
+
+	// Any policy can be stored in an archive: only allow privileged users.
+	if err := s.authorizer.IsAllowedFromContext(context.WithValue(stream.Context(), authorizer.OnUserKey, "root"),
+		actions.ActionPolicyUpdate); err != nil {
+		return err
+	}
+
+	snapshot, cleanup, err := loadSnapshot(stream.Context(), r.GetArchive())
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	return applySnapshot(stream.Context(), s.policyManager, s.adc.Hostname(), snapshot)
+}
+
+// ApplySnapshot applies the policies of a snapshot archive, captured with CapturePolicy, without any service running
+// nor any Active Directory configuration. This is used to provision a machine before joining it to a domain.
+func ApplySnapshot(ctx context.Context, archive string, opts ...option) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't apply policies snapshot %q", archive))
+
+	// defaults
+	args := options{}
+	// applied options
+	for _, o := range opts {
+		if err := o(&args); err != nil {
+			return err
+		}
+	}
+
+	snapshot, cleanup, err := loadSnapshot(ctx, archive)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	bus, err := newSystemBus()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := bus.Close(); err != nil {
+			log.Warning(ctx, gotext.Get("Can't disconnect system dbus: %v", err))
+		}
+	}()
+
+	hostname, err := localHostname()
+	if err != nil {
+		return err
+	}
+
+	m, err := policies.NewManager(bus, hostname, snapshot.Backend(), args.policyOptions()...)
+	if err != nil {
+		return err
+	}
+
+	return applySnapshot(ctx, m, hostname, snapshot)
+}
+
+// loadSnapshot extracts and loads the snapshot archive. cleanup must be called once the snapshot is not needed
+// anymore.
+func loadSnapshot(ctx context.Context, archive string) (snapshot ad.Snapshot, cleanup func(), err error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return snapshot, nil, err
+	}
+	defer f.Close()
+
+	dir, err := os.MkdirTemp("", "adsys-snapshot-*")
+	if err != nil {
+		return snapshot, nil, err
+	}
+	cleanup = func() {
+		if err := os.RemoveAll(dir); err != nil {
+			log.Warningf(ctx, "Can't remove extracted snapshot %q: %v", dir, err)
+		}
+	}
+
+	snapshot, err = ad.LoadSnapshot(ctx, f, dir)
+	if err != nil {
+		cleanup()
+		return snapshot, nil, err
+	}
+
+	return snapshot, func() {
+		if err := snapshot.Policies.Close(); err != nil {
+			log.Warningf(ctx, "Can't close snapshot policies: %v", err)
+		}
+		cleanup()
+	}, nil
+}
+
+// applySnapshot applies the snapshot policies with m. Computer policies are applied to hostname, whichever machine
+// they were captured on.
+func applySnapshot(ctx context.Context, m *policies.Manager, hostname string, snapshot ad.Snapshot) error {
+	target := snapshot.ObjectName
+	isComputer := snapshot.ObjectClass == ad.ComputerObject
+	if isComputer {
+		target = hostname
+	}
+
+	log.Infof(ctx, "Applying policies snapshot of %s to %s", snapshot.ObjectName, target)
+	return m.ApplyPolicies(ctx, target, isComputer, &snapshot.Policies)
+}
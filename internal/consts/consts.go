@@ -34,6 +34,9 @@ const (
 	// DefaultShareDir is the default path for adsys share directory.
 	DefaultShareDir = "/usr/share/adsys"
 
+	// DefaultPluginsDir is the default path for adsys policy plugins.
+	DefaultPluginsDir = "/usr/lib/adsys/plugins"
+
 	// DefaultClientTimeout is the maximum default time in seconds between 2 server activities before the client returns and abort the request.
 	DefaultClientTimeout = 30
 
@@ -43,6 +46,9 @@ const (
 	// DefaultGpoListTimeout is the default time to wait for the GPO list subcommand to finish.
 	DefaultGpoListTimeout = 10 * time.Second
 
+	// DefaultPoliciesHistorySize is the default number of applied policies snapshots kept per object.
+	DefaultPoliciesHistorySize = 5
+
 	// DistroID is the distro ID which can be overridden at build time.
 	DistroID = "Ubuntu"
 )
@@ -54,6 +60,8 @@ const (
 
 	// AdysMachineScriptsServiceName is the machine script systemd service.
 	AdysMachineScriptsServiceName = "adsys-machine-scripts.service"
+	// AdsysMachinePackagesServiceName is the transient systemd service running the machine packages operations.
+	AdsysMachinePackagesServiceName = "adsys-machine-packages.service"
 
 	// DefaultDconfDir is the default dconf directory.
 	DefaultDconfDir = "/etc/dconf"
@@ -67,6 +75,14 @@ const (
 	DefaultSystemUnitDir = "/etc/systemd/system"
 	// DefaultGlobalTrustDir is the default directory for the global trust store.
 	DefaultGlobalTrustDir = "/usr/local/share/ca-certificates"
+	// DefaultSecurityDir is the default directory for PAM modules configuration.
+	DefaultSecurityDir = "/etc/security"
+	// DefaultNftablesDir is the default directory for nftables rulesets.
+	DefaultNftablesDir = "/etc/nftables.d"
+	// DefaultEnvironmentDir is the default directory for the environment of the systemd user sessions.
+	DefaultEnvironmentDir = "/etc/environment.d"
+	// DefaultProfileDir is the default directory for login shells initialization scripts.
+	DefaultProfileDir = "/etc/profile.d"
 )
 
 // SSSD related properties.
@@ -38,6 +38,8 @@ import (
 	"github.com/ubuntu/adsys/internal/consts"
 	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
 	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/adsys/internal/policies/plan"
+	"github.com/ubuntu/adsys/internal/policies/transaction"
 	"github.com/ubuntu/adsys/internal/smbsafe"
 	"github.com/ubuntu/decorate"
 )
@@ -158,6 +160,215 @@ func (m *Manager) ApplyPolicy(ctx context.Context, objectName string, isComputer
 	return err
 }
 
+// Prepare backs up the apparmor profiles of objectName into tx, so that they can be restored if applying
+// the policies fails. Once restored, the machine profiles, which include the user ones, are loaded again.
+func (m *Manager) Prepare(ctx context.Context, objectName string, isComputer bool, tx *transaction.Transaction) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't prepare apparmor policy for %s", objectName))
+
+	log.Debugf(ctx, "Preparing apparmor policy for %s", objectName)
+
+	path := filepath.Join(m.apparmorDir, "machine")
+	if !isComputer {
+		path = filepath.Join(m.apparmorDir, "users", objectName)
+	}
+
+	tx.OnAbort(func(ctx context.Context) error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		if os.Getenv("ADSYS_SKIP_ROOT_CALLS") != "" {
+			return nil
+		}
+		// Nothing could have been loaded if apparmor isn't available
+		if _, err := exec.LookPath(m.apparmorParserCmd[0]); err != nil {
+			return nil
+		}
+		profiles, err := filesInDir(filepath.Join(m.apparmorDir, "machine"))
+		if errors.Is(err, fs.ErrNotExist) || len(profiles) == 0 {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return m.loadProfiles(ctx, profiles)
+	})
+
+	return tx.Backup(path)
+}
+
+// Plan returns the changes ApplyPolicy would make for an apparmor policy, without applying them.
+// Assets are only dumped to a temporary directory to compute the profiles to install.
+func (m *Manager) Plan(ctx context.Context, objectName string, isComputer bool, entries []entry.Entry, assetsDumper AssetsDumper) (changes []plan.Change, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't plan apparmor policy for %s", objectName))
+
+	objectDir := "machine"
+	if !isComputer {
+		objectDir = "users"
+	}
+	apparmorPath := filepath.Join(m.apparmorDir, objectDir)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	absPath, err := exec.LookPath(m.apparmorParserCmd[0])
+	if err != nil {
+		if len(entries) > 0 {
+			return nil, err
+		}
+		log.Warning(ctx, gotext.Get("Apparmor is not available on this system: %v", err))
+		return nil, nil
+	}
+	m.apparmorParserCmd[0] = absPath
+	parserArgs := m.apparmorParserCmd[1:]
+	reloadArgs := append(slices.Clone(parserArgs), "-r", "-W", "-L", m.apparmorCacheDir)
+
+	idx := slices.IndexFunc(entries, func(e entry.Entry) bool { return e.Key == fmt.Sprintf("apparmor-%s", objectDir) })
+	if idx == -1 || entries[idx].Disabled {
+		machinePoliciesPath := filepath.Join(m.apparmorDir, "machine")
+		pathToRemove := machinePoliciesPath
+		if !isComputer {
+			pathToRemove = filepath.Join(m.apparmorDir, "users", objectName)
+		}
+		if _, err := os.Stat(machinePoliciesPath); err == nil {
+			policies, err := m.adsysLoadedPolicies(ctx, objectName, isComputer)
+			if err != nil {
+				return nil, err
+			}
+			if len(policies) > 0 {
+				unloadArgs := append(append(slices.Clone(parserArgs), "-R"), policies...)
+				changes = append(changes, plan.Call(absPath, unloadArgs...))
+			}
+		}
+		if c, ok := plan.Removal(pathToRemove); ok {
+			changes = append(changes, c)
+		}
+		return changes, nil
+	}
+
+	tmpdir, err := os.MkdirTemp("", "adsys_apparmor_plan_")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpdir)
+	assetsDir := filepath.Join(tmpdir, "apparmor")
+	if err := assetsDumper(ctx, "apparmor/", assetsDir, -1, -1); err != nil {
+		return nil, err
+	}
+	profilePaths, err := filesFromEntry(entries[idx], assetsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isComputer {
+		// The user policy is always a single file
+		if len(profilePaths) != 1 {
+			return nil, errors.New(gotext.Get("expected exactly one profile, got %d", len(profilePaths)))
+		}
+		profileContents, err := os.ReadFile(profilePaths[0])
+		if err != nil {
+			return nil, err
+		}
+		parsedProfile := fmt.Sprintf("^%s {\n%s\n}\n", objectName, strings.TrimSpace(string(profileContents)))
+		c, ok := plan.File(filepath.Join(apparmorPath, objectName), parsedProfile)
+		if !ok {
+			return nil, nil
+		}
+		changes = append(changes, c)
+
+		existingProfiles, err := filesInDir(filepath.Join(m.apparmorDir, "machine"))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if len(existingProfiles) > 0 {
+			changes = append(changes, plan.Call(absPath, append(reloadArgs, existingProfiles...)...))
+		}
+		return changes, nil
+	}
+
+	// Compare the wanted profiles with the existing ones
+	var filesToLoad []string
+	for _, p := range profilePaths {
+		rel, err := filepath.Rel(assetsDir, p)
+		if err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		dest := filepath.Join(apparmorPath, rel)
+		filesToLoad = append(filesToLoad, dest)
+		if c, ok := plan.File(dest, string(content)); ok {
+			changes = append(changes, c)
+		}
+	}
+	existingProfiles, err := filesInDir(apparmorPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, p := range existingProfiles {
+		if slices.Contains(filesToLoad, p) {
+			continue
+		}
+		changes = append(changes, plan.Change{Action: plan.Remove, Target: p})
+	}
+
+	if len(filesToLoad) > 0 {
+		changes = append(changes, plan.Call(absPath, append(reloadArgs, filesToLoad...)...))
+	}
+
+	return changes, nil
+}
+
+// Verify returns the differences between the apparmor profiles of objectName on the system and the ones ApplyPolicy
+// would install. Installed machine profiles which are not loaded anymore are reported as a reload to run.
+func (m *Manager) Verify(ctx context.Context, objectName string, isComputer bool, entries []entry.Entry, assetsDumper AssetsDumper) (changes []plan.Change, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't verify apparmor policy for %s", objectName))
+
+	planned, err := m.Plan(ctx, objectName, isComputer, entries, assetsDumper)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range planned {
+		// Profiles are always reloaded on apply.
+		if c.Action == plan.Run {
+			continue
+		}
+		changes = append(changes, c)
+	}
+
+	// User profiles are loaded as part of the machine ones.
+	if !isComputer || !slices.ContainsFunc(entries, func(e entry.Entry) bool { return e.Key == "apparmor-machine" && !e.Disabled }) {
+		return changes, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	profiles, err := filesInDir(filepath.Join(m.apparmorDir, "machine"))
+	if errors.Is(err, fs.ErrNotExist) || len(profiles) == 0 {
+		return changes, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	policies, err := m.policiesFromFiles(ctx, profiles)
+	if err != nil {
+		return nil, err
+	}
+	loaded, err := m.loadedPolicies()
+	if err != nil {
+		return nil, err
+	}
+	if unloaded := difference(policies, loaded); len(unloaded) > 0 {
+		log.Debugf(ctx, "Apparmor policies not loaded anymore: %v", unloaded)
+		reloadArgs := append(slices.Clone(m.apparmorParserCmd[1:]), "-r", "-W", "-L", m.apparmorCacheDir)
+		changes = append(changes, plan.Call(m.apparmorParserCmd[0], append(reloadArgs, profiles...)...))
+	}
+
+	return changes, nil
+}
+
 // applyUserPolicy applies apparmor policies for the machine object.
 func (m *Manager) applyMachinePolicy(ctx context.Context, e entry.Entry, apparmorPath string, assetsDumper AssetsDumper) (err error) {
 	defer decorate.OnError(&err, gotext.Get("can't apply machine policy"))
@@ -233,18 +444,8 @@ func (m *Manager) applyMachinePolicy(ctx context.Context, e entry.Entry, apparmo
 	}
 
 	if len(filesToLoad) > 0 && os.Getenv("ADSYS_SKIP_ROOT_CALLS") == "" {
-		// Run apparmor_parser on the files to load, relying on apparmor's caching mechanism
-		apparmorParserCmd := append(m.apparmorParserCmd, []string{"-r", "-W", "-L", m.apparmorCacheDir}...)
-		apparmorParserCmd = append(apparmorParserCmd, filesToLoad...)
-
-		// #nosec G204 - We are in control of the arguments
-		cmd := exec.CommandContext(ctx, apparmorParserCmd[0], apparmorParserCmd[1:]...)
-		cmd.Dir = m.apparmorDir
-		smbsafe.WaitExec()
-		out, err := cmd.CombinedOutput()
-		smbsafe.DoneExec()
-		if err != nil {
-			return errors.New(gotext.Get("failed to load apparmor rules: %v\n%s", err, string(out)))
+		if err := m.loadProfiles(ctx, filesToLoad); err != nil {
+			return err
 		}
 	}
 
@@ -307,16 +508,7 @@ func (m *Manager) applyUserPolicy(ctx context.Context, e entry.Entry, apparmorPa
 	if err != nil {
 		return err
 	}
-	apparmorParserCmd := append(m.apparmorParserCmd, []string{"-r", "-W", "-L", m.apparmorCacheDir}...)
-	apparmorParserCmd = append(apparmorParserCmd, existingProfiles...)
-
-	// #nosec G204 - We are in control of the arguments
-	cmd := exec.CommandContext(ctx, apparmorParserCmd[0], apparmorParserCmd[1:]...)
-	cmd.Dir = m.apparmorDir
-	smbsafe.WaitExec()
-	out, err := cmd.CombinedOutput()
-	smbsafe.DoneExec()
-	if err != nil {
+	if err := m.loadProfiles(ctx, existingProfiles); err != nil {
 		// Restore the old content
 		var restoreErr error
 		if len(oldContent) == 0 {
@@ -329,6 +521,23 @@ func (m *Manager) applyUserPolicy(ctx context.Context, e entry.Entry, apparmorPa
 		}
 
 		// Return the execution error
+		return err
+	}
+	return nil
+}
+
+// loadProfiles runs apparmor_parser on the given profiles, relying on apparmor's caching mechanism.
+func (m *Manager) loadProfiles(ctx context.Context, profiles []string) error {
+	apparmorParserCmd := append(slices.Clone(m.apparmorParserCmd), []string{"-r", "-W", "-L", m.apparmorCacheDir}...)
+	apparmorParserCmd = append(apparmorParserCmd, profiles...)
+
+	// #nosec G204 - We are in control of the arguments
+	cmd := exec.CommandContext(ctx, apparmorParserCmd[0], apparmorParserCmd[1:]...)
+	cmd.Dir = m.apparmorDir
+	smbsafe.WaitExec()
+	out, err := cmd.CombinedOutput()
+	smbsafe.DoneExec()
+	if err != nil {
 		return errors.New(gotext.Get("failed to load apparmor rules: %v\n%s", err, string(out)))
 	}
 	return nil
@@ -355,19 +564,39 @@ func (m *Manager) unloadAllRules(ctx context.Context, objectName string, isCompu
 		return nil
 	}
 
-	// Walk the directory and get all the files to unload
-	filesToUnload, err := filesInDir(machinePoliciesPath)
+	policies, err := m.adsysLoadedPolicies(ctx, objectName, isComputer)
 	if err != nil {
 		return err
 	}
+
+	if err := m.unloadPolicies(ctx, policies); err != nil {
+		return err
+	}
+
+	// Unloading succeeded, remove apparmor policy dir
+	if err := os.RemoveAll(pathToRemove); err != nil {
+		return err
+	}
+	return nil
+}
+
+// adsysLoadedPolicies returns the policies from the machine apparmor directory which are currently
+// loaded in the system.
+// If isComputer is false, only policies pertaining to the given user are returned.
+func (m *Manager) adsysLoadedPolicies(ctx context.Context, objectName string, isComputer bool) (policies []string, err error) {
+	// Walk the directory and get all the files to unload
+	filesToUnload, err := filesInDir(filepath.Join(m.apparmorDir, "machine"))
+	if err != nil {
+		return nil, err
+	}
 	// Get the currently loaded list of policies
 	prevLoadedPolicies, err := m.loadedPolicies()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	policies, err := m.policiesFromFiles(ctx, filesToUnload)
+	policies, err = m.policiesFromFiles(ctx, filesToUnload)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	policies = intersection(policies, prevLoadedPolicies)
 
@@ -376,7 +605,6 @@ func (m *Manager) unloadAllRules(ctx context.Context, objectName string, isCompu
 	// /usr/bin/su//administrator@warthogs.biz (enforce)
 	// /usr/bin/su//anotheruser@warthogs.biz (enforce)
 	if !isComputer {
-		pathToRemove = filepath.Join(m.apparmorDir, "users", objectName)
 		i := 0
 		for _, policy := range policies {
 			if strings.HasSuffix(policy, fmt.Sprintf("//%s", objectName)) {
@@ -387,15 +615,7 @@ func (m *Manager) unloadAllRules(ctx context.Context, objectName string, isCompu
 		policies = policies[:i]
 	}
 
-	if err := m.unloadPolicies(ctx, policies); err != nil {
-		return err
-	}
-
-	// Unloading succeeded, remove apparmor policy dir
-	if err := os.RemoveAll(pathToRemove); err != nil {
-		return err
-	}
-	return nil
+	return policies, nil
 }
 
 // policiesFromFiles produces a list of policies from a given set of apparmor profiles.
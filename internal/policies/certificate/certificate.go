@@ -34,6 +34,8 @@ import (
 	"github.com/ubuntu/adsys/internal/consts"
 	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
 	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/adsys/internal/policies/plan"
+	"github.com/ubuntu/adsys/internal/policies/transaction"
 	"github.com/ubuntu/adsys/internal/smbsafe"
 	"github.com/ubuntu/decorate"
 )
@@ -166,19 +168,67 @@ func (m *Manager) ApplyPolicy(ctx context.Context, objectName string, isComputer
 		return nil
 	}
 
+	action, extraArgs, err := m.scriptArgs(ctx, entries)
+	if err != nil {
+		return err
+	}
+	if action == "" {
+		return nil
+	}
+
+	if err := m.runScript(ctx, action, objectName, extraArgs...); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Prepare backs up the Samba cache and the enrolled certificates into tx, so that they can be restored
+// if applying the policies fails. Requests already submitted to certmonger can't be reverted.
+func (m *Manager) Prepare(ctx context.Context, objectName string, isComputer bool, tx *transaction.Transaction) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't prepare certificate policy"))
+
+	if !isComputer {
+		return nil
+	}
+
+	log.Debugf(ctx, "Preparing certificate policy for %s", objectName)
+
+	return tx.Backup(filepath.Join(m.stateDir, "samba"), filepath.Join(m.stateDir, "certs"), filepath.Join(m.stateDir, "private"))
+}
+
+// Plan returns the call ApplyPolicy would make to the certificate autoenrollment script, without running it.
+func (m *Manager) Plan(ctx context.Context, objectName string, isComputer, isOnline bool, entries []entry.Entry) (changes []plan.Change, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't plan certificate policy"))
+
+	if !isComputer || !isOnline {
+		return nil, nil
+	}
+
+	action, extraArgs, err := m.scriptArgs(ctx, entries)
+	if err != nil {
+		return nil, err
+	}
+	if action == "" {
+		return nil, nil
+	}
+
+	scriptArgs := append([]string{action, objectName, m.domain}, extraArgs...)
+	return []plan.Change{plan.Call("cert-autoenroll", scriptArgs...)}, nil
+}
+
+// scriptArgs returns the action and additional arguments the certificate autoenrollment script should be
+// called with. action is empty if there is nothing to do.
+func (m *Manager) scriptArgs(ctx context.Context, entries []entry.Entry) (action string, extraArgs []string, err error) {
 	idx := slices.IndexFunc(entries, func(e entry.Entry) bool { return e.Key == "autoenroll" })
 	if idx == -1 {
 		// If the Samba cache directory doesn't exist, we don't have anything to unenroll
 		if _, err := os.Stat(filepath.Join(m.stateDir, "samba")); err != nil && os.IsNotExist(err) {
-			return nil
+			return "", nil, nil
 		}
 
 		log.Debug(ctx, "Certificate autoenrollment is not configured, unenrolling machine")
-		if err := m.runScript(ctx, "unenroll", objectName); err != nil {
-			return err
-		}
-
-		return nil
+		return "unenroll", nil, nil
 	}
 
 	log.Debug(ctx, "ApplyPolicy certificate policy")
@@ -186,12 +236,12 @@ func (m *Manager) ApplyPolicy(ctx context.Context, objectName string, isComputer
 	entry := entries[idx]
 	value, err := strconv.Atoi(entry.Value)
 	if err != nil {
-		return errors.New(gotext.Get("failed to parse certificate policy entry value: %v", err))
+		return "", nil, errors.New(gotext.Get("failed to parse certificate policy entry value: %v", err))
 	}
 
 	if value&disabledFlag == disabledFlag {
 		log.Debug(ctx, "Certificate policy is disabled, skipping...")
-		return nil
+		return "", nil, nil
 	}
 
 	var polSrvRegistryEntries []gpoEntry
@@ -207,14 +257,13 @@ func (m *Manager) ApplyPolicy(ctx context.Context, objectName string, isComputer
 		valuename := keyparts[len(keyparts)-1]
 		gpoData, err := gpoData(entry.Value, valuename)
 		if err != nil {
-			return errors.New(gotext.Get("failed to parse policy entry value: %v", err))
+			return "", nil, errors.New(gotext.Get("failed to parse policy entry value: %v", err))
 		}
 		polSrvRegistryEntries = append(polSrvRegistryEntries, gpoEntry{keyname, valuename, gpoData, gpoType(valuename)})
 
 		log.Debugf(ctx, "Certificate policy entry: %#v", entry)
 	}
 
-	var action string
 	log.Debugf(ctx, "Certificate policy value: %d", value)
 	action = "unenroll"
 	if value&enrollFlag == enrollFlag {
@@ -223,14 +272,10 @@ func (m *Manager) ApplyPolicy(ctx context.Context, objectName string, isComputer
 
 	jsonGPOData, err := json.Marshal(polSrvRegistryEntries)
 	if err != nil {
-		return errors.New(gotext.Get("failed to marshal policy server registry entries: %v", err))
+		return "", nil, errors.New(gotext.Get("failed to marshal policy server registry entries: %v", err))
 	}
 
-	if err := m.runScript(ctx, action, objectName, "--policy_servers_json", string(jsonGPOData)); err != nil {
-		return err
-	}
-
-	return nil
+	return action, []string{"--policy_servers_json", string(jsonGPOData)}, nil
 }
 
 // runScript runs the certificate autoenrollment script with the given arguments.
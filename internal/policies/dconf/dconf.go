@@ -44,6 +44,8 @@ import (
 	"github.com/ubuntu/adsys/internal/consts"
 	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
 	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/adsys/internal/policies/plan"
+	"github.com/ubuntu/adsys/internal/policies/transaction"
 	"github.com/ubuntu/adsys/internal/smbsafe"
 	"github.com/ubuntu/decorate"
 )
@@ -128,44 +130,9 @@ func (m *Manager) ApplyPolicy(ctx context.Context, objectName string, isComputer
 	}
 
 	// Generate defaults and locks content from policy
-	dataWithGroups := make(map[string][]string)
-	var locks []string
-	var errMsgs []string
-	for _, e := range entries {
-		log.Debugf(ctx, "Analyzing entry %+v", e)
-
-		if !e.Disabled {
-			section := filepath.Dir(e.Key)
-
-			// normalize common user error cases and check gsettings schema signature match.
-			e.Value = normalizeValue(e.Meta, e.Value)
-			if err := checkSignature(e.Meta, e.Value); err != nil {
-				errMsgs = append(errMsgs, gotext.Get("- error on %s: %v", e.Key, err))
-				continue
-			}
-
-			l := fmt.Sprintf("%s=%s", filepath.Base(e.Key), e.Value)
-			dataWithGroups[section] = append(dataWithGroups[section], l)
-		}
-		locks = append(locks, "/"+e.Key)
-	}
-
-	// Stop on any error
-	if errMsgs != nil {
-		return errors.New(strings.Join(errMsgs, "\n"))
-	}
-
-	// Prepare file contents
-	// Order sections to have a reliable output
-	var data []string
-	sections := make([]string, 0, len(dataWithGroups))
-	for s := range dataWithGroups {
-		sections = append(sections, s)
-	}
-	sort.Strings(sections)
-	for _, s := range sections {
-		data = append(data, fmt.Sprintf("[%s]", s))
-		data = append(data, dataWithGroups[s]...)
+	defaults, locks, err := policyContent(ctx, entries)
+	if err != nil {
+		return err
 	}
 
 	var needsRefresh bool
@@ -177,14 +144,14 @@ func (m *Manager) ApplyPolicy(ctx context.Context, objectName string, isComputer
 	}
 
 	defaultPath := filepath.Join(dbPath, "adsys")
-	changed, err := writeIfChanged(defaultPath, strings.Join(data, "\n")+"\n")
+	changed, err := writeIfChanged(defaultPath, defaults)
 	if err != nil {
 		return err
 	}
 	needsRefresh = needsRefresh || changed
 
 	locksPath := filepath.Join(dbPath, "locks", "adsys")
-	changed, err = writeIfChanged(locksPath, strings.Join(locks, "\n")+"\n")
+	changed, err = writeIfChanged(locksPath, locks)
 	if err != nil {
 		return err
 	}
@@ -214,6 +181,143 @@ func (m *Manager) ApplyPolicy(ctx context.Context, objectName string, isComputer
 	return nil
 }
 
+// Plan returns the changes ApplyPolicy would make for a dconf computer or user policy, without applying them.
+func (m *Manager) Plan(ctx context.Context, objectName string, isComputer bool, entries []entry.Entry) (changes []plan.Change, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't plan dconf policy for %s", objectName))
+
+	dconfDir := m.dconfDir
+	if dconfDir == "" {
+		dconfDir = consts.DefaultDconfDir
+	}
+
+	log.Debugf(ctx, "Planning dconf policy for %s", objectName)
+
+	if isComputer {
+		objectName = "machine"
+	}
+	profilesPath := filepath.Join(dconfDir, "profile")
+	dbsPath := filepath.Join(dconfDir, "db")
+	dbPath := filepath.Join(dbsPath, objectName+".d")
+
+	if !isComputer && len(entries) > 0 {
+		if _, err := os.Stat(filepath.Join(dbsPath, "machine.d", "locks", "adsys")); err != nil {
+			return nil, errors.New(gotext.Get("machine dconf database is required before generating a policy for an user. This one returns: %v", err))
+		}
+	}
+
+	if !isComputer && len(entries) == 0 {
+		for _, p := range []string{dbPath, filepath.Join(dbsPath, objectName), filepath.Join(profilesPath, objectName)} {
+			if c, ok := plan.Removal(p); ok {
+				changes = append(changes, c)
+			}
+		}
+		return changes, nil
+	}
+
+	if !isComputer {
+		profilePath := filepath.Join(profilesPath, objectName)
+		content, err := os.ReadFile(profilePath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		if c, ok := plan.File(profilePath, profileContent(objectName, content)); ok {
+			changes = append(changes, c)
+		}
+	}
+
+	defaults, locks, err := policyContent(ctx, entries)
+	if err != nil {
+		return nil, err
+	}
+
+	needsRefresh := dconfNeedsUpdate(filepath.Join(dbsPath, "machine"))
+	if !isComputer {
+		needsRefresh = needsRefresh || dconfNeedsUpdate(filepath.Join(dbsPath, objectName))
+	}
+	for _, f := range []struct{ path, content string }{
+		{filepath.Join(dbPath, "adsys"), defaults},
+		{filepath.Join(dbPath, "locks", "adsys"), locks},
+	} {
+		c, ok := plan.File(f.path, f.content)
+		if !ok {
+			continue
+		}
+		changes = append(changes, c)
+		needsRefresh = true
+	}
+
+	if needsRefresh {
+		changes = append(changes, plan.Call("dconf", "update", dbsPath))
+	}
+
+	return changes, nil
+}
+
+// Prepare backs up the dconf profile and databases of objectName into tx, so that they can be restored
+// if applying the policies fails.
+func (m *Manager) Prepare(ctx context.Context, objectName string, isComputer bool, tx *transaction.Transaction) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't prepare dconf policy for %s", objectName))
+
+	dconfDir := m.dconfDir
+	if dconfDir == "" {
+		dconfDir = consts.DefaultDconfDir
+	}
+
+	log.Debugf(ctx, "Preparing dconf policy for %s", objectName)
+
+	dbsPath := filepath.Join(dconfDir, "db")
+	if isComputer {
+		// We never touch the machine profile, only its database.
+		return tx.Backup(filepath.Join(dbsPath, "machine.d"), filepath.Join(dbsPath, "machine"))
+	}
+	return tx.Backup(filepath.Join(dconfDir, "profile", objectName), filepath.Join(dbsPath, objectName+".d"), filepath.Join(dbsPath, objectName))
+}
+
+// policyContent generates the defaults and locks database content from the policy entries.
+func policyContent(ctx context.Context, entries []entry.Entry) (defaults, locks string, err error) {
+	dataWithGroups := make(map[string][]string)
+	var locksList []string
+	var errMsgs []string
+	for _, e := range entries {
+		log.Debugf(ctx, "Analyzing entry %+v", e)
+
+		if !e.Disabled {
+			section := filepath.Dir(e.Key)
+
+			// normalize common user error cases and check gsettings schema signature match.
+			e.Value = normalizeValue(e.Meta, e.Value, e.Strategy)
+			if err := checkSignature(e.Meta, e.Value); err != nil {
+				errMsgs = append(errMsgs, gotext.Get("- error on %s: %v", e.Key, err))
+				continue
+			}
+
+			l := fmt.Sprintf("%s=%s", filepath.Base(e.Key), e.Value)
+			dataWithGroups[section] = append(dataWithGroups[section], l)
+		}
+		locksList = append(locksList, "/"+e.Key)
+	}
+
+	// Stop on any error
+	if errMsgs != nil {
+		return "", "", errors.New(strings.Join(errMsgs, "\n"))
+	}
+
+	// Prepare file contents
+	// Order sections to have a reliable output
+	var data []string
+	sections := make([]string, 0, len(dataWithGroups))
+	for s := range dataWithGroups {
+		sections = append(sections, s)
+	}
+	sort.Strings(sections)
+	for _, s := range sections {
+		data = append(data, fmt.Sprintf("[%s]", s))
+		data = append(data, dataWithGroups[s]...)
+	}
+
+	return strings.Join(data, "\n") + "\n", strings.Join(locksList, "\n") + "\n", nil
+}
+
 // writeIfChanged will only write to path if content is different from current content.
 func writeIfChanged(path string, content string) (done bool, err error) {
 	defer decorate.OnError(&err, gotext.Get("can't save %s", path))
@@ -242,9 +346,6 @@ func writeProfile(ctx context.Context, user, profilesPath string) (err error) {
 	profilePath := filepath.Join(profilesPath, user)
 	log.Debugf(ctx, "Update user profile %s", profilePath)
 
-	adsysMachineDB := "system-db:machine"
-	adsysUserDB := fmt.Sprintf("system-db:%s", user)
-
 	// Read existing content and create file if doesn’t exists
 	content, err := os.ReadFile(profilePath)
 	if err != nil {
@@ -252,21 +353,10 @@ func writeProfile(ctx context.Context, user, profilesPath string) (err error) {
 			return err
 		}
 		// #nosec G306. This asset needs to be world-readable.
-		return os.WriteFile(profilePath, []byte(fmt.Sprintf("user-db:user\n%s\n%s", adsysUserDB, adsysMachineDB)), 0644)
+		return os.WriteFile(profilePath, []byte(profileContent(user, nil)), 0644)
 	}
 
-	// Read file to insert them at the end, removing duplicates
-	var out []string
-	for _, d := range bytes.Split(bytes.TrimSpace(content), []byte("\n")) {
-		// Add current line if it’s not an adsys one
-		if string(d) == adsysMachineDB || string(d) == adsysUserDB {
-			continue
-		}
-		out = append(out, string(d))
-	}
-	out = append(out, adsysUserDB, adsysMachineDB)
-
-	newContent := []byte(strings.Join(out, "\n"))
+	newContent := []byte(profileContent(user, content))
 
 	// Is file already up to date?
 	if string(content) == string(newContent) {
@@ -284,6 +374,30 @@ func writeProfile(ctx context.Context, user, profilesPath string) (err error) {
 	return nil
 }
 
+// profileContent returns the dconf profile for user, based on its current content.
+// adsys databases are moved at the end of the profile, after any existing database.
+func profileContent(user string, content []byte) string {
+	adsysMachineDB := "system-db:machine"
+	adsysUserDB := fmt.Sprintf("system-db:%s", user)
+
+	if content == nil {
+		return fmt.Sprintf("user-db:user\n%s\n%s", adsysUserDB, adsysMachineDB)
+	}
+
+	// Read file to insert them at the end, removing duplicates
+	var out []string
+	for _, d := range bytes.Split(bytes.TrimSpace(content), []byte("\n")) {
+		// Add current line if it’s not an adsys one
+		if string(d) == adsysMachineDB || string(d) == adsysUserDB {
+			continue
+		}
+		out = append(out, string(d))
+	}
+	out = append(out, adsysUserDB, adsysMachineDB)
+
+	return strings.Join(out, "\n")
+}
+
 // dconfNeedsUpdate will notify if we need to run dconf update for that binary database.
 // For now, it only checks its existence.
 func dconfNeedsUpdate(path string) bool {
@@ -295,7 +409,8 @@ func dconfNeedsUpdate(path string) bool {
 }
 
 // normalizeValue simplify user entry by handling common mistakes on key types.
-func normalizeValue(keyType, value string) string {
+// Arrays elements are deduplicated with the merge strategy.
+func normalizeValue(keyType, value, strategy string) string {
 	value = strings.TrimSpace(value)
 	switch keyType {
 	case "s":
@@ -305,9 +420,9 @@ func normalizeValue(keyType, value string) string {
 	case "i":
 		return strings.ReplaceAll(strings.ReplaceAll(value, `"`, ""), "'", "")
 	case "as":
-		return quoteASVariant(value)
+		return quoteASVariant(value, strategy == entry.StrategyMerge)
 	case "ai":
-		return normalizeAIVariant(value)
+		return normalizeAIVariant(value, strategy == entry.StrategyMerge)
 	}
 
 	return value
@@ -344,7 +459,8 @@ func normalizeBoolean(v string) string {
 }
 
 // quoteASVariant returns a variant array of string properly quoted and separated.
-func quoteASVariant(v string) string {
+// If unique is true, only the first occurrence of each element is kept.
+func quoteASVariant(v string, unique bool) string {
 	v = strings.TrimRight(strings.TrimLeft(v, " ["), " ]")
 
 	// Remove any empty \n elements
@@ -368,6 +484,9 @@ func quoteASVariant(v string) string {
 		for _, e := range t {
 			r = append(r, quoteValue(e))
 		}
+		if unique {
+			r = uniqueElements(r)
+		}
 		return fmt.Sprintf("[%s]", strings.Join(r, ", "))
 	}
 
@@ -381,12 +500,16 @@ func quoteASVariant(v string) string {
 		e = fmt.Sprintf("'%s'", strings.TrimSpace(e))
 		r = append(r, quoteValue(e))
 	}
+	if unique {
+		r = uniqueElements(r)
+	}
 
 	return fmt.Sprintf("[%s]", strings.Join(r, ", "))
 }
 
 // normalizeAIVariant returns a variant array of int with proper separator.
-func normalizeAIVariant(v string) string {
+// If unique is true, only the first occurrence of each element is kept.
+func normalizeAIVariant(v string, unique bool) string {
 	v = strings.TrimRight(strings.TrimLeft(v, " ["), " ]")
 
 	// Remove any empty \n elements
@@ -401,9 +524,27 @@ func normalizeAIVariant(v string) string {
 
 	// normalize separator spaces
 	v = strings.Join(elems, ",")
-	v = strings.ReplaceAll(strings.ReplaceAll(v, " ", ""), ",", ", ")
+	v = strings.ReplaceAll(v, " ", "")
+	r := strings.Split(v, ",")
+	if unique {
+		r = uniqueElements(r)
+	}
+
+	return fmt.Sprintf("[%s]", strings.Join(r, ", "))
+}
 
-	return fmt.Sprintf("[%s]", v)
+// uniqueElements returns elems with only the first occurrence of each element.
+func uniqueElements(elems []string) []string {
+	var r []string
+	seen := make(map[string]struct{})
+	for _, e := range elems {
+		if _, exists := seen[e]; exists {
+			continue
+		}
+		seen[e] = struct{}{}
+		r = append(r, e)
+	}
+	return r
 }
 
 // splitOnNonEscaped splits v by sep, only if sep is not escaped.
@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -222,3 +223,85 @@ func TestApplyPolicy(t *testing.T) {
 		})
 	}
 }
+
+func TestPlan(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		isComputer       bool
+		entries          []entry.Entry
+		existingDconfDir string
+
+		wantErr bool
+	}{
+		// User cases
+		"New user": {entries: []entry.Entry{
+			{Key: "com/ubuntu/category/key-s", Value: "'onekey-s-othervalue'", Meta: "s"}}},
+		"User updates existing value": {entries: []entry.Entry{
+			{Key: "com/ubuntu/category/key-s", Value: "'onekey-s-thirdvalue'", Meta: "s"}},
+			existingDconfDir: "existing-user"},
+		"User empty state, with existing machine policy": {entries: []entry.Entry{}, existingDconfDir: "existing-user"},
+		"Update existing profile without needed db append them": {
+			existingDconfDir: "existing-user-no-adsysdb"},
+
+		// Machine cases
+		"First boot": {entries: []entry.Entry{
+			{Key: "com/ubuntu/category/key-s", Value: "'onekey-s-othervalue'", Meta: "s"}},
+			isComputer: true, existingDconfDir: "-"},
+		"Machine updates existing value": {entries: []entry.Entry{
+			{Key: "com/ubuntu/category/key-s", Value: "'onekey-s-thirdvalue'", Meta: "s"}},
+			isComputer: true},
+
+		// Update edge cases
+		"No change when policy is already applied": {entries: []entry.Entry{
+			{Key: "com/ubuntu/category/key-s", Value: "'onekey-s-othervalue'", Meta: "s"}},
+			existingDconfDir: "existing-user"},
+
+		// Error cases
+		"Error when machine db does not exist": {entries: []entry.Entry{
+			{Key: "com/ubuntu/category/key-s", Value: "'onekey-s-othervalue'", Meta: "s"},
+		}, existingDconfDir: "-", wantErr: true},
+		"Error on invalid ai": {entries: []entry.Entry{
+			{Key: "com/ubuntu/category/key-ai", Value: "[1,b]", Meta: "ai"},
+		}, wantErr: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			dconfDir := t.TempDir()
+
+			if tc.entries == nil {
+				tc.entries = []entry.Entry{{Key: "com/ubuntu/category/key-s", Value: "'onekey-s-othervalue'", Meta: "s"}}
+			}
+
+			if tc.existingDconfDir == "" {
+				tc.existingDconfDir = "machine-base"
+			}
+			if tc.existingDconfDir != "-" {
+				require.NoError(t, os.Remove(dconfDir), "Setup: can't delete dconf base directory before recreation")
+				require.NoError(t,
+					shutil.CopyTree(
+						filepath.Join("testdata", "TestApplyPolicy", "dconf", tc.existingDconfDir), dconfDir,
+						&shutil.CopyTreeOptions{Symlinks: true, CopyFunction: shutil.Copy}),
+					"Setup: can't create initial dconf directory")
+			}
+
+			m := dconf.NewWithDconfDir(dconfDir)
+			changes, err := m.Plan(context.Background(), "ubuntu", tc.isComputer, tc.entries)
+			if tc.wantErr {
+				require.NotNil(t, err, "Plan should have failed but didn't")
+				return
+			}
+			require.NoError(t, err, "Plan failed but shouldn't have")
+
+			var got []string
+			for _, c := range changes {
+				got = append(got, strings.ReplaceAll(c.String(), dconfDir, "#DCONFDIR#"))
+			}
+			want := testutils.LoadWithUpdateFromGolden(t, strings.Join(got, "\n"))
+			require.Equal(t, want, strings.Join(got, "\n"), "Plan returned expected changes")
+		})
+	}
+}
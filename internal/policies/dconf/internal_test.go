@@ -4,14 +4,16 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/ubuntu/adsys/internal/policies/entry"
 )
 
 func TestNormalize(t *testing.T) {
 	t.Parallel()
 
 	tests := map[string]struct {
-		keyType string
-		value   string
+		keyType  string
+		value    string
+		strategy string
 
 		want string
 	}{
@@ -79,6 +81,10 @@ func TestNormalize(t *testing.T) {
 		"Multi-lines as with leading or trailing empty lines before [] are ignored": {keyType: "as", value: "[\n\n\n\naa\nbb\ncc\n\n\n\n\n]", want: "['aa', 'bb', 'cc']"},
 		"Multi-lines as with leading or trailing empty lines after [] are ignored":  {keyType: "as", value: "\n\n\n\n[aa\nbb\ncc]\n\n\n\n\n", want: "['aa', 'bb', 'cc']"},
 
+		"Duplicated as elements are kept by default":              {keyType: "as", value: "aa\nbb\naa", want: "['aa', 'bb', 'aa']"},
+		"Duplicated as elements are removed with merge, unquoted": {keyType: "as", value: "aa,bb\ncc, aa\nbb", strategy: entry.StrategyMerge, want: "['aa', 'bb', 'cc']"},
+		"Duplicated as elements are removed with merge, quoted":   {keyType: "as", value: "'aa','bb'\n'cc'\n'aa'", strategy: entry.StrategyMerge, want: "['aa', 'bb', 'cc']"},
+
 		// ai cases
 		"simple ai":                                        {keyType: "ai", value: "[1, 2, 3]", want: "[1, 2, 3]"},
 		"simple ai with no spaces":                         {keyType: "ai", value: "[1,2,3]", want: "[1, 2, 3]"},
@@ -99,6 +105,9 @@ func TestNormalize(t *testing.T) {
 		"Multi-lines ai with leading or trailing empty lines before [] are ignored": {keyType: "ai", value: "[\n\n\n\n1\n2\n3\n\n\n\n\n]", want: "[1, 2, 3]"},
 		"Multi-lines ai with leading or trailing empty lines after [] are ignored":  {keyType: "ai", value: "\n\n\n\n[1\n2\n3]\n\n\n\n\n", want: "[1, 2, 3]"},
 
+		"Duplicated ai elements are kept by default":    {keyType: "ai", value: "1\n2\n1", want: "[1, 2, 1]"},
+		"Duplicated ai elements are removed with merge": {keyType: "ai", value: "1, 2\n3\n 1", strategy: entry.StrategyMerge, want: "[1, 2, 3]"},
+
 		// Unmanaged cases
 		"unmanaged types are returned as is": {keyType: "xxx", value: "hello [ %x bar 🤪", want: "hello [ %x bar 🤪"},
 	}
@@ -107,7 +116,7 @@ func TestNormalize(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			got := normalizeValue(tc.keyType, tc.value)
+			got := normalizeValue(tc.keyType, tc.value, tc.strategy)
 			assert.Equal(t, tc.want, got, "normalizeValue returned expected value")
 		})
 	}
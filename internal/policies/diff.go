@@ -0,0 +1,184 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/leonelquinteros/gotext"
+	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
+	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/decorate"
+)
+
+// DiffPolicies displays the rules which would change for objectName if pols replaced its currently applied policies.
+func (m *Manager) DiffPolicies(ctx context.Context, objectName string, pols *Policies) (msg string, err error) {
+	defer decorate.OnError(&err, gotext.Get("failed to compare policies for %q", objectName))
+
+	log.Infof(ctx, "Comparing applied policies for %s with new ones", objectName)
+
+	// An object which never had policies applied is compared with empty policies: everything is added.
+	var current Policies
+	p := filepath.Join(m.policiesCacheDir, objectName)
+	if _, err := os.Stat(p); err == nil {
+		if current, err = NewFromCache(ctx, p); err != nil {
+			return "", err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	defer current.Close()
+
+	var out strings.Builder
+	fmt.Fprintln(&out, gotext.Get("Policies changes for %s, from applied ones to Active Directory ones:", objectName))
+	formatDiff(&out, current, *pols)
+	return out.String(), nil
+}
+
+// DiffSnapshot displays the rules which changed for objectName between snapshot id of its history and its currently
+// applied policies.
+func (m *Manager) DiffSnapshot(ctx context.Context, objectName string, id int) (msg string, err error) {
+	defer decorate.OnError(&err, gotext.Get("failed to compare policies for %q with snapshot %d", objectName, id))
+
+	log.Infof(ctx, "Comparing applied policies for %s with snapshot %d", objectName, id)
+
+	p := filepath.Join(m.historyCacheDir, objectName, strconv.Itoa(id))
+	if _, err := os.Stat(p); err != nil {
+		return "", errors.New(gotext.Get("no snapshot %d in history: %v", id, err))
+	}
+	snapshot, err := NewFromCache(ctx, p)
+	if err != nil {
+		return "", err
+	}
+	defer snapshot.Close()
+
+	current, err := NewFromCache(ctx, filepath.Join(m.policiesCacheDir, objectName))
+	if err != nil {
+		return "", errors.New(gotext.Get("no policy applied for %q: %v", objectName, err))
+	}
+	defer current.Close()
+
+	var out strings.Builder
+	fmt.Fprintln(&out, gotext.Get("Policies changes for %s, from snapshot %d to applied ones:", objectName, id))
+	formatDiff(&out, snapshot, current)
+	return out.String(), nil
+}
+
+// winningRule is the resulting value of a rule, with the GPOs which set it.
+type winningRule struct {
+	entry.Entry
+	gpos []string
+}
+
+// winningRules returns, per rule type and key, the rule resulting from pols with the GPOs setting it.
+// The GPOs are the ones which are not overridden: the closest one, or all those appending a value.
+func winningRules(pols Policies) map[string]map[string]winningRule {
+	r := make(map[string]map[string]winningRule)
+	for t, entries := range pols.GetUniqueRules() {
+		r[t] = make(map[string]winningRule)
+		for _, e := range entries {
+			r[t][e.Key] = winningRule{Entry: e}
+		}
+	}
+
+	alreadyProcessedRules := make(map[string]struct{})
+	for _, g := range pols.GPOs {
+		for _, t := range g.ruleTypes() {
+			for _, rule := range g.annotatedRules(t, alreadyProcessedRules) {
+				w, ok := r[t][rule.Key]
+				// Disabled keys are not appended.
+				if !ok || rule.Overridden || (entry.IsListStrategy(rule.Strategy) && rule.Disabled) {
+					continue
+				}
+				// Only values of the same list strategy are merged: further GPOs don't count once a closer one set the key.
+				if len(w.gpos) > 0 && (!entry.IsListStrategy(w.Strategy) || rule.Strategy != w.Strategy) {
+					continue
+				}
+				w.gpos = append(w.gpos, fmt.Sprintf("%s %s", g.Name, g.ID))
+				r[t][rule.Key] = w
+			}
+		}
+	}
+
+	return r
+}
+
+// formatDiff writes to w the rules added, removed and changed per rule type from one set of policies to another.
+func formatDiff(w *strings.Builder, from, to Policies) {
+	oldRules, newRules := winningRules(from), winningRules(to)
+
+	var types []string
+	for t := range oldRules {
+		types = append(types, t)
+	}
+	for t := range newRules {
+		if _, ok := oldRules[t]; !ok {
+			types = append(types, t)
+		}
+	}
+	slices.Sort(types)
+
+	var changed bool
+	for _, t := range types {
+		var keys []string
+		for k := range oldRules[t] {
+			keys = append(keys, k)
+		}
+		for k := range newRules[t] {
+			if _, ok := oldRules[t][k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+
+		var lines []string
+		for _, k := range keys {
+			o, inOld := oldRules[t][k]
+			n, inNew := newRules[t][k]
+			switch {
+			case !inOld:
+				lines = append(lines, fmt.Sprintf("+ %s: %s (%s)", k, diffValue(n.Entry), strings.Join(n.gpos, ", ")))
+			case !inNew:
+				lines = append(lines, fmt.Sprintf("- %s: %s (%s)", k, diffValue(o.Entry), strings.Join(o.gpos, ", ")))
+			case diffValue(o.Entry) != diffValue(n.Entry) || !slices.Equal(o.gpos, n.gpos):
+				v := diffValue(n.Entry)
+				if oldV := diffValue(o.Entry); oldV != v {
+					v = oldV + " -> " + v
+				}
+				gpos := strings.Join(n.gpos, ", ")
+				if oldGPOs := strings.Join(o.gpos, ", "); oldGPOs != gpos {
+					gpos = oldGPOs + " -> " + gpos
+				}
+				lines = append(lines, fmt.Sprintf("~ %s: %s (%s)", k, v, gpos))
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+
+		changed = true
+		fmt.Fprintf(w, "* %s:\n", t)
+		for _, l := range lines {
+			fmt.Fprintf(w, "    %s\n", l)
+		}
+	}
+
+	if !changed {
+		fmt.Fprintln(w, gotext.Get("No change"))
+	}
+}
+
+// diffValue returns the value of e on a single line, as displayed in a diff.
+func diffValue(e entry.Entry) string {
+	if e.Disabled {
+		return gotext.Get("<disabled>")
+	}
+	// Trim EOL \n and replace them all with \n in text to keep each value printed in one single line
+	return strings.ReplaceAll(strings.TrimSpace(e.Value), "\n", `\n`)
+}
@@ -0,0 +1,90 @@
+package policies_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/termie/go-shutil"
+	"github.com/ubuntu/adsys/internal/policies"
+	"github.com/ubuntu/adsys/internal/testutils"
+)
+
+func TestDiffPolicies(t *testing.T) {
+	t.Parallel()
+
+	bus := testutils.NewDbusConn(t)
+
+	tests := map[string]struct {
+		applied     string
+		other       string
+		fromHistory bool
+		snapshotID  int
+
+		wantErr bool
+	}{
+		"No change with the same policies":             {applied: "three_gpos_with_append", other: "three_gpos_with_append"},
+		"Added, removed and changed keys":              {applied: "three_gpos_with_append", other: "three_gpos_with_append_other"},
+		"All keys added from empty applied policies":   {applied: "", other: "three_gpos_with_append"},
+		"All keys removed when new policies are empty": {applied: "three_gpos_with_append", other: ""},
+		"All keys added when no policy was applied":    {applied: "-", other: "three_gpos_with_append"},
+
+		// Snapshot cases
+		"Changes from snapshot to applied policies":   {applied: "three_gpos_with_append_other", other: "three_gpos_with_append", fromHistory: true, snapshotID: 1},
+		"No change from snapshot to applied policies": {applied: "three_gpos_with_append", other: "three_gpos_with_append", fromHistory: true, snapshotID: 1},
+
+		// Error cases
+		"Error on no applied policies, snapshot": {applied: "-", other: "three_gpos_with_append", fromHistory: true, snapshotID: 1, wantErr: true},
+		"Error on unknown snapshot":              {applied: "three_gpos_with_append", other: "three_gpos_with_append", fromHistory: true, snapshotID: 2, wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			cacheDir := t.TempDir()
+			m, err := policies.NewManager(bus, "hostname", mockBackend{}, policies.WithCacheDir(cacheDir), policies.WithRunDir(t.TempDir()))
+			require.NoError(t, err, "Setup: couldn’t get a new policy manager")
+
+			copyPolicies := func(src, dst string) {
+				t.Helper()
+
+				if src == "" {
+					pols, err := policies.New(context.Background(), nil, "")
+					require.NoError(t, err, "Setup: can not create empty policies")
+					require.NoError(t, pols.Save(dst), "Setup: can not save empty policies")
+					return
+				}
+				require.NoError(t, os.MkdirAll(filepath.Dir(dst), 0750), "Setup: can not create policies parent directory")
+				err := shutil.CopyTree(filepath.Join("testdata", "cache", "policies", src), dst, nil)
+				require.NoError(t, err, "Setup: couldn’t copy policies cache")
+			}
+
+			if tc.applied != "-" {
+				copyPolicies(tc.applied, filepath.Join(cacheDir, policies.PoliciesCacheBaseName, "user"))
+			}
+
+			var got string
+			if tc.fromHistory {
+				copyPolicies(tc.other, filepath.Join(cacheDir, policies.HistoryCacheBaseName, "user", "1"))
+				got, err = m.DiffSnapshot(context.Background(), "user", tc.snapshotID)
+			} else {
+				otherDir := filepath.Join(t.TempDir(), "other")
+				copyPolicies(tc.other, otherDir)
+				pols, errLoad := policies.NewFromCache(context.Background(), otherDir)
+				require.NoError(t, errLoad, "Setup: can not load other policies")
+				defer pols.Close()
+				got, err = m.DiffPolicies(context.Background(), "user", &pols)
+			}
+			if tc.wantErr {
+				require.Error(t, err, "Diff should return an error but got none")
+				return
+			}
+			require.NoError(t, err, "Diff should return no error but got one")
+
+			want := testutils.LoadWithUpdateFromGolden(t, got)
+			require.Equal(t, want, got, "Diff returned expected output")
+		})
+	}
+}
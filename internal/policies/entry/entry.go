@@ -25,5 +25,19 @@ const (
 	// append means from a GPO standpoint that the further GPO value is listed before closest GPO
 	// (and then, enforced GPO in reverse order).
 	StrategyAppend = "append"
-	// This can be extended to support prepend but it is implemented yet as there is no real world cases.
+	// StrategyPrepend is the strategy to prepend a value to an existing one.
+	// prepend means from a GPO standpoint that the closest GPO value is listed before further GPO.
+	StrategyPrepend = "prepend"
+	// StrategyMerge is the strategy to append a value to an existing one, without duplicates.
+	// Values are listed in the same order than append, only keeping the first occurrence of each element.
+	StrategyMerge = "merge"
 )
+
+// IsListStrategy returns true if strategy combines values of the same key between multiple GPOs.
+func IsListStrategy(strategy string) bool {
+	switch strategy {
+	case StrategyAppend, StrategyPrepend, StrategyMerge:
+		return true
+	}
+	return false
+}
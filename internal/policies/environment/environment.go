@@ -0,0 +1,541 @@
+// Package environment is the policy manager for the environment variables of the machine and of the users.
+//
+// Variables are set from two kinds of rules:
+//   - the system-variables and user-variables settings list, one per line, variables of the form NAME=value, or
+//     NAME+=value to append value to the current one;
+//   - Group Policy Preferences environment variables are keyed by their name. Partial ones, like PATH, use the
+//     append strategy and their values are appended to the current one.
+//
+// Appended values are separated by ":", as in PATH, except for the no_proxy and NO_PROXY lists which use ",".
+// Setting a variable drops the values appended before it, and a disabled variable is not set by adsys anymore.
+// Invalid variable names are skipped with a warning.
+//
+// The machine variables are written to 90-adsys.conf under /etc/environment.d, for the systemd user sessions, and
+// exported by adsys-environment.sh under /etc/profile.d, for login shells.
+// The user variables are written to 91-adsys-user.conf under the ~/.config/environment.d directory of the user,
+// owned by the user, so that they are read after the machine ones. This directory is only created once the home
+// directory of the user exists.
+//
+// Files are removed when no variable is set anymore.
+package environment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/leonelquinteros/gotext"
+	"github.com/ubuntu/adsys/internal/consts"
+	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
+	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/adsys/internal/policies/plan"
+	"github.com/ubuntu/adsys/internal/policies/transaction"
+	"github.com/ubuntu/decorate"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	machineConfName = "90-adsys.conf"
+	profileName     = "adsys-environment.sh"
+	userConfName    = "91-adsys-user.conf"
+
+	header = `# This file is managed by adsys.
+# Do not edit this file manually.
+# Any changes will be overwritten.
+
+`
+)
+
+var (
+	// nameRe matches valid environment variable names.
+	nameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+	// userConfDir is the environment.d directory of a user, relative to its home directory.
+	userConfDir = filepath.Join(".config", "environment.d")
+)
+
+// Manager holds the directories where the environment variables are written.
+type Manager struct {
+	environmentDir string
+	profileDir     string
+
+	userLookup func(string) (*user.User, error)
+}
+
+type options struct {
+	userLookup func(string) (*user.User, error)
+}
+
+// Option reprents an optional function to change the environment manager.
+type Option func(*options)
+
+// New creates a manager writing the machine environment variables in environmentDir and profileDir.
+func New(environmentDir, profileDir string, opts ...Option) *Manager {
+	// defaults
+	args := options{
+		userLookup: user.Lookup,
+	}
+	// applied options
+	for _, o := range opts {
+		o(&args)
+	}
+
+	if environmentDir == "" {
+		environmentDir = consts.DefaultEnvironmentDir
+	}
+	if profileDir == "" {
+		profileDir = consts.DefaultProfileDir
+	}
+
+	return &Manager{
+		environmentDir: environmentDir,
+		profileDir:     profileDir,
+		userLookup:     args.userLookup,
+	}
+}
+
+// ApplyPolicy writes the environment variables of the object based on a list of entries.
+func (m *Manager) ApplyPolicy(ctx context.Context, objectName string, isComputer bool, entries []entry.Entry) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't apply environment policy to %s", objectName))
+
+	log.Debugf(ctx, "Applying environment policy to %s", objectName)
+
+	vars := variables(ctx, isComputer, entries)
+
+	if isComputer {
+		for _, f := range m.machineFiles(vars) {
+			if err := writeOrRemove(f.path, f.content); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	u, uid, gid, err := m.lookupUser(objectName)
+	if err != nil {
+		return err
+	}
+	content := environmentContent(vars)
+
+	// The user owns its home directory: we only work relative to directories opened without following any symlink.
+	dir, err := userDir(u.HomeDir, uid, gid, content != "")
+	if err != nil {
+		return err
+	}
+	if dir == nil {
+		if content != "" {
+			log.Warning(ctx, gotext.Get("Home directory of %q doesn't exist yet, its environment variables will be set on next refresh", objectName))
+		}
+		return nil
+	}
+	defer dir.Close()
+
+	if content == "" {
+		if err := unix.Unlinkat(int(dir.Fd()), userConfName, 0); err != nil && !errors.Is(err, unix.ENOENT) {
+			return &fs.PathError{Op: "remove", Path: filepath.Join(dir.Name(), userConfName), Err: err}
+		}
+		return nil
+	}
+	return writeFileAt(dir, userConfName, uid, gid, content)
+}
+
+// Plan returns the changes ApplyPolicy would make for an environment policy, without applying them.
+func (m *Manager) Plan(ctx context.Context, objectName string, isComputer bool, entries []entry.Entry) (changes []plan.Change, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't plan environment policy for %s", objectName))
+
+	vars := variables(ctx, isComputer, entries)
+
+	files := m.machineFiles(vars)
+	if !isComputer {
+		u, uid, gid, err := m.lookupUser(objectName)
+		if err != nil {
+			return nil, err
+		}
+		// Nothing is set until the home directory exists, as in ApplyPolicy.
+		if _, err := os.Stat(u.HomeDir); errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		dir, err := userDir(u.HomeDir, uid, gid, false)
+		if err != nil {
+			return nil, err
+		}
+		if dir != nil {
+			dir.Close()
+		}
+		files = []file{{path: filepath.Join(u.HomeDir, userConfDir, userConfName), content: environmentContent(vars)}}
+	}
+
+	for _, f := range files {
+		c, ok := plan.File(f.path, f.content)
+		if f.content == "" {
+			c, ok = plan.Removal(f.path)
+		}
+		if ok {
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
+
+// Prepare backs up the environment files of the object into tx, so that they can be restored if applying the
+// policies fails.
+func (m *Manager) Prepare(ctx context.Context, objectName string, isComputer bool, tx *transaction.Transaction) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't prepare environment policy for %s", objectName))
+
+	log.Debugf(ctx, "Preparing environment policy for %s", objectName)
+
+	if isComputer {
+		return tx.Backup(filepath.Join(m.environmentDir, machineConfName), filepath.Join(m.profileDir, profileName))
+	}
+
+	u, err := m.userLookup(objectName)
+	if err != nil {
+		return errors.New(gotext.Get("could not retrieve user for %q: %v", objectName, err))
+	}
+	return tx.Backup(filepath.Join(u.HomeDir, userConfDir, userConfName))
+}
+
+// variable is an environment variable set by the policy.
+type variable struct {
+	name string
+	// value is the value the variable is set to, if set is true. Otherwise, appended values are appended to the
+	// current value of the variable.
+	value    string
+	set      bool
+	appended []string
+}
+
+// variables returns the variables to set from entries, sorted by name.
+func variables(ctx context.Context, isComputer bool, entries []entry.Entry) []variable {
+	listKey := "environment/user-variables"
+	if isComputer {
+		listKey = "environment/system-variables"
+	}
+
+	vars := make(map[string]*variable)
+	setVar := func(name, value string, appended bool) {
+		if !nameRe.MatchString(name) {
+			log.Warning(ctx, gotext.Get("Invalid environment variable name %q, skipping", name))
+			return
+		}
+		v, ok := vars[name]
+		if !ok || !appended {
+			v = &variable{name: name}
+			vars[name] = v
+		}
+		if !appended {
+			v.value, v.set = value, true
+			return
+		}
+		v.appended = append(v.appended, value)
+	}
+
+	// Settings lists are applied first, so that preferences variables can override them.
+	for _, e := range entries {
+		if e.Key != listKey {
+			continue
+		}
+		if e.Disabled {
+			log.Debug(ctx, gotext.Get("The entry %q is disabled and will be skipped", e.Key))
+			continue
+		}
+		for _, l := range strings.Split(e.Value, "\n") {
+			l = strings.TrimSpace(l)
+			if l == "" || strings.HasPrefix(l, "#") {
+				continue
+			}
+			name, value, found := strings.Cut(l, "=")
+			if !found {
+				log.Warning(ctx, gotext.Get("Invalid environment variable %q, expected NAME=value, skipping", l))
+				continue
+			}
+			name, appended := strings.CutSuffix(strings.TrimSpace(name), "+")
+			setVar(strings.TrimSpace(name), strings.TrimSpace(value), appended)
+		}
+	}
+
+	for _, e := range entries {
+		if strings.Contains(e.Key, "/") {
+			if e.Key != listKey {
+				log.Debug(ctx, gotext.Get("The entry %q is not supported by the environment manager and will be skipped", e.Key))
+			}
+			continue
+		}
+		if e.Disabled {
+			delete(vars, e.Key)
+			continue
+		}
+		if e.Strategy != entry.StrategyAppend {
+			setVar(e.Key, e.Value, false)
+			continue
+		}
+		// Values of partial variables are appended, one per GPO.
+		for _, value := range strings.Split(e.Value, "\n") {
+			if value = strings.TrimSpace(value); value != "" {
+				setVar(e.Key, value, true)
+			}
+		}
+	}
+
+	var r []variable
+	for _, v := range vars {
+		r = append(r, *v)
+	}
+	slices.SortFunc(r, func(a, b variable) int { return strings.Compare(a.name, b.name) })
+	return r
+}
+
+// separator returns the separator of the values appended to the variable: "," for the proxy exceptions lists, and
+// ":" otherwise, as in PATH.
+func (v variable) separator() string {
+	if v.name == "no_proxy" || v.name == "NO_PROXY" {
+		return ","
+	}
+	return ":"
+}
+
+// rendered returns the value to assign to the variable.
+// Appended values only add a separator if the variable is already set and not empty.
+func (v variable) rendered() string {
+	sep := v.separator()
+	appended := strings.Join(v.appended, sep)
+	switch {
+	case !v.set:
+		return fmt.Sprintf("${%s:+${%s}%s}%s", v.name, v.name, sep, appended)
+	case appended == "":
+		return v.value
+	case v.value == "":
+		return appended
+	default:
+		return v.value + sep + appended
+	}
+}
+
+// quote returns value between double quotes, escaping the characters which are not kept as is, but leaving
+// variables expansion in place. This is common to environment.d and shell scripts.
+// Any "$" not starting a $NAME or ${NAME} reference is escaped, so that commands like $(cmd) are never run.
+func quote(value string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`")
+	value = r.Replace(value)
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(value); i++ {
+		if value[i] == '$' && (i+1 == len(value) || !isReferenceStart(value[i+1])) {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(value[i])
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// isReferenceStart returns true if c starts a variable reference after a "$".
+func isReferenceStart(c byte) bool {
+	return c == '{' || c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// environmentContent returns the content of the environment.d configuration file setting vars.
+// It is empty if there is no variable to set.
+func environmentContent(vars []variable) string {
+	if len(vars) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(header)
+	for _, v := range vars {
+		fmt.Fprintf(&b, "%s=%s\n", v.name, quote(v.rendered()))
+	}
+	return b.String()
+}
+
+// profileContent returns the content of the profile.d script exporting vars.
+// It is empty if there is no variable to set.
+func profileContent(vars []variable) string {
+	if len(vars) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(header)
+	for _, v := range vars {
+		fmt.Fprintf(&b, "export %s=%s\n", v.name, quote(v.rendered()))
+	}
+	return b.String()
+}
+
+// file is a file to write with content, or to remove if content is empty.
+type file struct {
+	path    string
+	content string
+}
+
+// machineFiles returns the files setting vars for the machine.
+func (m *Manager) machineFiles(vars []variable) []file {
+	return []file{
+		{path: filepath.Join(m.environmentDir, machineConfName), content: environmentContent(vars)},
+		{path: filepath.Join(m.profileDir, profileName), content: profileContent(vars)},
+	}
+}
+
+// lookupUser returns the user objectName with its uid and gid.
+func (m *Manager) lookupUser(objectName string) (u *user.User, uid, gid int, err error) {
+	if u, err = m.userLookup(objectName); err != nil {
+		return nil, 0, 0, errors.New(gotext.Get("could not retrieve user for %q: %v", objectName, err))
+	}
+	if uid, err = strconv.Atoi(u.Uid); err != nil {
+		return nil, 0, 0, errors.New(gotext.Get("couldn't convert %q to a valid uid for %q", u.Uid, objectName))
+	}
+	if gid, err = strconv.Atoi(u.Gid); err != nil {
+		return nil, 0, 0, errors.New(gotext.Get("couldn't convert %q to a valid gid for %q", u.Gid, objectName))
+	}
+	return u, uid, gid, nil
+}
+
+// writeOrRemove writes content to path, creating its directory if needed, or removes path if content is empty.
+// The file is only written if its content changed.
+func writeOrRemove(path, content string) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't save %s", path))
+
+	if content == "" {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	if oldContent, err := os.ReadFile(path); err == nil && string(oldContent) == content {
+		return nil
+	}
+
+	// nolint:gosec // G301 match distribution permission
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// nolint:gosec // G306 This asset needs to be world-readable.
+	if err := os.WriteFile(path+".new", []byte(content), 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".new", path)
+}
+
+// userDir opens the environment.d directory of the user under home. Each directory under home is opened relatively
+// to its parent without following symlinks, so that the user can't redirect our writes by replacing them.
+// It returns nil if home doesn't exist, or if a directory is missing and create is false. If create is true, the
+// missing directories are created, owned by uid and gid.
+func userDir(home string, uid, gid int, create bool) (dir *os.File, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't open user environment directory in %q", home))
+
+	dir, err = os.Open(home)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	p := home
+	for _, d := range strings.Split(userConfDir, string(os.PathSeparator)) {
+		p = filepath.Join(p, d)
+		next, err := openDirAt(dir, d, p, uid, gid, create)
+		dir.Close()
+		if err != nil || next == nil {
+			return nil, err
+		}
+		dir = next
+	}
+
+	return dir, nil
+}
+
+// openDirAt opens the directory name under parent, without following symlinks. If it doesn't exist, it is created
+// owned by uid and gid when create is true, and nil is returned otherwise. p is the path of the directory.
+func openDirAt(parent *os.File, name, p string, uid, gid int, create bool) (*os.File, error) {
+	const flags = unix.O_RDONLY | unix.O_DIRECTORY | unix.O_NOFOLLOW | unix.O_CLOEXEC
+
+	fd, err := unix.Openat(int(parent.Fd()), name, flags, 0)
+	if errors.Is(err, unix.ELOOP) || errors.Is(err, unix.ENOTDIR) {
+		return nil, errors.New(gotext.Get("%q is not a directory", p))
+	}
+	if err == nil {
+		return os.NewFile(uintptr(fd), p), nil
+	}
+	if !errors.Is(err, unix.ENOENT) {
+		return nil, &fs.PathError{Op: "open", Path: p, Err: err}
+	}
+	if !create {
+		return nil, nil
+	}
+
+	if err := unix.Mkdirat(int(parent.Fd()), name, 0700); err != nil {
+		return nil, &fs.PathError{Op: "mkdir", Path: p, Err: err}
+	}
+	fd, err = unix.Openat(int(parent.Fd()), name, flags, 0)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: p, Err: err}
+	}
+	d := os.NewFile(uintptr(fd), p)
+	if err := chown(p, d, uid, gid); err != nil {
+		d.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// writeFileAt writes content into the file name under dir and changes its ownership to uid and gid.
+// The temporary file is created exclusively and without following symlinks, to not write through a symlink put there
+// by the user.
+func writeFileAt(dir *os.File, name string, uid, gid int, content string) (err error) {
+	p := filepath.Join(dir.Name(), name)
+	defer decorate.OnError(&err, gotext.Get("failed when writing file %s", p))
+
+	dirFd := int(dir.Fd())
+	if err := unix.Unlinkat(dirFd, name+".new", 0); err != nil && !errors.Is(err, unix.ENOENT) {
+		return err
+	}
+	fd, err := unix.Openat(dirFd, name+".new", unix.O_WRONLY|unix.O_CREAT|unix.O_EXCL|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0600)
+	if err != nil {
+		return err
+	}
+	f := os.NewFile(uintptr(fd), p+".new")
+	defer f.Close()
+
+	if _, err := f.WriteString(content); err != nil {
+		return err
+	}
+	// Fixes the file ownership before renaming it.
+	if err := chown(p+".new", f, uid, gid); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return unix.Renameat(dirFd, name+".new", dirFd, name)
+}
+
+// chown either chown the file descriptor attached, or the path if this one is null to uid and gid.
+// It will know if we should skip chown for tests.
+func chown(p string, f *os.File, uid, gid int) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't chown %q", p))
+
+	if os.Getenv("ADSYS_SKIP_ROOT_CALLS") != "" {
+		uid = -1
+		gid = -1
+	}
+
+	if f == nil {
+		// Ensure that if p is a symlink, we only change the symlink itself, not what was pointed by it.
+		return os.Lchown(p, uid, gid)
+	}
+
+	return f.Chown(uid, gid)
+}
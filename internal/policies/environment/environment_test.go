@@ -0,0 +1,239 @@
+package environment_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/user"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/adsys/internal/policies/environment"
+	"github.com/ubuntu/adsys/internal/policies/transaction"
+	"github.com/ubuntu/adsys/internal/testutils"
+)
+
+func TestApplyPolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		notComputer   bool
+		entries       []entry.Entry
+		existingFiles bool
+		noHome        bool
+		symlinkDir    string
+		userLookupErr bool
+
+		wantErr bool
+	}{
+		"Set variables from settings": {entries: []entry.Entry{
+			{Key: "environment/system-variables", Value: "LICENSE_SERVER=27000@licence.example.com\nREGISTRY_MIRROR = https://mirror.example.com"},
+		}},
+		"Append values to variables": {entries: []entry.Entry{
+			{Key: "environment/system-variables", Value: "PATH+=/opt/tools/bin\nPATH+=/opt/other/bin\nno_proxy=localhost\nno_proxy+=.example.com\nNO_PROXY+=.example.com"},
+		}},
+		"Setting a variable drops previously appended values": {entries: []entry.Entry{
+			{Key: "environment/system-variables", Value: "PATH+=/opt/tools/bin\nPATH=/usr/bin"},
+		}},
+		"Preferences variables override settings": {entries: []entry.Entry{
+			{Key: "environment/system-variables", Value: "EDITOR=nano\nLICENSE_SERVER=27000@licence.example.com"},
+			{Key: "EDITOR", Value: "vim"},
+		}},
+		"Partial preferences variables are appended": {entries: []entry.Entry{
+			{Key: "environment/system-variables", Value: "PATH+=/opt/tools/bin"},
+			{Key: "PATH", Value: "/opt/gpo1/bin\n/opt/gpo2/bin", Strategy: entry.StrategyAppend},
+		}},
+		"Disabled preferences variables are not set": {entries: []entry.Entry{
+			{Key: "environment/system-variables", Value: "OLD_PROXY=http://proxy.example.com"},
+			{Key: "OLD_PROXY", Disabled: true},
+			{Key: "EDITOR", Value: "vim"},
+		}},
+		"Values are quoted": {entries: []entry.Entry{
+			{Key: "GREETING", Value: `say "hello" with \ and ` + "`cmd`" + ` to $USER`},
+			{Key: "WHOAMI", Value: `$(id) costs $5 in ${HOME}`},
+		}},
+		"Comments, empty and invalid lines are skipped": {entries: []entry.Entry{
+			{Key: "environment/system-variables", Value: "# licence server\n\nLICENSE_SERVER=27000@licence.example.com\nnot a variable\n1INVALID=value\nINVALID NAME=value"},
+			{Key: "my-var", Value: "value"},
+		}},
+		"Disabled settings are skipped": {entries: []entry.Entry{
+			{Key: "environment/system-variables", Value: "LICENSE_SERVER=27000@licence.example.com", Disabled: true},
+			{Key: "EDITOR", Value: "vim"},
+		}},
+		"Settings of users are ignored for the computer": {entries: []entry.Entry{
+			{Key: "environment/user-variables", Value: "EDITOR=vim"},
+			{Key: "environment/unsupported", Value: "EDITOR=vim"},
+		}},
+		"No entries removes files":             {existingFiles: true},
+		"Existing files are replaced":          {existingFiles: true, entries: []entry.Entry{{Key: "EDITOR", Value: "vim"}}},
+		"No entries and no files does nothing": {},
+
+		// User cases
+		"User variables": {notComputer: true, entries: []entry.Entry{
+			{Key: "environment/user-variables", Value: "EDITOR=vim\nPATH+=/home/ubuntu/bin"},
+			{Key: "environment/system-variables", Value: "LICENSE_SERVER=27000@licence.example.com"},
+			{Key: "JAVA_HOME", Value: "/usr/lib/jvm/default-java"},
+		}},
+		"User variables replace existing file": {notComputer: true, existingFiles: true, entries: []entry.Entry{
+			{Key: "environment/user-variables", Value: "EDITOR=nano"},
+		}},
+		"No entries removes user file":                   {notComputer: true, existingFiles: true},
+		"Missing home directory is skipped":              {notComputer: true, noHome: true, entries: []entry.Entry{{Key: "EDITOR", Value: "vim"}}},
+		"No entries and missing config dir does nothing": {notComputer: true},
+
+		// Error cases
+		"Error on user lookup failure": {notComputer: true, userLookupErr: true, wantErr: true, entries: []entry.Entry{{Key: "EDITOR", Value: "vim"}}},
+		"Error on symlinked config directory": {notComputer: true, symlinkDir: ".config", wantErr: true, entries: []entry.Entry{
+			{Key: "EDITOR", Value: "vim"},
+		}},
+		"Error on symlinked environment.d directory": {notComputer: true, symlinkDir: ".config/environment.d", wantErr: true, entries: []entry.Entry{
+			{Key: "EDITOR", Value: "vim"},
+		}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rootDir := filepath.Join(t.TempDir(), "root")
+			if tc.existingFiles {
+				testutils.Copy(t, filepath.Join("testdata", "existing-files"), rootDir)
+			}
+			home := filepath.Join(rootDir, "home", "ubuntu")
+			if !tc.noHome {
+				require.NoError(t, os.MkdirAll(home, 0750), "Setup: can't create home directory")
+			}
+			target := filepath.Join(rootDir, "elsewhere")
+			if tc.symlinkDir != "" {
+				require.NoError(t, os.MkdirAll(filepath.Join(target, "environment.d"), 0750), "Setup: can't create symlink target")
+				require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(home, tc.symlinkDir)), 0750), "Setup: can't create symlink parent")
+				require.NoError(t, os.Symlink(target, filepath.Join(home, tc.symlinkDir)), "Setup: can't create symlink")
+			}
+
+			m := environment.New(filepath.Join(rootDir, "etc", "environment.d"), filepath.Join(rootDir, "etc", "profile.d"),
+				environment.WithUserLookup(mockUserLookup(t, home, tc.userLookupErr)))
+			err := m.ApplyPolicy(context.Background(), "ubuntu", !tc.notComputer, tc.entries)
+			if tc.wantErr {
+				require.Error(t, err, "ApplyPolicy should have failed but didn't")
+				require.NoFileExists(t, filepath.Join(target, "environment.d", "91-adsys-user.conf"), "ApplyPolicy should not write through symlinks")
+				require.NoFileExists(t, filepath.Join(target, "91-adsys-user.conf"), "ApplyPolicy should not write through symlinks")
+				return
+			}
+			require.NoError(t, err, "ApplyPolicy failed but shouldn't have")
+
+			testutils.CompareTreesWithFiltering(t, rootDir, testutils.GoldenPath(t), testutils.UpdateEnabled())
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		notComputer   bool
+		entries       []entry.Entry
+		existingFiles bool
+		noHome        bool
+
+		wantTargets []string
+	}{
+		"Machine files are planned": {
+			entries:     []entry.Entry{{Key: "EDITOR", Value: "vim"}},
+			wantTargets: []string{"etc/environment.d/90-adsys.conf", "etc/profile.d/adsys-environment.sh"},
+		},
+		"Removal of machine files": {
+			existingFiles: true,
+			wantTargets:   []string{"etc/environment.d/90-adsys.conf", "etc/profile.d/adsys-environment.sh"},
+		},
+		"User file is planned": {
+			notComputer: true,
+			entries:     []entry.Entry{{Key: "EDITOR", Value: "vim"}},
+			wantTargets: []string{"home/ubuntu/.config/environment.d/91-adsys-user.conf"},
+		},
+		"Missing home directory is not planned": {
+			notComputer: true,
+			noHome:      true,
+			entries:     []entry.Entry{{Key: "EDITOR", Value: "vim"}},
+		},
+		"Up to date user file": {
+			notComputer:   true,
+			existingFiles: true,
+			entries:       []entry.Entry{{Key: "EDITOR", Value: "vim"}},
+		},
+		"Nothing to do": {},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rootDir := filepath.Join(t.TempDir(), "root")
+			if tc.existingFiles {
+				testutils.Copy(t, filepath.Join("testdata", "existing-files"), rootDir)
+			}
+			home := filepath.Join(rootDir, "home", "ubuntu")
+			if !tc.noHome {
+				require.NoError(t, os.MkdirAll(home, 0750), "Setup: can't create home directory")
+			}
+
+			m := environment.New(filepath.Join(rootDir, "etc", "environment.d"), filepath.Join(rootDir, "etc", "profile.d"),
+				environment.WithUserLookup(mockUserLookup(t, home, false)))
+			changes, err := m.Plan(context.Background(), "ubuntu", !tc.notComputer, tc.entries)
+			require.NoError(t, err, "Plan failed but shouldn't have")
+
+			var got []string
+			for _, c := range changes {
+				target, err := filepath.Rel(rootDir, c.Target)
+				require.NoError(t, err, "Setup: can't get relative target")
+				got = append(got, target)
+			}
+			require.Equal(t, tc.wantTargets, got, "Plan returns the expected changes")
+
+			// Planning should not touch the system.
+			if !tc.existingFiles {
+				require.NoDirExists(t, filepath.Join(rootDir, "etc"), "Plan should not write any file")
+				require.NoDirExists(t, filepath.Join(home, ".config"), "Plan should not write any file")
+			}
+		})
+	}
+}
+
+func TestPrepareRestoresFilesOnAbort(t *testing.T) {
+	t.Parallel()
+
+	for _, isComputer := range []bool{true, false} {
+		rootDir := filepath.Join(t.TempDir(), "root")
+		testutils.Copy(t, filepath.Join("testdata", "existing-files"), rootDir)
+		home := filepath.Join(rootDir, "home", "ubuntu")
+
+		m := environment.New(filepath.Join(rootDir, "etc", "environment.d"), filepath.Join(rootDir, "etc", "profile.d"),
+			environment.WithUserLookup(mockUserLookup(t, home, false)))
+
+		tx, err := transaction.New(t.TempDir(), "test-")
+		require.NoError(t, err, "Setup: can't create transaction")
+		require.NoError(t, m.Prepare(context.Background(), "ubuntu", isComputer, tx), "Prepare failed but shouldn't have")
+
+		entries := []entry.Entry{{Key: "PATH", Value: "/opt/tools/bin", Strategy: entry.StrategyAppend}}
+		require.NoError(t, m.ApplyPolicy(context.Background(), "ubuntu", isComputer, entries), "Setup: ApplyPolicy failed")
+		require.NoError(t, tx.Abort(context.Background()), "Abort failed but shouldn't have")
+
+		testutils.CompareTreesWithFiltering(t, rootDir, filepath.Join("testdata", "existing-files"), false)
+	}
+}
+
+// mockUserLookup returns a user lookup function returning the current user with home as home directory.
+func mockUserLookup(t *testing.T, home string, fail bool) func(string) (*user.User, error) {
+	t.Helper()
+
+	u, err := user.Current()
+	require.NoError(t, err, "Setup: can't get current user")
+
+	return func(name string) (*user.User, error) {
+		if fail {
+			return nil, errors.New("user lookup failed")
+		}
+		return &user.User{Username: name, Uid: u.Uid, Gid: u.Gid, HomeDir: home}, nil
+	}
+}
@@ -0,0 +1,12 @@
+package environment
+
+import (
+	"os/user"
+)
+
+// WithUserLookup defines a custom userLookup function for tests.
+func WithUserLookup(f func(string) (*user.User, error)) Option {
+	return func(o *options) {
+		o.userLookup = f
+	}
+}
@@ -0,0 +1,7 @@
+# This file is managed by adsys.
+# Do not edit this file manually.
+# Any changes will be overwritten.
+
+export NO_PROXY="${NO_PROXY:+${NO_PROXY},}.example.com"
+export PATH="${PATH:+${PATH}:}/opt/tools/bin:/opt/other/bin"
+export no_proxy="localhost,.example.com"
@@ -0,0 +1,5 @@
+# This file is managed by adsys.
+# Do not edit this file manually.
+# Any changes will be overwritten.
+
+export LICENSE_SERVER="27000@licence.example.com"
@@ -0,0 +1,5 @@
+# This file is managed by adsys.
+# Do not edit this file manually.
+# Any changes will be overwritten.
+
+export PATH="${PATH:+${PATH}:}/opt/tools/bin:/opt/gpo1/bin:/opt/gpo2/bin"
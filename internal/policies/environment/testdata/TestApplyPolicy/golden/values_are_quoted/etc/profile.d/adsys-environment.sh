@@ -0,0 +1,6 @@
+# This file is managed by adsys.
+# Do not edit this file manually.
+# Any changes will be overwritten.
+
+export GREETING="say \"hello\" with \\ and \`cmd\` to $USER"
+export WHOAMI="\$(id) costs \$5 in ${HOME}"
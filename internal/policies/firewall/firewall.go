@@ -0,0 +1,455 @@
+// Package firewall is the policy manager for the firewall of the machine.
+//
+// The policy is rendered as an nftables ruleset in adsys.nft, under /etc/nftables.d by default. The ruleset only
+// contains the "inet adsys" table, which is replaced as a whole each time the policy is applied:
+//   - the default inbound and outbound policies are the ones of the input and output chains. Both accept if not set,
+//     so that only an explicit default inbound policy drops incoming connections;
+//   - established connections, the loopback interface and ICMP are always allowed;
+//   - denied sources are dropped before allowed sources and ports are accepted. Sources are IPv4 or IPv6
+//     addresses or networks in CIDR notation, and ports are port numbers, port ranges or service names, optionally
+//     followed by /tcp or /udp.
+//
+// The ruleset is validated with "nft -c" before replacing the previous one, and loaded with "nft -f". Invalid values
+// are skipped with a warning. Other tables, like the ones of ufw, are left untouched.
+//
+// When no firewall setting is enabled, the table is deleted and the ruleset file is removed.
+// The firewall policy is only applied on computers.
+package firewall
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/leonelquinteros/gotext"
+	"github.com/ubuntu/adsys/internal/consts"
+	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
+	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/adsys/internal/policies/plan"
+	"github.com/ubuntu/adsys/internal/policies/transaction"
+	"github.com/ubuntu/adsys/internal/smbsafe"
+	"github.com/ubuntu/decorate"
+)
+
+const (
+	adsysRulesetName = "adsys.nft"
+
+	header = `# This file is managed by adsys.
+# Do not edit this file manually.
+# Any changes will be overwritten.
+
+`
+
+	// deleteTable deletes the adsys table, without failing if it doesn't exist.
+	deleteTable = `table inet adsys
+delete table inet adsys
+`
+)
+
+// serviceRe matches the service names nftables resolves from /etc/services.
+var serviceRe = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// Manager prevents running multiple nft processes in parallel while applying the firewall policy.
+type Manager struct {
+	nftablesDir string
+	nftCmd      []string
+
+	mu sync.Mutex
+}
+
+type options struct {
+	nftCmd []string
+}
+
+// Option reprents an optional function to change the firewall manager.
+type Option func(*options)
+
+// WithNftCmd overrides the default nft command.
+func WithNftCmd(cmd []string) Option {
+	return func(o *options) {
+		o.nftCmd = cmd
+	}
+}
+
+// New creates a manager writing the firewall ruleset in nftablesDir.
+func New(nftablesDir string, opts ...Option) *Manager {
+	// defaults
+	args := options{
+		nftCmd: []string{"nft"},
+	}
+	// applied options
+	for _, o := range opts {
+		o(&args)
+	}
+
+	if nftablesDir == "" {
+		nftablesDir = consts.DefaultNftablesDir
+	}
+
+	return &Manager{
+		nftablesDir: nftablesDir,
+		nftCmd:      args.nftCmd,
+	}
+}
+
+// ApplyPolicy renders, validates and loads the firewall ruleset based on a list of entries.
+// Steps are:
+// 1.  Write the ruleset to adsys.nft.new
+// 2.  Validate it with nft -c, leaving the previous ruleset in place on failure
+// 3.  Move adsys.nft.new to adsys.nft
+// 4.  Load it with nft -f.
+func (m *Manager) ApplyPolicy(ctx context.Context, objectName string, isComputer bool, entries []entry.Entry) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't apply firewall policy to %s", objectName))
+
+	// Firewall policies are only set on computers.
+	if !isComputer {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rulesetPath := filepath.Join(m.nftablesDir, adsysRulesetName)
+	content := rulesetContent(ctx, entries)
+
+	// No point in continuing if nft isn't available
+	nft, err := exec.LookPath(m.nftCmd[0])
+	if err != nil {
+		// If we do have rules to apply we should explicitly fail
+		if content != "" {
+			return err
+		}
+		// Otherwise, just let the user know, nothing can be loaded
+		log.Warning(ctx, gotext.Get("nftables is not available on this system: %v", err))
+		return removeRuleset(rulesetPath)
+	}
+
+	if content == "" {
+		if _, err := os.Stat(rulesetPath); errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		log.Debugf(ctx, "No firewall rules to apply to %s, removing previous ones", objectName)
+		if err := m.unload(ctx, nft); err != nil {
+			return err
+		}
+		return removeRuleset(rulesetPath)
+	}
+
+	log.Debugf(ctx, "Applying firewall policy to %s", objectName)
+
+	// nolint:gosec // G301 match distribution permission
+	if err := os.MkdirAll(m.nftablesDir, 0755); err != nil {
+		return err
+	}
+	newRulesetPath := rulesetPath + ".new"
+	// nolint:gosec // G306 match distribution permission
+	if err := os.WriteFile(newRulesetPath, []byte(content), 0644); err != nil {
+		return err
+	}
+	defer func() {
+		if err := os.Remove(newRulesetPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Warningf(ctx, "Can't remove %q: %v", newRulesetPath, err)
+		}
+	}()
+
+	if os.Getenv("ADSYS_SKIP_ROOT_CALLS") == "" {
+		if err := m.runNft(ctx, nft, "", "-c", "-f", newRulesetPath); err != nil {
+			return errors.New(gotext.Get("invalid firewall ruleset: %v", err))
+		}
+	}
+	if err := os.Rename(newRulesetPath, rulesetPath); err != nil {
+		return err
+	}
+
+	if os.Getenv("ADSYS_SKIP_ROOT_CALLS") != "" {
+		return nil
+	}
+	// Always load the ruleset, as it is lost on reboot.
+	if err := m.runNft(ctx, nft, "", "-f", rulesetPath); err != nil {
+		return errors.New(gotext.Get("failed to load firewall ruleset: %v", err))
+	}
+	return nil
+}
+
+// Plan returns the changes ApplyPolicy would make for a firewall policy, without applying them.
+func (m *Manager) Plan(ctx context.Context, objectName string, isComputer bool, entries []entry.Entry) (changes []plan.Change, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't plan firewall policy for %s", objectName))
+
+	// Firewall policies are only set on computers.
+	if !isComputer {
+		return nil, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rulesetPath := filepath.Join(m.nftablesDir, adsysRulesetName)
+	content := rulesetContent(ctx, entries)
+
+	nft, err := exec.LookPath(m.nftCmd[0])
+	if err != nil {
+		if content != "" {
+			return nil, err
+		}
+		log.Warning(ctx, gotext.Get("nftables is not available on this system: %v", err))
+		if c, ok := plan.Removal(rulesetPath); ok {
+			changes = append(changes, c)
+		}
+		return changes, nil
+	}
+	nftArgs := m.nftCmd[1:]
+
+	if content == "" {
+		c, ok := plan.Removal(rulesetPath)
+		if !ok {
+			return nil, nil
+		}
+		return []plan.Change{plan.Call(nft, append(slices.Clone(nftArgs), "-f", "-")...), c}, nil
+	}
+
+	if c, ok := plan.File(rulesetPath, content); ok {
+		changes = append(changes, c)
+	}
+	return append(changes, plan.Call(nft, append(slices.Clone(nftArgs), "-f", rulesetPath)...)), nil
+}
+
+// Prepare backs up the firewall ruleset into tx, so that it can be restored if applying the policies fails.
+// Once restored, the previous ruleset is loaded again, or the adsys table is deleted if there was none.
+func (m *Manager) Prepare(ctx context.Context, objectName string, isComputer bool, tx *transaction.Transaction) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't prepare firewall policy for %s", objectName))
+
+	// Firewall policies are only set on computers.
+	if !isComputer {
+		return nil
+	}
+
+	log.Debugf(ctx, "Preparing firewall policy for %s", objectName)
+
+	rulesetPath := filepath.Join(m.nftablesDir, adsysRulesetName)
+	tx.OnAbort(func(ctx context.Context) error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		if os.Getenv("ADSYS_SKIP_ROOT_CALLS") != "" {
+			return nil
+		}
+		// Nothing could have been loaded if nft isn't available
+		nft, err := exec.LookPath(m.nftCmd[0])
+		if err != nil {
+			return nil
+		}
+		if _, err := os.Stat(rulesetPath); errors.Is(err, fs.ErrNotExist) {
+			return m.unload(ctx, nft)
+		}
+		return m.runNft(ctx, nft, "", "-f", rulesetPath)
+	})
+
+	return tx.Backup(rulesetPath)
+}
+
+// unload deletes the adsys table.
+func (m *Manager) unload(ctx context.Context, nft string) error {
+	if os.Getenv("ADSYS_SKIP_ROOT_CALLS") != "" {
+		return nil
+	}
+	if err := m.runNft(ctx, nft, deleteTable, "-f", "-"); err != nil {
+		return errors.New(gotext.Get("failed to unload firewall ruleset: %v", err))
+	}
+	return nil
+}
+
+// runNft runs nft with args, and stdin as its standard input if not empty.
+func (m *Manager) runNft(ctx context.Context, nft, stdin string, args ...string) error {
+	args = append(slices.Clone(m.nftCmd[1:]), args...)
+	// #nosec G204 - We are in control of the arguments
+	cmd := exec.CommandContext(ctx, nft, args...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	smbsafe.WaitExec()
+	out, err := cmd.CombinedOutput()
+	smbsafe.DoneExec()
+	if err != nil {
+		return fmt.Errorf("%w\n%s", err, string(out))
+	}
+	return nil
+}
+
+// removeRuleset removes the ruleset file at p, if any.
+func removeRuleset(p string) error {
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// rulesetContent returns the nftables ruleset for entries.
+// An empty content means that no firewall setting is enabled.
+func rulesetContent(ctx context.Context, entries []entry.Entry) string {
+	// Incoming connections are only dropped by default when the administrator explicitly requests it.
+	inbound, outbound := "accept", "accept"
+	var ports = make(map[string][]string)
+	var allowed, denied = make(map[string][]string), make(map[string][]string)
+	var enabled bool
+	for _, e := range entries {
+		if e.Disabled {
+			continue
+		}
+		enabled = true
+
+		key := e.Key[strings.LastIndex(e.Key, "/")+1:]
+		switch key {
+		case "default-inbound", "default-outbound":
+			v := strings.ToLower(strings.TrimSpace(e.Value))
+			if v != "drop" && v != "accept" {
+				log.Warningf(ctx, "Ignoring firewall setting %s with invalid value %q: expected drop or accept", e.Key, e.Value)
+				continue
+			}
+			if key == "default-inbound" {
+				inbound = v
+			} else {
+				outbound = v
+			}
+		case "allowed-ports":
+			for _, p := range splitValues(e.Value) {
+				port, proto, found := strings.Cut(strings.ToLower(p), "/")
+				protos := []string{"tcp", "udp"}
+				if found {
+					protos = []string{proto}
+				}
+				if !validPort(port) || (found && proto != "tcp" && proto != "udp") {
+					log.Warningf(ctx, "Ignoring invalid firewall port %q: expected <port>[/tcp|/udp]", p)
+					continue
+				}
+				for _, proto := range protos {
+					if !slices.Contains(ports[proto], port) {
+						ports[proto] = append(ports[proto], port)
+					}
+				}
+			}
+		case "allowed-sources", "denied-sources":
+			sources := allowed
+			if key == "denied-sources" {
+				sources = denied
+			}
+			for _, s := range splitValues(e.Value) {
+				source, family, ok := parseSource(s)
+				if !ok {
+					log.Warningf(ctx, "Ignoring invalid firewall source %q: expected an IP address or network", s)
+					continue
+				}
+				if !slices.Contains(sources[family], source) {
+					sources[family] = append(sources[family], source)
+				}
+			}
+		default:
+			log.Warning(ctx, gotext.Get("Encountered unsupported key '%s' while parsing firewall entries, skipping it", e.Key))
+		}
+	}
+
+	if !enabled {
+		return ""
+	}
+
+	var input []string
+	for _, family := range []string{"ip", "ip6"} {
+		if len(denied[family]) > 0 {
+			input = append(input, fmt.Sprintf("%s saddr { %s } drop", family, strings.Join(denied[family], ", ")))
+		}
+	}
+	for _, family := range []string{"ip", "ip6"} {
+		if len(allowed[family]) > 0 {
+			input = append(input, fmt.Sprintf("%s saddr { %s } accept", family, strings.Join(allowed[family], ", ")))
+		}
+	}
+	for _, proto := range []string{"tcp", "udp"} {
+		if len(ports[proto]) > 0 {
+			input = append(input, fmt.Sprintf("%s dport { %s } accept", proto, strings.Join(ports[proto], ", ")))
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(header)
+	b.WriteString(deleteTable)
+	fmt.Fprintf(&b, `
+table inet adsys {
+	chain input {
+		type filter hook input priority filter; policy %s;
+		ct state established,related accept
+		ct state invalid drop
+		iifname "lo" accept
+		meta l4proto { icmp, ipv6-icmp } accept
+`, inbound)
+	for _, r := range input {
+		fmt.Fprintf(&b, "\t\t%s\n", r)
+	}
+	fmt.Fprintf(&b, `	}
+
+	chain output {
+		type filter hook output priority filter; policy %s;
+		ct state established,related accept
+		oifname "lo" accept
+		meta l4proto { icmp, ipv6-icmp } accept
+	}
+}
+`, outbound)
+
+	return b.String()
+}
+
+// splitValues returns the non empty values of a multi lines value.
+func splitValues(value string) (values []string) {
+	for _, v := range strings.Split(value, "\n") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+// validPort returns true if port is a port number, a range of port numbers or a service name.
+func validPort(port string) bool {
+	if serviceRe.MatchString(port) {
+		return true
+	}
+
+	low, high, isRange := strings.Cut(port, "-")
+	if !isRange {
+		high = low
+	}
+	l, errLow := strconv.ParseUint(low, 10, 16)
+	h, errHigh := strconv.ParseUint(high, 10, 16)
+	return errLow == nil && errHigh == nil && l > 0 && l <= h
+}
+
+// parseSource returns the normalized address or network in CIDR notation s, with its nftables address family,
+// ip or ip6. ok is false if s is not a valid address or network.
+func parseSource(s string) (source, family string, ok bool) {
+	if ip := net.ParseIP(s); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			return ip4.String(), "ip", true
+		}
+		return ip.String(), "ip6", true
+	}
+
+	_, network, err := net.ParseCIDR(s)
+	if err != nil {
+		return "", "", false
+	}
+	// Host bits are cleared, as nftables refuses them in prefixes.
+	if network.IP.To4() != nil {
+		return network.String(), "ip", true
+	}
+	return network.String(), "ip6", true
+}
@@ -0,0 +1,253 @@
+package firewall_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/termie/go-shutil"
+	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/adsys/internal/policies/firewall"
+	"github.com/ubuntu/adsys/internal/testutils"
+)
+
+func TestApplyPolicy(t *testing.T) {
+	t.Parallel()
+
+	defaultPolicy := []entry.Entry{
+		{Key: "firewall/default-inbound", Value: "drop"},
+		{Key: "firewall/default-outbound", Value: "accept"},
+		{Key: "firewall/allowed-ports", Value: "ssh\n443/tcp\n53/udp\n8000-8100"},
+		{Key: "firewall/allowed-sources", Value: "192.168.1.0/24\nfd00::/8"},
+		{Key: "firewall/denied-sources", Value: "192.168.1.66\n2001:db8::1"},
+	}
+
+	tests := map[string]struct {
+		notComputer     bool
+		entries         []entry.Entry
+		existingRuleset bool
+		noNft           bool
+		nftError        string
+		destIsDir       bool
+
+		noNftOutput bool
+		wantErr     bool
+	}{
+		"Full policy":           {entries: defaultPolicy},
+		"Default policies only": {entries: []entry.Entry{{Key: "firewall/default-inbound", Value: "accept"}, {Key: "firewall/default-outbound", Value: "drop"}}},
+		"Allowed ports only keep accepting inbound":  {entries: []entry.Entry{{Key: "firewall/allowed-ports", Value: "22"}}},
+		"Denied sources only keep accepting inbound": {entries: []entry.Entry{{Key: "firewall/denied-sources", Value: "192.168.1.66"}}},
+		"Values are trimmed and case insensitive": {entries: []entry.Entry{
+			{Key: "firewall/default-inbound", Value: " ACCEPT "},
+			{Key: "firewall/allowed-ports", Value: " 22/TCP \n\n HTTP "},
+		}},
+		"Duplicated values are listed once": {entries: []entry.Entry{
+			{Key: "firewall/allowed-ports", Value: "22/tcp\n22\n22/udp"},
+			{Key: "firewall/allowed-sources", Value: "10.0.0.0/8\n10.0.0.0/8\n10.1.2.3/8"},
+		}},
+		"Invalid values are skipped": {entries: []entry.Entry{
+			{Key: "firewall/default-inbound", Value: "reject"},
+			{Key: "firewall/allowed-ports", Value: "0\n65536\n100-10\n22/sctp\nnot a service\n; flush ruleset\n80"},
+			{Key: "firewall/allowed-sources", Value: "10.0.0.0/33\nexample.com\n10.0.0.1"},
+		}},
+		"Unsupported keys are ignored": {entries: []entry.Entry{{Key: "firewall/logging", Value: "on"}}},
+		"Existing ruleset is replaced": {entries: defaultPolicy[:3], existingRuleset: true},
+
+		// Removal cases
+		"No rules and no existing ruleset":   {noNftOutput: true},
+		"No rules, existing ruleset removed": {existingRuleset: true},
+		"Disabled rules, existing ruleset removed": {existingRuleset: true, entries: []entry.Entry{
+			{Key: "firewall/default-inbound", Disabled: true},
+			{Key: "firewall/allowed-ports", Disabled: true},
+		}},
+		"No nft and no rules, existing ruleset removed": {existingRuleset: true, noNft: true, noNftOutput: true},
+		"Not a computer": {notComputer: true, entries: defaultPolicy, noNftOutput: true},
+
+		// Error cases
+		"Error on no nft and rules":              {entries: defaultPolicy, noNft: true, noNftOutput: true, wantErr: true},
+		"Error on invalid ruleset, keep old one": {entries: defaultPolicy, existingRuleset: true, nftError: "check", wantErr: true},
+		"Error on loading ruleset":               {entries: defaultPolicy, nftError: "load", wantErr: true},
+		"Error on unloading ruleset":             {existingRuleset: true, nftError: "unload", wantErr: true},
+		"Error on ruleset being a directory":     {entries: defaultPolicy, destIsDir: true, wantErr: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			tempEtc := t.TempDir()
+			nftablesDir := filepath.Join(tempEtc, "nftables.d")
+			if tc.existingRuleset {
+				require.NoError(t,
+					shutil.CopyTree(
+						filepath.Join("testdata", "existing-ruleset", "nftables.d"), nftablesDir,
+						&shutil.CopyTreeOptions{Symlinks: true, CopyFunction: shutil.Copy}),
+					"Setup: can't create initial nftables directory")
+			}
+			if tc.destIsDir {
+				require.NoError(t, os.MkdirAll(filepath.Join(nftablesDir, "adsys.nft", "subdir"), 0750), "Setup: can't create directory instead of ruleset")
+			}
+
+			nftOutputFile := filepath.Join(t.TempDir(), "nft-output")
+			nftCmd := mockNftCmd(t, nftOutputFile)
+			if tc.noNft {
+				nftCmd = []string{"this-definitely-does-not-exist"}
+			}
+			if tc.nftError != "" {
+				// Let the mock know we want an error on a specific call
+				nftCmd = append(nftCmd, "-Exit1-"+tc.nftError)
+			}
+
+			m := firewall.New(nftablesDir, firewall.WithNftCmd(nftCmd))
+			err := m.ApplyPolicy(context.Background(), "ubuntu", !tc.notComputer, tc.entries)
+			if tc.wantErr {
+				// We don't return here as we want to check that the nftables
+				// dir is in the expected state even in error cases
+				require.Error(t, err, "ApplyPolicy should have failed but didn't")
+			} else {
+				require.NoError(t, err, "ApplyPolicy failed but shouldn't have")
+			}
+
+			testutils.CompareTreesWithFiltering(t, tempEtc, filepath.Join(testutils.GoldenPath(t), "etc"), testutils.UpdateEnabled())
+
+			// Check that nft was called with the expected arguments
+			got, err := os.ReadFile(nftOutputFile)
+			if tc.noNftOutput {
+				require.Error(t, err, "Setup: No nft output requested but we got some")
+				return
+			}
+			require.NoError(t, err, "Setup: Can't read nft output file")
+			gotOutput := strings.ReplaceAll(string(got), tempEtc, "#TMPDIR#")
+
+			want := testutils.LoadWithUpdateFromGolden(t, gotOutput, testutils.WithGoldenPath(filepath.Join(testutils.GoldenPath(t), "nft_output")))
+			require.Equal(t, want, gotOutput, "nft command output doesn't match")
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		notComputer     bool
+		entries         []entry.Entry
+		existingRuleset bool
+
+		wantChanges []string
+	}{
+		"New ruleset is written and loaded": {
+			entries:     []entry.Entry{{Key: "firewall/allowed-ports", Value: "22"}},
+			wantChanges: []string{"create adsys.nft", "run -f adsys.nft"},
+		},
+		"Up to date ruleset is only loaded": {
+			entries:         []entry.Entry{{Key: "firewall/default-inbound", Value: "drop"}, {Key: "firewall/allowed-ports", Value: "22/tcp"}},
+			existingRuleset: true,
+			wantChanges:     []string{"run -f adsys.nft"},
+		},
+		"Removals are planned": {
+			existingRuleset: true,
+			wantChanges:     []string{"run -f -", "remove adsys.nft"},
+		},
+		"Nothing to remove": {},
+		"Not a computer":    {notComputer: true, entries: []entry.Entry{{Key: "firewall/allowed-ports", Value: "22"}}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			nftablesDir := filepath.Join(t.TempDir(), "nftables.d")
+			if tc.existingRuleset {
+				require.NoError(t,
+					shutil.CopyTree(
+						filepath.Join("testdata", "existing-ruleset", "nftables.d"), nftablesDir,
+						&shutil.CopyTreeOptions{Symlinks: true, CopyFunction: shutil.Copy}),
+					"Setup: can't create initial nftables directory")
+			}
+			nftOutputFile := filepath.Join(t.TempDir(), "nft-output")
+
+			m := firewall.New(nftablesDir, firewall.WithNftCmd(mockNftCmd(t, nftOutputFile)))
+			changes, err := m.Plan(context.Background(), "ubuntu", !tc.notComputer, tc.entries)
+			require.NoError(t, err, "Plan failed but shouldn't have")
+
+			var got []string
+			for _, c := range changes {
+				target := filepath.Base(c.Target)
+				if c.Action == "run" {
+					target = strings.ReplaceAll(c.Content, nftablesDir+"/", "")
+					target = target[strings.Index(target, "-f"):]
+				}
+				got = append(got, fmt.Sprintf("%s %s", c.Action, target))
+			}
+			require.Equal(t, tc.wantChanges, got, "Plan returns the expected changes")
+
+			// Planning should not touch the system.
+			require.NoFileExists(t, nftOutputFile, "Plan should not call nft")
+		})
+	}
+}
+
+func mockNftCmd(t *testing.T, outputFile string) []string {
+	t.Helper()
+
+	return []string{"env", "GO_WANT_HELPER_PROCESS=1", os.Args[0], "-test.run=TestMockNft", "--", outputFile}
+}
+
+func TestMockNft(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	defer os.Exit(0)
+
+	var outputFile, wantExit string
+	args := os.Args
+	for len(args) > 0 {
+		if args[0] != "--" {
+			args = args[1:]
+			continue
+		}
+		// First arg after -- is the output file to write to
+		outputFile = args[1]
+		args = args[2:]
+		// Args are shifted by 1 if exit was requested
+		if strings.HasPrefix(args[0], "-Exit1-") {
+			wantExit = strings.TrimPrefix(args[0], "-Exit1-")
+			args = args[1:]
+		}
+		break
+	}
+
+	call := "load"
+	switch {
+	case args[0] == "-c":
+		call = "check"
+	case args[len(args)-1] == "-":
+		call = "unload"
+	}
+
+	out := fmt.Sprintf("%s: %s\n", call, strings.Join(args, " "))
+	// Ruleset read from stdin
+	if call == "unload" {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			out += fmt.Sprintf("  %s\n", scanner.Text())
+		}
+		require.NoError(t, scanner.Err(), "Setup: Can't read from stdin")
+	}
+
+	f, err := os.OpenFile(outputFile, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	require.NoError(t, err, "Setup: Can't open nft output file")
+	_, err = f.WriteString(out)
+	require.NoError(t, err, "Setup: Can't write to nft output file")
+	require.NoError(t, f.Close(), "Setup: Can't close nft output file")
+
+	if wantExit == call {
+		fmt.Println("EXIT 1 requested in mock")
+		os.Exit(1)
+	}
+}
@@ -13,6 +13,8 @@ import (
 	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
 	"github.com/ubuntu/adsys/internal/policies/dconf"
 	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/adsys/internal/policies/plan"
+	"github.com/ubuntu/adsys/internal/policies/transaction"
 	"github.com/ubuntu/decorate"
 	"golang.org/x/sync/errgroup"
 )
@@ -62,15 +64,10 @@ func (m *Manager) ApplyPolicy(ctx context.Context, entries []entry.Entry) (err e
 	log.Debug(ctx, "ApplyPolicy gdm policy")
 
 	// Order all entries by keytype for gdm
-	sortedEntries := make(map[string][]entry.Entry)
-	for _, e := range entries {
-		keyType := strings.Split(e.Key, "/")[0]
-		e.Key = strings.TrimPrefix(e.Key, keyType+"/")
-		sortedEntries[keyType] = append(sortedEntries[keyType], e)
-	}
+	sorted := sortedEntries(entries)
 
 	var g errgroup.Group
-	g.Go(func() error { return m.dconf.ApplyPolicy(ctx, "gdm", false, sortedEntries["dconf"]) })
+	g.Go(func() error { return m.dconf.ApplyPolicy(ctx, "gdm", false, sorted["dconf"]) })
 
 	if err := g.Wait(); err != nil {
 		return err
@@ -78,3 +75,30 @@ func (m *Manager) ApplyPolicy(ctx context.Context, entries []entry.Entry) (err e
 
 	return nil
 }
+
+// Plan returns the changes ApplyPolicy would make for the gdm dconf policy, without applying them.
+func (m *Manager) Plan(ctx context.Context, entries []entry.Entry) (changes []plan.Change, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't plan gdm policy"))
+
+	log.Debug(ctx, "Plan gdm policy")
+
+	return m.dconf.Plan(ctx, "gdm", false, sortedEntries(entries)["dconf"])
+}
+
+// Prepare backs up the gdm dconf policy into tx, so that it can be restored if applying the policies fails.
+func (m *Manager) Prepare(ctx context.Context, tx *transaction.Transaction) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't prepare gdm policy"))
+
+	return m.dconf.Prepare(ctx, "gdm", false, tx)
+}
+
+// sortedEntries orders all entries by keytype for gdm.
+func sortedEntries(entries []entry.Entry) map[string][]entry.Entry {
+	r := make(map[string][]entry.Entry)
+	for _, e := range entries {
+		keyType := strings.Split(e.Key, "/")[0]
+		e.Key = strings.TrimPrefix(e.Key, keyType+"/")
+		r[keyType] = append(r[keyType], e)
+	}
+	return r
+}
@@ -6,7 +6,9 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/leonelquinteros/gotext"
 	"github.com/ubuntu/adsys/internal/policies/entry"
 )
 
@@ -14,8 +16,42 @@ import (
 type GPO struct {
 	ID   string
 	Name string
+	// Link is the container (domain or OU) the GPO is linked to, if known.
+	Link string `yaml:",omitempty"`
+	// Enforced is true if the GPO link is enforced, taking precedence over the links of closer containers.
+	Enforced bool `yaml:",omitempty"`
 	// the string is the domain of rules (dconf, install…)
 	Rules map[string][]entry.Entry
+	// ReleaseOverrides lists, per domain of rules, the keys whose value was replaced by the one specific to
+	// the running release.
+	ReleaseOverrides map[string][]string `yaml:",omitempty"`
+	// Loopback is the user policy loopback processing mode configured by this computer GPO, if any.
+	Loopback LoopbackMode `yaml:",omitempty"`
+}
+
+// LoopbackMode is the user policy loopback processing mode, configured in computer GPOs. When enabled, users get
+// the user configuration of the GPOs linked to the computer containers.
+type LoopbackMode string
+
+const (
+	// LoopbackMerge applies the loopback GPOs in addition to the user ones, with a higher priority.
+	LoopbackMerge LoopbackMode = "merge"
+	// LoopbackReplace only applies the loopback GPOs, ignoring the user ones.
+	LoopbackReplace LoopbackMode = "replace"
+	// LoopbackDisabled is set by GPOs explicitly disabling loopback processing.
+	LoopbackDisabled LoopbackMode = "disabled"
+)
+
+// SkippedGPO is a GPO applying to an object which was not applied, with the reason why.
+type SkippedGPO struct {
+	ID     string
+	Name   string
+	Reason string
+}
+
+// Format write to w a formatted skipped GPO.
+func (g SkippedGPO) Format(w io.Writer) {
+	fmt.Fprintf(w, "* %s (%s): %s\n", g.Name, g.ID, gotext.Get("skipped, %s", g.Reason))
 }
 
 // Format write to w a formatted GPO. overridden entries are prepended with -.
@@ -30,22 +66,14 @@ func (g GPO) Format(w io.Writer, withRules, withOverridden bool, alreadyProcesse
 		alreadyProcessedRules = make(map[string]struct{})
 	}
 
-	var domains []string
-	for domain := range g.Rules {
-		domains = append(domains, domain)
-	}
-	sort.Strings(domains)
-
-	for _, d := range domains {
+	for _, d := range g.ruleTypes() {
 		fmt.Fprintf(w, "** %s:\n", d)
-		for _, r := range g.Rules[d] {
-			k := filepath.Join(d, r.Key)
-			_, overr := alreadyProcessedRules[k]
-			if !withOverridden && overr {
+		for _, r := range g.annotatedRules(d, alreadyProcessedRules) {
+			if !withOverridden && r.Overridden {
 				continue
 			}
 			prefix := "***"
-			if overr {
+			if r.Overridden {
 				prefix += "-"
 			}
 			// Trim EOL \n and replace them all with \n in text to keep each value printed in one single line
@@ -56,14 +84,54 @@ func (g GPO) Format(w io.Writer, withRules, withOverridden bool, alreadyProcesse
 			} else {
 				fmt.Fprintf(w, "%s %s: %s\n", prefix, r.Key, v)
 			}
-
-			// Do not add non overridable key to the alreadyProcessedRules override detection map.
-			if r.Strategy == "append" {
-				continue
-			}
-			alreadyProcessedRules[k] = struct{}{}
 		}
 	}
 
 	return alreadyProcessedRules
 }
+
+// ruleTypes returns the sorted domains of the rules of g.
+func (g GPO) ruleTypes() []string {
+	var domains []string
+	for domain := range g.Rules {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+	return domains
+}
+
+// AppliedGPO is a GPO applied to an object, with its rules flagged when overridden.
+type AppliedGPO struct {
+	ID   string
+	Name string
+	// IsComputer is true if the GPO comes from the machine configuration.
+	IsComputer bool
+	// LastUpdate is the last time the policies containing this GPO were applied.
+	LastUpdate time.Time
+	Rules      []AppliedRule
+}
+
+// AppliedRule is a rule of an applied GPO.
+type AppliedRule struct {
+	Type string
+	entry.Entry
+	// Overridden is true if the rule was already set by a GPO with higher priority.
+	Overridden bool
+}
+
+// annotatedRules returns the rules of domain d, flagging the ones whose keys are in alreadyProcessedRules
+// as overridden. The keys of overridable rules are added to alreadyProcessedRules.
+func (g GPO) annotatedRules(d string, alreadyProcessedRules map[string]struct{}) (rules []AppliedRule) {
+	for _, r := range g.Rules[d] {
+		k := filepath.Join(d, r.Key)
+		_, overr := alreadyProcessedRules[k]
+		rules = append(rules, AppliedRule{Type: d, Entry: r, Overridden: overr})
+
+		// Do not add non overridable key to the alreadyProcessedRules override detection map.
+		if entry.IsListStrategy(r.Strategy) {
+			continue
+		}
+		alreadyProcessedRules[k] = struct{}{}
+	}
+	return rules
+}
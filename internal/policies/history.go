@@ -0,0 +1,211 @@
+package policies
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/leonelquinteros/gotext"
+	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
+	"github.com/ubuntu/decorate"
+)
+
+// HistoryCacheBaseName is the base directory where we keep snapshots of previously applied policies.
+const HistoryCacheBaseName = "history"
+
+// saveSnapshot records the policies currently cached for objectName in its history.
+// No new snapshot is created if the policies did not change since the last one.
+// Only the m.historySize most recent snapshots are kept.
+func (m *Manager) saveSnapshot(ctx context.Context, objectName string) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't save policies snapshot for %q", objectName))
+
+	objectHistoryDir := filepath.Join(m.historyCacheDir, objectName)
+	ids, err := snapshotIDs(objectHistoryDir)
+	if err != nil {
+		return err
+	}
+
+	cacheDir := filepath.Join(m.policiesCacheDir, objectName)
+	id := 1
+	if len(ids) > 0 {
+		last := filepath.Join(objectHistoryDir, strconv.Itoa(ids[len(ids)-1]))
+		same, err := sameFiles(cacheDir, last)
+		if err != nil {
+			return err
+		}
+		if same {
+			log.Debugf(ctx, "Policies for %q did not change since snapshot %d", objectName, ids[len(ids)-1])
+			return nil
+		}
+		id = ids[len(ids)-1] + 1
+	}
+
+	log.Debugf(ctx, "Saving policies snapshot %d for %q", id, objectName)
+
+	// Copy in a temporary directory first so that we never list partial snapshots.
+	dest := filepath.Join(objectHistoryDir, strconv.Itoa(id))
+	if err := os.RemoveAll(dest + ".new"); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dest+".new", 0700); err != nil {
+		return err
+	}
+	for _, f := range []string{policiesFileName, policiesAssetsFileName} {
+		if err := copyFile(filepath.Join(cacheDir, f), filepath.Join(dest+".new", f)); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(dest+".new", dest); err != nil {
+		return err
+	}
+
+	// Rotate old snapshots
+	ids = append(ids, id)
+	for len(ids) > m.historySize {
+		if err := os.RemoveAll(filepath.Join(objectHistoryDir, strconv.Itoa(ids[0]))); err != nil {
+			return err
+		}
+		ids = ids[1:]
+	}
+
+	return nil
+}
+
+// History lists the snapshots of previously applied policies for objectName, the most recent first.
+func (m *Manager) History(ctx context.Context, objectName string) (msg string, err error) {
+	defer decorate.OnError(&err, gotext.Get("failed to list policies history for %q", objectName))
+
+	log.Infof(ctx, "Listing policies history for %s", objectName)
+
+	objectHistoryDir := filepath.Join(m.historyCacheDir, objectName)
+	ids, err := snapshotIDs(objectHistoryDir)
+	if err != nil {
+		return "", err
+	}
+	if len(ids) == 0 {
+		return "", errors.New(gotext.Get("no policy history for %q", objectName))
+	}
+
+	var out strings.Builder
+	fmt.Fprintln(&out, gotext.Get("Policies history for %s:", objectName))
+	for i := len(ids) - 1; i >= 0; i-- {
+		id := ids[i]
+		p := filepath.Join(objectHistoryDir, strconv.Itoa(id))
+		info, err := os.Stat(filepath.Join(p, policiesFileName))
+		if err != nil {
+			return "", err
+		}
+		pols, err := NewFromCache(ctx, p)
+		if err != nil {
+			return "", err
+		}
+		pols.Close()
+
+		current := ""
+		if i == len(ids)-1 {
+			current = gotext.Get(" (current)")
+		}
+		fmt.Fprintf(&out, "* %d%s: %s\n", id, current, info.ModTime().Format("Mon Jan 2 15:04:05 2006"))
+		if len(pols.GPOs) == 0 {
+			fmt.Fprintf(&out, "    - %s\n", gotext.Get("no GPO"))
+		}
+		for _, g := range pols.GPOs {
+			fmt.Fprintf(&out, "    - %s {%s}\n", g.Name, g.ID)
+		}
+	}
+
+	return out.String(), nil
+}
+
+// Rollback applies again the policies from snapshot id of objectName history.
+func (m *Manager) Rollback(ctx context.Context, objectName string, isComputer bool, id int) (err error) {
+	defer decorate.OnError(&err, gotext.Get("failed to rollback policies for %q to snapshot %d", objectName, id))
+
+	log.Info(ctx, gotext.Get("Rolling back policies for %s to snapshot %d", objectName, id))
+
+	p := filepath.Join(m.historyCacheDir, objectName, strconv.Itoa(id))
+	if _, err := os.Stat(p); err != nil {
+		return errors.New(gotext.Get("no snapshot %d in history: %v", id, err))
+	}
+
+	pols, err := NewFromCache(ctx, p)
+	if err != nil {
+		return err
+	}
+	defer pols.Close()
+
+	return m.ApplyPolicies(ctx, objectName, isComputer, &pols)
+}
+
+// snapshotIDs returns the sorted ids of the snapshots available in dir.
+func snapshotIDs(dir string) (ids []int, err error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		// Ignore any partial snapshot
+		id, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	return ids, nil
+}
+
+// sameFiles returns true if the policies and assets in both directories are identical.
+func sameFiles(dirA, dirB string) (bool, error) {
+	for _, f := range []string{policiesFileName, policiesAssetsFileName} {
+		a, errA := os.ReadFile(filepath.Join(dirA, f))
+		if errA != nil && !errors.Is(errA, fs.ErrNotExist) {
+			return false, errA
+		}
+		b, errB := os.ReadFile(filepath.Join(dirB, f))
+		if errB != nil && !errors.Is(errB, fs.ErrNotExist) {
+			return false, errB
+		}
+		if (errA == nil) != (errB == nil) || !bytes.Equal(a, b) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// copyFile copies src to dest. A missing src is not an error, as assets are optional.
+func copyFile(src, dest string) (err error) {
+	in, err := os.Open(src)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,138 @@
+package policies_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/ubuntu/adsys/internal/consts"
+	"github.com/ubuntu/adsys/internal/policies"
+	"github.com/ubuntu/adsys/internal/testutils"
+)
+
+func TestPoliciesHistory(t *testing.T) {
+	//t.Parallel()
+
+	bus := testutils.NewDbusConn(t)
+
+	// Only keep dconf rules, which don't need any asset, by disabling the subscription.
+	subscriptionDbus := bus.Object(consts.SubscriptionDbusRegisteredName,
+		dbus.ObjectPath(consts.SubscriptionDbusObjectPath))
+	require.NoError(t, subscriptionDbus.SetProperty(consts.SubscriptionDbusInterface+".Attached", false), "Setup: can not set subscription status")
+
+	tests := map[string]struct {
+		appliedPolicies []string
+		historySize     int
+		rollbackTo      int
+
+		wantErrHistory  bool
+		wantErrRollback bool
+	}{
+		"List snapshots of applied policies, most recent first": {appliedPolicies: []string{"one_gpo", "two_gpos_no_override"}},
+		"Snapshots include assets":                              {appliedPolicies: []string{"with_assets"}},
+		"Identical policies create a single snapshot":           {appliedPolicies: []string{"one_gpo", "one_gpo"}},
+		"Purging policies creates a snapshot":                   {appliedPolicies: []string{"one_gpo", ""}},
+		"Only keep the most recent snapshots":                   {appliedPolicies: []string{"one_gpo", "two_gpos_no_override", "with_assets"}, historySize: 2},
+
+		// Rollback
+		"Rollback applies again previous snapshot":          {appliedPolicies: []string{"with_assets", "one_gpo"}, rollbackTo: 1},
+		"Rollback to current snapshot does not add new one": {appliedPolicies: []string{"one_gpo", "with_assets"}, rollbackTo: 2},
+
+		// Error cases
+		"Error on history when nothing was applied":   {wantErrHistory: true},
+		"Error on rollback to an unknown snapshot":    {appliedPolicies: []string{"one_gpo"}, rollbackTo: 2, wantErrRollback: true},
+		"Error on rollback to a rotated out snapshot": {appliedPolicies: []string{"one_gpo", "with_assets"}, historySize: 1, rollbackTo: 1, wantErrRollback: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			//t.Parallel()
+
+			fakeRootDir := t.TempDir()
+			cacheDir := filepath.Join(fakeRootDir, "var", "cache", "adsys")
+			loadedPoliciesFile := filepath.Join(fakeRootDir, "sys", "kernel", "security", "apparmor", "profiles")
+			err := os.MkdirAll(filepath.Dir(loadedPoliciesFile), 0700)
+			require.NoError(t, err, "Setup: can not create loadedPoliciesFile dir")
+			err = os.WriteFile(loadedPoliciesFile, []byte("someprofile (enforce)\n"), 0600)
+			require.NoError(t, err, "Setup: can not create loadedPoliciesFile")
+
+			opts := []policies.Option{
+				policies.WithCacheDir(cacheDir),
+				policies.WithStateDir(filepath.Join(fakeRootDir, "var", "lib", "adsys")),
+				policies.WithRunDir(filepath.Join(fakeRootDir, "run", "adsys")),
+				policies.WithShareDir(filepath.Join(fakeRootDir, "usr", "share", "adsys")),
+				policies.WithDconfDir(filepath.Join(fakeRootDir, "etc", "dconf")),
+				policies.WithPolicyKitDir(filepath.Join(fakeRootDir, "etc", "polkit-1")),
+				policies.WithSudoersDir(filepath.Join(fakeRootDir, "etc", "sudoers.d")),
+				policies.WithSecurityDir(filepath.Join(fakeRootDir, "etc", "security")),
+				policies.WithNftablesDir(filepath.Join(fakeRootDir, "etc", "nftables.d")),
+				policies.WithEnvironmentDir(filepath.Join(fakeRootDir, "etc", "environment.d")),
+				policies.WithProfileDir(filepath.Join(fakeRootDir, "etc", "profile.d")),
+				policies.WithApparmorDir(filepath.Join(fakeRootDir, "etc", "apparmor.d", "adsys")),
+				policies.WithApparmorFsDir(filepath.Dir(loadedPoliciesFile)),
+				policies.WithApparmorParserCmd([]string{"/bin/true"}),
+				policies.WithNftCmd([]string{"/bin/true"}),
+				policies.WithPackagesExecutor(testutils.MockPackagesExecutor{}),
+				policies.WithGroupFile(filepath.Join("testdata", "group")),
+				policies.WithGpasswdCmd([]string{"/bin/true"}),
+				policies.WithCertAutoenrollCmd([]string{"/bin/true"}),
+				policies.WithSystemUnitDir(filepath.Join(fakeRootDir, "etc", "systemd", "system")),
+				policies.WithProxyApplier(&mockProxyApplier{}),
+				policies.WithSystemdCaller(&testutils.MockSystemdCaller{}),
+			}
+			if tc.historySize != 0 {
+				opts = append(opts, policies.WithHistorySize(tc.historySize))
+			}
+			m, err := policies.NewManager(bus, "hostname", mockBackend{}, opts...)
+			require.NoError(t, err, "Setup: couldn’t get a new policy manager")
+
+			for _, p := range tc.appliedPolicies {
+				pols, err := policies.New(context.Background(), nil, "")
+				require.NoError(t, err, "Setup: can not create empty policies")
+				if p != "" {
+					pols, err = policies.NewFromCache(context.Background(), filepath.Join("testdata", "cache", "policies", p))
+					require.NoError(t, err, "Setup: can not load policies list")
+				}
+				err = m.ApplyPolicies(context.Background(), "hostname", true, &pols)
+				require.NoError(t, err, "Setup: ApplyPolicies should return no error but got one")
+				pols.Close()
+			}
+
+			if tc.rollbackTo != 0 {
+				err = m.Rollback(context.Background(), "hostname", true, tc.rollbackTo)
+				if tc.wantErrRollback {
+					require.Error(t, err, "Rollback should return an error but got none")
+					return
+				}
+				require.NoError(t, err, "Rollback should return no error but got one")
+			}
+
+			got, err := m.History(context.Background(), "hostname")
+			if tc.wantErrHistory {
+				require.Error(t, err, "History should return an error but got none")
+				return
+			}
+			require.NoError(t, err, "History should return no error but got one")
+
+			// Snapshot dates are not reproducible
+			got = regexp.MustCompile(`(?m)^(\* \d+.*): .*$`).ReplaceAllString(got, "$1: #DATE#")
+			want := testutils.LoadWithUpdateFromGolden(t, got)
+			require.Equal(t, want, got, "History returned expected output")
+
+			removeReports(t, cacheDir)
+			testutils.CompareTreesWithFiltering(t, cacheDir, testutils.GoldenPath(t)+"_cache", testutils.UpdateEnabled())
+		})
+	}
+}
+
+func TestWithHistorySize(t *testing.T) {
+	t.Parallel()
+
+	bus := testutils.NewDbusConn(t)
+
+	_, err := policies.NewManager(bus, "hostname", mockBackend{}, policies.WithCacheDir(t.TempDir()), policies.WithRunDir(t.TempDir()), policies.WithHistorySize(0))
+	require.Error(t, err, "NewManager should fail with a history size lower than 1")
+}
@@ -0,0 +1,482 @@
+// Package localgroups is the policy manager for the membership of AD users and groups to local groups.
+//
+// Each setting is keyed by the name of a local group, like docker or lpadmin, and lists its members one per line,
+// using the same user@domain, domain\user and %group syntax than the privilege manager. A member prefixed with "-"
+// is removed from the group, and the last occurrence of a member wins, matching the add and remove actions of
+// Group Policy Preferences Groups.xml. AD groups are expanded to their members when applying the policy.
+//
+// Only the memberships added by adsys are owned by the policy: they are tracked in the state directory so that
+// members which are not listed anymore are removed, while memberships set up locally are left untouched unless
+// explicitly removed. Groups which don't exist on the client are skipped with a warning.
+//
+// Memberships are changed with gpasswd. The local groups policy is only applied on computers.
+package localgroups
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/leonelquinteros/gotext"
+	"github.com/ubuntu/adsys/internal/consts"
+	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
+	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/adsys/internal/policies/plan"
+	"github.com/ubuntu/adsys/internal/policies/privilege"
+	"github.com/ubuntu/adsys/internal/policies/transaction"
+	"github.com/ubuntu/adsys/internal/smbsafe"
+	"github.com/ubuntu/decorate"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// localGroupsDirName is the directory, in the state directory, where the local groups manager keeps its files.
+	localGroupsDirName = "localgroups"
+
+	membershipsName = "memberships"
+)
+
+// groupRe matches valid local group names.
+var groupRe = regexp.MustCompile(`^[a-z_][a-z0-9_-]*\$?$`)
+
+// Resolver resolves the AD users and groups referenced by the policy.
+type Resolver interface {
+	// UserExists returns if name is a known user.
+	UserExists(ctx context.Context, name string) bool
+	// GroupMembers returns the user members of the group name, or an error if the group is unknown.
+	GroupMembers(ctx context.Context, name string) ([]string, error)
+}
+
+// operation is a membership change of member in group.
+type operation struct {
+	add    bool
+	member string
+	group  string
+}
+
+// Manager prevents running multiple gpasswd processes in parallel while applying the local groups policy.
+type Manager struct {
+	localGroupsDir string
+	groupFile      string
+	gpasswdCmd     []string
+	resolver       Resolver
+
+	// applied are the operations run during the last application, to be reverted on abort.
+	applied []operation
+
+	mu sync.Mutex
+}
+
+type options struct {
+	groupFile  string
+	gpasswdCmd []string
+	resolver   Resolver
+}
+
+// Option reprents an optional function to change the local groups manager.
+type Option func(*options)
+
+// WithGroupFile overrides the default group file, used to read the current members of local groups.
+func WithGroupFile(p string) Option {
+	return func(o *options) {
+		o.groupFile = p
+	}
+}
+
+// WithGpasswdCmd overrides the default gpasswd command.
+func WithGpasswdCmd(cmd []string) Option {
+	return func(o *options) {
+		o.gpasswdCmd = cmd
+	}
+}
+
+// WithResolver overrides the default resolver querying NSS.
+func WithResolver(r Resolver) Option {
+	return func(o *options) {
+		o.resolver = r
+	}
+}
+
+// New creates a manager keeping track of the memberships it owns in stateDir.
+func New(stateDir string, opts ...Option) *Manager {
+	// defaults
+	args := options{
+		groupFile:  "/etc/group",
+		gpasswdCmd: []string{"gpasswd"},
+		resolver:   nssResolver{},
+	}
+	// applied options
+	for _, o := range opts {
+		o(&args)
+	}
+
+	if stateDir == "" {
+		stateDir = consts.DefaultStateDir
+	}
+
+	return &Manager{
+		localGroupsDir: filepath.Join(stateDir, localGroupsDirName),
+		groupFile:      args.groupFile,
+		gpasswdCmd:     args.gpasswdCmd,
+		resolver:       args.resolver,
+	}
+}
+
+// ApplyPolicy adds and removes members of local groups based on a list of entries.
+// Steps are:
+// 1.  Compute the members of each group from the entries, expanding AD groups
+// 2.  Compare them to the current members of the local groups and to the memberships previously owned by adsys
+// 3.  Run gpasswd for each needed change
+// 4.  Save the memberships now owned by adsys.
+func (m *Manager) ApplyPolicy(ctx context.Context, objectName string, isComputer bool, entries []entry.Entry) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't apply local groups policy to %s", objectName))
+
+	// Local groups policies are only set on computers.
+	if !isComputer {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.applied = nil
+
+	ops, owned, err := m.operations(ctx, entries)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf(ctx, "Applying local groups policy to %s", objectName)
+
+	var opErr error
+	for _, op := range ops {
+		if opErr = m.gpasswd(ctx, op); opErr != nil {
+			break
+		}
+		m.applied = append(m.applied, op)
+		if op.add {
+			owned[op.group] = append(owned[op.group], op.member)
+		}
+	}
+
+	// Always save the memberships we own, even partially, so that we can remove them later.
+	if err := m.saveOwned(owned); err != nil {
+		return errors.Join(opErr, err)
+	}
+	return opErr
+}
+
+// Plan returns the changes ApplyPolicy would make for a local groups policy, without applying them.
+func (m *Manager) Plan(ctx context.Context, objectName string, isComputer bool, entries []entry.Entry) (changes []plan.Change, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't plan local groups policy for %s", objectName))
+
+	// Local groups policies are only set on computers.
+	if !isComputer {
+		return nil, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ops, _, err := m.operations(ctx, entries)
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range ops {
+		changes = append(changes, plan.Call(m.gpasswdCmd[0], append(slices.Clone(m.gpasswdCmd[1:]), op.args()...)...))
+	}
+	return changes, nil
+}
+
+// Prepare backs up the memberships owned by adsys, and reverts the memberships changed during the application
+// if the transaction is aborted.
+func (m *Manager) Prepare(ctx context.Context, objectName string, isComputer bool, tx *transaction.Transaction) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't prepare local groups policy for %s", objectName))
+
+	// Local groups policies are only set on computers.
+	if !isComputer {
+		return nil
+	}
+
+	log.Debugf(ctx, "Preparing local groups policy for %s", objectName)
+
+	if err := tx.Backup(filepath.Join(m.localGroupsDir, membershipsName)); err != nil {
+		return err
+	}
+
+	tx.OnAbort(func(ctx context.Context) error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		var errs []error
+		for i := len(m.applied) - 1; i >= 0; i-- {
+			op := m.applied[i]
+			op.add = !op.add
+			errs = append(errs, m.gpasswd(ctx, op))
+		}
+		m.applied = nil
+		return errors.Join(errs...)
+	})
+
+	return nil
+}
+
+// operations returns the membership changes to run to apply entries, sorted by group and member, and the
+// memberships owned by adsys which are kept as is.
+func (m *Manager) operations(ctx context.Context, entries []entry.Entry) (ops []operation, owned map[string][]string, err error) {
+	wanted, removed := m.wantedMembers(ctx, entries)
+
+	previous, err := m.loadOwned()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	groups := make(map[string]struct{})
+	for g := range wanted {
+		groups[g] = struct{}{}
+	}
+	for g := range removed {
+		groups[g] = struct{}{}
+	}
+	for g := range previous {
+		groups[g] = struct{}{}
+	}
+	if len(groups) == 0 {
+		return nil, nil, nil
+	}
+
+	current, err := m.currentMembers()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	owned = make(map[string][]string)
+	for _, g := range sortedKeys(groups) {
+		members, exists := current[g]
+		if !exists {
+			if _, ok := previous[g]; ok {
+				log.Infof(ctx, "Group %q doesn't exist anymore, forgetting its members", g)
+			}
+			if _, ok := wanted[g]; ok {
+				log.Warningf(ctx, gotext.Get("Group %q doesn't exist on this system, skipping", g))
+			}
+			continue
+		}
+
+		for _, u := range wanted[g] {
+			if !slices.Contains(members, u) {
+				ops = append(ops, operation{add: true, member: u, group: g})
+				continue
+			}
+			// Only keep ownership of memberships we added: local ones are left untouched.
+			if slices.Contains(previous[g], u) {
+				owned[g] = append(owned[g], u)
+			}
+		}
+
+		var toRemove []string
+		for _, u := range previous[g] {
+			if !slices.Contains(wanted[g], u) && !slices.Contains(removed[g], u) {
+				toRemove = append(toRemove, u)
+			}
+		}
+		toRemove = append(toRemove, removed[g]...)
+		slices.Sort(toRemove)
+		for _, u := range slices.Compact(toRemove) {
+			if slices.Contains(members, u) {
+				ops = append(ops, operation{add: false, member: u, group: g})
+			}
+		}
+	}
+
+	return ops, owned, nil
+}
+
+// wantedMembers returns the sorted members to add and to remove of each group listed in entries.
+func (m *Manager) wantedMembers(ctx context.Context, entries []entry.Entry) (wanted, removed map[string][]string) {
+	wanted = make(map[string][]string)
+	removed = make(map[string][]string)
+
+	for _, e := range entries {
+		if e.Disabled {
+			continue
+		}
+		group := filepath.Base(e.Key)
+		if !groupRe.MatchString(group) {
+			log.Warningf(ctx, gotext.Get("Invalid local group name %q, skipping", group))
+			continue
+		}
+
+		// The last occurrence of a member decides if it is added or removed.
+		members := make(map[string]bool)
+		for _, l := range strings.Split(e.Value, "\n") {
+			l = strings.TrimSpace(l)
+			remove := strings.HasPrefix(l, "-")
+			l = strings.TrimPrefix(l, "-")
+
+			for _, name := range privilege.SplitAndNormalizeUsersAndGroups(ctx, l) {
+				for _, u := range m.resolve(ctx, name) {
+					members[u] = !remove
+				}
+			}
+		}
+
+		for u, add := range members {
+			if add {
+				wanted[group] = append(wanted[group], u)
+				continue
+			}
+			removed[group] = append(removed[group], u)
+		}
+		slices.Sort(wanted[group])
+		slices.Sort(removed[group])
+	}
+
+	return wanted, removed
+}
+
+// resolve returns the users referenced by name. Groups, prefixed with %, are expanded to their members.
+// Names without prefix are looked up as users first and then as groups.
+func (m *Manager) resolve(ctx context.Context, name string) []string {
+	if g, isGroup := strings.CutPrefix(name, "%"); isGroup {
+		members, err := m.resolver.GroupMembers(ctx, g)
+		if err != nil {
+			log.Warningf(ctx, gotext.Get("Can't resolve members of group %q, skipping: %v", g, err))
+			return nil
+		}
+		return members
+	}
+
+	if m.resolver.UserExists(ctx, name) {
+		return []string{name}
+	}
+	members, err := m.resolver.GroupMembers(ctx, name)
+	if err != nil {
+		log.Warningf(ctx, gotext.Get("Unknown user or group %q, skipping", name))
+		return nil
+	}
+	return members
+}
+
+// currentMembers returns the members of each local group, as listed in the group file.
+func (m *Manager) currentMembers() (members map[string][]string, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't read local groups from %s", m.groupFile))
+
+	f, err := os.Open(m.groupFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	members = make(map[string][]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// name:password:gid:member1,member2
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) != 4 {
+			continue
+		}
+		members[fields[0]] = nil
+		for _, u := range strings.Split(fields[3], ",") {
+			if u = strings.TrimSpace(u); u != "" {
+				members[fields[0]] = append(members[fields[0]], u)
+			}
+		}
+	}
+	return members, scanner.Err()
+}
+
+// loadOwned returns the memberships owned by adsys, per group.
+func (m *Manager) loadOwned() (owned map[string][]string, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't load memberships owned by adsys"))
+
+	d, err := os.ReadFile(filepath.Join(m.localGroupsDir, membershipsName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(d, &owned); err != nil {
+		return nil, err
+	}
+	return owned, nil
+}
+
+// saveOwned saves the memberships owned by adsys, removing the tracking file if there are none.
+func (m *Manager) saveOwned(owned map[string][]string) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't save memberships owned by adsys"))
+
+	p := filepath.Join(m.localGroupsDir, membershipsName)
+	for g, members := range owned {
+		if len(members) == 0 {
+			delete(owned, g)
+			continue
+		}
+		slices.Sort(members)
+	}
+	if len(owned) == 0 {
+		if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	d, err := yaml.Marshal(owned)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.localGroupsDir, 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(p+".new", d, 0600); err != nil {
+		return err
+	}
+	return os.Rename(p+".new", p)
+}
+
+// gpasswd runs gpasswd to apply op.
+func (m *Manager) gpasswd(ctx context.Context, op operation) error {
+	if os.Getenv("ADSYS_SKIP_ROOT_CALLS") != "" {
+		return nil
+	}
+
+	args := append(slices.Clone(m.gpasswdCmd[1:]), op.args()...)
+	// #nosec G204 - We are in control of the arguments
+	cmd := exec.CommandContext(ctx, m.gpasswdCmd[0], args...)
+	smbsafe.WaitExec()
+	out, err := cmd.CombinedOutput()
+	smbsafe.DoneExec()
+	if err != nil {
+		if op.add {
+			return errors.New(gotext.Get("failed to add %q to group %q: %v\n%s", op.member, op.group, err, out))
+		}
+		return errors.New(gotext.Get("failed to remove %q from group %q: %v\n%s", op.member, op.group, err, out))
+	}
+	return nil
+}
+
+// args returns the gpasswd arguments of the operation.
+func (op operation) args() []string {
+	if op.add {
+		return []string{"-a", op.member, op.group}
+	}
+	return []string{"-d", op.member, op.group}
+}
+
+// sortedKeys returns the keys of m in ascending order.
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,254 @@
+package localgroups_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/adsys/internal/policies/localgroups"
+	"github.com/ubuntu/adsys/internal/policies/transaction"
+	"github.com/ubuntu/adsys/internal/testutils"
+)
+
+func TestApplyPolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		notComputer   bool
+		entries       []entry.Entry
+		existingState string
+		noGroupFile   bool
+
+		wantErr bool
+	}{
+		"Add users and group members": {entries: []entry.Entry{
+			{Key: "localgroups/docker", Value: "alice@example.com\n%developers@example.com"},
+			{Key: "localgroups/dialout", Value: `bob@example.com, example.com\carol`},
+		}},
+		"Members already in the group are not owned": {entries: []entry.Entry{
+			{Key: "localgroups/docker", Value: "localadmin\nalice@example.com"},
+		}},
+		"Members prefixed with - are removed": {entries: []entry.Entry{
+			{Key: "localgroups/lpadmin", Value: "-localadmin\n-alice@example.com"},
+		}},
+		"Last occurrence of a member wins": {entries: []entry.Entry{
+			{Key: "localgroups/docker", Value: "alice@example.com\n-alice@example.com\n-bob@example.com\nbob@example.com"},
+		}},
+		"Names without prefix can be groups": {entries: []entry.Entry{
+			{Key: "localgroups/docker", Value: "developers@example.com"},
+		}},
+		"Unknown users and groups are skipped": {entries: []entry.Entry{
+			{Key: "localgroups/docker", Value: "unknown@example.com\n%unknown@example.com\nalice@example.com"},
+		}},
+		"Groups not on the system are skipped": {entries: []entry.Entry{
+			{Key: "localgroups/wireshark", Value: "alice@example.com"},
+			{Key: "localgroups/docker", Value: "alice@example.com"},
+		}},
+		"Invalid group names are skipped": {entries: []entry.Entry{
+			{Key: "localgroups/Docker Users", Value: "alice@example.com"},
+		}},
+
+		// Owned memberships cases
+		"Owned members not listed anymore are removed": {existingState: "owned-memberships", entries: []entry.Entry{
+			{Key: "localgroups/lpadmin", Value: "bob@example.com"},
+		}},
+		"Owned members added locally again are kept": {existingState: "owned-memberships", entries: []entry.Entry{
+			{Key: "localgroups/lpadmin", Value: "bob@example.com"},
+			{Key: "localgroups/libvirt", Value: "alice@example.com"},
+		}},
+		"Disabled entries remove owned members": {existingState: "owned-memberships", entries: []entry.Entry{
+			{Key: "localgroups/lpadmin", Value: "bob@example.com"},
+			{Key: "localgroups/libvirt", Value: "alice@example.com", Disabled: true},
+		}},
+		"No rules removes owned members":         {existingState: "owned-memberships"},
+		"No rules and no state do not read file": {noGroupFile: true},
+		"Not a computer":                         {notComputer: true, existingState: "owned-memberships"},
+
+		// Error cases
+		"Error on missing group file": {noGroupFile: true, wantErr: true, entries: []entry.Entry{
+			{Key: "localgroups/docker", Value: "alice@example.com"},
+		}},
+		"Error on invalid state": {existingState: "invalid-state", wantErr: true, entries: []entry.Entry{
+			{Key: "localgroups/docker", Value: "alice@example.com"},
+		}},
+		"Error on gpasswd keeps tracking previous changes": {wantErr: true, entries: []entry.Entry{
+			{Key: "localgroups/docker", Value: "alice@example.com\nfailing@example.com\nbob@example.com"},
+		}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			tmpDir := t.TempDir()
+			stateDir := filepath.Join(tmpDir, "state")
+			if tc.existingState != "" {
+				testutils.Copy(t, filepath.Join("testdata", tc.existingState), stateDir)
+			}
+			groupFile := filepath.Join("testdata", "group")
+			if tc.noGroupFile {
+				groupFile = filepath.Join(tmpDir, "doesnotexist")
+			}
+			gpasswdOutput := filepath.Join(tmpDir, "gpasswd")
+
+			m := localgroups.New(stateDir,
+				localgroups.WithGroupFile(groupFile),
+				localgroups.WithGpasswdCmd(mockGpasswdCmd(t, gpasswdOutput)),
+				localgroups.WithResolver(mockResolver{}))
+			err := m.ApplyPolicy(context.Background(), "ubuntu", !tc.notComputer, tc.entries)
+			if tc.wantErr {
+				require.Error(t, err, "ApplyPolicy should have failed but didn't")
+			} else {
+				require.NoError(t, err, "ApplyPolicy failed but shouldn't have")
+			}
+
+			testutils.CompareTreesWithFiltering(t, stateDir, filepath.Join(testutils.GoldenPath(t), "state"), testutils.UpdateEnabled())
+
+			calls, err := os.ReadFile(gpasswdOutput)
+			if err != nil {
+				require.ErrorIs(t, err, os.ErrNotExist, "Setup: can't read gpasswd output")
+			}
+			want := testutils.LoadWithUpdateFromGolden(t, string(calls), testutils.WithGoldenPath(filepath.Join(testutils.GoldenPath(t), "gpasswd")))
+			require.Equal(t, want, string(calls), "gpasswd calls don't match")
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		notComputer   bool
+		entries       []entry.Entry
+		existingState string
+
+		wantChanges []string
+	}{
+		"Changes are planned as calls": {
+			existingState: "owned-memberships",
+			entries: []entry.Entry{
+				{Key: "localgroups/docker", Value: "alice@example.com"},
+				{Key: "localgroups/lpadmin", Value: "bob@example.com\n-localadmin"},
+			},
+			wantChanges: []string{
+				"-a alice@example.com docker",
+				"-d alice@example.com libvirt",
+				"-d localadmin lpadmin",
+			},
+		},
+		"Up to date system": {entries: []entry.Entry{{Key: "localgroups/docker", Value: "localadmin"}}},
+		"Nothing to do":     {},
+		"Not a computer":    {notComputer: true, entries: []entry.Entry{{Key: "localgroups/docker", Value: "alice@example.com"}}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			tmpDir := t.TempDir()
+			stateDir := filepath.Join(tmpDir, "state")
+			if tc.existingState != "" {
+				testutils.Copy(t, filepath.Join("testdata", tc.existingState), stateDir)
+			}
+			gpasswdOutput := filepath.Join(tmpDir, "gpasswd")
+
+			m := localgroups.New(stateDir,
+				localgroups.WithGroupFile(filepath.Join("testdata", "group")),
+				localgroups.WithGpasswdCmd(mockGpasswdCmd(t, gpasswdOutput)),
+				localgroups.WithResolver(mockResolver{}))
+			changes, err := m.Plan(context.Background(), "ubuntu", !tc.notComputer, tc.entries)
+			require.NoError(t, err, "Plan failed but shouldn't have")
+
+			var got []string
+			for _, c := range changes {
+				got = append(got, c.Content[strings.Index(c.Content, "-- ")+len("-- "+gpasswdOutput+" "):])
+			}
+			require.Equal(t, tc.wantChanges, got, "Plan returns the expected changes")
+
+			// Planning should not touch the system.
+			require.NoFileExists(t, gpasswdOutput, "Plan should not call gpasswd")
+		})
+	}
+}
+
+func TestPrepareRevertsChangesOnAbort(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	stateDir := filepath.Join(tmpDir, "state")
+	testutils.Copy(t, filepath.Join("testdata", "owned-memberships"), stateDir)
+	gpasswdOutput := filepath.Join(tmpDir, "gpasswd")
+
+	m := localgroups.New(stateDir,
+		localgroups.WithGroupFile(filepath.Join("testdata", "group")),
+		localgroups.WithGpasswdCmd(mockGpasswdCmd(t, gpasswdOutput)),
+		localgroups.WithResolver(mockResolver{}))
+
+	tx, err := transaction.New(t.TempDir(), "test-")
+	require.NoError(t, err, "Setup: can't create transaction")
+	require.NoError(t, m.Prepare(context.Background(), "ubuntu", true, tx), "Prepare failed but shouldn't have")
+
+	entries := []entry.Entry{{Key: "localgroups/docker", Value: "alice@example.com"}}
+	require.NoError(t, m.ApplyPolicy(context.Background(), "ubuntu", true, entries), "Setup: ApplyPolicy failed")
+	require.NoError(t, tx.Abort(context.Background()), "Abort failed but shouldn't have")
+
+	testutils.CompareTreesWithFiltering(t, stateDir, filepath.Join("testdata", "owned-memberships"), false)
+
+	calls, err := os.ReadFile(gpasswdOutput)
+	require.NoError(t, err, "Setup: can't read gpasswd output")
+	want := testutils.LoadWithUpdateFromGolden(t, string(calls))
+	require.Equal(t, want, string(calls), "gpasswd calls don't match")
+}
+
+// mockResolver knows a fixed set of AD users and groups.
+type mockResolver struct{}
+
+func (mockResolver) UserExists(_ context.Context, name string) bool {
+	return slices.Contains([]string{"alice@example.com", "bob@example.com", "carol@example.com", "failing@example.com", "localadmin"}, name)
+}
+
+func (mockResolver) GroupMembers(_ context.Context, name string) ([]string, error) {
+	if name != "developers@example.com" {
+		return nil, errors.New("group not found")
+	}
+	return []string{"alice@example.com", "carol@example.com"}, nil
+}
+
+func mockGpasswdCmd(t *testing.T, outputFile string) []string {
+	t.Helper()
+
+	return []string{"env", "GO_WANT_HELPER_PROCESS=1", os.Args[0], "-test.run=TestMockGpasswd", "--", outputFile}
+}
+
+func TestMockGpasswd(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	defer os.Exit(0)
+
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	// First arg after -- is the output file to write to
+	outputFile := args[1]
+	args = args[2:]
+
+	if args[1] == "failing@example.com" {
+		fmt.Println("EXIT 1 requested in mock")
+		os.Exit(1)
+	}
+
+	f, err := os.OpenFile(outputFile, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	require.NoError(t, err, "Setup: Can't open gpasswd output file")
+	_, err = f.WriteString(strings.Join(args, " ") + "\n")
+	require.NoError(t, err, "Setup: Can't write to gpasswd output file")
+	require.NoError(t, f.Close(), "Setup: Can't close gpasswd output file")
+}
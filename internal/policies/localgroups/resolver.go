@@ -0,0 +1,46 @@
+package localgroups
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"os/user"
+	"strings"
+
+	"github.com/ubuntu/adsys/internal/smbsafe"
+)
+
+// nssResolver resolves users and groups with NSS, which covers the AD ones through SSSD or winbind.
+type nssResolver struct{}
+
+// UserExists returns if name is a user known by NSS.
+func (nssResolver) UserExists(_ context.Context, name string) bool {
+	_, err := user.Lookup(name)
+	return err == nil
+}
+
+// GroupMembers returns the members of the group name, as listed by getent.
+func (nssResolver) GroupMembers(ctx context.Context, name string) (members []string, err error) {
+	// #nosec G204 - We are in control of the arguments
+	cmd := exec.CommandContext(ctx, "getent", "group", name)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	smbsafe.WaitExec()
+	out, err := cmd.Output()
+	smbsafe.DoneExec()
+	if err != nil {
+		return nil, fmt.Errorf("%w\n%s", err, stderr.String())
+	}
+
+	// name:password:gid:member1,member2
+	fields := strings.Split(strings.TrimSpace(string(out)), ":")
+	if len(fields) != 4 {
+		return nil, fmt.Errorf("unexpected getent output: %q", out)
+	}
+	for _, u := range strings.Split(fields[3], ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			members = append(members, u)
+		}
+	}
+	return members, nil
+}
@@ -21,12 +21,18 @@
 //
 // This is supposed to be a guideline, rather than a rule. Therefore, some of these errors can be
 // interchangeable depending on which policy is being applied.
+//
+// Each policy manager is registered for the rule type it handles, declaring whether it applies to machines
+// and/or users, if it requires an Ubuntu Pro subscription and which managers should be applied before it.
+// Managers are applied concurrently, respecting those dependencies. Additional managers can be registered
+// with WithPolicyManager.
 package policies
 
 import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"slices"
@@ -43,35 +49,37 @@ import (
 	"github.com/ubuntu/adsys/internal/policies/certificate"
 	"github.com/ubuntu/adsys/internal/policies/dconf"
 	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/adsys/internal/policies/environment"
+	"github.com/ubuntu/adsys/internal/policies/firewall"
 	"github.com/ubuntu/adsys/internal/policies/gdm"
+	"github.com/ubuntu/adsys/internal/policies/localgroups"
 	"github.com/ubuntu/adsys/internal/policies/mount"
+	"github.com/ubuntu/adsys/internal/policies/packages"
+	"github.com/ubuntu/adsys/internal/policies/plan"
+	"github.com/ubuntu/adsys/internal/policies/plugin"
 	"github.com/ubuntu/adsys/internal/policies/privilege"
 	"github.com/ubuntu/adsys/internal/policies/proxy"
 	"github.com/ubuntu/adsys/internal/policies/scripts"
+	"github.com/ubuntu/adsys/internal/policies/systemaccess"
+	"github.com/ubuntu/adsys/internal/policies/transaction"
+	"github.com/ubuntu/adsys/internal/policies/units"
 	"github.com/ubuntu/adsys/internal/systemd"
 	"github.com/ubuntu/decorate"
-	"golang.org/x/sync/errgroup"
 )
 
-// ProOnlyRules are the rules that are only available for Pro subscribers. They
-// will be filtered otherwise.
-var ProOnlyRules = []string{"privilege", "scripts", "mount", "apparmor", "proxy", "certificate"}
-
 // Manager handles all managers for various policy handlers.
 type Manager struct {
+	cacheDir         string
 	policiesCacheDir string
+	historyCacheDir  string
+	historySize      int
+	reportsCacheDir  string
 	hostname         string
 
 	backend backends.Backend
 
-	dconf       *dconf.Manager
-	privilege   *privilege.Manager
-	scripts     *scripts.Manager
-	mount       *mount.Manager
-	gdm         *gdm.Manager
-	apparmor    *apparmor.Manager
-	proxy       *proxy.Manager
-	certificate *certificate.Manager
+	// registry holds all policy managers, in the order they are applied.
+	registry registry
 
 	subscriptionDbus dbus.BusObject
 
@@ -86,8 +94,13 @@ type systemdCaller interface {
 	StartUnit(context.Context, string) error
 	StopUnit(context.Context, string) error
 
+	StartTransientUnit(ctx context.Context, unit, description string, cmd []string) error
+
 	EnableUnit(context.Context, string) error
 	DisableUnit(context.Context, string) error
+	MaskUnit(context.Context, string) error
+	UnmaskUnit(context.Context, string) error
+	UnitFileState(context.Context, string) (string, error)
 
 	DaemonReload(context.Context) error
 }
@@ -104,12 +117,23 @@ type options struct {
 	apparmorFsDir  string
 	systemUnitDir  string
 	globalTrustDir string
+	securityDir    string
+	nftablesDir    string
+	environmentDir string
+	profileDir     string
+	pluginsDir     string
 	proxyApplier   proxy.Caller
 	systemdCaller  systemdCaller
 	gdm            *gdm.Manager
+	historySize    int
+	policyManagers []Registration
 
 	apparmorParserCmd []string
 	certAutoenrollCmd []string
+	nftCmd            []string
+	packagesExecutor  packages.Executor
+	groupFile         string
+	gpasswdCmd        []string
 }
 
 // Option reprents an optional function to change Policies behavior.
@@ -123,6 +147,17 @@ func WithCacheDir(p string) Option {
 	}
 }
 
+// WithHistorySize specifies the number of applied policies snapshots kept per object.
+func WithHistorySize(n int) Option {
+	return func(o *options) error {
+		if n < 1 {
+			return errors.New(gotext.Get("policies history size should be at least 1, got %d", n))
+		}
+		o.historySize = n
+		return nil
+	}
+}
+
 // WithStateDir specifies a personalized state directory.
 func WithStateDir(p string) Option {
 	return func(o *options) error {
@@ -213,6 +248,47 @@ func WithGlobalTrustDir(p string) Option {
 	}
 }
 
+// WithSecurityDir specifies a personalized directory for PAM modules configuration, used by the
+// system access manager.
+func WithSecurityDir(p string) Option {
+	return func(o *options) error {
+		o.securityDir = p
+		return nil
+	}
+}
+
+// WithNftablesDir specifies a personalized directory for nftables rulesets, used by the firewall manager.
+func WithNftablesDir(p string) Option {
+	return func(o *options) error {
+		o.nftablesDir = p
+		return nil
+	}
+}
+
+// WithEnvironmentDir specifies a personalized environment.d directory, used by the environment manager.
+func WithEnvironmentDir(p string) Option {
+	return func(o *options) error {
+		o.environmentDir = p
+		return nil
+	}
+}
+
+// WithProfileDir specifies a personalized profile.d directory, used by the environment manager.
+func WithProfileDir(p string) Option {
+	return func(o *options) error {
+		o.profileDir = p
+		return nil
+	}
+}
+
+// WithPluginsDir specifies a personalized directory for policy plugins.
+func WithPluginsDir(p string) Option {
+	return func(o *options) error {
+		o.pluginsDir = p
+		return nil
+	}
+}
+
 // WithProxyApplier specifies a personalized proxy applier for the proxy policy manager.
 func WithProxyApplier(p proxy.Caller) Option {
 	return func(o *options) error {
@@ -237,6 +313,46 @@ func WithCertAutoenrollCmd(cmd []string) Option {
 	}
 }
 
+// WithNftCmd specifies a personalized nft command.
+func WithNftCmd(cmd []string) Option {
+	return func(o *options) error {
+		o.nftCmd = cmd
+		return nil
+	}
+}
+
+// WithPackagesExecutor specifies a personalized executor to query the packages of the system.
+func WithPackagesExecutor(e packages.Executor) Option {
+	return func(o *options) error {
+		o.packagesExecutor = e
+		return nil
+	}
+}
+
+// WithGroupFile specifies a personalized group file, listing the local groups and their members.
+func WithGroupFile(p string) Option {
+	return func(o *options) error {
+		o.groupFile = p
+		return nil
+	}
+}
+
+// WithGpasswdCmd specifies a personalized gpasswd command.
+func WithGpasswdCmd(cmd []string) Option {
+	return func(o *options) error {
+		o.gpasswdCmd = cmd
+		return nil
+	}
+}
+
+// WithPolicyManager registers an additional policy manager, handling the rules of type r.Name.
+func WithPolicyManager(r Registration) Option {
+	return func(o *options) error {
+		o.policyManagers = append(o.policyManagers, r)
+		return nil
+	}
+}
+
 // NewManager returns a new manager with all default policy handlers.
 func NewManager(bus *dbus.Conn, hostname string, backend backends.Backend, opts ...Option) (m *Manager, err error) {
 	defer decorate.OnError(&err, gotext.Get("can't create a new policy handlers manager"))
@@ -255,8 +371,14 @@ func NewManager(bus *dbus.Conn, hostname string, backend backends.Backend, opts
 		apparmorDir:    consts.DefaultApparmorDir,
 		systemUnitDir:  consts.DefaultSystemUnitDir,
 		globalTrustDir: consts.DefaultGlobalTrustDir,
+		securityDir:    consts.DefaultSecurityDir,
+		nftablesDir:    consts.DefaultNftablesDir,
+		environmentDir: consts.DefaultEnvironmentDir,
+		profileDir:     consts.DefaultProfileDir,
+		pluginsDir:     consts.DefaultPluginsDir,
 		systemdCaller:  defaultSystemdCaller,
 		gdm:            nil,
+		historySize:    consts.DefaultPoliciesHistorySize,
 	}
 	// applied options (including dconf manager used by gdm)
 	for _, o := range opts {
@@ -314,6 +436,39 @@ func NewManager(bus *dbus.Conn, hostname string, backend backends.Backend, opts
 	}
 	certificateManager := certificate.New(backend.Domain(), certificateOpts...)
 
+	// system access manager
+	systemAccessManager := systemaccess.New(args.securityDir)
+
+	// firewall manager
+	var firewallOptions []firewall.Option
+	if args.nftCmd != nil {
+		firewallOptions = append(firewallOptions, firewall.WithNftCmd(args.nftCmd))
+	}
+	firewallManager := firewall.New(args.nftablesDir, firewallOptions...)
+
+	// packages manager
+	var packagesOptions []packages.Option
+	if args.packagesExecutor != nil {
+		packagesOptions = append(packagesOptions, packages.WithExecutor(args.packagesExecutor))
+	}
+	packagesManager := packages.New(args.stateDir, args.systemdCaller, packagesOptions...)
+
+	// local groups manager
+	var localGroupsOptions []localgroups.Option
+	if args.groupFile != "" {
+		localGroupsOptions = append(localGroupsOptions, localgroups.WithGroupFile(args.groupFile))
+	}
+	if args.gpasswdCmd != nil {
+		localGroupsOptions = append(localGroupsOptions, localgroups.WithGpasswdCmd(args.gpasswdCmd))
+	}
+	localGroupsManager := localgroups.New(args.stateDir, localGroupsOptions...)
+
+	// units manager
+	unitsManager := units.New(args.stateDir, args.systemUnitDir, args.systemdCaller)
+
+	// environment manager
+	environmentManager := environment.New(args.environmentDir, args.profileDir)
+
 	// inject applied dconf mangager if we need to build a gdm manager
 	if args.gdm == nil {
 		if args.gdm, err = gdm.New(gdm.WithDconf(dconfManager)); err != nil {
@@ -321,26 +476,80 @@ func NewManager(bus *dbus.Conn, hostname string, backend backends.Backend, opts
 		}
 	}
 
+	// GDM policy is applied after dconf as it needs the dconf machine database to be ready first.
+	registrations := []Registration{
+		{Name: "dconf", Manager: dconfHandler{dconfManager}, Machine: true, User: true},
+		{Name: "privilege", Manager: privilegeHandler{privilegeManager}, Machine: true, ProOnly: true},
+		{Name: "scripts", Manager: scriptsHandler{scriptsManager}, Machine: true, User: true, ProOnly: true},
+		{Name: "mount", Manager: mountHandler{mountManager}, Machine: true, User: true, ProOnly: true},
+		{Name: "apparmor", Manager: apparmorHandler{apparmorManager}, Machine: true, User: true, ProOnly: true},
+		{Name: "proxy", Manager: proxyHandler{proxyManager}, Machine: true, ProOnly: true},
+		{Name: "certificate", Manager: certificateHandler{certificateManager, backend}, Machine: true, ProOnly: true},
+		{Name: "systemaccess", Manager: systemAccessHandler{systemAccessManager}, Machine: true, ProOnly: true},
+		{Name: "firewall", Manager: firewallHandler{firewallManager}, Machine: true, ProOnly: true},
+		{Name: "packages", Manager: packagesHandler{packagesManager}, Machine: true, ProOnly: true},
+		{Name: "localgroups", Manager: localGroupsHandler{localGroupsManager}, Machine: true, ProOnly: true},
+		{Name: "units", Manager: unitsHandler{unitsManager}, Machine: true, ProOnly: true},
+		{Name: "environment", Manager: environmentHandler{environmentManager}, Machine: true, User: true, ProOnly: true},
+		{Name: "gdm", Manager: gdmHandler{args.gdm}, Machine: true, After: []string{"dconf"}},
+	}
+	registrations = append(registrations, args.policyManagers...)
+
+	// plugin managers
+	plugins, err := plugin.Discover(context.Background(), args.pluginsDir)
+	if err != nil {
+		return nil, err
+	}
+	var pluginRegistrations []Registration
+	for _, p := range plugins {
+		if slices.ContainsFunc(registrations, func(r Registration) bool { return r.Name == p.Name() }) {
+			log.Warning(context.Background(), gotext.Get("Ignoring policy plugin %q: a policy manager already handles this rule type", p.Name()))
+			continue
+		}
+		machine, user, after := p.Capabilities()
+		pluginRegistrations = append(pluginRegistrations, Registration{
+			Name:    p.Name(),
+			Manager: pluginHandler{p},
+			Machine: machine,
+			User:    user,
+			ProOnly: true,
+			After:   after,
+		})
+	}
+	registrations = append(registrations, validPlugins(context.Background(), registrations, pluginRegistrations)...)
+
+	registry, err := newRegistry(registrations)
+	if err != nil {
+		return nil, err
+	}
+
 	policiesCacheDir := filepath.Join(args.cacheDir, PoliciesCacheBaseName)
 	if err := os.MkdirAll(policiesCacheDir, 0700); err != nil {
 		return nil, err
 	}
 
+	historyCacheDir := filepath.Join(args.cacheDir, HistoryCacheBaseName)
+	if err := os.MkdirAll(historyCacheDir, 0700); err != nil {
+		return nil, err
+	}
+
+	reportsCacheDir := filepath.Join(args.cacheDir, ReportsCacheBaseName)
+	if err := os.MkdirAll(reportsCacheDir, 0700); err != nil {
+		return nil, err
+	}
+
 	subscriptionDbus := bus.Object(consts.SubscriptionDbusRegisteredName,
 		dbus.ObjectPath(consts.SubscriptionDbusObjectPath))
 
 	return &Manager{
 		backend:          backend,
+		cacheDir:         args.cacheDir,
 		policiesCacheDir: policiesCacheDir,
+		historyCacheDir:  historyCacheDir,
+		historySize:      args.historySize,
+		reportsCacheDir:  reportsCacheDir,
 		hostname:         hostname,
-		dconf:            dconfManager,
-		privilege:        privilegeManager,
-		scripts:          scriptsManager,
-		mount:            mountManager,
-		apparmor:         apparmorManager,
-		proxy:            proxyManager,
-		certificate:      certificateManager,
-		gdm:              args.gdm,
+		registry:         registry,
 
 		subscriptionDbus: subscriptionDbus,
 
@@ -370,51 +579,210 @@ func (m *Manager) ApplyPolicies(ctx context.Context, objectName string, isComput
 	}
 	log.Info(ctx, gotext.Get("%s policies for %s (machine: %v)", action, objectName, isComputer))
 
-	var g errgroup.Group
-	// Applying dconf policies take a while to complete, so it's better to start applying them before
-	// querying dbus for the Pro subscription state, as it does not rely on that.
-	g.Go(func() error {
-		return m.dconf.ApplyPolicy(ctx, objectName, isComputer, rules["dconf"])
-	})
-	if !m.GetSubscriptionState(ctx) {
-		if filteredRules := filterRules(ctx, rules); len(filteredRules) > 0 {
+	// Every policy manager backs up its current state before applying anything. If any of them fails,
+	// the state of all of them is restored, so that we never end up with a partially applied policy.
+	tx, err := transaction.New(m.cacheDir, ".transaction-"+objectName+"-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == nil {
+			if err := tx.Commit(); err != nil {
+				log.Warning(ctx, err)
+			}
+			return
+		}
+		if errAbort := tx.Abort(ctx); errAbort != nil {
+			log.Warning(ctx, gotext.Get("Could not restore previous policies state for %s: %v", objectName, errAbort))
+		}
+	}()
+
+	subscribed := m.GetSubscriptionState(ctx)
+	if !subscribed {
+		if filteredRules := filterRules(ctx, rules, m.ProOnlyRules()); len(filteredRules) > 0 {
 			log.Warning(ctx, gotext.Get("Rules from the following policy types will be filtered out as the machine is not enrolled to Ubuntu Pro: %s", strings.Join(filteredRules, ", ")))
 		}
 	}
 
-	g.Go(func() error {
-		return m.privilege.ApplyPolicy(ctx, objectName, isComputer, rules["privilege"])
-	})
-	g.Go(func() error {
-		return m.scripts.ApplyPolicy(ctx, objectName, isComputer, rules["scripts"], pols.SaveAssetsTo)
-	})
-	g.Go(func() error {
-		return m.mount.ApplyPolicy(ctx, objectName, isComputer, rules["mount"])
-	})
-	g.Go(func() error {
-		return m.apparmor.ApplyPolicy(ctx, objectName, isComputer, rules["apparmor"], pols.SaveAssetsTo)
-	})
-	g.Go(func() error {
-		return m.proxy.ApplyPolicy(ctx, objectName, isComputer, rules["proxy"])
-	})
-	g.Go(func() error {
-		// Ignore error as we don't want to fail because of online status this late in the process
-		isOnline, _ := m.backend.IsOnline()
-		return m.certificate.ApplyPolicy(ctx, objectName, isComputer, isOnline, rules["certificate"])
-	})
-	if err := g.Wait(); err != nil {
+	regs := m.registry.forObject(isComputer)
+	requests := newRequests(regs, objectName, isComputer, rules, pols)
+	if err := m.prepare(ctx, objectName, subscribed, regs, requests, tx); err != nil {
 		return err
 	}
 
-	if isComputer {
-		// Apply GDM policy only now as we need dconf machine database to be ready first
-		if err := m.gdm.ApplyPolicy(ctx, rules["gdm"]); err != nil {
-			return err
+	// Record the outcome of each manager, even if applying the policies fails.
+	var muReports sync.Mutex
+	reports := make(map[string]ManagerReport)
+	defer func() {
+		r := Report{ObjectName: objectName, IsComputer: isComputer, Time: time.Now()}
+		if err != nil {
+			r.Error = err.Error()
+		}
+		for _, reg := range regs {
+			mr, ok := reports[reg.Name]
+			if !ok {
+				mr = ManagerReport{
+					Name:    reg.Name,
+					Outcome: OutcomeFailed,
+					Entries: len(requests[reg.Name].Entries),
+					Error:   gotext.Get("not applied as a policy manager it depends on failed"),
+				}
+			}
+			r.Managers = append(r.Managers, mr)
 		}
+		if err := m.saveReport(r); err != nil {
+			log.Warning(ctx, err)
+		}
+	}()
+
+	// Managers are applied concurrently, once the managers they depend on are applied.
+	if err := regs.run(func(reg Registration) error {
+		req := requests[reg.Name]
+		start := time.Now()
+		err := reg.Manager.ApplyPolicy(ctx, req)
+
+		mr := newManagerReport(reg, req, subscribed, time.Since(start), err)
+		if r, ok := reg.Manager.(Reporter); ok && err == nil {
+			mr.Details = r.Details(ctx, req)
+		}
+
+		muReports.Lock()
+		defer muReports.Unlock()
+		reports[reg.Name] = mr
+		return err
+	}); err != nil {
+		return err
 	}
 
 	// Write cache Policies
-	return pols.Save(filepath.Join(m.policiesCacheDir, objectName))
+	if err := pols.Save(filepath.Join(m.policiesCacheDir, objectName)); err != nil {
+		return err
+	}
+
+	// Keep track of applied policies for any later rollback. Policies are applied at this stage, so don't fail.
+	if err := m.saveSnapshot(ctx, objectName); err != nil {
+		log.Warning(ctx, err)
+	}
+	return nil
+}
+
+// prepare backs up the current state of all policy managers into tx.
+func (m *Manager) prepare(ctx context.Context, objectName string, subscribed bool, regs registry, requests map[string]Request, tx *transaction.Transaction) (err error) {
+	defer decorate.OnError(&err, gotext.Get("failed to prepare policy for %q", objectName))
+
+	// Some managers need the previously applied rules to restore them.
+	var previous map[string][]entry.Entry
+	if p, err := NewFromCache(ctx, filepath.Join(m.policiesCacheDir, objectName)); err == nil {
+		previous = p.GetUniqueRules()
+		p.Close()
+	}
+
+	for _, reg := range regs {
+		req := requests[reg.Name]
+		// Pro only rules were not applied if the machine is not subscribed.
+		if subscribed || !reg.ProOnly {
+			req.Previous = previous[reg.Name]
+			requests[reg.Name] = req
+		}
+		if err := reg.Manager.Prepare(ctx, req, tx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newRequests returns the request to each policy manager of regs, indexed by rule type.
+func newRequests(regs registry, objectName string, isComputer bool, rules map[string][]entry.Entry, pols *Policies) map[string]Request {
+	requests := make(map[string]Request)
+	for _, reg := range regs {
+		requests[reg.Name] = Request{
+			ObjectName:   objectName,
+			IsComputer:   isComputer,
+			Entries:      rules[reg.Name],
+			SaveAssetsTo: pols.SaveAssetsTo,
+		}
+	}
+	return requests
+}
+
+// PlanPolicies returns the changes ApplyPolicies would make on the system for a computer or user policy.
+// Nothing is applied and the policies cache is not updated.
+func (m *Manager) PlanPolicies(ctx context.Context, objectName string, isComputer bool, pols *Policies) (msg string, err error) {
+	defer decorate.OnError(&err, gotext.Get("failed to plan policy for %q", objectName))
+
+	log.Info(ctx, gotext.Get("Planning policies for %s (machine: %v)", objectName, isComputer))
+
+	planned, err := m.objectChanges(ctx, objectName, isComputer, pols, func(ctx context.Context, reg Registration, req Request) ([]plan.Change, error) {
+		return reg.Manager.Plan(ctx, req)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	fmt.Fprintln(&out, gotext.Get("Planned changes for %s (machine: %v):", objectName, isComputer))
+	for _, c := range planned {
+		if len(c.changes) == 0 {
+			fmt.Fprintln(&out, gotext.Get("* %s: no change", c.name))
+			continue
+		}
+		c.format(&out)
+	}
+
+	return out.String(), nil
+}
+
+// managerChanges are the changes of a policy manager on the system.
+type managerChanges struct {
+	name    string
+	changes []plan.Change
+}
+
+// format writes the list of changes to out.
+func (c managerChanges) format(out io.Writer) {
+	fmt.Fprintf(out, "* %s:\n", c.name)
+	for _, change := range c.changes {
+		for i, l := range strings.Split(change.String(), "\n") {
+			prefix := "    "
+			if i == 0 {
+				prefix = "  - "
+			}
+			fmt.Fprintf(out, "%s%s\n", prefix, l)
+		}
+	}
+}
+
+// objectChanges returns the changes computed by compute for each policy manager of objectName, in the same order
+// than ApplyPolicies runs them. Pro only rules are filtered out the same way ApplyPolicies does.
+func (m *Manager) objectChanges(ctx context.Context, objectName string, isComputer bool, pols *Policies, compute func(context.Context, Registration, Request) ([]plan.Change, error)) (changes []managerChanges, err error) {
+	// Prevent any concurrent apply for the same object while we compare with the current state.
+	m.muMu.Lock()
+	if _, ok := m.objectMu[objectName]; !ok {
+		m.objectMu[objectName] = &sync.Mutex{}
+	}
+	m.objectMu[objectName].Lock()
+	defer m.objectMu[objectName].Unlock()
+	m.muMu.Unlock()
+
+	rules := pols.GetUniqueRules()
+	if !m.GetSubscriptionState(ctx) {
+		if filteredRules := filterRules(ctx, rules, m.ProOnlyRules()); len(filteredRules) > 0 {
+			log.Warning(ctx, gotext.Get("Rules from the following policy types will be filtered out as the machine is not enrolled to Ubuntu Pro: %s", strings.Join(filteredRules, ", ")))
+		}
+	}
+
+	regs := m.registry.forObject(isComputer)
+	requests := newRequests(regs, objectName, isComputer, rules, pols)
+	for _, reg := range regs {
+		c, err := compute(ctx, reg, requests[reg.Name])
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, managerChanges{name: reg.Name, changes: c})
+	}
+
+	return changes, nil
 }
 
 // DumpPolicies displays the currently applied policies and rules (since last update) for objectName.
@@ -436,6 +804,12 @@ func (m *Manager) DumpPolicies(ctx context.Context, objectName string, computerO
 		for _, g := range policiesHost.GPOs {
 			alreadyProcessedRules = g.Format(&out, withRules, withOverridden, alreadyProcessedRules)
 		}
+		for _, g := range policiesHost.SkippedGPOs {
+			g.Format(&out)
+		}
+		if mode := policiesHost.Loopback(); mode != "" {
+			fmt.Fprintln(&out, gotext.Get("User policies loopback processing mode: %s", mode))
+		}
 		fmt.Fprintln(&out, gotext.Get("Policies from user configuration:"))
 	}
 
@@ -448,10 +822,55 @@ func (m *Manager) DumpPolicies(ctx context.Context, objectName string, computerO
 	for _, g := range policiesTarget.GPOs {
 		alreadyProcessedRules = g.Format(&out, withRules, withOverridden, alreadyProcessedRules)
 	}
+	for _, g := range policiesTarget.SkippedGPOs {
+		g.Format(&out)
+	}
+	if mode := policiesTarget.Loopback(); computerOnly && mode != "" {
+		fmt.Fprintln(&out, gotext.Get("User policies loopback processing mode: %s", mode))
+	}
 
 	return out.String(), nil
 }
 
+// AppliedPolicies returns the GPOs applied to objectName, in order of priority, with all their rules.
+// Unless computerOnly is set, the GPOs from the machine configuration are listed first, as they take precedence.
+func (m *Manager) AppliedPolicies(ctx context.Context, objectName string, computerOnly bool) (gpos []AppliedGPO, err error) {
+	defer decorate.OnError(&err, gotext.Get("failed to get applied policies for %q", objectName))
+
+	log.Infof(ctx, "Get applied policies for %s", objectName)
+
+	type object struct {
+		name       string
+		isComputer bool
+	}
+	objects := []object{{name: objectName, isComputer: computerOnly}}
+	if !computerOnly {
+		objects = append([]object{{name: m.hostname, isComputer: true}}, objects...)
+	}
+
+	alreadyProcessedRules := make(map[string]struct{})
+	for _, o := range objects {
+		pols, err := NewFromCache(ctx, filepath.Join(m.policiesCacheDir, o.name))
+		if err != nil {
+			return nil, errors.New(gotext.Get("no policy applied for %q: %v", o.name, err))
+		}
+		t, err := m.LastUpdateFor(ctx, o.name, false)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, g := range pols.GPOs {
+			a := AppliedGPO{ID: g.ID, Name: g.Name, IsComputer: o.isComputer, LastUpdate: t}
+			for _, d := range g.ruleTypes() {
+				a.Rules = append(a.Rules, g.annotatedRules(d, alreadyProcessedRules)...)
+			}
+			gpos = append(gpos, a)
+		}
+	}
+
+	return gpos, nil
+}
+
 // LastUpdateFor returns the last update time for object or current machine.
 func (m *Manager) LastUpdateFor(ctx context.Context, objectName string, isMachine bool) (t time.Time, err error) {
 	defer decorate.OnError(&err, gotext.Get("failed to get policy last update time %q (machine: %v)", objectName, isMachine))
@@ -501,24 +920,30 @@ func (m *Manager) GetSubscriptionState(ctx context.Context) (subscriptionEnabled
 	return true
 }
 
-// filterRules allows to filter any rules that are not eligible for the current device,
+// ProOnlyRules returns the rules that are only available for Pro subscribers, in the order they are applied.
+// They will be filtered otherwise.
+func (m *Manager) ProOnlyRules() []string {
+	return m.registry.proOnly()
+}
+
+// filterRules allows to filter any rules from proOnlyRules that are not eligible for the current device,
 // and returns the sorted list of filtered rules.
-func filterRules(ctx context.Context, rules map[string][]entry.Entry) []string {
+func filterRules(ctx context.Context, rules map[string][]entry.Entry, proOnlyRules []string) []string {
 	log.Debug(ctx, "Filtering Rules")
 
 	var filteredRules []string
 	for rule := range rules {
-		if !slices.Contains(ProOnlyRules, rule) {
+		if !slices.Contains(proOnlyRules, rule) {
 			continue
 		}
 		filteredRules = append(filteredRules, rule)
 		rules[rule] = nil
 	}
 
-	// Return the filtered rules in the same order as ProOnlyRules, which is the
+	// Return the filtered rules in the same order as proOnlyRules, which is the
 	// order of the rules to apply
 	slices.SortFunc(filteredRules, func(a, b string) int {
-		return slices.Index(ProOnlyRules, a) - slices.Index(ProOnlyRules, b)
+		return slices.Index(proOnlyRules, a) - slices.Index(proOnlyRules, b)
 	})
 
 	return filteredRules
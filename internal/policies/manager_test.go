@@ -79,6 +79,7 @@ func TestApplyPolicies(t *testing.T) {
 			dconfDir := filepath.Join(fakeRootDir, "etc", "dconf")
 			policyKitDir := filepath.Join(fakeRootDir, "etc", "polkit-1")
 			sudoersDir := filepath.Join(fakeRootDir, "etc", "sudoers.d")
+			securityDir := filepath.Join(fakeRootDir, "etc", "security")
 			apparmorDir := filepath.Join(fakeRootDir, "etc", "apparmor.d", "adsys")
 			systemUnitDir := filepath.Join(fakeRootDir, "etc", "systemd", "system")
 			stateDir := filepath.Join(fakeRootDir, "var", "lib", "adsys")
@@ -109,9 +110,17 @@ func TestApplyPolicies(t *testing.T) {
 				policies.WithDconfDir(dconfDir),
 				policies.WithPolicyKitDir(policyKitDir),
 				policies.WithSudoersDir(sudoersDir),
+				policies.WithSecurityDir(securityDir),
+				policies.WithNftablesDir(filepath.Join(fakeRootDir, "etc", "nftables.d")),
+				policies.WithEnvironmentDir(filepath.Join(fakeRootDir, "etc", "environment.d")),
+				policies.WithProfileDir(filepath.Join(fakeRootDir, "etc", "profile.d")),
 				policies.WithApparmorDir(apparmorDir),
 				policies.WithApparmorFsDir(filepath.Dir(loadedPoliciesFile)),
 				policies.WithApparmorParserCmd([]string{"/bin/true"}),
+				policies.WithNftCmd([]string{"/bin/true"}),
+				policies.WithPackagesExecutor(testutils.MockPackagesExecutor{}),
+				policies.WithGroupFile(filepath.Join("testdata", "group")),
+				policies.WithGpasswdCmd([]string{"/bin/true"}),
 				policies.WithCertAutoenrollCmd([]string{"/bin/true"}),
 				policies.WithSystemUnitDir(systemUnitDir),
 				policies.WithProxyApplier(&mockProxyApplier{wantApplyError: tc.noUbuntuProxyManager}),
@@ -143,7 +152,7 @@ func TestApplyPolicies(t *testing.T) {
 			require.NoError(t, errCopy, "Setup: Couldn't copy logs to buffer")
 
 			if tc.isNotSubscribed {
-				want := fmt.Sprintf("Rules from the following policy types will be filtered out as the machine is not enrolled to Ubuntu Pro: %s", strings.Join(policies.ProOnlyRules, ", "))
+				want := fmt.Sprintf("Rules from the following policy types will be filtered out as the machine is not enrolled to Ubuntu Pro: %s", strings.Join(m.ProOnlyRules(), ", "))
 				require.Contains(t, out.String(), want, "ApplyPolicy should have logged the filtered rules")
 			}
 
@@ -177,11 +186,210 @@ func TestApplyPolicies(t *testing.T) {
 				require.NoError(t, err, "ApplyPolicy should return no error but got one")
 			}
 
+			removeReports(t, cacheDir)
 			testutils.CompareTreesWithFiltering(t, fakeRootDir, testutils.GoldenPath(t), testutils.UpdateEnabled())
 		})
 	}
 }
 
+func TestApplyPoliciesRestoresPreviousStateOnFailure(t *testing.T) {
+	//t.Parallel()
+
+	bus := testutils.NewDbusConn(t)
+
+	subscriptionDbus := bus.Object(consts.SubscriptionDbusRegisteredName,
+		dbus.ObjectPath(consts.SubscriptionDbusObjectPath))
+
+	tests := map[string]struct {
+		previousPoliciesDir string
+		policiesDir         string
+		proxyError          bool
+	}{
+		"Failing dconf policy on a new system leaves it untouched":       {policiesDir: "dconf_failing"},
+		"Failing certificate policy on a new system leaves it untouched": {policiesDir: "certificate_failing"},
+		"Failing proxy policy on a new system leaves it untouched":       {policiesDir: "all_entry_types", proxyError: true},
+
+		"Failing dconf policy restores previously applied policies":       {previousPoliciesDir: "all_entry_types", policiesDir: "dconf_failing"},
+		"Failing certificate policy restores previously applied policies": {previousPoliciesDir: "all_entry_types", policiesDir: "certificate_failing"},
+		"Failing proxy policy restores previously applied policies":       {previousPoliciesDir: "all_entry_types", policiesDir: "all_entry_types", proxyError: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			//t.Parallel()
+
+			fakeRootDir := t.TempDir()
+			loadedPoliciesFile := filepath.Join(fakeRootDir, "sys", "kernel", "security", "apparmor", "profiles")
+
+			err := os.MkdirAll(filepath.Dir(loadedPoliciesFile), 0700)
+			require.NoError(t, err, "Setup: can not create loadedPoliciesFile dir")
+			err = os.WriteFile(loadedPoliciesFile, []byte("someprofile (enforce)\n"), 0600)
+			require.NoError(t, err, "Setup: can not create loadedPoliciesFile")
+
+			require.NoError(t, subscriptionDbus.SetProperty(consts.SubscriptionDbusInterface+".Attached", true), "Setup: can not set subscription status")
+			defer func() {
+				require.NoError(t, subscriptionDbus.SetProperty(consts.SubscriptionDbusInterface+".Attached", false), "Teardown: can not restore subscription status")
+			}()
+
+			proxyApplier := &mockProxyApplier{}
+			m, err := policies.NewManager(bus,
+				"hostname",
+				mockBackend{},
+				policies.WithCacheDir(filepath.Join(fakeRootDir, "var", "cache", "adsys")),
+				policies.WithStateDir(filepath.Join(fakeRootDir, "var", "lib", "adsys")),
+				policies.WithRunDir(filepath.Join(fakeRootDir, "run", "adsys")),
+				policies.WithShareDir(filepath.Join(fakeRootDir, "usr", "share", "adsys")),
+				policies.WithDconfDir(filepath.Join(fakeRootDir, "etc", "dconf")),
+				policies.WithPolicyKitDir(filepath.Join(fakeRootDir, "etc", "polkit-1")),
+				policies.WithSudoersDir(filepath.Join(fakeRootDir, "etc", "sudoers.d")),
+				policies.WithSecurityDir(filepath.Join(fakeRootDir, "etc", "security")),
+				policies.WithNftablesDir(filepath.Join(fakeRootDir, "etc", "nftables.d")),
+				policies.WithEnvironmentDir(filepath.Join(fakeRootDir, "etc", "environment.d")),
+				policies.WithProfileDir(filepath.Join(fakeRootDir, "etc", "profile.d")),
+				policies.WithApparmorDir(filepath.Join(fakeRootDir, "etc", "apparmor.d", "adsys")),
+				policies.WithApparmorFsDir(filepath.Dir(loadedPoliciesFile)),
+				policies.WithApparmorParserCmd([]string{"/bin/true"}),
+				policies.WithNftCmd([]string{"/bin/true"}),
+				policies.WithPackagesExecutor(testutils.MockPackagesExecutor{}),
+				policies.WithGroupFile(filepath.Join("testdata", "group")),
+				policies.WithGpasswdCmd([]string{"/bin/true"}),
+				policies.WithCertAutoenrollCmd([]string{"/bin/true"}),
+				policies.WithSystemUnitDir(filepath.Join(fakeRootDir, "etc", "systemd", "system")),
+				policies.WithProxyApplier(proxyApplier),
+				policies.WithSystemdCaller(&testutils.MockSystemdCaller{}),
+			)
+			require.NoError(t, err, "Setup: couldn’t get a new policy manager")
+
+			if tc.previousPoliciesDir != "" {
+				pols, err := policies.NewFromCache(context.Background(), filepath.Join("testdata", "cache", "policies", tc.previousPoliciesDir))
+				require.NoError(t, err, "Setup: can not load previous policies list")
+				err = m.ApplyPolicies(context.Background(), "hostname", true, &pols)
+				pols.Close()
+				require.NoError(t, err, "Setup: ApplyPolicies should return no error but got one")
+			}
+			// Only fail the new policy, so that restoring the previous proxy settings succeeds.
+			proxyApplier.failOnce = tc.proxyError
+
+			removeReports(t, filepath.Join(fakeRootDir, "var", "cache", "adsys"))
+			before := filesContent(t, fakeRootDir)
+
+			pols, err := policies.NewFromCache(context.Background(), filepath.Join("testdata", "cache", "policies", tc.policiesDir))
+			require.NoError(t, err, "Setup: can not load policies list")
+			defer pols.Close()
+
+			err = m.ApplyPolicies(context.Background(), "hostname", true, &pols)
+			require.Error(t, err, "ApplyPolicies should return an error but got none")
+
+			// Parent directories created by the policy managers are left behind, but are empty.
+			removeReports(t, filepath.Join(fakeRootDir, "var", "cache", "adsys"))
+			require.Equal(t, before, filesContent(t, fakeRootDir), "ApplyPolicies should restore the previous system state on failure")
+		})
+	}
+}
+
+func TestPlanPolicies(t *testing.T) {
+	//t.Parallel()
+
+	bus := testutils.NewDbusConn(t)
+
+	subscriptionDbus := bus.Object(consts.SubscriptionDbusRegisteredName,
+		dbus.ObjectPath(consts.SubscriptionDbusObjectPath))
+
+	tests := map[string]struct {
+		policiesDir     string
+		applyFirst      bool
+		planWithNoRules bool
+		isNotSubscribed bool
+
+		wantErr bool
+	}{
+		"Plan everything on a new system":                        {policiesDir: "all_entry_types"},
+		"Plan only scripts when policies are already applied":    {policiesDir: "all_entry_types", applyFirst: true},
+		"Plan removals when policies are applied and no rules":   {policiesDir: "all_entry_types", applyFirst: true, planWithNoRules: true},
+		"Plan nothing when no rules on a new system":             {policiesDir: "all_entry_types", planWithNoRules: true},
+		"No subscription only plans dconf content":               {policiesDir: "all_entry_types", isNotSubscribed: true},
+		"No subscription only plans dconf content after applied": {policiesDir: "all_entry_types", applyFirst: true, isNotSubscribed: true},
+
+		// Error cases
+		"Error when planning dconf policy": {policiesDir: "dconf_failing", wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			//t.Parallel()
+
+			pols, err := policies.NewFromCache(context.Background(), filepath.Join("testdata", "cache", "policies", tc.policiesDir))
+			require.NoError(t, err, "Setup: can not load policies list")
+			defer pols.Close()
+
+			fakeRootDir := t.TempDir()
+			cacheDir := filepath.Join(fakeRootDir, "var", "cache", "adsys")
+			runDir := filepath.Join(fakeRootDir, "run", "adsys")
+			loadedPoliciesFile := filepath.Join(fakeRootDir, "sys", "kernel", "security", "apparmor", "profiles")
+
+			err = os.MkdirAll(filepath.Dir(loadedPoliciesFile), 0700)
+			require.NoError(t, err, "Setup: can not create loadedPoliciesFile dir")
+			err = os.WriteFile(loadedPoliciesFile, []byte("someprofile (enforce)\n"), 0600)
+			require.NoError(t, err, "Setup: can not create loadedPoliciesFile")
+
+			require.NoError(t, subscriptionDbus.SetProperty(consts.SubscriptionDbusInterface+".Attached", !tc.isNotSubscribed), "Setup: can not set subscription status")
+			defer func() {
+				require.NoError(t, subscriptionDbus.SetProperty(consts.SubscriptionDbusInterface+".Attached", false), "Teardown: can not restore subscription status")
+			}()
+
+			m, err := policies.NewManager(bus,
+				"hostname",
+				mockBackend{},
+				policies.WithCacheDir(cacheDir),
+				policies.WithStateDir(filepath.Join(fakeRootDir, "var", "lib", "adsys")),
+				policies.WithRunDir(runDir),
+				policies.WithShareDir(filepath.Join(fakeRootDir, "usr", "share", "adsys")),
+				policies.WithDconfDir(filepath.Join(fakeRootDir, "etc", "dconf")),
+				policies.WithPolicyKitDir(filepath.Join(fakeRootDir, "etc", "polkit-1")),
+				policies.WithSudoersDir(filepath.Join(fakeRootDir, "etc", "sudoers.d")),
+				policies.WithSecurityDir(filepath.Join(fakeRootDir, "etc", "security")),
+				policies.WithNftablesDir(filepath.Join(fakeRootDir, "etc", "nftables.d")),
+				policies.WithEnvironmentDir(filepath.Join(fakeRootDir, "etc", "environment.d")),
+				policies.WithProfileDir(filepath.Join(fakeRootDir, "etc", "profile.d")),
+				policies.WithApparmorDir(filepath.Join(fakeRootDir, "etc", "apparmor.d", "adsys")),
+				policies.WithApparmorFsDir(filepath.Dir(loadedPoliciesFile)),
+				policies.WithApparmorParserCmd([]string{"/bin/true"}),
+				policies.WithNftCmd([]string{"/bin/true"}),
+				policies.WithPackagesExecutor(testutils.MockPackagesExecutor{}),
+				policies.WithGroupFile(filepath.Join("testdata", "group")),
+				policies.WithGpasswdCmd([]string{"/bin/true"}),
+				policies.WithCertAutoenrollCmd([]string{"/bin/true"}),
+				policies.WithSystemUnitDir(filepath.Join(fakeRootDir, "etc", "systemd", "system")),
+				policies.WithProxyApplier(&mockProxyApplier{}),
+				policies.WithSystemdCaller(&testutils.MockSystemdCaller{}),
+			)
+			require.NoError(t, err, "Setup: couldn’t get a new policy manager")
+
+			if tc.applyFirst {
+				err = m.ApplyPolicies(context.Background(), "hostname", true, &pols)
+				require.NoError(t, err, "Setup: ApplyPolicies should return no error but got one")
+			}
+			if tc.planWithNoRules {
+				pols, err = policies.New(context.Background(), nil, "")
+				require.NoError(t, err, "Setup: can not empty policies before planning")
+			}
+
+			before := treeContent(t, fakeRootDir)
+
+			got, err := m.PlanPolicies(context.Background(), "hostname", true, &pols)
+			if tc.wantErr {
+				require.Error(t, err, "PlanPolicies should return an error but got none")
+				return
+			}
+			require.NoError(t, err, "PlanPolicies should return no error but got one")
+
+			require.Equal(t, before, treeContent(t, fakeRootDir), "PlanPolicies should not modify the system")
+
+			got = strings.ReplaceAll(got, fakeRootDir, "#FAKEROOT#")
+			want := testutils.LoadWithUpdateFromGolden(t, got)
+			require.Equal(t, want, got, "PlanPolicies returned expected output")
+		})
+	}
+}
+
 func TestDumpPolicies(t *testing.T) {
 	t.Parallel()
 
@@ -254,6 +462,27 @@ func TestDumpPolicies(t *testing.T) {
 			withOverridden:     true,
 		},
 
+		// Skipped GPOs
+		"One GPO and one skipped GPO User": {
+			cachePoliciesUser: "one_gpo_with_skipped",
+		},
+		"Skipped GPOs User + Machine with rules": {
+			cachePoliciesUser:  "one_gpo_with_skipped",
+			cachePolicyMachine: "one_gpo_with_skipped",
+			withRules:          true,
+		},
+
+		// Loopback processing
+		"Loopback mode User + Machine": {
+			cachePoliciesUser:  "one_gpo",
+			cachePolicyMachine: "one_gpo_with_loopback",
+		},
+		"Loopback mode Machine": {
+			cachePolicyMachine: "one_gpo_with_loopback",
+			target:             hostname,
+			computerOnly:       true,
+		},
+
 		// Edge cases
 		"Same GPO Machine and User": {
 			cachePoliciesUser:  "one_gpo",
@@ -325,6 +554,90 @@ func TestDumpPolicies(t *testing.T) {
 	}
 }
 
+func TestAppliedPolicies(t *testing.T) {
+	t.Parallel()
+
+	bus := testutils.NewDbusConn(t)
+
+	hostname, err := os.Hostname()
+	require.NoError(t, err, "Setup: failed to get hostname")
+
+	tests := map[string]struct {
+		cachePoliciesUser  string
+		cachePolicyMachine string
+		target             string
+		computerOnly       bool
+
+		wantErr bool
+	}{
+		"One GPO User + Machine": {
+			cachePoliciesUser:  "one_gpo",
+			cachePolicyMachine: "one_gpo_other",
+		},
+		"Machine only GPO": {
+			cachePolicyMachine: "one_gpo",
+			target:             hostname,
+			computerOnly:       true,
+		},
+		"Multiple GPOs with overrides": {
+			cachePoliciesUser:  "two_gpos_with_overrides",
+			cachePolicyMachine: "one_gpo_other",
+		},
+		"Overrides between machine and user GPOs": {
+			cachePoliciesUser:  "one_gpo",
+			cachePolicyMachine: "two_gpos_override_one_gpo",
+		},
+
+		// Error cases
+		"Error on missing target cache": {
+			cachePolicyMachine: "one_gpo",
+			wantErr:            true,
+		},
+		"Error on missing machine cache when targeting user": {
+			cachePoliciesUser: "one_gpo",
+			wantErr:           true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			cacheDir, runDir := t.TempDir(), t.TempDir()
+			m, err := policies.NewManager(bus, hostname, mockBackend{}, policies.WithCacheDir(cacheDir), policies.WithRunDir(runDir))
+			require.NoError(t, err, "Setup: couldn’t get a new policy manager")
+
+			if tc.cachePoliciesUser != "" {
+				err := shutil.CopyTree(filepath.Join("testdata", "cache", "policies", tc.cachePoliciesUser), filepath.Join(cacheDir, policies.PoliciesCacheBaseName, "user"), nil)
+				require.NoError(t, err, "Setup: couldn’t copy user policies cache")
+			}
+			if tc.cachePolicyMachine != "" {
+				err := shutil.CopyTree(filepath.Join("testdata", "cache", "policies", tc.cachePolicyMachine), filepath.Join(cacheDir, policies.PoliciesCacheBaseName, hostname), nil)
+				require.NoError(t, err, "Setup: couldn’t copy machine policies cache")
+			}
+
+			if tc.target == "" {
+				tc.target = "user"
+			}
+			got, err := m.AppliedPolicies(context.Background(), tc.target, tc.computerOnly)
+			if tc.wantErr {
+				require.Error(t, err, "AppliedPolicies should return an error but got none")
+				return
+			}
+			require.NoError(t, err, "AppliedPolicies should return no error but got one")
+
+			// Update time is not reproducible
+			for i := range got {
+				require.False(t, got[i].LastUpdate.IsZero(), "AppliedPolicies should set the last update time of each GPO")
+				got[i].LastUpdate = time.Time{}
+			}
+
+			want := testutils.LoadWithUpdateFromGoldenYAML(t, got)
+			require.Equal(t, want, got, "AppliedPolicies returned expected GPOs")
+		})
+	}
+}
+
 func TestLastUpdateFor(t *testing.T) {
 	t.Parallel()
 
@@ -421,18 +734,72 @@ func TestGetSubscriptionState(t *testing.T) {
 	}
 }
 
+// treeContent returns the content of each file and directory under root, indexed by its path.
+func treeContent(t *testing.T, root string) map[string]string {
+	t.Helper()
+
+	r := make(map[string]string)
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			r[path] = info.Mode().String()
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		r[path] = info.Mode().String() + " " + string(content)
+		return nil
+	})
+	require.NoError(t, err, "Setup: can't read tree content")
+
+	return r
+}
+
+// filesContent returns the content of each file under root, indexed by its path.
+func filesContent(t *testing.T, root string) map[string]string {
+	t.Helper()
+
+	r := treeContent(t, root)
+	for p, content := range r {
+		if strings.HasPrefix(content, "d") {
+			delete(r, p)
+		}
+	}
+
+	return r
+}
+
+// removeReports removes the policies reports of cacheDir, as their timings are not reproducible.
+// Reports are checked in TestReport.
+func removeReports(t *testing.T, cacheDir string) {
+	t.Helper()
+
+	require.NoError(t, os.RemoveAll(filepath.Join(cacheDir, policies.ReportsCacheBaseName)), "Setup: can't remove policies reports")
+}
+
 // mockProxyApplier is a mock for the proxy apply object.
 type mockProxyApplier struct {
 	wantApplyError bool
+	// failOnce makes only the next apply call fail.
+	failOnce bool
 }
 
 // Call mocks the proxy apply call.
 func (d *mockProxyApplier) Call(_ string, _ dbus.Flags, _ ...interface{}) *dbus.Call {
 	var errApply error
 
-	if d.wantApplyError {
+	if d.wantApplyError || d.failOnce {
 		errApply = errors.New("proxy apply error")
 	}
+	d.failOnce = false
 
 	return &dbus.Call{Err: errApply}
 }
@@ -0,0 +1,224 @@
+package policies
+
+import (
+	"context"
+
+	"github.com/ubuntu/adsys/internal/ad/backends"
+	"github.com/ubuntu/adsys/internal/policies/apparmor"
+	"github.com/ubuntu/adsys/internal/policies/certificate"
+	"github.com/ubuntu/adsys/internal/policies/dconf"
+	"github.com/ubuntu/adsys/internal/policies/environment"
+	"github.com/ubuntu/adsys/internal/policies/firewall"
+	"github.com/ubuntu/adsys/internal/policies/gdm"
+	"github.com/ubuntu/adsys/internal/policies/localgroups"
+	"github.com/ubuntu/adsys/internal/policies/mount"
+	"github.com/ubuntu/adsys/internal/policies/packages"
+	"github.com/ubuntu/adsys/internal/policies/plan"
+	"github.com/ubuntu/adsys/internal/policies/plugin"
+	"github.com/ubuntu/adsys/internal/policies/privilege"
+	"github.com/ubuntu/adsys/internal/policies/proxy"
+	"github.com/ubuntu/adsys/internal/policies/scripts"
+	"github.com/ubuntu/adsys/internal/policies/systemaccess"
+	"github.com/ubuntu/adsys/internal/policies/transaction"
+	"github.com/ubuntu/adsys/internal/policies/units"
+)
+
+// This file adapts the built-in policy managers to the PolicyManager interface.
+
+type dconfHandler struct{ m *dconf.Manager }
+
+func (d dconfHandler) Prepare(ctx context.Context, req Request, tx *transaction.Transaction) error {
+	return d.m.Prepare(ctx, req.ObjectName, req.IsComputer, tx)
+}
+func (d dconfHandler) ApplyPolicy(ctx context.Context, req Request) error {
+	return d.m.ApplyPolicy(ctx, req.ObjectName, req.IsComputer, req.Entries)
+}
+func (d dconfHandler) Plan(ctx context.Context, req Request) ([]plan.Change, error) {
+	return d.m.Plan(ctx, req.ObjectName, req.IsComputer, req.Entries)
+}
+
+type privilegeHandler struct{ m *privilege.Manager }
+
+func (p privilegeHandler) Prepare(ctx context.Context, req Request, tx *transaction.Transaction) error {
+	return p.m.Prepare(ctx, req.ObjectName, req.IsComputer, tx)
+}
+func (p privilegeHandler) ApplyPolicy(ctx context.Context, req Request) error {
+	return p.m.ApplyPolicy(ctx, req.ObjectName, req.IsComputer, req.Entries)
+}
+func (p privilegeHandler) Plan(ctx context.Context, req Request) ([]plan.Change, error) {
+	return p.m.Plan(ctx, req.ObjectName, req.IsComputer, req.Entries)
+}
+
+type scriptsHandler struct{ m *scripts.Manager }
+
+func (s scriptsHandler) Prepare(ctx context.Context, req Request, tx *transaction.Transaction) error {
+	return s.m.Prepare(ctx, req.ObjectName, req.IsComputer, tx)
+}
+func (s scriptsHandler) ApplyPolicy(ctx context.Context, req Request) error {
+	return s.m.ApplyPolicy(ctx, req.ObjectName, req.IsComputer, req.Entries, scripts.AssetsDumper(req.SaveAssetsTo))
+}
+func (s scriptsHandler) Plan(ctx context.Context, req Request) ([]plan.Change, error) {
+	return s.m.Plan(ctx, req.ObjectName, req.IsComputer, req.Entries, scripts.AssetsDumper(req.SaveAssetsTo))
+}
+func (s scriptsHandler) Verify(ctx context.Context, req Request) ([]plan.Change, error) {
+	return s.m.Verify(ctx, req.ObjectName, req.IsComputer, req.Entries, scripts.AssetsDumper(req.SaveAssetsTo))
+}
+
+type mountHandler struct{ m *mount.Manager }
+
+func (mo mountHandler) Prepare(ctx context.Context, req Request, tx *transaction.Transaction) error {
+	return mo.m.Prepare(ctx, req.ObjectName, req.IsComputer, tx)
+}
+func (mo mountHandler) ApplyPolicy(ctx context.Context, req Request) error {
+	return mo.m.ApplyPolicy(ctx, req.ObjectName, req.IsComputer, req.Entries)
+}
+func (mo mountHandler) Plan(ctx context.Context, req Request) ([]plan.Change, error) {
+	return mo.m.Plan(ctx, req.ObjectName, req.IsComputer, req.Entries)
+}
+
+type apparmorHandler struct{ m *apparmor.Manager }
+
+func (a apparmorHandler) Prepare(ctx context.Context, req Request, tx *transaction.Transaction) error {
+	return a.m.Prepare(ctx, req.ObjectName, req.IsComputer, tx)
+}
+func (a apparmorHandler) ApplyPolicy(ctx context.Context, req Request) error {
+	return a.m.ApplyPolicy(ctx, req.ObjectName, req.IsComputer, req.Entries, apparmor.AssetsDumper(req.SaveAssetsTo))
+}
+func (a apparmorHandler) Plan(ctx context.Context, req Request) ([]plan.Change, error) {
+	return a.m.Plan(ctx, req.ObjectName, req.IsComputer, req.Entries, apparmor.AssetsDumper(req.SaveAssetsTo))
+}
+func (a apparmorHandler) Verify(ctx context.Context, req Request) ([]plan.Change, error) {
+	return a.m.Verify(ctx, req.ObjectName, req.IsComputer, req.Entries, apparmor.AssetsDumper(req.SaveAssetsTo))
+}
+
+type proxyHandler struct{ m *proxy.Manager }
+
+func (p proxyHandler) Prepare(ctx context.Context, req Request, tx *transaction.Transaction) error {
+	// Proxy settings are not stored by us: we need the previously applied rules to restore them.
+	return p.m.Prepare(ctx, req.ObjectName, req.IsComputer, req.Previous, tx)
+}
+func (p proxyHandler) ApplyPolicy(ctx context.Context, req Request) error {
+	return p.m.ApplyPolicy(ctx, req.ObjectName, req.IsComputer, req.Entries)
+}
+func (p proxyHandler) Plan(ctx context.Context, req Request) ([]plan.Change, error) {
+	return p.m.Plan(ctx, req.ObjectName, req.IsComputer, req.Entries)
+}
+
+type systemAccessHandler struct{ m *systemaccess.Manager }
+
+func (s systemAccessHandler) Prepare(ctx context.Context, req Request, tx *transaction.Transaction) error {
+	return s.m.Prepare(ctx, req.ObjectName, req.IsComputer, tx)
+}
+func (s systemAccessHandler) ApplyPolicy(ctx context.Context, req Request) error {
+	return s.m.ApplyPolicy(ctx, req.ObjectName, req.IsComputer, req.Entries)
+}
+func (s systemAccessHandler) Plan(ctx context.Context, req Request) ([]plan.Change, error) {
+	return s.m.Plan(ctx, req.ObjectName, req.IsComputer, req.Entries)
+}
+
+type firewallHandler struct{ m *firewall.Manager }
+
+func (f firewallHandler) Prepare(ctx context.Context, req Request, tx *transaction.Transaction) error {
+	return f.m.Prepare(ctx, req.ObjectName, req.IsComputer, tx)
+}
+func (f firewallHandler) ApplyPolicy(ctx context.Context, req Request) error {
+	return f.m.ApplyPolicy(ctx, req.ObjectName, req.IsComputer, req.Entries)
+}
+func (f firewallHandler) Plan(ctx context.Context, req Request) ([]plan.Change, error) {
+	return f.m.Plan(ctx, req.ObjectName, req.IsComputer, req.Entries)
+}
+
+type packagesHandler struct{ m *packages.Manager }
+
+func (p packagesHandler) Prepare(ctx context.Context, req Request, tx *transaction.Transaction) error {
+	return p.m.Prepare(ctx, req.ObjectName, req.IsComputer, tx)
+}
+func (p packagesHandler) ApplyPolicy(ctx context.Context, req Request) error {
+	return p.m.ApplyPolicy(ctx, req.ObjectName, req.IsComputer, req.Entries)
+}
+func (p packagesHandler) Plan(ctx context.Context, req Request) ([]plan.Change, error) {
+	return p.m.Plan(ctx, req.ObjectName, req.IsComputer, req.Entries)
+}
+func (p packagesHandler) Details(ctx context.Context, req Request) []string {
+	return p.m.Details(ctx, req.ObjectName, req.IsComputer)
+}
+
+type localGroupsHandler struct{ m *localgroups.Manager }
+
+func (l localGroupsHandler) Prepare(ctx context.Context, req Request, tx *transaction.Transaction) error {
+	return l.m.Prepare(ctx, req.ObjectName, req.IsComputer, tx)
+}
+func (l localGroupsHandler) ApplyPolicy(ctx context.Context, req Request) error {
+	return l.m.ApplyPolicy(ctx, req.ObjectName, req.IsComputer, req.Entries)
+}
+func (l localGroupsHandler) Plan(ctx context.Context, req Request) ([]plan.Change, error) {
+	return l.m.Plan(ctx, req.ObjectName, req.IsComputer, req.Entries)
+}
+
+type unitsHandler struct{ m *units.Manager }
+
+func (u unitsHandler) Prepare(ctx context.Context, req Request, tx *transaction.Transaction) error {
+	return u.m.Prepare(ctx, req.ObjectName, req.IsComputer, tx)
+}
+func (u unitsHandler) ApplyPolicy(ctx context.Context, req Request) error {
+	return u.m.ApplyPolicy(ctx, req.ObjectName, req.IsComputer, req.Entries)
+}
+func (u unitsHandler) Plan(ctx context.Context, req Request) ([]plan.Change, error) {
+	return u.m.Plan(ctx, req.ObjectName, req.IsComputer, req.Entries)
+}
+
+type environmentHandler struct{ m *environment.Manager }
+
+func (e environmentHandler) Prepare(ctx context.Context, req Request, tx *transaction.Transaction) error {
+	return e.m.Prepare(ctx, req.ObjectName, req.IsComputer, tx)
+}
+func (e environmentHandler) ApplyPolicy(ctx context.Context, req Request) error {
+	return e.m.ApplyPolicy(ctx, req.ObjectName, req.IsComputer, req.Entries)
+}
+func (e environmentHandler) Plan(ctx context.Context, req Request) ([]plan.Change, error) {
+	return e.m.Plan(ctx, req.ObjectName, req.IsComputer, req.Entries)
+}
+
+type certificateHandler struct {
+	m       *certificate.Manager
+	backend backends.Backend
+}
+
+func (c certificateHandler) Prepare(ctx context.Context, req Request, tx *transaction.Transaction) error {
+	return c.m.Prepare(ctx, req.ObjectName, req.IsComputer, tx)
+}
+func (c certificateHandler) ApplyPolicy(ctx context.Context, req Request) error {
+	// Ignore error as we don't want to fail because of online status this late in the process
+	isOnline, _ := c.backend.IsOnline()
+	return c.m.ApplyPolicy(ctx, req.ObjectName, req.IsComputer, isOnline, req.Entries)
+}
+func (c certificateHandler) Plan(ctx context.Context, req Request) ([]plan.Change, error) {
+	// Ignore error as we don't want to fail because of online status
+	isOnline, _ := c.backend.IsOnline()
+	return c.m.Plan(ctx, req.ObjectName, req.IsComputer, isOnline, req.Entries)
+}
+
+// gdmHandler applies the gdm rules, which are always machine ones.
+type gdmHandler struct{ m *gdm.Manager }
+
+func (g gdmHandler) Prepare(ctx context.Context, _ Request, tx *transaction.Transaction) error {
+	return g.m.Prepare(ctx, tx)
+}
+func (g gdmHandler) ApplyPolicy(ctx context.Context, req Request) error {
+	return g.m.ApplyPolicy(ctx, req.Entries)
+}
+func (g gdmHandler) Plan(ctx context.Context, req Request) ([]plan.Change, error) {
+	return g.m.Plan(ctx, req.Entries)
+}
+
+type pluginHandler struct{ m *plugin.Manager }
+
+func (p pluginHandler) Prepare(ctx context.Context, req Request, tx *transaction.Transaction) error {
+	return p.m.Prepare(ctx, req.ObjectName, req.IsComputer, req.Previous, tx)
+}
+func (p pluginHandler) ApplyPolicy(ctx context.Context, req Request) error {
+	return p.m.ApplyPolicy(ctx, req.ObjectName, req.IsComputer, req.Entries)
+}
+func (p pluginHandler) Plan(ctx context.Context, req Request) ([]plan.Change, error) {
+	return p.m.Plan(ctx, req.ObjectName, req.IsComputer, req.Entries)
+}
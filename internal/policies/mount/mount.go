@@ -29,6 +29,8 @@ import (
 	"github.com/leonelquinteros/gotext"
 	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
 	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/adsys/internal/policies/plan"
+	"github.com/ubuntu/adsys/internal/policies/transaction"
 	"github.com/ubuntu/decorate"
 )
 
@@ -244,6 +246,91 @@ func (m *Manager) applySystemMountsPolicy(ctx context.Context, machineName strin
 	return nil
 }
 
+// Plan returns the changes ApplyPolicy would make for mount policies, without applying them.
+func (m *Manager) Plan(ctx context.Context, objectName string, isComputer bool, entries []entry.Entry) (changes []plan.Change, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't plan mount policy for %s", objectName))
+
+	log.Debugf(ctx, "Planning mount policy for %s", objectName)
+
+	key := "user"
+	if isComputer {
+		key = "system"
+	}
+
+	var parsedValues []string
+	i := slices.IndexFunc(entries, func(e entry.Entry) bool {
+		return e.Key == key+"-mounts"
+	})
+	if i != -1 && !entries[i].Disabled {
+		if parsedValues, err = parseEntryValues(ctx, entries[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	if !isComputer {
+		u, err := m.userLookup(objectName)
+		if err != nil {
+			return nil, errors.New(gotext.Get("could not retrieve user for %q: %v", objectName, err))
+		}
+		mountsPath := filepath.Join(m.runDir, "users", u.Uid, "mounts")
+
+		s := strings.Join(parsedValues, "\n")
+		if s == "" {
+			if c, ok := plan.Removal(mountsPath); ok {
+				changes = append(changes, c)
+			}
+			return changes, nil
+		}
+		if c, ok := plan.File(mountsPath, s+"\n"); ok {
+			changes = append(changes, c)
+		}
+		return changes, nil
+	}
+
+	newUnits := createUnits(parsedValues)
+	prevUnits := m.currentSystemMountUnits()
+	for name := range newUnits {
+		delete(prevUnits, name)
+	}
+
+	var unitsToClean, unitsToEnable []string
+	for name := range prevUnits {
+		unitsToClean = append(unitsToClean, name)
+	}
+	for name := range newUnits {
+		unitsToEnable = append(unitsToEnable, name)
+	}
+	slices.Sort(unitsToClean)
+	slices.Sort(unitsToEnable)
+
+	for _, name := range unitsToClean {
+		changes = append(changes,
+			plan.Call("systemctl", "stop", name),
+			plan.Call("systemctl", "disable", name),
+			plan.Change{Action: plan.Remove, Target: filepath.Join(m.systemUnitDir, name)})
+	}
+
+	var written []string
+	for _, name := range unitsToEnable {
+		c, ok := plan.File(filepath.Join(m.systemUnitDir, name), newUnits[name])
+		if !ok {
+			continue
+		}
+		changes = append(changes, c)
+		written = append(written, name)
+	}
+	if len(written) == 0 {
+		return changes, nil
+	}
+
+	changes = append(changes, plan.Call("systemctl", "daemon-reload"))
+	for _, name := range written {
+		changes = append(changes, plan.Call("systemctl", "enable", name), plan.Call("systemctl", "start", name))
+	}
+
+	return changes, nil
+}
+
 // mountInfo stores relevant information about a mount.
 type mountInfo struct {
 	hostname   string
@@ -252,6 +339,67 @@ type mountInfo struct {
 	options    []string
 }
 
+// Prepare backs up the mounts file of the user or the mount units of the machine into tx, so that they
+// can be restored if applying the policies fails.
+func (m *Manager) Prepare(ctx context.Context, objectName string, isComputer bool, tx *transaction.Transaction) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't prepare mount policy for %s", objectName))
+
+	log.Debugf(ctx, "Preparing mount policy for %s", objectName)
+
+	if !isComputer {
+		u, err := m.userLookup(objectName)
+		if err != nil {
+			return errors.New(gotext.Get("could not retrieve user for %q: %v", objectName, err))
+		}
+		return tx.Backup(filepath.Join(m.runDir, "users", u.Uid, "mounts"))
+	}
+
+	prevUnits := m.currentSystemMountUnits()
+
+	// Once restored, enable and start again the previous units.
+	tx.OnAbort(func(ctx context.Context) error {
+		if err := m.systemdCaller.DaemonReload(ctx); err != nil {
+			return err
+		}
+		var units []string
+		for name := range prevUnits {
+			units = append(units, name)
+		}
+		slices.Sort(units)
+		for _, name := range units {
+			if err := m.systemdCaller.EnableUnit(ctx, name); err != nil {
+				return err
+			}
+			if err := m.systemdCaller.StartUnit(ctx, name); err != nil {
+				log.Warning(ctx, gotext.Get("failed to start unit %q: %v", name, err))
+			}
+		}
+		return nil
+	})
+
+	if err := tx.BackupMatching(m.systemUnitDir, "adsys-*.mount"); err != nil {
+		return err
+	}
+
+	// Before restoring the unit files, stop and disable the units which were added.
+	tx.OnAbort(func(ctx context.Context) error {
+		for name := range m.currentSystemMountUnits() {
+			if _, ok := prevUnits[name]; ok {
+				continue
+			}
+			if err := m.systemdCaller.StopUnit(ctx, name); err != nil {
+				log.Warning(ctx, gotext.Get("Failed to stop unit %q: %v", name, err))
+			}
+			if err := m.systemdCaller.DisableUnit(ctx, name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return nil
+}
+
 // createUnits formats the adsys-.mount template with the specified paths.
 func createUnits(mountPaths []string) map[string]string {
 	units := make(map[string]string)
@@ -0,0 +1,86 @@
+package packages
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/ubuntu/adsys/internal/smbsafe"
+)
+
+// defaultExecutor queries the packages of the system with dpkg, apt and snapd.
+type defaultExecutor struct{}
+
+// InstalledDebs returns the names of the deb packages dpkg reports as installed.
+// Packages of the native architecture are listed with and without their architecture qualifier, and foreign ones
+// only with it, like apt resolves names.
+func (defaultExecutor) InstalledDebs(ctx context.Context) (debs []string, err error) {
+	arch, err := run(ctx, "dpkg", "--print-architecture")
+	if err != nil {
+		return nil, err
+	}
+	out, err := run(ctx, "dpkg-query", "-W", "-f", "${db:Status-Abbrev} ${Package} ${Architecture}\n")
+	if err != nil {
+		return nil, err
+	}
+	return parseInstalledDebs(out, strings.TrimSpace(string(arch))), nil
+}
+
+// parseInstalledDebs returns the names of the installed debs from the output of dpkg-query, for the native
+// architecture arch.
+func parseInstalledDebs(out []byte, arch string) (debs []string) {
+	for _, l := range lines(out) {
+		fields := strings.Fields(l)
+		// The second letter of the status is the current state of the package.
+		if len(fields) != 3 || len(fields[0]) < 2 || fields[0][1] != 'i' {
+			continue
+		}
+		name, pkgArch := fields[1], fields[2]
+		debs = append(debs, name+":"+pkgArch)
+		if pkgArch == arch || pkgArch == "all" {
+			debs = append(debs, name)
+		}
+	}
+	return debs
+}
+
+// HeldDebs returns the names of the deb packages apt reports as held.
+func (defaultExecutor) HeldDebs(ctx context.Context) ([]string, error) {
+	out, err := run(ctx, "apt-mark", "showhold")
+	if err != nil {
+		return nil, err
+	}
+	return lines(out), nil
+}
+
+// InstalledSnaps returns the names of the snaps installed, as listed by snap.
+func (defaultExecutor) InstalledSnaps(ctx context.Context) (snaps []string, err error) {
+	out, err := run(ctx, "snap", "list", "--unicode=never")
+	if err != nil {
+		return nil, err
+	}
+	// The first line is the header of the table.
+	for i, l := range lines(out) {
+		if i == 0 {
+			continue
+		}
+		snaps = append(snaps, strings.Fields(l)[0])
+	}
+	return snaps, nil
+}
+
+// run runs the command name with args and returns its standard output.
+func run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	// #nosec G204 - We are in control of the arguments
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	smbsafe.WaitExec()
+	out, err := cmd.Output()
+	smbsafe.DoneExec()
+	if err != nil {
+		return nil, fmt.Errorf("%w\n%s", err, stderr.String())
+	}
+	return out, nil
+}
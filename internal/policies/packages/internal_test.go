@@ -0,0 +1,23 @@
+package packages
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInstalledDebs(t *testing.T) {
+	t.Parallel()
+
+	out := []byte(`ii  curl amd64
+ii  tzdata all
+ii  libfoo i386
+rc  telnet amd64
+un  vim amd64
+malformed line
+`)
+
+	got := parseInstalledDebs(out, "amd64")
+	require.Equal(t, []string{"curl:amd64", "curl", "tzdata:all", "tzdata", "libfoo:i386"}, got,
+		"Native and architecture independent debs should be listed with and without qualifier, foreign ones only with it")
+}
@@ -0,0 +1,507 @@
+// Package packages is the policy manager for the deb and snap packages of the machine.
+//
+// The policy lists, one package name per line, the debs and snaps to ensure installed or absent, and the debs to
+// hold. Each application compares those lists to the packages installed on the system and only queues the
+// operations needed to reach the requested state:
+//   - debs and snaps to install which are not installed yet are installed;
+//   - debs and snaps to remove which are installed are removed;
+//   - debs to hold which are not held yet are held, and debs held by a previous policy which are not listed anymore
+//     are unheld. Only the debs held by adsys are owned by the policy: they are tracked in the state directory once
+//     their operations are queued, until they are unheld. Debs already held locally are left untouched.
+//
+// Packages which are not listed anymore are left as is. A package listed both to install and to remove is ignored.
+//
+// The operations are written to a script run in the background in a transient systemd unit, so that applying the
+// policy doesn't block the boot or user logins while packages are downloaded. The outcome of each operation of the
+// last run is recorded and displayed in the policies report. If a previous run is still in progress, the operations
+// are queued again on next policies refresh.
+// The packages policy is only applied on computers.
+package packages
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/leonelquinteros/gotext"
+	"github.com/ubuntu/adsys/internal/consts"
+	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
+	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/adsys/internal/policies/plan"
+	"github.com/ubuntu/adsys/internal/policies/transaction"
+	"github.com/ubuntu/decorate"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// packagesDirName is the directory, in the state directory, where the packages manager keeps its files.
+	packagesDirName = "packages"
+
+	scriptName  = "run"
+	resultsName = "results"
+	heldName    = "held"
+	lockName    = ".lock"
+
+	header = `#!/bin/sh
+# This file is managed by adsys.
+# Do not edit this file manually.
+# Any changes will be overwritten.
+
+`
+)
+
+var (
+	// debRe matches valid deb package names, with an optional architecture qualifier.
+	debRe = regexp.MustCompile(`^[a-z0-9][a-z0-9+.-]+(:[a-z0-9]+)?$`)
+	// snapRe matches valid snap names.
+	snapRe = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+)
+
+// Executor queries the state of the packages of the system.
+type Executor interface {
+	// InstalledDebs returns the names of the installed deb packages, qualified with their architecture, and also
+	// unqualified for the native ones.
+	InstalledDebs(ctx context.Context) ([]string, error)
+	// HeldDebs returns the names of the held deb packages.
+	HeldDebs(ctx context.Context) ([]string, error)
+	// InstalledSnaps returns the names of the installed snaps.
+	InstalledSnaps(ctx context.Context) ([]string, error)
+}
+
+type unitStarter interface {
+	StartTransientUnit(ctx context.Context, unit, description string, cmd []string) error
+}
+
+// Manager prevents running multiple packages updates in parallel while applying the packages policy.
+type Manager struct {
+	packagesDir string
+	unitStarter unitStarter
+	executor    Executor
+
+	// queued are the operations started in the background during the last application.
+	queued []string
+
+	mu sync.Mutex
+}
+
+type options struct {
+	executor Executor
+}
+
+// Option reprents an optional function to change the packages manager.
+type Option func(*options)
+
+// WithExecutor overrides the default executor querying dpkg, apt and snapd.
+func WithExecutor(e Executor) Option {
+	return func(o *options) {
+		o.executor = e
+	}
+}
+
+// New creates a manager keeping its state in stateDir and running the packages operations with unitStarter.
+func New(stateDir string, unitStarter unitStarter, opts ...Option) *Manager {
+	// defaults
+	args := options{
+		executor: defaultExecutor{},
+	}
+	// applied options
+	for _, o := range opts {
+		o(&args)
+	}
+
+	if stateDir == "" {
+		stateDir = consts.DefaultStateDir
+	}
+
+	return &Manager{
+		packagesDir: filepath.Join(stateDir, packagesDirName),
+		unitStarter: unitStarter,
+		executor:    args.executor,
+	}
+}
+
+// operation is a package operation run in the background.
+type operation struct {
+	action string
+	snap   bool
+	name   string
+}
+
+// cmd returns the command running the operation.
+func (o operation) cmd() []string {
+	if o.snap {
+		return []string{"snap", o.action, o.name}
+	}
+	switch o.action {
+	case "hold", "unhold":
+		return []string{"apt-mark", o.action, o.name}
+	default:
+		return []string{"apt-get", o.action, "-y", "-q", "-o", "Dpkg::Options::=--force-confold", o.name}
+	}
+}
+
+// ApplyPolicy queues the packages operations needed to reach the state requested by entries, and starts them in
+// the background. Steps are:
+// 1.  Compare the requested packages to the ones installed and held on the system
+// 2.  Write the operations to a script, unless a previous run is still in progress
+// 3.  Start the script in a transient unit
+// 4.  Track the debs held by the policy, including the ones to hold if their operations were queued.
+func (m *Manager) ApplyPolicy(ctx context.Context, objectName string, isComputer bool, entries []entry.Entry) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't apply packages policy to %s", objectName))
+
+	// Packages policies are only set on computers.
+	if !isComputer {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.queued = nil
+
+	log.Debugf(ctx, "Applying packages policy to %s", objectName)
+
+	ops, held, err := m.operations(ctx, entries)
+	if err != nil {
+		return err
+	}
+
+	if err := m.queue(ctx, ops); err != nil {
+		// Always save the debs we hold, so that we can unhold them later.
+		return errors.Join(err, m.saveHeld(held))
+	}
+
+	// Debs to hold are only owned once their operations are queued.
+	if m.queued != nil {
+		for _, o := range ops {
+			if !o.snap && o.action == "hold" {
+				held = append(held, o.name)
+			}
+		}
+	}
+	return m.saveHeld(held)
+}
+
+// queue writes ops to the packages script and starts it in a transient unit, unless a previous run is still
+// in progress. The queued operations are recorded for the details of the application.
+func (m *Manager) queue(ctx context.Context, ops []operation) error {
+	scriptPath := filepath.Join(m.packagesDir, scriptName)
+	if len(ops) == 0 {
+		return removeFile(scriptPath)
+	}
+
+	if err := os.MkdirAll(m.packagesDir, 0700); err != nil {
+		return err
+	}
+
+	running, err := m.running()
+	if err != nil {
+		return err
+	}
+	if running {
+		log.Warning(ctx, gotext.Get("Previous packages operations are still running, they will be checked again on next refresh"))
+		return nil
+	}
+
+	if err := writeFile(scriptPath, script(ops), 0700); err != nil {
+		return err
+	}
+
+	log.Infof(ctx, "Running %d packages operations in the background", len(ops))
+	if err := m.unitStarter.StartTransientUnit(ctx, consts.AdsysMachinePackagesServiceName,
+		gotext.Get("ADSys machine packages operations"), []string{"/bin/sh", scriptPath}); err != nil {
+		return err
+	}
+	for _, o := range ops {
+		m.queued = append(m.queued, strings.Join(o.cmd(), " "))
+	}
+	return nil
+}
+
+// saveHeld saves the debs held by the policy, removing the tracking file if there are none.
+func (m *Manager) saveHeld(held []string) error {
+	heldPath := filepath.Join(m.packagesDir, heldName)
+	if len(held) == 0 {
+		return removeFile(heldPath)
+	}
+
+	slices.Sort(held)
+	if err := os.MkdirAll(m.packagesDir, 0700); err != nil {
+		return err
+	}
+	return writeFile(heldPath, strings.Join(held, "\n")+"\n", 0600)
+}
+
+// Plan returns the changes ApplyPolicy would make for a packages policy, without applying them.
+// Each packages operation is planned as a call.
+func (m *Manager) Plan(ctx context.Context, objectName string, isComputer bool, entries []entry.Entry) (changes []plan.Change, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't plan packages policy for %s", objectName))
+
+	// Packages policies are only set on computers.
+	if !isComputer {
+		return nil, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ops, _, err := m.operations(ctx, entries)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range ops {
+		cmd := o.cmd()
+		changes = append(changes, plan.Call(cmd[0], cmd[1:]...))
+	}
+	return changes, nil
+}
+
+// Prepare backs up the debs held by the policy and the packages script into tx, so that they can be restored if
+// applying the policies fails. Packages operations already started can't be rolled back.
+func (m *Manager) Prepare(ctx context.Context, objectName string, isComputer bool, tx *transaction.Transaction) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't prepare packages policy for %s", objectName))
+
+	// Packages policies are only set on computers.
+	if !isComputer {
+		return nil
+	}
+
+	log.Debugf(ctx, "Preparing packages policy for %s", objectName)
+
+	for _, f := range []string{heldName, scriptName} {
+		if err := tx.Backup(filepath.Join(m.packagesDir, f)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Details returns the outcome of the operations of the last background run, and the operations started during
+// the last application.
+func (m *Manager) Details(_ context.Context, _ string, isComputer bool) (details []string) {
+	if !isComputer {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	d, err := os.ReadFile(filepath.Join(m.packagesDir, resultsName))
+	if err == nil {
+		for _, l := range strings.Split(strings.TrimSpace(string(d)), "\n") {
+			if l == "" {
+				continue
+			}
+			details = append(details, gotext.Get("last run: %s", l))
+		}
+	}
+	for _, q := range m.queued {
+		details = append(details, gotext.Get("started: %s", q))
+	}
+	return details
+}
+
+// operations returns the packages operations needed to reach the state requested by entries, and the debs
+// held by the policy which are still held. The debs to hold are not part of them.
+func (m *Manager) operations(ctx context.Context, entries []entry.Entry) (ops []operation, held []string, err error) {
+	requested := make(map[string][]string)
+	for _, e := range entries {
+		if e.Disabled {
+			continue
+		}
+
+		key := e.Key[strings.LastIndex(e.Key, "/")+1:]
+		re := debRe
+		switch key {
+		case "install-debs", "remove-debs", "hold-debs":
+		case "install-snaps", "remove-snaps":
+			re = snapRe
+		default:
+			log.Warningf(ctx, "Ignoring unsupported packages setting %s", e.Key)
+			continue
+		}
+		for _, name := range strings.Split(e.Value, "\n") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			if !re.MatchString(name) {
+				log.Warningf(ctx, "Ignoring invalid package name %q in %s", name, e.Key)
+				continue
+			}
+			if slices.Contains(requested[key], name) {
+				continue
+			}
+			requested[key] = append(requested[key], name)
+		}
+	}
+
+	for _, kind := range []string{"debs", "snaps"} {
+		for _, name := range requested["install-"+kind] {
+			if !slices.Contains(requested["remove-"+kind], name) {
+				continue
+			}
+			log.Warningf(ctx, "Ignoring package %q which is requested to be both installed and removed", name)
+			requested["install-"+kind] = slices.DeleteFunc(requested["install-"+kind], func(n string) bool { return n == name })
+			requested["remove-"+kind] = slices.DeleteFunc(requested["remove-"+kind], func(n string) bool { return n == name })
+		}
+	}
+
+	// Debs held by a previous policy.
+	var previouslyHeld []string
+	d, err := os.ReadFile(filepath.Join(m.packagesDir, heldName))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, err
+	}
+	for _, name := range strings.Split(string(d), "\n") {
+		if name != "" {
+			previouslyHeld = append(previouslyHeld, name)
+		}
+	}
+
+	if len(requested["install-debs"])+len(requested["remove-debs"]) > 0 {
+		installed, err := m.executor.InstalledDebs(ctx)
+		if err != nil {
+			return nil, nil, errors.New(gotext.Get("can't list installed debs: %v", err))
+		}
+		for _, name := range requested["install-debs"] {
+			if !slices.Contains(installed, name) {
+				ops = append(ops, operation{action: "install", name: name})
+			}
+		}
+		for _, name := range requested["remove-debs"] {
+			if slices.Contains(installed, name) {
+				ops = append(ops, operation{action: "remove", name: name})
+			}
+		}
+	}
+
+	if len(requested["hold-debs"])+len(previouslyHeld) > 0 {
+		currentlyHeld, err := m.executor.HeldDebs(ctx)
+		if err != nil {
+			return nil, nil, errors.New(gotext.Get("can't list held debs: %v", err))
+		}
+		for _, name := range requested["hold-debs"] {
+			if !slices.Contains(currentlyHeld, name) {
+				ops = append(ops, operation{action: "hold", name: name})
+			}
+		}
+		// Only keep ownership of debs we held which are still held: local holds are left untouched.
+		// Debs to unhold are kept until they are unheld, so that failed operations are retried.
+		for _, name := range previouslyHeld {
+			if !slices.Contains(currentlyHeld, name) {
+				continue
+			}
+			held = append(held, name)
+			if !slices.Contains(requested["hold-debs"], name) {
+				ops = append(ops, operation{action: "unhold", name: name})
+			}
+		}
+	}
+
+	if len(requested["install-snaps"])+len(requested["remove-snaps"]) > 0 {
+		installed, err := m.executor.InstalledSnaps(ctx)
+		if err != nil {
+			return nil, nil, errors.New(gotext.Get("can't list installed snaps: %v", err))
+		}
+		for _, name := range requested["install-snaps"] {
+			if !slices.Contains(installed, name) {
+				ops = append(ops, operation{action: "install", snap: true, name: name})
+			}
+		}
+		for _, name := range requested["remove-snaps"] {
+			if slices.Contains(installed, name) {
+				ops = append(ops, operation{action: "remove", snap: true, name: name})
+			}
+		}
+	}
+
+	return ops, held, nil
+}
+
+// script returns the shell script running ops and recording their outcome in the results file.
+// The script exits right away if a previous run is still in progress.
+func script(ops []operation) string {
+	var s strings.Builder
+	s.WriteString(header)
+	// The lock and results files are next to the script.
+	s.WriteString(`cd "$(dirname "$0")" || exit 1` + "\n")
+	fmt.Fprintf(&s, "exec 9>%s\n", lockName)
+	s.WriteString("flock -n 9 || exit 0\n\n")
+	fmt.Fprintf(&s, "results=%s\n", resultsName)
+	s.WriteString(`: > "$results.new"
+run() {
+	if "$@"; then
+		echo "succeeded: $*" >> "$results.new"
+	else
+		echo "failed ($?): $*" >> "$results.new"
+	fi
+}
+
+export DEBIAN_FRONTEND=noninteractive
+`)
+	if slices.ContainsFunc(ops, func(o operation) bool { return !o.snap && o.action == "install" }) {
+		s.WriteString("run apt-get update -q\n")
+	}
+	for _, o := range ops {
+		fmt.Fprintf(&s, "run %s\n", strings.Join(o.cmd(), " "))
+	}
+	s.WriteString(`mv "$results.new" "$results"` + "\n")
+	return s.String()
+}
+
+// running returns true if a previous run of the packages operations is still in progress.
+func (m *Manager) running() (bool, error) {
+	// The lock file is only created by the script.
+	f, err := os.Open(filepath.Join(m.packagesDir, lockName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	// The lock, if taken, is released when closing the file.
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_EX|unix.LOCK_NB); errors.Is(err, unix.EWOULDBLOCK) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// writeFile atomically writes content to p with perm permissions.
+func writeFile(p, content string, perm os.FileMode) error {
+	if err := os.WriteFile(p+".new", []byte(content), perm); err != nil {
+		return err
+	}
+	return os.Rename(p+".new", p)
+}
+
+// removeFile removes the file at p, if any.
+func removeFile(p string) error {
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// lines returns the non empty lines of out.
+func lines(out []byte) (l []string) {
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if t := strings.TrimSpace(scanner.Text()); t != "" {
+			l = append(l, t)
+		}
+	}
+	return l
+}
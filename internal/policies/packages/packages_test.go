@@ -0,0 +1,214 @@
+package packages_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/adsys/internal/policies/packages"
+	"github.com/ubuntu/adsys/internal/testutils"
+	"golang.org/x/sys/unix"
+)
+
+func TestApplyPolicy(t *testing.T) {
+	t.Parallel()
+
+	defaultPolicy := []entry.Entry{
+		{Key: "packages/install-debs", Value: "vpn-client\nedr-agent\ncurl"},
+		{Key: "packages/remove-debs", Value: "telnet\nnot-installed"},
+		{Key: "packages/hold-debs", Value: "edr-agent\nlibc6"},
+		{Key: "packages/install-snaps", Value: "firefox\nteams-for-linux"},
+		{Key: "packages/remove-snaps", Value: "chromium\nnot-installed"},
+	}
+
+	tests := map[string]struct {
+		notComputer   bool
+		entries       []entry.Entry
+		existingState string
+		running       bool
+		executorError string
+		startError    bool
+
+		wantStarted bool
+		wantErr     bool
+	}{
+		"Full policy":                      {entries: defaultPolicy, wantStarted: true},
+		"Snaps only do not query debs":     {entries: defaultPolicy[3:], executorError: "debs", wantStarted: true},
+		"Debs only do not query snaps":     {entries: defaultPolicy[:3], executorError: "snaps", wantStarted: true},
+		"Only holds do not update indexes": {entries: []entry.Entry{{Key: "packages/hold-debs", Value: "curl"}}, wantStarted: true},
+		"Values are trimmed, lowercased and deduplicated": {entries: []entry.Entry{
+			{Key: "packages/install-debs", Value: " VPN-client \n\nvpn-client\nlibfoo:i386"},
+		}, wantStarted: true},
+		"Arch-qualified debs are matched with the installed ones": {entries: []entry.Entry{
+			{Key: "packages/install-debs", Value: "libbar:i386\ncurl:amd64\nlibc6:i386"},
+			{Key: "packages/remove-debs", Value: "telnet:amd64\nedr-agent:i386"},
+		}, wantStarted: true},
+		"Invalid names are skipped": {entries: []entry.Entry{
+			{Key: "packages/install-debs", Value: "vpn-client; rm -rf /\n-o\nvpn-client\na"},
+			{Key: "packages/install-snaps", Value: "teams_for_linux\nfirefox"},
+		}, wantStarted: true},
+		"Packages to install and remove are ignored": {entries: []entry.Entry{
+			{Key: "packages/install-debs", Value: "vpn-client\nedr-agent"},
+			{Key: "packages/remove-debs", Value: "vpn-client"},
+		}},
+		"Unsupported keys are ignored": {entries: []entry.Entry{{Key: "packages/upgrade", Value: "all"}}},
+		"Disabled entries are ignored": {entries: []entry.Entry{{Key: "packages/install-debs", Value: "vpn-client", Disabled: true}}},
+		"Up to date system":            {entries: []entry.Entry{{Key: "packages/install-debs", Value: "curl"}, {Key: "packages/remove-snaps", Value: "vlc"}}},
+		"Previously held debs are unheld": {entries: []entry.Entry{{Key: "packages/hold-debs", Value: "libc6"}},
+			existingState: "held-by-policy", wantStarted: true},
+		"No rules unholds previously held debs":                  {existingState: "held-by-policy", wantStarted: true},
+		"No rules and up to date system removes previous script": {existingState: "previous-run"},
+		"Previous run still in progress":                         {entries: defaultPolicy, existingState: "previous-run", running: true},
+		"Locally held debs are not owned":                        {entries: []entry.Entry{{Key: "packages/hold-debs", Value: "libc6"}}},
+		"Debs to hold are not owned while a previous run is still in progress": {entries: []entry.Entry{{Key: "packages/hold-debs", Value: "curl"}},
+			existingState: "previous-run", running: true},
+		"Debs to unhold are kept while a previous run is still in progress": {existingState: "held-by-policy", running: true},
+		"Not a computer": {notComputer: true, entries: defaultPolicy},
+
+		// Error cases
+		"Error on listing installed debs":  {entries: defaultPolicy, executorError: "debs", wantErr: true},
+		"Error on listing held debs":       {entries: defaultPolicy, executorError: "held", wantErr: true},
+		"Error on listing installed snaps": {entries: defaultPolicy, executorError: "snaps", wantErr: true},
+		"Error on starting the unit":       {entries: defaultPolicy, startError: true, wantErr: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			stateDir := filepath.Join(t.TempDir(), "state")
+			if tc.existingState != "" {
+				testutils.Copy(t, filepath.Join("testdata", tc.existingState), stateDir)
+			}
+			if tc.running {
+				f, err := os.OpenFile(filepath.Join(stateDir, "packages", ".lock"), os.O_RDWR|os.O_CREATE, 0600)
+				require.NoError(t, err, "Setup: can't open lock file")
+				defer f.Close()
+				require.NoError(t, unix.Flock(int(f.Fd()), unix.LOCK_EX|unix.LOCK_NB), "Setup: can't take lock")
+			}
+
+			starter := &mockUnitStarter{wantErr: tc.startError}
+			m := packages.New(stateDir, starter, packages.WithExecutor(mockExecutor{failOn: tc.executorError}))
+			err := m.ApplyPolicy(context.Background(), "ubuntu", !tc.notComputer, tc.entries)
+			if tc.wantErr {
+				require.Error(t, err, "ApplyPolicy should have failed but didn't")
+				return
+			}
+			require.NoError(t, err, "ApplyPolicy failed but shouldn't have")
+
+			require.Equal(t, tc.wantStarted, starter.started != nil, "Packages operations unit started as expected")
+			if tc.wantStarted {
+				require.Equal(t, []string{"/bin/sh", filepath.Join(stateDir, "packages", "run")}, starter.started, "Unit runs the packages script")
+			}
+
+			testutils.CompareTreesWithFiltering(t, stateDir, filepath.Join(testutils.GoldenPath(t), "state"), testutils.UpdateEnabled())
+
+			details := strings.Join(m.Details(context.Background(), "ubuntu", !tc.notComputer), "\n")
+			want := testutils.LoadWithUpdateFromGolden(t, details, testutils.WithGoldenPath(filepath.Join(testutils.GoldenPath(t), "details")))
+			require.Equal(t, want, details, "Details don't match")
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		notComputer   bool
+		entries       []entry.Entry
+		existingState string
+
+		wantChanges []string
+	}{
+		"Operations are planned as calls": {
+			entries: []entry.Entry{
+				{Key: "packages/install-debs", Value: "vpn-client\ncurl"},
+				{Key: "packages/hold-debs", Value: "curl"},
+				{Key: "packages/remove-snaps", Value: "chromium"},
+			},
+			wantChanges: []string{
+				"apt-get install -y -q -o Dpkg::Options::=--force-confold vpn-client",
+				"apt-mark hold curl",
+				"snap remove chromium",
+			},
+		},
+		"Previously held debs are planned to be unheld": {
+			existingState: "held-by-policy",
+			wantChanges:   []string{"apt-mark unhold edr-agent", "apt-mark unhold libc6"},
+		},
+		"Up to date system": {entries: []entry.Entry{{Key: "packages/install-debs", Value: "curl"}}},
+		"Nothing to do":     {},
+		"Not a computer":    {notComputer: true, entries: []entry.Entry{{Key: "packages/install-debs", Value: "vpn-client"}}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			stateDir := filepath.Join(t.TempDir(), "state")
+			if tc.existingState != "" {
+				testutils.Copy(t, filepath.Join("testdata", tc.existingState), stateDir)
+			}
+
+			starter := &mockUnitStarter{}
+			m := packages.New(stateDir, starter, packages.WithExecutor(mockExecutor{}))
+			changes, err := m.Plan(context.Background(), "ubuntu", !tc.notComputer, tc.entries)
+			require.NoError(t, err, "Plan failed but shouldn't have")
+
+			var got []string
+			for _, c := range changes {
+				got = append(got, c.Target+" "+c.Content)
+			}
+			require.Equal(t, tc.wantChanges, got, "Plan returns the expected changes")
+
+			// Planning should not touch the system.
+			require.Nil(t, starter.started, "Plan should not start any unit")
+			require.NoFileExists(t, filepath.Join(stateDir, "packages", "run"), "Plan should not write the packages script")
+		})
+	}
+}
+
+// mockExecutor returns a fixed state of the system.
+type mockExecutor struct {
+	failOn string
+}
+
+func (e mockExecutor) InstalledDebs(_ context.Context) ([]string, error) {
+	if e.failOn == "debs" {
+		return nil, errors.New("dpkg-query failed")
+	}
+	return []string{"curl", "curl:amd64", "edr-agent", "edr-agent:amd64", "libbar:i386", "libc6", "libc6:amd64", "telnet", "telnet:amd64"}, nil
+}
+
+func (e mockExecutor) HeldDebs(_ context.Context) ([]string, error) {
+	if e.failOn == "held" {
+		return nil, errors.New("apt-mark failed")
+	}
+	return []string{"libc6", "edr-agent"}, nil
+}
+
+func (e mockExecutor) InstalledSnaps(_ context.Context) ([]string, error) {
+	if e.failOn == "snaps" {
+		return nil, errors.New("snap failed")
+	}
+	return []string{"chromium", "core22", "firefox"}, nil
+}
+
+// mockUnitStarter records the command of the started transient unit.
+type mockUnitStarter struct {
+	started []string
+	wantErr bool
+}
+
+func (s *mockUnitStarter) StartTransientUnit(_ context.Context, _, _ string, cmd []string) error {
+	if s.wantErr {
+		return errors.New("failed to start unit")
+	}
+	s.started = cmd
+	return nil
+}
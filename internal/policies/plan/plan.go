@@ -0,0 +1,98 @@
+// Package plan describes the changes a policy manager would make on the system when applying a policy.
+//
+// Policy managers compute those changes without touching the system, so that an administrator can
+// preview the result of a GPO update before applying it for real.
+package plan
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/leonelquinteros/gotext"
+)
+
+// Action is the kind of modification a policy manager would do.
+type Action string
+
+const (
+	// Create is a file or directory which would be created.
+	Create Action = "create"
+	// Update is an existing file which content would change.
+	Update Action = "update"
+	// Remove is a file or directory which would be removed.
+	Remove Action = "remove"
+	// Run is a command, systemd or D-Bus call which would be executed.
+	Run Action = "run"
+)
+
+// Change is a single modification a policy manager would do on the system.
+type Change struct {
+	Action Action
+	// Target is the file, directory, unit or command affected by the change.
+	Target string
+	// Content is the content of the file after the change, or the arguments of the call.
+	Content string
+}
+
+// File returns the change needed for path to contain content.
+// ok is false if the file is already up to date.
+func File(path, content string) (c Change, ok bool) {
+	oldContent, err := os.ReadFile(path)
+	if err == nil && string(oldContent) == content {
+		return Change{}, false
+	}
+
+	action := Update
+	if errors.Is(err, fs.ErrNotExist) {
+		action = Create
+	}
+	return Change{Action: action, Target: path, Content: content}, true
+}
+
+// Removal returns the change needed for path to be removed.
+// ok is false if path does not exist.
+func Removal(path string) (c Change, ok bool) {
+	if _, err := os.Lstat(path); errors.Is(err, fs.ErrNotExist) {
+		return Change{}, false
+	}
+	return Change{Action: Remove, Target: path}, true
+}
+
+// Call returns the change representing a command or an external call with its arguments.
+func Call(target string, args ...string) Change {
+	return Change{Action: Run, Target: target, Content: strings.Join(args, " ")}
+}
+
+// String returns a human readable version of the change, with any content indented below it.
+func (c Change) String() string {
+	var action string
+	switch c.Action {
+	case Create:
+		action = gotext.Get("create")
+	case Update:
+		action = gotext.Get("update")
+	case Remove:
+		action = gotext.Get("remove")
+	case Run:
+		action = gotext.Get("run")
+	default:
+		action = string(c.Action)
+	}
+
+	content := strings.TrimRight(c.Content, "\n")
+	if content == "" {
+		return fmt.Sprintf("%s %s", action, c.Target)
+	}
+	if c.Action == Run {
+		return fmt.Sprintf("%s %s %s", action, c.Target, content)
+	}
+
+	var lines []string
+	for _, l := range strings.Split(content, "\n") {
+		lines = append(lines, "    "+l)
+	}
+	return fmt.Sprintf("%s %s:\n%s", action, c.Target, strings.Join(lines, "\n"))
+}
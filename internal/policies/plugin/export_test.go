@@ -0,0 +1,15 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+)
+
+// SetDescribeTimeout changes the time plugins have to describe their capabilities for the duration of the test.
+func SetDescribeTimeout(t *testing.T, d time.Duration) {
+	t.Helper()
+
+	orig := describeTimeout
+	describeTimeout = d
+	t.Cleanup(func() { describeTimeout = orig })
+}
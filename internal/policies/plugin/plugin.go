@@ -0,0 +1,322 @@
+// Package plugin is the policy manager for rules handled by external executables.
+//
+// A plugin is an executable installed in the plugins directory (/usr/lib/adsys/plugins by default). Its file name is
+// the rule type it handles: a plugin named "foo" receives all the entries under Software/Policies/Ubuntu/foo/ in the
+// GPOs.
+//
+// Plugins communicate with adsysd over their standard input and output: each invocation receives a single JSON
+// encoded Request on stdin and is expected to write a single JSON encoded Response on stdout before exiting with a 0
+// exit code. Any output on stderr is logged on error. The protocol is versioned: adsysd sends its ProtocolVersion in
+// every request and plugins must answer with the same version.
+//
+// On startup, every plugin is invoked with the "describe" action to declare whether it applies to machines, users
+// or both, and which other policy managers need to be applied before it.
+// Then, on each policy update, it is invoked with the "apply" action and all its entries for the object, even when
+// there is none, so that it can revert any previously applied settings. It returns the result of applying each key.
+// When previewing an update, it is invoked with the "plan" action and returns the changes it would make, without
+// applying anything.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/leonelquinteros/gotext"
+	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
+	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/adsys/internal/policies/plan"
+	"github.com/ubuntu/adsys/internal/policies/transaction"
+	"github.com/ubuntu/adsys/internal/smbsafe"
+	"github.com/ubuntu/decorate"
+)
+
+// ProtocolVersion is the version of the protocol spoken with plugins.
+const ProtocolVersion = 1
+
+// describeTimeout is the time a plugin has to describe its capabilities, so that a hanging plugin doesn't block the
+// daemon startup.
+var describeTimeout = 10 * time.Second
+
+// Action is the operation requested to a plugin.
+type Action string
+
+const (
+	// Describe requests the plugin capabilities.
+	Describe Action = "describe"
+	// Apply requests the plugin to apply its entries.
+	Apply Action = "apply"
+	// Plan requests the changes the plugin would make when applying its entries.
+	Plan Action = "plan"
+)
+
+// Status is the outcome of applying a key.
+type Status string
+
+const (
+	// Applied means that the key was applied on the system.
+	Applied Status = "applied"
+	// Unchanged means that the system already matched the key value.
+	Unchanged Status = "unchanged"
+	// Skipped means that the key was intentionally not applied.
+	Skipped Status = "skipped"
+	// Failed means that the key could not be applied.
+	Failed Status = "failed"
+)
+
+// Request is sent to the plugin on its standard input.
+type Request struct {
+	Version    int     `json:"version"`
+	Action     Action  `json:"action"`
+	ObjectName string  `json:"object_name,omitempty"`
+	IsComputer bool    `json:"is_computer,omitempty"`
+	Entries    []Entry `json:"entries,omitempty"`
+}
+
+// Entry is a policy entry of the plugin rule type.
+type Entry struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled,omitempty"`
+	Meta     string `json:"meta,omitempty"`
+	Strategy string `json:"strategy,omitempty"`
+}
+
+// Response is written by the plugin on its standard output.
+type Response struct {
+	Version int `json:"version"`
+
+	// Machine, User and After are the plugin capabilities, returned on describe.
+	Machine bool     `json:"machine,omitempty"`
+	User    bool     `json:"user,omitempty"`
+	After   []string `json:"after,omitempty"`
+
+	// Results are the outcome of applying each key, returned on apply.
+	Results []Result `json:"results,omitempty"`
+	// Changes are the modifications the plugin would make, returned on plan.
+	Changes []Change `json:"changes,omitempty"`
+
+	// Error is a global error message if the request failed.
+	Error string `json:"error,omitempty"`
+}
+
+// Result is the outcome of applying a single key.
+type Result struct {
+	Key     string `json:"key"`
+	Status  Status `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// Change is a modification the plugin would make on the system.
+type Change struct {
+	Action  string `json:"action"`
+	Target  string `json:"target"`
+	Content string `json:"content,omitempty"`
+}
+
+// Manager applies the rules of a plugin.
+type Manager struct {
+	name string
+	path string
+
+	machine bool
+	user    bool
+	after   []string
+}
+
+// validName restricts the plugin names to the ones which can be used as a rule type.
+var validName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Discover returns a manager for each valid plugin in dir. A missing directory means no plugin.
+// Invalid plugins are skipped with a warning, so that they don't prevent other policies from being applied.
+func Discover(ctx context.Context, dir string) (managers []*Manager, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't discover policy plugins in %s", dir))
+
+	dirEntries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, e := range dirEntries {
+		m, err := New(ctx, filepath.Join(dir, e.Name()))
+		if err != nil {
+			log.Warning(ctx, gotext.Get("Ignoring policy plugin: %v", err))
+			continue
+		}
+		log.Debugf(ctx, "Found policy plugin %q (machine: %v, user: %v)", m.name, m.machine, m.user)
+		managers = append(managers, m)
+	}
+
+	return managers, nil
+}
+
+// New returns a manager for the plugin at path, after querying its capabilities.
+func New(ctx context.Context, path string) (m *Manager, err error) {
+	defer decorate.OnError(&err, gotext.Get("invalid policy plugin %s", path))
+
+	name := filepath.Base(path)
+	if !validName.MatchString(name) {
+		return nil, errors.New(gotext.Get("plugin name can only contain letters, digits, '-' and '_'"))
+	}
+
+	// As plugins run as root, only trust executables which can't be modified by other users.
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.Mode().IsRegular() || fi.Mode().Perm()&0111 == 0 {
+		return nil, errors.New(gotext.Get("plugin is not an executable file"))
+	}
+	if fi.Mode().Perm()&0022 != 0 {
+		return nil, errors.New(gotext.Get("plugin is writable by group or others"))
+	}
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok && st.Uid != 0 && int(st.Uid) != os.Getuid() {
+		return nil, errors.New(gotext.Get("plugin is not owned by root"))
+	}
+
+	m = &Manager{name: name, path: path}
+	describeCtx, cancel := context.WithTimeout(ctx, describeTimeout)
+	defer cancel()
+	resp, err := m.call(describeCtx, Request{Action: Describe})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Machine && !resp.User {
+		return nil, errors.New(gotext.Get("plugin applies neither to machines nor users"))
+	}
+	m.machine, m.user, m.after = resp.Machine, resp.User, resp.After
+
+	return m, nil
+}
+
+// Name returns the rule type handled by the plugin.
+func (m *Manager) Name() string {
+	return m.name
+}
+
+// Capabilities returns whether the plugin applies to machines and users, and the policy managers it needs to be
+// applied after.
+func (m *Manager) Capabilities() (machine, user bool, after []string) {
+	return m.machine, m.user, m.after
+}
+
+// ApplyPolicy sends the entries to the plugin to apply them on the system.
+// Any key which failed to be applied makes the policy fail.
+func (m *Manager) ApplyPolicy(ctx context.Context, objectName string, isComputer bool, entries []entry.Entry) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't apply %s plugin policy to %s", m.name, objectName))
+
+	log.Debugf(ctx, "Applying %s plugin policy to %s", m.name, objectName)
+
+	resp, err := m.call(ctx, newRequest(Apply, objectName, isComputer, entries))
+	if err != nil {
+		return err
+	}
+
+	var failures []string
+	for _, r := range resp.Results {
+		switch r.Status {
+		case Applied, Unchanged, Skipped:
+			log.Debugf(ctx, "%s plugin: key %q %s %s", m.name, r.Key, r.Status, r.Message)
+		case Failed:
+			failures = append(failures, fmt.Sprintf("%s: %s", r.Key, r.Message))
+		default:
+			return errors.New(gotext.Get("unknown status %q for key %q", r.Status, r.Key))
+		}
+	}
+	if len(failures) > 0 {
+		return errors.New(gotext.Get("failed to apply keys:\n%s", strings.Join(failures, "\n")))
+	}
+
+	return nil
+}
+
+// Prepare registers into tx the previously applied entries, so that they are applied again if applying the policies
+// fails. Plugins own their state, so there is nothing to back up on our side.
+func (m *Manager) Prepare(ctx context.Context, objectName string, isComputer bool, previous []entry.Entry, tx *transaction.Transaction) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't prepare %s plugin policy", m.name))
+
+	log.Debugf(ctx, "Preparing %s plugin policy for %s", m.name, objectName)
+
+	tx.OnAbort(func(ctx context.Context) error {
+		return m.ApplyPolicy(ctx, objectName, isComputer, previous)
+	})
+	return nil
+}
+
+// Plan returns the changes the plugin would make when applying the entries.
+func (m *Manager) Plan(ctx context.Context, objectName string, isComputer bool, entries []entry.Entry) (changes []plan.Change, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't plan %s plugin policy", m.name))
+
+	resp, err := m.call(ctx, newRequest(Plan, objectName, isComputer, entries))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range resp.Changes {
+		changes = append(changes, plan.Change{Action: plan.Action(c.Action), Target: c.Target, Content: c.Content})
+	}
+	return changes, nil
+}
+
+// newRequest returns a request for action with the given entries.
+func newRequest(action Action, objectName string, isComputer bool, entries []entry.Entry) Request {
+	req := Request{Action: action, ObjectName: objectName, IsComputer: isComputer}
+	for _, e := range entries {
+		req.Entries = append(req.Entries, Entry{
+			Key:      e.Key,
+			Value:    e.Value,
+			Disabled: e.Disabled,
+			Meta:     e.Meta,
+			Strategy: e.Strategy,
+		})
+	}
+	return req
+}
+
+// call runs the plugin with req and returns its response.
+func (m *Manager) call(ctx context.Context, req Request) (resp Response, err error) {
+	req.Version = ProtocolVersion
+	in, err := json.Marshal(req)
+	if err != nil {
+		return Response{}, err
+	}
+
+	// #nosec G204 - plugins are trusted executables installed by the administrator
+	cmd := exec.CommandContext(ctx, m.path)
+	cmd.Stdin = bytes.NewReader(in)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Don't wait for children of the plugin still holding its output once it is killed.
+	cmd.WaitDelay = time.Second
+	smbsafe.WaitExec()
+	err = cmd.Run()
+	smbsafe.DoneExec()
+	if err != nil {
+		return Response{}, errors.New(gotext.Get("%s action failed: %v\n%s", req.Action, err, stderr.String()))
+	}
+
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return Response{}, errors.New(gotext.Get("invalid response to %s action: %v", req.Action, err))
+	}
+	if resp.Version != ProtocolVersion {
+		return Response{}, errors.New(gotext.Get("unsupported protocol version %d, expected %d", resp.Version, ProtocolVersion))
+	}
+	if resp.Error != "" {
+		return Response{}, errors.New(resp.Error)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,321 @@
+package plugin_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/adsys/internal/policies/plugin"
+	"github.com/ubuntu/adsys/internal/policies/transaction"
+	"github.com/ubuntu/adsys/internal/testutils"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		name     string
+		behavior string
+		perm     os.FileMode
+
+		wantMachine bool
+		wantUser    bool
+		wantAfter   []string
+		wantErr     bool
+	}{
+		"Plugin applying to machines and users": {behavior: "machine_and_user", wantMachine: true, wantUser: true, wantAfter: []string{"dconf"}},
+		"Plugin applying to users only":         {behavior: "user_only", wantUser: true},
+
+		// Error cases
+		"Error on invalid plugin name":                  {name: "my.plugin", wantErr: true},
+		"Error on plugin not executable":                {perm: 0600, wantErr: true},
+		"Error on plugin writable by others":            {perm: 0757, wantErr: true},
+		"Error on plugin failing to describe":           {behavior: "exit_error", wantErr: true},
+		"Error on plugin returning invalid response":    {behavior: "invalid_json", wantErr: true},
+		"Error on plugin with other protocol version":   {behavior: "wrong_version", wantErr: true},
+		"Error on plugin returning an error":            {behavior: "error", wantErr: true},
+		"Error on plugin applying to no kind of object": {behavior: "no_object", wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if tc.name == "" {
+				tc.name = "myplugin"
+			}
+			if tc.behavior == "" {
+				tc.behavior = "machine_and_user"
+			}
+			p := mockPlugin(t, t.TempDir(), tc.name, tc.behavior)
+			if tc.perm != 0 {
+				require.NoError(t, os.Chmod(p, tc.perm), "Setup: can't change plugin permissions")
+			}
+
+			m, err := plugin.New(context.Background(), p)
+			if tc.wantErr {
+				require.Error(t, err, "New should return an error but got none")
+				return
+			}
+			require.NoError(t, err, "New should return no error but got one")
+
+			require.Equal(t, tc.name, m.Name(), "Name should be the plugin file name")
+			machine, user, after := m.Capabilities()
+			require.Equal(t, tc.wantMachine, machine, "Plugin should apply to machines as described")
+			require.Equal(t, tc.wantUser, user, "Plugin should apply to users as described")
+			require.Equal(t, tc.wantAfter, after, "Plugin dependencies should be the described ones")
+		})
+	}
+}
+
+func TestNewTimesOutOnHangingPlugin(t *testing.T) {
+	// Not parallel as it changes the describe timeout of all plugins.
+	plugin.SetDescribeTimeout(t, 100*time.Millisecond)
+
+	_, err := plugin.New(context.Background(), mockPlugin(t, t.TempDir(), "myplugin", "hang"))
+	require.Error(t, err, "New should return an error on a plugin hanging to describe itself")
+}
+
+func TestDiscover(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	mockPlugin(t, dir, "first", "machine_and_user")
+	mockPlugin(t, dir, "second", "user_only")
+	mockPlugin(t, dir, "invalid", "exit_error")
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "somedir"), 0750), "Setup: can't create directory")
+
+	managers, err := plugin.Discover(context.Background(), dir)
+	require.NoError(t, err, "Discover should return no error but got one")
+
+	var names []string
+	for _, m := range managers {
+		names = append(names, m.Name())
+	}
+	require.Equal(t, []string{"first", "second"}, names, "Discover should only return valid plugins")
+
+	managers, err = plugin.Discover(context.Background(), filepath.Join(dir, "doesnotexist"))
+	require.NoError(t, err, "Discover should return no error on missing directory")
+	require.Empty(t, managers, "Discover should return no plugin on missing directory")
+}
+
+func TestApplyPolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		behavior   string
+		entries    []entry.Entry
+		isComputer bool
+
+		wantErr bool
+	}{
+		"Entries are sent to the plugin for users":     {entries: []entry.Entry{{Key: "key1", Value: "value1"}, {Key: "key2", Value: "value2", Meta: "s", Strategy: "append"}}},
+		"Entries are sent to the plugin for computers": {entries: []entry.Entry{{Key: "key1", Value: "value1"}}, isComputer: true},
+		"Disabled entries are sent to the plugin":      {entries: []entry.Entry{{Key: "key1", Disabled: true}}},
+		"No entries are sent to the plugin":            {},
+		"Skipped and unchanged keys are not an error":  {behavior: "skipped", entries: []entry.Entry{{Key: "key1", Value: "value1"}}},
+
+		// Error cases
+		"Error on failed key":              {behavior: "failed", entries: []entry.Entry{{Key: "key1", Value: "value1"}}, wantErr: true},
+		"Error on unknown status":          {behavior: "unknown_status", entries: []entry.Entry{{Key: "key1", Value: "value1"}}, wantErr: true},
+		"Error on plugin returning error":  {behavior: "error_on_apply", wantErr: true},
+		"Error on plugin exiting in error": {behavior: "exit_error_on_apply", wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if tc.behavior == "" {
+				tc.behavior = "machine_and_user"
+			}
+			m, requestsFile := newMockPluginManager(t, tc.behavior)
+
+			err := m.ApplyPolicy(context.Background(), "ubuntu", tc.isComputer, tc.entries)
+			if tc.wantErr {
+				require.Error(t, err, "ApplyPolicy should return an error but got none")
+				return
+			}
+			require.NoError(t, err, "ApplyPolicy should return no error but got one")
+
+			got := readRequests(t, requestsFile)
+			want := testutils.LoadWithUpdateFromGolden(t, got)
+			require.Equal(t, want, got, "Plugin should have received the expected requests")
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	t.Parallel()
+
+	m, _ := newMockPluginManager(t, "machine_and_user")
+	changes, err := m.Plan(context.Background(), "ubuntu", false, []entry.Entry{{Key: "key1", Value: "value1"}})
+	require.NoError(t, err, "Plan should return no error but got one")
+
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.String())
+	}
+	require.Equal(t, []string{"update /etc/myplugin.conf:\n    key1=value1"}, got, "Plan should return the plugin changes")
+
+	m, _ = newMockPluginManager(t, "error_on_apply")
+	_, err = m.Plan(context.Background(), "ubuntu", false, nil)
+	require.Error(t, err, "Plan should return an error when the plugin fails")
+}
+
+func TestPrepare(t *testing.T) {
+	t.Parallel()
+
+	m, requestsFile := newMockPluginManager(t, "machine_and_user")
+
+	tx, err := transaction.New(t.TempDir(), "test-")
+	require.NoError(t, err, "Setup: can't create transaction")
+	err = m.Prepare(context.Background(), "ubuntu", true, []entry.Entry{{Key: "previous", Value: "previous value"}}, tx)
+	require.NoError(t, err, "Prepare should return no error but got one")
+
+	require.Empty(t, readRequests(t, requestsFile), "Prepare should not call the plugin")
+
+	require.NoError(t, tx.Abort(context.Background()), "Abort should return no error but got one")
+
+	got := readRequests(t, requestsFile)
+	want := testutils.LoadWithUpdateFromGolden(t, got)
+	require.Equal(t, want, got, "Abort should apply again the previous entries")
+}
+
+// newMockPluginManager returns a manager for a mock plugin and the file where the plugin records its
+// apply and plan requests.
+func newMockPluginManager(t *testing.T, behavior string) (*plugin.Manager, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	m, err := plugin.New(context.Background(), mockPlugin(t, dir, "myplugin", behavior))
+	require.NoError(t, err, "Setup: can't create plugin manager")
+
+	return m, filepath.Join(dir, "myplugin.requests")
+}
+
+// mockPlugin creates an executable plugin named name in dir, running TestMockPlugin with the given behavior.
+// Requests are recorded in <name>.requests, next to the plugin.
+func mockPlugin(t *testing.T, dir, name, behavior string) string {
+	t.Helper()
+
+	p := filepath.Join(dir, name)
+	script := fmt.Sprintf("#!/bin/sh\nexec env GO_WANT_HELPER_PROCESS=1 %s -test.run=TestMockPlugin -- %q %q\n",
+		os.Args[0], behavior, p+".requests")
+	// #nosec G306. We want this plugin to be executable.
+	require.NoError(t, os.WriteFile(p, []byte(script), 0700), "Setup: can't create mock plugin")
+
+	return p
+}
+
+// readRequests returns the content of the requests recorded by the mock plugin.
+func readRequests(t *testing.T, p string) string {
+	t.Helper()
+
+	content, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return ""
+	}
+	require.NoError(t, err, "Setup: can't read plugin requests")
+	return string(content)
+}
+
+func TestMockPlugin(_ *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	defer os.Exit(0)
+
+	args := os.Args
+	for len(args) > 0 {
+		if args[0] == "--" {
+			args = args[1:]
+			break
+		}
+		args = args[1:]
+	}
+	behavior, requestsFile := args[0], args[1]
+
+	in, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "can't read request: %v", err)
+		os.Exit(1)
+	}
+	var req plugin.Request
+	if err := json.Unmarshal(in, &req); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid request: %v", err)
+		os.Exit(1)
+	}
+
+	resp := plugin.Response{Version: plugin.ProtocolVersion}
+	if req.Action == plugin.Describe {
+		switch behavior {
+		case "exit_error":
+			fmt.Fprint(os.Stderr, "something went wrong")
+			os.Exit(1)
+		case "invalid_json":
+			fmt.Print("not json")
+			return
+		case "wrong_version":
+			resp.Version = plugin.ProtocolVersion + 1
+		case "error":
+			resp.Error = "describe error"
+		case "hang":
+			time.Sleep(time.Minute)
+		case "no_object":
+		case "user_only":
+			resp.User = true
+		default:
+			resp.Machine, resp.User, resp.After = true, true, []string{"dconf"}
+		}
+		out, _ := json.Marshal(resp)
+		fmt.Print(string(out))
+		return
+	}
+
+	// Record apply and plan requests
+	f, err := os.OpenFile(requestsFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "can't record request: %v", err)
+		os.Exit(1)
+	}
+	out, _ := json.MarshalIndent(req, "", "  ")
+	fmt.Fprintln(f, string(out))
+	f.Close()
+
+	switch behavior {
+	case "exit_error_on_apply":
+		fmt.Fprint(os.Stderr, "something went wrong")
+		os.Exit(1)
+	case "error_on_apply":
+		resp.Error = "apply error"
+	}
+
+	for _, e := range req.Entries {
+		switch req.Action {
+		case plugin.Plan:
+			resp.Changes = append(resp.Changes, plugin.Change{Action: "update", Target: "/etc/myplugin.conf", Content: e.Key + "=" + e.Value})
+		default:
+			r := plugin.Result{Key: e.Key, Status: plugin.Applied}
+			switch behavior {
+			case "skipped":
+				r.Status, r.Message = plugin.Skipped, "nothing to do"
+				resp.Results = append(resp.Results, plugin.Result{Key: e.Key + "-other", Status: plugin.Unchanged})
+			case "failed":
+				r.Status, r.Message = plugin.Failed, "can't apply "+strings.ToUpper(e.Key)
+			case "unknown_status":
+				r.Status = "unknown"
+			}
+			resp.Results = append(resp.Results, r)
+		}
+	}
+
+	out, _ = json.Marshal(resp)
+	fmt.Print(string(out))
+}
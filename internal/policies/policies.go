@@ -38,8 +38,11 @@ type assetsFromMMAP struct {
 
 // Policies is the list of GPOs applied to a particular object, with the global data cache.
 type Policies struct {
-	GPOs   []GPO
-	assets *assetsFromMMAP `yaml:"-"`
+	GPOs []GPO
+	// SkippedGPOs are the GPOs applying to the object which were not applied, like the ones whose WMI filter
+	// doesn't match the machine.
+	SkippedGPOs []SkippedGPO    `yaml:",omitempty"`
+	assets      *assetsFromMMAP `yaml:"-"`
 }
 
 // New returns new policies with GPOs and assets loaded from DB.
@@ -64,6 +67,21 @@ func New(ctx context.Context, gpos []GPO, assetsDBPath string) (pols Policies, e
 	}, nil
 }
 
+// Loopback returns the user policy loopback processing mode of computer policies, configured by the GPO with the
+// highest priority. It is empty if loopback processing is not enabled.
+func (pols Policies) Loopback() LoopbackMode {
+	for _, g := range pols.GPOs {
+		switch g.Loopback {
+		case "":
+			continue
+		case LoopbackDisabled:
+			return ""
+		}
+		return g.Loopback
+	}
+	return ""
+}
+
 // NewFromCache returns cached policies loaded from the p cache directory.
 func NewFromCache(ctx context.Context, p string) (pols Policies, err error) {
 	defer decorate.OnError(&err, gotext.Get("can't get cached policies from %s", p))
@@ -378,23 +396,31 @@ func (pols Policies) GetUniqueRules() map[string][]entry.Entry {
 			}
 			for _, e := range entries {
 				switch e.Strategy {
-				case entry.StrategyAppend:
+				case entry.StrategyAppend, entry.StrategyPrepend, entry.StrategyMerge:
 					// We skip disabled keys as we only append enabled one.
 					if e.Disabled {
 						continue
 					}
 					var keyAlreadySeen bool
-					// If there is an existing value, prepend new value to it. We are analyzing GPOs in reverse order (closest first).
+					// If there is an existing value, combine new value with it. We are analyzing GPOs in reverse order (closest first).
 					if _, exists := seen[t+e.Key]; exists {
 						keyAlreadySeen = true
-						// We have seen a closest key which is an override. We don’t append furthest append values.
-						if dedup[t][e.Key].Strategy != entry.StrategyAppend {
+						// We have seen a closest key which is an override or another strategy. We don’t combine furthest values.
+						if dedup[t][e.Key].Strategy != e.Strategy {
 							continue
 						}
-						e.Value = e.Value + "\n" + dedup[t][e.Key].Value
+						switch e.Strategy {
+						case entry.StrategyPrepend:
+							e.Value = dedup[t][e.Key].Value + "\n" + e.Value
+						default:
+							e.Value = e.Value + "\n" + dedup[t][e.Key].Value
+						}
 						// Keep closest meta value.
 						e.Meta = dedup[t][e.Key].Meta
 					}
+					if e.Strategy == entry.StrategyMerge {
+						e.Value = uniqueLines(e.Value)
+					}
 					dedup[t][e.Key] = e
 					if keyAlreadySeen {
 						continue
@@ -427,6 +453,25 @@ func (pols Policies) GetUniqueRules() map[string][]entry.Entry {
 	return r
 }
 
+// uniqueLines returns v with only the first occurrence of each line, ignoring leading and trailing spaces.
+// Empty lines are removed.
+func uniqueLines(v string) string {
+	var lines []string
+	seen := make(map[string]struct{})
+	for _, l := range strings.Split(v, "\n") {
+		l = strings.TrimSpace(l)
+		if l == "" {
+			continue
+		}
+		if _, exists := seen[l]; exists {
+			continue
+		}
+		seen[l] = struct{}{}
+		lines = append(lines, l)
+	}
+	return strings.Join(lines, "\n")
+}
+
 // chown either chown the file descriptor attached, or the path if this one is null to uid and gid.
 // It will know if we should skip chown for tests.
 func chown(p string, f *os.File, uid, gid int) (err error) {
@@ -836,6 +836,133 @@ func TestGetUniqueRules(t *testing.T) {
 				},
 			}},
 
+		"Prepend policy entry, multiple GPOs": {
+			gpos: []policies.GPO{
+				{ID: "closest", Name: "closest-name", Rules: map[string][]entry.Entry{
+					"domain": {
+						{Key: "A", Value: "closest value", Strategy: entry.StrategyPrepend},
+					}}},
+				{ID: "furthest", Name: "furthest-name", Rules: map[string][]entry.Entry{
+					"domain": {
+						{Key: "A", Value: "furthest value", Strategy: entry.StrategyPrepend},
+					}}},
+			},
+			want: map[string][]entry.Entry{
+				"domain": {
+					{Key: "A", Value: "closest value\nfurthest value", Strategy: entry.StrategyPrepend},
+				},
+			}},
+		"Prepend policy entry, multiple GPOs, disabled key is ignored": {
+			gpos: []policies.GPO{
+				{ID: "closest", Name: "closest-name", Rules: map[string][]entry.Entry{
+					"domain": {
+						{Key: "A", Value: "closest value", Strategy: entry.StrategyPrepend},
+					}}},
+				{ID: "furthest", Name: "furthest-name", Rules: map[string][]entry.Entry{
+					"domain": {
+						{Key: "A", Value: "furthest value", Strategy: entry.StrategyPrepend, Disabled: true},
+					}}},
+			},
+			want: map[string][]entry.Entry{
+				"domain": {
+					{Key: "A", Value: "closest value", Strategy: entry.StrategyPrepend},
+				},
+			}},
+		"Merge policy entry, one GPO, duplicates are removed": {
+			gpos: []policies.GPO{
+				{ID: "standard", Name: "standard-name", Rules: map[string][]entry.Entry{
+					"domain": {
+						{Key: "A", Value: "value1\nvalue2\n value1 \n\nvalue3", Strategy: entry.StrategyMerge},
+					}}},
+			},
+			want: map[string][]entry.Entry{
+				"domain": {
+					{Key: "A", Value: "value1\nvalue2\nvalue3", Strategy: entry.StrategyMerge},
+				},
+			}},
+		"Merge policy entry, multiple GPOs, furthest first without duplicates": {
+			gpos: []policies.GPO{
+				{ID: "closest", Name: "closest-name", Rules: map[string][]entry.Entry{
+					"domain": {
+						{Key: "A", Value: "value2\nvalue3", Strategy: entry.StrategyMerge},
+					}}},
+				{ID: "furthest", Name: "furthest-name", Rules: map[string][]entry.Entry{
+					"domain": {
+						{Key: "A", Value: "value1\nvalue2", Strategy: entry.StrategyMerge},
+					}}},
+			},
+			want: map[string][]entry.Entry{
+				"domain": {
+					{Key: "A", Value: "value1\nvalue2\nvalue3", Strategy: entry.StrategyMerge},
+				},
+			}},
+		"Merge policy entry, multiple GPOs, disabled key is ignored": {
+			gpos: []policies.GPO{
+				{ID: "closest", Name: "closest-name", Rules: map[string][]entry.Entry{
+					"domain": {
+						{Key: "A", Value: "value2\nvalue3", Strategy: entry.StrategyMerge, Disabled: true},
+					}}},
+				{ID: "furthest", Name: "furthest-name", Rules: map[string][]entry.Entry{
+					"domain": {
+						{Key: "A", Value: "value1\nvalue2", Strategy: entry.StrategyMerge},
+					}}},
+			},
+			want: map[string][]entry.Entry{
+				"domain": {
+					{Key: "A", Value: "value1\nvalue2", Strategy: entry.StrategyMerge},
+				},
+			}},
+
+		// Mix list strategies: values are only combined with the same strategy as the closest one
+		"Mix list strategies, closest is prepend, furthest append is ignored": {
+			gpos: []policies.GPO{
+				{ID: "closest", Name: "closest-name", Rules: map[string][]entry.Entry{
+					"domain": {
+						{Key: "A", Value: "closest value", Strategy: entry.StrategyPrepend},
+					}}},
+				{ID: "furthest", Name: "furthest-name", Rules: map[string][]entry.Entry{
+					"domain": {
+						{Key: "A", Value: "furthest value", Strategy: entry.StrategyAppend},
+					}}},
+			},
+			want: map[string][]entry.Entry{
+				"domain": {
+					{Key: "A", Value: "closest value", Strategy: entry.StrategyPrepend},
+				},
+			}},
+		"Mix list strategies, closest is merge, furthest append is ignored": {
+			gpos: []policies.GPO{
+				{ID: "closest", Name: "closest-name", Rules: map[string][]entry.Entry{
+					"domain": {
+						{Key: "A", Value: "closest value", Strategy: entry.StrategyMerge},
+					}}},
+				{ID: "furthest", Name: "furthest-name", Rules: map[string][]entry.Entry{
+					"domain": {
+						{Key: "A", Value: "furthest value", Strategy: entry.StrategyAppend},
+					}}},
+			},
+			want: map[string][]entry.Entry{
+				"domain": {
+					{Key: "A", Value: "closest value", Strategy: entry.StrategyMerge},
+				},
+			}},
+		"Mix meta on GPOs, furthest policy entry is merge, closest is override": {
+			gpos: []policies.GPO{
+				{ID: "closest", Name: "closest-name", Rules: map[string][]entry.Entry{
+					"domain": {
+						{Key: "A", Value: "closest value"},
+					}}},
+				{ID: "furthest", Name: "furthest-name", Rules: map[string][]entry.Entry{
+					"domain": {
+						{Key: "A", Value: "furthest value", Strategy: entry.StrategyMerge},
+					}}},
+			},
+			want: map[string][]entry.Entry{
+				"domain": {
+					{Key: "A", Value: "closest value"},
+				},
+			}},
+
 		// Mix append and override: closest win
 		"Mix meta on GPOs, furthest policy entry is append, closest is override": {
 			gpos: []policies.GPO{
@@ -884,6 +1011,35 @@ func TestGetUniqueRules(t *testing.T) {
 	}
 }
 
+func TestLoopback(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		modes []policies.LoopbackMode
+
+		want policies.LoopbackMode
+	}{
+		"No GPO":               {},
+		"GPO without loopback": {modes: []policies.LoopbackMode{""}},
+		"Merge mode":           {modes: []policies.LoopbackMode{policies.LoopbackMerge}, want: policies.LoopbackMerge},
+		"Replace mode":         {modes: []policies.LoopbackMode{policies.LoopbackReplace}, want: policies.LoopbackReplace},
+		"Disabled mode":        {modes: []policies.LoopbackMode{policies.LoopbackDisabled}},
+		"Highest priority GPO configuring it wins": {modes: []policies.LoopbackMode{"", policies.LoopbackReplace, policies.LoopbackMerge}, want: policies.LoopbackReplace},
+		"Disabled in highest priority GPO wins":    {modes: []policies.LoopbackMode{policies.LoopbackDisabled, policies.LoopbackMerge}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var pols policies.Policies
+			for i, mode := range tc.modes {
+				pols.GPOs = append(pols.GPOs, policies.GPO{ID: fmt.Sprintf("gpo%d", i), Loopback: mode})
+			}
+			require.Equal(t, tc.want, pols.Loopback(), "Loopback returns the expected mode")
+		})
+	}
+}
+
 // equalPoliciesToGolden compares the policies to the given file.
 func equalPoliciesToGolden(t *testing.T, got policies.Policies, golden string, update bool) {
 	t.Helper()
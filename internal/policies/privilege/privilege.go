@@ -29,6 +29,8 @@ import (
 	"github.com/ubuntu/adsys/internal/consts"
 	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
 	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/adsys/internal/policies/plan"
+	"github.com/ubuntu/adsys/internal/policies/transaction"
 	"github.com/ubuntu/decorate"
 	"gopkg.in/ini.v1"
 )
@@ -73,16 +75,7 @@ func (m *Manager) ApplyPolicy(ctx context.Context, objectName string, isComputer
 		return nil
 	}
 
-	sudoersDir := m.sudoersDir
-	if sudoersDir == "" {
-		sudoersDir = consts.DefaultSudoersDir
-	}
-	policyKitDir := m.policyKitDir
-	if policyKitDir == "" {
-		policyKitDir = consts.DefaultPolicyKitDir
-	}
-	sudoersConf := filepath.Join(sudoersDir, adsysBaseConfName)
-	policyKitConf := filepath.Join(policyKitDir, "localauthority.conf.d", adsysBaseConfName+".conf")
+	sudoersConf, policyKitConf, policyKitDir := m.confPaths()
 
 	log.Debugf(ctx, "Applying privilege policy to %s", objectName)
 
@@ -97,34 +90,111 @@ func (m *Manager) ApplyPolicy(ctx context.Context, objectName string, isComputer
 		return nil
 	}
 
+	systemPolkitAdmins, err := getSystemPolkitAdminIdentities(ctx, policyKitDir)
+	if err != nil {
+		return err
+	}
+	contentSudo, contentPolkit := policyContent(ctx, entries, systemPolkitAdmins)
+
 	// Create our temp files and parent directories
 	// nolint:gosec // G301 match distribution permission
 	if err := os.MkdirAll(filepath.Dir(sudoersConf), 0755); err != nil {
 		return err
 	}
-	// nolint:gosec // G302 match distribution permission
-	sudoersF, err := os.OpenFile(sudoersConf+".new", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0440)
-	if err != nil {
+	// nolint:gosec // G306 match distribution permission
+	if err := os.WriteFile(sudoersConf+".new", []byte(contentSudo), 0440); err != nil {
 		return err
 	}
-	defer sudoersF.Close()
 	// nolint:gosec // G301 match distribution permission
 	if err := os.MkdirAll(filepath.Dir(policyKitConf), 0755); err != nil {
 		return err
 	}
-	// nolint:gosec // G302 match distribution permission
-	policyKitConfF, err := os.OpenFile(policyKitConf+".new", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
-	if err != nil {
+	// nolint:gosec // G306 match distribution permission
+	if err := os.WriteFile(policyKitConf+".new", []byte(contentPolkit), 0644); err != nil {
 		return err
 	}
-	defer policyKitConfF.Close()
+
+	// Move temp files to their final destination
+	if err := os.Rename(sudoersConf+".new", sudoersConf); err != nil {
+		return err
+	}
+	if err := os.Rename(policyKitConf+".new", policyKitConf); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Plan returns the changes ApplyPolicy would make for a privilege policy, without applying them.
+func (m *Manager) Plan(ctx context.Context, objectName string, isComputer bool, entries []entry.Entry) (changes []plan.Change, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't plan privilege policy for %s", objectName))
+
+	// We only have privilege escalation on computers.
+	if !isComputer {
+		return nil, nil
+	}
+
+	sudoersConf, policyKitConf, policyKitDir := m.confPaths()
+
+	if len(entries) == 0 {
+		for _, p := range []string{sudoersConf, policyKitConf} {
+			if c, ok := plan.Removal(p); ok {
+				changes = append(changes, c)
+			}
+		}
+		return changes, nil
+	}
 
 	systemPolkitAdmins, err := getSystemPolkitAdminIdentities(ctx, policyKitDir)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	contentSudo, contentPolkit := policyContent(ctx, entries, systemPolkitAdmins)
 
-	// Parse our rules and write to temp files
+	if c, ok := plan.File(sudoersConf, contentSudo); ok {
+		changes = append(changes, c)
+	}
+	if c, ok := plan.File(policyKitConf, contentPolkit); ok {
+		changes = append(changes, c)
+	}
+
+	return changes, nil
+}
+
+// Prepare backs up the sudoers and policykit configuration into tx, so that they can be restored
+// if applying the policies fails. A partially applied privilege policy could lock administrators out.
+func (m *Manager) Prepare(ctx context.Context, objectName string, isComputer bool, tx *transaction.Transaction) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't prepare privilege policy for %s", objectName))
+
+	// We only have privilege escalation on computers.
+	if !isComputer {
+		return nil
+	}
+
+	log.Debugf(ctx, "Preparing privilege policy for %s", objectName)
+
+	sudoersConf, policyKitConf, _ := m.confPaths()
+	return tx.Backup(sudoersConf, policyKitConf)
+}
+
+// confPaths returns the sudoers and policykit configuration files managed by adsys, as well as the policykit directory.
+func (m *Manager) confPaths() (sudoersConf, policyKitConf, policyKitDir string) {
+	sudoersDir := m.sudoersDir
+	if sudoersDir == "" {
+		sudoersDir = consts.DefaultSudoersDir
+	}
+	policyKitDir = m.policyKitDir
+	if policyKitDir == "" {
+		policyKitDir = consts.DefaultPolicyKitDir
+	}
+
+	return filepath.Join(sudoersDir, adsysBaseConfName),
+		filepath.Join(policyKitDir, "localauthority.conf.d", adsysBaseConfName+".conf"),
+		policyKitDir
+}
+
+// policyContent parses our rules and returns the content of the sudoers and policykit configuration files.
+func policyContent(ctx context.Context, entries []entry.Entry, systemPolkitAdmins string) (sudoers, polkit string) {
 	var headerWritten bool
 	header := `# This file is managed by adsys.
 # Do not edit this file manually.
@@ -156,7 +226,7 @@ func (m *Manager) ApplyPolicy(ctx context.Context, objectName string, isComputer
 			}
 
 			var polkitElem []string
-			for _, e := range splitAndNormalizeUsersAndGroups(ctx, entry.Value) {
+			for _, e := range SplitAndNormalizeUsersAndGroups(ctx, entry.Value) {
 				contentSudo += fmt.Sprintf("\"%s\"	ALL=(ALL:ALL) ALL\n", e)
 				polkitID := fmt.Sprintf("unix-user:%s", e)
 				if strings.HasPrefix(e, "%") {
@@ -170,10 +240,7 @@ func (m *Manager) ApplyPolicy(ctx context.Context, objectName string, isComputer
 			polkitAdditionalUsersGroups = polkitElem
 		}
 
-		// Write to our files
-		if _, err := sudoersF.WriteString(contentSudo + "\n"); err != nil {
-			return err
-		}
+		sudoers += contentSudo + "\n"
 		headerWritten = true
 	}
 	// PolicyKitConf files depends on multiple keys, so we need to write it at the end
@@ -188,26 +255,17 @@ func (m *Manager) ApplyPolicy(ctx context.Context, objectName string, isComputer
 			users = systemPolkitAdmins + users
 		}
 
-		if _, err := policyKitConfF.WriteString(fmt.Sprintf("%s[Configuration]\nAdminIdentities=%s", header, users) + "\n"); err != nil {
-			return err
-		}
-	}
-
-	// Move temp files to their final destination
-	if err := os.Rename(sudoersConf+".new", sudoersConf); err != nil {
-		return err
-	}
-	if err := os.Rename(policyKitConf+".new", policyKitConf); err != nil {
-		return err
+		polkit = fmt.Sprintf("%s[Configuration]\nAdminIdentities=%s", header, users) + "\n"
 	}
 
-	return nil
+	return sudoers, polkit
 }
 
-// splitAndNormalizeUsersAndGroups allow splitting on lines and ,.
+// SplitAndNormalizeUsersAndGroups allow splitting on lines and ,.
 // We remove any invalid characters and empty elements.
-// All will have the form of user@domain.
-func splitAndNormalizeUsersAndGroups(ctx context.Context, v string) []string {
+// All will have the form of user@domain, groups being prefixed with %.
+// It is shared with other managers referencing AD users and groups.
+func SplitAndNormalizeUsersAndGroups(ctx context.Context, v string) []string {
 	var elems []string
 	elems = append(elems, strings.Split(v, "\n")...)
 	v = strings.Join(elems, ",")
@@ -15,6 +15,7 @@ package proxy
 import (
 	"context"
 	"errors"
+	"fmt"
 	"slices"
 	"strings"
 
@@ -22,6 +23,8 @@ import (
 	"github.com/leonelquinteros/gotext"
 	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
 	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/adsys/internal/policies/plan"
+	"github.com/ubuntu/adsys/internal/policies/transaction"
 	"github.com/ubuntu/decorate"
 )
 
@@ -88,14 +91,7 @@ func (m *Manager) ApplyPolicy(ctx context.Context, objectName string, isComputer
 		return nil
 	}
 
-	args := make(map[string]string)
-	for _, e := range entries {
-		key := e.Key[strings.LastIndex(e.Key, "/")+1:]
-		if !slices.Contains(supportedKeys, key) {
-			log.Warning(ctx, gotext.Get("Encountered unsupported key '%s' while parsing proxy entries, skipping it", key))
-		}
-		args[key] = e.Value
-	}
+	args := proxyArgs(ctx, entries)
 
 	// Idempotency is handled by the proxy manager service
 	log.Debugf(ctx, "Applying system proxy policy to %s", objectName)
@@ -103,12 +99,8 @@ func (m *Manager) ApplyPolicy(ctx context.Context, objectName string, isComputer
 	if err := m.proxyApplier.Call(
 		"com.ubuntu.ProxyManager.Apply",
 		dbus.FlagAllowInteractiveAuthorization,
-		args["http"],
-		args["https"],
-		args["ftp"],
-		args["socks"],
-		args["no-proxy"],
-		args["auto"]).Err; err != nil {
+		// http, https, ftp, socks, no-proxy and auto
+		args[0], args[1], args[2], args[3], args[4], args[5]).Err; err != nil {
 		var dbusErr dbus.Error
 		if errors.As(err, &dbusErr) && dbusErr.Name == errDBusServiceUnknownName {
 			log.Warning(ctx, gotext.Get("Not applying proxy settings as ubuntu-proxy-manager is not installed: %s", dbusErr.Error()))
@@ -119,3 +111,57 @@ func (m *Manager) ApplyPolicy(ctx context.Context, objectName string, isComputer
 
 	return nil
 }
+
+// Prepare registers into tx the previously applied proxy entries, so that they are applied again if applying
+// the policies fails. Proxy settings are written by ubuntu-proxy-manager, so there is no file to back up.
+func (m *Manager) Prepare(ctx context.Context, objectName string, isComputer bool, previous []entry.Entry, tx *transaction.Transaction) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't prepare proxy policy"))
+
+	// Proxy policies are currently only supported on computers
+	if !isComputer {
+		return nil
+	}
+
+	log.Debugf(ctx, "Preparing system proxy policy for %s", objectName)
+
+	tx.OnAbort(func(ctx context.Context) error {
+		return m.ApplyPolicy(ctx, objectName, isComputer, previous)
+	})
+	return nil
+}
+
+// Plan returns the call ApplyPolicy would make to ubuntu-proxy-manager, without doing it.
+func (m *Manager) Plan(ctx context.Context, objectName string, isComputer bool, entries []entry.Entry) (changes []plan.Change, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't plan proxy policy"))
+
+	if !isComputer || len(entries) == 0 {
+		return nil, nil
+	}
+
+	log.Debugf(ctx, "Planning system proxy policy for %s", objectName)
+
+	args := proxyArgs(ctx, entries)
+	var callArgs []string
+	for i, key := range supportedKeys {
+		callArgs = append(callArgs, fmt.Sprintf("%s=%q", key, args[i]))
+	}
+	return []plan.Change{plan.Call("com.ubuntu.ProxyManager.Apply", callArgs...)}, nil
+}
+
+// proxyArgs returns the values of the supported keys from entries, in the order of supportedKeys.
+func proxyArgs(ctx context.Context, entries []entry.Entry) []string {
+	values := make(map[string]string)
+	for _, e := range entries {
+		key := e.Key[strings.LastIndex(e.Key, "/")+1:]
+		if !slices.Contains(supportedKeys, key) {
+			log.Warning(ctx, gotext.Get("Encountered unsupported key '%s' while parsing proxy entries, skipping it", key))
+		}
+		values[key] = e.Value
+	}
+
+	args := make([]string, 0, len(supportedKeys))
+	for _, key := range supportedKeys {
+		args = append(args, values[key])
+	}
+	return args
+}
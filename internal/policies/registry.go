@@ -0,0 +1,234 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/leonelquinteros/gotext"
+	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
+	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/adsys/internal/policies/plan"
+	"github.com/ubuntu/adsys/internal/policies/transaction"
+	"github.com/ubuntu/decorate"
+	"golang.org/x/sync/errgroup"
+)
+
+// PolicyManager is a policy handler for a given rule type.
+type PolicyManager interface {
+	// Prepare backs up into tx the current state of the system the manager is responsible for,
+	// so that it can be restored if applying the policies fails.
+	Prepare(ctx context.Context, req Request, tx *transaction.Transaction) error
+	// ApplyPolicy applies the rules of the request on the system.
+	ApplyPolicy(ctx context.Context, req Request) error
+	// Plan returns the changes ApplyPolicy would make on the system, without applying anything.
+	Plan(ctx context.Context, req Request) ([]plan.Change, error)
+}
+
+// AssetsDumper is a function which uncompress policies assets to a directory.
+type AssetsDumper func(ctx context.Context, relSrc, dest string, uid int, gid int) (err error)
+
+// Request is the policy a manager is requested to handle for a given object.
+type Request struct {
+	ObjectName string
+	IsComputer bool
+
+	// Entries are the rules of the manager type to apply.
+	Entries []entry.Entry
+	// Previous are the rules of the manager type applied during the last update, if any.
+	Previous []entry.Entry
+	// SaveAssetsTo uncompresses the policies assets to a directory.
+	SaveAssetsTo AssetsDumper
+}
+
+// Registration declares a policy manager and how it is run.
+type Registration struct {
+	// Name is the rule type handled by the manager.
+	Name string
+	// Manager is the policy handler for this rule type.
+	Manager PolicyManager
+
+	// Machine and User declare which kind of objects the manager applies policies to.
+	Machine bool
+	User    bool
+	// ProOnly filters out the rules of this type if the machine is not enrolled to Ubuntu Pro.
+	ProOnly bool
+	// After lists the managers which need to be applied before this one.
+	After []string
+}
+
+// registry is the list of registered policy managers, sorted in the order they should be applied.
+type registry []Registration
+
+// newRegistry validates the registrations and returns them in an order which respects their dependencies.
+// Registrations which don't depend on each other are kept in their original order.
+func newRegistry(regs []Registration) (r registry, err error) {
+	defer decorate.OnError(&err, gotext.Get("invalid policy managers registration"))
+
+	names := make(map[string]struct{})
+	for _, reg := range regs {
+		if reg.Name == "" {
+			return nil, errors.New(gotext.Get("policy manager has no name"))
+		}
+		if _, exists := names[reg.Name]; exists {
+			return nil, errors.New(gotext.Get("policy manager %q is registered multiple times", reg.Name))
+		}
+		if reg.Manager == nil {
+			return nil, errors.New(gotext.Get("policy manager %q has no handler", reg.Name))
+		}
+		if !reg.Machine && !reg.User {
+			return nil, errors.New(gotext.Get("policy manager %q applies neither to machines nor users", reg.Name))
+		}
+		names[reg.Name] = struct{}{}
+	}
+	for _, reg := range regs {
+		for _, dep := range reg.After {
+			if _, exists := names[dep]; !exists {
+				return nil, errors.New(gotext.Get("policy manager %q depends on unknown policy manager %q", reg.Name, dep))
+			}
+		}
+	}
+
+	// Pick the first registration with all its dependencies already sorted, until none are left.
+	sorted := make(map[string]struct{})
+	for len(r) < len(regs) {
+		i := slices.IndexFunc(regs, func(reg Registration) bool {
+			if _, done := sorted[reg.Name]; done {
+				return false
+			}
+			for _, dep := range reg.After {
+				if _, done := sorted[dep]; !done {
+					return false
+				}
+			}
+			return true
+		})
+		if i < 0 {
+			return nil, errors.New(gotext.Get("policy managers have cyclic dependencies"))
+		}
+		r = append(r, regs[i])
+		sorted[regs[i].Name] = struct{}{}
+	}
+
+	return r, nil
+}
+
+// validPlugins returns the plugin registrations which can be registered along with regs, in their original order.
+// Plugins depending on unknown policy managers or with cyclic dependencies are skipped with a warning, so that they
+// don't prevent other policies from being applied.
+func validPlugins(ctx context.Context, regs, plugins []Registration) (valid []Registration) {
+	valid = slices.Clone(plugins)
+
+	// Skipping a plugin can make the ones depending on it invalid too.
+	for {
+		known := make(map[string]struct{})
+		for _, reg := range slices.Concat(regs, valid) {
+			known[reg.Name] = struct{}{}
+		}
+		i := slices.IndexFunc(valid, func(reg Registration) bool {
+			return slices.ContainsFunc(reg.After, func(dep string) bool {
+				_, exists := known[dep]
+				return !exists
+			})
+		})
+		if i < 0 {
+			break
+		}
+		log.Warning(ctx, gotext.Get("Ignoring policy plugin %q: it depends on unknown policy managers %v", valid[i].Name, valid[i].After))
+		valid = slices.Delete(valid, i, i+1)
+	}
+
+	// Plugins which can't be sorted after the built-in managers are part of, or depend on, a cycle.
+	sorted := make(map[string]struct{})
+	for _, reg := range regs {
+		sorted[reg.Name] = struct{}{}
+	}
+	for {
+		i := slices.IndexFunc(valid, func(reg Registration) bool {
+			if _, done := sorted[reg.Name]; done {
+				return false
+			}
+			for _, dep := range reg.After {
+				if _, done := sorted[dep]; !done {
+					return false
+				}
+			}
+			return true
+		})
+		if i < 0 {
+			break
+		}
+		sorted[valid[i].Name] = struct{}{}
+	}
+	return slices.DeleteFunc(valid, func(reg Registration) bool {
+		if _, done := sorted[reg.Name]; done {
+			return false
+		}
+		log.Warning(ctx, gotext.Get("Ignoring policy plugin %q: it has cyclic dependencies", reg.Name))
+		return true
+	})
+}
+
+// forObject returns the registrations which apply to a machine or user object.
+func (r registry) forObject(isComputer bool) registry {
+	var regs registry
+	for _, reg := range r {
+		if (isComputer && !reg.Machine) || (!isComputer && !reg.User) {
+			continue
+		}
+		regs = append(regs, reg)
+	}
+	return regs
+}
+
+// proOnly returns the rule types only available to Ubuntu Pro subscribers, in application order.
+func (r registry) proOnly() []string {
+	var rules []string
+	for _, reg := range r {
+		if reg.ProOnly {
+			rules = append(rules, reg.Name)
+		}
+	}
+	return rules
+}
+
+// run calls f for each registration concurrently, only once all their dependencies in r succeeded.
+// It returns the first error encountered.
+func (r registry) run(f func(Registration) error) error {
+	type result struct {
+		done chan struct{}
+		err  error
+	}
+	results := make(map[string]*result)
+	for _, reg := range r {
+		results[reg.Name] = &result{done: make(chan struct{})}
+	}
+
+	var g errgroup.Group
+	for _, reg := range r {
+		g.Go(func() error {
+			res := results[reg.Name]
+			// The result is only read by dependent managers once done is closed.
+			defer close(res.done)
+
+			for _, dep := range reg.After {
+				// Dependencies which don't apply to this object are ignored.
+				depRes, ok := results[dep]
+				if !ok {
+					continue
+				}
+				<-depRes.done
+				// The dependency error is already reported.
+				if depRes.err != nil {
+					res.err = depRes.err
+					return nil
+				}
+			}
+
+			res.err = f(reg)
+			return res.err
+		})
+	}
+
+	return g.Wait()
+}
@@ -0,0 +1,327 @@
+package policies_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/ubuntu/adsys/internal/consts"
+	"github.com/ubuntu/adsys/internal/policies"
+	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/adsys/internal/policies/plan"
+	"github.com/ubuntu/adsys/internal/policies/transaction"
+	"github.com/ubuntu/adsys/internal/testutils"
+)
+
+func TestWithPolicyManager(t *testing.T) {
+	//t.Parallel()
+
+	bus := testutils.NewDbusConn(t)
+
+	subscriptionDbus := bus.Object(consts.SubscriptionDbusRegisteredName,
+		dbus.ObjectPath(consts.SubscriptionDbusObjectPath))
+
+	tests := map[string]struct {
+		registrations   []policies.Registration
+		isNotComputer   bool
+		isNotSubscribed bool
+
+		wantCalls        []string
+		wantProOnlyRules []string
+		wantNewErr       bool
+		wantApplyErr     bool
+	}{
+		"Custom manager is applied with its rules": {
+			registrations: []policies.Registration{{Name: "first", Machine: true}},
+			wantCalls:     []string{"prepare first", "apply first [first-key=first-value]"},
+		},
+		"Custom managers are applied after their dependencies": {
+			registrations: []policies.Registration{
+				{Name: "second", Machine: true, After: []string{"first"}},
+				{Name: "first", Machine: true},
+			},
+			wantCalls: []string{"prepare first", "prepare second", "apply first [first-key=first-value]", "apply second [second-key=second-value]"},
+		},
+		"Custom manager can depend on a built-in manager": {
+			registrations: []policies.Registration{{Name: "first", Machine: true, After: []string{"dconf"}}},
+			wantCalls:     []string{"prepare first", "apply first [first-key=first-value]"},
+		},
+		"Dependencies not applying to the object are ignored": {
+			registrations: []policies.Registration{{Name: "first", User: true, After: []string{"gdm"}}},
+			isNotComputer: true,
+			wantCalls:     []string{"prepare first", "apply first [first-key=first-value]"},
+		},
+		"Machine only manager is not applied to users": {
+			registrations: []policies.Registration{{Name: "first", Machine: true}, {Name: "second", User: true}},
+			isNotComputer: true,
+			wantCalls:     []string{"prepare second", "apply second [second-key=second-value]"},
+		},
+		"User only manager is not applied to machines": {
+			registrations: []policies.Registration{{Name: "first", Machine: true}, {Name: "second", User: true}},
+			wantCalls:     []string{"prepare first", "apply first [first-key=first-value]"},
+		},
+		"Pro only rules of custom managers are declared": {
+			registrations:    []policies.Registration{{Name: "first", Machine: true, ProOnly: true}, {Name: "second", Machine: true, After: []string{"first"}}},
+			wantCalls:        []string{"prepare first", "prepare second", "apply first [first-key=first-value]", "apply second [second-key=second-value]"},
+			wantProOnlyRules: []string{"privilege", "scripts", "mount", "apparmor", "proxy", "certificate", "systemaccess", "firewall", "packages", "localgroups", "units", "environment", "first"},
+		},
+		"Pro only rules of custom managers are filtered without subscription": {
+			registrations:    []policies.Registration{{Name: "first", Machine: true, ProOnly: true}, {Name: "second", Machine: true, After: []string{"first"}}},
+			isNotSubscribed:  true,
+			wantCalls:        []string{"prepare first", "prepare second", "apply first []", "apply second [second-key=second-value]"},
+			wantProOnlyRules: []string{"privilege", "scripts", "mount", "apparmor", "proxy", "certificate", "systemaccess", "firewall", "packages", "localgroups", "units", "environment", "first"},
+		},
+
+		// Error cases
+		"Error on applying a failing manager skips dependent managers and restores all of them": {
+			registrations: []policies.Registration{
+				{Name: "failing", Machine: true},
+				{Name: "second", Machine: true, After: []string{"failing"}},
+			},
+			wantCalls:    []string{"prepare failing", "prepare second", "apply failing [failing-key=failing-value]", "abort second", "abort failing"},
+			wantApplyErr: true,
+		},
+		"Error on manager without name":              {registrations: []policies.Registration{{Machine: true}}, wantNewErr: true},
+		"Error on manager registered multiple times": {registrations: []policies.Registration{{Name: "dconf", Machine: true}}, wantNewErr: true},
+		"Error on manager applying to no object":     {registrations: []policies.Registration{{Name: "first"}}, wantNewErr: true},
+		"Error on manager depending on unknown one":  {registrations: []policies.Registration{{Name: "first", Machine: true, After: []string{"unknown"}}}, wantNewErr: true},
+		"Error on managers with cyclic dependencies": {
+			registrations: []policies.Registration{
+				{Name: "first", Machine: true, After: []string{"second"}},
+				{Name: "second", Machine: true, After: []string{"first"}},
+			},
+			wantNewErr: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			//t.Parallel()
+
+			require.NoError(t, subscriptionDbus.SetProperty(consts.SubscriptionDbusInterface+".Attached", !tc.isNotSubscribed), "Setup: can not set subscription status")
+			defer func() {
+				require.NoError(t, subscriptionDbus.SetProperty(consts.SubscriptionDbusInterface+".Attached", false), "Teardown: can not restore subscription status")
+			}()
+
+			fakeRootDir := t.TempDir()
+			calls := &mockPolicyManagerCalls{}
+			opts := []policies.Option{
+				policies.WithCacheDir(filepath.Join(fakeRootDir, "var", "cache", "adsys")),
+				policies.WithStateDir(filepath.Join(fakeRootDir, "var", "lib", "adsys")),
+				policies.WithRunDir(filepath.Join(fakeRootDir, "run", "adsys")),
+				policies.WithShareDir(filepath.Join(fakeRootDir, "usr", "share", "adsys")),
+				policies.WithDconfDir(filepath.Join(fakeRootDir, "etc", "dconf")),
+				policies.WithPolicyKitDir(filepath.Join(fakeRootDir, "etc", "polkit-1")),
+				policies.WithSudoersDir(filepath.Join(fakeRootDir, "etc", "sudoers.d")),
+				policies.WithSecurityDir(filepath.Join(fakeRootDir, "etc", "security")),
+				policies.WithNftablesDir(filepath.Join(fakeRootDir, "etc", "nftables.d")),
+				policies.WithEnvironmentDir(filepath.Join(fakeRootDir, "etc", "environment.d")),
+				policies.WithProfileDir(filepath.Join(fakeRootDir, "etc", "profile.d")),
+				policies.WithApparmorDir(filepath.Join(fakeRootDir, "etc", "apparmor.d", "adsys")),
+				policies.WithApparmorFsDir(filepath.Join(fakeRootDir, "sys", "kernel", "security", "apparmor")),
+				policies.WithApparmorParserCmd([]string{"/bin/true"}),
+				policies.WithNftCmd([]string{"/bin/true"}),
+				policies.WithPackagesExecutor(testutils.MockPackagesExecutor{}),
+				policies.WithGroupFile(filepath.Join("testdata", "group")),
+				policies.WithGpasswdCmd([]string{"/bin/true"}),
+				policies.WithCertAutoenrollCmd([]string{"/bin/true"}),
+				policies.WithSystemUnitDir(filepath.Join(fakeRootDir, "etc", "systemd", "system")),
+				policies.WithProxyApplier(&mockProxyApplier{}),
+				policies.WithSystemdCaller(&testutils.MockSystemdCaller{}),
+			}
+			var rules = make(map[string][]entry.Entry)
+			for _, r := range tc.registrations {
+				if r.Manager == nil && r.Name != "" {
+					r.Manager = &mockPolicyManager{name: r.Name, calls: calls}
+				}
+				opts = append(opts, policies.WithPolicyManager(r))
+				rules[r.Name] = []entry.Entry{{Key: r.Name + "-key", Value: r.Name + "-value"}}
+			}
+
+			m, err := policies.NewManager(bus, "hostname", mockBackend{}, opts...)
+			if tc.wantNewErr {
+				require.Error(t, err, "NewManager should return an error but got none")
+				return
+			}
+			require.NoError(t, err, "NewManager should return no error but got one")
+
+			if tc.wantProOnlyRules == nil {
+				tc.wantProOnlyRules = []string{"privilege", "scripts", "mount", "apparmor", "proxy", "certificate", "systemaccess", "firewall", "packages", "localgroups", "units", "environment"}
+			}
+			require.Equal(t, tc.wantProOnlyRules, m.ProOnlyRules(), "ProOnlyRules should list all pro only rule types in application order")
+
+			pols, err := policies.New(context.Background(), []policies.GPO{{ID: "{GPOId}", Name: "GPOName", Rules: rules}}, "")
+			require.NoError(t, err, "Setup: can not create policies")
+
+			objectName := "hostname"
+			if tc.isNotComputer {
+				// Use current user as scripts and mount managers need an existing user.
+				objectName = currentUser(t)
+			}
+			err = m.ApplyPolicies(context.Background(), objectName, !tc.isNotComputer, &pols)
+			if tc.wantApplyErr {
+				require.Error(t, err, "ApplyPolicies should return an error but got none")
+			} else {
+				require.NoError(t, err, "ApplyPolicies should return no error but got one")
+			}
+
+			require.Equal(t, tc.wantCalls, calls.get(), "Policy managers should be called in the expected order")
+		})
+	}
+}
+
+// mockPolicyManagerCalls records the calls made to all mock policy managers.
+type mockPolicyManagerCalls struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (c *mockPolicyManagerCalls) add(call string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, call)
+}
+
+func (c *mockPolicyManagerCalls) get() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls
+}
+
+// mockPolicyManager is a custom policy manager recording its calls.
+// The manager named "failing" fails to apply its policy.
+type mockPolicyManager struct {
+	name  string
+	calls *mockPolicyManagerCalls
+}
+
+func (m *mockPolicyManager) Prepare(_ context.Context, _ policies.Request, tx *transaction.Transaction) error {
+	m.calls.add("prepare " + m.name)
+	tx.OnAbort(func(context.Context) error {
+		m.calls.add("abort " + m.name)
+		return nil
+	})
+	return nil
+}
+
+func (m *mockPolicyManager) ApplyPolicy(_ context.Context, req policies.Request) error {
+	// Give time to any wrongly concurrent manager to be applied first.
+	time.Sleep(10 * time.Millisecond)
+
+	var entries []string
+	for _, e := range req.Entries {
+		entries = append(entries, e.Key+"="+e.Value)
+	}
+	m.calls.add("apply " + m.name + " [" + strings.Join(entries, " ") + "]")
+
+	if m.name == "failing" {
+		return errors.New("apply error")
+	}
+	return nil
+}
+
+func (m *mockPolicyManager) Plan(context.Context, policies.Request) ([]plan.Change, error) {
+	return nil, nil
+}
+
+// currentUser returns the name of the user running the tests.
+func currentUser(t *testing.T) string {
+	t.Helper()
+
+	u, err := user.Current()
+	require.NoError(t, err, "Setup: can't get current user")
+	return u.Username
+}
+
+func TestWithPluginsDir(t *testing.T) {
+	//t.Parallel()
+
+	bus := testutils.NewDbusConn(t)
+
+	subscriptionDbus := bus.Object(consts.SubscriptionDbusRegisteredName,
+		dbus.ObjectPath(consts.SubscriptionDbusObjectPath))
+	require.NoError(t, subscriptionDbus.SetProperty(consts.SubscriptionDbusInterface+".Attached", true), "Setup: can not set subscription status")
+	defer func() {
+		require.NoError(t, subscriptionDbus.SetProperty(consts.SubscriptionDbusInterface+".Attached", false), "Teardown: can not restore subscription status")
+	}()
+
+	// Plugins applying to machines, which record their requests.
+	pluginsDir := t.TempDir()
+	plugin := "#!/bin/sh\ncat >> \"$0.requests\"\necho >> \"$0.requests\"\necho '{\"version\": 1, \"machine\": true}'\n"
+	for _, name := range []string{"myplugin", "dconf"} {
+		// #nosec G306. We want this plugin to be executable.
+		require.NoError(t, os.WriteFile(filepath.Join(pluginsDir, name), []byte(plugin), 0700), "Setup: can't create plugin")
+	}
+	// Plugins with invalid dependencies, which are skipped.
+	for name, after := range map[string]string{
+		"unknowndep":   `"unknown"`,
+		"dependsonbad": `"unknowndep"`,
+		"cyclic1":      `"cyclic2"`,
+		"cyclic2":      `"cyclic1"`,
+		"dependsoncyc": `"cyclic1", "dconf"`,
+	} {
+		describe := fmt.Sprintf("#!/bin/sh\necho '{\"version\": 1, \"machine\": true, \"after\": [%s]}'\n", after)
+		// #nosec G306. We want this plugin to be executable.
+		require.NoError(t, os.WriteFile(filepath.Join(pluginsDir, name), []byte(describe), 0700), "Setup: can't create plugin")
+	}
+
+	fakeRootDir := t.TempDir()
+	loadedPoliciesFile := filepath.Join(fakeRootDir, "sys", "kernel", "security", "apparmor", "profiles")
+	require.NoError(t, os.MkdirAll(filepath.Dir(loadedPoliciesFile), 0700), "Setup: can not create loadedPoliciesFile dir")
+	require.NoError(t, os.WriteFile(loadedPoliciesFile, []byte("someprofile (enforce)\n"), 0600), "Setup: can not create loadedPoliciesFile")
+
+	m, err := policies.NewManager(bus, "hostname", mockBackend{},
+		policies.WithCacheDir(filepath.Join(fakeRootDir, "var", "cache", "adsys")),
+		policies.WithStateDir(filepath.Join(fakeRootDir, "var", "lib", "adsys")),
+		policies.WithRunDir(filepath.Join(fakeRootDir, "run", "adsys")),
+		policies.WithShareDir(filepath.Join(fakeRootDir, "usr", "share", "adsys")),
+		policies.WithDconfDir(filepath.Join(fakeRootDir, "etc", "dconf")),
+		policies.WithPolicyKitDir(filepath.Join(fakeRootDir, "etc", "polkit-1")),
+		policies.WithSudoersDir(filepath.Join(fakeRootDir, "etc", "sudoers.d")),
+		policies.WithSecurityDir(filepath.Join(fakeRootDir, "etc", "security")),
+		policies.WithNftablesDir(filepath.Join(fakeRootDir, "etc", "nftables.d")),
+		policies.WithEnvironmentDir(filepath.Join(fakeRootDir, "etc", "environment.d")),
+		policies.WithProfileDir(filepath.Join(fakeRootDir, "etc", "profile.d")),
+		policies.WithApparmorDir(filepath.Join(fakeRootDir, "etc", "apparmor.d", "adsys")),
+		policies.WithApparmorFsDir(filepath.Dir(loadedPoliciesFile)),
+		policies.WithApparmorParserCmd([]string{"/bin/true"}),
+		policies.WithNftCmd([]string{"/bin/true"}),
+		policies.WithPackagesExecutor(testutils.MockPackagesExecutor{}),
+		policies.WithGroupFile(filepath.Join("testdata", "group")),
+		policies.WithGpasswdCmd([]string{"/bin/true"}),
+		policies.WithCertAutoenrollCmd([]string{"/bin/true"}),
+		policies.WithSystemUnitDir(filepath.Join(fakeRootDir, "etc", "systemd", "system")),
+		policies.WithProxyApplier(&mockProxyApplier{}),
+		policies.WithSystemdCaller(&testutils.MockSystemdCaller{}),
+		policies.WithPluginsDir(pluginsDir),
+	)
+	require.NoError(t, err, "NewManager should return no error but got one")
+
+	require.Equal(t, []string{"privilege", "scripts", "mount", "apparmor", "proxy", "certificate", "systemaccess", "firewall", "packages", "localgroups", "units", "environment", "myplugin"}, m.ProOnlyRules(),
+		"Plugins should be registered as pro only rules, and plugins with invalid dependencies skipped")
+
+	pols, err := policies.New(context.Background(), []policies.GPO{{ID: "{GPOId}", Name: "GPOName", Rules: map[string][]entry.Entry{
+		"myplugin": {{Key: "mykey", Value: "myvalue"}},
+	}}}, "")
+	require.NoError(t, err, "Setup: can not create policies")
+	err = m.ApplyPolicies(context.Background(), "hostname", true, &pols)
+	require.NoError(t, err, "ApplyPolicies should return no error but got one")
+
+	got, err := os.ReadFile(filepath.Join(pluginsDir, "myplugin.requests"))
+	require.NoError(t, err, "Setup: can't read plugin requests")
+	require.Contains(t, string(got), `{"version":1,"action":"apply","object_name":"hostname","is_computer":true,"entries":[{"key":"mykey","value":"myvalue"}]}`,
+		"Plugin should have received its entries")
+
+	// The plugin conflicting with a built-in manager is ignored.
+	got, err = os.ReadFile(filepath.Join(pluginsDir, "dconf.requests"))
+	require.NoError(t, err, "Setup: can't read plugin requests")
+	require.NotContains(t, string(got), `"action":"apply"`, "Plugin conflicting with a built-in manager should not be applied")
+}
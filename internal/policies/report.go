@@ -0,0 +1,164 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/leonelquinteros/gotext"
+	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
+	"github.com/ubuntu/decorate"
+	"gopkg.in/yaml.v3"
+)
+
+// ReportsCacheBaseName is the base directory where we keep the report of the last policies application.
+const ReportsCacheBaseName = "reports"
+
+// Outcome is the result of a policy manager during the last policies application.
+type Outcome string
+
+const (
+	// OutcomeApplied means that the manager applied rules which changed since the previous application.
+	OutcomeApplied Outcome = "applied"
+	// OutcomeUnchanged means that the manager applied the same rules than during the previous application.
+	OutcomeUnchanged Outcome = "unchanged"
+	// OutcomeSkippedPro means that the manager rules were filtered out as the machine is not enrolled to Ubuntu Pro.
+	OutcomeSkippedPro Outcome = "skipped-pro"
+	// OutcomeFailed means that the manager failed to apply its rules.
+	OutcomeFailed Outcome = "failed"
+)
+
+// Reporter is implemented by policy managers which have more to report about their outcome than their error,
+// for instance because they run operations in the background.
+type Reporter interface {
+	// Details returns the lines describing the outcome of the request, displayed in the policies report.
+	Details(ctx context.Context, req Request) []string
+}
+
+// Report is the outcome of the last policies application for an object.
+type Report struct {
+	ObjectName string    `yaml:"object_name"`
+	IsComputer bool      `yaml:"is_computer"`
+	Time       time.Time `yaml:"time"`
+	// Error is the reason the whole application failed, if any.
+	Error    string          `yaml:"error,omitempty"`
+	Managers []ManagerReport `yaml:"managers"`
+}
+
+// ManagerReport is the outcome of a single policy manager.
+type ManagerReport struct {
+	Name     string        `yaml:"name"`
+	Outcome  Outcome       `yaml:"outcome"`
+	Duration time.Duration `yaml:"duration"`
+	Entries  int           `yaml:"entries"`
+	Error    string        `yaml:"error,omitempty"`
+	// Details are the lines reported by managers implementing Reporter.
+	Details []string `yaml:"details,omitempty"`
+}
+
+// newManagerReport returns the report of a manager which handled req in duration, with err as its result.
+func newManagerReport(reg Registration, req Request, subscribed bool, duration time.Duration, err error) ManagerReport {
+	r := ManagerReport{
+		Name:     reg.Name,
+		Outcome:  OutcomeApplied,
+		Duration: duration,
+		Entries:  len(req.Entries),
+	}
+	switch {
+	case err != nil:
+		r.Outcome = OutcomeFailed
+		r.Error = err.Error()
+	case reg.ProOnly && !subscribed:
+		r.Outcome = OutcomeSkippedPro
+	case slices.Equal(req.Entries, req.Previous):
+		r.Outcome = OutcomeUnchanged
+	}
+	return r
+}
+
+// saveReport stores the report of the last policies application for its object.
+func (m *Manager) saveReport(r Report) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't save policies report for %q", r.ObjectName))
+
+	d, err := yaml.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	p := filepath.Join(m.reportsCacheDir, r.ObjectName)
+	if err := os.WriteFile(p+".new", d, 0600); err != nil {
+		return err
+	}
+	return os.Rename(p+".new", p)
+}
+
+// loadReport returns the report of the last policies application for objectName.
+func (m *Manager) loadReport(objectName string) (r Report, err error) {
+	d, err := os.ReadFile(filepath.Join(m.reportsCacheDir, objectName))
+	if err != nil {
+		return Report{}, errors.New(gotext.Get("no policies report for %q: %v", objectName, err))
+	}
+	if err := yaml.Unmarshal(d, &r); err != nil {
+		return Report{}, errors.New(gotext.Get("invalid policies report for %q: %v", objectName, err))
+	}
+	return r, nil
+}
+
+// Report displays the outcome of each policy manager during the last policies application for objectName.
+func (m *Manager) Report(ctx context.Context, objectName string) (msg string, err error) {
+	defer decorate.OnError(&err, gotext.Get("failed to display policies report for %q", objectName))
+
+	log.Infof(ctx, "Displaying policies report for %s", objectName)
+
+	r, err := m.loadReport(objectName)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	fmt.Fprintln(&out, gotext.Get("Policies report for %s (machine: %v), applied on %s:", r.ObjectName, r.IsComputer, r.Time.Format("Mon Jan 2 15:04:05 2006")))
+	for _, mr := range r.Managers {
+		entries := gotext.GetN("%d entry", "%d entries", mr.Entries, mr.Entries)
+		fmt.Fprintf(&out, "* %s: %s (%s, %s)\n", mr.Name, mr.Outcome, entries, mr.Duration.Round(time.Millisecond))
+		if mr.Error != "" {
+			fmt.Fprintf(&out, "    %s\n", strings.ReplaceAll(strings.TrimSpace(mr.Error), "\n", "\n    "))
+		}
+		for _, d := range mr.Details {
+			fmt.Fprintf(&out, "    - %s\n", d)
+		}
+	}
+	if r.Error != "" {
+		fmt.Fprintln(&out, gotext.Get("Policies were not applied: %s", r.Error))
+	}
+
+	return out.String(), nil
+}
+
+// LastFailures returns the reports of the policy managers which failed during the last policies application
+// for objectName.
+func (m *Manager) LastFailures(ctx context.Context, objectName string, isMachine bool) (failures []ManagerReport, err error) {
+	defer decorate.OnError(&err, gotext.Get("failed to get policies last failures %q (machine: %v)", objectName, isMachine))
+
+	log.Debugf(ctx, "Get policies last failures %q (machine: %t)", objectName, isMachine)
+
+	if isMachine {
+		objectName = m.hostname
+	}
+
+	r, err := m.loadReport(objectName)
+	if err != nil {
+		return nil, err
+	}
+	for _, mr := range r.Managers {
+		if mr.Outcome != OutcomeFailed {
+			continue
+		}
+		failures = append(failures, mr)
+	}
+	return failures, nil
+}
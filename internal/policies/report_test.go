@@ -0,0 +1,124 @@
+package policies_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/ubuntu/adsys/internal/consts"
+	"github.com/ubuntu/adsys/internal/policies"
+	"github.com/ubuntu/adsys/internal/testutils"
+)
+
+func TestReport(t *testing.T) {
+	//t.Parallel()
+
+	bus := testutils.NewDbusConn(t)
+
+	subscriptionDbus := bus.Object(consts.SubscriptionDbusRegisteredName,
+		dbus.ObjectPath(consts.SubscriptionDbusObjectPath))
+
+	tests := map[string]struct {
+		appliedPolicies []string
+		isNotSubscribed bool
+
+		wantFailures []string
+		wantErr      bool
+	}{
+		"Report of applied policies":                          {appliedPolicies: []string{"all_entry_types"}},
+		"Report of policies applied again without change":     {appliedPolicies: []string{"all_entry_types", "all_entry_types"}},
+		"Report of purged policies":                           {appliedPolicies: []string{"all_entry_types", ""}},
+		"Report of pro only policies skipped":                 {appliedPolicies: []string{"all_entry_types"}, isNotSubscribed: true},
+		"Report failing manager and the ones depending on it": {appliedPolicies: []string{"dconf_failing"}, wantFailures: []string{"dconf", "gdm"}},
+
+		// Error cases
+		"Error when policies were never applied": {wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			//t.Parallel()
+
+			fakeRootDir := t.TempDir()
+			loadedPoliciesFile := filepath.Join(fakeRootDir, "sys", "kernel", "security", "apparmor", "profiles")
+			err := os.MkdirAll(filepath.Dir(loadedPoliciesFile), 0700)
+			require.NoError(t, err, "Setup: can not create loadedPoliciesFile dir")
+			err = os.WriteFile(loadedPoliciesFile, []byte("someprofile (enforce)\n"), 0600)
+			require.NoError(t, err, "Setup: can not create loadedPoliciesFile")
+
+			require.NoError(t, subscriptionDbus.SetProperty(consts.SubscriptionDbusInterface+".Attached", !tc.isNotSubscribed), "Setup: can not set subscription status")
+			defer func() {
+				require.NoError(t, subscriptionDbus.SetProperty(consts.SubscriptionDbusInterface+".Attached", false), "Teardown: can not restore subscription status")
+			}()
+
+			m, err := policies.NewManager(bus,
+				"hostname",
+				mockBackend{},
+				policies.WithCacheDir(filepath.Join(fakeRootDir, "var", "cache", "adsys")),
+				policies.WithStateDir(filepath.Join(fakeRootDir, "var", "lib", "adsys")),
+				policies.WithRunDir(filepath.Join(fakeRootDir, "run", "adsys")),
+				policies.WithShareDir(filepath.Join(fakeRootDir, "usr", "share", "adsys")),
+				policies.WithDconfDir(filepath.Join(fakeRootDir, "etc", "dconf")),
+				policies.WithPolicyKitDir(filepath.Join(fakeRootDir, "etc", "polkit-1")),
+				policies.WithSudoersDir(filepath.Join(fakeRootDir, "etc", "sudoers.d")),
+				policies.WithSecurityDir(filepath.Join(fakeRootDir, "etc", "security")),
+				policies.WithNftablesDir(filepath.Join(fakeRootDir, "etc", "nftables.d")),
+				policies.WithEnvironmentDir(filepath.Join(fakeRootDir, "etc", "environment.d")),
+				policies.WithProfileDir(filepath.Join(fakeRootDir, "etc", "profile.d")),
+				policies.WithApparmorDir(filepath.Join(fakeRootDir, "etc", "apparmor.d", "adsys")),
+				policies.WithApparmorFsDir(filepath.Dir(loadedPoliciesFile)),
+				policies.WithApparmorParserCmd([]string{"/bin/true"}),
+				policies.WithNftCmd([]string{"/bin/true"}),
+				policies.WithPackagesExecutor(testutils.MockPackagesExecutor{}),
+				policies.WithGroupFile(filepath.Join("testdata", "group")),
+				policies.WithGpasswdCmd([]string{"/bin/true"}),
+				policies.WithCertAutoenrollCmd([]string{"/bin/true"}),
+				policies.WithSystemUnitDir(filepath.Join(fakeRootDir, "etc", "systemd", "system")),
+				policies.WithProxyApplier(&mockProxyApplier{}),
+				policies.WithSystemdCaller(&testutils.MockSystemdCaller{}),
+			)
+			require.NoError(t, err, "Setup: couldn’t get a new policy manager")
+
+			for _, p := range tc.appliedPolicies {
+				pols, err := policies.New(context.Background(), nil, "")
+				require.NoError(t, err, "Setup: can not create empty policies")
+				if p != "" {
+					pols, err = policies.NewFromCache(context.Background(), filepath.Join("testdata", "cache", "policies", p))
+					require.NoError(t, err, "Setup: can not load policies list")
+				}
+				// Failing policies are still reported.
+				_ = m.ApplyPolicies(context.Background(), "hostname", true, &pols)
+				pols.Close()
+			}
+
+			got, err := m.Report(context.Background(), "hostname")
+			if tc.wantErr {
+				require.Error(t, err, "Report should return an error but got none")
+				_, err = m.LastFailures(context.Background(), "", true)
+				require.Error(t, err, "LastFailures should return an error but got none")
+				return
+			}
+			require.NoError(t, err, "Report should return no error but got one")
+
+			// Dates and durations are not reproducible
+			got = regexp.MustCompile(`applied on .*:`).ReplaceAllString(got, "applied on #DATE#:")
+			got = regexp.MustCompile(`, [0-9.]+[µnm]?s\)`).ReplaceAllString(got, ", #DURATION#)")
+			got = strings.ReplaceAll(got, fakeRootDir, "#FAKEROOT#")
+			want := testutils.LoadWithUpdateFromGolden(t, got)
+			require.Equal(t, want, got, "Report returned expected output")
+
+			failures, err := m.LastFailures(context.Background(), "", true)
+			require.NoError(t, err, "LastFailures should return no error but got one")
+			var gotFailures []string
+			for _, f := range failures {
+				require.NotEmpty(t, f.Error, "LastFailures should return the error of the failing manager")
+				gotFailures = append(gotFailures, f.Name)
+			}
+			require.Equal(t, tc.wantFailures, gotFailures, "LastFailures should return the failing managers")
+		})
+	}
+}
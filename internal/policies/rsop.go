@@ -0,0 +1,179 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/leonelquinteros/gotext"
+	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
+	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/decorate"
+)
+
+// RSOP displays the resultant set of policies applied to objectName: for each effective key, the GPO setting it,
+// with all the GPOs whose value was overridden or appended.
+// Unless computerOnly is set, the resultant set of policies of the machine is displayed first.
+func (m *Manager) RSOP(ctx context.Context, objectName string, computerOnly bool) (msg string, err error) {
+	defer decorate.OnError(&err, gotext.Get("failed to compute resultant set of policies for %q", objectName))
+
+	log.Infof(ctx, "Computing resultant set of policies for %s", objectName)
+
+	type object struct {
+		name       string
+		isComputer bool
+	}
+	objects := []object{{name: objectName, isComputer: computerOnly}}
+	if !computerOnly {
+		objects = append([]object{{name: m.hostname, isComputer: true}}, objects...)
+	}
+
+	var out strings.Builder
+	for _, o := range objects {
+		pols, err := NewFromCache(ctx, filepath.Join(m.policiesCacheDir, o.name))
+		if err != nil {
+			return "", errors.New(gotext.Get("no policy applied for %q: %v", o.name, err))
+		}
+		fmt.Fprintln(&out, gotext.Get("Resultant set of policies for %s (machine: %v):", o.name, o.isComputer))
+		formatRSOP(&out, pols)
+		if err := pols.Close(); err != nil {
+			return "", err
+		}
+	}
+
+	return out.String(), nil
+}
+
+// rsopOutcome is what happened to the value a GPO sets for a key.
+type rsopOutcome int
+
+const (
+	rsopWinner rsopOutcome = iota
+	rsopAppended
+	rsopOverridden
+	rsopIgnoredDisabled
+)
+
+// rsopContribution is the value set by a GPO for a key, with its outcome.
+type rsopContribution struct {
+	gpo     int
+	e       entry.Entry
+	outcome rsopOutcome
+	release bool
+}
+
+// formatRSOP writes to w the GPOs of pols in order of precedence, then every effective key per rule type, with the
+// GPOs which set a value for it.
+// The outcome of each value follows the same rules than GetUniqueRules: the closest GPO wins, unless it appends,
+// prepends or merges its value, in which case all further GPOs using the same strategy are merged.
+func formatRSOP(w *strings.Builder, pols Policies) {
+	if len(pols.GPOs) == 0 {
+		fmt.Fprintln(w, gotext.Get("No GPO applied"))
+		return
+	}
+
+	fmt.Fprintln(w, gotext.Get("GPOs, by order of precedence:"))
+	for i, g := range pols.GPOs {
+		fmt.Fprintf(w, "  %d. %s %s", i+1, g.Name, g.ID)
+		if g.Link != "" {
+			fmt.Fprint(w, gotext.Get(", linked to %s", g.Link))
+		}
+		if g.Enforced {
+			fmt.Fprint(w, gotext.Get(" (enforced)"))
+		}
+		fmt.Fprintln(w)
+	}
+
+	// Collect, in order of precedence, the values set by each GPO per rule type and key.
+	contributions := make(map[string]map[string][]rsopContribution)
+	for i, g := range pols.GPOs {
+		for _, t := range g.ruleTypes() {
+			if contributions[t] == nil {
+				contributions[t] = make(map[string][]rsopContribution)
+			}
+			for _, e := range g.Rules[t] {
+				c := rsopContribution{gpo: i, e: e, release: slices.Contains(g.ReleaseOverrides[t], e.Key)}
+				contributions[t][e.Key] = append(contributions[t][e.Key], c)
+			}
+		}
+	}
+
+	unique := pols.GetUniqueRules()
+	var types []string
+	for t := range contributions {
+		types = append(types, t)
+	}
+	slices.Sort(types)
+
+	for _, t := range types {
+		fmt.Fprintf(w, "* %s:\n", t)
+
+		var keys []string
+		for k := range contributions[t] {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+
+		for _, k := range keys {
+			cs := annotateContributions(contributions[t][k])
+
+			// Display the resulting value, as applied by the policy managers.
+			v := gotext.Get("<not applied>")
+			for _, e := range unique[t] {
+				if e.Key == k {
+					v = diffValue(e)
+					break
+				}
+			}
+			fmt.Fprintf(w, "    %s: %s\n", k, v)
+
+			for _, c := range cs {
+				g := pols.GPOs[c.gpo]
+				var outcome string
+				switch c.outcome {
+				case rsopWinner:
+					outcome = gotext.Get("winner")
+				case rsopAppended:
+					outcome = gotext.Get("appended")
+				case rsopOverridden:
+					outcome = gotext.Get("overridden")
+				case rsopIgnoredDisabled:
+					outcome = gotext.Get("ignored, disabled")
+				}
+				details := []string{gotext.Get("precedence %d", c.gpo+1)}
+				if g.Enforced {
+					details = append(details, gotext.Get("enforced"))
+				}
+				if c.release {
+					details = append(details, gotext.Get("value for this release"))
+				}
+				fmt.Fprintf(w, "        %s: %s %s (%s): %s\n", outcome, g.Name, g.ID, strings.Join(details, ", "), diffValue(c.e))
+			}
+		}
+	}
+}
+
+// annotateContributions sets the outcome of each value for a key, ordered by precedence.
+func annotateContributions(cs []rsopContribution) []rsopContribution {
+	var winner *rsopContribution
+	for i := range cs {
+		c := &cs[i]
+		switch {
+		// Disabled keys are not appended.
+		case entry.IsListStrategy(c.e.Strategy) && c.e.Disabled:
+			c.outcome = rsopIgnoredDisabled
+		case winner == nil:
+			c.outcome = rsopWinner
+			winner = c
+		// Only values of the same list strategy are merged: further GPOs don't count once a closer one set the key.
+		case entry.IsListStrategy(winner.e.Strategy) && c.e.Strategy == winner.e.Strategy:
+			c.outcome = rsopAppended
+		default:
+			c.outcome = rsopOverridden
+		}
+	}
+	return cs
+}
@@ -0,0 +1,82 @@
+package policies_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/termie/go-shutil"
+	"github.com/ubuntu/adsys/internal/policies"
+	"github.com/ubuntu/adsys/internal/testutils"
+)
+
+func TestRSOP(t *testing.T) {
+	t.Parallel()
+
+	bus := testutils.NewDbusConn(t)
+
+	tests := map[string]struct {
+		machinePolicies string
+		userPolicies    string
+		computerOnly    bool
+
+		wantErr bool
+	}{
+		"Machine and user policies":                     {machinePolicies: "one_gpo", userPolicies: "three_gpos_with_append"},
+		"Machine policies only":                         {machinePolicies: "three_gpos_with_append", computerOnly: true},
+		"Links, enforced GPOs and release overrides":    {machinePolicies: "one_gpo", userPolicies: "gpos_with_links_and_release_overrides"},
+		"Overridden keys from the same and other types": {machinePolicies: "one_gpo", userPolicies: "two_gpos_with_overrides"},
+		"No GPO applied":                                {machinePolicies: "", userPolicies: ""},
+
+		// Error cases
+		"Error on no policies applied to user":    {machinePolicies: "one_gpo", userPolicies: "-", wantErr: true},
+		"Error on no policies applied to machine": {machinePolicies: "-", userPolicies: "one_gpo", wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			cacheDir := t.TempDir()
+			m, err := policies.NewManager(bus, "hostname", mockBackend{}, policies.WithCacheDir(cacheDir), policies.WithRunDir(t.TempDir()))
+			require.NoError(t, err, "Setup: couldn’t get a new policy manager")
+
+			copyPolicies := func(src, objectName string) {
+				t.Helper()
+
+				if src == "-" {
+					return
+				}
+				dst := filepath.Join(cacheDir, policies.PoliciesCacheBaseName, objectName)
+				if src == "" {
+					pols, err := policies.New(context.Background(), nil, "")
+					require.NoError(t, err, "Setup: can not create empty policies")
+					require.NoError(t, pols.Save(dst), "Setup: can not save empty policies")
+					return
+				}
+				require.NoError(t, os.MkdirAll(filepath.Dir(dst), 0750), "Setup: can not create policies parent directory")
+				err := shutil.CopyTree(filepath.Join("testdata", "cache", "policies", src), dst, nil)
+				require.NoError(t, err, "Setup: couldn’t copy policies cache")
+			}
+			copyPolicies(tc.machinePolicies, "hostname")
+
+			objectName := "user"
+			if tc.computerOnly {
+				objectName = "hostname"
+			} else {
+				copyPolicies(tc.userPolicies, objectName)
+			}
+
+			got, err := m.RSOP(context.Background(), objectName, tc.computerOnly)
+			if tc.wantErr {
+				require.Error(t, err, "RSOP should return an error but got none")
+				return
+			}
+			require.NoError(t, err, "RSOP should return no error but got one")
+
+			want := testutils.LoadWithUpdateFromGolden(t, got)
+			require.Equal(t, want, got, "RSOP returned expected output")
+		})
+	}
+}
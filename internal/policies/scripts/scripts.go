@@ -22,6 +22,7 @@ import (
 	"os/exec"
 	"os/user"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -29,6 +30,8 @@ import (
 	"github.com/ubuntu/adsys/internal/consts"
 	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
 	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/adsys/internal/policies/plan"
+	"github.com/ubuntu/adsys/internal/policies/transaction"
 	"github.com/ubuntu/decorate"
 )
 
@@ -150,33 +153,9 @@ func (m *Manager) ApplyPolicy(ctx context.Context, objectName string, isComputer
 
 	// create order files, check that the scripts existings in the destination
 	log.Debugf(ctx, "Creating script order file for user %q", objectName)
-	orderFilesContent := make(map[string][]string)
-	for _, e := range entries {
-		lifecycle := filepath.Base(e.Key)
-		for _, script := range strings.Split(e.Value, "\n") {
-			script = strings.TrimSpace(script)
-			if script == "" {
-				continue
-			}
-
-			// check that the script exists and make it executable
-			scriptFilePath := filepath.Join(scriptsPath, executableDir, script)
-			log.Debugf(ctx, "%q: found %q. Marking as executable %q", e.Key, script, scriptFilePath)
-			info, err := os.Stat(scriptFilePath)
-			if errors.Is(err, os.ErrNotExist) {
-				return errors.New(gotext.Get("script %q doesn't exist in SYSVOL scripts/ subdirectory", script))
-			}
-			if info.IsDir() {
-				return errors.New(gotext.Get("script %q is a directory and not a file to execute", script))
-			}
-			// nolint:gosec // G302 - scripts need rx permissions
-			if err := os.Chmod(scriptFilePath, 0550); err != nil {
-				return errors.New(gotext.Get("can't change mode of script %qto %o: %v", scriptFilePath, 0550, err))
-			}
-
-			// append it to the list of our scripts
-			orderFilesContent[lifecycle] = append(orderFilesContent[lifecycle], filepath.Join(executableDir, script))
-		}
+	orderFilesContent, err := orderFiles(ctx, entries, scriptsPath)
+	if err != nil {
+		return err
 	}
 
 	for lifecycle, scripts := range orderFilesContent {
@@ -221,6 +200,211 @@ func (m *Manager) ApplyPolicy(ctx context.Context, objectName string, isComputer
 	return m.unitStarter.StartUnit(ctx, consts.AdysMachineScriptsServiceName)
 }
 
+// Plan returns the changes ApplyPolicy would make for scripts policy, without applying them.
+// Assets are only dumped to a temporary directory to check that the requested scripts exist.
+func (m *Manager) Plan(ctx context.Context, objectName string, isComputer bool, entries []entry.Entry, assetsDumper AssetsDumper) (changes []plan.Change, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't plan scripts policy for %s", objectName))
+
+	log.Debugf(ctx, "Planning scripts policy for %s", objectName)
+
+	objectDir := "machine"
+	if !isComputer {
+		user, err := m.userLookup(objectName)
+		if err != nil {
+			return nil, errors.New(gotext.Get("couldn't retrieve user for %q: %v", objectName, err))
+		}
+		objectDir = filepath.Join("users", user.Uid)
+	}
+	scriptsPath := filepath.Join(m.runDir, objectDir, executableDir)
+
+	if _, err := os.Stat(filepath.Join(scriptsPath, inSessionFlag)); err == nil {
+		log.Infof(ctx, "%q already exists, a session is already running, ignoring.", filepath.Join(scriptsPath, inSessionFlag))
+		return nil, nil
+	}
+
+	if c, ok := plan.Removal(scriptsPath); ok {
+		changes = append(changes, c)
+	}
+
+	if len(entries) == 0 {
+		return changes, nil
+	}
+
+	tmpdir, err := os.MkdirTemp("", "adsys_scripts_plan_")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpdir)
+	if err := assetsDumper(ctx, "scripts/", filepath.Join(tmpdir, "scripts"), -1, -1); err != nil {
+		return nil, err
+	}
+	orderFilesContent, err := orderFiles(ctx, entries, tmpdir)
+	if err != nil {
+		return nil, err
+	}
+
+	changes = append(changes, plan.Change{Action: plan.Create, Target: filepath.Join(scriptsPath, "scripts")})
+	lifecycles := make([]string, 0, len(orderFilesContent))
+	for lifecycle := range orderFilesContent {
+		lifecycles = append(lifecycles, lifecycle)
+	}
+	slices.Sort(lifecycles)
+	for _, lifecycle := range lifecycles {
+		changes = append(changes, plan.Change{
+			Action:  plan.Create,
+			Target:  filepath.Join(scriptsPath, lifecycle),
+			Content: strings.Join(orderFilesContent[lifecycle], "\n") + "\n",
+		})
+	}
+	changes = append(changes, plan.Change{Action: plan.Create, Target: filepath.Join(scriptsPath, readyFlag)})
+
+	if _, ok := orderFilesContent["startup"]; isComputer && ok {
+		changes = append(changes, plan.Call("systemctl", "start", consts.AdysMachineScriptsServiceName))
+	}
+
+	return changes, nil
+}
+
+// Verify returns the differences between the scripts set up for objectName and the ones ApplyPolicy would set up.
+// As ApplyPolicy always sets up the scripts again from scratch, only missing or modified files are reported.
+func (m *Manager) Verify(ctx context.Context, objectName string, isComputer bool, entries []entry.Entry, assetsDumper AssetsDumper) (changes []plan.Change, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't verify scripts policy for %s", objectName))
+
+	planned, err := m.Plan(ctx, objectName, isComputer, entries, assetsDumper)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range planned {
+		switch c.Action {
+		case plan.Remove:
+			// Scripts are left behind while no script should be set up.
+			if len(entries) == 0 {
+				changes = append(changes, c)
+			}
+		case plan.Create:
+			if c.Content != "" {
+				if c, ok := plan.File(c.Target, c.Content); ok {
+					changes = append(changes, c)
+				}
+				continue
+			}
+			if _, err := os.Stat(c.Target); err != nil {
+				changes = append(changes, c)
+			}
+		}
+	}
+
+	if len(entries) == 0 {
+		return changes, nil
+	}
+
+	// Check the content of the scripts themselves.
+	objectDir := "machine"
+	if !isComputer {
+		user, err := m.userLookup(objectName)
+		if err != nil {
+			return nil, errors.New(gotext.Get("couldn't retrieve user for %q: %v", objectName, err))
+		}
+		objectDir = filepath.Join("users", user.Uid)
+	}
+	dest := filepath.Join(m.runDir, objectDir, executableDir, "scripts")
+	// The whole directory is already reported missing, or a session is running.
+	if _, err := os.Stat(dest); err != nil || len(planned) == 0 {
+		return changes, nil
+	}
+	tmpdir, err := os.MkdirTemp("", "adsys_scripts_verify_")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpdir)
+	if err := assetsDumper(ctx, "scripts/", filepath.Join(tmpdir, "scripts"), -1, -1); err != nil {
+		return nil, err
+	}
+	err = filepath.WalkDir(filepath.Join(tmpdir, "scripts"), func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(filepath.Join(tmpdir, "scripts"), path)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if c, ok := plan.File(filepath.Join(dest, rel), string(content)); ok {
+			changes = append(changes, c)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// Prepare backs up the scripts directory of objectName into tx, so that it can be restored if applying
+// the policies fails. Scripts already started can't be reverted.
+func (m *Manager) Prepare(ctx context.Context, objectName string, isComputer bool, tx *transaction.Transaction) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't prepare scripts policy for %s", objectName))
+
+	log.Debugf(ctx, "Preparing scripts policy for %s", objectName)
+
+	objectDir := "machine"
+	if !isComputer {
+		user, err := m.userLookup(objectName)
+		if err != nil {
+			return errors.New(gotext.Get("couldn't retrieve user for %q: %v", objectName, err))
+		}
+		objectDir = filepath.Join("users", user.Uid)
+	}
+	scriptsPath := filepath.Join(m.runDir, objectDir, executableDir)
+
+	// Nothing will be changed while a session is running.
+	if _, err := os.Stat(filepath.Join(scriptsPath, inSessionFlag)); err == nil {
+		return nil
+	}
+
+	return tx.Backup(scriptsPath)
+}
+
+// orderFiles checks that the scripts listed in entries exist in scriptsPath and make them executable.
+// It returns, for each lifecycle, the ordered list of scripts to run.
+func orderFiles(ctx context.Context, entries []entry.Entry, scriptsPath string) (map[string][]string, error) {
+	orderFilesContent := make(map[string][]string)
+	for _, e := range entries {
+		lifecycle := filepath.Base(e.Key)
+		for _, script := range strings.Split(e.Value, "\n") {
+			script = strings.TrimSpace(script)
+			if script == "" {
+				continue
+			}
+
+			// check that the script exists and make it executable
+			scriptFilePath := filepath.Join(scriptsPath, executableDir, script)
+			log.Debugf(ctx, "%q: found %q. Marking as executable %q", e.Key, script, scriptFilePath)
+			info, err := os.Stat(scriptFilePath)
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, errors.New(gotext.Get("script %q doesn't exist in SYSVOL scripts/ subdirectory", script))
+			}
+			if info.IsDir() {
+				return nil, errors.New(gotext.Get("script %q is a directory and not a file to execute", script))
+			}
+			// nolint:gosec // G302 - scripts need rx permissions
+			if err := os.Chmod(scriptFilePath, 0550); err != nil {
+				return nil, errors.New(gotext.Get("can't change mode of script %qto %o: %v", scriptFilePath, 0550, err))
+			}
+
+			// append it to the list of our scripts
+			orderFilesContent[lifecycle] = append(orderFilesContent[lifecycle], filepath.Join(executableDir, script))
+		}
+	}
+
+	return orderFilesContent, nil
+}
+
 // RunScripts executes all scripts in directory if ready and not already executed.
 // allowOrderMissing will not require order to exists if we are ready to execute.
 func RunScripts(ctx context.Context, order string, allowOrderMissing bool) (err error) {
@@ -0,0 +1,249 @@
+// Package systemaccess is the policy manager for the password and account lockout policies of security templates.
+//
+// Those policies are set in the System Access section of the GptTmpl.inf security template of a GPO, like the
+// Default Domain Policy. This manager applies them to local accounts by configuring the PAM modules in charge of
+// them, under /etc/security by default:
+//   - The password policy is set in pwquality.conf.d/99-adsys-system-access.conf for pam_pwquality;
+//   - The account lockout policy is set in a block managed by adsys at the end of faillock.conf for pam_faillock,
+//     as it does not support drop-in files. The rest of the file is kept untouched.
+//
+// When no policy is set, the pwquality file and the faillock block are removed, so that the distribution
+// configuration is restored.
+// Those policies are only applied on computers.
+package systemaccess
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/leonelquinteros/gotext"
+	"github.com/ubuntu/adsys/internal/consts"
+	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
+	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/adsys/internal/policies/plan"
+	"github.com/ubuntu/adsys/internal/policies/transaction"
+	"github.com/ubuntu/decorate"
+)
+
+const (
+	adsysPwqualityConfName = "99-adsys-system-access.conf"
+
+	faillockBlockStart = "# BEGIN adsys system access policy. Do not edit this block manually."
+	faillockBlockEnd   = "# END adsys system access policy"
+
+	header = `# This file is managed by adsys.
+# Do not edit this file manually.
+# Any changes will be overwritten.
+
+`
+)
+
+// Manager handles the password and account lockout policies.
+type Manager struct {
+	securityDir string
+}
+
+// New creates a manager configuring the PAM modules in securityDir.
+func New(securityDir string) *Manager {
+	return &Manager{
+		securityDir: securityDir,
+	}
+}
+
+// ApplyPolicy configures the password and account lockout policies based on a list of entries.
+func (m *Manager) ApplyPolicy(ctx context.Context, objectName string, isComputer bool, entries []entry.Entry) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't apply system access policy to %s", objectName))
+
+	// Password and lockout policies are only set on computers.
+	if !isComputer {
+		return nil
+	}
+
+	log.Debugf(ctx, "Applying system access policy to %s", objectName)
+
+	pwqualityConf, faillockConf := m.confPaths()
+	contentPwquality, contentFaillock, err := policyContent(ctx, entries, faillockConf)
+	if err != nil {
+		return err
+	}
+
+	if err := writeOrRemove(pwqualityConf, contentPwquality); err != nil {
+		return err
+	}
+	return writeOrRemove(faillockConf, contentFaillock)
+}
+
+// Plan returns the changes ApplyPolicy would make for a system access policy, without applying them.
+func (m *Manager) Plan(ctx context.Context, objectName string, isComputer bool, entries []entry.Entry) (changes []plan.Change, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't plan system access policy for %s", objectName))
+
+	// Password and lockout policies are only set on computers.
+	if !isComputer {
+		return nil, nil
+	}
+
+	pwqualityConf, faillockConf := m.confPaths()
+	contentPwquality, contentFaillock, err := policyContent(ctx, entries, faillockConf)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range []struct{ path, content string }{
+		{pwqualityConf, contentPwquality},
+		{faillockConf, contentFaillock},
+	} {
+		c, ok := plan.File(f.path, f.content)
+		if f.content == "" {
+			c, ok = plan.Removal(f.path)
+		}
+		if ok {
+			changes = append(changes, c)
+		}
+	}
+
+	return changes, nil
+}
+
+// Prepare backs up the pwquality and faillock configuration into tx, so that they can be restored
+// if applying the policies fails.
+func (m *Manager) Prepare(ctx context.Context, objectName string, isComputer bool, tx *transaction.Transaction) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't prepare system access policy for %s", objectName))
+
+	// Password and lockout policies are only set on computers.
+	if !isComputer {
+		return nil
+	}
+
+	log.Debugf(ctx, "Preparing system access policy for %s", objectName)
+
+	pwqualityConf, faillockConf := m.confPaths()
+	return tx.Backup(pwqualityConf, faillockConf)
+}
+
+// confPaths returns the pwquality configuration file managed by adsys and the faillock configuration file.
+func (m *Manager) confPaths() (pwqualityConf, faillockConf string) {
+	securityDir := m.securityDir
+	if securityDir == "" {
+		securityDir = consts.DefaultSecurityDir
+	}
+
+	return filepath.Join(securityDir, "pwquality.conf.d", adsysPwqualityConfName),
+		filepath.Join(securityDir, "faillock.conf")
+}
+
+// policyContent returns the content of the pwquality and faillock configuration files for entries.
+// The faillock configuration is the current content of faillockConf, with the adsys block updated.
+// An empty content means that the file should be removed.
+func policyContent(ctx context.Context, entries []entry.Entry, faillockConf string) (pwquality, faillock string, err error) {
+	var pwqualitySettings, faillockSettings []string
+	for _, e := range entries {
+		if e.Disabled {
+			continue
+		}
+
+		v, err := strconv.Atoi(strings.TrimSpace(e.Value))
+		if err != nil {
+			log.Warningf(ctx, "Ignoring system access setting %s with invalid value %q: %v", e.Key, e.Value, err)
+			continue
+		}
+
+		switch e.Key {
+		case "MinimumPasswordLength":
+			// 0 means that no password is required at all, which pam_pwquality doesn't support.
+			if v <= 0 {
+				continue
+			}
+			pwqualitySettings = append(pwqualitySettings, fmt.Sprintf("minlen = %d", v))
+		case "PasswordComplexity":
+			if v != 1 {
+				continue
+			}
+			// Windows requires characters from 3 of the 4 classes, and no user name in the password.
+			pwqualitySettings = append(pwqualitySettings, "minclass = 3", "usercheck = 1")
+		case "LockoutBadCount":
+			// 0 never locks accounts out, which is the same for pam_faillock.
+			faillockSettings = append(faillockSettings, fmt.Sprintf("deny = %d", max(v, 0)))
+		case "ResetLockoutCount":
+			faillockSettings = append(faillockSettings, fmt.Sprintf("fail_interval = %d", max(v, 0)*60))
+		case "LockoutDuration":
+			// Accounts are locked out until an administrator unlocks them with 0 or -1.
+			if v <= 0 {
+				faillockSettings = append(faillockSettings, "unlock_time = never")
+				continue
+			}
+			faillockSettings = append(faillockSettings, fmt.Sprintf("unlock_time = %d", v*60))
+		default:
+			log.Debugf(ctx, "Ignoring unsupported system access setting %s", e.Key)
+		}
+	}
+
+	if pwqualitySettings != nil {
+		pwquality = header + strings.Join(pwqualitySettings, "\n") + "\n"
+	}
+
+	current, err := os.ReadFile(faillockConf)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", "", err
+	}
+	faillock = withoutAdsysBlock(string(current))
+	if faillockSettings != nil {
+		if faillock != "" && !strings.HasSuffix(faillock, "\n") {
+			faillock += "\n"
+		}
+		faillock += fmt.Sprintf("%s\n%s\n%s\n", faillockBlockStart, strings.Join(faillockSettings, "\n"), faillockBlockEnd)
+	}
+
+	return pwquality, faillock, nil
+}
+
+// withoutAdsysBlock returns content without the faillock block managed by adsys.
+func withoutAdsysBlock(content string) string {
+	var lines []string
+	var inBlock bool
+	for _, l := range strings.SplitAfter(content, "\n") {
+		switch strings.TrimSpace(l) {
+		case faillockBlockStart:
+			inBlock = true
+			continue
+		case faillockBlockEnd:
+			inBlock = false
+			continue
+		}
+		if inBlock {
+			continue
+		}
+		lines = append(lines, l)
+	}
+	return strings.Join(lines, "")
+}
+
+// writeOrRemove atomically writes content to p, or removes p if content is empty.
+// p is left untouched if it already has this content.
+func writeOrRemove(p, content string) (err error) {
+	if current, err := os.ReadFile(p); err == nil && string(current) == content {
+		return nil
+	}
+
+	if content == "" {
+		if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	// nolint:gosec // G301 match distribution permission
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	// nolint:gosec // G306 match distribution permission
+	if err := os.WriteFile(p+".new", []byte(content), 0644); err != nil {
+		return err
+	}
+	return os.Rename(p+".new", p)
+}
@@ -0,0 +1,147 @@
+package systemaccess_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/termie/go-shutil"
+	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/adsys/internal/policies/systemaccess"
+	"github.com/ubuntu/adsys/internal/testutils"
+)
+
+func TestApplyPolicy(t *testing.T) {
+	t.Parallel()
+
+	defaultDomainPolicy := []entry.Entry{
+		{Key: "MinimumPasswordLength", Value: "12"},
+		{Key: "PasswordComplexity", Value: "1"},
+		{Key: "LockoutBadCount", Value: "5"},
+		{Key: "ResetLockoutCount", Value: "15"},
+		{Key: "LockoutDuration", Value: "30"},
+	}
+
+	tests := map[string]struct {
+		notComputer      bool
+		entries          []entry.Entry
+		existingSecurity string
+		destIsDir        string
+
+		wantErr bool
+	}{
+		"Password and lockout policies": {entries: defaultDomainPolicy, existingSecurity: "distribution-files"},
+		"Password policy only":          {entries: defaultDomainPolicy[:2], existingSecurity: "distribution-files"},
+		"Lockout policy only":           {entries: defaultDomainPolicy[2:], existingSecurity: "distribution-files"},
+		"Without complexity":            {entries: []entry.Entry{{Key: "MinimumPasswordLength", Value: "8"}, {Key: "PasswordComplexity", Value: "0"}}},
+		"No minimum password length":    {entries: []entry.Entry{{Key: "MinimumPasswordLength", Value: "0"}}},
+		"Never lock out accounts":       {entries: []entry.Entry{{Key: "LockoutBadCount", Value: "0"}}},
+		"Lock out until an administrator unlocks": {entries: []entry.Entry{
+			{Key: "LockoutBadCount", Value: "5"},
+			{Key: "LockoutDuration", Value: "-1"},
+		}},
+		"No faillock configuration file":   {entries: defaultDomainPolicy},
+		"Invalid values are ignored":       {entries: []entry.Entry{{Key: "MinimumPasswordLength", Value: "twelve"}, {Key: "LockoutBadCount", Value: "5"}}},
+		"Unsupported settings are ignored": {entries: []entry.Entry{{Key: "PasswordHistorySize", Value: "24"}, {Key: "LockoutBadCount", Value: "5"}}},
+		"Disabled settings are ignored":    {entries: []entry.Entry{{Key: "MinimumPasswordLength", Value: "12", Disabled: true}, {Key: "LockoutBadCount", Value: "5"}}},
+
+		// Refresh existing configuration
+		"Replace existing adsys configuration": {entries: defaultDomainPolicy, existingSecurity: "existing-adsys-files"},
+		"No rules remove adsys configuration":  {existingSecurity: "existing-adsys-files"},
+		"No rules keep distribution files":     {existingSecurity: "distribution-files"},
+		"No rules and no existing files":       {},
+
+		// Not a computer, don’t do anything
+		"Not a computer": {notComputer: true, entries: defaultDomainPolicy, existingSecurity: "distribution-files"},
+
+		// Error cases
+		"Error if can’t rename to destination for pwquality conf file": {destIsDir: "security/pwquality.conf.d/99-adsys-system-access.conf", entries: defaultDomainPolicy, wantErr: true},
+		"Error if can’t read faillock conf file":                       {destIsDir: "security/faillock.conf", entries: defaultDomainPolicy, wantErr: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			tempEtc := t.TempDir()
+			securityDir := filepath.Join(tempEtc, "security")
+
+			if tc.existingSecurity != "" {
+				require.NoError(t,
+					shutil.CopyTree(
+						filepath.Join("testdata", tc.existingSecurity, "security"), securityDir,
+						&shutil.CopyTreeOptions{Symlinks: true, CopyFunction: shutil.Copy}),
+					"Setup: can't create initial security directory")
+			}
+			// Fake destination unwritable file
+			if tc.destIsDir != "" {
+				require.NoError(t, os.MkdirAll(filepath.Join(tempEtc, tc.destIsDir), 0750), "Setup: can't create fake unwritable file")
+			}
+
+			m := systemaccess.New(securityDir)
+			err := m.ApplyPolicy(context.Background(), "ubuntu", !tc.notComputer, tc.entries)
+			if tc.wantErr {
+				require.Error(t, err, "ApplyPolicy should have failed but didn't")
+				return
+			}
+			require.NoError(t, err, "ApplyPolicy failed but shouldn't have")
+
+			testutils.CompareTreesWithFiltering(t, tempEtc, testutils.GoldenPath(t), testutils.UpdateEnabled())
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		notComputer bool
+		entries     []entry.Entry
+
+		wantChanges []string
+	}{
+		"Changed files are planned": {
+			entries:     []entry.Entry{{Key: "MinimumPasswordLength", Value: "12"}, {Key: "LockoutBadCount", Value: "5"}},
+			wantChanges: []string{"99-adsys-system-access.conf", "faillock.conf"},
+		},
+		"Up to date files are not planned": {
+			entries:     []entry.Entry{{Key: "MinimumPasswordLength", Value: "6"}, {Key: "LockoutBadCount", Value: "3"}, {Key: "LockoutDuration", Value: "1"}},
+			wantChanges: nil,
+		},
+		"Removals are planned": {
+			wantChanges: []string{"99-adsys-system-access.conf", "faillock.conf"},
+		},
+		"Not a computer": {notComputer: true, entries: []entry.Entry{{Key: "MinimumPasswordLength", Value: "12"}}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			securityDir := filepath.Join(t.TempDir(), "security")
+			require.NoError(t,
+				shutil.CopyTree(
+					filepath.Join("testdata", "existing-adsys-files", "security"), securityDir,
+					&shutil.CopyTreeOptions{Symlinks: true, CopyFunction: shutil.Copy}),
+				"Setup: can't create initial security directory")
+
+			m := systemaccess.New(securityDir)
+			changes, err := m.Plan(context.Background(), "ubuntu", !tc.notComputer, tc.entries)
+			require.NoError(t, err, "Plan failed but shouldn't have")
+
+			var got []string
+			for _, c := range changes {
+				got = append(got, filepath.Base(c.Target))
+			}
+			require.Equal(t, tc.wantChanges, got, "Plan returns changes for the expected files")
+
+			// Planning should not touch the system.
+			content, err := os.ReadFile(filepath.Join(securityDir, "faillock.conf"))
+			require.NoError(t, err, "Teardown: can't read faillock configuration")
+			require.True(t, strings.HasSuffix(string(content), "# END adsys system access policy\n"), "Plan should not change faillock configuration")
+		})
+	}
+}
@@ -0,0 +1,7 @@
+# This file is managed by adsys.
+# Do not edit this file manually.
+# Any changes will be overwritten.
+
+export EDITOR="vim"
+export LICENSE_SERVER="27000@licence.example.com"
+export PATH="${PATH:+${PATH}:}/opt/tools/bin"
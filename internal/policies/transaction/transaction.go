@@ -0,0 +1,282 @@
+// Package transaction allows to restore the system as it was before applying policies.
+//
+// Before applying a policy, each policy manager prepares the transaction by backing up the files and
+// directories it owns, and registering any additional step needed to go back to the previous state,
+// like reloading a service.
+// Once all policy managers succeeded, the transaction is committed and the backups are discarded.
+// If any of them failed, the transaction is aborted: every step is undone in the reverse order of
+// registration, restoring the previous on-disk state of all policy managers.
+package transaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+
+	"github.com/leonelquinteros/gotext"
+	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
+	"github.com/ubuntu/decorate"
+)
+
+// Transaction records how to restore the system state as it was before applying policies.
+type Transaction struct {
+	dir string
+
+	mu       sync.Mutex
+	nBackups int
+	undo     []func(context.Context) error
+}
+
+// New returns a new transaction storing its backups in a new directory created under dir.
+// The directory name starts with prefix.
+func New(dir, prefix string) (t *Transaction, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't create a new transaction"))
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	backupDir, err := os.MkdirTemp(dir, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Transaction{dir: backupDir}, nil
+}
+
+// Backup saves the current state of paths, which can be files, symlinks or directories.
+// On abort, paths are restored to their saved content. Paths which didn't exist are removed.
+func (t *Transaction) Backup(paths ...string) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't backup %v", paths))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, p := range paths {
+		dest := filepath.Join(t.dir, strconv.Itoa(t.nBackups))
+		t.nBackups++
+
+		exists := true
+		if _, err := os.Lstat(p); errors.Is(err, fs.ErrNotExist) {
+			exists = false
+		} else if err != nil {
+			return err
+		}
+		if exists {
+			if err := copyTree(p, dest); err != nil {
+				return err
+			}
+		}
+
+		t.undo = append(t.undo, func(context.Context) error {
+			return restore(p, dest, exists)
+		})
+	}
+
+	return nil
+}
+
+// BackupMatching saves the current state of all files in dir matching pattern.
+// On abort, matching files are restored to their saved content and any new matching file is removed.
+func (t *Transaction) BackupMatching(dir, pattern string) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't backup files matching %q in %q", pattern, dir))
+
+	paths, err := filepath.Glob(filepath.Join(dir, pattern))
+	if err != nil {
+		return err
+	}
+
+	if err := t.Backup(paths...); err != nil {
+		return err
+	}
+
+	// Remove all matching files before restoring the saved ones, so that any file created after the backup is removed.
+	t.OnAbort(func(context.Context) error {
+		current, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return err
+		}
+		for _, p := range current {
+			if err := os.RemoveAll(p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return nil
+}
+
+// OnAbort registers f to be called if the transaction is aborted.
+func (t *Transaction) OnAbort(f func(context.Context) error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.undo = append(t.undo, f)
+}
+
+// Commit ends the transaction successfully and discards the backups.
+func (t *Transaction) Commit() (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't commit transaction"))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.undo = nil
+	return os.RemoveAll(t.dir)
+}
+
+// Abort restores the system as it was before the transaction started.
+// Every registered step is undone in reverse order, even if some of them failed.
+func (t *Transaction) Abort(ctx context.Context) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't abort transaction"))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	log.Info(ctx, gotext.Get("Restoring previous policies state"))
+
+	var errs []error
+	for i := len(t.undo) - 1; i >= 0; i-- {
+		if err := t.undo[i](ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	t.undo = nil
+
+	// Keep the backups around if we could not restore everything.
+	if len(errs) > 0 {
+		return fmt.Errorf("%w (%s)", errors.Join(errs...), gotext.Get("backups are kept in %s", t.dir))
+	}
+	return os.RemoveAll(t.dir)
+}
+
+// restore replaces p with its backup. If p didn't exist at backup time, it is removed.
+func restore(p, backup string, exists bool) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't restore %q", p))
+
+	if !exists {
+		return os.RemoveAll(p)
+	}
+
+	// Restore files atomically, as some of them, like sudoers, can't be partially written.
+	tmp := p + ".adsys-restore"
+	if err := os.RemoveAll(tmp); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0750); err != nil {
+		return err
+	}
+	if err := copyTree(backup, tmp); err != nil {
+		return err
+	}
+
+	// Directories can't be renamed over non empty ones.
+	if fi, err := os.Lstat(tmp); err == nil && fi.IsDir() {
+		if err := os.RemoveAll(p); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp, p)
+}
+
+// copyTree recursively copies src to dest, keeping modes, ownership and symlinks.
+func copyTree(src, dest string) error {
+	type dirMode struct {
+		path string
+		perm fs.FileMode
+	}
+	var dirs []dirMode
+
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			// Directories are writable until their whole content is copied.
+			if err := os.Mkdir(target, 0700); err != nil {
+				return err
+			}
+			dirs = append(dirs, dirMode{path: target, perm: fi.Mode().Perm()})
+		case fi.Mode()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if err := os.Symlink(link, target); err != nil {
+				return err
+			}
+		default:
+			if err := copyFile(path, target, fi.Mode().Perm()); err != nil {
+				return err
+			}
+		}
+
+		return keepOwnership(target, fi)
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Chmod(dirs[i].path, dirs[i].perm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFile copies the content of regular file src to dest, with the given permissions.
+func copyFile(src, dest string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+	// Ensure the permissions are not altered by the umask.
+	if err := out.Chmod(perm); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// keepOwnership changes the owner of p to the one of the original file, if it differs from the current user.
+func keepOwnership(p string, orig fs.FileInfo) error {
+	st, ok := orig.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	if int(st.Uid) == os.Getuid() && int(st.Gid) == os.Getgid() {
+		return nil
+	}
+	return os.Lchown(p, int(st.Uid), int(st.Gid))
+}
@@ -0,0 +1,212 @@
+package transaction_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/ubuntu/adsys/internal/policies/transaction"
+)
+
+func TestTransaction(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		backup         []string
+		backupMatching string
+		abort          bool
+		failingUndo    bool
+
+		wantErr bool
+	}{
+		"Abort restores modified file":                       {backup: []string{"file"}, abort: true},
+		"Abort restores removed file":                        {backup: []string{"removed"}, abort: true},
+		"Abort removes created file":                         {backup: []string{"new"}, abort: true},
+		"Abort restores directory content":                   {backup: []string{"dir"}, abort: true},
+		"Abort restores symlink":                             {backup: []string{"symlink"}, abort: true},
+		"Abort restores read only directory":                 {backup: []string{"readonlydir"}, abort: true},
+		"Abort restores matching files and removes new ones": {backupMatching: "*.unit", abort: true},
+		"Commit keeps new state":                             {backup: []string{"file", "removed", "new", "dir", "symlink"}, backupMatching: "*.unit"},
+
+		// Error cases
+		"Error on abort if any step failed, but other steps are restored": {backup: []string{"file", "dir"}, abort: true, failingUndo: true, wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			root := t.TempDir()
+			backupsDir := filepath.Join(t.TempDir(), "backups")
+
+			// Initial state
+			require.NoError(t, os.WriteFile(filepath.Join(root, "file"), []byte("initial content"), 0600), "Setup: can't create file")
+			require.NoError(t, os.WriteFile(filepath.Join(root, "removed"), []byte("removed content"), 0440), "Setup: can't create file")
+			require.NoError(t, os.MkdirAll(filepath.Join(root, "dir", "subdir"), 0750), "Setup: can't create directory")
+			require.NoError(t, os.WriteFile(filepath.Join(root, "dir", "subdir", "script"), []byte("#!/bin/sh"), 0550), "Setup: can't create file")
+			require.NoError(t, os.Symlink("file", filepath.Join(root, "symlink")), "Setup: can't create symlink")
+			require.NoError(t, os.MkdirAll(filepath.Join(root, "readonlydir"), 0750), "Setup: can't create directory")
+			require.NoError(t, os.WriteFile(filepath.Join(root, "readonlydir", "file"), []byte("read only"), 0600), "Setup: can't create file")
+			require.NoError(t, os.Chmod(filepath.Join(root, "readonlydir"), 0550), "Setup: can't make directory read only")
+			//nolint:errcheck // This is to allow the temporary directory to be cleaned up.
+			t.Cleanup(func() { _ = os.Chmod(filepath.Join(root, "readonlydir"), 0750) })
+			require.NoError(t, os.WriteFile(filepath.Join(root, "a.unit"), []byte("unit a"), 0600), "Setup: can't create file")
+			require.NoError(t, os.WriteFile(filepath.Join(root, "b.unit"), []byte("unit b"), 0600), "Setup: can't create file")
+
+			initialState := treeContent(t, root)
+
+			tx, err := transaction.New(backupsDir, "test-")
+			require.NoError(t, err, "New should not return an error")
+
+			var paths []string
+			for _, p := range tc.backup {
+				paths = append(paths, filepath.Join(root, p))
+			}
+			require.NoError(t, tx.Backup(paths...), "Backup should not return an error")
+			if tc.backupMatching != "" {
+				require.NoError(t, tx.BackupMatching(root, tc.backupMatching), "BackupMatching should not return an error")
+			}
+			if tc.failingUndo {
+				tx.OnAbort(func(context.Context) error { return errors.New("undo error") })
+			}
+
+			// Modify the system
+			require.NoError(t, os.WriteFile(filepath.Join(root, "file"), []byte("new content"), 0600), "Setup: can't modify file")
+			require.NoError(t, os.Remove(filepath.Join(root, "removed")), "Setup: can't remove file")
+			require.NoError(t, os.WriteFile(filepath.Join(root, "new"), []byte("new file"), 0600), "Setup: can't create file")
+			require.NoError(t, os.RemoveAll(filepath.Join(root, "dir", "subdir")), "Setup: can't remove directory")
+			require.NoError(t, os.WriteFile(filepath.Join(root, "dir", "other"), []byte("other"), 0600), "Setup: can't create file")
+			require.NoError(t, os.Remove(filepath.Join(root, "symlink")), "Setup: can't remove symlink")
+			require.NoError(t, os.Symlink("new", filepath.Join(root, "symlink")), "Setup: can't create symlink")
+			require.NoError(t, os.Chmod(filepath.Join(root, "readonlydir"), 0750), "Setup: can't make directory writable")
+			require.NoError(t, os.WriteFile(filepath.Join(root, "readonlydir", "file"), []byte("modified"), 0600), "Setup: can't modify file")
+			require.NoError(t, os.Chmod(filepath.Join(root, "readonlydir"), 0550), "Setup: can't make directory read only")
+			require.NoError(t, os.Remove(filepath.Join(root, "a.unit")), "Setup: can't remove file")
+			require.NoError(t, os.WriteFile(filepath.Join(root, "b.unit"), []byte("unit b modified"), 0600), "Setup: can't modify file")
+			require.NoError(t, os.WriteFile(filepath.Join(root, "c.unit"), []byte("unit c"), 0600), "Setup: can't create file")
+
+			modifiedState := treeContent(t, root)
+
+			if !tc.abort {
+				require.NoError(t, tx.Commit(), "Commit should not return an error")
+				require.Equal(t, modifiedState, treeContent(t, root), "Commit should keep the new state")
+				requireEmptyDir(t, backupsDir)
+				return
+			}
+
+			err = tx.Abort(context.Background())
+			if tc.wantErr {
+				require.Error(t, err, "Abort should return an error but got none")
+			} else {
+				require.NoError(t, err, "Abort should not return an error")
+				requireEmptyDir(t, backupsDir)
+			}
+
+			// Only the backed up paths are restored, anything else keeps its new state.
+			want := modifiedState
+			for _, p := range paths {
+				for k := range want {
+					if k == p || filepath.Dir(k) == p || filepath.Dir(filepath.Dir(k)) == p {
+						delete(want, k)
+					}
+				}
+				for k, v := range initialState {
+					if k == p || filepath.Dir(k) == p || filepath.Dir(filepath.Dir(k)) == p {
+						want[k] = v
+					}
+				}
+			}
+			if tc.backupMatching != "" {
+				for k := range want {
+					if ok, _ := filepath.Match(filepath.Join(root, tc.backupMatching), k); ok {
+						delete(want, k)
+					}
+				}
+				for k, v := range initialState {
+					if ok, _ := filepath.Match(filepath.Join(root, tc.backupMatching), k); ok {
+						want[k] = v
+					}
+				}
+			}
+			require.Equal(t, want, treeContent(t, root), "Abort should restore the backed up paths")
+		})
+	}
+}
+
+func TestAbortRunsStepsInReverseOrder(t *testing.T) {
+	t.Parallel()
+
+	tx, err := transaction.New(t.TempDir(), "test-")
+	require.NoError(t, err, "New should not return an error")
+
+	var got []int
+	for i := range 3 {
+		tx.OnAbort(func(context.Context) error {
+			got = append(got, i)
+			return nil
+		})
+	}
+
+	require.NoError(t, tx.Abort(context.Background()), "Abort should not return an error")
+	require.Equal(t, []int{2, 1, 0}, got, "Abort should undo steps in reverse order")
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(dir, nil, 0600), "Setup: can't create file")
+
+	_, err := transaction.New(dir, "test-")
+	require.Error(t, err, "New should fail when the backup directory can't be created")
+}
+
+// treeContent returns the mode and content of each file, symlink and directory under root, indexed by its path.
+func treeContent(t *testing.T, root string) map[string]string {
+	t.Helper()
+
+	r := make(map[string]string)
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			r[path] = info.Mode().String()
+		case info.Mode()&os.ModeSymlink != 0:
+			dest, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			r[path] = info.Mode().Type().String() + " " + dest
+		default:
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			r[path] = info.Mode().String() + " " + string(content)
+		}
+		return nil
+	})
+	require.NoError(t, err, "Setup: can't read tree content")
+
+	return r
+}
+
+// requireEmptyDir checks that dir has no content left.
+func requireEmptyDir(t *testing.T, dir string) {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err, "Can't read directory %q", dir)
+	require.Empty(t, entries, "Directory %q should be empty", dir)
+}
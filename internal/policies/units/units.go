@@ -0,0 +1,657 @@
+// Package units is the policy manager for the state of the systemd units of the machine.
+//
+// The policy lists, one unit name per line, the units to enable, disable, mask or unmask. Unit names without type
+// are services. Enabled units are started, and disabled or masked units are stopped. A unit listed with several
+// states is ignored. Each application only changes the units which are not in the requested state yet.
+//
+// The state of each unit before adsys first changed it is tracked in the state directory: when a unit is not listed
+// anymore, it is restored to this vendor or local state.
+//
+// The policy can also set drop-in overrides, one per line, of the form "<unit> <Key>=<Value>", like
+// "cups.service Restart=always". They are written in the section matching the unit type of a 90-adsys.conf drop-in
+// file, in the unit drop-in directory under /etc/systemd/system, and are removed once not listed anymore.
+// The units policy is only applied on computers.
+package units
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/leonelquinteros/gotext"
+	"github.com/ubuntu/adsys/internal/consts"
+	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
+	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/adsys/internal/policies/plan"
+	"github.com/ubuntu/adsys/internal/policies/transaction"
+	"github.com/ubuntu/adsys/internal/systemd"
+	"github.com/ubuntu/decorate"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// unitsDirName is the directory, in the state directory, where the units manager keeps its files.
+	unitsDirName = "units"
+
+	originalStatesName = "original-states"
+	dropInName         = "90-adsys.conf"
+
+	header = `# This file is managed by adsys.
+# Do not edit this file manually.
+# Any changes will be overwritten.
+
+`
+
+	stateEnabled = "enabled"
+	stateMasked  = "masked"
+)
+
+var (
+	// unitRe matches valid unit names, with an optional type suffix.
+	unitRe = regexp.MustCompile(`^[a-zA-Z0-9:_.@\\-]+$`)
+	// directiveRe matches drop-in directives.
+	directiveRe = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*=`)
+
+	// sections are the drop-in sections of the unit types supporting overrides.
+	sections = map[string]string{
+		".service":   "Service",
+		".socket":    "Socket",
+		".timer":     "Timer",
+		".path":      "Path",
+		".mount":     "Mount",
+		".automount": "Automount",
+		".swap":      "Swap",
+		".slice":     "Slice",
+		".scope":     "Scope",
+	}
+
+	// unitTypes are the suffixes of all unit types.
+	unitTypes = []string{".service", ".socket", ".timer", ".path", ".mount", ".automount", ".swap", ".slice", ".scope",
+		".target", ".device"}
+
+	// actions are the supported unit states keys, in the order conflicts are reported.
+	actions = []string{"enable", "disable", "mask", "unmask"}
+)
+
+type systemdCaller interface {
+	StartUnit(context.Context, string) error
+	StopUnit(context.Context, string) error
+	EnableUnit(context.Context, string) error
+	DisableUnit(context.Context, string) error
+	MaskUnit(context.Context, string) error
+	UnmaskUnit(context.Context, string) error
+	UnitFileState(context.Context, string) (string, error)
+	DaemonReload(context.Context) error
+}
+
+// operation is a systemd call on a unit.
+type operation struct {
+	call string
+	unit string
+}
+
+// changesUnitFile returns true if op changes the unit files, which requires a systemd reload to be taken into account.
+func (op operation) changesUnitFile() bool {
+	return op.call != "start" && op.call != "stop"
+}
+
+// change is a unit changed during an application, with its state before the change.
+type change struct {
+	unit     string
+	previous string
+}
+
+// Manager prevents running multiple units changes in parallel while applying the units policy.
+type Manager struct {
+	unitsDir      string
+	systemUnitDir string
+	systemdCaller systemdCaller
+
+	// applied are the units changed during the last application, to be restored on abort.
+	applied []change
+
+	mu sync.Mutex
+}
+
+// New creates a manager keeping track of the units it changed in stateDir, and writing drop-ins in systemUnitDir.
+func New(stateDir, systemUnitDir string, systemdCaller systemdCaller) *Manager {
+	if stateDir == "" {
+		stateDir = consts.DefaultStateDir
+	}
+	if systemUnitDir == "" {
+		systemUnitDir = consts.DefaultSystemUnitDir
+	}
+
+	return &Manager{
+		unitsDir:      filepath.Join(stateDir, unitsDirName),
+		systemUnitDir: systemUnitDir,
+		systemdCaller: systemdCaller,
+	}
+}
+
+// ApplyPolicy sets the state and drop-ins of units based on a list of entries.
+// Steps are:
+// 1.  Write the drop-ins and remove the ones not listed anymore
+// 2.  Change the unit files of the units which are not in their requested state, tracking their original state,
+// and restore the ones of the units not listed anymore
+// 3.  Reload systemd once if any drop-in or unit file changed
+// 4.  Start or stop the changed units.
+func (m *Manager) ApplyPolicy(ctx context.Context, objectName string, isComputer bool, entries []entry.Entry) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't apply units policy to %s", objectName))
+
+	// Units policies are only set on computers.
+	if !isComputer {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.applied = nil
+
+	states, dropIns := parseEntries(ctx, entries)
+	original, err := m.loadOriginalStates()
+	if err != nil {
+		return err
+	}
+
+	log.Debugf(ctx, "Applying units policy to %s", objectName)
+
+	// Drop-ins are written first, so that started units use them.
+	reload, err := m.writeDropIns(dropIns)
+	if err != nil {
+		return err
+	}
+
+	ops, err := m.operations(ctx, states, original)
+	if err != nil {
+		return err
+	}
+	// Always save the original states we know about, so that we can restore them later.
+	defer func() {
+		if errSave := m.saveOriginalStates(original); errSave != nil {
+			err = errors.Join(err, errSave)
+		}
+	}()
+
+	// Unit files of every unit are changed first, so that systemd is only reloaded once before starting or
+	// stopping them.
+	var runtimeOps []operation
+	for _, unitOps := range ops {
+		// Track the unit before changing it, so that a partially changed unit is restored on abort.
+		m.applied = append(m.applied, change{unit: unitOps.unit, previous: unitOps.current})
+		for _, op := range unitOps.ops {
+			if !op.changesUnitFile() {
+				runtimeOps = append(runtimeOps, op)
+				continue
+			}
+			if err := m.run(ctx, op); err != nil {
+				return err
+			}
+			reload = true
+		}
+		if unitOps.restored {
+			delete(original, unitOps.unit)
+		}
+	}
+
+	if reload && os.Getenv("ADSYS_SKIP_ROOT_CALLS") == "" {
+		if err := m.systemdCaller.DaemonReload(ctx); err != nil {
+			return err
+		}
+	}
+
+	for _, op := range runtimeOps {
+		if err := m.run(ctx, op); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Plan returns the changes ApplyPolicy would make for a units policy, without applying them.
+func (m *Manager) Plan(ctx context.Context, objectName string, isComputer bool, entries []entry.Entry) (changes []plan.Change, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't plan units policy for %s", objectName))
+
+	// Units policies are only set on computers.
+	if !isComputer {
+		return nil, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	states, dropIns := parseEntries(ctx, entries)
+
+	dropInChanges, err := m.dropInChanges(dropIns)
+	if err != nil {
+		return nil, err
+	}
+	changes = append(changes, dropInChanges...)
+	reload := len(dropInChanges) > 0
+
+	original, err := m.loadOriginalStates()
+	if err != nil {
+		return nil, err
+	}
+	var runtimeChanges []plan.Change
+	ops, err := m.operations(ctx, states, original)
+	if err != nil {
+		return nil, err
+	}
+	for _, unitOps := range ops {
+		for _, op := range unitOps.ops {
+			if !op.changesUnitFile() {
+				runtimeChanges = append(runtimeChanges, plan.Call("systemctl", op.call, op.unit))
+				continue
+			}
+			changes = append(changes, plan.Call("systemctl", op.call, op.unit))
+			reload = true
+		}
+	}
+	if reload {
+		changes = append(changes, plan.Call("systemctl", "daemon-reload"))
+	}
+
+	return append(changes, runtimeChanges...), nil
+}
+
+// Prepare backs up the drop-ins and the original states of the units, and restores the units changed during the
+// application if the transaction is aborted.
+func (m *Manager) Prepare(ctx context.Context, objectName string, isComputer bool, tx *transaction.Transaction) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't prepare units policy for %s", objectName))
+
+	// Units policies are only set on computers.
+	if !isComputer {
+		return nil
+	}
+
+	log.Debugf(ctx, "Preparing units policy for %s", objectName)
+
+	// Registered first so that it runs once the drop-ins are restored.
+	tx.OnAbort(func(ctx context.Context) error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		if os.Getenv("ADSYS_SKIP_ROOT_CALLS") != "" {
+			return nil
+		}
+
+		var errs []error
+		var runtimeOps []operation
+		for i := len(m.applied) - 1; i >= 0; i-- {
+			c := m.applied[i]
+			current, err := m.systemdCaller.UnitFileState(ctx, c.unit)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			for _, op := range restoration(c.unit, current, c.previous) {
+				if !op.changesUnitFile() {
+					runtimeOps = append(runtimeOps, op)
+					continue
+				}
+				errs = append(errs, m.run(ctx, op))
+			}
+		}
+		// Restored unit files and drop-ins are only taken into account once systemd is reloaded.
+		errs = append(errs, m.systemdCaller.DaemonReload(ctx))
+		for _, op := range runtimeOps {
+			errs = append(errs, m.run(ctx, op))
+		}
+		m.applied = nil
+		return errors.Join(errs...)
+	})
+
+	if err := tx.Backup(filepath.Join(m.unitsDir, originalStatesName)); err != nil {
+		return err
+	}
+	return tx.BackupMatching(m.systemUnitDir, filepath.Join("*.d", dropInName))
+}
+
+// unitOperations are the operations to run on unit, in its current state.
+type unitOperations struct {
+	unit    string
+	current string
+	ops     []operation
+	// restored is true if the unit is restored to its original state.
+	restored bool
+}
+
+// operations returns the operations to run on each unit to reach their requested state, and to restore the ones not
+// listed anymore. original is updated with the state of the units changed for the first time.
+func (m *Manager) operations(ctx context.Context, states map[string]string, original map[string]string) (ops []unitOperations, err error) {
+	units := make([]string, 0, len(states)+len(original))
+	for u := range states {
+		units = append(units, u)
+	}
+	for u := range original {
+		units = append(units, u)
+	}
+	slices.Sort(units)
+
+	for _, u := range slices.Compact(units) {
+		current, err := m.systemdCaller.UnitFileState(ctx, u)
+		if err != nil {
+			if _, ok := states[u]; ok {
+				log.Warningf(ctx, gotext.Get("Unit %q is not available on this system, skipping: %v", u, err))
+				continue
+			}
+			// Only forget the original state of units which are really gone, as it is needed to restore them.
+			if !errors.Is(err, systemd.ErrUnitNotFound) {
+				return nil, err
+			}
+			log.Infof(ctx, "Unit %q doesn't exist anymore, forgetting its original state", u)
+			delete(original, u)
+			continue
+		}
+
+		action, listed := states[u]
+		if !listed {
+			if current == original[u] {
+				delete(original, u)
+				continue
+			}
+			ops = append(ops, unitOperations{unit: u, current: current, ops: restoration(u, current, original[u]), restored: true})
+			continue
+		}
+
+		unitOps := transition(u, current, action)
+		if len(unitOps) == 0 {
+			continue
+		}
+		if _, ok := original[u]; !ok {
+			original[u] = current
+		}
+		ops = append(ops, unitOperations{unit: u, current: current, ops: unitOps})
+	}
+
+	return ops, nil
+}
+
+// transition returns the operations to move unit from its current state to the one requested by action.
+func transition(unit, current, action string) []operation {
+	switch action {
+	case "enable":
+		var ops []operation
+		if current == stateMasked {
+			ops = append(ops, operation{"unmask", unit})
+		} else if current != "disabled" {
+			return nil
+		}
+		return append(ops, operation{"enable", unit}, operation{"start", unit})
+	case "disable":
+		if current != stateEnabled {
+			return nil
+		}
+		return []operation{{"disable", unit}, {"stop", unit}}
+	case "mask":
+		if current == stateMasked {
+			return nil
+		}
+		return []operation{{"mask", unit}, {"stop", unit}}
+	case "unmask":
+		if current != stateMasked {
+			return nil
+		}
+		return []operation{{"unmask", unit}}
+	}
+	return nil
+}
+
+// restoration returns the operations to restore unit from its current state to its original one.
+func restoration(unit, current, original string) (ops []operation) {
+	if current == original {
+		return nil
+	}
+	if original == stateMasked {
+		return []operation{{"mask", unit}, {"stop", unit}}
+	}
+	if current == stateMasked {
+		ops = append(ops, operation{"unmask", unit})
+	}
+	switch original {
+	case stateEnabled:
+		ops = append(ops, operation{"enable", unit}, operation{"start", unit})
+	case "disabled":
+		if current == stateEnabled {
+			ops = append(ops, operation{"disable", unit}, operation{"stop", unit})
+		}
+	}
+	return ops
+}
+
+// run executes op with systemd.
+func (m *Manager) run(ctx context.Context, op operation) error {
+	if os.Getenv("ADSYS_SKIP_ROOT_CALLS") != "" {
+		return nil
+	}
+
+	log.Debugf(ctx, "Running %s on unit %s", op.call, op.unit)
+	switch op.call {
+	case "enable":
+		return m.systemdCaller.EnableUnit(ctx, op.unit)
+	case "disable":
+		return m.systemdCaller.DisableUnit(ctx, op.unit)
+	case "mask":
+		return m.systemdCaller.MaskUnit(ctx, op.unit)
+	case "unmask":
+		return m.systemdCaller.UnmaskUnit(ctx, op.unit)
+	case "start":
+		return m.systemdCaller.StartUnit(ctx, op.unit)
+	case "stop":
+		return m.systemdCaller.StopUnit(ctx, op.unit)
+	}
+	return fmt.Errorf("unknown unit operation %q", op.call)
+}
+
+// parseEntries returns the requested action of each unit, and the drop-ins content of each unit.
+// Units listed with several actions and invalid values are skipped with a warning.
+func parseEntries(ctx context.Context, entries []entry.Entry) (states map[string]string, dropIns map[string]string) {
+	requested := make(map[string][]string)
+	directives := make(map[string][]string)
+	var overridden []string
+
+	for _, e := range entries {
+		if e.Disabled {
+			continue
+		}
+		key := filepath.Base(e.Key)
+
+		for _, l := range strings.Split(e.Value, "\n") {
+			l = strings.TrimSpace(l)
+			if l == "" {
+				continue
+			}
+
+			if key == "overrides" {
+				name, directive, _ := strings.Cut(l, " ")
+				u, ok := unitName(ctx, name)
+				if !ok {
+					continue
+				}
+				directive = strings.TrimSpace(directive)
+				if _, ok := sections[filepath.Ext(u)]; !ok {
+					log.Warningf(ctx, gotext.Get("Drop-ins are not supported for unit %q, skipping", u))
+					continue
+				}
+				if !directiveRe.MatchString(directive) {
+					log.Warningf(ctx, gotext.Get("Invalid drop-in directive %q for unit %q, skipping", directive, u))
+					continue
+				}
+				if !slices.Contains(overridden, u) {
+					overridden = append(overridden, u)
+				}
+				directives[u] = append(directives[u], directive)
+				continue
+			}
+
+			if !slices.Contains(actions, key) {
+				log.Warningf(ctx, gotext.Get("Unsupported units key %q, skipping", key))
+				break
+			}
+			u, ok := unitName(ctx, l)
+			if !ok {
+				continue
+			}
+			if !slices.Contains(requested[u], key) {
+				requested[u] = append(requested[u], key)
+			}
+		}
+	}
+
+	states = make(map[string]string)
+	for u, keys := range requested {
+		if len(keys) > 1 {
+			log.Warningf(ctx, gotext.Get("Unit %q is requested to %s at the same time, skipping", u, strings.Join(keys, " and ")))
+			continue
+		}
+		states[u] = keys[0]
+	}
+
+	dropIns = make(map[string]string)
+	for _, u := range overridden {
+		dropIns[u] = fmt.Sprintf("%s[%s]\n%s\n", header, sections[filepath.Ext(u)], strings.Join(directives[u], "\n"))
+	}
+
+	return states, dropIns
+}
+
+// unitName returns the normalized unit name of name, with the service type if it has none.
+func unitName(ctx context.Context, name string) (string, bool) {
+	if !unitRe.MatchString(name) {
+		log.Warningf(ctx, gotext.Get("Invalid unit name %q, skipping", name))
+		return "", false
+	}
+	if !slices.Contains(unitTypes, filepath.Ext(name)) {
+		name += ".service"
+	}
+	return name, true
+}
+
+// writeDropIns writes the drop-ins of each unit and removes the ones of units not listed anymore.
+// It returns true if any drop-in changed.
+func (m *Manager) writeDropIns(dropIns map[string]string) (changed bool, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't write units drop-ins"))
+
+	existing, err := filepath.Glob(filepath.Join(m.systemUnitDir, "*.d", dropInName))
+	if err != nil {
+		return false, err
+	}
+	for _, p := range existing {
+		if _, ok := dropIns[strings.TrimSuffix(filepath.Base(filepath.Dir(p)), ".d")]; ok {
+			continue
+		}
+		if err := os.Remove(p); err != nil {
+			return false, err
+		}
+		// Only remove the drop-in directory if we were the only one using it.
+		if err := os.Remove(filepath.Dir(p)); err != nil && !errors.Is(err, syscall.ENOTEMPTY) {
+			return false, err
+		}
+		changed = true
+	}
+
+	for u, content := range dropIns {
+		p := filepath.Join(m.systemUnitDir, u+".d", dropInName)
+		if old, err := os.ReadFile(p); err == nil && string(old) == content {
+			continue
+		}
+		// nolint:gosec // G301 match distribution permission
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return false, err
+		}
+		// nolint:gosec // G306 match distribution permission
+		if err := os.WriteFile(p+".new", []byte(content), 0644); err != nil {
+			return false, err
+		}
+		if err := os.Rename(p+".new", p); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+
+	return changed, nil
+}
+
+// dropInChanges returns the changes writeDropIns would make.
+func (m *Manager) dropInChanges(dropIns map[string]string) (changes []plan.Change, err error) {
+	existing, err := filepath.Glob(filepath.Join(m.systemUnitDir, "*.d", dropInName))
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range existing {
+		if _, ok := dropIns[strings.TrimSuffix(filepath.Base(filepath.Dir(p)), ".d")]; ok {
+			continue
+		}
+		if c, ok := plan.Removal(p); ok {
+			changes = append(changes, c)
+		}
+	}
+
+	units := make([]string, 0, len(dropIns))
+	for u := range dropIns {
+		units = append(units, u)
+	}
+	slices.Sort(units)
+	for _, u := range units {
+		if c, ok := plan.File(filepath.Join(m.systemUnitDir, u+".d", dropInName), dropIns[u]); ok {
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
+
+// loadOriginalStates returns the state of the units before adsys first changed them.
+func (m *Manager) loadOriginalStates() (original map[string]string, err error) {
+	defer decorate.OnError(&err, gotext.Get("can't load original units states"))
+
+	original = make(map[string]string)
+	d, err := os.ReadFile(filepath.Join(m.unitsDir, originalStatesName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return original, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(d, &original); err != nil {
+		return nil, err
+	}
+	if original == nil {
+		original = make(map[string]string)
+	}
+	return original, nil
+}
+
+// saveOriginalStates saves the original states of the units changed by adsys, removing the file if there are none.
+func (m *Manager) saveOriginalStates(original map[string]string) (err error) {
+	defer decorate.OnError(&err, gotext.Get("can't save original units states"))
+
+	p := filepath.Join(m.unitsDir, originalStatesName)
+	if len(original) == 0 {
+		if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	d, err := yaml.Marshal(original)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.unitsDir, 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(p+".new", d, 0600); err != nil {
+		return err
+	}
+	return os.Rename(p+".new", p)
+}
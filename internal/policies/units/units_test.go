@@ -0,0 +1,332 @@
+package units_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/ubuntu/adsys/internal/policies/entry"
+	"github.com/ubuntu/adsys/internal/policies/transaction"
+	"github.com/ubuntu/adsys/internal/policies/units"
+	"github.com/ubuntu/adsys/internal/systemd"
+	"github.com/ubuntu/adsys/internal/testutils"
+)
+
+func TestApplyPolicy(t *testing.T) {
+	t.Parallel()
+
+	defaultPolicy := []entry.Entry{
+		{Key: "units/enable", Value: "bluetooth.service"},
+		{Key: "units/disable", Value: "cups-browsed.service"},
+		{Key: "units/mask", Value: "avahi-daemon.service\navahi-daemon.socket"},
+		{Key: "units/unmask", Value: "ssh.service"},
+		{Key: "units/overrides", Value: "cups.service Restart=always\ncups.service CPUQuota=20%\nbackup.timer OnCalendar=daily"},
+	}
+
+	tests := map[string]struct {
+		notComputer   bool
+		entries       []entry.Entry
+		existingState string
+		failOn        string
+
+		wantErr bool
+	}{
+		"Full policy": {entries: defaultPolicy},
+		"Units without type are services": {entries: []entry.Entry{
+			{Key: "units/disable", Value: "cups-browsed"},
+			{Key: "units/overrides", Value: "cups Restart=always"},
+		}},
+		"Units in requested state are not changed": {entries: []entry.Entry{
+			{Key: "units/enable", Value: "cups-browsed.service"},
+			{Key: "units/disable", Value: "bluetooth.service"},
+			{Key: "units/mask", Value: "ssh.service"},
+			{Key: "units/unmask", Value: "avahi-daemon.service"},
+		}},
+		"Static units are not changed":      {entries: []entry.Entry{{Key: "units/enable", Value: "systemd-journald.service"}}},
+		"Enabling a masked unit unmasks it": {entries: []entry.Entry{{Key: "units/enable", Value: "ssh.service"}}},
+		"Units requested in several states are ignored": {entries: []entry.Entry{
+			{Key: "units/enable", Value: "cups-browsed.service\nbluetooth.service"},
+			{Key: "units/disable", Value: "cups-browsed"},
+		}},
+		"Unavailable units are skipped": {entries: []entry.Entry{{Key: "units/disable", Value: "not-installed.service\ncups-browsed.service"}}},
+		"Invalid values are skipped": {entries: []entry.Entry{
+			{Key: "units/disable", Value: "cups-browsed; rm -rf /\navahi-daemon"},
+			{Key: "units/overrides", Value: "cups restart=always\nmulti-user.target Wants=foo.service\ncups Restart\ncups.service Restart=always"},
+		}},
+		"Unsupported keys are ignored": {entries: []entry.Entry{{Key: "units/restart", Value: "cups-browsed.service"}}},
+		"Disabled entries are ignored": {entries: []entry.Entry{{Key: "units/disable", Value: "cups-browsed.service", Disabled: true}}},
+
+		// Previous policy cases
+		"Units not listed anymore are restored": {existingState: "previous-policy"},
+		"Units still listed keep their original state": {existingState: "previous-policy", entries: []entry.Entry{
+			{Key: "units/disable", Value: "whoopsie.service"},
+			{Key: "units/mask", Value: "apport.service"},
+		}},
+		"Units changed to another state keep their original state": {existingState: "previous-policy", entries: []entry.Entry{
+			{Key: "units/enable", Value: "apport.service"},
+		}},
+		"Drop-ins are updated": {existingState: "previous-policy", entries: []entry.Entry{
+			{Key: "units/overrides", Value: "cups.service Restart=on-failure"},
+		}},
+		"Drop-ins up to date only reload systemd for unit changes": {existingState: "previous-policy", entries: []entry.Entry{
+			{Key: "units/overrides", Value: "cups.service Restart=always\ngone.service CPUQuota=20%"},
+		}},
+		"Not a computer": {notComputer: true, existingState: "previous-policy", entries: defaultPolicy},
+
+		// Error cases
+		"Error on systemd call keeps tracking original states": {entries: defaultPolicy, failOn: "mask", wantErr: true},
+		"Error on daemon reload":                               {entries: defaultPolicy, failOn: "daemon-reload", wantErr: true},
+		"Error on restoring units":                             {existingState: "previous-policy", failOn: "enable", wantErr: true},
+		"Error on unit state keeps tracking original states":   {existingState: "previous-policy", failOn: "unit-file-state", wantErr: true},
+		"Error on invalid original states":                     {existingState: "invalid-state", entries: defaultPolicy, wantErr: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			root := filepath.Join(t.TempDir(), "root")
+			if tc.existingState != "" {
+				testutils.Copy(t, filepath.Join("testdata", tc.existingState), root)
+			}
+
+			systemd := newMockSystemd(tc.failOn)
+			m := units.New(filepath.Join(root, "state"), filepath.Join(root, "etc", "systemd", "system"), systemd)
+			err := m.ApplyPolicy(context.Background(), "ubuntu", !tc.notComputer, tc.entries)
+			if tc.wantErr {
+				require.Error(t, err, "ApplyPolicy should have failed but didn't")
+			} else {
+				require.NoError(t, err, "ApplyPolicy failed but shouldn't have")
+			}
+
+			testutils.CompareTreesWithFiltering(t, root, filepath.Join(testutils.GoldenPath(t), "root"), testutils.UpdateEnabled())
+
+			calls := strings.Join(systemd.calls, "\n")
+			want := testutils.LoadWithUpdateFromGolden(t, calls, testutils.WithGoldenPath(filepath.Join(testutils.GoldenPath(t), "calls")))
+			require.Equal(t, want, calls, "systemd calls don't match")
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		notComputer   bool
+		entries       []entry.Entry
+		existingState string
+
+		wantChanges []string
+	}{
+		"Changes are planned as files and calls": {
+			existingState: "previous-policy",
+			entries: []entry.Entry{
+				{Key: "units/disable", Value: "whoopsie.service\ncups-browsed.service"},
+				{Key: "units/overrides", Value: "backup.timer OnCalendar=daily"},
+			},
+			wantChanges: []string{
+				"remove etc/systemd/system/cups.service.d/90-adsys.conf",
+				"remove etc/systemd/system/gone.service.d/90-adsys.conf",
+				"create etc/systemd/system/backup.timer.d/90-adsys.conf",
+				"run systemctl unmask apport.service",
+				"run systemctl disable cups-browsed.service",
+				"run systemctl daemon-reload",
+				"run systemctl stop cups-browsed.service",
+			},
+		},
+		"Up to date system": {entries: []entry.Entry{{Key: "units/enable", Value: "cups-browsed.service"}}},
+		"Nothing to do":     {},
+		"Not a computer":    {notComputer: true, entries: []entry.Entry{{Key: "units/disable", Value: "cups-browsed.service"}}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			root := filepath.Join(t.TempDir(), "root")
+			if tc.existingState != "" {
+				testutils.Copy(t, filepath.Join("testdata", tc.existingState), root)
+			}
+
+			systemd := newMockSystemd("")
+			m := units.New(filepath.Join(root, "state"), filepath.Join(root, "etc", "systemd", "system"), systemd)
+			changes, err := m.Plan(context.Background(), "ubuntu", !tc.notComputer, tc.entries)
+			require.NoError(t, err, "Plan failed but shouldn't have")
+
+			var got []string
+			for _, c := range changes {
+				target := strings.TrimPrefix(c.Target, root+"/")
+				if c.Content != "" && c.Action == "run" {
+					target += " " + c.Content
+				}
+				got = append(got, fmt.Sprintf("%s %s", c.Action, target))
+			}
+			require.Equal(t, tc.wantChanges, got, "Plan returns the expected changes")
+
+			// Planning should not touch the system.
+			require.Empty(t, systemd.calls, "Plan should not change any unit")
+			require.NoDirExists(t, filepath.Join(root, "etc", "systemd", "system", "backup.timer.d"), "Plan should not write drop-ins")
+		})
+	}
+}
+
+func TestPrepareRestoresUnitsOnAbort(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.Join(t.TempDir(), "root")
+	testutils.Copy(t, filepath.Join("testdata", "previous-policy"), root)
+
+	systemd := newMockSystemd("")
+	m := units.New(filepath.Join(root, "state"), filepath.Join(root, "etc", "systemd", "system"), systemd)
+
+	tx, err := transaction.New(t.TempDir(), "test-")
+	require.NoError(t, err, "Setup: can't create transaction")
+	require.NoError(t, m.Prepare(context.Background(), "ubuntu", true, tx), "Prepare failed but shouldn't have")
+
+	entries := []entry.Entry{
+		{Key: "units/mask", Value: "cups-browsed.service"},
+		{Key: "units/overrides", Value: "backup.timer OnCalendar=daily"},
+	}
+	require.NoError(t, m.ApplyPolicy(context.Background(), "ubuntu", true, entries), "Setup: ApplyPolicy failed")
+	require.NoError(t, tx.Abort(context.Background()), "Abort failed but shouldn't have")
+
+	for _, p := range []string{
+		filepath.Join("state", "units", "original-states"),
+		filepath.Join("etc", "systemd", "system", "cups.service.d", "90-adsys.conf"),
+		filepath.Join("etc", "systemd", "system", "gone.service.d", "90-adsys.conf"),
+	} {
+		want, err := os.ReadFile(filepath.Join("testdata", "previous-policy", p))
+		require.NoError(t, err, "Setup: can't read reference file")
+		got, err := os.ReadFile(filepath.Join(root, p))
+		require.NoError(t, err, "%s should have been restored", p)
+		require.Equal(t, string(want), string(got), "%s should have been restored", p)
+	}
+	require.NoFileExists(t, filepath.Join(root, "etc", "systemd", "system", "backup.timer.d", "90-adsys.conf"), "New drop-in should have been removed")
+
+	calls := strings.Join(systemd.calls, "\n")
+	want := testutils.LoadWithUpdateFromGolden(t, calls)
+	require.Equal(t, want, calls, "systemd calls don't match")
+}
+
+func TestPrepareRestoresPartiallyChangedUnitsOnAbort(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.Join(t.TempDir(), "root")
+
+	systemd := newMockSystemd("enable")
+	m := units.New(filepath.Join(root, "state"), filepath.Join(root, "etc", "systemd", "system"), systemd)
+
+	tx, err := transaction.New(t.TempDir(), "test-")
+	require.NoError(t, err, "Setup: can't create transaction")
+	require.NoError(t, m.Prepare(context.Background(), "ubuntu", true, tx), "Prepare failed but shouldn't have")
+
+	// ssh.service is unmasked before failing to be enabled.
+	entries := []entry.Entry{{Key: "units/enable", Value: "ssh.service"}}
+	require.Error(t, m.ApplyPolicy(context.Background(), "ubuntu", true, entries), "Setup: ApplyPolicy should have failed")
+	require.NoError(t, tx.Abort(context.Background()), "Abort failed but shouldn't have")
+
+	state, err := systemd.UnitFileState(context.Background(), "ssh.service")
+	require.NoError(t, err, "Setup: can't get unit state")
+	require.Equal(t, "masked", state, "Partially changed unit should have been restored")
+
+	calls := strings.Join(systemd.calls, "\n")
+	want := testutils.LoadWithUpdateFromGolden(t, calls)
+	require.Equal(t, want, calls, "systemd calls don't match")
+}
+
+// mockSystemd keeps the state of a few units in memory and records the calls changing them.
+type mockSystemd struct {
+	states map[string]string
+	masked map[string]bool
+	failOn string
+
+	calls []string
+	mu    sync.Mutex
+}
+
+func newMockSystemd(failOn string) *mockSystemd {
+	return &mockSystemd{
+		states: map[string]string{
+			"apport.service":           "disabled",
+			"avahi-daemon.service":     "enabled",
+			"avahi-daemon.socket":      "enabled",
+			"bluetooth.service":        "disabled",
+			"cups-browsed.service":     "enabled",
+			"kerneloops.service":       "enabled",
+			"ssh.service":              "enabled",
+			"systemd-journald.service": "static",
+			"whoopsie.service":         "disabled",
+		},
+		masked: map[string]bool{
+			"apport.service": true,
+			"ssh.service":    true,
+		},
+		failOn: failOn,
+	}
+}
+
+func (s *mockSystemd) UnitFileState(_ context.Context, unit string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failOn == "unit-file-state" {
+		return "", errors.New("unit-file-state failed")
+	}
+	state, ok := s.states[unit]
+	if !ok {
+		return "", systemd.ErrUnitNotFound
+	}
+	if s.masked[unit] {
+		return "masked", nil
+	}
+	return state, nil
+}
+
+func (s *mockSystemd) StartUnit(_ context.Context, unit string) error {
+	return s.call("start", unit, nil)
+}
+
+func (s *mockSystemd) StopUnit(_ context.Context, unit string) error {
+	return s.call("stop", unit, nil)
+}
+
+func (s *mockSystemd) EnableUnit(_ context.Context, unit string) error {
+	return s.call("enable", unit, func() { s.states[unit] = "enabled" })
+}
+
+func (s *mockSystemd) DisableUnit(_ context.Context, unit string) error {
+	return s.call("disable", unit, func() { s.states[unit] = "disabled" })
+}
+
+func (s *mockSystemd) MaskUnit(_ context.Context, unit string) error {
+	return s.call("mask", unit, func() { s.masked[unit] = true })
+}
+
+func (s *mockSystemd) UnmaskUnit(_ context.Context, unit string) error {
+	return s.call("unmask", unit, func() { delete(s.masked, unit) })
+}
+
+func (s *mockSystemd) DaemonReload(_ context.Context) error {
+	return s.call("daemon-reload", "", nil)
+}
+
+// call records the call and applies its effect, unless it was requested to fail.
+func (s *mockSystemd) call(name, unit string, effect func()) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if name == s.failOn {
+		return fmt.Errorf("%s failed", name)
+	}
+	s.calls = append(s.calls, strings.TrimSpace(name+" "+unit))
+	if effect != nil {
+		effect()
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/leonelquinteros/gotext"
+	log "github.com/ubuntu/adsys/internal/grpc/logstreamer"
+	"github.com/ubuntu/adsys/internal/policies/plan"
+	"github.com/ubuntu/decorate"
+)
+
+// Verifier is implemented by policy managers which can't rely on their Plan alone to detect that the system drifted
+// from the applied policy, for instance because they always set up their state again from scratch.
+type Verifier interface {
+	// Verify returns the differences between the system and the state the request would produce.
+	Verify(ctx context.Context, req Request) ([]plan.Change, error)
+}
+
+// VerifyPolicies compares the system with the policies last applied to objectName, without modifying anything.
+// It returns a report of the differences found for each policy manager and whether the system drifted.
+//
+// Policy managers implementing Verifier are asked directly. For the others, any file their Plan would create,
+// update or remove is a drift. Commands and external calls are always planned, and so are ignored.
+func (m *Manager) VerifyPolicies(ctx context.Context, objectName string, isComputer bool) (msg string, drift bool, err error) {
+	defer decorate.OnError(&err, gotext.Get("failed to verify policies for %q", objectName))
+
+	log.Info(ctx, gotext.Get("Verifying policies for %s (machine: %v)", objectName, isComputer))
+
+	pols, err := NewFromCache(ctx, filepath.Join(m.policiesCacheDir, objectName))
+	if err != nil {
+		return "", false, errors.New(gotext.Get("no policy applied for %q: %v", objectName, err))
+	}
+	defer pols.Close()
+
+	drifted, err := m.objectChanges(ctx, objectName, isComputer, &pols, func(ctx context.Context, reg Registration, req Request) ([]plan.Change, error) {
+		if v, ok := reg.Manager.(Verifier); ok {
+			return v.Verify(ctx, req)
+		}
+		changes, err := reg.Manager.Plan(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		var fileChanges []plan.Change
+		for _, c := range changes {
+			if c.Action == plan.Run {
+				continue
+			}
+			fileChanges = append(fileChanges, c)
+		}
+		return fileChanges, nil
+	})
+	if err != nil {
+		return "", false, err
+	}
+
+	var out strings.Builder
+	fmt.Fprintln(&out, gotext.Get("Policies verification for %s (machine: %v):", objectName, isComputer))
+	for _, c := range drifted {
+		if len(c.changes) == 0 {
+			fmt.Fprintln(&out, gotext.Get("* %s: no drift", c.name))
+			continue
+		}
+		drift = true
+		c.format(&out)
+	}
+
+	if drift {
+		log.Warning(ctx, gotext.Get("System state drifted from the policies applied to %s", objectName))
+	}
+
+	return out.String(), drift, nil
+}
+
+// Repair applies again the policies last applied to objectName, restoring any drift of the system.
+func (m *Manager) Repair(ctx context.Context, objectName string, isComputer bool) (err error) {
+	defer decorate.OnError(&err, gotext.Get("failed to repair policies for %q", objectName))
+
+	log.Info(ctx, gotext.Get("Applying again policies for %s", objectName))
+
+	pols, err := NewFromCache(ctx, filepath.Join(m.policiesCacheDir, objectName))
+	if err != nil {
+		return errors.New(gotext.Get("no policy applied for %q: %v", objectName, err))
+	}
+	defer pols.Close()
+
+	return m.ApplyPolicies(ctx, objectName, isComputer, &pols)
+}
@@ -0,0 +1,136 @@
+package policies_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/ubuntu/adsys/internal/consts"
+	"github.com/ubuntu/adsys/internal/policies"
+	"github.com/ubuntu/adsys/internal/testutils"
+)
+
+func TestVerifyPolicies(t *testing.T) {
+	//t.Parallel()
+
+	bus := testutils.NewDbusConn(t)
+
+	subscriptionDbus := bus.Object(consts.SubscriptionDbusRegisteredName,
+		dbus.ObjectPath(consts.SubscriptionDbusObjectPath))
+
+	tests := map[string]struct {
+		policiesDir     string
+		noApply         bool
+		removeFile      string
+		modifyFile      string
+		isNotSubscribed bool
+		repair          bool
+
+		wantDrift bool
+		wantErr   bool
+	}{
+		"No drift right after applying policies":           {policiesDir: "all_entry_types"},
+		"No drift when no rules were applied":              {policiesDir: ""},
+		"No drift on pro only rules when not subscribed":   {policiesDir: "all_entry_types", isNotSubscribed: true},
+		"Drift on removed dconf lock":                      {policiesDir: "all_entry_types", removeFile: "etc/dconf/db/machine.d/locks/adsys", wantDrift: true},
+		"Drift on modified sudoers file":                   {policiesDir: "all_entry_types", modifyFile: "etc/sudoers.d/99-adsys-privilege-enforcement", wantDrift: true},
+		"Drift on removed apparmor profile":                {policiesDir: "all_entry_types", removeFile: "etc/apparmor.d/adsys/machine/usr.bin.foo", wantDrift: true},
+		"Drift on modified script":                         {policiesDir: "all_entry_types", modifyFile: "run/adsys/machine/scripts/scripts/script-machine-startup", wantDrift: true},
+		"Drift on removed scripts order file":              {policiesDir: "all_entry_types", removeFile: "run/adsys/machine/scripts/startup", wantDrift: true},
+		"Repair restores the system to the applied policy": {policiesDir: "all_entry_types", modifyFile: "etc/sudoers.d/99-adsys-privilege-enforcement", removeFile: "etc/dconf/db/machine.d/locks/adsys", repair: true},
+
+		// Error cases
+		"Error when no policies were applied": {noApply: true, wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			//t.Parallel()
+
+			fakeRootDir := t.TempDir()
+			loadedPoliciesFile := filepath.Join(fakeRootDir, "sys", "kernel", "security", "apparmor", "profiles")
+			err := os.MkdirAll(filepath.Dir(loadedPoliciesFile), 0700)
+			require.NoError(t, err, "Setup: can not create loadedPoliciesFile dir")
+			err = os.WriteFile(loadedPoliciesFile, []byte("someprofile (enforce)\n"), 0600)
+			require.NoError(t, err, "Setup: can not create loadedPoliciesFile")
+
+			require.NoError(t, subscriptionDbus.SetProperty(consts.SubscriptionDbusInterface+".Attached", !tc.isNotSubscribed), "Setup: can not set subscription status")
+			defer func() {
+				require.NoError(t, subscriptionDbus.SetProperty(consts.SubscriptionDbusInterface+".Attached", false), "Teardown: can not restore subscription status")
+			}()
+
+			m, err := policies.NewManager(bus,
+				"hostname",
+				mockBackend{},
+				policies.WithCacheDir(filepath.Join(fakeRootDir, "var", "cache", "adsys")),
+				policies.WithStateDir(filepath.Join(fakeRootDir, "var", "lib", "adsys")),
+				policies.WithRunDir(filepath.Join(fakeRootDir, "run", "adsys")),
+				policies.WithShareDir(filepath.Join(fakeRootDir, "usr", "share", "adsys")),
+				policies.WithDconfDir(filepath.Join(fakeRootDir, "etc", "dconf")),
+				policies.WithPolicyKitDir(filepath.Join(fakeRootDir, "etc", "polkit-1")),
+				policies.WithSudoersDir(filepath.Join(fakeRootDir, "etc", "sudoers.d")),
+				policies.WithSecurityDir(filepath.Join(fakeRootDir, "etc", "security")),
+				policies.WithNftablesDir(filepath.Join(fakeRootDir, "etc", "nftables.d")),
+				policies.WithEnvironmentDir(filepath.Join(fakeRootDir, "etc", "environment.d")),
+				policies.WithProfileDir(filepath.Join(fakeRootDir, "etc", "profile.d")),
+				policies.WithApparmorDir(filepath.Join(fakeRootDir, "etc", "apparmor.d", "adsys")),
+				policies.WithApparmorFsDir(filepath.Dir(loadedPoliciesFile)),
+				policies.WithApparmorParserCmd([]string{"/bin/true"}),
+				policies.WithNftCmd([]string{"/bin/true"}),
+				policies.WithPackagesExecutor(testutils.MockPackagesExecutor{}),
+				policies.WithGroupFile(filepath.Join("testdata", "group")),
+				policies.WithGpasswdCmd([]string{"/bin/true"}),
+				policies.WithCertAutoenrollCmd([]string{"/bin/true"}),
+				policies.WithSystemUnitDir(filepath.Join(fakeRootDir, "etc", "systemd", "system")),
+				policies.WithProxyApplier(&mockProxyApplier{}),
+				policies.WithSystemdCaller(&testutils.MockSystemdCaller{}),
+			)
+			require.NoError(t, err, "Setup: couldn’t get a new policy manager")
+
+			if !tc.noApply {
+				pols, err := policies.New(context.Background(), nil, "")
+				require.NoError(t, err, "Setup: can not create empty policies")
+				if tc.policiesDir != "" {
+					pols, err = policies.NewFromCache(context.Background(), filepath.Join("testdata", "cache", "policies", tc.policiesDir))
+					require.NoError(t, err, "Setup: can not load policies list")
+				}
+				err = m.ApplyPolicies(context.Background(), "hostname", true, &pols)
+				require.NoError(t, err, "Setup: ApplyPolicies should return no error but got one")
+				require.NoError(t, pols.Close(), "Setup: can not close policies")
+			}
+
+			if tc.removeFile != "" {
+				require.NoError(t, os.Remove(filepath.Join(fakeRootDir, tc.removeFile)), "Setup: can not remove file")
+			}
+			if tc.modifyFile != "" {
+				p := filepath.Join(fakeRootDir, tc.modifyFile)
+				require.NoError(t, os.MkdirAll(filepath.Dir(p), 0750), "Setup: can not create file directory")
+				// #nosec G306. This is the original permission of the files we modify.
+				require.NoError(t, os.WriteFile(p, []byte("modified locally\n"), 0600), "Setup: can not modify file")
+			}
+
+			if tc.repair {
+				require.NoError(t, m.Repair(context.Background(), "hostname", true), "Repair should return no error but got one")
+			}
+
+			before := treeContent(t, fakeRootDir)
+
+			got, drift, err := m.VerifyPolicies(context.Background(), "hostname", true)
+			if tc.wantErr {
+				require.Error(t, err, "VerifyPolicies should return an error but got none")
+				return
+			}
+			require.NoError(t, err, "VerifyPolicies should return no error but got one")
+
+			require.Equal(t, before, treeContent(t, fakeRootDir), "VerifyPolicies should not modify the system")
+			require.Equal(t, tc.wantDrift, drift, "VerifyPolicies should report drift as expected")
+
+			got = strings.ReplaceAll(got, fakeRootDir, "#FAKEROOT#")
+			want := testutils.LoadWithUpdateFromGolden(t, got)
+			require.Equal(t, want, got, "VerifyPolicies returned expected output")
+		})
+	}
+}
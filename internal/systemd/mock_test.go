@@ -24,6 +24,7 @@ var errNoSuchUnit = dbus.NewError(fmt.Sprintf("%s.NoSuchUnit", consts.SystemdDbu
 const (
 	absentUnit  = "not-a-service.service"
 	failingUnit = "fail-to-start-stop.service"
+	loadedUnit  = "already-loaded.service"
 )
 
 func (s *systemdBus) StartUnit(name string, _ string) (dbus.ObjectPath, *dbus.Error) {
@@ -34,6 +35,27 @@ func (s *systemdBus) StartUnit(name string, _ string) (dbus.ObjectPath, *dbus.Er
 	return s.emitJobSignals(name), nil
 }
 
+// property is a systemd unit property, as sent over dbus.
+type property struct {
+	Name  string
+	Value dbus.Variant
+}
+
+func (s *systemdBus) StartTransientUnit(name string, _ string, props []property, _ []struct {
+	Name  string
+	Props []property
+}) (dbus.ObjectPath, *dbus.Error) {
+	if name == loadedUnit {
+		return dbus.ObjectPath("/"), dbus.MakeFailedError(fmt.Errorf("unit %s already exists", name))
+	}
+	for _, p := range props {
+		if p.Name == "ExecStart" {
+			return s.emitJobSignals(name), nil
+		}
+	}
+	return dbus.ObjectPath("/"), dbus.MakeFailedError(fmt.Errorf("unit %s has no ExecStart", name))
+}
+
 func (s *systemdBus) StopUnit(name string, _ string) (dbus.ObjectPath, *dbus.Error) {
 	if name == absentUnit {
 		return dbus.ObjectPath("/"), errNoSuchUnit
@@ -66,6 +88,46 @@ func (s *systemdBus) DisableUnitFiles(names []string, _ bool) ([][]string, *dbus
 	return [][]string{{"symlink", "/from/path", "/to/path"}}, nil
 }
 
+func (s *systemdBus) MaskUnitFiles(names []string, _ bool, _ bool) ([][]string, *dbus.Error) {
+	if len(names) != 1 {
+		panic("method is only expected to be called with a single name")
+	}
+
+	return [][]string{{"symlink", "/etc/systemd/system/" + names[0], "/dev/null"}}, nil
+}
+
+func (s *systemdBus) UnmaskUnitFiles(names []string, _ bool) ([][]string, *dbus.Error) {
+	if len(names) != 1 {
+		panic("method is only expected to be called with a single name")
+	}
+
+	if name := names[0]; name == absentUnit {
+		return nil, errNoSuchUnit
+	}
+
+	return [][]string{{"unlink", "/etc/systemd/system/" + names[0], ""}}, nil
+}
+
+func (s *systemdBus) ListUnitFilesByPatterns(_ []string, patterns []string) ([]struct {
+	Path string
+	Type string
+}, *dbus.Error) {
+	var files []struct {
+		Path string
+		Type string
+	}
+	for _, name := range patterns {
+		if name == absentUnit {
+			continue
+		}
+		files = append(files, struct {
+			Path string
+			Type string
+		}{Path: "/usr/lib/systemd/system/" + name, Type: "enabled"})
+	}
+	return files, nil
+}
+
 func (s *systemdBus) Reload() *dbus.Error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
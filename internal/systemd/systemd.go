@@ -1,14 +1,16 @@
 // Package systemd provides a wrapper around systemd dbus API that allows basic
-// service operations (start/stop/enable/disable).
+// service operations (start/stop/enable/disable/mask/unmask) and starting transient units.
 package systemd
 
 import (
 	"context"
 	"errors"
+	"path/filepath"
 
 	systemdDbus "github.com/coreos/go-systemd/v22/dbus"
 	"github.com/godbus/dbus/v5"
 	"github.com/leonelquinteros/gotext"
+	"github.com/ubuntu/adsys/internal/consts"
 	"github.com/ubuntu/decorate"
 )
 
@@ -20,6 +22,9 @@ type DefaultCaller struct {
 // jobDone is the string returned by systemd when a job completed successfully.
 const jobDone = "done"
 
+// ErrUnitNotFound is returned when the requested unit doesn't exist on the system.
+var ErrUnitNotFound = errors.New(gotext.Get("unit file not found"))
+
 // New returns a new systemdCaller using the given dbus connection.
 func New(bus *dbus.Conn) (*DefaultCaller, error) {
 	conn, err := systemdDbus.NewConnection(func() (*dbus.Conn, error) { return bus, nil })
@@ -60,7 +65,35 @@ func (s DefaultCaller) StopUnit(ctx context.Context, unit string) (err error) {
 	return nil
 }
 
-// EnableUnit enables the given unit.
+// StartTransientUnit starts cmd in the background in a transient service unit with the given name and description.
+// It returns once the command is started, without waiting for it to finish. The unit is garbage collected once
+// the command exits, even on failure.
+func (s DefaultCaller) StartTransientUnit(ctx context.Context, unit, description string, cmd []string) (err error) {
+	defer decorate.OnError(&err, gotext.Get("failed to start transient unit %s", unit))
+
+	if len(cmd) == 0 {
+		return errors.New(gotext.Get("no command to run"))
+	}
+
+	props := []systemdDbus.Property{
+		systemdDbus.PropDescription(description),
+		systemdDbus.PropType("exec"),
+		systemdDbus.PropExecStart(cmd, true),
+		{Name: "CollectMode", Value: dbus.MakeVariant("inactive-or-failed")},
+	}
+
+	reschan := make(chan string)
+	if _, err = s.conn.StartTransientUnitContext(ctx, unit, "fail", props, reschan); err != nil {
+		return err
+	}
+
+	if job := <-reschan; job != jobDone {
+		return errors.New(gotext.Get("start job failed"))
+	}
+	return nil
+}
+
+// EnableUnit enables the given unit. Systemd needs to be reloaded before starting it.
 func (s DefaultCaller) EnableUnit(ctx context.Context, unit string) (err error) {
 	defer decorate.OnError(&err, gotext.Get("failed to enable unit %s", unit))
 
@@ -70,7 +103,7 @@ func (s DefaultCaller) EnableUnit(ctx context.Context, unit string) (err error)
 	return nil
 }
 
-// DisableUnit disables the given unit.
+// DisableUnit disables the given unit. Systemd needs to be reloaded before stopping it.
 func (s DefaultCaller) DisableUnit(ctx context.Context, unit string) (err error) {
 	defer decorate.OnError(&err, gotext.Get("failed to disable unit %s", unit))
 
@@ -80,6 +113,48 @@ func (s DefaultCaller) DisableUnit(ctx context.Context, unit string) (err error)
 	return nil
 }
 
+// MaskUnit masks the given unit, so that it can't be started, even manually or as a dependency.
+// Systemd needs to be reloaded before stopping it.
+func (s DefaultCaller) MaskUnit(ctx context.Context, unit string) (err error) {
+	defer decorate.OnError(&err, gotext.Get("failed to mask unit %s", unit))
+
+	if _, err := s.conn.MaskUnitFilesContext(ctx, []string{unit}, false, true); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnmaskUnit unmasks the given unit. Systemd needs to be reloaded before starting it.
+func (s DefaultCaller) UnmaskUnit(ctx context.Context, unit string) (err error) {
+	defer decorate.OnError(&err, gotext.Get("failed to unmask unit %s", unit))
+
+	if _, err := s.conn.UnmaskUnitFilesContext(ctx, []string{unit}, false); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnitFileState returns the enablement state of the given unit file, like enabled, disabled, static or masked.
+// ErrUnitNotFound is returned if the unit doesn't exist.
+func (s DefaultCaller) UnitFileState(ctx context.Context, unit string) (state string, err error) {
+	defer decorate.OnError(&err, gotext.Get("failed to get state of unit %s", unit))
+
+	files, err := s.conn.ListUnitFilesByPatternsContext(ctx, nil, []string{unit})
+	var dbusErr dbus.Error
+	if errors.As(err, &dbusErr) && dbusErr.Name == consts.SystemdDbusRegisteredName+".NoSuchUnit" {
+		return "", ErrUnitNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	for _, f := range files {
+		if filepath.Base(f.Path) == unit {
+			return f.Type, nil
+		}
+	}
+	return "", ErrUnitNotFound
+}
+
 // DaemonReload scans and reloads unit files. This is an equivalent to systemctl daemon-reload.
 func (s DefaultCaller) DaemonReload(ctx context.Context) (err error) {
 	defer decorate.OnError(&err, gotext.Get("failed to reload units"))
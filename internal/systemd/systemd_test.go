@@ -36,6 +36,9 @@ func TestManageUnit(t *testing.T) {
 		"Stop unit that exists":    {action: "stop"},
 		"Enable unit that exists":  {action: "enable"},
 		"Disable unit that exists": {action: "disable"},
+		"Mask unit that exists":    {action: "mask"},
+		"Unmask unit that exists":  {action: "unmask"},
+		"Start transient unit":     {action: "start-transient"},
 
 		// Error cases
 		"Error when starting unit that doesn't exist": {unitName: absentUnit, action: "start", wantErr: true},
@@ -46,6 +49,11 @@ func TestManageUnit(t *testing.T) {
 
 		"Error when enabling unit that doesn't exist":  {unitName: absentUnit, action: "enable", wantErr: true},
 		"Error when disabling unit that doesn't exist": {unitName: absentUnit, action: "disable", wantErr: true},
+
+		"Error when unmasking unit that doesn't exist": {unitName: absentUnit, action: "unmask", wantErr: true},
+
+		"Error when starting transient unit that already exists": {unitName: loadedUnit, action: "start-transient", wantErr: true},
+		"Error when starting failing transient unit":             {unitName: failingUnit, action: "start-transient", wantErr: true},
 	}
 
 	for name, tc := range tests {
@@ -60,12 +68,18 @@ func TestManageUnit(t *testing.T) {
 			switch tc.action {
 			case "start":
 				err = systemdCaller.StartUnit(ctx, tc.unitName)
+			case "start-transient":
+				err = systemdCaller.StartTransientUnit(ctx, tc.unitName, "transient unit", []string{"/bin/true"})
 			case "stop":
 				err = systemdCaller.StopUnit(ctx, tc.unitName)
 			case "enable":
 				err = systemdCaller.EnableUnit(ctx, tc.unitName)
 			case "disable":
 				err = systemdCaller.DisableUnit(ctx, tc.unitName)
+			case "mask":
+				err = systemdCaller.MaskUnit(ctx, tc.unitName)
+			case "unmask":
+				err = systemdCaller.UnmaskUnit(ctx, tc.unitName)
 			default:
 				panic("unknown systemd action")
 			}
@@ -79,6 +93,22 @@ func TestManageUnit(t *testing.T) {
 	}
 }
 
+func TestUnitFileState(t *testing.T) {
+	t.Parallel()
+
+	bus := testutils.NewDbusConn(t)
+
+	systemdCaller, err := systemd.New(bus)
+	require.NoError(t, err, "Setup: failed to create systemd caller")
+
+	state, err := systemdCaller.UnitFileState(ctx, "existing-service.service")
+	require.NoError(t, err, "UnitFileState shouldn't have failed but it did")
+	require.Equal(t, "enabled", state, "UnitFileState returns the state of the unit file")
+
+	_, err = systemdCaller.UnitFileState(ctx, absentUnit)
+	require.ErrorIs(t, err, systemd.ErrUnitNotFound, "UnitFileState should have failed on a unit which doesn't exist but it didn't")
+}
+
 func TestDaemonReload(t *testing.T) {
 	t.Parallel()
 
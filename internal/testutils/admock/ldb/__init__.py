@@ -13,6 +13,7 @@ def binary_encode(s):
 
 OUs = {}
 GPOs = {}
+WMIFilters = {}
 accounts = {}
 
 ##############################
@@ -54,6 +55,10 @@ accounts = {}
 ##            -- RnDDep7 machine only GPO                             <- user flag disabled
 #  /example/RnD/RnDDep8                 <- RnDUserDep8
 ##            -- RnDDep8 allow for one user only GPO  <- RnDUserDep8  <- nTSecurityDescriptor allowed for another user that our one
+#  /example/RnD/RnDDep9                 <- RnDUserDep9
+##            -- RnDDep9 WMI filtered GPO                             <- WMI filter {LAPTOPS}
+##            -- RnDDep9 unreadable WMI filter GPO                    <- WMI filter {MISSING} does not exist
+##            -- RnDDep9 invalid WMI filter reference GPO             <- invalid gPCWQLFilter
 #  /example/RnD/RnDDepBlockInheritance               <-RnDUserWithBlockedInheritance      <- block inheritance
 ##            -- RnDDepBlockInheritance GPO
 #  /example/NoGPO                       <- UserNoGPO
@@ -72,6 +77,13 @@ accounts = {}
 ##            -- {5EC4DF8F-FF4E-41DE-846B-52AA6FFAF242} "GPO1 for current User"
 ##            -- {073AA7FC-5C1A-4A12-9AFC-42EC9C5CAF04} "GPO2 for current User"
 
+# Sites, under CN=Sites,CN=Configuration,DC=example,DC=com
+#  Branch
+##            -- Branch site GPO
+#  BranchWithForcedGPO
+##            -- Branch site GPO
+##            -- Branch site Forced GPO                               <- forced GPO
+
 ##############################
 
 # Only called on user/machine, returns correct account object
@@ -99,6 +111,9 @@ class OU:
 
         OUs[strdn] = self
 
+    def __str__(self):
+        return self.strdn
+
     def parent(self):
         ppath = path.dirname(self.strdn)
         if ppath == "":
@@ -137,7 +152,7 @@ class GPO:
             self.flags = [str.encode(str(dsdb.GPO_FLAG_USER_DISABLE))]
 
         self.enforced = False
-        if name == "RnDDep2 Forced GPO" or name == "SubDep2ForcedPolicy Forced GPO":
+        if name in ("RnDDep2 Forced GPO", "SubDep2ForcedPolicy Forced GPO", "Branch site Forced GPO"):
             self.enforced = True
         self.disabled = False
         if name == "RnDDep3 Disabled GPO":
@@ -164,6 +179,14 @@ class GPO:
 
         self.gPCFileSysPath = ['\\\\localhost%s\\SYSVOL\\%s\\Policies\\%s' % (smb_port, smb_domain, self.name)]
 
+        self.gPCWQLFilter = None
+        if name == "RnDDep9 WMI filtered GPO":
+            self.gPCWQLFilter = ['[example.com;{LAPTOPS};0]']
+        if name == "RnDDep9 unreadable WMI filter GPO":
+            self.gPCWQLFilter = ['[example.com;{MISSING};0]']
+        if name == "RnDDep9 invalid WMI filter reference GPO":
+            self.gPCWQLFilter = ['invalid']
+
 
 # Can be a User or a Computer
 class Account:
@@ -250,6 +273,12 @@ o = OU("/example/RnD/RnDDep8")
 o.addGPO(GPO("RnDDep8 allow for one user only GPO"))
 o.addAccount("RnDUserDep8")
 
+o = OU("/example/RnD/RnDDep9")
+o.addGPO(GPO("RnDDep9 WMI filtered GPO"))
+o.addGPO(GPO("RnDDep9 unreadable WMI filter GPO"))
+o.addGPO(GPO("RnDDep9 invalid WMI filter reference GPO"))
+o.addAccount("RnDUserDep9")
+
 o = OU("/example/RnD/RnDDepBlockInheritance")
 o.addGPO(GPO("RnDDepBlockInheritance GPO"))
 o.addAccount("RnDUserWithBlockedInheritance")
@@ -287,6 +316,20 @@ o.addGPO(GPO("{5EC4DF8F-FF4E-41DE-846B-52AA6FFAF242}", display_name="GPO1 for cu
 o.addGPO(GPO("{073AA7FC-5C1A-4A12-9AFC-42EC9C5CAF04}", display_name="GPO2 for current User"))
 o.addAccount(getuserWithoutDomain())
 
+# Sites
+o = OU("CN=Branch,CN=Sites,CN=Configuration,DC=example,DC=com")
+o.addGPO(GPO("Branch site GPO"))
+
+o = OU("CN=BranchWithForcedGPO,CN=Sites,CN=Configuration,DC=example,DC=com")
+o.addGPO(GPO("Branch site GPO"))
+o.addGPO(GPO("Branch site Forced GPO"))
+
+# WMI filters, under CN=SOM,CN=WMIPolicy,CN=System of the domain
+WMIFilters["CN={LAPTOPS},CN=SOM,CN=WMIPolicy,CN=System,/example"] = {
+    'msWMI-Name': ['Laptops'],
+    'msWMI-Parm2': ['1;3;10;65;WQL;root\\CIMv2;SELECT * FROM Win32_ComputerSystem\n\tWHERE Model LIKE "%ThinkPad%";'],
+}
+
 # [b'[LDAP://cn={83A5BD5B-1D5D-472D-827F-DE0E6F714300},cn=policies,cn=system,DC=domain,DC=com;0][LDAP://cn={5EC4DF8F-FF4E-41DE-846B-52AA6FFAF242},cn=policies,cn=system,DC=domain,DC=com;0]'
 
 # Message({'dn': Dn('OU=RnD,OU=IT Dept,DC=domain,DC=com'),
@@ -0,0 +1,4 @@
+# TiCS: disabled # samba mock
+
+NBT_SERVER_LDAP = 0x00000008
+NBT_SERVER_DS = 0x00000010
@@ -0,0 +1,18 @@
+# TiCS: disabled # samba mock
+
+from collections import namedtuple
+import os
+
+FindDCResponse = namedtuple('FindDCResponse', ['client_site'])
+
+
+class Net:
+    def __init__(self, creds=None, lp=None):
+        pass
+
+    def finddc(self, address=None, flags=0):
+        # The client site is set by the tests, and defaults to no site at all
+        site = os.getenv("ADSYS_TESTS_MOCK_SITE", "")
+        if site == "FAILED":
+            raise Exception("Failed to find a writeable DC for domain")
+        return FindDCResponse(client_site=site)
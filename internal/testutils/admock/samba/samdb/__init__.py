@@ -13,13 +13,15 @@ class AccountSearch(dict):
         dict.__setitem__(self, "objectSid", objectSid)
 
 class GPOSearch(dict):
-    def __init__(self, name, displayName, flags, nTSecurityDescriptor, gPCFileSysPath):
+    def __init__(self, name, displayName, flags, nTSecurityDescriptor, gPCFileSysPath, gPCWQLFilter=None):
         self.dn = name
         dict.__setitem__(self, "name", name)
         dict.__setitem__(self, "displayName", [displayName])
         dict.__setitem__(self, "flags", flags)
         dict.__setitem__(self, "nTSecurityDescriptor", nTSecurityDescriptor)
         dict.__setitem__(self, "gPCFileSysPath", gPCFileSysPath)
+        if gPCWQLFilter:
+            dict.__setitem__(self, "gPCWQLFilter", gPCWQLFilter)
 
 class SamDB:
     def __init__(self, url=None, session_info=None, credentials=None, lp=None):
@@ -62,20 +64,28 @@ class SamDB:
 
         # OU search
         elif "gPLink" in attrs:
-            ou = ldb.OUs[base.strdn]
+            ou = ldb.OUs[str(base)]
             r = {'gPLink': ou.gPLink}
             if hasattr(ou, 'gPOptions'):
                 r['gPOptions'] = ou.gPOptions
             return [r]
 
 
+        # WMI filter search
+        elif "msWMI-Parm2" in attrs:
+            return [ldb.WMIFilters[base]]
+
         # GPO Attribute
         gpo = ldb.GPOs[base]
         if gpo.nTSecurityDescriptor[0] == "MISSING":
             raise "nTSecurityDescriptor not available as requested"
-        return [GPOSearch(gpo.name, gpo.display_name, gpo.flags, gpo.nTSecurityDescriptor, gpo.gPCFileSysPath)]
+        return [GPOSearch(gpo.name, gpo.display_name, gpo.flags, gpo.nTSecurityDescriptor, gpo.gPCFileSysPath, gpo.gPCWQLFilter)]
 
 
     def get_default_basedn(self):
         return ldb.OUs["/example"]
 
+
+    def get_config_basedn(self):
+        return "CN=Configuration,DC=example,DC=com"
+
@@ -69,3 +69,33 @@ func (s MockSystemdCaller) StopUnit(_ context.Context, _ string) error    { retu
 func (s MockSystemdCaller) EnableUnit(_ context.Context, _ string) error  { return nil } //nolint:revive
 func (s MockSystemdCaller) DisableUnit(_ context.Context, _ string) error { return nil } //nolint:revive
 func (s MockSystemdCaller) DaemonReload(_ context.Context) error          { return nil } //nolint:revive
+func (s MockSystemdCaller) MaskUnit(_ context.Context, _ string) error    { return nil } //nolint:revive
+func (s MockSystemdCaller) UnmaskUnit(_ context.Context, _ string) error  { return nil } //nolint:revive
+
+//nolint:revive
+func (s MockSystemdCaller) UnitFileState(_ context.Context, _ string) (string, error) {
+	return "enabled", nil
+}
+
+//nolint:revive
+func (s MockSystemdCaller) StartTransientUnit(_ context.Context, _, _ string, _ []string) error {
+	return nil
+}
+
+// MockPackagesExecutor is a mock implementation of the packages executor, for a system with only a few packages.
+type MockPackagesExecutor struct{}
+
+// InstalledDebs returns a fixed list of installed debs.
+func (e MockPackagesExecutor) InstalledDebs(_ context.Context) ([]string, error) {
+	return []string{"curl", "curl:amd64", "libc6", "libc6:amd64"}, nil
+}
+
+// HeldDebs returns a fixed list of held debs.
+func (e MockPackagesExecutor) HeldDebs(_ context.Context) ([]string, error) {
+	return []string{"libc6"}, nil
+}
+
+// InstalledSnaps returns a fixed list of installed snaps.
+func (e MockPackagesExecutor) InstalledSnaps(_ context.Context) ([]string, error) {
+	return []string{"firefox"}, nil
+}